@@ -0,0 +1,16 @@
+// Package main реализует генератор пар ключей RSA и Ed25519 в формате PEM.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/RoGogDBD/metric-alerter/internal/app"
+)
+
+// main — точка входа генератора ключей.
+func main() {
+	if err := app.Keygen(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,10 +1,19 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/RoGogDBD/metric-alerter/cmd/linter"
+	"github.com/RoGogDBD/metric-alerter/internal/version"
 	"golang.org/x/tools/go/analysis/singlechecker"
 )
 
 func main() {
+	// singlechecker разбирает флаги самостоятельно, поэтому -version обрабатывается до него.
+	if len(os.Args) == 2 && (os.Args[1] == "-version" || os.Args[1] == "--version") {
+		fmt.Print(version.Get())
+		return
+	}
 	singlechecker.Main(linter.Analyzer)
 }
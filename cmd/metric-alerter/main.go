@@ -0,0 +1,21 @@
+// Package main реализует единый бинарник metric-alerter с подкомандами agent, server,
+// keygen и migrate.
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"github.com/RoGogDBD/metric-alerter/internal/app"
+)
+
+// main — точка входа единого бинарника. Выполняет подкоманду из первого аргумента.
+func main() {
+	if err := app.Run(os.Stderr, os.Args[1:]); err != nil {
+		if errors.Is(err, app.ErrUnknownCommand) {
+			os.Exit(2)
+		}
+		log.Fatal(err)
+	}
+}
@@ -13,6 +13,7 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"go/ast"
 	"go/format"
@@ -21,6 +22,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/version"
 )
 
 // generateComment — маркер комментария для генерации метода Reset().
@@ -39,6 +43,14 @@ type structInfo struct {
 
 // "BURN_BABY_BURN" - Apollo 11.
 func main() {
+	versionFlag := flag.Bool(config.FlagVersion, false, "Print build information and exit")
+	flag.Usage = config.Options{{Flag: config.FlagVersion}}.Usage("reset", flag.CommandLine)
+	flag.Parse()
+	if *versionFlag {
+		fmt.Print(version.Get())
+		return
+	}
+
 	if err := run(); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
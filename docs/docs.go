@@ -95,9 +95,15 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "HMAC-SHA256 подпись тела запроса",
+                        "description": "HMAC-SHA256 подпись несжатого тела запроса",
                         "name": "HashSHA256",
                         "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ed25519 подпись несжатого тела запроса (обязательна при заданном -verify-key)",
+                        "name": "X-Signature-Ed25519",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -182,7 +188,7 @@ const docTemplate = `{
         },
         "/updates/": {
             "post": {
-                "description": "Обновляет несколько метрик за один запрос, переданных в теле запроса в формате JSON",
+                "description": "Обновляет несколько метрик за один запрос, переданных в теле запроса в формате JSON. Если задан -stream-batch, неподписанный батч применяется порциями по мере разбора и не атомарно: при ошибке уже применённые порции остаются в хранилище, а их количество возвращается в заголовке X-Batch-Applied (ненулевое значение в ответе с ошибкой означает частично применённый батч)",
                 "consumes": [
                     "application/json"
                 ],
@@ -208,9 +214,15 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "HMAC-SHA256 подпись тела запроса",
+                        "description": "HMAC-SHA256 подпись несжатого тела запроса",
                         "name": "HashSHA256",
                         "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ed25519 подпись несжатого тела запроса (обязательна при заданном -verify-key)",
+                        "name": "X-Signature-Ed25519",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -221,18 +233,36 @@ const docTemplate = `{
                             "items": {
                                 "$ref": "#/definitions/models.Metrics"
                             }
+                        },
+                        "headers": {
+                            "X-Batch-Applied": {
+                                "type": "integer",
+                                "description": "Количество применённых метрик (только при -stream-batch)"
+                            }
                         }
                     },
                     "400": {
                         "description": "Некорректный JSON или неверная подпись",
                         "schema": {
                             "type": "string"
+                        },
+                        "headers": {
+                            "X-Batch-Applied": {
+                                "type": "integer",
+                                "description": "Количество применённых метрик (только при -stream-batch)"
+                            }
                         }
                     },
                     "500": {
                         "description": "Ошибка сохранения метрик",
                         "schema": {
                             "type": "string"
+                        },
+                        "headers": {
+                            "X-Batch-Applied": {
+                                "type": "integer",
+                                "description": "Количество применённых метрик (только при -stream-batch)"
+                            }
                         }
                     }
                 }
@@ -331,6 +361,58 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/values/": {
+            "post": {
+                "description": "Возвращает найденные метрики из списка id и type в порядке запроса; отсутствующие метрики пропускаются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Получить значения нескольких метрик",
+                "parameters": [
+                    {
+                        "description": "Запрашиваемые метрики (id и type обязательны)",
+                        "name": "metrics",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Metrics"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Найденные метрики со значениями",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Metrics"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный JSON, пустой список или метрика без имени",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "501": {
+                        "description": "Неизвестный тип метрики",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
@@ -349,6 +431,9 @@ const docTemplate = `{
                 "type": {
                     "type": "string"
                 },
+                "updated_at": {
+                    "type": "string"
+                },
                 "value": {
                     "type": "number"
                 }
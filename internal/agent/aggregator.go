@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// errInt64Overflow возвращается, если сумма приращений счётчика выходит за пределы int64.
+var errInt64Overflow = errors.New("int64 overflow")
+
+// timerStats — агрегат значений таймера за интервал отправки.
+type timerStats struct {
+	count    int64
+	sum      float64
+	min, max float64
+}
+
+// Aggregator накапливает метрики внешних источников (StatsD, локальный push API) между отправками.
+//
+// Счётчики и таймеры сбрасываются при каждом Flush, gauge сохраняют последнее значение,
+// как в StatsD.
+//
+// Поля:
+//   - counters: сумма приращений счётчиков за интервал
+//   - gauges: текущие значения gauge
+//   - timers: агрегаты таймеров за интервал
+type Aggregator struct {
+	mu       sync.Mutex
+	counters map[string]int64
+	gauges   map[string]float64
+	timers   map[string]*timerStats
+}
+
+// NewAggregator создаёт пустой агрегатор.
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		counters: make(map[string]int64),
+		gauges:   make(map[string]float64),
+		timers:   make(map[string]*timerStats),
+	}
+}
+
+// AddCounter прибавляет delta к счётчику name.
+//
+// Возвращает ошибку, если сумма за интервал выходит за пределы int64.
+func (a *Aggregator) AddCounter(name string, delta int64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	sum, err := addInt64(a.counters[name], delta)
+	if err != nil {
+		return fmt.Errorf("counter %s: %w", name, err)
+	}
+	a.counters[name] = sum
+	return nil
+}
+
+// AddCounters прибавляет приращения deltas (имя → приращение) к счётчикам.
+//
+// Приращения применяются все или ни одного: если хотя бы один счётчик переполнится,
+// возвращается ошибка, а счётчики не изменяются.
+func (a *Aggregator) AddCounters(deltas map[string]int64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	sums := make(map[string]int64, len(deltas))
+	for name, delta := range deltas {
+		sum, err := addInt64(a.counters[name], delta)
+		if err != nil {
+			return fmt.Errorf("counter %s: %w", name, err)
+		}
+		sums[name] = sum
+	}
+	for name, sum := range sums {
+		a.counters[name] = sum
+	}
+	return nil
+}
+
+// SetGauge устанавливает значение gauge name.
+func (a *Aggregator) SetGauge(name string, value float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.gauges[name] = value
+}
+
+// AddGauge изменяет значение gauge name на delta.
+func (a *Aggregator) AddGauge(name string, delta float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.gauges[name] += delta
+}
+
+// ObserveTimer учитывает одно измерение таймера name.
+func (a *Aggregator) ObserveTimer(name string, value float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t, ok := a.timers[name]
+	if !ok {
+		t = &timerStats{min: value, max: value}
+		a.timers[name] = t
+	}
+	t.count++
+	t.sum += value
+	t.min = math.Min(t.min, value)
+	t.max = math.Max(t.max, value)
+}
+
+// addInt64 складывает a и b, возвращая ошибку при переполнении int64.
+func addInt64(a, b int64) (int64, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, errInt64Overflow
+	}
+	return a + b, nil
+}
+
+// Flush возвращает метрики, накопленные с предыдущего вызова, и сбрасывает счётчики и таймеры.
+//
+// Таймер name превращается в counter name_count и gauge name_min, name_max, name_mean.
+// Метрики упорядочены по имени.
+func (a *Aggregator) Flush() []models.Metrics {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	metrics := make([]models.Metrics, 0, len(a.counters)+len(a.gauges)+4*len(a.timers))
+	counter := func(name string, delta int64) {
+		metrics = append(metrics, models.Metrics{ID: name, MType: models.Counter, Delta: &delta})
+	}
+	gauge := func(name string, value float64) {
+		metrics = append(metrics, models.Metrics{ID: name, MType: models.Gauge, Value: &value})
+	}
+	for name, delta := range a.counters {
+		counter(name, delta)
+	}
+	for name, value := range a.gauges {
+		gauge(name, value)
+	}
+	for name, t := range a.timers {
+		counter(name+"_count", t.count)
+		gauge(name+"_min", t.min)
+		gauge(name+"_max", t.max)
+		gauge(name+"_mean", t.sum/float64(t.count))
+	}
+	clear(a.counters)
+	clear(a.timers)
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].ID < metrics[j].ID })
+	return metrics
+}
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"math"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+// flushMap переводит результат Flush в карту имя → значение.
+func flushMap(metrics []models.Metrics) map[string]float64 {
+	out := make(map[string]float64, len(metrics))
+	for _, m := range metrics {
+		if m.MType == models.Counter {
+			out[m.ID] = float64(*m.Delta)
+		} else {
+			out[m.ID] = *m.Value
+		}
+	}
+	return out
+}
+
+// TestAggregator_Flush проверяет, что счётчики и таймеры сбрасываются, а gauge сохраняются.
+func TestAggregator_Flush(t *testing.T) {
+	agg := NewAggregator()
+	require.NoError(t, agg.AddCounter("hits", 1))
+	agg.SetGauge("temp", 5)
+	agg.ObserveTimer("rt", 1)
+	require.Len(t, agg.Flush(), 6)
+
+	require.Equal(t, map[string]float64{"temp": 5}, flushMap(agg.Flush()))
+}
+
+// TestAggregator_AddCounterOverflow проверяет, что переполнение счётчика не меняет накопленное значение.
+func TestAggregator_AddCounterOverflow(t *testing.T) {
+	agg := NewAggregator()
+	require.NoError(t, agg.AddCounter("hits", math.MaxInt64))
+	require.Error(t, agg.AddCounter("hits", 1))
+	require.Equal(t, map[string]float64{"hits": math.MaxInt64}, flushMap(agg.Flush()))
+}
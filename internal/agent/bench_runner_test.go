@@ -1,4 +1,4 @@
-package main
+package agent
 
 import (
 	"io"
@@ -46,8 +46,8 @@ func BenchmarkSendMetrics(b *testing.B) {
 	defer ts.Close()
 
 	client := resty.New().SetBaseURL(ts.URL)
-	state := &AgentState{
-		Collector: &MetricsCollector{metrics: map[string]Metric{"m": {Type: "gauge", Value: 1.0}}},
+	state := &Runner{
+		Collector: &Collector{metrics: map[string]Metric{"m": {Type: "gauge", Value: 1.0}}},
 		Config:    Config{ReportInterval: 1, PollInterval: 1, RateLimit: 1},
 		Sender:    &RestySender{Client: client},
 	}
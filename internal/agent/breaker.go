@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen возвращается вместо отправки, пока размыкатель разомкнут.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker — размыкатель цепи для отправки метрик.
+//
+// После threshold ошибок подряд размыкается и cooldown отклоняет отправки без обращения
+// к серверу, не создавая лавину повторов. Затем пропускает одну пробную отправку:
+// при успехе замыкается, при ошибке снова размыкается на cooldown.
+//
+// Поля:
+//   - threshold: число ошибок подряд, после которого размыкатель размыкается
+//   - cooldown: время, на которое размыкатель размыкается
+//   - failures: число ошибок подряд
+//   - openUntil: момент, до которого отправки отклоняются (нулевой — замкнут)
+//   - probing: пробная отправка выполняется и другие отклоняются
+//   - now: функция получения текущего времени
+//   - mu: мьютекс для доступа к состоянию
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+// NewCircuitBreaker создаёт замкнутый размыкатель.
+//
+// threshold — число ошибок подряд до размыкания.
+// cooldown — время между пробными отправками при разомкнутом размыкателе.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow разрешает отправку или возвращает ErrCircuitOpen.
+//
+// После истечения cooldown разрешает одну пробную отправку; её результат
+// нужно передать в Success или Failure.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if b.probing || b.now().Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// Success замыкает размыкатель и сбрасывает счётчик ошибок.
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
+
+// Failure учитывает ошибку отправки и размыкает размыкатель после threshold ошибок подряд
+// или после неудачной пробной отправки.
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.probing || b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+	b.probing = false
+}
+
+// Open сообщает, разомкнут ли размыкатель.
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.openUntil.IsZero()
+}
@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCircuitBreaker проверяет размыкание после ошибок подряд, пробную отправку и замыкание.
+//
+// t — указатель на структуру теста.
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewCircuitBreaker(3, time.Minute)
+	b.now = func() time.Time { return now }
+
+	// Успех между ошибками сбрасывает счётчик.
+	b.Failure()
+	b.Failure()
+	b.Success()
+	b.Failure()
+	b.Failure()
+	require.NoError(t, b.Allow())
+	require.False(t, b.Open())
+
+	b.Failure()
+	require.True(t, b.Open())
+	require.ErrorIs(t, b.Allow(), ErrCircuitOpen)
+
+	// После cooldown пропускается только одна пробная отправка.
+	now = now.Add(time.Minute)
+	require.NoError(t, b.Allow())
+	require.ErrorIs(t, b.Allow(), ErrCircuitOpen)
+
+	// Неудачная проба снова размыкает на cooldown.
+	b.Failure()
+	require.ErrorIs(t, b.Allow(), ErrCircuitOpen)
+	now = now.Add(time.Minute)
+	require.NoError(t, b.Allow())
+
+	b.Success()
+	require.False(t, b.Open())
+	require.NoError(t, b.Allow())
+	require.NoError(t, b.Allow())
+}
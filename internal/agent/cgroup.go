@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cgroupRoot — точка монтирования cgroup внутри контейнера.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// CgroupCPU читает ограничение и потребление CPU контейнера из cgroup v2 или v1.
+//
+// Поля:
+//   - fsys: файловая система с корнем в точке монтирования cgroup
+//   - lastUsage: потребление CPU на момент предыдущего замера
+//   - lastAt: время предыдущего замера (нулевое — замеров ещё не было)
+type CgroupCPU struct {
+	fsys      fs.FS
+	lastUsage time.Duration
+	lastAt    time.Time
+}
+
+// NewCgroupCPU создаёт читателя cgroup текущего контейнера.
+func NewCgroupCPU() *CgroupCPU {
+	return &CgroupCPU{fsys: os.DirFS(cgroupRoot)}
+}
+
+// Limit возвращает ограничение CPU контейнера в ядрах (квота / период).
+//
+// Возвращает false, если ограничение не задано или cgroup недоступна (например, вне контейнера).
+func (c *CgroupCPU) Limit() (float64, bool) {
+	// cgroup v2: "max 100000" или "200000 100000".
+	if data, err := fs.ReadFile(c.fsys, "cpu.max"); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0, false
+		}
+		return quotaCores(fields[0], fields[1])
+	}
+	// cgroup v1: квота -1 означает отсутствие ограничения.
+	for _, dir := range []string{"cpu", "cpu,cpuacct"} {
+		quota, err := fs.ReadFile(c.fsys, dir+"/cpu.cfs_quota_us")
+		if err != nil {
+			continue
+		}
+		period, err := fs.ReadFile(c.fsys, dir+"/cpu.cfs_period_us")
+		if err != nil {
+			continue
+		}
+		return quotaCores(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+	}
+	return 0, false
+}
+
+// quotaCores переводит квоту и период CFS в количество ядер.
+func quotaCores(quota, period string) (float64, bool) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return q / p, true
+}
+
+// usage возвращает суммарное потребление CPU контейнером с момента его запуска.
+func (c *CgroupCPU) usage() (time.Duration, bool) {
+	// cgroup v2: строка "usage_usec N" в cpu.stat.
+	if data, err := fs.ReadFile(c.fsys, "cpu.stat"); err == nil {
+		s := bufio.NewScanner(bytes.NewReader(data))
+		for s.Scan() {
+			if v, ok := strings.CutPrefix(s.Text(), "usage_usec "); ok {
+				us, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+				if err != nil {
+					return 0, false
+				}
+				return time.Duration(us) * time.Microsecond, true
+			}
+		}
+		return 0, false
+	}
+	// cgroup v1: наносекунды в cpuacct.usage.
+	for _, dir := range []string{"cpuacct", "cpu,cpuacct"} {
+		data, err := fs.ReadFile(c.fsys, dir+"/cpuacct.usage")
+		if err != nil {
+			continue
+		}
+		ns, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(ns), true
+	}
+	return 0, false
+}
+
+// Utilization возвращает загрузку CPU контейнера в процентах от ограничения limit
+// с момента предыдущего вызова.
+//
+// Первый вызов только запоминает исходный замер и возвращает false.
+func (c *CgroupCPU) Utilization(limit float64, now time.Time) (float64, bool) {
+	used, ok := c.usage()
+	if !ok || limit <= 0 {
+		return 0, false
+	}
+	prevUsage, prevAt := c.lastUsage, c.lastAt
+	c.lastUsage, c.lastAt = used, now
+	if prevAt.IsZero() || !now.After(prevAt) || used < prevUsage {
+		return 0, false
+	}
+	cores := float64(used-prevUsage) / float64(now.Sub(prevAt))
+	return math.Min(cores/limit*100, 100), true
+}
+
+// AdjustMaxProcs уменьшает GOMAXPROCS до ограничения CPU контейнера limit (с округлением вверх),
+// чтобы планировщик Go не создавал потоков больше, чем позволяет квота.
+//
+// Явно заданная переменная окружения GOMAXPROCS имеет приоритет.
+// Возвращает новое значение и true, если GOMAXPROCS изменён.
+func AdjustMaxProcs(limit float64) (int, bool) {
+	if os.Getenv("GOMAXPROCS") != "" || limit <= 0 {
+		return 0, false
+	}
+	n := max(int(math.Ceil(limit)), 1)
+	if n >= runtime.GOMAXPROCS(0) {
+		return 0, false
+	}
+	runtime.GOMAXPROCS(n)
+	return n, true
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCgroupCPU_Limit проверяет чтение ограничения CPU из cgroup v2 и v1.
+func TestCgroupCPU_Limit(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    float64
+		limited bool
+	}{
+		{"v2 quota", fstest.MapFS{"cpu.max": {Data: []byte("150000 100000\n")}}, 1.5, true},
+		{"v2 unlimited", fstest.MapFS{"cpu.max": {Data: []byte("max 100000\n")}}, 0, false},
+		{"v1 quota", fstest.MapFS{
+			"cpu,cpuacct/cpu.cfs_quota_us":  {Data: []byte("200000\n")},
+			"cpu,cpuacct/cpu.cfs_period_us": {Data: []byte("100000\n")},
+		}, 2, true},
+		{"v1 unlimited", fstest.MapFS{
+			"cpu/cpu.cfs_quota_us":  {Data: []byte("-1\n")},
+			"cpu/cpu.cfs_period_us": {Data: []byte("100000\n")},
+		}, 0, false},
+		{"no cgroup", fstest.MapFS{}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := (&CgroupCPU{fsys: tt.files}).Limit()
+			require.Equal(t, tt.limited, ok)
+			require.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+// TestCgroupCPU_Utilization проверяет расчёт загрузки от ограничения контейнера между замерами.
+func TestCgroupCPU_Utilization(t *testing.T) {
+	files := fstest.MapFS{"cpu.stat": {Data: []byte("usage_usec 1000000\nuser_usec 800000\n")}}
+	c := &CgroupCPU{fsys: files}
+	start := time.Unix(100, 0)
+
+	_, ok := c.Utilization(2, start)
+	require.False(t, ok, "first call only records the baseline")
+
+	// За 1 секунду израсходовано 0.5 с CPU при ограничении 2 ядра — 25%.
+	files["cpu.stat"] = &fstest.MapFile{Data: []byte("usage_usec 1500000\n")}
+	got, ok := c.Utilization(2, start.Add(time.Second))
+	require.True(t, ok)
+	require.InDelta(t, 25.0, got, 1e-9)
+
+	// Загрузка выше ограничения обрезается до 100%.
+	files["cpu.stat"] = &fstest.MapFile{Data: []byte("usage_usec 5500000\n")}
+	got, ok = c.Utilization(2, start.Add(2*time.Second))
+	require.True(t, ok)
+	require.Equal(t, 100.0, got)
+}
+
+// TestCgroupCPU_UtilizationV1 проверяет чтение потребления CPU из cgroup v1.
+func TestCgroupCPU_UtilizationV1(t *testing.T) {
+	files := fstest.MapFS{"cpuacct/cpuacct.usage": {Data: []byte("0\n")}}
+	c := &CgroupCPU{fsys: files}
+	start := time.Unix(100, 0)
+	_, _ = c.Utilization(1, start)
+
+	files["cpuacct/cpuacct.usage"] = &fstest.MapFile{Data: []byte("500000000\n")}
+	got, ok := c.Utilization(1, start.Add(time.Second))
+	require.True(t, ok)
+	require.InDelta(t, 50.0, got, 1e-9)
+}
+
+// TestAdjustMaxProcs проверяет, что явный GOMAXPROCS не переопределяется.
+func TestAdjustMaxProcs(t *testing.T) {
+	t.Setenv("GOMAXPROCS", "4")
+	_, ok := AdjustMaxProcs(1)
+	require.False(t, ok)
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"sync"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// ChangeFilter отбрасывает из батча gauge, значение которых не изменилось с последней
+// успешной отправки. Счётчики передаются всегда.
+//
+// Поля:
+//   - mu: мьютекс для конкурентного доступа из цикла отправки и воркеров
+//   - sent: последние успешно отправленные значения gauge
+type ChangeFilter struct {
+	mu   sync.Mutex
+	sent map[string]float64
+}
+
+// NewChangeFilter создаёт фильтр без отправленных значений: первый батч передаётся целиком.
+func NewChangeFilter() *ChangeFilter {
+	return &ChangeFilter{sent: make(map[string]float64)}
+}
+
+// Filter возвращает батч без gauge, совпадающих с последними успешно отправленными значениями.
+func (f *ChangeFilter) Filter(batch []models.Metrics) []models.Metrics {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := batch[:0:0]
+	for _, m := range batch {
+		if m.MType == models.Gauge && m.Value != nil {
+			if v, ok := f.sent[m.ID]; ok && v == *m.Value {
+				continue
+			}
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// Commit запоминает значения gauge из успешно отправленного батча.
+//
+// Пока батч не подтверждён, изменённые gauge продолжают попадать в следующие батчи.
+func (f *ChangeFilter) Commit(batch []models.Metrics) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, m := range batch {
+		if m.MType == models.Gauge && m.Value != nil {
+			f.sent[m.ID] = *m.Value
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+// gaugeMetric создаёт gauge-метрику для тестов.
+func gaugeMetric(id string, v float64) models.Metrics {
+	return models.Metrics{ID: id, MType: models.Gauge, Value: &v}
+}
+
+// counterMetric создаёт counter-метрику для тестов.
+func counterMetric(id string, d int64) models.Metrics {
+	return models.Metrics{ID: id, MType: models.Counter, Delta: &d}
+}
+
+// ids возвращает имена метрик батча.
+func ids(batch []models.Metrics) []string {
+	out := make([]string, len(batch))
+	for i, m := range batch {
+		out[i] = m.ID
+	}
+	return out
+}
+
+// TestChangeFilter проверяет, что неизменённые gauge пропускаются только после успешной отправки.
+func TestChangeFilter(t *testing.T) {
+	f := NewChangeFilter()
+	first := []models.Metrics{gaugeMetric("a", 1), gaugeMetric("b", 2), counterMetric("c", 1)}
+	require.Equal(t, []string{"a", "b", "c"}, ids(f.Filter(first)))
+
+	// Без подтверждения отправки батч не сокращается.
+	require.Equal(t, []string{"a", "b", "c"}, ids(f.Filter(first)))
+
+	f.Commit(first)
+	next := []models.Metrics{gaugeMetric("a", 1), gaugeMetric("b", 3), counterMetric("c", 1), gaugeMetric("d", 0)}
+	require.Equal(t, []string{"b", "c", "d"}, ids(f.Filter(next)))
+	require.Len(t, next, 4, "Filter must not modify the input batch")
+}
@@ -0,0 +1,248 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+type (
+	// Metric — структура для хранения метрики (тип и значение).
+	Metric struct {
+		Type  string  // Тип метрики: "gauge" или "counter"
+		Value float64 // Значение метрики
+	}
+
+	// Collector — сборщик метрик, хранит значения и счетчик опросов.
+	Collector struct {
+		metrics    map[string]Metric // Собранные метрики.
+		pollCount  int64             // Счетчик опросов.
+		rng        *rand.Rand        // Генератор случайных чисел.
+		collectors Collectors        // Включённые необязательные сборщики системных метрик.
+		netFilter  NetFilter         // Отбор сетевых интерфейсов для сборщика net.
+		self       *SelfCollector    // Метрики процесса агента для сборщика self (nil — отключён).
+		intervals  CollectIntervals  // Периоды опроса, заданные для отдельных групп сборщиков.
+		cgroup     *CgroupCPU        // Учёт CPU контейнера (nil — ограничение CPU не задано).
+		cpuLimit   float64           // Ограничение CPU контейнера в ядрах.
+		cpuMode    CPUMode           // Режим отчёта о загрузке CPU.
+		mu         sync.RWMutex      // Мьютекс для конкурентного доступа.
+	}
+)
+
+// NewCollector создаёт сборщик метрик по настройкам Collect, NetInclude, NetExclude,
+// CollectIntervals и CPUMode из cfg.
+//
+// В контейнере с квотой CPU загрузка считается от ограничения контейнера.
+// Возвращает ошибку, если какая-либо из настроек некорректна.
+func NewCollector(cfg Config) (*Collector, error) {
+	cpuMode, err := ParseCPUMode(cfg.CPUMode)
+	if err != nil {
+		return nil, err
+	}
+	collectors, err := ParseCollectors(cfg.Collect)
+	if err != nil {
+		return nil, err
+	}
+	netFilter, err := ParseNetFilter(cfg.NetInclude, cfg.NetExclude)
+	if err != nil {
+		return nil, err
+	}
+	intervals, err := ParseCollectIntervals(cfg.CollectIntervals)
+	if err != nil {
+		return nil, err
+	}
+	var self *SelfCollector
+	if collectors.Enabled(CollectorSelf) {
+		if self, err = NewSelfCollector(); err != nil {
+			return nil, err
+		}
+	}
+
+	cgroup := NewCgroupCPU()
+	cpuLimit, limited := cgroup.Limit()
+	if limited {
+		log.Printf("Container CPU limit: %.2f cores", cpuLimit)
+	} else {
+		cgroup = nil
+	}
+
+	return &Collector{
+		metrics:    make(map[string]Metric),
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		collectors: collectors,
+		netFilter:  netFilter,
+		self:       self,
+		intervals:  intervals,
+		cgroup:     cgroup,
+		cpuLimit:   cpuLimit,
+		cpuMode:    cpuMode,
+	}, nil
+}
+
+// Snapshot возвращает копию собранных на данный момент метрик.
+func (c *Collector) Snapshot() map[string]Metric {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := make(map[string]Metric, len(c.metrics))
+	for k, v := range c.metrics {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+// collectMetrics собирает метрики из runtime и обновляет их в коллекторе.
+//
+// state — текущее состояние агента.
+func collectMetrics(state *Runner) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	metrics := map[string]float64{
+		"Alloc":         float64(m.Alloc),
+		"BuckHashSys":   float64(m.BuckHashSys),
+		"Frees":         float64(m.Frees),
+		"GCCPUFraction": m.GCCPUFraction,
+		"GCSys":         float64(m.GCSys),
+		"HeapAlloc":     float64(m.HeapAlloc),
+		"HeapIdle":      float64(m.HeapIdle),
+		"HeapInuse":     float64(m.HeapInuse),
+		"HeapObjects":   float64(m.HeapObjects),
+		"HeapReleased":  float64(m.HeapReleased),
+		"HeapSys":       float64(m.HeapSys),
+		"LastGC":        float64(m.LastGC),
+		"Lookups":       float64(m.Lookups),
+		"MCacheInuse":   float64(m.MCacheInuse),
+		"MCacheSys":     float64(m.MCacheSys),
+		"MSpanInuse":    float64(m.MSpanInuse),
+		"MSpanSys":      float64(m.MSpanSys),
+		"Mallocs":       float64(m.Mallocs),
+		"NextGC":        float64(m.NextGC),
+		"NumForcedGC":   float64(m.NumForcedGC),
+		"NumGC":         float64(m.NumGC),
+		"OtherSys":      float64(m.OtherSys),
+		"PauseTotalNs":  float64(m.PauseTotalNs),
+		"StackInuse":    float64(m.StackInuse),
+		"StackSys":      float64(m.StackSys),
+		"Sys":           float64(m.Sys),
+		"TotalAlloc":    float64(m.TotalAlloc),
+	}
+
+	state.Collector.mu.Lock()
+	defer state.Collector.mu.Unlock()
+
+	for k, v := range metrics {
+		state.Collector.metrics[k] = Metric{"gauge", v}
+	}
+
+	state.Collector.pollCount++
+	state.Collector.metrics["PollCount"] = Metric{"counter", float64(state.Collector.pollCount)}
+	state.Collector.metrics["RandomValue"] = Metric{"gauge", state.Collector.rng.Float64() * 100}
+}
+
+// collectSystemMetrics собирает системные метрики (память и CPU) и обновляет их в коллекторе.
+func (c *Collector) collectSystemMetrics() {
+	updates := make(map[string]Metric)
+
+	if vm, err := mem.VirtualMemory(); err == nil {
+		updates["TotalMemory"] = Metric{"gauge", float64(vm.Total)}
+		updates["FreeMemory"] = Metric{"gauge", float64(vm.Free)}
+	}
+
+	if c.cgroup != nil {
+		// В контейнере с квотой загрузка считается от ограничения, а не от ядер хоста:
+		// CPUutilization1..N по числу выделенных ядер (с округлением вверх).
+		updates["ContainerCPULimit"] = Metric{"gauge", c.cpuLimit}
+		if p, ok := c.cgroup.Utilization(c.cpuLimit, time.Now()); ok {
+			if c.cpuMode == CPUTotal {
+				updates["CPUutilization"] = Metric{"gauge", p}
+			} else {
+				for i := range int(math.Ceil(c.cpuLimit)) {
+					updates[fmt.Sprintf("CPUutilization%d", i+1)] = Metric{"gauge", p}
+				}
+			}
+		}
+	} else if c.cpuMode == CPUTotal {
+		if percents, err := cpu.Percent(0, false); err == nil && len(percents) > 0 {
+			updates["CPUutilization"] = Metric{"gauge", percents[0]}
+		}
+	} else if percents, err := cpu.Percent(0, true); err == nil {
+		for i, p := range percents {
+			key := fmt.Sprintf("CPUutilization%d", i+1)
+			updates[key] = Metric{"gauge", p}
+		}
+	}
+
+	c.mu.Lock()
+	for k, v := range updates {
+		c.metrics[k] = v
+	}
+	c.mu.Unlock()
+}
+
+// collectOptional собирает метрики необязательного сборщика name (Collector*),
+// включённого через -collect, и обновляет их в коллекторе.
+func (c *Collector) collectOptional(name string) {
+	var values map[string]float64
+	switch name {
+	case CollectorDisk:
+		values = DiskMetrics()
+	case CollectorNet:
+		values = NetworkMetrics(c.netFilter)
+	case CollectorSelf:
+		if c.self == nil {
+			return
+		}
+		values = c.self.Collect()
+	}
+
+	c.mu.Lock()
+	for k, v := range values {
+		c.metrics[k] = Metric{"gauge", v}
+	}
+	c.mu.Unlock()
+}
+
+// startCollectors запускает периодический опрос групп сборщиков: runtime, system и
+// включённых необязательных сборщиков. Период группы берётся из -collect-intervals,
+// по умолчанию — PollInterval.
+//
+// Опрос останавливается при отмене ctx.
+func startCollectors(ctx context.Context, state *Runner) {
+	c := state.Collector
+	groups := map[string]func(){
+		GroupRuntime: func() { collectMetrics(state) },
+		GroupSystem:  c.collectSystemMetrics,
+	}
+	for name := range c.collectors {
+		groups[name] = func() { c.collectOptional(name) }
+	}
+
+	poll := time.Duration(state.Config.PollInterval) * time.Second
+	for group, collect := range groups {
+		interval := c.intervals.Get(group, poll)
+		if interval != poll {
+			log.Printf("Collector group %s polled every %s", group, interval)
+		}
+		go func() {
+			t := time.NewTicker(interval)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					collect()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// TestStartCollectors_PerGroupIntervals проверяет, что группы сборщиков опрашиваются со своими периодами.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestStartCollectors_PerGroupIntervals(t *testing.T) {
+	state := &Runner{
+		Config: Config{PollInterval: 3600},
+		Collector: &Collector{
+			metrics:   make(map[string]Metric),
+			rng:       rand.New(rand.NewSource(1)),
+			intervals: CollectIntervals{GroupRuntime: 10 * time.Millisecond},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	startCollectors(ctx, state)
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	state.Collector.mu.RLock()
+	defer state.Collector.mu.RUnlock()
+	if _, ok := state.Collector.metrics["PollCount"]; !ok {
+		t.Fatal("expected runtime group to be polled at its own interval")
+	}
+	if _, ok := state.Collector.metrics["TotalMemory"]; ok {
+		t.Fatal("system group must not be polled before PollInterval")
+	}
+}
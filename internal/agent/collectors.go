@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Необязательные сборщики системных метрик, включаемые флагом -collect.
+const (
+	// CollectorDisk — заполненность файловых систем и счётчики ввода-вывода дисков.
+	CollectorDisk = "disk"
+	// CollectorNet — счётчики сетевых интерфейсов.
+	CollectorNet = "net"
+	// CollectorSelf — ресурсы процесса агента, глубина очереди отправки и спула.
+	CollectorSelf = "self"
+)
+
+// knownCollectors — сборщики, которые можно включить через -collect.
+var knownCollectors = []string{CollectorDisk, CollectorNet, CollectorSelf}
+
+// Collectors — набор включённых необязательных сборщиков метрик.
+type Collectors map[string]struct{}
+
+// ParseCollectors разбирает список сборщиков через запятую (например, "disk").
+//
+// Пустая строка означает, что необязательные сборщики отключены.
+func ParseCollectors(s string) (Collectors, error) {
+	c := make(Collectors)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !isKnownCollector(name) {
+			return nil, fmt.Errorf("unknown collector %q (want one of %s)", name, strings.Join(knownCollectors, ", "))
+		}
+		c[name] = struct{}{}
+	}
+	return c, nil
+}
+
+// Enabled сообщает, включён ли сборщик name.
+func (c Collectors) Enabled(name string) bool {
+	_, ok := c[name]
+	return ok
+}
+
+// isKnownCollector проверяет, что сборщик с таким именем существует.
+func isKnownCollector(name string) bool {
+	for _, k := range knownCollectors {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// metricSuffix преобразует точку монтирования, имя устройства или сетевого интерфейса в суффикс имени метрики:
+// "/" становится "root", разделители путей и прочие символы, кроме букв, цифр, "-" и "_", заменяются на "_".
+func metricSuffix(name string) string {
+	name = strings.Trim(name, `/\`)
+	if name == "" {
+		return "root"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
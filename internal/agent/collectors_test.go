@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseCollectors проверяет разбор списка необязательных сборщиков.
+func TestParseCollectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		wantDisk bool
+		wantErr  bool
+	}{
+		{name: "empty", in: ""},
+		{name: "disk", in: "disk", wantDisk: true},
+		{name: "spaces and empty items", in: " disk ,", wantDisk: true},
+		{name: "unknown", in: "disk,gpu", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCollectors(tt.in)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDisk, c.Enabled(CollectorDisk))
+		})
+	}
+}
+
+// TestMetricSuffix проверяет преобразование точек монтирования, устройств и интерфейсов в суффиксы имён метрик.
+func TestMetricSuffix(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"/", "root"},
+		{"/var/lib", "var_lib"},
+		{"/mnt/data disk", "mnt_data_disk"},
+		{"sda1", "sda1"},
+		{`C:\`, "C_"},
+		{"nvme0n1", "nvme0n1"},
+		{"eth0.100", "eth0_100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			require.Equal(t, tt.want, metricSuffix(tt.in))
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package agent
+
+import "fmt"
+
+// CPUMode определяет, как агент отчитывается о загрузке CPU.
+type CPUMode string
+
+// Режимы отчёта о загрузке CPU.
+const (
+	// CPUPerCore — gauge CPUutilization1..N для каждого ядра.
+	CPUPerCore CPUMode = "per-core"
+	// CPUTotal — один gauge CPUutilization с общей загрузкой всех ядер.
+	CPUTotal CPUMode = "total"
+)
+
+// ParseCPUMode проверяет имя режима отчёта о загрузке CPU.
+func ParseCPUMode(s string) (CPUMode, error) {
+	switch m := CPUMode(s); m {
+	case CPUPerCore, CPUTotal:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid cpu mode %q (want %q or %q)", s, CPUPerCore, CPUTotal)
+	}
+}
@@ -0,0 +1,20 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseCPUMode проверяет разбор имени режима отчёта о загрузке CPU.
+//
+// t — указатель на структуру теста.
+func TestParseCPUMode(t *testing.T) {
+	for _, s := range []string{"per-core", "total"} {
+		m, err := ParseCPUMode(s)
+		require.NoError(t, err)
+		require.Equal(t, CPUMode(s), m)
+	}
+	_, err := ParseCPUMode("average")
+	require.Error(t, err)
+}
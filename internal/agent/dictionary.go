@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"sync"
+
+	"github.com/RoGogDBD/metric-alerter/internal/compression"
+)
+
+// DictionaryRetrain — через сколько успешно отправленных батчей словарь обучается заново,
+// чтобы следовать за изменившимся набором метрик.
+const DictionaryRetrain = 100
+
+// Dictionary — словарь сжатия батчей на стороне агента.
+//
+// Словарь обучается на отправленном батче, регистрируется на сервере и только после
+// этого используется для сжатия (Activate). Если сервер словаря не знает (например,
+// после перезапуска), Reset возвращает отправку к gzip до следующей регистрации.
+//
+// Поля:
+//   - codec: сжатие активным словарём (nil — батчи сжимаются gzip)
+//   - id: идентификатор активного словаря
+//   - sends: число успешно отправленных батчей с последнего обучения
+//   - retrain: число батчей между обучениями
+//   - mu: мьютекс для конкурентного доступа воркеров
+type Dictionary struct {
+	codec   *compression.Codec
+	id      string
+	sends   int
+	retrain int
+	mu      sync.Mutex
+}
+
+// NewDictionary создаёт словарь без активного содержимого.
+//
+// retrain — число успешно отправленных батчей между обучениями.
+func NewDictionary(retrain int) *Dictionary {
+	return &Dictionary{retrain: max(retrain, 1)}
+}
+
+// Compress сжимает тело батча активным словарём.
+//
+// Возвращает false, если словарь ещё не зарегистрирован на сервере.
+func (d *Dictionary) Compress(body []byte) ([]byte, string, bool) {
+	d.mu.Lock()
+	codec, id := d.codec, d.id
+	d.mu.Unlock()
+	if codec == nil {
+		return nil, "", false
+	}
+	data, err := codec.Compress(body)
+	if err != nil {
+		return nil, "", false
+	}
+	return data, id, true
+}
+
+// Candidate учитывает успешную отправку батча body серверу, поддерживающему словари,
+// и возвращает новый словарь для регистрации, когда активного нет или пора обучить заново.
+func (d *Dictionary) Candidate(body []byte) ([]byte, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sends++
+	if d.codec != nil && d.sends < d.retrain {
+		return nil, false
+	}
+	d.sends = 0
+	dict := compression.Train(body)
+	if d.codec != nil && compression.ID(dict) == d.id {
+		return nil, false
+	}
+	return dict, true
+}
+
+// Activate делает словарь, зарегистрированный на сервере, активным.
+func (d *Dictionary) Activate(dict []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.codec = compression.NewCodec(dict)
+	d.id = compression.ID(dict)
+}
+
+// Reset отключает активный словарь, которого сервер не знает.
+func (d *Dictionary) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.codec = nil
+	d.id = ""
+	d.sends = 0
+}
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/compression"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDictionary проверяет обучение, активацию, периодическое переобучение и сброс словаря.
+func TestDictionary(t *testing.T) {
+	d := NewDictionary(2)
+	body := []byte(`[{"id":"Alloc","type":"gauge","value":1}]`)
+
+	_, _, ok := d.Compress(body)
+	require.False(t, ok, "no dictionary before registration")
+
+	dict, ok := d.Candidate(body)
+	require.True(t, ok)
+	d.Activate(dict)
+
+	data, id, ok := d.Compress(body)
+	require.True(t, ok)
+	require.Equal(t, compression.ID(dict), id)
+	plain, err := compression.NewCodec(dict).Decompress(data, 1<<10)
+	require.NoError(t, err)
+	require.Equal(t, body, plain)
+
+	// До переобучения новый словарь не предлагается; то же содержимое не регистрируется повторно.
+	_, ok = d.Candidate(body)
+	require.False(t, ok)
+	_, ok = d.Candidate(body)
+	require.False(t, ok)
+	_, ok = d.Candidate(body)
+	require.False(t, ok)
+	_, ok = d.Candidate([]byte(`[{"id":"HeapAlloc","type":"gauge","value":2}]`))
+	require.True(t, ok)
+
+	d.Reset()
+	_, _, ok = d.Compress(body)
+	require.False(t, ok)
+	_, ok = d.Candidate(body)
+	require.True(t, ok)
+}
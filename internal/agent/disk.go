@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// DiskMetrics собирает метрики дисков: заполненность каждой физической файловой системы
+// и накопленные с момента загрузки счётчики ввода-вывода по устройствам.
+//
+// Имена метрик содержат суффикс точки монтирования или устройства (см. metricSuffix),
+// например DiskUsed_root, DiskUsedPercent_var_lib, DiskReadBytes_sda.
+// Ошибки получения отдельных значений пропускаются: возвращается всё, что удалось собрать.
+func DiskMetrics() map[string]float64 {
+	metrics := make(map[string]float64)
+
+	if partitions, err := disk.Partitions(false); err == nil {
+		for _, p := range partitions {
+			usage, err := disk.Usage(p.Mountpoint)
+			if err != nil {
+				continue
+			}
+			suffix := metricSuffix(p.Mountpoint)
+			metrics["DiskTotal_"+suffix] = float64(usage.Total)
+			metrics["DiskUsed_"+suffix] = float64(usage.Used)
+			metrics["DiskFree_"+suffix] = float64(usage.Free)
+			metrics["DiskUsedPercent_"+suffix] = usage.UsedPercent
+		}
+	}
+
+	if counters, err := disk.IOCounters(); err == nil {
+		for name, io := range counters {
+			suffix := metricSuffix(name)
+			metrics["DiskReadBytes_"+suffix] = float64(io.ReadBytes)
+			metrics["DiskWriteBytes_"+suffix] = float64(io.WriteBytes)
+			metrics["DiskReadCount_"+suffix] = float64(io.ReadCount)
+			metrics["DiskWriteCount_"+suffix] = float64(io.WriteCount)
+		}
+	}
+
+	return metrics
+}
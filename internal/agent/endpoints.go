@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EndpointPolicy определяет порядок перебора серверов.
+type EndpointPolicy string
+
+// Политики выбора сервера.
+const (
+	// EndpointFailover всегда начинает с первого исправного сервера в списке.
+	EndpointFailover EndpointPolicy = "failover"
+	// EndpointRoundRobin начинает каждую отправку со следующего сервера по кругу.
+	EndpointRoundRobin EndpointPolicy = "round-robin"
+)
+
+// ParseEndpointPolicy проверяет имя политики выбора сервера.
+func ParseEndpointPolicy(s string) (EndpointPolicy, error) {
+	switch p := EndpointPolicy(s); p {
+	case EndpointFailover, EndpointRoundRobin:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid endpoint policy %q (want %q or %q)", s, EndpointFailover, EndpointRoundRobin)
+	}
+}
+
+// Endpoints — набор адресов серверов с учётом их доступности.
+//
+// Сервер, запрос к которому завершился ошибкой, помечается неисправным на время cooldown
+// и до его истечения перебирается только после исправных.
+//
+// Поля:
+//   - urls: базовые адреса серверов
+//   - policy: политика выбора сервера
+//   - cooldown: время, на которое неисправный сервер откладывается
+//   - downUntil: момент, до которого сервер считается неисправным
+//   - next: номер сервера, с которого начнётся следующий перебор (для round-robin)
+//   - now: функция получения текущего времени
+//   - mu: мьютекс для доступа к состоянию
+type Endpoints struct {
+	urls      []string
+	policy    EndpointPolicy
+	cooldown  time.Duration
+	downUntil []time.Time
+	next      int
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+// NewEndpoints создаёт набор серверов.
+//
+// urls — базовые адреса серверов в порядке приоритета.
+// policy — политика выбора сервера.
+// cooldown — время, на которое неисправный сервер откладывается.
+func NewEndpoints(urls []string, policy EndpointPolicy, cooldown time.Duration) *Endpoints {
+	return &Endpoints{
+		urls:      urls,
+		policy:    policy,
+		cooldown:  cooldown,
+		downUntil: make([]time.Time, len(urls)),
+		now:       time.Now,
+	}
+}
+
+// Order возвращает адреса в порядке перебора для очередной отправки:
+// сначала исправные согласно политике, затем неисправные (на случай, если отказали все).
+func (e *Endpoints) Order() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	start := 0
+	if e.policy == EndpointRoundRobin && len(e.urls) > 0 {
+		start = e.next % len(e.urls)
+		e.next++
+	}
+
+	now := e.now()
+	healthy := make([]string, 0, len(e.urls))
+	var down []string
+	for i := range e.urls {
+		idx := (start + i) % len(e.urls)
+		if now.Before(e.downUntil[idx]) {
+			down = append(down, e.urls[idx])
+			continue
+		}
+		healthy = append(healthy, e.urls[idx])
+	}
+	return append(healthy, down...)
+}
+
+// MarkFailed помечает сервер неисправным на время cooldown.
+func (e *Endpoints) MarkFailed(url string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, u := range e.urls {
+		if u == url {
+			e.downUntil[i] = e.now().Add(e.cooldown)
+		}
+	}
+}
+
+// MarkHealthy снимает с сервера отметку о неисправности.
+func (e *Endpoints) MarkHealthy(url string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, u := range e.urls {
+		if u == url {
+			e.downUntil[i] = time.Time{}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestEndpoints_Order проверяет порядок перебора серверов и отложенный повтор неисправных.
+//
+// t — указатель на структуру теста.
+func TestEndpoints_Order(t *testing.T) {
+	now := time.Unix(1000, 0)
+	clock := func() time.Time { return now }
+
+	t.Run("failover", func(t *testing.T) {
+		e := NewEndpoints([]string{"a", "b", "c"}, EndpointFailover, time.Minute)
+		e.now = clock
+		require.Equal(t, []string{"a", "b", "c"}, e.Order())
+
+		e.MarkFailed("a")
+		require.Equal(t, []string{"b", "c", "a"}, e.Order())
+
+		now = now.Add(2 * time.Minute)
+		require.Equal(t, []string{"a", "b", "c"}, e.Order())
+
+		e.MarkFailed("b")
+		e.MarkHealthy("b")
+		require.Equal(t, []string{"a", "b", "c"}, e.Order())
+	})
+
+	t.Run("round robin", func(t *testing.T) {
+		e := NewEndpoints([]string{"a", "b", "c"}, EndpointRoundRobin, time.Minute)
+		e.now = clock
+		require.Equal(t, []string{"a", "b", "c"}, e.Order())
+		require.Equal(t, []string{"b", "c", "a"}, e.Order())
+
+		e.MarkFailed("c")
+		require.Equal(t, []string{"a", "b", "c"}, e.Order())
+	})
+}
+
+// TestParseEndpointPolicy проверяет разбор имени политики выбора сервера.
+//
+// t — указатель на структуру теста.
+func TestParseEndpointPolicy(t *testing.T) {
+	for _, s := range []string{"failover", "round-robin"} {
+		p, err := ParseEndpointPolicy(s)
+		require.NoError(t, err)
+		require.Equal(t, EndpointPolicy(s), p)
+	}
+	_, err := ParseEndpointPolicy("random")
+	require.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// enrollPath — путь обработчика регистрации агентов на сервере.
+const enrollPath = "/api/v1/enroll"
+
+// Credentials — учётные данные агента, полученные при регистрации.
+//
+// Поля:
+//   - AgentID: идентификатор агента, передаётся в заголовке X-Agent-ID
+//   - Key: персональный ключ для HMAC-подписи запросов
+type Credentials struct {
+	AgentID string `json:"agent_id"`
+	Key     string `json:"key"`
+}
+
+// LoadCredentials читает учётные данные агента из файла path.
+//
+// Возвращает ошибку, удовлетворяющую os.IsNotExist, если агент ещё не зарегистрирован.
+func LoadCredentials(path string) (Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	var c Credentials
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse credentials file: %w", err)
+	}
+	if c.AgentID == "" || c.Key == "" {
+		return Credentials{}, errors.New("credentials file is incomplete")
+	}
+	return c, nil
+}
+
+// SaveCredentials атомарно записывает учётные данные агента в файл path с правами 0600.
+func SaveCredentials(path string, c Credentials) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Enroll обменивает одноразовый токен на учётные данные агента.
+//
+// client — HTTP-клиент для запроса.
+// baseURL — адрес сервера (например, "http://localhost:8080").
+// token — одноразовый токен регистрации.
+// hostname — имя хоста агента, сохраняемое в реестре сервера.
+// realIP — IP хоста агента для проверки доверенной подсети (пусто — заголовок X-Real-IP не передаётся).
+func Enroll(ctx context.Context, client *http.Client, baseURL, token, hostname, realIP string) (Credentials, error) {
+	body, err := json.Marshal(models.EnrollRequest{Token: token, Hostname: hostname})
+	if err != nil {
+		return Credentials{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+enrollPath, bytes.NewReader(body))
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if realIP != "" {
+		req.Header.Set("X-Real-IP", realIP)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("enrollment request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Credentials{}, fmt.Errorf("enrollment rejected: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var er models.EnrollResponse
+	if err := json.NewDecoder(resp.Body).Decode(&er); err != nil {
+		return Credentials{}, fmt.Errorf("invalid enrollment response: %w", err)
+	}
+	if er.AgentID == "" || er.Key == "" {
+		return Credentials{}, errors.New("invalid enrollment response: missing agent id or key")
+	}
+	return Credentials{AgentID: er.AgentID, Key: er.Key}, nil
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEnroll проверяет обмен токена на учётные данные и их сохранение.
+//
+// t — указатель на структуру теста.
+func TestEnroll(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.EnrollRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != enrollPath || req.Token != "tok" {
+			http.Error(w, "invalid enrollment token", http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(models.EnrollResponse{AgentID: "agent-1", Key: "secret"})
+	}))
+	defer srv.Close()
+
+	_, err := Enroll(context.Background(), srv.Client(), srv.URL, "bad", "host", "")
+	require.Error(t, err)
+
+	creds, err := Enroll(context.Background(), srv.Client(), srv.URL, "tok", "host", "")
+	require.NoError(t, err)
+	require.Equal(t, Credentials{AgentID: "agent-1", Key: "secret"}, creds)
+
+	path := filepath.Join(t.TempDir(), "creds.json")
+	_, err = LoadCredentials(path)
+	require.True(t, os.IsNotExist(err))
+
+	require.NoError(t, SaveCredentials(path, creds))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := LoadCredentials(path)
+	require.NoError(t, err)
+	require.Equal(t, creds, loaded)
+}
@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"net/http"
+	"sync"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrorCategory — стабильная категория ошибки отправки на стороне агента.
+//
+// Категории не зависят от текста ответа сервера и используются в логах и
+// в счётчиках самотелеметрии SendErrors_<категория>.
+type ErrorCategory string
+
+// Категории ошибок отправки.
+const (
+	// ErrCategoryAuth — сервер не принял учётные данные агента или запретил операцию.
+	ErrCategoryAuth ErrorCategory = "auth"
+	// ErrCategorySignature — сервер не смог проверить подпись HMAC или расшифровать тело.
+	ErrCategorySignature ErrorCategory = "signature"
+	// ErrCategoryRejected — сервер отклонил сами данные (некорректный JSON, метрика, маршрут).
+	ErrCategoryRejected ErrorCategory = "rejected"
+	// ErrCategoryRateLimited — запрос отклонён ограничением частоты.
+	ErrCategoryRateLimited ErrorCategory = "rate_limited"
+	// ErrCategoryServer — внутренняя ошибка сервера (хранилище, база данных).
+	ErrCategoryServer ErrorCategory = "server"
+	// ErrCategoryNetwork — сервер недоступен или не ответил вовремя.
+	ErrCategoryNetwork ErrorCategory = "network"
+)
+
+// CategorizeHTTP определяет категорию ошибки по статусу HTTP и коду models.ErrorResponse.
+//
+// Код из тела ответа приоритетнее статуса; apiCode может быть пустым, если сервер
+// вернул ответ в другом формате.
+func CategorizeHTTP(status int, apiCode string) ErrorCategory {
+	switch apiCode {
+	case models.ErrCodeUnauthorized, models.ErrCodeForbidden,
+		models.ErrCodeInvalidEnrollToken, models.ErrCodeEnrollmentDisabled:
+		return ErrCategoryAuth
+	case models.ErrCodeInvalidSignature, models.ErrCodeDecryptFailed:
+		return ErrCategorySignature
+	case models.ErrCodeBadRequest, models.ErrCodeInvalidJSON, models.ErrCodeEmptyBody, models.ErrCodeEmptyBatch, models.ErrCodeInvalidMetric,
+		models.ErrCodeUnknownMetricType, models.ErrCodeCounterOverflow, models.ErrCodeMetricNotFound,
+		models.ErrCodeNotFound, models.ErrCodeMethodNotAllowed, models.ErrCodeUnknownDictionary:
+		return ErrCategoryRejected
+	case models.ErrCodeStorageFailed, models.ErrCodeDatabaseUnavailable, models.ErrCodeInternal:
+		return ErrCategoryServer
+	}
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrCategoryAuth
+	case status == http.StatusTooManyRequests:
+		return ErrCategoryRateLimited
+	case status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout:
+		return ErrCategoryNetwork
+	case status >= http.StatusInternalServerError:
+		return ErrCategoryServer
+	default:
+		return ErrCategoryRejected
+	}
+}
+
+// CategorizeGRPC определяет категорию ошибки по коду статуса gRPC.
+func CategorizeGRPC(code codes.Code) ErrorCategory {
+	switch code {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return ErrCategoryAuth
+	case codes.ResourceExhausted:
+		return ErrCategoryRateLimited
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return ErrCategoryNetwork
+	case codes.InvalidArgument, codes.NotFound, codes.Unimplemented, codes.FailedPrecondition, codes.OutOfRange:
+		return ErrCategoryRejected
+	default:
+		return ErrCategoryServer
+	}
+}
+
+// ErrorStats — счётчики ошибок отправки по категориям.
+//
+// Поля:
+//   - counts: количество ошибок по категориям
+//   - mu: мьютекс для доступа к счётчикам
+type ErrorStats struct {
+	counts map[ErrorCategory]uint64
+	mu     sync.Mutex
+}
+
+// NewErrorStats создаёт пустые счётчики ошибок.
+func NewErrorStats() *ErrorStats {
+	return &ErrorStats{counts: make(map[ErrorCategory]uint64)}
+}
+
+// Record увеличивает счётчик категории c.
+func (s *ErrorStats) Record(c ErrorCategory) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[c]++
+}
+
+// Snapshot возвращает копию счётчиков; категории без ошибок в неё не попадают.
+func (s *ErrorStats) Snapshot() map[ErrorCategory]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[ErrorCategory]uint64, len(s.counts))
+	for c, n := range s.counts {
+		out[c] = n
+	}
+	return out
+}
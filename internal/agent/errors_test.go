@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"net/http"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+// TestCategorizeHTTP проверяет сопоставление ответов сервера категориям ошибок.
+func TestCategorizeHTTP(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		apiCode string
+		want    ErrorCategory
+	}{
+		{"invalid signature", http.StatusBadRequest, models.ErrCodeInvalidSignature, ErrCategorySignature},
+		{"decrypt failed", http.StatusBadRequest, models.ErrCodeDecryptFailed, ErrCategorySignature},
+		{"forbidden subnet", http.StatusForbidden, models.ErrCodeForbidden, ErrCategoryAuth},
+		{"invalid metric", http.StatusBadRequest, models.ErrCodeInvalidMetric, ErrCategoryRejected},
+		{"storage failed", http.StatusInternalServerError, models.ErrCodeStorageFailed, ErrCategoryServer},
+		{"plain 401", http.StatusUnauthorized, "", ErrCategoryAuth},
+		{"plain 429", http.StatusTooManyRequests, "", ErrCategoryRateLimited},
+		{"proxy 503", http.StatusServiceUnavailable, "", ErrCategoryNetwork},
+		{"plain 500", http.StatusInternalServerError, "", ErrCategoryServer},
+		{"plain 400", http.StatusBadRequest, "", ErrCategoryRejected},
+		{"unknown code falls back to status", http.StatusInternalServerError, "something_new", ErrCategoryServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, CategorizeHTTP(tt.status, tt.apiCode))
+		})
+	}
+}
+
+// TestCategorizeGRPC проверяет сопоставление кодов gRPC категориям ошибок.
+func TestCategorizeGRPC(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want ErrorCategory
+	}{
+		{codes.Unauthenticated, ErrCategoryAuth},
+		{codes.PermissionDenied, ErrCategoryAuth},
+		{codes.ResourceExhausted, ErrCategoryRateLimited},
+		{codes.Unavailable, ErrCategoryNetwork},
+		{codes.InvalidArgument, ErrCategoryRejected},
+		{codes.Internal, ErrCategoryServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			require.Equal(t, tt.want, CategorizeGRPC(tt.code))
+		})
+	}
+}
+
+// TestErrorStats проверяет накопление счётчиков по категориям.
+func TestErrorStats(t *testing.T) {
+	s := NewErrorStats()
+	require.Empty(t, s.Snapshot())
+
+	s.Record(ErrCategorySignature)
+	s.Record(ErrCategorySignature)
+	s.Record(ErrCategoryNetwork)
+
+	snap := s.Snapshot()
+	require.Equal(t, map[ErrorCategory]uint64{ErrCategorySignature: 2, ErrCategoryNetwork: 1}, snap)
+
+	snap[ErrCategoryAuth] = 5
+	require.NotContains(t, s.Snapshot(), ErrCategoryAuth)
+}
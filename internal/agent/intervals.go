@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Группы сборщиков, помимо необязательных (CollectorDisk, CollectorNet, CollectorSelf),
+// для которых можно задать собственный период опроса.
+const (
+	// GroupRuntime — метрики runtime Go, PollCount и RandomValue.
+	GroupRuntime = "runtime"
+	// GroupSystem — память и загрузка CPU хоста.
+	GroupSystem = "system"
+)
+
+// CollectIntervals — периоды опроса по группам сборщиков.
+type CollectIntervals map[string]time.Duration
+
+// ParseCollectIntervals разбирает периоды опроса групп в формате "group=duration,...",
+// например "runtime=2s,disk=60s".
+//
+// Допустимые группы — runtime, system и необязательные сборщики (disk, net, self).
+// Пустая строка означает, что все группы опрашиваются с общим интервалом.
+func ParseCollectIntervals(s string) (CollectIntervals, error) {
+	ci := make(CollectIntervals)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		group, value, ok := strings.Cut(item, "=")
+		group = strings.TrimSpace(group)
+		if !ok {
+			return nil, fmt.Errorf("invalid collect interval %q (want group=duration)", item)
+		}
+		if group != GroupRuntime && group != GroupSystem && !isKnownCollector(group) {
+			return nil, fmt.Errorf("unknown collector group %q (want one of %s, %s, %s)",
+				group, GroupRuntime, GroupSystem, strings.Join(knownCollectors, ", "))
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid interval for collector group %q: %q", group, value)
+		}
+		ci[group] = d
+	}
+	return ci, nil
+}
+
+// Get возвращает период опроса группы group или def, если он не задан.
+func (ci CollectIntervals) Get(group string, def time.Duration) time.Duration {
+	if d, ok := ci[group]; ok {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseCollectIntervals проверяет разбор периодов опроса по группам сборщиков.
+func TestParseCollectIntervals(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    CollectIntervals
+		wantErr bool
+	}{
+		{"empty", "", CollectIntervals{}, false},
+		{"several groups", "runtime=2s, disk=1m,system=10s", CollectIntervals{"runtime": 2 * time.Second, "disk": time.Minute, "system": 10 * time.Second}, false},
+		{"unknown group", "gpu=1s", nil, true},
+		{"missing duration", "runtime", nil, true},
+		{"invalid duration", "runtime=fast", nil, true},
+		{"zero duration", "net=0s", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCollectIntervals(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestCollectIntervals_Get проверяет выбор общего интервала для незаданных групп.
+func TestCollectIntervals_Get(t *testing.T) {
+	ci := CollectIntervals{GroupRuntime: time.Second}
+	require.Equal(t, time.Second, ci.Get(GroupRuntime, 5*time.Second))
+	require.Equal(t, 5*time.Second, ci.Get(CollectorDisk, 5*time.Second))
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket — ограничитель частоты запросов «маркерная корзина», общий для всех воркеров.
+//
+// Корзина пополняется со скоростью rate маркеров в секунду и вмещает не больше burst;
+// каждый запрос забирает один маркер или ждёт его появления.
+//
+// Поля:
+//   - rate: скорость пополнения (маркеров в секунду)
+//   - burst: ёмкость корзины
+//   - tokens: текущее число маркеров
+//   - last: время последнего пополнения
+//   - now: функция получения текущего времени
+//   - mu: мьютекс для доступа к состоянию
+type TokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+// NewTokenBucket создаёт заполненную корзину: не больше rps запросов в секунду
+// с допустимым всплеском до rps запросов.
+func NewTokenBucket(rps int) *TokenBucket {
+	rps = max(rps, 1)
+	return &TokenBucket{
+		rate:   float64(rps),
+		burst:  float64(rps),
+		tokens: float64(rps),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// reserve забирает маркер и возвращает время, которое нужно подождать до его появления.
+//
+// Маркер резервируется сразу, поэтому ожидающие воркеры получают маркеры по очереди,
+// а не все одновременно.
+func (b *TokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel возвращает маркер, зарезервированный запросом, который так и не был выполнен.
+func (b *TokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+// Wait ждёт маркер для одного запроса.
+//
+// Возвращает ошибку контекста, если он завершился раньше, чем появился маркер.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	delay := b.reserve()
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
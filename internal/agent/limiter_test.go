@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTokenBucket_Reserve проверяет всплеск до ёмкости корзины и равномерную выдачу после него.
+//
+// t — указатель на структуру теста.
+func TestTokenBucket_Reserve(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewTokenBucket(2)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	require.Zero(t, b.reserve())
+	require.Zero(t, b.reserve())
+	require.Equal(t, 500*time.Millisecond, b.reserve())
+	require.Equal(t, time.Second, b.reserve())
+
+	// За две секунды долг погашен и корзина снова заполнена.
+	now = now.Add(2 * time.Second)
+	require.Zero(t, b.reserve())
+	require.Zero(t, b.reserve())
+	require.Equal(t, 500*time.Millisecond, b.reserve())
+
+	// Простой не накапливает маркеров больше ёмкости корзины.
+	now = now.Add(time.Hour)
+	require.Zero(t, b.reserve())
+	require.Zero(t, b.reserve())
+	require.Equal(t, 500*time.Millisecond, b.reserve())
+}
+
+// TestTokenBucket_WaitContext проверяет, что отменённое ожидание возвращает маркер.
+//
+// t — указатель на структуру теста.
+func TestTokenBucket_WaitContext(t *testing.T) {
+	b := NewTokenBucket(1)
+	require.NoError(t, b.Wait(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, b.Wait(ctx), context.Canceled)
+
+	b.mu.Lock()
+	tokens := b.tokens
+	b.mu.Unlock()
+	require.InDelta(t, 0, tokens, 0.1)
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// NetFilter отбирает сетевые интерфейсы по шаблонам имён (синтаксис path.Match, например "eth*").
+//
+// Интерфейс учитывается, если он подходит хотя бы под один шаблон Include (пустой Include
+// пропускает все интерфейсы) и не подходит ни под один шаблон Exclude.
+//
+// Поля:
+//   - Include: шаблоны учитываемых интерфейсов
+//   - Exclude: шаблоны исключаемых интерфейсов
+type NetFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// ParseNetFilter создаёт NetFilter из списков шаблонов через запятую и проверяет их синтаксис.
+func ParseNetFilter(include, exclude string) (NetFilter, error) {
+	f := NetFilter{Include: splitPatterns(include), Exclude: splitPatterns(exclude)}
+	if err := f.Validate(); err != nil {
+		return NetFilter{}, err
+	}
+	return f, nil
+}
+
+// splitPatterns разбивает список шаблонов через запятую, пропуская пустые элементы.
+func splitPatterns(s string) []string {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// Validate проверяет синтаксис шаблонов.
+func (f NetFilter) Validate() error {
+	for _, p := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid interface pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Match сообщает, учитывается ли интерфейс с именем name.
+func (f NetFilter) Match(name string) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, name) {
+		return false
+	}
+	return !matchAny(f.Exclude, name)
+}
+
+// matchAny сообщает, подходит ли name хотя бы под один шаблон.
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// NetworkMetrics собирает накопленные счётчики сетевых интерфейсов, отобранных filter.
+//
+// Имена метрик содержат суффикс интерфейса (см. metricSuffix), например NetBytesSent_eth0.
+// При ошибке получения счётчиков возвращает пустой набор.
+func NetworkMetrics(filter NetFilter) map[string]float64 {
+	metrics := make(map[string]float64)
+
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return metrics
+	}
+	for _, c := range counters {
+		if !filter.Match(c.Name) {
+			continue
+		}
+		suffix := metricSuffix(c.Name)
+		metrics["NetBytesSent_"+suffix] = float64(c.BytesSent)
+		metrics["NetBytesRecv_"+suffix] = float64(c.BytesRecv)
+		metrics["NetPacketsSent_"+suffix] = float64(c.PacketsSent)
+		metrics["NetPacketsRecv_"+suffix] = float64(c.PacketsRecv)
+		metrics["NetErrIn_"+suffix] = float64(c.Errin)
+		metrics["NetErrOut_"+suffix] = float64(c.Errout)
+	}
+	return metrics
+}
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestNetFilter_Match проверяет отбор интерфейсов по шаблонам include/exclude.
+func TestNetFilter_Match(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter NetFilter
+		iface  string
+		want   bool
+	}{
+		{name: "no patterns", iface: "eth0", want: true},
+		{name: "included", filter: NetFilter{Include: []string{"eth*", "en*"}}, iface: "enp3s0", want: true},
+		{name: "not included", filter: NetFilter{Include: []string{"eth*"}}, iface: "wlan0", want: false},
+		{name: "excluded", filter: NetFilter{Exclude: []string{"lo", "docker*"}}, iface: "docker0", want: false},
+		{name: "exclude wins", filter: NetFilter{Include: []string{"*"}, Exclude: []string{"lo"}}, iface: "lo", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.filter.Match(tt.iface))
+		})
+	}
+}
+
+// TestParseNetFilter проверяет разбор списков шаблонов и отклонение некорректных шаблонов.
+func TestParseNetFilter(t *testing.T) {
+	f, err := ParseNetFilter(" eth*, en* ,", "lo")
+	require.NoError(t, err)
+	require.Equal(t, NetFilter{Include: []string{"eth*", "en*"}, Exclude: []string{"lo"}}, f)
+
+	f, err = ParseNetFilter("", "")
+	require.NoError(t, err)
+	require.Empty(t, f.Include)
+	require.Empty(t, f.Exclude)
+
+	_, err = ParseNetFilter("", "eth[")
+	require.Error(t, err)
+}
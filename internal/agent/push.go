@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// PushPath — путь локального API приёма метрик агентом.
+const PushPath = "/push"
+
+// pushMaxBody — максимальный размер тела запроса к локальному API.
+const pushMaxBody = 1 << 20
+
+// NewPushHandler возвращает обработчик локального API POST /push.
+//
+// Тело — метрика или массив метрик в формате models.Metrics. Счётчики прибавляются,
+// gauge перезаписываются; метрики отправляются на сервер вместе с остальными.
+// Запрос с хотя бы одной некорректной метрикой отклоняется целиком с 400 и ошибкой
+// в формате models.ErrorResponse; при успехе возвращается 204.
+func NewPushHandler(agg *Aggregator) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+PushPath, func(w http.ResponseWriter, r *http.Request) {
+		metrics, err := decodePush(http.MaxBytesReader(w, r.Body, pushMaxBody))
+		if err != nil {
+			writePushError(w, models.ErrCodeInvalidJSON, err.Error())
+			return
+		}
+		deltas, err := validatePush(metrics)
+		if err != nil {
+			code := models.ErrCodeInvalidMetric
+			if errors.Is(err, errInt64Overflow) {
+				code = models.ErrCodeCounterOverflow
+			}
+			writePushError(w, code, err.Error())
+			return
+		}
+		// Счётчики применяются первыми и атомарно: при переполнении относительно уже
+		// накопленных значений запрос отклоняется без изменений в агрегаторе.
+		if err := agg.AddCounters(deltas); err != nil {
+			writePushError(w, models.ErrCodeCounterOverflow, err.Error())
+			return
+		}
+		for _, m := range metrics {
+			if m.MType == models.Gauge {
+				agg.SetGauge(m.ID, *m.Value)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// decodePush разбирает тело запроса: одну метрику или массив метрик.
+func decodePush(body io.Reader) ([]models.Metrics, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("empty body")
+	}
+	if data[0] != '[' {
+		var m models.Metrics
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("invalid json: %w", err)
+		}
+		return []models.Metrics{m}, nil
+	}
+	var metrics []models.Metrics
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	if len(metrics) == 0 {
+		return nil, errors.New("empty batch")
+	}
+	return metrics, nil
+}
+
+// validatePush проверяет все метрики запроса до их учёта в агрегаторе.
+//
+// Возвращает сумму приращений каждого счётчика запроса; если сумма приращений одного
+// счётчика выходит за пределы int64, возвращается ошибка errInt64Overflow.
+func validatePush(metrics []models.Metrics) (map[string]int64, error) {
+	deltas := make(map[string]int64)
+	for i, m := range metrics {
+		switch {
+		case m.ID == "":
+			return nil, fmt.Errorf("metric %d: metric id is required", i)
+		case m.MType == models.Gauge && m.Value == nil:
+			return nil, fmt.Errorf("metric %s: missing value for gauge", m.ID)
+		case m.MType == models.Counter && m.Delta == nil:
+			return nil, fmt.Errorf("metric %s: missing delta for counter", m.ID)
+		case m.MType != models.Gauge && m.MType != models.Counter:
+			return nil, fmt.Errorf("metric %s: unknown metric type %q", m.ID, m.MType)
+		}
+		if m.MType == models.Counter {
+			sum, err := addInt64(deltas[m.ID], *m.Delta)
+			if err != nil {
+				return nil, fmt.Errorf("counter %s: %w", m.ID, err)
+			}
+			deltas[m.ID] = sum
+		}
+	}
+	return deltas, nil
+}
+
+// writePushError отвечает 400 с ошибкой в формате models.ErrorResponse.
+func writePushError(w http.ResponseWriter, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(models.ErrorResponse{Code: code, Message: message})
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPushHandler проверяет приём метрик через локальный API и отклонение некорректных запросов.
+func TestPushHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		wantCode int
+		wantErr  string
+		want     map[string]float64
+	}{
+		{"single gauge", http.MethodPost, `{"id":"temp","type":"gauge","value":21.5}`, http.StatusNoContent, "", map[string]float64{"temp": 21.5}},
+		{"batch", http.MethodPost, `[{"id":"hits","type":"counter","delta":2},{"id":"hits","type":"counter","delta":3}]`, http.StatusNoContent, "", map[string]float64{"hits": 5}},
+		{"empty body", http.MethodPost, ``, http.StatusBadRequest, models.ErrCodeInvalidJSON, map[string]float64{}},
+		{"empty batch", http.MethodPost, `[]`, http.StatusBadRequest, models.ErrCodeInvalidJSON, map[string]float64{}},
+		{"invalid json", http.MethodPost, `{`, http.StatusBadRequest, models.ErrCodeInvalidJSON, map[string]float64{}},
+		{"empty id rejects whole batch", http.MethodPost, `[{"id":"temp","type":"gauge","value":1},{"id":"","type":"gauge","value":1}]`, http.StatusBadRequest, models.ErrCodeInvalidMetric, map[string]float64{}},
+		{"missing delta", http.MethodPost, `{"id":"hits","type":"counter"}`, http.StatusBadRequest, models.ErrCodeInvalidMetric, map[string]float64{}},
+		{"unknown type", http.MethodPost, `{"id":"x","type":"histogram"}`, http.StatusBadRequest, models.ErrCodeInvalidMetric, map[string]float64{}},
+		{"overflow in payload rejects whole batch", http.MethodPost, `[{"id":"temp","type":"gauge","value":1},{"id":"hits","type":"counter","delta":9223372036854775807},{"id":"hits","type":"counter","delta":1}]`, http.StatusBadRequest, models.ErrCodeCounterOverflow, map[string]float64{}},
+		{"get not allowed", http.MethodGet, ``, http.StatusMethodNotAllowed, "", map[string]float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg := NewAggregator()
+			w := httptest.NewRecorder()
+			NewPushHandler(agg).ServeHTTP(w, httptest.NewRequest(tt.method, PushPath, bytes.NewBufferString(tt.body)))
+
+			require.Equal(t, tt.wantCode, w.Code)
+			if tt.wantErr != "" {
+				var resp models.ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				require.Equal(t, tt.wantErr, resp.Code)
+			}
+			require.Equal(t, tt.want, flushMap(agg.Flush()))
+		})
+	}
+}
+
+// TestPushHandler_OverflowNoPartialApply проверяет, что при переполнении счётчика относительно
+// уже накопленного значения ни одна метрика запроса не учитывается.
+func TestPushHandler_OverflowNoPartialApply(t *testing.T) {
+	agg := NewAggregator()
+	require.NoError(t, agg.AddCounter("hits", math.MaxInt64))
+
+	body := `[{"id":"temp","type":"gauge","value":1},{"id":"other","type":"counter","delta":1},{"id":"hits","type":"counter","delta":1}]`
+	w := httptest.NewRecorder()
+	NewPushHandler(agg).ServeHTTP(w, httptest.NewRequest(http.MethodPost, PushPath, bytes.NewBufferString(body)))
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var resp models.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, models.ErrCodeCounterOverflow, resp.Code)
+	require.Equal(t, map[string]float64{"hits": math.MaxInt64}, flushMap(agg.Flush()))
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// QueuePolicy определяет поведение очереди отправки при переполнении.
+type QueuePolicy string
+
+// Политики переполнения очереди.
+const (
+	// QueueDropOldest вытесняет самый старый батч, освобождая место для нового.
+	QueueDropOldest QueuePolicy = "drop-oldest"
+	// QueueBlock ждёт освобождения места не дольше таймаута и отбрасывает новый батч.
+	QueueBlock QueuePolicy = "block"
+)
+
+// ParseQueuePolicy проверяет имя политики переполнения очереди.
+func ParseQueuePolicy(s string) (QueuePolicy, error) {
+	switch p := QueuePolicy(s); p {
+	case QueueDropOldest, QueueBlock:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid queue policy %q (want %q or %q)", s, QueueDropOldest, QueueBlock)
+	}
+}
+
+// Queue — ограниченная очередь батчей метрик между циклом отправки и воркерами.
+//
+// Медленный сервер не блокирует цикл отправки дольше таймаута: при переполнении
+// батчи отбрасываются согласно политике, а их количество учитывается в Dropped.
+//
+// Поля:
+//   - ch: буферизованный канал батчей
+//   - policy: политика переполнения
+//   - timeout: время ожидания места для политики QueueBlock
+//   - dropped: количество отброшенных батчей
+type Queue struct {
+	ch      chan []models.Metrics
+	policy  QueuePolicy
+	timeout time.Duration
+	dropped atomic.Uint64
+}
+
+// NewQueue создаёт очередь ёмкостью size батчей.
+//
+// policy — политика переполнения.
+// timeout — время ожидания места для политики QueueBlock.
+func NewQueue(size int, policy QueuePolicy, timeout time.Duration) *Queue {
+	if size < 1 {
+		size = 1
+	}
+	return &Queue{ch: make(chan []models.Metrics, size), policy: policy, timeout: timeout}
+}
+
+// Push ставит батч в очередь согласно политике переполнения.
+//
+// Возвращает false, если новый батч был отброшен.
+func (q *Queue) Push(batch []models.Metrics) bool {
+	if q.policy == QueueDropOldest {
+		for {
+			select {
+			case q.ch <- batch:
+				return true
+			default:
+			}
+			// Очередь полна: вытесняем самый старый батч. Если его уже забрал воркер,
+			// место освободилось и следующая попытка записи пройдёт.
+			select {
+			case <-q.ch:
+				q.dropped.Add(1)
+			default:
+			}
+		}
+	}
+	return q.PushTimeout(batch, q.timeout)
+}
+
+// PushTimeout ставит батч в очередь, ожидая места не дольше timeout.
+//
+// Возвращает false, если место не освободилось и батч был отброшен.
+func (q *Queue) PushTimeout(batch []models.Metrics, timeout time.Duration) bool {
+	select {
+	case q.ch <- batch:
+		return true
+	default:
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case q.ch <- batch:
+		return true
+	case <-timer.C:
+		q.dropped.Add(1)
+		return false
+	}
+}
+
+// C возвращает канал, из которого воркеры забирают батчи.
+func (q *Queue) C() <-chan []models.Metrics {
+	return q.ch
+}
+
+// Close закрывает очередь; воркеры дочитывают оставшиеся батчи и завершаются.
+func (q *Queue) Close() {
+	close(q.ch)
+}
+
+// Len возвращает количество батчей в очереди.
+func (q *Queue) Len() int {
+	return len(q.ch)
+}
+
+// Dropped возвращает количество отброшенных батчей.
+func (q *Queue) Dropped() uint64 {
+	return q.dropped.Load()
+}
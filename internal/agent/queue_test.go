@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestQueue_Push проверяет поведение очереди при переполнении для каждой политики.
+//
+// t — указатель на структуру теста.
+func TestQueue_Push(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      QueuePolicy
+		wantPushed  []bool
+		wantQueued  []string
+		wantDropped uint64
+	}{
+		{
+			name:        "drop oldest",
+			policy:      QueueDropOldest,
+			wantPushed:  []bool{true, true, true},
+			wantQueued:  []string{"b", "c"},
+			wantDropped: 1,
+		},
+		{
+			name:        "block with timeout",
+			policy:      QueueBlock,
+			wantPushed:  []bool{true, true, false},
+			wantQueued:  []string{"a", "b"},
+			wantDropped: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQueue(2, tt.policy, 10*time.Millisecond)
+			var pushed []bool
+			for _, id := range []string{"a", "b", "c"} {
+				pushed = append(pushed, q.Push(gaugeBatch(id, 1)))
+			}
+			require.Equal(t, tt.wantPushed, pushed)
+			require.Equal(t, tt.wantDropped, q.Dropped())
+
+			q.Close()
+			var queued []string
+			for batch := range q.C() {
+				queued = append(queued, batch[0].ID)
+			}
+			require.Equal(t, tt.wantQueued, queued)
+		})
+	}
+}
+
+// TestQueue_BlockWaitsForSpace проверяет, что политика block дожидается освобождения места.
+//
+// t — указатель на структуру теста.
+func TestQueue_BlockWaitsForSpace(t *testing.T) {
+	q := NewQueue(1, QueueBlock, time.Second)
+	require.True(t, q.Push(gaugeBatch("a", 1)))
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		<-q.C()
+	}()
+	require.True(t, q.Push(gaugeBatch("b", 1)))
+	require.Zero(t, q.Dropped())
+}
+
+// TestParseQueuePolicy проверяет разбор имени политики переполнения.
+//
+// t — указатель на структуру теста.
+func TestParseQueuePolicy(t *testing.T) {
+	for _, s := range []string{"drop-oldest", "block"} {
+		p, err := ParseQueuePolicy(s)
+		require.NoError(t, err)
+		require.Equal(t, QueuePolicy(s), p)
+	}
+	_, err := ParseQueuePolicy("drop-newest")
+	require.Error(t, err)
+}
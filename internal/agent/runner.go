@@ -0,0 +1,671 @@
+package agent
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/proto"
+	"github.com/go-resty/resty/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+type (
+	// Config — конфигурация агента.
+	Config struct {
+		Servers          []string           // Адреса HTTP-серверов (host:port, http://host:port или https://host:port) в порядке предпочтения.
+		PollInterval     int                // Интервал опроса метрик (сек).
+		ReportInterval   int                // Интервал отправки метрик (сек).
+		RateLimit        int                // Ограничение на количество параллельных отправок.
+		MaxRPS           int                // Максимальное число запросов к серверу в секунду от всех воркеров (0 — без ограничения).
+		Key              string             // Ключ для подписи запросов.
+		CryptoKey        *rsa.PublicKey     // Публичный ключ для асимметричного шифрования.
+		SignKey          ed25519.PrivateKey // Закрытый ключ Ed25519 для подписи запросов (nil — не подписываются).
+		TLS              config.TLSConfig   // Политика TLS клиента для адресов https:// (версия, наборы шифров, кривые).
+		TLSCA            string             // Файл с доверенными сертификатами CA (PEM) для адресов https:// (пусто — системные).
+		GRPCAddress      string             // Адрес gRPC-сервера (с префиксом https:// — соединение по TLS).
+		SpoolDir         string             // Каталог дискового спула (пусто — спул отключён).
+		SpoolMaxSize     int                // Максимальный размер спула в байтах.
+		SpoolMaxAge      int                // Максимальный возраст батча в спуле (сек).
+		ShutdownTimeout  int                // Время ожидания отправки последних батчей при завершении (сек).
+		EnrollToken      string             // Одноразовый токен регистрации агента.
+		CredentialsFile  string             // Файл учётных данных, полученных при регистрации.
+		QueueSize        int                // Ёмкость очереди отправки (в батчах).
+		QueuePolicy      string             // Политика переполнения очереди: drop-oldest или block.
+		QueueTimeout     int                // Время ожидания места в очереди для политики block (сек).
+		APIKey           string             // API-ключ с ролью writer (пусто — не передаётся).
+		MaxBatchSize     int                // Максимальное число метрик в одном HTTP-запросе (0 — без ограничения).
+		EndpointPolicy   string             // Порядок перебора серверов: failover или round-robin.
+		EndpointCooldown int                // Время, на которое откладывается недоступный сервер (сек).
+		Collect          string             // Необязательные сборщики метрик через запятую (например, "disk,net").
+		NetInclude       string             // Шаблоны учитываемых сетевых интерфейсов через запятую.
+		NetExclude       string             // Шаблоны исключаемых сетевых интерфейсов через запятую.
+		StateFile        string             // Файл состояния агента между перезапусками (пусто — не сохраняется).
+		StatsDAddress    string             // UDP-адрес приёма метрик StatsD (пусто — приём отключён).
+		PushAddress      string             // Адрес локального HTTP API POST /push (пусто — отключён).
+		CollectIntervals string             // Периоды опроса групп сборщиков, например "runtime=2s,disk=60s" (пусто — PollInterval).
+		SendChangedOnly  bool               // Не отправлять gauge, не изменившиеся с последней успешной отправки.
+		CPUMode          string             // Режим отчёта о загрузке CPU: per-core или total.
+		BreakerThreshold int                // Число ошибок отправки подряд до размыкания цепи (0 — размыкатель отключён).
+		BreakerCooldown  int                // Время между пробными отправками при разомкнутой цепи (сек).
+		CompressionDict  bool               // Сжимать батчи словарём, обученным на предыдущих батчах, если сервер это поддерживает.
+		HTTP2            bool               // Отправлять по HTTP/2 (h2c для http://): параллельные батчи идут по одному соединению.
+
+		Tracing config.TracingConfig // Экспорт трассировки OpenTelemetry (пустой Endpoint — отключён).
+	}
+
+	// Runner — агент в сборе: конфиг, сборщик, отправитель и очередь заданий.
+	//
+	// Создаётся через NewRunner и запускается методом Run.
+	Runner struct {
+		Config    Config         // Конфигурация агента.
+		Collector *Collector     // Сборщик метрик.
+		Sender    Sender         // Отправитель метрик.
+		jobQueue  *Queue         // Очередь заданий для отправки метрик.
+		errStats  *ErrorStats    // Счётчики ошибок отправки по категориям.
+		spool     *Spool         // Дисковый спул неотправленных батчей (nil — отключён).
+		external  *Aggregator    // Метрики StatsD и локального push API (nil — оба отключены).
+		changes   *ChangeFilter  // Фильтр неизменившихся gauge (nil — отправляются все метрики).
+		lastSend  atomic.Int64   // Время последней успешной отправки (Unix-время в наносекундах, 0 — не было).
+		wg        sync.WaitGroup // Группа ожидания для воркеров.
+	}
+)
+
+// setDefaults подставляет значения по умолчанию вместо незаданных полей конфигурации.
+func (c *Config) setDefaults() {
+	if c.PollInterval <= 0 {
+		c.PollInterval = 2
+	}
+	if c.ReportInterval <= 0 {
+		c.ReportInterval = 10
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = config.DefaultQueueSize
+	}
+	if c.QueuePolicy == "" {
+		c.QueuePolicy = config.DefaultQueuePolicy
+	}
+	if c.EndpointPolicy == "" {
+		c.EndpointPolicy = config.DefaultEndpointPolicy
+	}
+	if c.CPUMode == "" {
+		c.CPUMode = config.DefaultCPUMode
+	}
+	if c.ShutdownTimeout <= 0 {
+		c.ShutdownTimeout = config.DefaultShutdownTimeout
+	}
+}
+
+// NewRunner проверяет конфигурацию и собирает агента: сборщик метрик, отправитель
+// (HTTP или gRPC), дисковый спул и сохранённое состояние.
+//
+// Незаданные поля cfg заменяются значениями по умолчанию.
+// Возвращает ошибку, если конфигурация некорректна или отправитель не удалось создать.
+func NewRunner(cfg Config) (*Runner, error) {
+	cfg.setDefaults()
+	if _, err := ParseQueuePolicy(cfg.QueuePolicy); err != nil {
+		return nil, err
+	}
+	if _, err := ParseEndpointPolicy(cfg.EndpointPolicy); err != nil {
+		return nil, err
+	}
+	if cfg.GRPCAddress == "" && len(cfg.Servers) == 0 {
+		return nil, errors.New("no server address configured")
+	}
+
+	collector, err := NewCollector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := &Runner{
+		Config:    cfg,
+		Collector: collector,
+		errStats:  NewErrorStats(),
+	}
+	if r.Sender, err = newSender(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.SpoolDir != "" {
+		spool, err := NewSpool(
+			cfg.SpoolDir,
+			int64(cfg.SpoolMaxSize),
+			time.Duration(cfg.SpoolMaxAge)*time.Second,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open spool: %w", err)
+		}
+		r.Sender = &SpoolingSender{Sender: r.Sender, Spool: spool}
+		r.spool = spool
+		log.Printf("Spool enabled: %s (%d batches pending)", cfg.SpoolDir, spool.Len())
+	}
+
+	if err := restoreState(r); err != nil {
+		log.Printf("Failed to restore agent state, starting from scratch: %v", err)
+	}
+
+	if cfg.SendChangedOnly {
+		r.changes = NewChangeFilter()
+	}
+	if cfg.StatsDAddress != "" || cfg.PushAddress != "" {
+		r.external = NewAggregator()
+	}
+	return r, nil
+}
+
+// clientTLSConfig создаёт конфигурацию TLS клиента по политике cfg.TLS и набору CA из cfg.TLSCA.
+func clientTLSConfig(cfg Config) (*tls.Config, error) {
+	tlsConfig, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TLSCA != "" {
+		data, err := os.ReadFile(cfg.TLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls ca bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in tls ca bundle %s", cfg.TLSCA)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// newSender создаёт отправителя метрик: через gRPC, если задан GRPCAddress, иначе через HTTP
+// с переключением между серверами Servers. При необходимости регистрирует агента на сервере.
+func newSender(cfg Config) (Sender, error) {
+	// Ограничение частоты запросов общее для всех воркеров, в отличие от RateLimit,
+	// который задаёт только их число.
+	var limiter *TokenBucket
+	if cfg.MaxRPS > 0 {
+		limiter = NewTokenBucket(cfg.MaxRPS)
+		log.Printf("Outgoing requests limited to %d per second", cfg.MaxRPS)
+	}
+
+	if cfg.GRPCAddress != "" {
+		target, secure := strings.CutPrefix(cfg.GRPCAddress, "https://")
+		creds := insecure.NewCredentials()
+		if secure {
+			tlsConfig, err := clientTLSConfig(cfg)
+			if err != nil {
+				return nil, err
+			}
+			creds = credentials.NewTLS(tlsConfig)
+		}
+		conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to gRPC server: %w", err)
+		}
+		log.Printf("gRPC sender enabled: %s", cfg.GRPCAddress)
+		return &GRPCSender{
+			Client:  proto.NewMetricsClient(conn),
+			Conn:    conn,
+			RealIP:  resolveHostIP(),
+			APIKey:  cfg.APIKey,
+			SignKey: cfg.SignKey,
+			Limiter: limiter,
+		}, nil
+	}
+
+	baseURLs := make([]string, len(cfg.Servers))
+	for i, s := range cfg.Servers {
+		if !strings.Contains(s, "://") {
+			s = "http://" + s
+		}
+		baseURLs[i] = s
+	}
+	restyClient := resty.New().
+		SetBaseURL(baseURLs[0]).
+		SetTimeout(5 * time.Second).
+		SetRetryWaitTime(500 * time.Millisecond)
+	if !cfg.TLS.IsZero() || cfg.TLSCA != "" {
+		tlsConfig, err := clientTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		restyClient.SetTLSClientConfig(tlsConfig)
+	}
+	if cfg.HTTP2 {
+		transport, err := restyClient.Transport()
+		if err != nil {
+			return nil, err
+		}
+		// Без HTTP1 транспорт не откатывается на HTTP/1.1: для http:// запросы сразу идут
+		// по h2c, для https:// протокол h2 согласуется через ALPN.
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP2(true)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+		log.Printf("HTTP/2 sender enabled")
+	}
+
+	sender := &RestySender{
+		Client:       restyClient,
+		Key:          cfg.Key,
+		CryptoKey:    cfg.CryptoKey,
+		SignKey:      cfg.SignKey,
+		RealIP:       resolveHostIP(),
+		APIKey:       cfg.APIKey,
+		MaxBatchSize: cfg.MaxBatchSize,
+		Limiter:      limiter,
+	}
+	if cfg.BreakerThreshold > 0 {
+		sender.Breaker = NewCircuitBreaker(
+			cfg.BreakerThreshold,
+			time.Duration(cfg.BreakerCooldown)*time.Second,
+		)
+	}
+	if cfg.CompressionDict {
+		if len(baseURLs) > 1 {
+			log.Printf("Compression dictionary is not used with multiple servers")
+		} else {
+			sender.Dict = NewDictionary(DictionaryRetrain)
+		}
+	}
+	if len(baseURLs) > 1 {
+		// Повторы на уровне клиента задержали бы переключение на следующий сервер.
+		sender.Endpoints = NewEndpoints(
+			baseURLs,
+			EndpointPolicy(cfg.EndpointPolicy),
+			time.Duration(cfg.EndpointCooldown)*time.Second,
+		)
+		log.Printf("Failover across %d servers (%s)", len(baseURLs), cfg.EndpointPolicy)
+	} else {
+		restyClient.SetRetryCount(3)
+	}
+	creds, err := loadOrEnroll(cfg, baseURLs[0], sender.RealIP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enroll agent: %w", err)
+	}
+	if creds.AgentID != "" {
+		sender.AgentID = creds.AgentID
+		sender.Key = creds.Key
+		log.Printf("Agent identity: %s", creds.AgentID)
+	}
+	return sender, nil
+}
+
+// loadOrEnroll возвращает учётные данные агента из файла, а если их нет и задан токен
+// регистрации — регистрирует агента на сервере и сохраняет полученные данные.
+//
+// Если файла нет и токен не задан, возвращает пустые учётные данные: запросы подписываются общим ключом.
+func loadOrEnroll(cfg Config, baseURL, realIP string) (Credentials, error) {
+	creds, err := LoadCredentials(cfg.CredentialsFile)
+	if err == nil {
+		return creds, nil
+	}
+	if !os.IsNotExist(err) {
+		return Credentials{}, err
+	}
+	if cfg.EnrollToken == "" {
+		return Credentials{}, nil
+	}
+
+	hostname, _ := os.Hostname()
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	creds, err = Enroll(ctx, &http.Client{Timeout: 5 * time.Second}, baseURL, cfg.EnrollToken, hostname, realIP)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if err := SaveCredentials(cfg.CredentialsFile, creds); err != nil {
+		return Credentials{}, fmt.Errorf("failed to save credentials: %w", err)
+	}
+	return creds, nil
+}
+
+// Run запускает приём метрик StatsD и push API, воркеры отправки, сборщики метрик
+// и периодическую отправку батчей.
+//
+// При отмене ctx отправляет последний батч, ждёт завершения отправок не дольше
+// ShutdownTimeout, сохраняет состояние и закрывает отправителя.
+// Возвращает ошибку, если не удалось открыть адрес StatsD или push API.
+func (r *Runner) Run(ctx context.Context) error {
+	if r.Config.StatsDAddress != "" {
+		conn, err := net.ListenPacket("udp", r.Config.StatsDAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen for StatsD: %w", err)
+		}
+		defer conn.Close()
+		statsd := NewStatsD(r.external)
+		go func() {
+			if err := statsd.Serve(conn); err != nil {
+				log.Printf("StatsD listener failed: %v", err)
+			}
+		}()
+		log.Printf("StatsD listener enabled: %s", conn.LocalAddr())
+	}
+	var pushServer *http.Server
+	if r.Config.PushAddress != "" {
+		ln, err := net.Listen("tcp", r.Config.PushAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen for push API: %w", err)
+		}
+		pushServer = &http.Server{
+			Handler:           NewPushHandler(r.external),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		go func() {
+			if err := pushServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Push API server failed: %v", err)
+			}
+		}()
+		log.Printf("Push API enabled: http://%s%s", ln.Addr(), PushPath)
+	}
+
+	startWorkerPool(r)
+
+	// Периодический сбор метрик по группам сборщиков.
+	pollCtx, pollCancel := context.WithCancel(ctx)
+	defer pollCancel()
+	startCollectors(pollCtx, r)
+
+	// Периодическая отправка метрик с поддержкой graceful shutdown.
+	reportTicker := time.NewTicker(time.Duration(r.Config.ReportInterval) * time.Second)
+	defer reportTicker.Stop()
+
+	for {
+		select {
+		case <-reportTicker.C:
+			recordDroppedBatches(r)
+			recordSendErrors(r)
+			recordQueueDepth(r)
+			persistState(r)
+			batch := buildBatchSnapshot(r)
+			if len(batch) == 0 {
+				continue
+			}
+			if !r.jobQueue.Push(batch) {
+				log.Printf("Send queue is full, batch of %d metrics dropped", len(batch))
+			}
+
+		case <-ctx.Done():
+			log.Println("Starting graceful shutdown...")
+
+			// Останавливаем горутины сбора метрик и приём метрик от локальных приложений.
+			pollCancel()
+			if pushServer != nil {
+				_ = pushServer.Close()
+			}
+
+			// Отправляем последний батч и ждём завершения отправок не дольше ShutdownTimeout.
+			finalBatch := buildBatchSnapshot(r)
+			if len(finalBatch) > 0 {
+				log.Printf("Sending final batch of %d metrics...\n", len(finalBatch))
+			}
+			log.Println("Waiting for pending requests to complete...")
+			timeout := time.Duration(r.Config.ShutdownTimeout) * time.Second
+			if !drainAndWait(r, finalBatch, timeout) {
+				log.Printf("Shutdown deadline of %s exceeded, abandoning in-flight sends", timeout)
+			}
+			persistState(r)
+
+			if closer, ok := r.Sender.(interface{ Close() error }); ok {
+				if err := closer.Close(); err != nil {
+					log.Printf("failed to close sender: %v", err)
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// buildBatchSnapshot формирует срез метрик для отправки (снимок текущего состояния)
+// и добавляет метрики StatsD и push API, накопленные с предыдущей отправки.
+// В режиме SendChangedOnly gauge, не изменившиеся с последней успешной отправки, пропускаются.
+//
+// state — текущее состояние агента.
+// Возвращает срез моделей метрик для отправки.
+func buildBatchSnapshot(state *Runner) []models.Metrics {
+	state.Collector.mu.RLock()
+	defer state.Collector.mu.RUnlock()
+
+	batch := make([]models.Metrics, 0, len(state.Collector.metrics))
+	for name, metric := range state.Collector.metrics {
+		m := models.Metrics{
+			ID:    name,
+			MType: metric.Type,
+		}
+		if metric.Type == "gauge" {
+			val := metric.Value
+			m.Value = &val
+		} else {
+			delta := int64(metric.Value)
+			m.Delta = &delta
+		}
+		batch = append(batch, m)
+	}
+	if state.external != nil {
+		batch = append(batch, state.external.Flush()...)
+	}
+	if state.changes != nil {
+		batch = state.changes.Filter(batch)
+	}
+	return batch
+}
+
+// recordDroppedBatches добавляет в коллектор counter-метрику DroppedBatches —
+// количество батчей, отброшенных из-за переполнения очереди отправки.
+//
+// state — текущее состояние агента.
+func recordDroppedBatches(state *Runner) {
+	dropped := state.jobQueue.Dropped()
+	if dropped == 0 {
+		return
+	}
+	state.Collector.mu.Lock()
+	defer state.Collector.mu.Unlock()
+	state.Collector.metrics["DroppedBatches"] = Metric{"counter", float64(dropped)}
+}
+
+// recordQueueDepth добавляет в коллектор gauge-метрики AgentQueueDepth и AgentSpoolDepth —
+// количество батчей в очереди отправки и в дисковом спуле (если он включён).
+// Используется только при включённом сборщике self.
+//
+// state — текущее состояние агента.
+func recordQueueDepth(state *Runner) {
+	if !state.Collector.collectors.Enabled(CollectorSelf) {
+		return
+	}
+	state.Collector.mu.Lock()
+	defer state.Collector.mu.Unlock()
+	state.Collector.metrics["AgentQueueDepth"] = Metric{"gauge", float64(state.jobQueue.Len())}
+	if state.spool != nil {
+		state.Collector.metrics["AgentSpoolDepth"] = Metric{"gauge", float64(state.spool.Len())}
+	}
+}
+
+// recordSendErrors добавляет в коллектор counter-метрики SendErrors_<категория> —
+// количество неудачных отправок по категориям ошибок (см. ErrorCategory).
+//
+// state — текущее состояние агента.
+func recordSendErrors(state *Runner) {
+	if state.errStats == nil {
+		return
+	}
+	counts := state.errStats.Snapshot()
+	if len(counts) == 0 {
+		return
+	}
+	state.Collector.mu.Lock()
+	defer state.Collector.mu.Unlock()
+	for c, n := range counts {
+		state.Collector.metrics["SendErrors_"+string(c)] = Metric{"counter", float64(n)}
+	}
+}
+
+// handleSendError классифицирует ошибку отправки, учитывает её в счётчиках и пишет в лог
+// с категорией, по которой ошибки можно отбирать независимо от текста.
+//
+// state — текущее состояние агента.
+// prefix — контекст сообщения (например, номер воркера).
+// err — ошибка отправки.
+func handleSendError(state *Runner, prefix string, err error) {
+	category := classifySendError(err)
+	if state.errStats != nil {
+		state.errStats.Record(category)
+	}
+	log.Printf("%s: send error [%s]: %v", prefix, category, err)
+}
+
+// classifySendError определяет стабильную категорию ошибки отправки по ответу сервера:
+// коду models.ErrorResponse и статусу HTTP или коду статуса gRPC.
+// Ошибки без ответа сервера относятся к категории network.
+func classifySendError(err error) ErrorCategory {
+	var se *statusError
+	if errors.As(err, &se) {
+		return CategorizeHTTP(se.code, se.apiCode)
+	}
+	if st, ok := status.FromError(err); ok {
+		return CategorizeGRPC(st.Code())
+	}
+	return ErrCategoryNetwork
+}
+
+// restoreState загружает сохранённое состояние агента (PollCount, курсор спула, время последней
+// отправки), чтобы накопительные метрики не обнулялись при перезапуске.
+//
+// state — текущее состояние агента; спул должен быть уже открыт.
+func restoreState(state *Runner) error {
+	if state.Config.StateFile == "" {
+		return nil
+	}
+	st, err := LoadState(state.Config.StateFile)
+	if err != nil {
+		return err
+	}
+	state.Collector.mu.Lock()
+	state.Collector.pollCount = st.PollCount
+	state.Collector.mu.Unlock()
+	if state.spool != nil {
+		state.spool.SetCursor(st.SpoolCursor)
+	}
+	if !st.LastSend.IsZero() {
+		state.lastSend.Store(st.LastSend.UnixNano())
+	}
+	return nil
+}
+
+// persistState сохраняет состояние агента в файл StateFile (если он задан).
+//
+// state — текущее состояние агента.
+func persistState(state *Runner) {
+	if state.Config.StateFile == "" {
+		return
+	}
+	state.Collector.mu.RLock()
+	st := State{PollCount: state.Collector.pollCount}
+	state.Collector.mu.RUnlock()
+	if state.spool != nil {
+		st.SpoolCursor = state.spool.Cursor()
+	}
+	if ns := state.lastSend.Load(); ns != 0 {
+		st.LastSend = time.Unix(0, ns).UTC()
+	}
+	if err := SaveState(state.Config.StateFile, st); err != nil {
+		log.Printf("Failed to save agent state: %v", err)
+	}
+}
+
+// sendMetrics отправляет батч метрик через Sender.
+//
+// state — текущее состояние агента.
+func sendMetrics(state *Runner) {
+	batch := buildBatchSnapshot(state)
+	if len(batch) == 0 {
+		return
+	}
+	if err := state.Sender.SendBatch(batch); err != nil {
+		handleSendError(state, "sendMetrics", err)
+		return
+	}
+	recordSendSuccess(state, batch)
+}
+
+// recordSendSuccess фиксирует успешную отправку батча: время отправки и значения
+// отправленных gauge для фильтра SendChangedOnly.
+//
+// state — текущее состояние агента.
+// batch — успешно отправленный батч.
+func recordSendSuccess(state *Runner, batch []models.Metrics) {
+	state.lastSend.Store(time.Now().UnixNano())
+	if state.changes != nil {
+		state.changes.Commit(batch)
+	}
+}
+
+// startWorkerPool запускает пул воркеров для параллельной отправки метрик.
+//
+// state — текущее состояние агента.
+func startWorkerPool(state *Runner) {
+	if state.Config.RateLimit <= 0 {
+		state.Config.RateLimit = 1
+	}
+
+	state.jobQueue = NewQueue(
+		state.Config.QueueSize,
+		QueuePolicy(state.Config.QueuePolicy),
+		time.Duration(state.Config.QueueTimeout)*time.Second,
+	)
+
+	for i := 0; i < state.Config.RateLimit; i++ {
+		state.wg.Add(1)
+		go func(id int) {
+			defer state.wg.Done()
+			for batch := range state.jobQueue.C() {
+				if err := state.Sender.SendBatch(batch); err != nil {
+					handleSendError(state, fmt.Sprintf("worker %d", id), err)
+					continue
+				}
+				recordSendSuccess(state, batch)
+			}
+		}(i + 1)
+	}
+}
+
+// drainAndWait ставит финальный батч в очередь, закрывает очередь заданий и ждёт завершения воркеров.
+//
+// state — текущее состояние агента.
+// finalBatch — последний батч метрик (может быть пустым).
+// timeout — максимальное время ожидания.
+// Возвращает false, если воркеры не завершились до истечения timeout.
+func drainAndWait(state *Runner, finalBatch []models.Metrics, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	if len(finalBatch) > 0 && !state.jobQueue.PushTimeout(finalBatch, timeout) {
+		state.jobQueue.Close()
+		return false
+	}
+	state.jobQueue.Close()
+
+	done := make(chan struct{})
+	go func() {
+		state.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-deadline.C:
+		return false
+	}
+}
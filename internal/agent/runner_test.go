@@ -0,0 +1,491 @@
+package agent
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/go-resty/resty/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// floatPtr возвращает указатель на переданное значение float64.
+//
+// f — значение типа float64.
+// Возвращает указатель на float64.
+func floatPtr(f float64) *float64 { return &f }
+
+// int64Ptr преобразует float64 в int64 и возвращает указатель на него.
+//
+// i — значение типа float64.
+// Возвращает указатель на int64.
+func int64Ptr(i float64) *int64 {
+	v := int64(i)
+	return &v
+}
+
+// TestSendMetrics тестирует функцию отправки метрик sendMetrics.
+// Проверяет корректность формирования и передачи метрик типа gauge и counter через HTTP.
+// Использует httptest для имитации сервера и проверки содержимого запроса.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestSendMetrics(t *testing.T) {
+	tests := []struct {
+		name     string         // Название теста
+		metric   Metric         // Входная метрика для отправки
+		expected models.Metrics // Ожидаемая структура метрики на сервере
+		status   int            // HTTP-статус, который должен вернуть сервер
+	}{
+		{
+			name:   "GaugeSuccess",
+			metric: Metric{"gauge", 12.3},
+			expected: models.Metrics{
+				ID:    "TestMetric",
+				MType: "gauge",
+				Value: floatPtr(12.3),
+				Delta: nil,
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "CounterSuccess",
+			metric: Metric{"counter", 5},
+			expected: models.Metrics{
+				ID:    "TestMetric",
+				MType: "counter",
+				Value: nil,
+				Delta: int64Ptr(5),
+			},
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config := Config{
+				PollInterval:   2,
+				ReportInterval: 10,
+				RateLimit:      1,
+				Key:            "",
+			}
+
+			collector := &Collector{
+				metrics:   map[string]Metric{"TestMetric": tc.metric},
+				pollCount: 0,
+				rng:       rand.New(rand.NewSource(1)),
+			}
+
+			state := &Runner{
+				Config:    config,
+				Collector: collector,
+			}
+
+			var got []models.Metrics
+
+			// Тестовый HTTP-сервер для проверки полученных метрик
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+
+				if r.URL.Path != "/updates/" {
+					t.Errorf("expected path /updates/, got %q", r.URL.Path)
+				}
+				if r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("expected Content-Type application/json, got %q", r.Header.Get("Content-Type"))
+				}
+
+				var reader = r.Body
+				if r.Header.Get("Content-Encoding") == "gzip" {
+					gz, err := gzip.NewReader(r.Body)
+					if err != nil {
+						t.Errorf("failed to create gzip reader: %v", err)
+						return
+					}
+					defer gz.Close()
+					reader = gz
+				}
+				if err := json.NewDecoder(reader).Decode(&got); err != nil {
+					t.Errorf("failed to decode body: %v", err)
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer ts.Close()
+
+			client := resty.New().SetBaseURL(ts.URL)
+			state.Sender = &RestySender{Client: client}
+
+			sendMetrics(state)
+
+			if len(got) == 0 {
+				t.Fatalf("no metrics were sent")
+			}
+
+			metricSent := got[0]
+
+			if metricSent.ID != tc.expected.ID {
+				t.Errorf("expected ID %q, got %q", tc.expected.ID, metricSent.ID)
+			}
+			if metricSent.MType != tc.expected.MType {
+				t.Errorf("expected MType %q, got %q", tc.expected.MType, metricSent.MType)
+			}
+			if tc.expected.Value != nil && (metricSent.Value == nil || *metricSent.Value != *tc.expected.Value) {
+				t.Errorf("expected Value %v, got %v", *tc.expected.Value, metricSent.Value)
+			}
+			if tc.expected.Delta != nil && (metricSent.Delta == nil || *metricSent.Delta != *tc.expected.Delta) {
+				t.Errorf("expected Delta %v, got %v", *tc.expected.Delta, metricSent.Delta)
+			}
+		})
+	}
+}
+
+// blockingSender — отправитель для тестов, ожидающий сигнала release перед завершением отправки.
+type blockingSender struct {
+	release chan struct{}
+	sent    chan []models.Metrics
+}
+
+// SendBatch ждёт release и передаёт батч в канал sent.
+func (b *blockingSender) SendBatch(metrics []models.Metrics) error {
+	<-b.release
+	b.sent <- metrics
+	return nil
+}
+
+// TestDrainAndWait проверяет доставку финального батча и соблюдение срока ожидания при завершении.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestDrainAndWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		release bool
+		want    bool
+	}{
+		{name: "final batch delivered", release: true, want: true},
+		{name: "deadline exceeded", release: false, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &blockingSender{release: make(chan struct{}), sent: make(chan []models.Metrics, 1)}
+			state := &Runner{Config: Config{RateLimit: 1}, Sender: sender}
+			startWorkerPool(state)
+			if tt.release {
+				close(sender.release)
+			}
+
+			final := []models.Metrics{{ID: "final", MType: "gauge", Value: floatPtr(1)}}
+			if got := drainAndWait(state, final, 200*time.Millisecond); got != tt.want {
+				t.Fatalf("drainAndWait() = %v, want %v", got, tt.want)
+			}
+			if tt.release {
+				if batch := <-sender.sent; batch[0].ID != "final" {
+					t.Fatalf("unexpected batch: %+v", batch)
+				}
+			} else {
+				close(sender.release)
+			}
+		})
+	}
+}
+
+// TestHandleSendError проверяет учёт ошибок отправки по категориям и их выгрузку в коллектор.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestHandleSendError(t *testing.T) {
+	state := &Runner{
+		Collector: &Collector{metrics: make(map[string]Metric)},
+		errStats:  NewErrorStats(),
+	}
+	handleSendError(state, "test", newStatusError(http.StatusBadRequest, []byte(`{"code":"invalid_signature","message":"invalid signature"}`)))
+	handleSendError(state, "test", fmt.Errorf("operation failed after retries: %w", newStatusError(http.StatusBadRequest, []byte(`{"code":"invalid_signature"}`))))
+	handleSendError(state, "test", errors.New("connection refused"))
+	handleSendError(state, "test", status.Error(codes.Unauthenticated, "unauthorized"))
+
+	recordSendErrors(state)
+
+	want := map[string]float64{
+		"SendErrors_signature": 2,
+		"SendErrors_network":   1,
+		"SendErrors_auth":      1,
+	}
+	for name, v := range want {
+		m, ok := state.Collector.metrics[name]
+		if !ok || m.Type != "counter" || m.Value != v {
+			t.Errorf("%s = %+v, want counter %v", name, m, v)
+		}
+	}
+}
+
+// TestRecordQueueDepth проверяет выгрузку глубины очереди и спула только при включённом сборщике self.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestRecordQueueDepth(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := spool.Put([]models.Metrics{{ID: "m", MType: "gauge", Value: floatPtr(1)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, enabled := range []bool{false, true} {
+		collectors := Collectors{}
+		if enabled {
+			collectors[CollectorSelf] = struct{}{}
+		}
+		state := &Runner{
+			Collector: &Collector{metrics: make(map[string]Metric), collectors: collectors},
+			jobQueue:  NewQueue(4, QueueDropOldest, time.Second),
+			spool:     spool,
+		}
+		state.jobQueue.Push([]models.Metrics{})
+		state.jobQueue.Push([]models.Metrics{})
+
+		recordQueueDepth(state)
+
+		queue, hasQueue := state.Collector.metrics["AgentQueueDepth"]
+		spooled, hasSpool := state.Collector.metrics["AgentSpoolDepth"]
+		if !enabled {
+			if hasQueue || hasSpool {
+				t.Fatalf("self collector disabled, got metrics %+v", state.Collector.metrics)
+			}
+			continue
+		}
+		if queue.Value != 2 || spooled.Value != 1 {
+			t.Fatalf("AgentQueueDepth = %v, AgentSpoolDepth = %v, want 2 and 1", queue.Value, spooled.Value)
+		}
+	}
+}
+
+// TestPersistRestoreState проверяет, что PollCount и время последней отправки переживают перезапуск агента.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestPersistRestoreState(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "agent-state.json")
+	newState := func() *Runner {
+		return &Runner{
+			Config:    Config{StateFile: stateFile},
+			Collector: &Collector{metrics: make(map[string]Metric), rng: rand.New(rand.NewSource(1))},
+			Sender:    &fakeSender{},
+		}
+	}
+
+	before := newState()
+	for i := 0; i < 3; i++ {
+		collectMetrics(before)
+	}
+	sendMetrics(before)
+	sentAt := before.lastSend.Load()
+	if sentAt == 0 {
+		t.Fatal("expected last send time to be recorded")
+	}
+	persistState(before)
+
+	after := newState()
+	if err := restoreState(after); err != nil {
+		t.Fatalf("restoreState() error = %v", err)
+	}
+	collectMetrics(after)
+	if got := after.Collector.metrics["PollCount"].Value; got != 4 {
+		t.Fatalf("PollCount after restart = %v, want 4", got)
+	}
+	if got := after.lastSend.Load(); got != sentAt {
+		t.Fatalf("lastSend after restart = %d, want %d", got, sentAt)
+	}
+}
+
+// TestSendMetrics_ChangedOnly проверяет, что в режиме SendChangedOnly неизменившиеся gauge
+// не отправляются повторно, а после ошибки отправки передаются снова.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestSendMetrics_ChangedOnly(t *testing.T) {
+	sender := &fakeSender{}
+	state := &Runner{
+		Config: Config{SendChangedOnly: true},
+		Collector: &Collector{metrics: map[string]Metric{
+			"Static":    {"gauge", 1},
+			"PollCount": {"counter", 1},
+		}},
+		Sender:   sender,
+		errStats: NewErrorStats(),
+		changes:  NewChangeFilter(),
+	}
+
+	sendMetrics(state)
+	if len(sender.sent) != 1 || len(sender.sent[0]) != 2 {
+		t.Fatalf("expected full first batch, got %+v", sender.sent)
+	}
+
+	sendMetrics(state)
+	if len(sender.sent) != 2 || len(sender.sent[1]) != 1 || sender.sent[1][0].ID != "PollCount" {
+		t.Fatalf("expected only the counter in the second batch, got %+v", sender.sent[1:])
+	}
+
+	state.Collector.metrics["Static"] = Metric{"gauge", 2}
+	sender.err = errors.New("connection refused")
+	sendMetrics(state)
+	sender.err = nil
+	sendMetrics(state)
+	if len(sender.sent) != 3 || len(sender.sent[2]) != 2 {
+		t.Fatalf("expected changed gauge to be resent after a failure, got %+v", sender.sent[2:])
+	}
+}
+
+// TestNewRunner_InvalidConfig проверяет, что NewRunner отклоняет некорректную конфигурацию.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestNewRunner_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string // Название теста
+		cfg  Config // Конфигурация агента
+	}{
+		{name: "NoServers", cfg: Config{}},
+		{name: "QueuePolicy", cfg: Config{Servers: []string{"localhost:8080"}, QueuePolicy: "drop-all"}},
+		{name: "EndpointPolicy", cfg: Config{Servers: []string{"localhost:8080"}, EndpointPolicy: "random"}},
+		{name: "CPUMode", cfg: Config{Servers: []string{"localhost:8080"}, CPUMode: "per-socket"}},
+		{name: "Collect", cfg: Config{Servers: []string{"localhost:8080"}, Collect: "gpu"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewRunner(tc.cfg); err == nil {
+				t.Fatal("NewRunner() error = nil, want error")
+			}
+		})
+	}
+}
+
+// TestRunner_Run проверяет, что при отмене контекста Run отправляет последний батч и завершается.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestRunner_Run(t *testing.T) {
+	received := make(chan struct{}, 16)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	runner, err := NewRunner(Config{
+		Servers:         []string{strings.TrimPrefix(ts.URL, "http://")},
+		PollInterval:    3600,
+		ReportInterval:  3600,
+		CredentialsFile: filepath.Join(t.TempDir(), "credentials.json"),
+	})
+	if err != nil {
+		t.Fatalf("NewRunner() error = %v", err)
+	}
+	runner.Collector.metrics["Alloc"] = Metric{"gauge", 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runner.Run(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after context cancellation")
+	}
+	if len(received) == 0 {
+		t.Fatal("final batch was not sent on shutdown")
+	}
+}
+
+// TestNewSender_HTTPSEndpoint проверяет, что адрес https:// используется как есть
+// и HTTP-клиент применяет к нему политику TLS.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestNewSender_HTTPSEndpoint(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	ts.StartTLS()
+	defer ts.Close()
+
+	sender, err := newSender(Config{Servers: []string{ts.URL}, TLS: config.TLSConfig{MinVersion: "1.3"}})
+	if err != nil {
+		t.Fatalf("newSender() error = %v", err)
+	}
+	client := sender.(*RestySender).Client
+	if got := client.BaseURL; got != ts.URL {
+		t.Fatalf("BaseURL = %q, want %q", got, ts.URL)
+	}
+	if _, err := client.R().Get("/"); err == nil {
+		t.Fatal("request to a TLS 1.2 server succeeded under a TLS 1.3 minimum")
+	}
+}
+
+// TestNewSender_TLSCA проверяет, что агент доверяет серверу, сертификат которого подписан CA из TLSCA.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestNewSender_TLSCA(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sender, err := newSender(Config{Servers: []string{ts.URL}, TLSCA: caFile})
+	if err != nil {
+		t.Fatalf("newSender() error = %v", err)
+	}
+	resp, err := sender.(*RestySender).Client.R().Get("/")
+	if err != nil {
+		t.Fatalf("request with CA bundle error = %v", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode(), http.StatusOK)
+	}
+
+	if _, err := newSender(Config{Servers: []string{ts.URL}, TLSCA: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Fatal("newSender() with a missing CA bundle error = nil, want error")
+	}
+}
+
+// TestNewSender_HTTP2 проверяет, что с HTTP2 агент отправляет запросы на http:// сервер по h2c.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestNewSender_HTTP2(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetHTTP1(true)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	defer ts.Close()
+
+	sender, err := newSender(Config{Servers: []string{ts.URL}, HTTP2: true})
+	if err != nil {
+		t.Fatalf("newSender() error = %v", err)
+	}
+	resp, err := sender.(*RestySender).Client.R().Get("/")
+	if err != nil {
+		t.Fatalf("h2c request error = %v", err)
+	}
+	if got := resp.Header().Get("X-Proto"); got != "HTTP/2.0" {
+		t.Fatalf("proto = %q, want HTTP/2.0", got)
+	}
+}
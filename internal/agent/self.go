@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// SelfCollector собирает метрики собственного процесса агента.
+//
+// Загрузка CPU считается между соседними вызовами Collect, поэтому
+// коллектор создаётся один раз и переиспользуется.
+type SelfCollector struct {
+	proc *process.Process
+}
+
+// NewSelfCollector создаёт коллектор метрик текущего процесса.
+func NewSelfCollector() (*SelfCollector, error) {
+	p, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent process: %w", err)
+	}
+	return &SelfCollector{proc: p}, nil
+}
+
+// Collect возвращает метрики процесса агента: AgentRSS (байты), AgentCPUPercent,
+// AgentOpenFDs и AgentGoroutines.
+//
+// Значения, которые не поддерживаются платформой (например, число дескрипторов
+// вне Linux), пропускаются.
+func (c *SelfCollector) Collect() map[string]float64 {
+	metrics := map[string]float64{
+		"AgentGoroutines": float64(runtime.NumGoroutine()),
+	}
+	if mem, err := c.proc.MemoryInfo(); err == nil {
+		metrics["AgentRSS"] = float64(mem.RSS)
+	}
+	if cpu, err := c.proc.Percent(0); err == nil {
+		metrics["AgentCPUPercent"] = cpu
+	}
+	if fds, err := c.proc.NumFDs(); err == nil {
+		metrics["AgentOpenFDs"] = float64(fds)
+	}
+	return metrics
+}
@@ -0,0 +1,22 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSelfCollector проверяет сбор метрик собственного процесса.
+func TestSelfCollector(t *testing.T) {
+	c, err := NewSelfCollector()
+	require.NoError(t, err)
+
+	metrics := c.Collect()
+	require.Positive(t, metrics["AgentGoroutines"])
+	if rss, ok := metrics["AgentRSS"]; ok {
+		require.Positive(t, rss)
+	}
+	if cpu, ok := metrics["AgentCPUPercent"]; ok {
+		require.GreaterOrEqual(t, cpu, 0.0)
+	}
+}
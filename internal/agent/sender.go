@@ -0,0 +1,618 @@
+package agent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/compression"
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/crypto"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/proto"
+	"github.com/RoGogDBD/metric-alerter/internal/tracing"
+	"github.com/RoGogDBD/metric-alerter/internal/version"
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// AgentVersionHeader — заголовок, в котором агент передаёт версию своей сборки.
+const AgentVersionHeader = "X-Agent-Version"
+
+var (
+	// gzipPool — пул для переиспользования gzip.Writer, чтобы уменьшить аллокации при сжатии данных.
+	gzipPool = sync.Pool{
+		New: func() interface{} {
+			// создаём writer, привязанный к io.Discard — он будет Reset-ом перенастроен перед использованием
+			return gzip.NewWriter(io.Discard)
+		},
+	}
+
+	// bufPool — пул для переиспользования bytes.Buffer при формировании тела запроса.
+	bufPool = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+)
+
+type (
+	// Sender — интерфейс для отправки батча метрик.
+	Sender interface {
+		// SendBatch отправляет срез метрик на сервер.
+		SendBatch(metrics []models.Metrics) error
+	}
+
+	// RestySender реализует Sender, отправляя метрики через resty.Client.
+	RestySender struct {
+		Client       *resty.Client      // HTTP-клиент.
+		Key          string             // Ключ для подписи.
+		CryptoKey    *rsa.PublicKey     // Публичный ключ для асимметричного шифрования.
+		SignKey      ed25519.PrivateKey // Закрытый ключ Ed25519 для подписи (nil — не подписывается).
+		RealIP       string             // IP хоста агента.
+		AgentID      string             // Идентификатор агента, выданный при регистрации.
+		APIKey       string             // API-ключ для заголовка Authorization.
+		MaxBatchSize int                // Максимальное число метрик в одном запросе (0 — без ограничения).
+		Endpoints    *Endpoints         // Серверы для переключения при отказе (nil — используется базовый адрес клиента).
+		Breaker      *CircuitBreaker    // Размыкатель цепи при недоступности сервера (nil — отключён).
+		Dict         *Dictionary        // Словарь сжатия батчей (nil — только gzip).
+		Limiter      *TokenBucket       // Ограничитель частоты запросов, общий для воркеров (nil — без ограничения).
+	}
+
+	// SpoolingSender оборачивает Sender дисковым спулом.
+	//
+	// Батч, который не удалось отправить после всех повторных попыток, сохраняется в спул,
+	// а после следующей успешной отправки накопленные батчи отправляются повторно.
+	SpoolingSender struct {
+		Sender Sender // Исходный отправитель.
+		Spool  *Spool // Дисковый спул неотправленных батчей.
+	}
+
+	// GRPCSender реализует Sender, отправляя метрики через gRPC.
+	GRPCSender struct {
+		Client  proto.MetricsClient // gRPC клиент метрик.
+		Conn    *grpc.ClientConn    // gRPC соединение.
+		RealIP  string              // IP хоста агента.
+		APIKey  string              // API-ключ для метаданных authorization.
+		SignKey ed25519.PrivateKey  // Закрытый ключ Ed25519 для подписи (nil — не подписывается).
+		Limiter *TokenBucket        // Ограничитель частоты запросов (nil — без ограничения).
+	}
+)
+
+// SendBatch отправляет батч метрик на сервер, разбивая его на части не больше MaxBatchSize.
+//
+// metrics — срез метрик для отправки.
+// Возвращает ошибку первой неудачной отправки; части, отправленные до неё, не повторяются.
+// Пока размыкатель Breaker разомкнут, возвращает ErrCircuitOpen без обращения к серверу.
+func (rs *RestySender) SendBatch(metrics []models.Metrics) error {
+	if rs.Breaker == nil {
+		return rs.sendChunks(metrics)
+	}
+	if err := rs.Breaker.Allow(); err != nil {
+		return err
+	}
+	err := rs.sendChunks(metrics)
+	if breakerFailure(err) {
+		wasOpen := rs.Breaker.Open()
+		rs.Breaker.Failure()
+		if !wasOpen && rs.Breaker.Open() {
+			log.Printf("Circuit breaker opened after consecutive send failures: %v", err)
+		}
+	} else {
+		if rs.Breaker.Open() {
+			log.Printf("Circuit breaker closed: server is reachable again")
+		}
+		rs.Breaker.Success()
+	}
+	return err
+}
+
+// sendChunks отправляет батч частями не больше MaxBatchSize.
+func (rs *RestySender) sendChunks(metrics []models.Metrics) error {
+	for _, chunk := range splitBatch(metrics, rs.MaxBatchSize) {
+		if err := rs.sendChunk(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// breakerFailure сообщает, говорит ли ошибка отправки о недоступности сервера.
+//
+// Ответ, отклоняющий сами данные или учётные данные, означает, что сервер доступен,
+// и размыкатель не размыкает.
+func breakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch classifySendError(err) {
+	case ErrCategoryNetwork, ErrCategoryServer:
+		return true
+	default:
+		return false
+	}
+}
+
+// splitBatch разбивает батч на части не больше size метрик (size <= 0 — без разбиения).
+func splitBatch(metrics []models.Metrics, size int) [][]models.Metrics {
+	if size <= 0 || len(metrics) <= size {
+		return [][]models.Metrics{metrics}
+	}
+	chunks := make([][]models.Metrics, 0, (len(metrics)+size-1)/size)
+	for start := 0; start < len(metrics); start += size {
+		end := min(start+size, len(metrics))
+		chunks = append(chunks, metrics[start:end])
+	}
+	return chunks
+}
+
+// sendChunk подписывает, сжимает, шифрует и отправляет часть батча метрик на сервер.
+//
+// Подписи вычисляются по несжатому JSON: сервер проверяет их после расшифровки и распаковки.
+//
+// metrics — срез метрик для отправки.
+// Возвращает ошибку при неудаче.
+func (rs *RestySender) sendChunk(metrics []models.Metrics) error {
+	body, err := models.MetricsBatch(metrics).MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	// Словарь используется только с одним сервером: регистрация на каждом из нескольких
+	// серверов не отслеживается.
+	dict := rs.Dict
+	if rs.Endpoints != nil {
+		dict = nil
+	}
+	encoding, dictID := "gzip", ""
+	var compressed []byte
+	if dict != nil {
+		if data, id, ok := dict.Compress(body); ok {
+			encoding, dictID, compressed = compression.Encoding, id, data
+		}
+	}
+	if compressed == nil {
+		if compressed, err = gzipBody(body); err != nil {
+			return err
+		}
+	}
+
+	var hashSignature string
+	if rs.Key != "" {
+		hashSignature = computeHMACSHA256(body, rs.Key)
+	}
+	var signature string
+	if rs.SignKey != nil {
+		signature = crypto.Sign(body, rs.SignKey)
+	}
+
+	// Шифруем сжатые данные, если задан публичный ключ.
+	dataToSend := compressed
+	if rs.CryptoKey != nil {
+		encrypted, err := crypto.EncryptData(compressed, rs.CryptoKey)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt data: %w", err)
+		}
+		dataToSend = encrypted
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	// Спан отправки охватывает все попытки; контекст трассировки передаётся серверу в заголовках.
+	ctx, span := tracing.Tracer().Start(ctx, "send batch", trace.WithAttributes(attribute.Int("metrics.count", len(metrics))))
+	defer span.End()
+
+	// acceptsDict — сервер сообщил о поддержке словарей сжатия.
+	acceptsDict := false
+
+	// post выполняет один POST батча по адресу url.
+	post := func(url string) error {
+		if err := waitLimiter(ctx, rs.Limiter); err != nil {
+			return err
+		}
+		req := rs.Client.R().
+			SetContext(ctx).
+			SetHeader("Content-Type", "application/json").
+			SetHeader("Content-Encoding", encoding).
+			SetHeader(AgentVersionHeader, version.Get().Version).
+			SetBody(dataToSend)
+
+		if dictID != "" {
+			req.SetHeader(compression.IDHeader, dictID)
+		}
+
+		if rs.RealIP != "" {
+			req.SetHeader("X-Real-IP", rs.RealIP)
+		}
+
+		if rs.CryptoKey != nil {
+			req.SetHeader("X-Encrypted", "true")
+		}
+
+		if hashSignature != "" {
+			req.SetHeader("HashSHA256", hashSignature)
+		}
+		if signature != "" {
+			req.SetHeader(crypto.SignatureHeader, signature)
+		}
+
+		rs.setAuthHeaders(req)
+		tracing.Inject(ctx, req.Header)
+
+		resp, err := req.Post(url)
+		if err != nil {
+			return fmt.Errorf("failed to POST metrics batch: %w", err)
+		}
+		if resp.StatusCode() != http.StatusOK {
+			se := newStatusError(resp.StatusCode(), resp.Body())
+			se.retryAfter = parseRetryAfter(resp.Header().Get("Retry-After"), time.Now())
+			return se
+		}
+		acceptsDict = resp.Header().Get(compression.AcceptHeader) == compression.Encoding
+		return nil
+	}
+
+	// Выполняем POST с повторными попытками; при нескольких серверах перебираем их,
+	// откладывая те, что недоступны или отвечают ошибкой 5xx.
+	err = config.RetryWithBackoff(ctx, func() error {
+		if rs.Endpoints == nil {
+			return post("/updates/")
+		}
+		var lastErr error
+		for _, base := range rs.Endpoints.Order() {
+			err := post(base + "/updates/")
+			if err == nil {
+				rs.Endpoints.MarkHealthy(base)
+				return nil
+			}
+			var se *statusError
+			if errors.As(err, &se) && se.code < http.StatusInternalServerError {
+				return err
+			}
+			log.Printf("Server %s failed: %v", base, err)
+			rs.Endpoints.MarkFailed(base)
+			lastErr = err
+		}
+		return lastErr
+	})
+
+	// Сервер не знает словаря (например, после перезапуска): повторяем отправку с gzip.
+	var se *statusError
+	if dictID != "" && errors.As(err, &se) && se.apiCode == models.ErrCodeUnknownDictionary {
+		log.Printf("Server does not know compression dictionary %s, falling back to gzip", dictID)
+		dict.Reset()
+		return rs.sendChunk(metrics)
+	}
+	if err == nil && dict != nil && acceptsDict {
+		rs.trainDictionary(ctx, body)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// waitLimiter ждёт разрешения ограничителя частоты запросов; nil-ограничитель не ограничивает.
+func waitLimiter(ctx context.Context, limiter *TokenBucket) error {
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
+
+// gzipBody сжимает тело запроса gzip с переиспользованием буферов из пулов.
+func gzipBody(body []byte) ([]byte, error) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	gz := gzipPool.Get().(*gzip.Writer)
+	gz.Reset(buf)
+	defer func() {
+		// Сбрасываем и возвращаем объекты в пул.
+		gz.Reset(io.Discard)
+		gzipPool.Put(gz)
+		buf.Reset()
+		bufPool.Put(buf)
+	}()
+
+	if _, err := gz.Write(body); err != nil {
+		return nil, fmt.Errorf("failed to write gzip: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
+	}
+	// Содержимое сжатого буфера.
+	compressed := make([]byte, buf.Len())
+	copy(compressed, buf.Bytes())
+	return compressed, nil
+}
+
+// setAuthHeaders добавляет к запросу идентификатор агента и API-ключ, если они заданы.
+func (rs *RestySender) setAuthHeaders(req *resty.Request) {
+	if rs.AgentID != "" {
+		req.SetHeader(models.AgentIDHeader, rs.AgentID)
+	}
+	if rs.APIKey != "" {
+		req.SetAuthToken(rs.APIKey)
+	}
+}
+
+// trainDictionary обучает словарь на отправленном батче body и регистрирует его на сервере,
+// когда пора обновить словарь. Ошибка регистрации только логируется: батчи продолжают
+// сжиматься прежним словарём или gzip.
+func (rs *RestySender) trainDictionary(ctx context.Context, body []byte) {
+	dict, ok := rs.Dict.Candidate(body)
+	if !ok {
+		return
+	}
+	id := compression.ID(dict)
+	if err := waitLimiter(ctx, rs.Limiter); err != nil {
+		return
+	}
+	req := rs.Client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/octet-stream").
+		SetBody(dict)
+	if rs.RealIP != "" {
+		req.SetHeader("X-Real-IP", rs.RealIP)
+	}
+	rs.setAuthHeaders(req)
+	resp, err := req.Put(compression.DictionaryPath + id)
+	if err != nil {
+		log.Printf("Failed to register compression dictionary: %v", err)
+		return
+	}
+	if resp.StatusCode() != http.StatusNoContent {
+		log.Printf("Failed to register compression dictionary: %v", newStatusError(resp.StatusCode(), resp.Body()))
+		return
+	}
+	rs.Dict.Activate(dict)
+	log.Printf("Compression dictionary %s registered (%d bytes)", id, len(dict))
+}
+
+// statusError — ответ сервера с неожиданным кодом статуса.
+//
+// apiCode — машинно-читаемый код ошибки из тела ответа (models.ErrorResponse),
+// пусто, если сервер не вернул тело в этом формате.
+// retryable и retryAfter — подсказки сервера о повторе: флаг retryable из тела
+// и пауза из заголовка Retry-After.
+type statusError struct {
+	code       int
+	apiCode    string
+	message    string
+	retryable  bool
+	retryAfter time.Duration
+}
+
+// newStatusError создаёт statusError, разбирая тело ответа сервера с ошибкой.
+func newStatusError(code int, body []byte) *statusError {
+	se := &statusError{code: code}
+	var resp models.ErrorResponse
+	if err := json.Unmarshal(body, &resp); err == nil {
+		se.apiCode = resp.Code
+		se.message = resp.Message
+		se.retryable = resp.Retryable
+	}
+	return se
+}
+
+// Retryable сообщает, стоит ли повторять запрос.
+//
+// Повтор выполняется, только если сервер явно пометил ошибку как временную
+// или ответил 429/503 с заголовком Retry-After; ошибки валидации не повторяются.
+func (e *statusError) Retryable() bool {
+	if e.retryable {
+		return true
+	}
+	return e.retryAfter > 0 && (e.code == http.StatusTooManyRequests || e.code == http.StatusServiceUnavailable)
+}
+
+// RetryAfter возвращает паузу перед повтором, рекомендованную сервером.
+func (e *statusError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// parseRetryAfter разбирает значение заголовка Retry-After: число секунд или HTTP-дату.
+//
+// Возвращает 0, если заголовок пуст, некорректен или указывает на прошедший момент.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	at, err := http.ParseTime(value)
+	if err != nil || !at.After(now) {
+		return 0
+	}
+	return at.Sub(now)
+}
+
+// Error возвращает описание ошибки.
+func (e *statusError) Error() string {
+	if e.apiCode == "" {
+		return fmt.Sprintf("unexpected status: %d", e.code)
+	}
+	return fmt.Sprintf("unexpected status: %d %s: %s", e.code, e.apiCode, e.message)
+}
+
+// SendBatch отправляет батч метрик на gRPC сервер.
+func (gs *GRPCSender) SendBatch(metrics []models.Metrics) error {
+	req := &proto.UpdateMetricsRequest{Metrics: buildGRPCMetrics(metrics)}
+	var signature string
+	if gs.SignKey != nil {
+		payload, err := crypto.MessagePayload(req)
+		if err != nil {
+			return fmt.Errorf("failed to sign request: %w", err)
+		}
+		signature = crypto.Sign(payload, gs.SignKey)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	return config.RetryWithBackoff(ctx, func() error {
+		if err := waitLimiter(ctx, gs.Limiter); err != nil {
+			return err
+		}
+		requestCtx := metadata.AppendToOutgoingContext(ctx, strings.ToLower(AgentVersionHeader), version.Get().Version)
+		if gs.RealIP != "" {
+			requestCtx = metadata.AppendToOutgoingContext(requestCtx, "x-real-ip", gs.RealIP)
+		}
+		if gs.APIKey != "" {
+			requestCtx = metadata.AppendToOutgoingContext(requestCtx, "authorization", "Bearer "+gs.APIKey)
+		}
+		if signature != "" {
+			requestCtx = metadata.AppendToOutgoingContext(requestCtx, crypto.SignatureMetadataKey, signature)
+		}
+		if _, err := gs.Client.UpdateMetrics(requestCtx, req); err != nil {
+			return fmt.Errorf("failed to send metrics via gRPC: %w", err)
+		}
+		return nil
+	})
+}
+
+// SendBatch отправляет батч, а при временной ошибке сохраняет его в спул.
+//
+// Батч, отклонённый сервером (ошибка данных, авторизации или подписи), в спул не попадает:
+// повторная отправка закончилась бы тем же отказом. После успешной отправки повторно
+// отправляет батчи, накопленные в спуле.
+func (ss *SpoolingSender) SendBatch(metrics []models.Metrics) error {
+	if err := ss.Sender.SendBatch(metrics); err != nil {
+		if !spoolable(err) {
+			return err
+		}
+		if spoolErr := ss.Spool.Put(metrics); spoolErr != nil {
+			return fmt.Errorf("%w (failed to spool batch: %v)", err, spoolErr)
+		}
+		return fmt.Errorf("%w (batch spooled)", err)
+	}
+	n, err := ss.Spool.Replay(ss.replaySend)
+	if n > 0 {
+		log.Printf("Replayed %d spooled batches", n)
+	}
+	if err != nil {
+		log.Printf("Failed to replay spooled batches: %v", err)
+	}
+	return nil
+}
+
+// replaySend отправляет батч из спула, помечая постоянные ошибки ErrBatchRejected.
+func (ss *SpoolingSender) replaySend(metrics []models.Metrics) error {
+	err := ss.Sender.SendBatch(metrics)
+	if err != nil && !spoolable(err) {
+		return fmt.Errorf("%w: %w", ErrBatchRejected, err)
+	}
+	return err
+}
+
+// spoolable сообщает, что ошибка отправки временная и батч стоит сохранить для повтора:
+// сервер недоступен, перегружен или ограничил частоту запросов.
+func spoolable(err error) bool {
+	switch classifySendError(err) {
+	case ErrCategoryNetwork, ErrCategoryServer, ErrCategoryRateLimited:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close закрывает исходного отправителя, если он это поддерживает.
+func (ss *SpoolingSender) Close() error {
+	if closer, ok := ss.Sender.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Close закрывает gRPC соединение.
+func (gs *GRPCSender) Close() error {
+	return gs.Conn.Close()
+}
+
+// resolveHostIP пытается определить IP-адрес хоста агента.
+func resolveHostIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+
+	for _, addr := range addrs {
+		var ip net.IP
+		switch v := addr.(type) {
+		case *net.IPNet:
+			ip = v.IP
+		case *net.IPAddr:
+			ip = v.IP
+		}
+		if ip == nil || ip.IsLoopback() {
+			continue
+		}
+		ip = ip.To4()
+		if ip == nil {
+			continue
+		}
+		return ip.String()
+	}
+
+	return "127.0.0.1"
+}
+
+// computeHMACSHA256 вычисляет HMAC-SHA256 для данных с заданным ключом.
+//
+// data — данные для подписи.
+// key — ключ для HMAC.
+// Возвращает hex-строку подписи.
+func computeHMACSHA256(data []byte, key string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// buildGRPCMetrics преобразует метрики агента в gRPC формат.
+func buildGRPCMetrics(metrics []models.Metrics) []*proto.Metric {
+	result := make([]*proto.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		out := &proto.Metric{
+			Id:   m.ID,
+			Type: proto.Metric_GAUGE,
+		}
+		switch m.MType {
+		case "counter":
+			out.Type = proto.Metric_COUNTER
+			if m.Delta != nil {
+				out.Delta = *m.Delta
+			}
+		default:
+			if m.Value != nil {
+				out.Value = *m.Value
+			}
+		}
+		result = append(result, out)
+	}
+	return result
+}
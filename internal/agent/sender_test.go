@@ -0,0 +1,410 @@
+package agent
+
+import (
+	"compress/gzip"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/compression"
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/RoGogDBD/metric-alerter/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+)
+
+// fakeSender — отправитель для тестов, возвращающий заданную ошибку и запоминающий батчи.
+type fakeSender struct {
+	err  error
+	sent [][]models.Metrics
+}
+
+// SendBatch запоминает батч или возвращает заданную ошибку.
+func (f *fakeSender) SendBatch(metrics []models.Metrics) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, metrics)
+	return nil
+}
+
+// rejectingSender — отправитель для тестов, отклоняющий батч с метрикой reject.
+type rejectingSender struct {
+	reject string
+	err    error
+}
+
+// SendBatch возвращает err для батча с метрикой reject.
+func (r *rejectingSender) SendBatch(metrics []models.Metrics) error {
+	if metrics[0].ID == r.reject {
+		return r.err
+	}
+	return nil
+}
+
+// TestSpoolingSender проверяет сохранение батча в спул при ошибке и повторную отправку после восстановления.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestSpoolingSender(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := &fakeSender{err: errors.New("connection refused")}
+	ss := &SpoolingSender{Sender: inner, Spool: spool}
+
+	failed := []models.Metrics{{ID: "failed", MType: "gauge", Value: floatPtr(1)}}
+	if err := ss.SendBatch(failed); err == nil {
+		t.Fatal("expected error while server is unavailable")
+	}
+	if spool.Len() != 1 {
+		t.Fatalf("expected 1 spooled batch, got %d", spool.Len())
+	}
+
+	inner.err = nil
+	current := []models.Metrics{{ID: "current", MType: "gauge", Value: floatPtr(2)}}
+	if err := ss.SendBatch(current); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inner.sent) != 2 || inner.sent[0][0].ID != "current" || inner.sent[1][0].ID != "failed" {
+		t.Fatalf("unexpected sent batches: %+v", inner.sent)
+	}
+	if spool.Len() != 0 {
+		t.Fatalf("expected empty spool, got %d", spool.Len())
+	}
+}
+
+// TestSpoolingSender_Rejected проверяет, что отклонённый сервером батч не попадает в спул,
+// а отклонённый при повторной отправке удаляется и не блокирует остальные.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestSpoolingSender_Rejected(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected := &statusError{code: http.StatusBadRequest, apiCode: models.ErrCodeInvalidMetric}
+	inner := &fakeSender{err: rejected}
+	ss := &SpoolingSender{Sender: inner, Spool: spool}
+
+	if err := ss.SendBatch([]models.Metrics{{ID: "bad", MType: "gauge"}}); !errors.As(err, new(*statusError)) {
+		t.Fatalf("expected rejection error, got %v", err)
+	}
+	if spool.Len() != 0 {
+		t.Fatalf("rejected batch must not be spooled, got %d", spool.Len())
+	}
+
+	// Батчи, попавшие в спул при недоступном сервере, а затем отклонённые при повторе.
+	if err := spool.Put([]models.Metrics{{ID: "poisoned", MType: "gauge"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := spool.Put([]models.Metrics{{ID: "good", MType: "gauge", Value: floatPtr(1)}}); err != nil {
+		t.Fatal(err)
+	}
+	ss.Sender = &rejectingSender{reject: "poisoned", err: rejected}
+	n, err := spool.Replay(ss.replaySend)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 replayed batch without error, got %d, %v", n, err)
+	}
+	if spool.Len() != 0 {
+		t.Fatalf("expected empty spool, got %d", spool.Len())
+	}
+}
+
+// TestRestySender_MaxBatchSize проверяет разбиение батча на запросы не больше MaxBatchSize метрик.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestRestySender_MaxBatchSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		total     int
+		maxSize   int
+		wantSizes []int
+	}{
+		{name: "no limit", total: 5, maxSize: 0, wantSizes: []int{5}},
+		{name: "fits in one request", total: 3, maxSize: 3, wantSizes: []int{3}},
+		{name: "split with remainder", total: 7, maxSize: 3, wantSizes: []int{3, 3, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sizes []int
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gz, err := gzip.NewReader(r.Body)
+				if err != nil {
+					t.Errorf("failed to create gzip reader: %v", err)
+					return
+				}
+				var batch []models.Metrics
+				if err := json.NewDecoder(gz).Decode(&batch); err != nil {
+					t.Errorf("failed to decode body: %v", err)
+				}
+				sizes = append(sizes, len(batch))
+			}))
+			defer ts.Close()
+
+			metrics := make([]models.Metrics, tt.total)
+			for i := range metrics {
+				metrics[i] = models.Metrics{ID: "m", MType: "gauge", Value: floatPtr(float64(i))}
+			}
+			sender := &RestySender{Client: resty.New().SetBaseURL(ts.URL), MaxBatchSize: tt.maxSize}
+			if err := sender.SendBatch(metrics); err != nil {
+				t.Fatalf("SendBatch() error = %v", err)
+			}
+			if len(sizes) != len(tt.wantSizes) {
+				t.Fatalf("requests = %v, want %v", sizes, tt.wantSizes)
+			}
+			for i := range sizes {
+				if sizes[i] != tt.wantSizes[i] {
+					t.Fatalf("requests = %v, want %v", sizes, tt.wantSizes)
+				}
+			}
+		})
+	}
+}
+
+// TestRestySender_Failover проверяет переключение на резервный сервер, когда основной отвечает 5xx.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestRestySender_Failover(t *testing.T) {
+	primaryHits, backupHits := 0, 0
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryHits++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backupHits++
+	}))
+	defer backup.Close()
+
+	sender := &RestySender{
+		Client:    resty.New(),
+		Endpoints: NewEndpoints([]string{primary.URL, backup.URL}, EndpointFailover, time.Minute),
+	}
+	batch := []models.Metrics{{ID: "m", MType: "gauge", Value: floatPtr(1)}}
+	for i := 0; i < 2; i++ {
+		if err := sender.SendBatch(batch); err != nil {
+			t.Fatalf("SendBatch() error = %v", err)
+		}
+	}
+	// Второй батч уходит сразу на резервный сервер: основной отложен на время cooldown.
+	if primaryHits != 1 || backupHits != 2 {
+		t.Fatalf("hits: primary=%d backup=%d, want 1 and 2", primaryHits, backupHits)
+	}
+}
+
+// TestRestySender_CircuitBreaker проверяет, что после ошибок подряд отправки не доходят до сервера,
+// а ответы, отклоняющие данные, размыкатель не размыкают.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestRestySender_CircuitBreaker(t *testing.T) {
+	hits := 0
+	code := http.StatusBadRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(code)
+	}))
+	defer ts.Close()
+
+	sender := &RestySender{
+		Client:  resty.New().SetBaseURL(ts.URL),
+		Breaker: NewCircuitBreaker(2, time.Minute),
+	}
+	batch := []models.Metrics{{ID: "m", MType: "gauge", Value: floatPtr(1)}}
+	for i := 0; i < 3; i++ {
+		if err := sender.SendBatch(batch); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("SendBatch() error = %v, want rejected batch", err)
+		}
+	}
+
+	code = http.StatusInternalServerError
+	for i := 0; i < 2; i++ {
+		if err := sender.SendBatch(batch); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("SendBatch() error = %v, want server error", err)
+		}
+	}
+	if err := sender.SendBatch(batch); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("SendBatch() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if hits != 5 {
+		t.Fatalf("hits = %d, want 5", hits)
+	}
+}
+
+// TestRestySender_CompressionDictionary проверяет переход на сжатие словарём после регистрации
+// и возврат к gzip, если сервер забыл словарь.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestRestySender_CompressionDictionary(t *testing.T) {
+	h := handler.NewHandler(repository.NewMemStorage(), nil)
+	h.SetDictionaries(compression.NewStore(4))
+	var encodings []string
+	r := chi.NewRouter()
+	decompress := service.DecompressRequest(0, h.DecryptBody)
+	r.Post("/updates/", func(w http.ResponseWriter, req *http.Request) {
+		encodings = append(encodings, req.Header.Get("Content-Encoding"))
+		decompress(http.HandlerFunc(h.HandlerUpdateBatchJSON)).ServeHTTP(w, req)
+	})
+	r.Put(compression.DictionaryPath+"{id}", h.HandleRegisterDictionary)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	sender := &RestySender{Client: resty.New().SetBaseURL(ts.URL), Dict: NewDictionary(DictionaryRetrain)}
+	batch := []models.Metrics{{ID: "Alloc", MType: "gauge", Value: floatPtr(1)}}
+	for i := 0; i < 2; i++ {
+		if err := sender.SendBatch(batch); err != nil {
+			t.Fatalf("SendBatch() error = %v", err)
+		}
+	}
+
+	// Сервер перезапущен и словаря не знает: батч повторяется с gzip, словарь регистрируется заново.
+	h.SetDictionaries(compression.NewStore(4))
+	for i := 0; i < 2; i++ {
+		if err := sender.SendBatch(batch); err != nil {
+			t.Fatalf("SendBatch() error = %v", err)
+		}
+	}
+	want := []string{"gzip", compression.Encoding, compression.Encoding, "gzip", compression.Encoding}
+	if strings.Join(encodings, ",") != strings.Join(want, ",") {
+		t.Fatalf("encodings = %v, want %v", encodings, want)
+	}
+}
+
+// TestRestySender_SignKey проверяет, что сервер с ключом проверки Ed25519 принимает батчи,
+// подписанные парным закрытым ключом, и отклоняет неподписанные.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestRestySender_SignKey(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.NewHandler(repository.NewMemStorage(), nil)
+	h.SetVerifyKey(pub)
+	ts := httptest.NewServer(service.DecompressRequest(0, h.DecryptBody)(http.HandlerFunc(h.HandlerUpdateBatchJSON)))
+	defer ts.Close()
+
+	tests := []struct {
+		name    string             // Название теста
+		signKey ed25519.PrivateKey // Ключ подписи агента
+		wantErr bool               // Ожидается ли ошибка отправки
+	}{
+		{name: "signed", signKey: priv},
+		{name: "unsigned", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &RestySender{Client: resty.New().SetBaseURL(ts.URL), SignKey: tt.signKey}
+			err := sender.SendBatch([]models.Metrics{{ID: "m", MType: "gauge", Value: floatPtr(1)}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var se *statusError
+			if tt.wantErr && (!errors.As(err, &se) || se.apiCode != models.ErrCodeInvalidSignature) {
+				t.Fatalf("SendBatch() error = %v, want %s", err, models.ErrCodeInvalidSignature)
+			}
+		})
+	}
+}
+
+// TestRestySender_RetryHints проверяет, что агент повторяет только ошибки, помеченные
+// сервером как временные, и не повторяет ошибки валидации.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestRestySender_RetryHints(t *testing.T) {
+	tests := []struct {
+		name     string // Название теста
+		status   int    // Код статуса первого ответа
+		code     string // Код ошибки первого ответа
+		wantErr  bool   // Ожидается ли ошибка отправки
+		wantHits int    // Ожидаемое число запросов к серверу
+	}{
+		{"validation not retried", http.StatusBadRequest, models.ErrCodeInvalidMetric, true, 1},
+		{"transient retried", http.StatusInternalServerError, models.ErrCodeStorageFailed, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits++
+				if hits == 1 {
+					handler.WriteError(w, r, tt.status, tt.code, "failed")
+				}
+			}))
+			defer ts.Close()
+
+			sender := &RestySender{Client: resty.New().SetBaseURL(ts.URL)}
+			err := sender.SendBatch([]models.Metrics{{ID: "m", MType: "gauge", Value: floatPtr(1)}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if hits != tt.wantHits {
+				t.Fatalf("hits = %d, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+// TestParseRetryAfter проверяет разбор заголовка Retry-After.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string        // Название теста
+		value string        // Значение заголовка
+		want  time.Duration // Ожидаемая пауза
+	}{
+		{"empty", "", 0},
+		{"seconds", "3", 3 * time.Second},
+		{"negative", "-1", 0},
+		{"http date", now.Add(5 * time.Second).Format(http.TimeFormat), 5 * time.Second},
+		{"past date", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"garbage", "soon", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNewStatusError проверяет разбор кода ошибки из ответа сервера.
+//
+// t — указатель на структуру тестирования *testing.T.
+func TestNewStatusError(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode string
+		wantText string
+	}{
+		{
+			name:     "structured error",
+			body:     `{"code":"invalid_signature","message":"invalid signature"}`,
+			wantCode: "invalid_signature",
+			wantText: "unexpected status: 400 invalid_signature: invalid signature",
+		},
+		{name: "plain text", body: "bad request", wantText: "unexpected status: 400"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			se := newStatusError(http.StatusBadRequest, []byte(tt.body))
+			if se.apiCode != tt.wantCode {
+				t.Errorf("apiCode = %q, want %q", se.apiCode, tt.wantCode)
+			}
+			if se.Error() != tt.wantText {
+				t.Errorf("Error() = %q, want %q", se.Error(), tt.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// Формат имени файла батча в спуле.
+const (
+	spoolFilePrefix = "batch-"
+	spoolFileSuffix = ".json"
+)
+
+// ErrBatchRejected помечает ошибку отправки, после которой батч не имеет смысла повторять
+// (сервер отклонил сами данные). Такой батч удаляется из спула при Replay.
+var ErrBatchRejected = errors.New("batch rejected by server")
+
+// Spool — дисковая очередь батчей метрик, которые не удалось отправить.
+//
+// Батчи хранятся в отдельных файлах и повторно отправляются после восстановления связи
+// с сервером. Размер спула ограничен maxBytes, а батчи старше maxAge удаляются.
+//
+// Поля:
+//   - dir: каталог спула
+//   - maxBytes: максимальный суммарный размер файлов (0 — без ограничения)
+//   - maxAge: максимальный возраст батча (0 — без ограничения)
+//   - now: функция получения текущего времени
+//   - cursor: номер последнего записанного батча; новые номера всегда больше
+//   - mu: мьютекс файловых операций спула; не удерживается во время отправки
+//   - replayMu: мьютекс, исключающий параллельную повторную отправку одних и тех же батчей
+type Spool struct {
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+	now      func() time.Time
+	cursor   int64
+	mu       sync.Mutex
+	replayMu sync.Mutex
+}
+
+// spoolEntry описывает файл батча в спуле.
+type spoolEntry struct {
+	path    string
+	created time.Time
+	size    int64
+}
+
+// NewSpool создаёт спул в каталоге dir, создавая каталог при необходимости.
+//
+// maxBytes — максимальный суммарный размер спула в байтах (0 — без ограничения).
+// maxAge — максимальный возраст батча (0 — без ограничения).
+func NewSpool(dir string, maxBytes int64, maxAge time.Duration) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Spool{dir: dir, maxBytes: maxBytes, maxAge: maxAge, now: time.Now}, nil
+}
+
+// Put сохраняет батч в спул и удаляет устаревшие батчи и самые старые батчи сверх лимита размера.
+func (s *Spool) Put(batch []models.Metrics) error {
+	data, err := models.MetricsBatch(batch).MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Имя файла задаёт порядок отправки; номер не меньше курсора, чтобы порядок
+	// сохранялся при переводе часов назад, а при совпадении берём следующий свободный.
+	ns := s.now().UnixNano()
+	if ns <= s.cursor {
+		ns = s.cursor + 1
+	}
+	path := filepath.Join(s.dir, spoolFilePrefix+strconv.FormatInt(ns, 10)+spoolFileSuffix)
+	for {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		ns++
+		path = filepath.Join(s.dir, spoolFilePrefix+strconv.FormatInt(ns, 10)+spoolFileSuffix)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".spool-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	s.cursor = ns
+	return s.evict()
+}
+
+// Cursor возвращает номер последнего записанного в спул батча.
+func (s *Spool) Cursor() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cursor
+}
+
+// SetCursor восстанавливает курсор, сохранённый до перезапуска агента.
+//
+// Меньшее значение, чем текущий курсор, игнорируется.
+func (s *Spool) SetCursor(cursor int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cursor > s.cursor {
+		s.cursor = cursor
+	}
+}
+
+// Replay отправляет батчи из спула от самого старого к самому новому.
+//
+// Успешно отправленный батч удаляется. Батч, отправка которого завершилась ошибкой
+// ErrBatchRejected, также удаляется, чтобы не блокировать остальные; при любой другой
+// ошибке отправка прекращается, а оставшиеся батчи остаются в спуле до следующей попытки.
+// Во время отправки спул не блокируется: Put из других воркеров не ждёт сети.
+//
+// Возвращает количество отправленных батчей и ошибку отправки.
+func (s *Spool) Replay(send func([]models.Metrics) error) (int, error) {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+
+	s.mu.Lock()
+	err := s.evict()
+	var entries []spoolEntry
+	if err == nil {
+		entries, err = s.list()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, e := range entries {
+		data, err := os.ReadFile(e.path)
+		if os.IsNotExist(err) {
+			// Батч удалён evict из Put, пока шла отправка предыдущих.
+			continue
+		}
+		if err != nil {
+			return sent, err
+		}
+		var batch models.MetricsBatch
+		if err := batch.UnmarshalJSON(data); err != nil {
+			// Повреждённый батч не может быть отправлен: удаляем, чтобы не блокировать очередь.
+			_ = s.remove(e.path)
+			continue
+		}
+		if err := send(batch); err != nil {
+			if !errors.Is(err, ErrBatchRejected) {
+				return sent, err
+			}
+			log.Printf("Dropping spooled batch %s: %v", filepath.Base(e.path), err)
+			if err := s.remove(e.path); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		if err := s.remove(e.path); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// remove удаляет файл батча; уже удалённый файл ошибкой не считается.
+func (s *Spool) remove(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Len возвращает количество батчей в спуле.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, _ := s.list()
+	return len(entries)
+}
+
+// evict удаляет батчи старше maxAge и самые старые батчи, пока размер спула превышает maxBytes.
+func (s *Spool) evict() error {
+	entries, err := s.list()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	kept := entries[:0]
+	for _, e := range entries {
+		if s.maxAge > 0 && s.now().Sub(e.created) > s.maxAge {
+			if err := os.Remove(e.path); err != nil {
+				return err
+			}
+			continue
+		}
+		total += e.size
+		kept = append(kept, e)
+	}
+	for i := 0; s.maxBytes > 0 && total > s.maxBytes && i < len(kept); i++ {
+		if err := os.Remove(kept[i].path); err != nil {
+			return err
+		}
+		total -= kept[i].size
+	}
+	return nil
+}
+
+// list возвращает батчи спула от самого старого к самому новому.
+func (s *Spool) list() ([]spoolEntry, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []spoolEntry
+	for _, de := range dirEntries {
+		name := de.Name()
+		if de.IsDir() || !strings.HasPrefix(name, spoolFilePrefix) || !strings.HasSuffix(name, spoolFileSuffix) {
+			continue
+		}
+		ns, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, spoolFilePrefix), spoolFileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat spool file %s: %w", name, err)
+		}
+		entries = append(entries, spoolEntry{
+			path:    filepath.Join(s.dir, name),
+			created: time.Unix(0, ns),
+			size:    info.Size(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].created.Before(entries[j].created) })
+	return entries, nil
+}
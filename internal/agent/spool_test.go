@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+// gaugeBatch возвращает батч из одной gauge-метрики.
+func gaugeBatch(id string, v float64) []models.Metrics {
+	return []models.Metrics{{ID: id, MType: "gauge", Value: &v}}
+}
+
+// TestSpool_PutReplay проверяет порядок повторной отправки и сохранение батчей при ошибке.
+//
+// t — указатель на структуру теста.
+func TestSpool_PutReplay(t *testing.T) {
+	s, err := NewSpool(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, s.Put(gaugeBatch(id, 1)))
+	}
+	require.Equal(t, 3, s.Len())
+
+	var sent []string
+	calls := 0
+	sendErr := errors.New("server unavailable")
+	n, err := s.Replay(func(batch []models.Metrics) error {
+		calls++
+		if calls == 2 {
+			return sendErr
+		}
+		sent = append(sent, batch[0].ID)
+		return nil
+	})
+	require.ErrorIs(t, err, sendErr)
+	require.Equal(t, 1, n)
+	require.Equal(t, []string{"a"}, sent)
+	require.Equal(t, 2, s.Len())
+
+	n, err = s.Replay(func(batch []models.Metrics) error {
+		sent = append(sent, batch[0].ID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []string{"a", "b", "c"}, sent)
+	require.Zero(t, s.Len())
+}
+
+// TestSpool_Eviction проверяет удаление батчей по возрасту и по размеру спула.
+//
+// t — указатель на структуру теста.
+func TestSpool_Eviction(t *testing.T) {
+	t.Run("max age", func(t *testing.T) {
+		s, err := NewSpool(t.TempDir(), 0, time.Hour)
+		require.NoError(t, err)
+		now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		s.now = func() time.Time { return now }
+		require.NoError(t, s.Put(gaugeBatch("old", 1)))
+
+		now = now.Add(2 * time.Hour)
+		require.NoError(t, s.Put(gaugeBatch("new", 1)))
+		require.Equal(t, 1, s.Len())
+	})
+
+	t.Run("max size", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewSpool(dir, 0, 0)
+		require.NoError(t, err)
+		require.NoError(t, s.Put(gaugeBatch("first", 1)))
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		info, err := entries[0].Info()
+		require.NoError(t, err)
+
+		s.maxBytes = 2 * info.Size()
+		require.NoError(t, s.Put(gaugeBatch("secnd", 1)))
+		require.NoError(t, s.Put(gaugeBatch("third", 1)))
+		require.Equal(t, 2, s.Len())
+
+		var ids []string
+		_, err = s.Replay(func(batch []models.Metrics) error {
+			ids = append(ids, batch[0].ID)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"secnd", "third"}, ids)
+	})
+}
+
+// TestSpool_Cursor проверяет сохранение порядка батчей после перезапуска с переводом часов назад.
+//
+// t — указатель на структуру теста.
+func TestSpool_Cursor(t *testing.T) {
+	dir := t.TempDir()
+	before, err := NewSpool(dir, 0, 0)
+	require.NoError(t, err)
+	before.now = func() time.Time { return time.Unix(2000, 0) }
+	require.NoError(t, before.Put(gaugeBatch("old", 1)))
+	cursor := before.Cursor()
+	require.Equal(t, time.Unix(2000, 0).UnixNano(), cursor)
+
+	// После перезапуска часы отстают, но курсор восстановлен из состояния агента.
+	after, err := NewSpool(dir, 0, 0)
+	require.NoError(t, err)
+	after.now = func() time.Time { return time.Unix(1000, 0) }
+	after.SetCursor(cursor)
+	after.SetCursor(cursor - 5)
+	require.NoError(t, after.Put(gaugeBatch("new", 2)))
+	require.Greater(t, after.Cursor(), cursor)
+
+	var sent []string
+	_, err = after.Replay(func(batch []models.Metrics) error {
+		sent = append(sent, batch[0].ID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"old", "new"}, sent)
+}
+
+// TestSpool_ReplayUnlocked проверяет, что во время отправки спул доступен для записи.
+//
+// t — указатель на структуру теста.
+func TestSpool_ReplayUnlocked(t *testing.T) {
+	s, err := NewSpool(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Put(gaugeBatch("a", 1)))
+
+	n, err := s.Replay(func(batch []models.Metrics) error {
+		done := make(chan error, 1)
+		go func() { done <- s.Put(gaugeBatch("b", 1)) }()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			return errors.New("Put blocked while replaying")
+		}
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, 1, s.Len())
+}
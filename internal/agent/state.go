@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State — состояние агента, сохраняемое между перезапусками.
+//
+// Поля:
+//   - PollCount: значение счётчика опросов PollCount
+//   - SpoolCursor: номер последнего батча, записанного в спул (см. Spool.Cursor)
+//   - LastSend: время последней успешной отправки
+type State struct {
+	PollCount   int64     `json:"poll_count"`
+	SpoolCursor int64     `json:"spool_cursor,omitempty"`
+	LastSend    time.Time `json:"last_send,omitempty"`
+}
+
+// LoadState читает состояние агента из файла path.
+//
+// Отсутствующий файл не считается ошибкой: возвращается пустое состояние.
+func LoadState(path string) (State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return State{}, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	return st, nil
+}
+
+// SaveState атомарно записывает состояние агента в файл path.
+func SaveState(path string, st State) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic записывает data во временный файл рядом с path с правами 0600
+// и переименовывает его в path, создавая каталог при необходимости.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSaveLoadState проверяет сохранение состояния агента и загрузку при отсутствии файла.
+func TestSaveLoadState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "agent-state.json")
+
+	st, err := LoadState(path)
+	require.NoError(t, err)
+	require.Equal(t, State{}, st)
+
+	want := State{PollCount: 42, SpoolCursor: 7, LastSend: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	require.NoError(t, SaveState(path, want))
+
+	got, err := LoadState(path)
+	require.NoError(t, err)
+	require.Equal(t, want.PollCount, got.PollCount)
+	require.Equal(t, want.SpoolCursor, got.SpoolCursor)
+	require.True(t, want.LastSend.Equal(got.LastSend))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0600))
+	_, err = LoadState(path)
+	require.Error(t, err)
+}
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// statsdMaxPacket — максимальный размер UDP-пакета StatsD.
+const statsdMaxPacket = 65535
+
+// statsdInvalidLines — counter-метрика с количеством отброшенных строк StatsD.
+const statsdInvalidLines = "StatsDInvalidLines"
+
+// StatsD разбирает метрики, полученные по протоколу StatsD, и учитывает их в агрегаторе.
+//
+// Поддерживаются типы c (counter), g (gauge, в том числе относительные +N/-N)
+// и ms/h (таймеры).
+type StatsD struct {
+	agg *Aggregator
+}
+
+// NewStatsD создаёт приёмник StatsD, учитывающий метрики в agg.
+func NewStatsD(agg *Aggregator) *StatsD {
+	return &StatsD{agg: agg}
+}
+
+// Handle разбирает пакет StatsD (одна или несколько строк "name:value|type[|@rate]")
+// и учитывает значения в агрегаторе.
+//
+// Некорректные строки пропускаются и учитываются в счётчике StatsDInvalidLines;
+// возвращается первая ошибка разбора.
+func (s *StatsD) Handle(packet []byte) error {
+	var firstErr error
+	for _, line := range strings.Split(string(packet), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := s.handleLine(line); err != nil {
+			_ = s.agg.AddCounter(statsdInvalidLines, 1)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// handleLine разбирает одну строку StatsD.
+func (s *StatsD) handleLine(line string) error {
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return fmt.Errorf("statsd: invalid line %q", line)
+	}
+	fields := strings.Split(rest, "|")
+	if len(fields) < 2 {
+		return fmt.Errorf("statsd: missing type in %q", line)
+	}
+	raw, kind := fields[0], fields[1]
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("statsd: invalid value in %q", line)
+	}
+	rate := 1.0
+	for _, f := range fields[2:] {
+		if !strings.HasPrefix(f, "@") {
+			continue // теги (#...) не поддерживаются и игнорируются
+		}
+		rate, err = strconv.ParseFloat(f[1:], 64)
+		if err != nil || rate <= 0 || rate > 1 {
+			return fmt.Errorf("statsd: invalid sample rate in %q", line)
+		}
+	}
+
+	switch kind {
+	case "c":
+		if err := s.agg.AddCounter(name, int64(math.Round(value/rate))); err != nil {
+			return fmt.Errorf("statsd: %w", err)
+		}
+	case "g":
+		if raw[0] == '+' || raw[0] == '-' {
+			s.agg.AddGauge(name, value)
+		} else {
+			s.agg.SetGauge(name, value)
+		}
+	case "ms", "h":
+		s.agg.ObserveTimer(name, value)
+	default:
+		return fmt.Errorf("statsd: unsupported metric type %q in %q", kind, line)
+	}
+	return nil
+}
+
+// Serve читает пакеты StatsD из conn и учитывает их в агрегаторе до закрытия соединения.
+//
+// Возвращает nil, если conn был закрыт, иначе ошибку чтения.
+func (s *StatsD) Serve(conn net.PacketConn) error {
+	buf := make([]byte, statsdMaxPacket)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		// Ошибки разбора учитываются в StatsDInvalidLines и не прерывают приём.
+		_ = s.Handle(buf[:n])
+	}
+}
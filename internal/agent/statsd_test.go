@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestStatsD_Handle проверяет разбор и агрегацию строк StatsD.
+func TestStatsD_Handle(t *testing.T) {
+	tests := []struct {
+		name    string
+		packets []string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{
+			name:    "counters are summed",
+			packets: []string{"hits:1|c", "hits:2|c\nhits:3|c"},
+			want:    map[string]float64{"hits": 6},
+		},
+		{
+			name:    "sample rate scales counter",
+			packets: []string{"hits:1|c|@0.1"},
+			want:    map[string]float64{"hits": 10},
+		},
+		{
+			name:    "gauge absolute and relative",
+			packets: []string{"temp:10|g", "temp:+5|g", "temp:-3|g"},
+			want:    map[string]float64{"temp": 12},
+		},
+		{
+			name:    "timers",
+			packets: []string{"rt:10|ms\nrt:30|ms|#env:prod", "rt:20|h"},
+			want:    map[string]float64{"rt_count": 3, "rt_min": 10, "rt_max": 30, "rt_mean": 20},
+		},
+		{
+			name:    "invalid lines are counted",
+			packets: []string{"hits:1|c\nbad\nset:1|s\n:1|c\nx:abc|g\ny:1|c|@2"},
+			want:    map[string]float64{"hits": 1, "StatsDInvalidLines": 5},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg := NewAggregator()
+			s := NewStatsD(agg)
+			var err error
+			for _, p := range tt.packets {
+				if e := s.Handle([]byte(p)); e != nil {
+					err = e
+				}
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.want, flushMap(agg.Flush()))
+		})
+	}
+}
+
+// TestStatsD_Serve проверяет приём пакетов по UDP и завершение при закрытии соединения.
+func TestStatsD_Serve(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	agg := NewAggregator()
+	s := NewStatsD(agg)
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(conn) }()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("hits:4|c"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		agg.mu.Lock()
+		defer agg.mu.Unlock()
+		return agg.counters["hits"] == 4
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, conn.Close())
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,384 @@
+package app
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/agent"
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/crypto"
+	"github.com/RoGogDBD/metric-alerter/internal/tracing"
+	"github.com/RoGogDBD/metric-alerter/internal/version"
+)
+
+// parseAgentFlags разбирает аргументы args, переменные окружения и JSON-конфиг агента.
+//
+// Возвращает конфигурацию агента или ошибку некорректной конфигурации.
+func parseAgentFlags(args []string) (agent.Config, error) {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	addr := config.AddressListFlag(fs)
+	configFileFlag := fs.String(config.FlagConfig, "", "Path to JSON config file")
+	versionFlag := fs.Bool(config.FlagVersion, false, "Print build information and exit")
+	configTraceFlag := fs.Bool(config.FlagConfigTrace, false, "Print where each configuration value comes from (env, flag, json or default) and exit")
+	selfTestFlag := fs.Bool(config.FlagSelfTest, false, "Run an in-process end-to-end check (server, signed encrypted batch, read-back, snapshot restore) and exit")
+	poll := fs.Int(config.FlagPollInterval, 2, "Poll interval in seconds")
+	report := fs.Int(config.FlagReportInterval, 10, "Report interval in seconds")
+	key := fs.String(config.FlagKey, "", "Key for signing requests")
+	limit := fs.Int(config.FlagRateLimit, 1, "Rate limit (max concurrent outgoing requests)")
+	maxRPS := fs.Int(config.FlagMaxRPS, 0, "Maximum outgoing requests per second across all workers (0 disables)")
+	cryptoKey := fs.String(config.FlagCryptoKey, "", "Path to public key for asymmetric encryption")
+	signKey := fs.String(config.FlagSignKey, "", "Path to Ed25519 private key for signing requests")
+	grpcAddress := fs.String(config.FlagGRPCAddress, "", "gRPC server address")
+	spoolDir := fs.String(config.FlagSpoolDir, "", "Directory for batches that failed to send (empty disables spooling)")
+	spoolMaxSize := fs.Int(config.FlagSpoolMaxSize, config.DefaultSpoolMaxSize, "Maximum spool size in bytes")
+	spoolMaxAge := fs.Int(config.FlagSpoolMaxAge, config.DefaultSpoolMaxAge, "Maximum age of a spooled batch in seconds")
+	shutdownTimeout := fs.Int(config.FlagShutdownTimeout, config.DefaultShutdownTimeout, "Time to wait for in-flight sends on shutdown in seconds")
+	enrollToken := fs.String(config.FlagEnrollToken, "", "One-time token to enroll with the server and obtain a per-agent key")
+	credentialsFile := fs.String(config.FlagCredentialsFile, config.DefaultCredentialsFile, "File to store enrollment credentials")
+	queueSize := fs.Int(config.FlagQueueSize, config.DefaultQueueSize, "Send queue capacity in batches")
+	queuePolicy := fs.String(config.FlagQueuePolicy, config.DefaultQueuePolicy, "Send queue overflow policy: drop-oldest or block")
+	endpointPolicy := fs.String(config.FlagEndpointPolicy, config.DefaultEndpointPolicy, "Server selection policy for multiple addresses: failover or round-robin")
+	endpointCooldown := fs.Int(config.FlagEndpointCooldown, config.DefaultEndpointCooldown, "Time to skip a failed server in seconds")
+	maxBatchSize := fs.Int(config.FlagMaxBatchSize, 0, "Maximum number of metrics per request; larger batches are split (0 disables)")
+	apiKey := fs.String(config.FlagAPIKey, "", "API key with the writer role for role-based access")
+	collect := fs.String(config.FlagCollect, "", "Comma-separated optional collectors to enable: disk, net, self")
+	netInclude := fs.String(config.FlagNetInclude, "", "Comma-separated network interface patterns to collect (empty collects all)")
+	stateFile := fs.String(config.FlagStateFile, "", "File to persist PollCount and send state across restarts (empty disables)")
+	netExclude := fs.String(config.FlagNetExclude, "", "Comma-separated network interface patterns to skip")
+	statsdAddress := fs.String(config.FlagStatsDAddress, "", "UDP address to receive StatsD metrics on, e.g. :8125 (empty disables)")
+	cpuMode := fs.String(config.FlagCPUMode, config.DefaultCPUMode, "CPU utilization report: per-core (CPUutilization1..N) or total (single CPUutilization)")
+	breakerThreshold := fs.Int(config.FlagBreakerThreshold, config.DefaultBreakerThreshold, "Consecutive send failures before the circuit breaker opens (0 disables)")
+	breakerCooldown := fs.Int(config.FlagBreakerCooldown, config.DefaultBreakerCooldown, "Time between probe sends while the circuit breaker is open in seconds")
+	compressionDict := fs.Bool(config.FlagCompressionDict, false, "Compress batches with a dictionary trained on previous batches when the server supports it")
+	http2 := fs.Bool(config.FlagHTTP2, false, "Send over HTTP/2 (h2c for http:// servers) so concurrent batches share one connection; the server must support it")
+	sendChangedOnly := fs.Bool(config.FlagSendChangedOnly, false, "Skip gauges whose value has not changed since the last successful report")
+	collectIntervals := fs.String(config.FlagCollectIntervals, "", "Per-group poll intervals, e.g. runtime=2s,disk=60s (groups: runtime, system, disk, net, self)")
+	pushAddress := fs.String(config.FlagPushAddress, "", "Local address for the POST /push metrics API, e.g. 127.0.0.1:8126 (empty disables)")
+	queueTimeout := fs.Int(config.FlagQueueTimeout, config.DefaultQueueTimeout, "Time to wait for queue space with the block policy in seconds")
+	tlsCA := fs.String(config.FlagTLSCA, "", "Path to CA bundle (PEM) trusted for https:// servers (empty uses system roots)")
+	dataDir := fs.String(config.FlagDataDir, "", "Directory for relative data paths: credentials, state file, spool (empty uses the working directory)")
+
+	fs.Usage = config.AgentOptions.Usage("agent", fs)
+	_ = fs.Parse(args)
+
+	if *versionFlag {
+		fmt.Print(version.Get())
+		os.Exit(0)
+	}
+	if *selfTestFlag {
+		if err := SelfTest(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	// Параметры, заданные окружением или флагом, JSON-конфиг не перезаписывает.
+	explicit := config.AgentOptions.Explicit(fs, os.LookupEnv)
+
+	if envPoll, err := config.EnvInt(config.EnvPollInterval); err == nil && envPoll != 0 {
+		*poll = envPoll
+	}
+	if envReport, err := config.EnvInt(config.EnvReportInterval); err == nil && envReport != 0 {
+		*report = envReport
+	}
+	if envLimit, err := config.EnvInt(config.EnvRateLimit); err == nil && envLimit != 0 {
+		*limit = envLimit
+	}
+	if envRPS, err := config.EnvInt(config.EnvMaxRPS); err == nil && envRPS != 0 {
+		*maxRPS = envRPS
+	}
+
+	if envKey := config.EnvString(config.EnvKey); envKey != "" {
+		*key = envKey
+	}
+	if envCrypto := config.EnvString(config.EnvCryptoKey); envCrypto != "" {
+		*cryptoKey = envCrypto
+	}
+	if envSignKey := config.EnvString(config.EnvSignKey); envSignKey != "" {
+		*signKey = envSignKey
+	}
+	if envDataDir := config.EnvString(config.EnvDataDir); envDataDir != "" {
+		*dataDir = envDataDir
+	}
+	if envTLSCA := config.EnvString(config.EnvTLSCA); envTLSCA != "" {
+		*tlsCA = envTLSCA
+	}
+	if envGRPC := config.EnvString(config.EnvGRPCAddress); envGRPC != "" {
+		*grpcAddress = envGRPC
+	}
+	if envSpoolDir := config.EnvString(config.EnvSpoolDir); envSpoolDir != "" {
+		*spoolDir = envSpoolDir
+	}
+	if envSpoolSize, err := config.EnvInt(config.EnvSpoolMaxSize); err == nil && envSpoolSize != 0 {
+		*spoolMaxSize = envSpoolSize
+	}
+	if envSpoolAge, err := config.EnvInt(config.EnvSpoolMaxAge); err == nil && envSpoolAge != 0 {
+		*spoolMaxAge = envSpoolAge
+	}
+	if envShutdown, err := config.EnvInt(config.EnvShutdownTimeout); err == nil && envShutdown != 0 {
+		*shutdownTimeout = envShutdown
+	}
+	if envToken := config.EnvString(config.EnvEnrollToken); envToken != "" {
+		*enrollToken = envToken
+	}
+	if envCreds := config.EnvString(config.EnvCredentialsFile); envCreds != "" {
+		*credentialsFile = envCreds
+	}
+	if envQueueSize, err := config.EnvInt(config.EnvQueueSize); err == nil && envQueueSize != 0 {
+		*queueSize = envQueueSize
+	}
+	if envQueuePolicy := config.EnvString(config.EnvQueuePolicy); envQueuePolicy != "" {
+		*queuePolicy = envQueuePolicy
+	}
+	if envQueueTimeout, err := config.EnvInt(config.EnvQueueTimeout); err == nil && envQueueTimeout != 0 {
+		*queueTimeout = envQueueTimeout
+	}
+	if envAPIKey := config.EnvString(config.EnvAPIKey); envAPIKey != "" {
+		*apiKey = envAPIKey
+	}
+	if envMaxBatch, err := config.EnvInt(config.EnvMaxBatchSize); err == nil && envMaxBatch != 0 {
+		*maxBatchSize = envMaxBatch
+	}
+	if envPolicy := config.EnvString(config.EnvEndpointPolicy); envPolicy != "" {
+		*endpointPolicy = envPolicy
+	}
+	if envCooldown, err := config.EnvInt(config.EnvEndpointCooldown); err == nil && envCooldown != 0 {
+		*endpointCooldown = envCooldown
+	}
+	if envCollect := config.EnvString(config.EnvCollect); envCollect != "" {
+		*collect = envCollect
+	}
+	if envNetInclude := config.EnvString(config.EnvNetInclude); envNetInclude != "" {
+		*netInclude = envNetInclude
+	}
+	if envNetExclude := config.EnvString(config.EnvNetExclude); envNetExclude != "" {
+		*netExclude = envNetExclude
+	}
+	if envStateFile := config.EnvString(config.EnvStateFile); envStateFile != "" {
+		*stateFile = envStateFile
+	}
+	if envStatsD := config.EnvString(config.EnvStatsDAddress); envStatsD != "" {
+		*statsdAddress = envStatsD
+	}
+	if envPush := config.EnvString(config.EnvPushAddress); envPush != "" {
+		*pushAddress = envPush
+	}
+	if envIntervals := config.EnvString(config.EnvCollectIntervals); envIntervals != "" {
+		*collectIntervals = envIntervals
+	}
+	if envChanged := config.EnvString(config.EnvSendChangedOnly); envChanged != "" {
+		*sendChangedOnly = envChanged == "true"
+	}
+	if envDict := config.EnvString(config.EnvCompressionDict); envDict != "" {
+		*compressionDict = envDict == "true"
+	}
+	if envHTTP2 := config.EnvString(config.EnvHTTP2); envHTTP2 != "" {
+		*http2 = envHTTP2 == "true"
+	}
+	if envCPUMode := config.EnvString(config.EnvCPUMode); envCPUMode != "" {
+		*cpuMode = envCPUMode
+	}
+	if envThreshold, err := config.EnvInt(config.EnvBreakerThreshold); err == nil && envThreshold != 0 {
+		*breakerThreshold = envThreshold
+	}
+	if envBreakerCooldown, err := config.EnvInt(config.EnvBreakerCooldown); err == nil && envBreakerCooldown != 0 {
+		*breakerCooldown = envBreakerCooldown
+	}
+
+	var tlsCfg config.TLSConfig
+	tracingCfg := config.DefaultTracingConfig()
+	var fromJSON []string
+	configFilePath := config.GetConfigFilePathWithFlag(*configFileFlag)
+	if configFilePath != "" {
+		jsonConfig, err := config.LoadAgentJSONConfig(configFilePath)
+		if err != nil {
+			log.Printf("Warning: failed to load JSON config: %v", err)
+		} else if jsonConfig != nil {
+			fromJSON = jsonConfig.ApplyToAgent(config.AgentTargets{
+				Address:          addr,
+				PollInterval:     poll,
+				ReportInterval:   report,
+				RateLimit:        limit,
+				Key:              key,
+				CryptoKey:        cryptoKey,
+				SignKey:          signKey,
+				GRPCAddress:      grpcAddress,
+				SpoolDir:         spoolDir,
+				SpoolMaxSize:     spoolMaxSize,
+				SpoolMaxAge:      spoolMaxAge,
+				ShutdownTimeout:  shutdownTimeout,
+				EnrollToken:      enrollToken,
+				CredentialsFile:  credentialsFile,
+				QueueSize:        queueSize,
+				QueuePolicy:      queuePolicy,
+				QueueTimeout:     queueTimeout,
+				APIKey:           apiKey,
+				MaxBatchSize:     maxBatchSize,
+				EndpointPolicy:   endpointPolicy,
+				EndpointCooldown: endpointCooldown,
+				Collect:          collect,
+				NetInclude:       netInclude,
+				NetExclude:       netExclude,
+				StateFile:        stateFile,
+				StatsDAddress:    statsdAddress,
+				PushAddress:      pushAddress,
+				CollectIntervals: collectIntervals,
+				SendChangedOnly:  sendChangedOnly,
+				CPUMode:          cpuMode,
+				BreakerThreshold: breakerThreshold,
+				BreakerCooldown:  breakerCooldown,
+				CompressionDict:  compressionDict,
+				HTTP2:            http2,
+				MaxRPS:           maxRPS,
+				TLS:              &tlsCfg,
+				TLSCA:            tlsCA,
+				DataDir:          dataDir,
+				Tracing:          &tracingCfg,
+			}, explicit)
+		}
+	}
+	if *configTraceFlag {
+		config.AgentOptions.TraceAndExit(fs, configFilePath, fromJSON)
+	}
+
+	if err := config.EnvServer(addr, config.EnvAddress); err != nil {
+		return agent.Config{}, fmt.Errorf("failed to apply env override: %w", err)
+	}
+	if *dataDir != "" {
+		if err := os.MkdirAll(*dataDir, 0o755); err != nil {
+			return agent.Config{}, fmt.Errorf("failed to create data dir: %w", err)
+		}
+	}
+	servers := make([]string, len(*addr))
+	for i := range *addr {
+		servers[i] = (*addr)[i].URL()
+	}
+
+	var publicKey *rsa.PublicKey
+	if *cryptoKey != "" {
+		var err error
+		publicKey, err = crypto.LoadPublicKey(*cryptoKey)
+		if err != nil {
+			return agent.Config{}, fmt.Errorf("failed to load public key: %w", err)
+		}
+	}
+
+	var signingKey ed25519.PrivateKey
+	if *signKey != "" {
+		var err error
+		signingKey, err = crypto.LoadSigningKey(*signKey)
+		if err != nil {
+			return agent.Config{}, fmt.Errorf("failed to load signing key: %w", err)
+		}
+	}
+
+	return agent.Config{
+		Servers:          servers,
+		PollInterval:     *poll,
+		ReportInterval:   *report,
+		RateLimit:        *limit,
+		MaxRPS:           *maxRPS,
+		Key:              *key,
+		CryptoKey:        publicKey,
+		SignKey:          signingKey,
+		TLS:              tlsCfg,
+		TLSCA:            *tlsCA,
+		GRPCAddress:      *grpcAddress,
+		SpoolDir:         config.ResolvePath(*dataDir, *spoolDir),
+		SpoolMaxSize:     *spoolMaxSize,
+		SpoolMaxAge:      *spoolMaxAge,
+		ShutdownTimeout:  *shutdownTimeout,
+		EnrollToken:      *enrollToken,
+		CredentialsFile:  config.ResolvePath(*dataDir, *credentialsFile),
+		QueueSize:        *queueSize,
+		QueuePolicy:      *queuePolicy,
+		QueueTimeout:     *queueTimeout,
+		APIKey:           *apiKey,
+		MaxBatchSize:     *maxBatchSize,
+		EndpointPolicy:   *endpointPolicy,
+		EndpointCooldown: *endpointCooldown,
+		Collect:          *collect,
+		NetInclude:       *netInclude,
+		NetExclude:       *netExclude,
+		StateFile:        config.ResolvePath(*dataDir, *stateFile),
+		StatsDAddress:    *statsdAddress,
+		PushAddress:      *pushAddress,
+		CollectIntervals: *collectIntervals,
+		SendChangedOnly:  *sendChangedOnly,
+		CPUMode:          *cpuMode,
+		BreakerThreshold: *breakerThreshold,
+		BreakerCooldown:  *breakerCooldown,
+		CompressionDict:  *compressionDict,
+		HTTP2:            *http2,
+		Tracing:          tracingCfg,
+	}, nil
+}
+
+// Agent разбирает конфигурацию агента из аргументов args, окружения и JSON-конфига, собирает
+// агента и запускает его до сигнала завершения.
+func Agent(args []string) error {
+	cfg, err := parseAgentFlags(args)
+	if err != nil {
+		return err
+	}
+	fmt.Print(version.Get())
+
+	fmt.Println("Server URL", strings.Join(cfg.Servers, ","))
+	fmt.Println("Report interval", cfg.ReportInterval)
+	fmt.Println("Poll interval", cfg.PollInterval)
+
+	// В контейнере с квотой CPU GOMAXPROCS не должен превышать ограничение.
+	if limit, ok := agent.NewCgroupCPU().Limit(); ok {
+		if n, ok := agent.AdjustMaxProcs(limit); ok {
+			log.Printf("GOMAXPROCS set to %d to match the container CPU limit", n)
+		}
+	}
+
+	// Трассировка OpenTelemetry: спаны отправки батчей экспортируются по OTLP, а контекст
+	// трассировки передаётся серверу в заголовках запросов.
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, tracing.ServiceAgent)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Printf("Failed to flush traces: %v", err)
+		}
+	}()
+
+	runner, err := agent.NewRunner(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Запуск pprof-сервера для профилирования.
+	go func() {
+		log.Println("pprof http server listening on :6060")
+		if err := http.ListenAndServe("localhost:6060", nil); err != nil {
+			log.Printf("pprof server failed: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	defer stop()
+
+	log.Println("Agent started. Waiting for signals...")
+	if err := runner.Run(ctx); err != nil {
+		return err
+	}
+	log.Println("Agent shutdown complete")
+	return nil
+}
@@ -0,0 +1,58 @@
+// Package app содержит точки входа агента и сервера и вспомогательные команды.
+//
+// Команды используются как отдельными бинарниками cmd/agent и cmd/server, так и единым
+// бинарником cmd/metric-alerter с подкомандами agent, server, keygen, migrate и migrate-storage.
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Command — подкоманда, принимающая аргументы командной строки без своего имени.
+type Command func(args []string) error
+
+// Commands — подкоманды единого бинарника по именам.
+var Commands = map[string]Command{
+	"agent":           Agent,
+	"server":          Server,
+	"keygen":          Keygen,
+	"migrate":         Migrate,
+	"migrate-storage": MigrateStorage,
+}
+
+// ErrUnknownCommand возвращается Run для отсутствующей или неизвестной подкоманды.
+var ErrUnknownCommand = errors.New("unknown command")
+
+// Run выполняет подкоманду args[0] с аргументами args[1:].
+//
+// Для отсутствующей или неизвестной подкоманды выводит справку в w и возвращает ErrUnknownCommand.
+func Run(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		Usage(w)
+		return ErrUnknownCommand
+	}
+	cmd, ok := Commands[args[0]]
+	if !ok {
+		Usage(w)
+		return fmt.Errorf("%w %q", ErrUnknownCommand, args[0])
+	}
+	return cmd(args[1:])
+}
+
+// Usage выводит в w список подкоманд единого бинарника.
+func Usage(w io.Writer) {
+	names := make([]string, 0, len(Commands))
+	for name := range Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "Usage: metric-alerter <command> [flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", name)
+	}
+	fmt.Fprintln(w, "\nRun 'metric-alerter <command> -h' for command flags.")
+}
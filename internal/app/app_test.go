@@ -0,0 +1,126 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRun_UnknownCommand проверяет справку и ошибку для отсутствующей или неизвестной подкоманды.
+//
+// t — указатель на структуру теста.
+func TestRun_UnknownCommand(t *testing.T) {
+	tests := []struct {
+		name string   // Название теста
+		args []string // Аргументы командной строки
+	}{
+		{name: "Missing"},
+		{name: "Unknown", args: []string{"proxy"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Run(&buf, tc.args)
+			require.ErrorIs(t, err, ErrUnknownCommand)
+			for name := range Commands {
+				require.Contains(t, buf.String(), name)
+			}
+		})
+	}
+}
+
+// TestKeygen проверяет, что созданные ключи читаются сервером и агентом и образуют пару.
+//
+// t — указатель на структуру теста.
+func TestKeygen(t *testing.T) {
+	tests := []struct {
+		name       string   // Название теста
+		args       []string // Дополнительные флаги keygen
+		passphrase string   // Содержимое файла пароля (пусто — без шифрования)
+		wantErr    bool     // Ожидается ошибка
+	}{
+		{name: "RSA", args: []string{"-bits", "1024"}},
+		{name: "RSAPassphrase", args: []string{"-bits", "1024"}, passphrase: "s3cret\n"},
+		{name: "Ed25519", args: []string{"-type", "ed25519"}},
+		{name: "InvalidType", args: []string{"-type", "dsa"}, wantErr: true},
+		{name: "APIKeyInvalidScope", args: []string{"-type", "api-key", "-scopes", "root"}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			privatePath := filepath.Join(dir, "private.pem")
+			publicPath := filepath.Join(dir, "public.pem")
+			args := append([]string{"keygen", "-private", privatePath, "-public", publicPath}, tc.args...)
+			if tc.passphrase != "" {
+				passphraseFile := filepath.Join(dir, "passphrase")
+				require.NoError(t, os.WriteFile(passphraseFile, []byte(tc.passphrase), 0o600))
+				args = append(args, "-passphrase-file", passphraseFile)
+			}
+
+			err := Run(&bytes.Buffer{}, args)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			if tc.name == "Ed25519" {
+				_, err := crypto.LoadPublicKey(publicPath)
+				require.ErrorContains(t, err, "not an RSA public key", "Ed25519 keys are PKIX-encoded but not usable for encryption")
+				return
+			}
+
+			priv, err := crypto.LoadPrivateKey(privatePath)
+			if tc.passphrase != "" {
+				require.Error(t, err, "encrypted key must require a passphrase")
+				_, err = crypto.LoadPrivateKeyWithPassphrase(privatePath, []byte("wrong"))
+				require.Error(t, err)
+				priv, err = crypto.LoadPrivateKeyWithPassphrase(privatePath, []byte("s3cret"))
+			}
+			require.NoError(t, err)
+			pub, err := crypto.LoadPublicKey(publicPath)
+			require.NoError(t, err)
+
+			encrypted, err := crypto.EncryptData([]byte("metrics"), pub)
+			require.NoError(t, err)
+			decrypted, err := crypto.DecryptData(encrypted, priv)
+			require.NoError(t, err)
+			require.Equal(t, "metrics", string(decrypted))
+		})
+	}
+}
+
+// TestMigrate_NoDSN проверяет ошибку при незаданной строке подключения.
+//
+// t — указатель на структуру теста.
+func TestMigrate_NoDSN(t *testing.T) {
+	t.Setenv(config.EnvDatabaseDSN, "")
+	t.Setenv(config.EnvConfig, "")
+	require.Error(t, Migrate(nil))
+}
+
+// TestMigrateStorage_MissingDSN проверяет ошибку при незаданном исходном или целевом хранилище.
+//
+// t — указатель на структуру теста.
+func TestMigrateStorage_MissingDSN(t *testing.T) {
+	require.ErrorContains(t, MigrateStorage(nil), "-from and -to")
+	require.ErrorContains(t, MigrateStorage([]string{"-from", "file://metrics.json"}), "-from and -to")
+}
+
+// TestSelfTest проверяет, что самопроверка проходит все шаги и сообщает о каждом.
+//
+// t — указатель на структуру теста.
+func TestSelfTest(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, SelfTest(&buf))
+	for _, step := range []string{"send signed encrypted batch", "read back metrics", "restore snapshot"} {
+		require.Contains(t, buf.String(), "selftest: "+step+": ok")
+	}
+}
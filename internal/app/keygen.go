@@ -0,0 +1,110 @@
+package app
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/RoGogDBD/metric-alerter/internal/crypto"
+)
+
+// Типы ключей, создаваемых Keygen.
+const (
+	KeyTypeRSA     = "rsa"
+	KeyTypeEd25519 = "ed25519"
+	KeyTypeAPIKey  = "api-key"
+)
+
+// Keygen создаёт пару ключей в формате PEM.
+//
+// Ключи RSA предназначены для асимметричного шифрования: приватный ключ (PKCS#1) передаётся
+// серверу, публичный (PKIX) — агенту через -crypto-key. Ключи Ed25519 (PKCS#8 и PKIX)
+// подходят только для подписи и не принимаются параметром -crypto-key.
+// Тип api-key печатает случайный API-ключ и запись для -api-keys-file с его хешем.
+func Keygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	keyType := fs.String("type", KeyTypeRSA, "Key type: rsa, ed25519 or api-key")
+	bits := fs.Int("bits", 4096, "RSA key size in bits")
+	privatePath := fs.String("private", "private.pem", "Output path for the private key")
+	publicPath := fs.String("public", "public.pem", "Output path for the public key")
+	passphraseFile := fs.String("passphrase-file", "", "File with a passphrase to encrypt the private key (AES-256, empty leaves it unencrypted)")
+	keyName := fs.String("name", "default", "API key name (-type api-key)")
+	scopes := fs.String("scopes", string(auth.ScopeWriteMetrics), "Comma-separated API key scopes: read-metrics, write-metrics, admin (-type api-key)")
+	_ = fs.Parse(args)
+
+	if *keyType == KeyTypeAPIKey {
+		return generateAPIKey(*keyName, *scopes)
+	}
+
+	var passphrase []byte
+	if *passphraseFile != "" {
+		data, err := os.ReadFile(*passphraseFile)
+		if err != nil {
+			return fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+		passphrase = bytes.TrimRight(data, "\r\n")
+		if len(passphrase) == 0 {
+			return fmt.Errorf("passphrase file %s is empty", *passphraseFile)
+		}
+	}
+
+	var private, public any
+	switch *keyType {
+	case KeyTypeRSA:
+		key, err := rsa.GenerateKey(rand.Reader, *bits)
+		if err != nil {
+			return fmt.Errorf("failed to generate key: %w", err)
+		}
+		private, public = key, &key.PublicKey
+	case KeyTypeEd25519:
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return fmt.Errorf("failed to generate key: %w", err)
+		}
+		private, public = key, pub
+	default:
+		return fmt.Errorf("invalid key type %q (want %q, %q or %q)", *keyType, KeyTypeRSA, KeyTypeEd25519, KeyTypeAPIKey)
+	}
+
+	if err := crypto.SavePrivateKey(*privatePath, private, passphrase); err != nil {
+		return err
+	}
+	if err := crypto.SavePublicKey(*publicPath, public); err != nil {
+		return err
+	}
+	fmt.Printf("Private key written to %s\nPublic key written to %s\n", *privatePath, *publicPath)
+	return nil
+}
+
+// generateAPIKey печатает случайный API-ключ и запись с его хешем для файла -api-keys-file.
+//
+// Сам ключ нигде не сохраняется: его нужно передать клиенту, а в файл добавить только запись.
+func generateAPIKey(name, scopes string) error {
+	entry := auth.HashedKey{Name: name}
+	for _, sc := range strings.Split(scopes, ",") {
+		if _, err := auth.ParseScope(sc); err != nil {
+			return err
+		}
+		entry.Scopes = append(entry.Scopes, strings.TrimSpace(sc))
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key := base64.RawURLEncoding.EncodeToString(raw)
+	entry.Hash = auth.HashKey(key)
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("API key: %s\nEntry for -api-keys-file: %s\n", key, data)
+	return nil
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/config/db"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+)
+
+// Migrate применяет миграции схемы к базе данных PostgreSQL сервера.
+//
+// DSN задаётся так же, как для сервера: -d, DATABASE_DSN или database_dsn в JSON-конфиге.
+func Migrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	configFileFlag := fs.String(config.FlagConfig, "", "Path to JSON config file")
+	dsnFlag := fs.String(config.FlagDatabaseDSN, "", "PostgreSQL DSN")
+	dir := fs.String("dir", "", "Directory with migration files (empty uses the migrations built into the binary)")
+	_ = fs.Parse(args)
+
+	dsn := repository.GetEnvOrFlagString(config.EnvDatabaseDSN, *dsnFlag)
+	if dsn == "" {
+		if path := config.GetConfigFilePathWithFlag(*configFileFlag); path != "" {
+			jsonConfig, err := config.LoadServerJSONConfig(path)
+			if err != nil {
+				log.Printf("Warning: failed to load JSON config: %v", err)
+			} else if jsonConfig != nil {
+				dsn = jsonConfig.DatabaseDSN
+			}
+		}
+	}
+	if dsn == "" {
+		return errors.New("database DSN is not set (use -d, DATABASE_DSN or database_dsn in the config file)")
+	}
+	return db.RunMigrationsFrom(dsn, *dir)
+}
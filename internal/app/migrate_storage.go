@@ -0,0 +1,38 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+)
+
+// MigrateStorage переносит метрики между хранилищами сервера, например из файла снимка
+// в PostgreSQL или из PostgreSQL в Redis, и проверяет результат.
+//
+// Хранилища задаются DSN в -from и -to (file://путь для файла снимка). Ход переноса
+// выводится в лог после каждого пакета.
+func MigrateStorage(args []string) error {
+	fs := flag.NewFlagSet("migrate-storage", flag.ExitOnError)
+	from := fs.String("from", "", "Source storage DSN (file://path, postgres://, redis:// or sqlite://)")
+	to := fs.String("to", "", "Destination storage DSN (file://path, postgres://, redis:// or sqlite://)")
+	batch := fs.Int("batch", repository.DefaultMigrateBatchSize, "Metrics written per batch")
+	_ = fs.Parse(args)
+
+	if *from == "" || *to == "" {
+		return errors.New("both -from and -to storage DSNs are required")
+	}
+	report, err := repository.MigrateStorage(context.Background(), *from, *to, repository.MigrateOptions{
+		BatchSize: *batch,
+		Progress: func(done, total int) {
+			log.Printf("Migrated %d/%d metrics", done, total)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Migration verified: %d gauges, %d counters in %d batches", report.Gauges, report.Counters, report.Batches)
+	return nil
+}
@@ -0,0 +1,199 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/agent"
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/crypto"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/server"
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+)
+
+// selfTestTimeout ограничивает продолжительность самопроверки.
+const selfTestTimeout = 30 * time.Second
+
+// Метрики, которые самопроверка отправляет и ожидает прочитать обратно.
+const (
+	selfTestGauge   = "SelfTestGauge"
+	selfTestCounter = "SelfTestCounter"
+)
+
+// SelfTest выполняет сквозную проверку в одном процессе: запускает временный сервер,
+// отправляет ему подписанный и зашифрованный батч отправителем агента, читает метрики обратно,
+// сохраняет снимок при остановке и восстанавливает его во втором сервере.
+//
+// Файлы создаются во временной директории и удаляются после проверки. Ход проверки
+// выводится в w. Возвращает ошибку первого неудачного шага.
+func SelfTest(w io.Writer) error {
+	dir, err := os.MkdirTemp("", "metric-alerter-selftest-")
+	if err != nil {
+		return fmt.Errorf("selftest: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), selfTestTimeout)
+	defer cancel()
+
+	step := func(name string, fn func() error) error {
+		if err := fn(); err != nil {
+			fmt.Fprintf(w, "selftest: %s: FAIL\n", name)
+			return fmt.Errorf("selftest: %s: %w", name, err)
+		}
+		fmt.Fprintf(w, "selftest: %s: ok\n", name)
+		return nil
+	}
+
+	var (
+		privateKey *rsa.PrivateKey
+		key        string
+	)
+	cfg := server.Config{
+		Address:      "127.0.0.1:0",
+		StoreFile:    "metrics.json",
+		CryptoKey:    filepath.Join(dir, "private.pem"),
+		DataDir:      dir,
+		LogDir:       dir,
+		Logger:       zap.NewNop(),
+		MaxBodySize:  config.DefaultMaxBodySize,
+		MaxGzipRatio: config.DefaultMaxGzipRatio,
+	}
+	if err := step("generate keys", func() error {
+		if privateKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			return err
+		}
+		secret := make([]byte, 16)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		key = hex.EncodeToString(secret)
+		cfg.Key = key
+		return crypto.SavePrivateKey(cfg.CryptoKey, privateKey, nil)
+	}); err != nil {
+		return err
+	}
+
+	var (
+		srv  *server.Server
+		done = make(chan error, 1)
+	)
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	if err := step("start server", func() error {
+		if srv, err = server.New(cfg); err != nil {
+			return err
+		}
+		go func() { done <- srv.Run(runCtx) }()
+		return nil
+	}); err != nil {
+		return err
+	}
+	baseURL := "http://" + srv.Addr()
+
+	gauge, counter := 1.5, int64(42)
+	if err := step("send signed encrypted batch", func() error {
+		sender := &agent.RestySender{
+			Client:    resty.New().SetBaseURL(baseURL).SetTimeout(5 * time.Second),
+			Key:       key,
+			CryptoKey: &privateKey.PublicKey,
+		}
+		return sender.SendBatch([]models.Metrics{
+			{ID: selfTestGauge, MType: models.Gauge, Value: &gauge},
+			{ID: selfTestCounter, MType: models.Counter, Delta: &counter},
+		})
+	}); err != nil {
+		return err
+	}
+
+	if err := step("read back metrics", func() error {
+		got, err := selfTestValue(ctx, baseURL, models.Metrics{ID: selfTestGauge, MType: models.Gauge})
+		if err != nil {
+			return err
+		}
+		if got.Value == nil || *got.Value != gauge {
+			return fmt.Errorf("gauge %s = %v, want %v", selfTestGauge, got.Value, gauge)
+		}
+		got, err = selfTestValue(ctx, baseURL, models.Metrics{ID: selfTestCounter, MType: models.Counter})
+		if err != nil {
+			return err
+		}
+		if got.Delta == nil || *got.Delta != counter {
+			return fmt.Errorf("counter %s = %v, want %d", selfTestCounter, got.Delta, counter)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := step("write snapshot", func() error {
+		stop()
+		select {
+		case err := <-done:
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		_, err := os.Stat(filepath.Join(dir, cfg.StoreFile))
+		return err
+	}); err != nil {
+		return err
+	}
+
+	return step("restore snapshot", func() error {
+		restoreCfg := cfg
+		restoreCfg.Restore = true
+		restored, err := server.New(restoreCfg)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = restored.Close() }()
+		if v, ok := restored.Storage().GetGauge(selfTestGauge); !ok || v != gauge {
+			return fmt.Errorf("restored gauge %s = %v, want %v", selfTestGauge, v, gauge)
+		}
+		if v, ok := restored.Storage().GetCounter(selfTestCounter); !ok || v != counter {
+			return fmt.Errorf("restored counter %s = %d, want %d", selfTestCounter, v, counter)
+		}
+		return nil
+	})
+}
+
+// selfTestValue запрашивает значение метрики m через POST /value.
+func selfTestValue(ctx context.Context, baseURL string, m models.Metrics) (models.Metrics, error) {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return m, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/value", bytes.NewReader(body))
+	if err != nil {
+		return m, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return m, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return m, fmt.Errorf("POST /value %s: unexpected status %d", m.ID, resp.StatusCode)
+	}
+	var got models.Metrics
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		return m, fmt.Errorf("POST /value %s: invalid response: %w", m.ID, err)
+	}
+	return got, nil
+}
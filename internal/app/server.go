@@ -0,0 +1,304 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/RoGogDBD/metric-alerter/internal/server"
+	"github.com/RoGogDBD/metric-alerter/internal/version"
+)
+
+// Server разбирает конфигурацию сервера из аргументов args, окружения и JSON-конфига, создаёт
+// сервер и обслуживает запросы до сигнала завершения.
+func Server(args []string) error {
+	// Определение флагов командной строки.
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	versionFlag := fs.Bool(config.FlagVersion, false, "Print build information and exit")
+	configTraceFlag := fs.Bool(config.FlagConfigTrace, false, "Print where each configuration value comes from (env, flag, json or default) and exit")
+	selfTestFlag := fs.Bool(config.FlagSelfTest, false, "Run an in-process end-to-end check (server, signed encrypted batch, read-back, snapshot restore) and exit")
+	configFileFlag := fs.String(config.FlagConfig, "", "Path to JSON config file")
+	dsnFlag := fs.String(config.FlagDatabaseDSN, "", "Storage DSN (memory://, postgres://, redis://, sqlite://; empty uses in-memory storage)")
+	storeIntervalFlag := fs.Int(config.FlagStoreInterval, 300, "Store interval in seconds")
+	fileStorageFlag := fs.String(config.FlagStoreFile, "metrics.json", "File storage path")
+	restoreFlag := fs.Bool(config.FlagRestore, true, "Restore metrics from file at startup")
+	keyFlag := fs.String(config.FlagKey, "", "Key for request signing verification")
+	cryptoKeyFlag := fs.String(config.FlagCryptoKey, "", "Path to private key for asymmetric decryption")
+	verifyKeyFlag := fs.String(config.FlagVerifyKey, "", "Path to Ed25519 public key for verifying agent request signatures")
+	auditFileFlag := fs.String(config.FlagAuditFile, "", "Path to audit log file")
+	auditURLFlag := fs.String(config.FlagAuditURL, "", "URL for remote audit server")
+	trustedSubnetFlag := fs.String(config.FlagTrustedSubnet, "", "Trusted subnet in CIDR format")
+	grpcAddressFlag := fs.String(config.FlagGRPCAddress, "", "gRPC server address")
+	snapshotFsyncFlag := fs.Bool(config.FlagSnapshotFsync, false, "Fsync metrics snapshot to disk on every save")
+	storageShardsFlag := fs.Int(config.FlagStorageShards, 0, "Number of in-memory storage shards (0 or 1 uses a single map)")
+	normalizeIDsFlag := fs.Bool(config.FlagNormalizeIDs, false, "Normalize metric IDs (trim spaces, lowercase) at ingestion")
+	pageRefreshFlag := fs.Int(config.FlagPageRefresh, 0, "Metrics page auto-refresh interval in seconds (0 disables)")
+	cspFlag := fs.String(config.FlagCSP, config.DefaultContentSecurityPolicy, "Content-Security-Policy for HTML pages")
+	enrollTokensFlag := fs.String(config.FlagEnrollTokens, "", "Comma-separated one-time agent enrollment tokens")
+	agentsFileFlag := fs.String(config.FlagAgentsFile, "", "Path to enrolled agents registry file (empty keeps it in memory)")
+	apiKeysFlag := fs.String(config.FlagAPIKeys, "", "Comma-separated API keys with roles (key:admin,key:writer,key:reader)")
+	jwtSecretFlag := fs.String(config.FlagJWTSecret, "", "HS256 secret for JWTs carrying role and exp claims")
+	apiKeysFileFlag := fs.String(config.FlagAPIKeysFile, "", "Path to JSON file of hashed API keys with scopes (read-metrics, write-metrics, admin)")
+	apiKeysDBFlag := fs.Bool(config.FlagAPIKeysDB, false, "Load hashed API keys with scopes from the api_keys table of the -d database")
+	adminAddressFlag := fs.String(config.FlagAdminAddress, config.DefaultAdminAddress, "Admin listener address for /admin/*, /status and pprof (empty serves /admin/* on the main listeners)")
+	adminTokenFlag := fs.String(config.FlagAdminToken, "", "Bearer token required on the admin listener (empty uses the admin role)")
+	requestIDFormatFlag := fs.String(config.FlagRequestIDFormat, config.DefaultRequestIDFormat, "Request and audit event ID format: ulid or uuidv7")
+	listenFlag := fs.String(config.FlagListen, "", "Comma-separated listeners addr[=ingest+read+admin]; replaces -a when set")
+	walFileFlag := fs.String(config.FlagWALFile, "", "Path to write-ahead log file (empty disables WAL)")
+	watchdogFlag := fs.Int(config.FlagWatchdog, 0, "Leak watchdog sampling interval in seconds (0 disables)")
+	tlsCertFlag := fs.String(config.FlagTLSCert, "", "Path to TLS certificate (PEM); with -tls-key serves HTTPS and TLS gRPC")
+	tlsKeyFlag := fs.String(config.FlagTLSKey, "", "Path to TLS private key (PEM)")
+	tlsRedirectFlag := fs.String(config.FlagTLSRedirect, "", "Plain HTTP address that redirects to HTTPS, e.g. :80 (empty disables)")
+	dataDirFlag := fs.String(config.FlagDataDir, "", "Directory for relative data paths: snapshot, WAL, audit, agents registry, backups (empty uses the working directory)")
+	logDirFlag := fs.String(config.FlagLogDir, "", "Directory for app.log (empty uses logs under -data-dir)")
+	deadLetterFileFlag := fs.String(config.FlagDeadLetterFile, "", "File for audit events that could not be delivered after all retries (empty drops them)")
+	compressLevelFlag := fs.Int(config.FlagCompressLevel, config.DefaultCompressionLevel, "Response compression level 1-9 (0 uses the default, -1 disables compression)")
+	compressExcludeFlag := fs.String(config.FlagCompressExclude, "/ping", "Comma-separated paths whose responses are never compressed; a trailing * matches a prefix")
+	rateLimitRPSFlag := fs.Int(config.FlagRateLimitRPS, 0, "Requests per second allowed per client (API key or IP); 0 disables rate limiting")
+	rateLimitBurstFlag := fs.Int(config.FlagRateLimitBurst, 0, "Burst of requests allowed per client above the rate (0 uses the rate)")
+	maxBodySizeFlag := fs.Int(config.FlagMaxBodySize, config.DefaultMaxBodySize, "Maximum request body size in bytes (0 disables the limit)")
+	maxGzipRatioFlag := fs.Int(config.FlagMaxGzipRatio, config.DefaultMaxGzipRatio, "Maximum expansion ratio of gzip request bodies (0 disables the check)")
+	streamBatchFlag := fs.Int(config.FlagStreamBatch, 0, "Apply unsigned /updates/ batches while decoding, this many metrics at a time (0 decodes the whole batch first)")
+	metricTTLFlag := fs.Int(config.FlagMetricTTL, 0, "Seconds without updates after which a metric is expired (0 keeps metrics forever)")
+	metricTTLActionFlag := fs.String(config.FlagMetricTTLAction, config.MetricTTLDelete, "What to do with expired metrics: delete, or flag (audit event and log only)")
+	reusePortFlag := fs.Bool(config.FlagReusePort, false, "Bind listeners with SO_REUSEPORT so a new server process can take over the ports before the old one drains")
+	h2cFlag := fs.Bool(config.FlagH2C, false, "Accept HTTP/2 without TLS (h2c, prior knowledge) on plain HTTP listeners")
+	auditQueueSizeFlag := fs.Int(config.FlagAuditQueueSize, config.DefaultAuditQueueSize, "Audit events buffered for asynchronous delivery (0 delivers synchronously within the request)")
+	auditWorkersFlag := fs.Int(config.FlagAuditWorkers, config.DefaultAuditQueueWorkers, "Goroutines delivering audit events from the queue")
+	auditQueuePolicyFlag := fs.String(config.FlagAuditQueuePolicy, config.AuditQueueBlock, "What to do when the audit queue is full: block the request, or drop the event")
+	auditRetriesFlag := fs.Int(config.FlagAuditRetries, 3, "Delivery attempts per audit observer before an event goes to the dead letter file")
+	addr := config.AddressFlag(fs)
+	fs.Usage = config.ServerOptions.Usage("server", fs)
+	_ = fs.Parse(args)
+
+	if *versionFlag {
+		fmt.Print(version.Get())
+		return nil
+	}
+	if *selfTestFlag {
+		return SelfTest(os.Stdout)
+	}
+	if !*configTraceFlag {
+		fmt.Print(version.Get())
+	}
+
+	// Получение базовых значений (Приоритет: ENV > Flag).
+	dsn := repository.GetEnvOrFlagString(config.EnvDatabaseDSN, *dsnFlag)
+	storeInterval := repository.GetEnvOrFlagInt(config.EnvStoreInterval, *storeIntervalFlag)
+	fileStoragePath := repository.GetEnvOrFlagString(config.EnvStoreFile, *fileStorageFlag)
+	restore := repository.GetEnvOrFlagBool(config.EnvRestore, *restoreFlag)
+	key := repository.GetEnvOrFlagString(config.EnvKey, *keyFlag)
+	cryptoKeyPath := repository.GetEnvOrFlagString(config.EnvCryptoKey, *cryptoKeyFlag)
+	verifyKeyPath := repository.GetEnvOrFlagString(config.EnvVerifyKey, *verifyKeyFlag)
+	auditFile := repository.GetEnvOrFlagString(config.EnvAuditFile, *auditFileFlag)
+	auditURL := repository.GetEnvOrFlagString(config.EnvAuditURL, *auditURLFlag)
+	trustedSubnet := repository.GetEnvOrFlagString(config.EnvTrustedSubnet, *trustedSubnetFlag)
+	grpcAddress := repository.GetEnvOrFlagString(config.EnvGRPCAddress, *grpcAddressFlag)
+	snapshotFsync := repository.GetEnvOrFlagBool(config.EnvSnapshotFsync, *snapshotFsyncFlag)
+	walFile := repository.GetEnvOrFlagString(config.EnvWALFile, *walFileFlag)
+	storageShards := repository.GetEnvOrFlagInt(config.EnvStorageShards, *storageShardsFlag)
+	normalizeIDs := repository.GetEnvOrFlagBool(config.EnvNormalizeIDs, *normalizeIDsFlag)
+	securityCfg := config.DefaultSecurityHeadersConfig()
+	securityCfg.ContentSecurityPolicy = repository.GetEnvOrFlagString(config.EnvCSP, *cspFlag)
+	backupCfg := config.DefaultBackupConfig()
+	s3Cfg := config.DefaultS3Config()
+	tracingCfg := config.DefaultTracingConfig()
+	loggerCfg := config.DefaultLoggerConfig()
+	pageRefreshCfg := config.DefaultPageRefreshConfig()
+	pageRefreshCfg.Interval = time.Duration(repository.GetEnvOrFlagInt(config.EnvPageRefresh, *pageRefreshFlag)) * time.Second
+	enrollTokens := repository.GetEnvOrFlagString(config.EnvEnrollTokens, *enrollTokensFlag)
+	agentsFile := repository.GetEnvOrFlagString(config.EnvAgentsFile, *agentsFileFlag)
+	authCfg := config.AuthConfig{
+		JWTSecret:   repository.GetEnvOrFlagString(config.EnvJWTSecret, *jwtSecretFlag),
+		APIKeysFile: repository.GetEnvOrFlagString(config.EnvAPIKeysFile, *apiKeysFileFlag),
+		APIKeysDB:   repository.GetEnvOrFlagBool(config.EnvAPIKeysDB, *apiKeysDBFlag),
+	}
+	var err error
+	authCfg.APIKeys, err = config.ParseAPIKeys(repository.GetEnvOrFlagString(config.EnvAPIKeys, *apiKeysFlag))
+	if err != nil {
+		return err
+	}
+	listeners, err := config.ParseListeners(repository.GetEnvOrFlagString(config.EnvListen, *listenFlag))
+	if err != nil {
+		return err
+	}
+	adminAddress := repository.GetEnvOrFlagString(config.EnvAdminAddress, *adminAddressFlag)
+	adminToken := repository.GetEnvOrFlagString(config.EnvAdminToken, *adminTokenFlag)
+	requestIDFormat := repository.GetEnvOrFlagString(config.EnvRequestIDFormat, *requestIDFormatFlag)
+	var observers []config.ObserverConfig
+	var storageCfg config.StorageConfig
+	var tlsCfg config.TLSConfig
+	tlsCert := repository.GetEnvOrFlagString(config.EnvTLSCert, *tlsCertFlag)
+	tlsKey := repository.GetEnvOrFlagString(config.EnvTLSKey, *tlsKeyFlag)
+	tlsRedirect := repository.GetEnvOrFlagString(config.EnvTLSRedirect, *tlsRedirectFlag)
+	dataDir := repository.GetEnvOrFlagString(config.EnvDataDir, *dataDirFlag)
+	logDir := repository.GetEnvOrFlagString(config.EnvLogDir, *logDirFlag)
+	deadLetterFile := repository.GetEnvOrFlagString(config.EnvDeadLetterFile, *deadLetterFileFlag)
+	auditRetries := repository.GetEnvOrFlagInt(config.EnvAuditRetries, *auditRetriesFlag)
+	rateLimitRPS := repository.GetEnvOrFlagInt(config.EnvRateLimitRPS, *rateLimitRPSFlag)
+	rateLimitBurst := repository.GetEnvOrFlagInt(config.EnvRateLimitBurst, *rateLimitBurstFlag)
+	maxBodySize := repository.GetEnvOrFlagInt(config.EnvMaxBodySize, *maxBodySizeFlag)
+	maxGzipRatio := repository.GetEnvOrFlagInt(config.EnvMaxGzipRatio, *maxGzipRatioFlag)
+	streamBatch := repository.GetEnvOrFlagInt(config.EnvStreamBatch, *streamBatchFlag)
+	compressionCfg := config.CompressionConfig{
+		Level:   repository.GetEnvOrFlagInt(config.EnvCompressLevel, *compressLevelFlag),
+		Exclude: config.ParseCompressionExclude(repository.GetEnvOrFlagString(config.EnvCompressExclude, *compressExcludeFlag)),
+	}
+	watchdogCfg := config.DefaultWatchdogConfig()
+	watchdogCfg.Interval = time.Duration(repository.GetEnvOrFlagInt(config.EnvWatchdog, *watchdogFlag)) * time.Second
+	metricTTLCfg := config.MetricTTLConfig{
+		TTL:    time.Duration(repository.GetEnvOrFlagInt(config.EnvMetricTTL, *metricTTLFlag)) * time.Second,
+		Action: repository.GetEnvOrFlagString(config.EnvMetricTTLAction, *metricTTLActionFlag),
+	}
+	reusePort := repository.GetEnvOrFlagBool(config.EnvReusePort, *reusePortFlag)
+	h2c := repository.GetEnvOrFlagBool(config.EnvH2C, *h2cFlag)
+	auditQueueCfg := config.AuditQueueConfig{
+		Size:    repository.GetEnvOrFlagInt(config.EnvAuditQueueSize, *auditQueueSizeFlag),
+		Workers: repository.GetEnvOrFlagInt(config.EnvAuditWorkers, *auditWorkersFlag),
+		Policy:  repository.GetEnvOrFlagString(config.EnvAuditQueuePolicy, *auditQueuePolicyFlag),
+	}
+	var auditRotationCfg config.AuditRotationConfig
+	var auditFilters []config.AuditFilterRule
+
+	// Загрузка JSON конфигурации и применение к параметрам (низший приоритет).
+	var fromJSON []string
+	configFilePath := config.GetConfigFilePathWithFlag(*configFileFlag)
+	if configFilePath != "" {
+		jsonConfig, err := config.LoadServerJSONConfig(configFilePath)
+		if err != nil {
+			log.Printf("Warning: failed to load JSON config: %v", err)
+		} else if jsonConfig != nil {
+			fromJSON = jsonConfig.ApplyToServer(config.ServerTargets{
+				Address:         addr,
+				DatabaseDSN:     &dsn,
+				StoreInterval:   &storeInterval,
+				StoreFile:       &fileStoragePath,
+				Restore:         &restore,
+				Key:             &key,
+				CryptoKey:       &cryptoKeyPath,
+				VerifyKey:       &verifyKeyPath,
+				AuditFile:       &auditFile,
+				AuditURL:        &auditURL,
+				TrustedSubnet:   &trustedSubnet,
+				GRPCAddress:     &grpcAddress,
+				SnapshotFsync:   &snapshotFsync,
+				Watchdog:        &watchdogCfg,
+				WALFile:         &walFile,
+				StorageShards:   &storageShards,
+				NormalizeIDs:    &normalizeIDs,
+				Security:        &securityCfg,
+				Backup:          &backupCfg,
+				S3:              &s3Cfg,
+				PageRefresh:     &pageRefreshCfg,
+				EnrollTokens:    &enrollTokens,
+				AgentsFile:      &agentsFile,
+				Auth:            &authCfg,
+				Listeners:       &listeners,
+				AdminAddress:    &adminAddress,
+				AdminToken:      &adminToken,
+				Observers:       &observers,
+				RequestIDFormat: &requestIDFormat,
+				Storage:         &storageCfg,
+				TLS:             &tlsCfg,
+				TLSCert:         &tlsCert,
+				TLSKey:          &tlsKey,
+				TLSRedirect:     &tlsRedirect,
+				DataDir:         &dataDir,
+				LogDir:          &logDir,
+				DeadLetterFile:  &deadLetterFile,
+				AuditRetries:    &auditRetries,
+				Compression:     &compressionCfg,
+				RateLimitRPS:    &rateLimitRPS,
+				RateLimitBurst:  &rateLimitBurst,
+				MaxBodySize:     &maxBodySize,
+				MaxGzipRatio:    &maxGzipRatio,
+				StreamBatch:     &streamBatch,
+				MetricTTL:       &metricTTLCfg,
+				Tracing:         &tracingCfg,
+				Logger:          &loggerCfg,
+				ReusePort:       &reusePort,
+				H2C:             &h2c,
+				AuditQueue:      &auditQueueCfg,
+				AuditRotation:   &auditRotationCfg,
+				AuditFilters:    &auditFilters,
+			}, config.ServerOptions.Explicit(fs, os.LookupEnv))
+		}
+	}
+	if *configTraceFlag {
+		config.ServerOptions.TraceAndExit(fs, configFilePath, fromJSON)
+	}
+
+	// Переменная окружения ADDRESS имеет наивысший приоритет.
+	if err := config.EnvServer(addr, config.EnvAddress); err != nil {
+		return err
+	}
+
+	srv, err := server.New(server.Config{
+		Address:         addr.String(),
+		Listeners:       listeners,
+		DatabaseDSN:     dsn,
+		StoreInterval:   storeInterval,
+		StoreFile:       fileStoragePath,
+		Restore:         restore,
+		Key:             key,
+		CryptoKey:       cryptoKeyPath,
+		VerifyKey:       verifyKeyPath,
+		AuditFile:       auditFile,
+		AuditURL:        auditURL,
+		TrustedSubnet:   trustedSubnet,
+		GRPCAddress:     grpcAddress,
+		SnapshotFsync:   snapshotFsync,
+		WALFile:         walFile,
+		StorageShards:   storageShards,
+		NormalizeIDs:    normalizeIDs,
+		EnrollTokens:    enrollTokens,
+		AgentsFile:      agentsFile,
+		AdminAddress:    adminAddress,
+		AdminToken:      adminToken,
+		Watchdog:        watchdogCfg,
+		Security:        securityCfg,
+		Backup:          backupCfg,
+		S3:              s3Cfg,
+		PageRefresh:     pageRefreshCfg,
+		Auth:            authCfg,
+		Observers:       observers,
+		RequestIDFormat: requestIDFormat,
+		Storage:         storageCfg,
+		TLS:             tlsCfg,
+		TLSCert:         tlsCert,
+		TLSKey:          tlsKey,
+		TLSRedirect:     tlsRedirect,
+		DataDir:         dataDir,
+		LogDir:          logDir,
+		DeadLetterFile:  deadLetterFile,
+		AuditRetries:    auditRetries,
+		Compression:     compressionCfg,
+		RateLimitRPS:    rateLimitRPS,
+		RateLimitBurst:  rateLimitBurst,
+		MaxBodySize:     maxBodySize,
+		MaxGzipRatio:    maxGzipRatio,
+		StreamBatch:     streamBatch,
+		MetricTTL:       metricTTLCfg,
+		Tracing:         tracingCfg,
+		Log:             loggerCfg,
+		ReusePort:       reusePort,
+		H2C:             h2c,
+		AuditQueue:      auditQueueCfg,
+		AuditRotation:   auditRotationCfg,
+		AuditFilters:    auditFilters,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	defer stop()
+	return srv.Run(ctx)
+}
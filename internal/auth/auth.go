@@ -0,0 +1,242 @@
+// Package auth реализует ролевой доступ к API сервера метрик.
+//
+// Роль определяется по API-ключу или по claim "role" JWT, подписанного HS256.
+// JWT без срока действия (claim "exp") не принимаются: утёкший бессрочный токен
+// нельзя было бы отозвать без смены секрета.
+// Роли упорядочены по уровню доступа: reader < writer < admin; обработчик,
+// требующий роль, доступен и всем ролям выше неё.
+//
+// Ключи, хранящиеся в виде хеша (HashedKey), вместо роли получают набор областей
+// доступа (Scope), которые проверяются для каждой операции отдельно.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Role — роль клиента API.
+type Role int
+
+// Роли клиентов API в порядке возрастания уровня доступа.
+const (
+	RoleNone   Role = iota // Клиент не аутентифицирован
+	RoleReader             // Чтение метрик
+	RoleWriter             // Отправка метрик (агенты)
+	RoleAdmin              // Административные операции
+)
+
+// APIKeyHeader — заголовок, в котором можно передать API-ключ вместо Authorization: Bearer.
+const APIKeyHeader = "X-API-Key"
+
+var (
+	// ErrUnauthenticated возвращается, если учётные данные не переданы или недействительны.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden возвращается, если роли клиента недостаточно для операции.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// roleNames — имена ролей в конфигурации и claims JWT.
+var roleNames = map[string]Role{
+	"reader": RoleReader,
+	"writer": RoleWriter,
+	"admin":  RoleAdmin,
+}
+
+// ParseRole разбирает имя роли ("reader", "writer" или "admin").
+func ParseRole(s string) (Role, error) {
+	if r, ok := roleNames[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return r, nil
+	}
+	return RoleNone, fmt.Errorf("unknown role %q", s)
+}
+
+// String возвращает имя роли.
+func (r Role) String() string {
+	for name, role := range roleNames {
+		if role == r {
+			return name
+		}
+	}
+	return "none"
+}
+
+// Authenticator определяет роль клиента по API-ключу или JWT.
+//
+// Поля:
+//   - keys: роли API-ключей
+//   - hashed: области доступа хешированных API-ключей по HashKey(ключ)
+//   - names: имена хешированных API-ключей по HashKey(ключ)
+//   - jwtSecret: секрет для проверки подписи JWT (пусто — JWT не принимаются)
+//   - now: функция получения текущего времени (для проверки exp)
+type Authenticator struct {
+	keys      map[string]Role
+	hashed    map[string]scopeSet
+	names     map[string]string
+	jwtSecret []byte
+	now       func() time.Time
+}
+
+// New создаёт Authenticator.
+//
+// keys — API-ключи и имена их ролей.
+// jwtSecret — секрет HS256 для JWT (пусто — JWT не принимаются).
+// hashed — API-ключи, хранящиеся в виде хеша, с областями доступа.
+//
+// Возвращает nil, если не задан ни один ключ и секрет: ролевой доступ отключён.
+func New(keys map[string]string, jwtSecret string, hashed ...HashedKey) (*Authenticator, error) {
+	if len(keys) == 0 && jwtSecret == "" && len(hashed) == 0 {
+		return nil, nil
+	}
+	a := &Authenticator{
+		keys:      make(map[string]Role, len(keys)),
+		hashed:    make(map[string]scopeSet, len(hashed)),
+		names:     make(map[string]string, len(hashed)),
+		jwtSecret: []byte(jwtSecret),
+		now:       time.Now,
+	}
+	for _, k := range hashed {
+		if err := a.addHashedKey(k); err != nil {
+			return nil, err
+		}
+	}
+	for key, name := range keys {
+		role, err := ParseRole(name)
+		if err != nil {
+			return nil, err
+		}
+		a.keys[key] = role
+	}
+	return a, nil
+}
+
+// Modes возвращает поддерживаемые способы аутентификации: "api-key" и/или "jwt".
+//
+// Если a равен nil (ролевой доступ отключён), возвращает nil.
+func (a *Authenticator) Modes() []string {
+	if a == nil {
+		return nil
+	}
+	var modes []string
+	if len(a.keys) > 0 || len(a.hashed) > 0 {
+		modes = append(modes, "api-key")
+	}
+	if len(a.jwtSecret) > 0 {
+		modes = append(modes, "jwt")
+	}
+	return modes
+}
+
+// Authenticate возвращает роль, соответствующую токену (API-ключу или JWT).
+func (a *Authenticator) Authenticate(token string) (Role, error) {
+	if token == "" {
+		return RoleNone, ErrUnauthenticated
+	}
+	for key, role := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+			return role, nil
+		}
+	}
+	if len(a.jwtSecret) > 0 && strings.Count(token, ".") == 2 {
+		return a.authenticateJWT(token)
+	}
+	return RoleNone, ErrUnauthenticated
+}
+
+// Authorize проверяет, что токен даёт роль не ниже required, а для хешированного
+// ключа — что среди его областей есть область операций роли required.
+//
+// Если a равен nil (ролевой доступ отключён), разрешает любой запрос.
+func (a *Authenticator) Authorize(token string, required Role) error {
+	if a == nil {
+		return nil
+	}
+	if scopes, ok := a.hashed[HashKey(token)]; ok && token != "" {
+		if !scopes.allows(required) {
+			return ErrForbidden
+		}
+		return nil
+	}
+	role, err := a.Authenticate(token)
+	if err != nil {
+		return err
+	}
+	if role < required {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// TokenFromRequest извлекает токен из заголовка Authorization: Bearer или X-API-Key.
+func TokenFromRequest(r *http.Request) string {
+	if v, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(v)
+	}
+	return strings.TrimSpace(r.Header.Get(APIKeyHeader))
+}
+
+// jwtClaims — поддерживаемые claims JWT.
+type jwtClaims struct {
+	Role string `json:"role"`
+	Exp  int64  `json:"exp"`
+	Sub  string `json:"sub"`
+}
+
+// authenticateJWT проверяет подпись HS256 и срок действия JWT и возвращает роль из claim "role".
+func (a *Authenticator) authenticateJWT(token string) (Role, error) {
+	claims, err := a.verifyJWT(token)
+	if err != nil {
+		return RoleNone, err
+	}
+	role, err := ParseRole(claims.Role)
+	if err != nil {
+		return RoleNone, ErrUnauthenticated
+	}
+	return role, nil
+}
+
+// verifyJWT проверяет подпись HS256 и срок действия JWT (claim "exp" обязателен)
+// и возвращает его claims.
+func (a *Authenticator) verifyJWT(token string) (jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return jwtClaims{}, ErrUnauthenticated
+	}
+
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return jwtClaims{}, ErrUnauthenticated
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return jwtClaims{}, ErrUnauthenticated
+	}
+	// Токен без exp (или с нулевым exp) действовал бы бессрочно.
+	if claims.Exp <= 0 || a.now().Unix() >= claims.Exp {
+		return jwtClaims{}, ErrUnauthenticated
+	}
+	return claims, nil
+}
+
+// decodeSegment декодирует сегмент JWT (base64url без выравнивания) в v.
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
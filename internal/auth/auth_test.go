@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// signJWT формирует JWT HS256 с заданными claims.
+func signJWT(secret, claims string) string {
+	head := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	body := base64.RawURLEncoding.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(head + "." + body))
+	return head + "." + body + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// TestAuthenticator_Authorize проверяет определение роли по API-ключам и JWT и сравнение уровней доступа.
+//
+// t — указатель на структуру теста.
+func TestAuthenticator_Authorize(t *testing.T) {
+	a, err := New(map[string]string{"adm": "admin", "agent": "writer", "viewer": "reader"}, "secret")
+	require.NoError(t, err)
+	a.now = func() time.Time { return time.Unix(1000, 0) }
+
+	tests := []struct {
+		name     string
+		token    string
+		required Role
+		wantErr  error
+	}{
+		{name: "admin key on admin", token: "adm", required: RoleAdmin},
+		{name: "admin key on read", token: "adm", required: RoleReader},
+		{name: "writer key on admin", token: "agent", required: RoleAdmin, wantErr: ErrForbidden},
+		{name: "writer key on ingest", token: "agent", required: RoleWriter},
+		{name: "reader key on ingest", token: "viewer", required: RoleWriter, wantErr: ErrForbidden},
+		{name: "no token", required: RoleReader, wantErr: ErrUnauthenticated},
+		{name: "unknown key", token: "nope", required: RoleReader, wantErr: ErrUnauthenticated},
+		{name: "jwt admin", token: signJWT("secret", `{"role":"admin","exp":2000}`), required: RoleAdmin},
+		{name: "jwt expired", token: signJWT("secret", `{"role":"admin","exp":1000}`), required: RoleReader, wantErr: ErrUnauthenticated},
+		{name: "jwt without exp", token: signJWT("secret", `{"role":"admin"}`), required: RoleReader, wantErr: ErrUnauthenticated},
+		{name: "jwt zero exp", token: signJWT("secret", `{"role":"admin","exp":0}`), required: RoleReader, wantErr: ErrUnauthenticated},
+		{name: "jwt wrong secret", token: signJWT("other", `{"role":"admin","exp":2000}`), required: RoleReader, wantErr: ErrUnauthenticated},
+		{name: "jwt unknown role", token: signJWT("secret", `{"role":"root","exp":2000}`), required: RoleReader, wantErr: ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := a.Authorize(tt.token, tt.required)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+// TestNew проверяет отключение ролевого доступа, проверку имён ролей и способы аутентификации.
+//
+// t — указатель на структуру теста.
+func TestNew(t *testing.T) {
+	a, err := New(nil, "")
+	require.NoError(t, err)
+	require.Nil(t, a)
+	require.NoError(t, a.Authorize("", RoleAdmin))
+
+	require.Nil(t, a.Modes())
+
+	_, err = New(map[string]string{"k": "superuser"}, "")
+	require.Error(t, err)
+
+	a, err = New(map[string]string{"k": "reader"}, "secret")
+	require.NoError(t, err)
+	require.Equal(t, []string{"api-key", "jwt"}, a.Modes())
+}
+
+func TestAuthenticator_HashedKeyScopes(t *testing.T) {
+	a, err := New(nil, "",
+		HashedKey{Name: "ingest", Hash: HashKey("w"), Scopes: []string{"write-metrics"}},
+		HashedKey{Name: "dashboards", Hash: HashKey("r"), Scopes: []string{"read-metrics"}},
+		HashedKey{Name: "ops", Hash: HashKey("a"), Scopes: []string{"admin"}},
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		token    string
+		required Role
+		wantErr  error
+	}{
+		{name: "write scope on ingest", token: "w", required: RoleWriter},
+		{name: "write scope on read", token: "w", required: RoleReader, wantErr: ErrForbidden},
+		{name: "read scope on read", token: "r", required: RoleReader},
+		{name: "read scope on ingest", token: "r", required: RoleWriter, wantErr: ErrForbidden},
+		{name: "admin scope on everything", token: "a", required: RoleReader},
+		{name: "admin scope on admin", token: "a", required: RoleAdmin},
+		{name: "hash is not a key", token: HashKey("w"), required: RoleWriter, wantErr: ErrUnauthenticated},
+		{name: "no token", required: RoleReader, wantErr: ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := a.Authorize(tt.token, tt.required)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNew_InvalidHashedKey(t *testing.T) {
+	_, err := New(nil, "", HashedKey{Name: "k", Hash: "md5:abc", Scopes: []string{"admin"}})
+	require.Error(t, err)
+	_, err = New(nil, "", HashedKey{Name: "k", Hash: HashKey("k"), Scopes: []string{"root"}})
+	require.Error(t, err)
+	_, err = New(nil, "", HashedKey{Name: "k", Hash: HashKey("k")})
+	require.Error(t, err)
+}
+
+// TestAuthenticator_Identify проверяет идентификаторы клиентов для журнала аудита.
+//
+// t — указатель на структуру теста.
+func TestAuthenticator_Identify(t *testing.T) {
+	a, err := New(map[string]string{"agent": "writer"}, "secret",
+		HashedKey{Name: "ci-writer", Hash: HashKey("w"), Scopes: []string{"write-metrics"}},
+	)
+	require.NoError(t, err)
+	a.now = func() time.Time { return time.Unix(1000, 0) }
+
+	tests := []struct {
+		name  string // Название теста
+		token string // Токен клиента
+		want  string // Ожидаемый идентификатор
+	}{
+		{name: "hashed key name", token: "w", want: "key:ci-writer"},
+		{name: "plain key fingerprint", token: "agent", want: "key:" + HashKey("agent")[:len(HashPrefix)+8]},
+		{name: "jwt subject", token: signJWT("secret", `{"role":"writer","sub":"alice","exp":2000}`), want: "jwt:alice"},
+		{name: "jwt without subject", token: signJWT("secret", `{"role":"writer","exp":2000}`), want: "jwt"},
+		{name: "jwt without exp", token: signJWT("secret", `{"role":"writer","sub":"alice"}`)},
+		{name: "jwt wrong secret", token: signJWT("other", `{"role":"writer","sub":"alice","exp":2000}`)},
+		{name: "unknown key", token: "nope"},
+		{name: "no token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, a.Identify(tt.token))
+		})
+	}
+
+	var disabled *Authenticator
+	require.Empty(t, disabled.Identify("agent"))
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+)
+
+// AdminTokenIdentity — идентификатор клиента, прошедшего проверку токена административного слушателя.
+const AdminTokenIdentity = "admin-token"
+
+// identityKey — ключ контекста для идентификатора клиента.
+type identityKey struct{}
+
+// WithIdentity возвращает контекст с идентификатором аутентифицированного клиента.
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext возвращает идентификатор клиента из контекста (пусто, если запрос
+// не проходил аутентификацию).
+func IdentityFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(identityKey{}).(string)
+	return id
+}
+
+// Identify возвращает идентификатор клиента с токеном token для журнала аудита.
+//
+// Хешированный ключ обозначается именем ("key:ci-writer"), обычный API-ключ — началом его
+// хеша ("key:sha256:1a2b3c4d"), чтобы сам ключ не попадал в записи, JWT — claim "sub"
+// ("jwt:alice"). Для недействительного токена возвращает пустую строку.
+func (a *Authenticator) Identify(token string) string {
+	if a == nil || token == "" {
+		return ""
+	}
+	hash := HashKey(token)
+	if name, ok := a.names[hash]; ok {
+		return "key:" + name
+	}
+	for key := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+			return "key:" + hash[:len(HashPrefix)+8]
+		}
+	}
+	if len(a.jwtSecret) > 0 && strings.Count(token, ".") == 2 {
+		claims, err := a.verifyJWT(token)
+		if err != nil {
+			return ""
+		}
+		if claims.Sub == "" {
+			return "jwt"
+		}
+		return "jwt:" + claims.Sub
+	}
+	return ""
+}
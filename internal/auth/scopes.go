@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Scope — право доступа API-ключа, хранящегося в виде хеша.
+//
+// В отличие от ролей области не упорядочены: ключ с write-metrics не может читать метрики,
+// если у него нет read-metrics. Область admin разрешает все операции.
+type Scope string
+
+// Области доступа API-ключей.
+const (
+	ScopeReadMetrics  Scope = "read-metrics"  // Чтение метрик
+	ScopeWriteMetrics Scope = "write-metrics" // Отправка метрик (агенты)
+	ScopeAdmin        Scope = "admin"         // Административные операции и всё остальное
+)
+
+// HashPrefix — префикс хеша API-ключа в файле ключей и таблице api_keys.
+const HashPrefix = "sha256:"
+
+// roleScopes — область, которая требуется для операций, доступных роли.
+var roleScopes = map[Role]Scope{
+	RoleReader: ScopeReadMetrics,
+	RoleWriter: ScopeWriteMetrics,
+	RoleAdmin:  ScopeAdmin,
+}
+
+// HashedKey — API-ключ, хранящийся в виде хеша, с областями доступа.
+//
+// Поля:
+//   - Name: имя ключа (команда или агент) для журнала
+//   - Hash: HashKey(ключ), например "sha256:9f86d0..."
+//   - Scopes: области доступа ("read-metrics", "write-metrics", "admin")
+type HashedKey struct {
+	Name   string   `json:"name"`
+	Hash   string   `json:"hash"`
+	Scopes []string `json:"scopes"`
+}
+
+// ParseScope разбирает имя области доступа.
+func ParseScope(s string) (Scope, error) {
+	switch sc := Scope(strings.ToLower(strings.TrimSpace(s))); sc {
+	case ScopeReadMetrics, ScopeWriteMetrics, ScopeAdmin:
+		return sc, nil
+	}
+	return "", fmt.Errorf("unknown scope %q", s)
+}
+
+// HashKey возвращает хеш API-ключа в формате хранения: HashPrefix и SHA-256 в hex.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return HashPrefix + hex.EncodeToString(sum[:])
+}
+
+// scopeSet — области доступа одного хешированного ключа.
+type scopeSet map[Scope]bool
+
+// allows сообщает, разрешает ли набор областей операции роли required.
+func (s scopeSet) allows(required Role) bool {
+	return s[ScopeAdmin] || s[roleScopes[required]]
+}
+
+// addHashedKey проверяет формат хеша и областей ключа k и добавляет его в a.
+func (a *Authenticator) addHashedKey(k HashedKey) error {
+	digest, ok := strings.CutPrefix(strings.ToLower(k.Hash), HashPrefix)
+	if b, err := hex.DecodeString(digest); !ok || err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("api key %q: invalid hash (want %s followed by 64 hex digits)", k.Name, HashPrefix)
+	}
+	if len(k.Scopes) == 0 {
+		return fmt.Errorf("api key %q: no scopes", k.Name)
+	}
+	scopes := make(scopeSet, len(k.Scopes))
+	for _, name := range k.Scopes {
+		sc, err := ParseScope(name)
+		if err != nil {
+			return fmt.Errorf("api key %q: %w", k.Name, err)
+		}
+		scopes[sc] = true
+	}
+	a.hashed[HashPrefix+digest] = scopes
+	a.names[HashPrefix+digest] = k.Name
+	return nil
+}
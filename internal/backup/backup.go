@@ -0,0 +1,209 @@
+// Package backup реализует периодическое резервное копирование снимков метрик.
+//
+// По расписанию в формате cron Scheduler записывает сжатый gzip снимок хранилища
+// в файл с датой в имени (metrics-20250101T1200.json.gz) и удаляет старые копии
+// сверх заданного количества.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Формат имени файла резервной копии.
+const (
+	filePrefix = "metrics-"
+	fileSuffix = ".json.gz"
+	timeLayout = "20060102T1504"
+)
+
+// Scheduler по расписанию создаёт резервные копии хранилища и удаляет устаревшие.
+//
+// Поля:
+//   - cfg: настройки резервного копирования
+//   - schedule: разобранное расписание
+//   - storage: хранилище, снимок которого сохраняется
+//   - logger: логгер для сообщений о копировании
+//   - now: функция получения текущего времени
+type Scheduler struct {
+	cfg      config.BackupConfig
+	schedule Schedule
+	storage  repository.Storage
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// New создаёт новый экземпляр Scheduler.
+//
+// cfg — настройки резервного копирования; cfg.Schedule должен быть непустым.
+// storage — хранилище, снимок которого сохраняется.
+// logger — логгер для сообщений о копировании.
+//
+// Возвращает ошибку, если расписание некорректно.
+func New(cfg config.BackupConfig, storage repository.Storage, logger *zap.Logger) (*Scheduler, error) {
+	schedule, err := ParseSchedule(cfg.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	return &Scheduler{
+		cfg:      cfg,
+		schedule: schedule,
+		storage:  storage,
+		logger:   logger,
+		now:      time.Now,
+	}, nil
+}
+
+// Run создаёт резервные копии по расписанию до отмены ctx.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		next := s.schedule.Next(s.now())
+		if next.IsZero() {
+			s.logger.Warn("Backup schedule has no upcoming runs", zap.String("schedule", s.cfg.Schedule))
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if _, _, err := s.RunOnce(next); err != nil {
+			s.logger.Error("Failed to write backup", zap.Error(err))
+		}
+	}
+}
+
+// RunOnce создаёт резервную копию с меткой времени t и удаляет устаревшие копии.
+//
+// Используется расписанием и для копирования по запросу (POST /admin/backup).
+// Ошибка удаления старых копий только логируется: созданная копия остаётся действительной.
+//
+// Возвращает путь к созданному файлу и пути удалённых файлов.
+func (s *Scheduler) RunOnce(t time.Time) (path string, removed []string, err error) {
+	path, err = s.Backup(t)
+	if err != nil {
+		return "", nil, err
+	}
+	s.logger.Info("Backup written", zap.String("file", path))
+	removed, err = s.Prune()
+	if err != nil {
+		s.logger.Error("Failed to prune backups", zap.Error(err))
+	}
+	for _, f := range removed {
+		s.logger.Info("Backup removed", zap.String("file", f))
+	}
+	return path, removed, nil
+}
+
+// Backup записывает сжатый снимок хранилища в файл с меткой времени t.
+//
+// Файл сначала пишется во временный и затем переименовывается, поэтому
+// незавершённая копия никогда не попадает под шаблон имени резервных копий.
+//
+// Возвращает путь к созданному файлу.
+func (s *Scheduler) Backup(t time.Time) (path string, err error) {
+	if err := os.MkdirAll(s.cfg.Dir, 0755); err != nil {
+		return "", err
+	}
+	path = filepath.Join(s.cfg.Dir, FileName(t))
+
+	tmp, err := os.CreateTemp(s.cfg.Dir, ".backup-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	zw := gzip.NewWriter(tmp)
+	if err = repository.WriteMetrics(s.storage, zw); err != nil {
+		return "", err
+	}
+	if err = zw.Close(); err != nil {
+		return "", err
+	}
+	if err = tmp.Sync(); err != nil {
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Prune удаляет самые старые резервные копии сверх cfg.Retention.
+//
+// Возвращает пути удалённых файлов.
+func (s *Scheduler) Prune() ([]string, error) {
+	if s.cfg.Retention <= 0 {
+		return nil, nil
+	}
+	backups, err := List(s.cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) <= s.cfg.Retention {
+		return nil, nil
+	}
+
+	var removed []string
+	for _, path := range backups[:len(backups)-s.cfg.Retention] {
+		if err := os.Remove(path); err != nil {
+			return removed, fmt.Errorf("failed to remove backup %s: %w", path, err)
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// FileName возвращает имя файла резервной копии для момента t (в UTC).
+func FileName(t time.Time) string {
+	return filePrefix + t.UTC().Format(timeLayout) + fileSuffix
+}
+
+// List возвращает пути резервных копий в каталоге dir от самой старой к самой новой.
+//
+// Отсутствующий каталог не считается ошибкой.
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix)
+		if _, err := time.Parse(timeLayout, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
@@ -0,0 +1,109 @@
+package backup
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestParseSchedule проверяет разбор расписаний и расчёт следующего срабатывания.
+//
+// t — указатель на структуру теста.
+func TestParseSchedule(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 7, 30, 0, time.UTC) // среда
+	tests := []struct {
+		spec    string
+		want    time.Time
+		wantErr bool
+	}{
+		{spec: "*/15 * * * *", want: time.Date(2025, 1, 1, 12, 15, 0, 0, time.UTC)},
+		{spec: "0 * * * *", want: time.Date(2025, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{spec: "@daily", want: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{spec: "30 3 * * 1-5", want: time.Date(2025, 1, 2, 3, 30, 0, 0, time.UTC)},
+		{spec: "0 0,12 * * *", want: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1 * *", want: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 * * 0", want: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{spec: "@every 6h", want: base.Add(6 * time.Hour)},
+		{spec: "", wantErr: true},
+		{spec: "* * *", wantErr: true},
+		{spec: "60 * * * *", wantErr: true},
+		{spec: "*/0 * * * *", wantErr: true},
+		{spec: "@every 1s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := ParseSchedule(tt.spec)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, s.Next(base))
+		})
+	}
+}
+
+// TestScheduler_BackupAndPrune проверяет запись сжатого снимка и удаление старых копий.
+//
+// t — указатель на структуру теста.
+func TestScheduler_BackupAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	storage := repository.NewMemStorage()
+	storage.SetGauge("g", 1.5)
+	storage.AddCounter("c", 3)
+
+	s, err := New(config.BackupConfig{Dir: dir, Schedule: "@hourly", Retention: 2}, storage, zap.NewNop())
+	require.NoError(t, err)
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	var paths []string
+	for i := 0; i < 3; i++ {
+		path, err := s.Backup(base.Add(time.Duration(i) * time.Hour))
+		require.NoError(t, err)
+		paths = append(paths, path)
+	}
+	require.Equal(t, "metrics-20250101T1200.json.gz", filepath.Base(paths[0]))
+
+	// Посторонние файлы не считаются резервными копиями.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644))
+
+	removed, err := s.Prune()
+	require.NoError(t, err)
+	require.Equal(t, []string{paths[0]}, removed)
+
+	list, err := List(dir)
+	require.NoError(t, err)
+	require.Equal(t, paths[1:], list)
+
+	// Копия читается как обычный файл снимка после распаковки.
+	f, err := os.Open(paths[2])
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	plain := filepath.Join(t.TempDir(), "metrics.json")
+	out, err := os.Create(plain)
+	require.NoError(t, err)
+	_, err = out.ReadFrom(zr)
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	restored := repository.NewMemStorage()
+	require.NoError(t, repository.LoadMetricsFromFile(restored, plain))
+	require.Equal(t, storage.GetAll(), restored.GetAll())
+
+	// Копия по запросу также удаляет устаревшие копии.
+	path, removed, err := s.RunOnce(base.Add(3 * time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []string{paths[1]}, removed)
+	list, err = List(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{paths[2], path}, list)
+}
@@ -0,0 +1,191 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxScheduleLookahead — горизонт поиска следующего срабатывания расписания.
+const maxScheduleLookahead = 5 * 366 * 24 * time.Hour
+
+// Schedule определяет моменты запуска резервного копирования.
+type Schedule interface {
+	// Next возвращает первый момент срабатывания строго после t
+	// (нулевое время, если срабатываний нет).
+	Next(t time.Time) time.Time
+}
+
+type (
+	// everySchedule срабатывает через равные промежутки времени ("@every 6h").
+	everySchedule struct {
+		interval time.Duration
+	}
+
+	// cronSchedule — расписание из пяти полей cron: минута, час, день месяца, месяц, день недели.
+	cronSchedule struct {
+		minute, hour, dom, month, dow uint64
+		domAny, dowAny                bool
+	}
+
+	// cronField описывает допустимый диапазон поля cron.
+	cronField struct {
+		name     string
+		min, max int
+	}
+)
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// cronDescriptors — сокращённые записи расписаний.
+var cronDescriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// ParseSchedule разбирает расписание в формате cron.
+//
+// Поддерживаются пять полей ("*/15 * * * *", "0 3 * * 1-5", "0 0,12 * * *"),
+// сокращения @hourly, @daily, @weekly, @monthly, @yearly и интервалы "@every 6h".
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q: %w", rest, err)
+		}
+		if d < time.Minute {
+			return nil, fmt.Errorf("interval %s is shorter than one minute", d)
+		}
+		return everySchedule{interval: d}, nil
+	}
+	if expanded, ok := cronDescriptors[spec]; ok {
+		spec = expanded
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid schedule %q: expected %d fields, got %d", spec, len(cronFields), len(parts))
+	}
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		bits[i] = b
+	}
+	return &cronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: strings.HasPrefix(parts[2], "*"),
+		dowAny: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parseCronField разбирает одно поле cron и возвращает битовую маску допустимых значений.
+func parseCronField(spec string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(spec, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			s, err := strconv.Atoi(stepStr)
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("%s: invalid step %q", f.name, stepStr)
+			}
+			step = s
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var errA, errB error
+			lo, errA = strconv.Atoi(a)
+			hi, errB = strconv.Atoi(b)
+			if errA != nil || errB != nil {
+				return 0, fmt.Errorf("%s: invalid range %q", f.name, rng)
+			}
+		default:
+			v, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("%s: invalid value %q", f.name, rng)
+			}
+			lo = v
+			if !hasStep {
+				hi = v
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s: %q out of range %d-%d", f.name, item, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	if bits == 0 {
+		return 0, errors.New(f.name + ": empty field")
+	}
+	return bits, nil
+}
+
+// Next возвращает момент t+interval.
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// Next возвращает первую минуту строго после t, подходящую под расписание.
+//
+// Как и в cron, если ограничены и день месяца, и день недели, достаточно совпадения любого из них.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxScheduleLookahead)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches проверяет совпадение дня месяца и дня недели.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domOK := s.dom&(1<<uint(t.Day())) != 0
+	dowOK := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
@@ -0,0 +1,234 @@
+// Package compression реализует сжатие батчей метрик zstd с общим словарём.
+//
+// Имена метрик в батчах агента повторяются каждый интервал, поэтому словарь,
+// обученный на недавних батчах, заметно уменьшает сжатый размер по сравнению с gzip.
+//
+// Согласование:
+//   - сервер, поддерживающий словари, добавляет к ответу на POST /updates/ заголовок
+//     AcceptHeader со значением Encoding;
+//   - агент обучает словарь и регистрирует его через PUT DictionaryPath + ID(dict);
+//   - последующие батчи отправляются с Content-Encoding: Encoding и заголовком
+//     IDHeader; неизвестный серверу словарь отклоняется с кодом
+//     models.ErrCodeUnknownDictionary, и агент возвращается к gzip.
+//
+// Батч с Content-Encoding: zstd без IDHeader распаковывается без словаря, поэтому
+// сервер принимает и обычные клиенты zstd.
+//
+// Словарь — raw content dictionary формата zstd (RFC 8878): батч можно распаковать
+// любым декодером zstd, передав ему тот же словарь (например, zstd -d -D dict).
+// Идентификатор словаря записывается в заголовок кадра (см. FrameID).
+package compression
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Заголовки и значения согласования сжатия со словарём.
+const (
+	// Encoding — значение Content-Encoding батча, сжатого со словарём.
+	Encoding = "zstd"
+	// IDHeader — заголовок запроса с идентификатором словаря.
+	IDHeader = "X-Compression-Dictionary"
+	// AcceptHeader — заголовок ответа сервера, сообщающий о поддержке словарей.
+	AcceptHeader = "X-Accept-Dictionary"
+	// DictionaryPath — префикс пути регистрации словаря (PUT DictionaryPath + ID).
+	DictionaryPath = "/api/v1/dictionaries/"
+)
+
+// MaxSize — максимальный размер словаря.
+const MaxSize = 32 << 10
+
+// DefaultStoreLimit — число словарей, которое сервер хранит по умолчанию (не больше 8 МиБ).
+const DefaultStoreLimit = 256
+
+// Train строит словарь по образцам батчей: последние MaxSize байт их конкатенации.
+//
+// Zstd находит ближайшие совпадения быстрее всего в конце словаря, поэтому более новые
+// образцы передаются последними.
+func Train(samples ...[]byte) []byte {
+	var buf bytes.Buffer
+	for _, s := range samples {
+		buf.Write(s)
+	}
+	dict := buf.Bytes()
+	if len(dict) > MaxSize {
+		dict = dict[len(dict)-MaxSize:]
+	}
+	return bytes.Clone(dict)
+}
+
+// ID возвращает идентификатор словаря: первые 8 байт SHA-256 в шестнадцатеричном виде.
+func ID(dict []byte) string {
+	sum := sha256.Sum256(dict)
+	return hex.EncodeToString(sum[:8])
+}
+
+// FrameID возвращает идентификатор словаря dict для заголовка кадра zstd.
+//
+// Значение выводится из SHA-256 словаря и лежит в диапазоне [32768, 2^31),
+// не зарезервированном спецификацией zstd.
+func FrameID(dict []byte) uint32 {
+	sum := sha256.Sum256(dict)
+	const lo, hi = 1 << 15, 1 << 31
+	return lo + binary.BigEndian.Uint32(sum[:4])%(hi-lo)
+}
+
+// Codec сжимает и распаковывает данные zstd одним словарём.
+//
+// Кодировщик создаётся при первом сжатии и используется всеми вызовами Compress (EncodeAll
+// безопасен для конкурентного использования), декодеры переиспользуются через sync.Pool,
+// поэтому создание кодировщика с SpeedBestCompression и буферов декодера не повторяется
+// для каждого батча. Методы безопасны для конкурентного использования.
+//
+// Поля:
+//   - dict: словарь (nil — обычные кадры zstd без словаря)
+//   - encOnce: однократное создание кодировщика
+//   - enc: кодировщик, общий для всех вызовов Compress
+//   - encErr: ошибка создания кодировщика
+//   - decoders: декодеры, готовые к повторному использованию (*pooledDecoder)
+type Codec struct {
+	dict     []byte
+	encOnce  sync.Once
+	enc      *zstd.Encoder
+	encErr   error
+	decoders sync.Pool
+}
+
+// pooledDecoder — декодер Codec вместе с ограничением памяти, с которым он создан.
+type pooledDecoder struct {
+	dec   *zstd.Decoder
+	limit int64
+}
+
+// NewCodec создаёт Codec для словаря dict; пустой dict — обычные кадры zstd без словаря.
+func NewCodec(dict []byte) *Codec {
+	return &Codec{dict: dict}
+}
+
+// Dict возвращает словарь Codec.
+func (c *Codec) Dict() []byte {
+	return c.dict
+}
+
+// Compress сжимает data zstd со словарём Codec.
+func (c *Codec) Compress(data []byte) ([]byte, error) {
+	c.encOnce.Do(func() {
+		opts := []zstd.EOption{zstd.WithEncoderLevel(zstd.SpeedBestCompression)}
+		if len(c.dict) > 0 {
+			opts = append(opts, zstd.WithEncoderDictRaw(FrameID(c.dict), c.dict))
+		}
+		c.enc, c.encErr = zstd.NewWriter(nil, opts...)
+	})
+	if c.encErr != nil {
+		return nil, c.encErr
+	}
+	return c.enc.EncodeAll(data, nil), nil
+}
+
+// Decompress распаковывает data, сжатые со словарём Codec; результат больше limit байт отклоняется.
+func (c *Codec) Decompress(data []byte, limit int64) ([]byte, error) {
+	d, err := c.decoder(limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.dec.Reset(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	out, err := io.ReadAll(io.LimitReader(d.dec, limit+1))
+	if err != nil {
+		// После ошибки разбора состояние декодера не переиспользуется.
+		d.dec.Close()
+		return nil, err
+	}
+	// Декодер не должен удерживать входные данные, пока лежит в пуле.
+	_ = d.dec.Reset(bytes.NewReader(nil))
+	c.decoders.Put(d)
+	if int64(len(out)) > limit {
+		return nil, fmt.Errorf("decompressed body exceeds %d bytes", limit)
+	}
+	return out, nil
+}
+
+// decoder возвращает декодер из пула, созданный с тем же ограничением limit, или новый.
+func (c *Codec) decoder(limit int64) (*pooledDecoder, error) {
+	if d, ok := c.decoders.Get().(*pooledDecoder); ok {
+		if d.limit == limit {
+			return d, nil
+		}
+		d.dec.Close()
+	}
+	opts := []zstd.DOption{
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderMaxMemory(uint64(limit) + 1),
+	}
+	if len(c.dict) > 0 {
+		opts = append(opts, zstd.WithDecoderDictRaw(FrameID(c.dict), c.dict))
+	}
+	dec, err := zstd.NewReader(nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledDecoder{dec: dec, limit: limit}, nil
+}
+
+// Store — реестр словарей, зарегистрированных агентами на сервере.
+//
+// Для каждого словаря хранится Codec, поэтому декодеры переиспользуются между батчами.
+// При переполнении вытесняется самый давно зарегистрированный словарь.
+//
+// Поля:
+//   - limit: максимальное число словарей
+//   - dicts: Codec словарей по идентификатору
+//   - order: идентификаторы в порядке регистрации
+//   - mu: мьютекс для доступа к реестру
+type Store struct {
+	limit int
+	dicts map[string]*Codec
+	order []string
+	mu    sync.RWMutex
+}
+
+// NewStore создаёт реестр не более чем на limit словарей.
+func NewStore(limit int) *Store {
+	return &Store{limit: max(limit, 1), dicts: make(map[string]*Codec)}
+}
+
+// Put регистрирует словарь и возвращает его идентификатор.
+func (s *Store) Put(dict []byte) string {
+	id := ID(dict)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.dicts[id]; ok {
+		return id
+	}
+	if len(s.order) >= s.limit {
+		delete(s.dicts, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.dicts[id] = NewCodec(bytes.Clone(dict))
+	s.order = append(s.order, id)
+	return id
+}
+
+// Get возвращает Codec словаря по идентификатору.
+func (s *Store) Get(id string) (*Codec, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.dicts[id]
+	return c, ok
+}
+
+// Len возвращает число зарегистрированных словарей.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.dicts)
+}
@@ -0,0 +1,122 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+// names — имена метрик runtime, которые агент отправляет в каждом батче.
+var names = []string{
+	"Alloc", "BuckHashSys", "Frees", "GCCPUFraction", "GCSys", "HeapAlloc", "HeapIdle",
+	"HeapInuse", "HeapObjects", "HeapReleased", "HeapSys", "LastGC", "Lookups", "MCacheInuse",
+	"MCacheSys", "MSpanInuse", "MSpanSys", "Mallocs", "NextGC", "NumForcedGC", "NumGC",
+	"OtherSys", "PauseTotalNs", "StackInuse", "StackSys", "Sys", "TotalAlloc", "RandomValue",
+}
+
+// batch возвращает JSON батча с повторяющимися именами метрик, как у агента.
+func batch(seed int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, `{"id":%q,"type":"gauge","value":%d}`, name, seed*(i+1)*7919)
+	}
+	buf.WriteString("]")
+	return buf.Bytes()
+}
+
+// TestCompressDecompress проверяет сжатие со словарём и выигрыш по сравнению с gzip.
+func TestCompressDecompress(t *testing.T) {
+	dict := Train(batch(1))
+	data := batch(2)
+
+	c := NewCodec(dict)
+	compressed, err := c.Compress(data)
+	require.NoError(t, err)
+	out, err := c.Decompress(compressed, 1<<20)
+	require.NoError(t, err)
+	require.Equal(t, data, out)
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.Less(t, len(compressed), gz.Len()/2)
+
+	_, err = c.Decompress(compressed, 10)
+	require.Error(t, err)
+}
+
+// TestCompressStandardFrame проверяет, что батч — стандартный кадр zstd с идентификатором
+// словаря и не распаковывается с другим словарём.
+func TestCompressStandardFrame(t *testing.T) {
+	dict := Train(batch(1))
+	compressed, err := NewCodec(dict).Compress(batch(2))
+	require.NoError(t, err)
+
+	var hdr zstd.Header
+	require.NoError(t, hdr.Decode(compressed))
+	require.Equal(t, FrameID(dict), hdr.DictionaryID)
+
+	_, err = NewCodec(Train(batch(3), []byte("other"))).Decompress(compressed, 1<<20)
+	require.Error(t, err)
+}
+
+// TestCodec_Reuse проверяет, что общий кодировщик и декодеры из пула дают верный результат
+// при повторных и конкурентных вызовах, в том числе после ошибки распаковки.
+func TestCodec_Reuse(t *testing.T) {
+	c := NewCodec(Train(batch(1)))
+	_, err := c.Decompress([]byte("not zstd"), 1<<20)
+	require.Error(t, err)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 20 {
+				data := batch(i*100 + j)
+				compressed, err := c.Compress(data)
+				require.NoError(t, err)
+				out, err := c.Decompress(compressed, 1<<20)
+				require.NoError(t, err)
+				require.Equal(t, data, out)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestTrain проверяет, что словарь ограничен MaxSize и содержит последние образцы.
+func TestTrain(t *testing.T) {
+	old := bytes.Repeat([]byte("a"), MaxSize)
+	dict := Train(old, []byte("recent"))
+	require.Len(t, dict, MaxSize)
+	require.True(t, bytes.HasSuffix(dict, []byte("recent")))
+}
+
+// TestStore проверяет регистрацию и вытеснение словарей.
+func TestStore(t *testing.T) {
+	s := NewStore(2)
+	a := s.Put([]byte("a"))
+	require.Equal(t, a, s.Put([]byte("a")))
+	b := s.Put([]byte("b"))
+	c := s.Put([]byte("c"))
+	require.Equal(t, 2, s.Len())
+
+	_, ok := s.Get(a)
+	require.False(t, ok)
+	for _, id := range []string{b, c} {
+		_, ok := s.Get(id)
+		require.True(t, ok)
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Политики переполнения очереди событий аудита.
+const (
+	AuditQueueBlock = "block" // Запрос ждёт освобождения места в очереди
+	AuditQueueDrop  = "drop"  // Новое событие отбрасывается и учитывается в счётчике
+)
+
+// Действия правил фильтрации событий аудита.
+const (
+	AuditFilterExclude = "exclude" // Событие не доставляется наблюдателям
+	AuditFilterInclude = "include" // Событие доставляется, последующие правила не проверяются
+)
+
+// Параметры очереди аудита по умолчанию для флагов сервера.
+const (
+	DefaultAuditQueueSize    = 1024
+	DefaultAuditQueueWorkers = 4
+)
+
+type (
+	// AuditQueueConfig описывает асинхронную доставку событий аудита наблюдателям.
+	//
+	// Поля:
+	//   - Size: ёмкость очереди событий (0 — синхронная доставка в обработчике запроса)
+	//   - Workers: число горутин, доставляющих события из очереди
+	//   - Policy: поведение при переполнении (AuditQueueBlock или AuditQueueDrop; пусто — AuditQueueBlock)
+	AuditQueueConfig struct {
+		Size    int
+		Workers int
+		Policy  string
+	}
+
+	// AuditQueueJSONConfig представляет секцию "audit_queue" JSON-конфигурации сервера.
+	AuditQueueJSONConfig struct {
+		Size    *int   `json:"size"`    // AUDIT_QUEUE_SIZE или флаг -audit-queue-size (0 — синхронно)
+		Workers *int   `json:"workers"` // AUDIT_WORKERS или флаг -audit-workers
+		Policy  string `json:"policy"`  // AUDIT_QUEUE_POLICY или флаг -audit-queue-policy ("block" или "drop")
+	}
+
+	// AuditRotationConfig описывает ротацию файлов аудита (FileAuditObserver).
+	//
+	// По умолчанию ротация выключена: удаление архивов аудита должно быть явным решением.
+	//
+	// Поля:
+	//   - MaxSizeMB: размер файла в мегабайтах, после которого он переименовывается и начинается новый (0 — без ротации)
+	//   - MaxAge: срок хранения архивов, округляется вверх до суток (0 — без ограничения)
+	//   - MaxBackups: количество хранимых архивов (0 — без ограничения)
+	//   - Compress: сжимать архивы gzip
+	AuditRotationConfig struct {
+		MaxSizeMB  int
+		MaxAge     time.Duration
+		MaxBackups int
+		Compress   bool
+	}
+
+	// AuditRotationJSONConfig представляет секцию "audit_rotation" JSON-конфигурации сервера.
+	AuditRotationJSONConfig struct {
+		MaxSizeMB  *int   `json:"max_size_mb"` // Размер файла аудита для ротации (МБ, 0 — без ротации)
+		MaxAge     string `json:"max_age"`     // Срок хранения архивов (в формате "2160h")
+		MaxBackups *int   `json:"max_backups"` // Количество хранимых архивов
+		Compress   *bool  `json:"compress"`    // Сжимать архивы
+	}
+
+	// AuditFilterRule — правило секции "audit_filters" JSON-конфигурации сервера, решающее,
+	// доставляется ли событие аудита наблюдателям.
+	//
+	// Правило срабатывает, если событие подходит под все непустые условия; правило без
+	// условий срабатывает для любого события. Правила проверяются по порядку, решает первое
+	// сработавшее; событие, не подошедшее ни под одно правило, доставляется.
+	//
+	// Поля:
+	//   - Action: AuditFilterExclude (пусто — он же) или AuditFilterInclude
+	//   - Metrics: шаблоны имён метрик (синтаксис path.Match, например "runtime_*"); условие выполнено, если под них подходят все метрики события
+	//   - Networks: подсети CIDR или отдельные IP-адреса клиента
+	//   - Operations: операции (update, batch, read, delete) или типы событий без операции (например "rate_limited")
+	AuditFilterRule struct {
+		Action     string   `json:"action"`
+		Metrics    []string `json:"metrics"`
+		Networks   []string `json:"networks"`
+		Operations []string `json:"operations"`
+	}
+)
+
+// Validate проверяет размер очереди, число воркеров и политику переполнения.
+func (c AuditQueueConfig) Validate() error {
+	if c.Size < 0 {
+		return fmt.Errorf("invalid audit queue size %d: must not be negative", c.Size)
+	}
+	if c.Size > 0 && c.Workers < 1 {
+		return fmt.Errorf("invalid audit queue workers %d: must be at least 1", c.Workers)
+	}
+	switch c.Policy {
+	case "", AuditQueueBlock, AuditQueueDrop:
+	default:
+		return fmt.Errorf("invalid audit queue policy %q (want %q or %q)", c.Policy, AuditQueueBlock, AuditQueueDrop)
+	}
+	return nil
+}
+
+// apply применяет значения секции JSON к cfg, не перезаписывая значения, заданные флагами или переменными окружения.
+func (jc *AuditQueueJSONConfig) apply(cfg *AuditQueueConfig, a *jsonApplier) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if jc.Size != nil {
+		applyJSON(a, FlagAuditQueueSize, &cfg.Size, *jc.Size)
+	}
+	if jc.Workers != nil {
+		applyJSON(a, FlagAuditWorkers, &cfg.Workers, *jc.Workers)
+	}
+	a.str(FlagAuditQueuePolicy, &cfg.Policy, jc.Policy)
+}
+
+// Validate проверяет, что параметры ротации файлов аудита не отрицательны.
+func (c AuditRotationConfig) Validate() error {
+	if c.MaxSizeMB < 0 {
+		return fmt.Errorf("invalid audit rotation max_size_mb %d: must not be negative", c.MaxSizeMB)
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("invalid audit rotation max_age %s: must not be negative", c.MaxAge)
+	}
+	if c.MaxBackups < 0 {
+		return fmt.Errorf("invalid audit rotation max_backups %d: must not be negative", c.MaxBackups)
+	}
+	return nil
+}
+
+// MaxAgeDays возвращает срок хранения архивов в сутках с округлением вверх.
+func (c AuditRotationConfig) MaxAgeDays() int {
+	return int((c.MaxAge + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
+// apply применяет значения секции JSON к cfg.
+func (jc *AuditRotationJSONConfig) apply(cfg *AuditRotationConfig) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if jc.MaxSizeMB != nil {
+		cfg.MaxSizeMB = *jc.MaxSizeMB
+	}
+	if d, err := time.ParseDuration(jc.MaxAge); jc.MaxAge != "" && err == nil {
+		cfg.MaxAge = d
+	}
+	if jc.MaxBackups != nil {
+		cfg.MaxBackups = *jc.MaxBackups
+	}
+	if jc.Compress != nil {
+		cfg.Compress = *jc.Compress
+	}
+}
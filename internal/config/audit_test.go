@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuditRotationConfig проверяет применение секции audit_rotation JSON-конфига и проверку параметров.
+func TestAuditRotationConfig(t *testing.T) {
+	var cfg AuditRotationConfig
+	jc := decodeServerJSON(t, map[string]any{"audit_rotation": map[string]any{"max_size_mb": 50, "max_age": "36h", "max_backups": 7, "compress": true}})
+	jc.ApplyToServer(ServerTargets{AuditRotation: &cfg}, nil)
+
+	require.Equal(t, AuditRotationConfig{MaxSizeMB: 50, MaxAge: 36 * time.Hour, MaxBackups: 7, Compress: true}, cfg)
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, 2, cfg.MaxAgeDays())
+	require.Zero(t, AuditRotationConfig{}.MaxAgeDays())
+
+	require.Error(t, AuditRotationConfig{MaxSizeMB: -1}.Validate())
+	require.Error(t, AuditRotationConfig{MaxAge: -time.Hour}.Validate())
+	require.Error(t, AuditRotationConfig{MaxBackups: -1}.Validate())
+}
+
+// TestAuditFilters проверяет чтение секции audit_filters JSON-конфига.
+func TestAuditFilters(t *testing.T) {
+	var rules []AuditFilterRule
+	jc := decodeServerJSON(t, map[string]any{"audit_filters": []map[string]any{
+		{"networks": []string{"10.0.0.0/8"}, "operations": []string{"update", "batch"}},
+		{"action": "include", "metrics": []string{"orders_*"}},
+	}})
+	jc.ApplyToServer(ServerTargets{AuditFilters: &rules}, nil)
+
+	require.Equal(t, []AuditFilterRule{
+		{Networks: []string{"10.0.0.0/8"}, Operations: []string{"update", "batch"}},
+		{Action: AuditFilterInclude, Metrics: []string{"orders_*"}},
+	}, rules)
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// AuthConfig описывает ролевой доступ к API сервера.
+	//
+	// Поля:
+	//   - APIKeys: API-ключи и имена их ролей (reader, writer, admin)
+	//   - JWTSecret: секрет HS256 для JWT с claims "role" и "exp" (пусто — JWT не принимаются)
+	//   - APIKeysFile: JSON-файл хешированных API-ключей с областями доступа (пусто — не используется)
+	//   - APIKeysDB: загружать хешированные API-ключи из таблицы api_keys базы данных
+	AuthConfig struct {
+		APIKeys     map[string]string
+		JWTSecret   string
+		APIKeysFile string
+		APIKeysDB   bool
+	}
+
+	// AuthJSONConfig представляет секцию "auth" JSON-конфигурации сервера.
+	AuthJSONConfig struct {
+		APIKeys     map[string]string `json:"api_keys"`      // API_KEYS или флаг -api-keys
+		JWTSecret   string            `json:"jwt_secret"`    // JWT_SECRET или флаг -jwt-secret
+		APIKeysFile string            `json:"api_keys_file"` // API_KEYS_FILE или флаг -api-keys-file
+		APIKeysDB   *bool             `json:"api_keys_db"`   // API_KEYS_DB или флаг -api-keys-db
+	}
+)
+
+// Enabled сообщает, включён ли ролевой доступ.
+func (c AuthConfig) Enabled() bool {
+	return len(c.APIKeys) > 0 || c.JWTSecret != "" || c.APIKeysFile != "" || c.APIKeysDB
+}
+
+// ParseAPIKeys разбирает список API-ключей в формате "key1:admin,key2:writer".
+func ParseAPIKeys(s string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, role, ok := strings.Cut(item, ":")
+		if !ok || key == "" || role == "" {
+			return nil, fmt.Errorf("invalid api key entry %q: expected key:role", item)
+		}
+		keys[key] = role
+	}
+	return keys, nil
+}
+
+// apply применяет значения секции JSON к cfg, не перезаписывая значения из флагов и переменных окружения.
+func (jc *AuthJSONConfig) apply(cfg *AuthConfig, a *jsonApplier) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if a.use(FlagAPIKeys, len(jc.APIKeys) > 0) {
+		cfg.APIKeys = jc.APIKeys
+	}
+	a.str(FlagJWTSecret, &cfg.JWTSecret, jc.JWTSecret)
+	a.str(FlagAPIKeysFile, &cfg.APIKeysFile, jc.APIKeysFile)
+	if jc.APIKeysDB != nil {
+		applyJSON(a, FlagAPIKeysDB, &cfg.APIKeysDB, *jc.APIKeysDB)
+	}
+}
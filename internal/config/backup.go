@@ -0,0 +1,49 @@
+package config
+
+// DefaultBackupRetention — количество хранимых резервных копий по умолчанию.
+const DefaultBackupRetention = 7
+
+type (
+	// BackupConfig описывает настройки периодического резервного копирования снимков метрик.
+	//
+	// Поля:
+	//   - Dir: каталог для резервных копий
+	//   - Schedule: расписание в формате cron ("0 * * * *", "@daily", "@every 6h"); пусто — копирование отключено
+	//   - Retention: количество хранимых копий; более старые удаляются (0 — без ограничения)
+	BackupConfig struct {
+		Dir       string
+		Schedule  string
+		Retention int
+	}
+
+	// BackupJSONConfig представляет секцию "backup" JSON-конфигурации сервера.
+	BackupJSONConfig struct {
+		Dir       string `json:"dir"`       // Каталог для резервных копий
+		Schedule  string `json:"schedule"`  // Расписание в формате cron
+		Retention *int   `json:"retention"` // Количество хранимых копий
+	}
+)
+
+// DefaultBackupConfig возвращает настройки резервного копирования по умолчанию (копирование отключено).
+func DefaultBackupConfig() BackupConfig {
+	return BackupConfig{
+		Dir:       "backups",
+		Retention: DefaultBackupRetention,
+	}
+}
+
+// apply применяет значения секции JSON к cfg.
+func (jc *BackupJSONConfig) apply(cfg *BackupConfig) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if jc.Dir != "" {
+		cfg.Dir = jc.Dir
+	}
+	if jc.Schedule != "" {
+		cfg.Schedule = jc.Schedule
+	}
+	if jc.Retention != nil {
+		cfg.Retention = *jc.Retention
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultCompressionLevel — уровень сжатия ответов по умолчанию.
+const DefaultCompressionLevel = 5
+
+type (
+	// CompressionConfig описывает сжатие ответов сервера.
+	//
+	// Поля:
+	//   - Level: уровень сжатия gzip/deflate от 1 до 9 (0 — DefaultCompressionLevel, -1 — сжатие отключено)
+	//   - Exclude: пути, ответы на которые не сжимаются; шаблон с "*" в конце задаёт префикс
+	CompressionConfig struct {
+		Level   int
+		Exclude []string
+	}
+
+	// CompressionJSONConfig представляет секцию "compression" JSON-конфигурации сервера.
+	CompressionJSONConfig struct {
+		Level   *int     `json:"level"`   // COMPRESS_LEVEL или флаг -compress-level
+		Exclude []string `json:"exclude"` // COMPRESS_EXCLUDE или флаг -compress-exclude
+	}
+)
+
+// DefaultCompressionConfig возвращает настройки сжатия по умолчанию: уровень 5, без сжатия /ping.
+func DefaultCompressionConfig() CompressionConfig {
+	return CompressionConfig{Level: DefaultCompressionLevel, Exclude: []string{"/ping"}}
+}
+
+// ParseCompressionExclude разбирает список путей через запятую, пропуская пустые элементы.
+func ParseCompressionExclude(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Validate проверяет, что уровень сжатия находится в диапазоне от -1 до 9.
+func (c CompressionConfig) Validate() error {
+	if c.Level < -1 || c.Level > 9 {
+		return fmt.Errorf("invalid compression level %d: must be 1-9, 0 for default or -1 to disable", c.Level)
+	}
+	return nil
+}
+
+// Excluded сообщает, отключено ли сжатие ответа для пути path.
+func (c CompressionConfig) Excluded(path string) bool {
+	return matchPaths(c.Exclude, path)
+}
+
+// matchPaths сообщает, подходит ли path под один из шаблонов patterns:
+// точный путь или префикс с завершающей "*" ("/admin/*").
+func matchPaths(patterns []string, path string) bool {
+	for _, p := range patterns {
+		if matchPath(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath сообщает, подходит ли path под шаблон pattern (см. matchPaths).
+func matchPath(pattern, path string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+	return path == pattern
+}
+
+// apply применяет значения секции JSON к cfg, не перезаписывая параметры, заданные флагом или переменной окружения.
+func (jc *CompressionJSONConfig) apply(cfg *CompressionConfig, a *jsonApplier) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if jc.Level != nil {
+		applyJSON(a, FlagCompressLevel, &cfg.Level, *jc.Level)
+	}
+	if a.use(FlagCompressExclude, jc.Exclude != nil) {
+		cfg.Exclude = jc.Exclude
+	}
+}
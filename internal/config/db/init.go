@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/tracing"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -21,8 +22,13 @@ import (
 func InitDB(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
 	var pool *pgxpool.Pool
 	err := config.RetryWithBackoff(ctx, func() error {
-		var innerErr error
-		pool, innerErr = pgxpool.New(ctx, dsn)
+		poolConfig, innerErr := pgxpool.ParseConfig(dsn)
+		if innerErr != nil {
+			return innerErr
+		}
+		// Запросы записываются дочерними спанами трассировки (если она включена).
+		poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
+		pool, innerErr = pgxpool.NewWithConfig(ctx, poolConfig)
 		if innerErr != nil {
 			return innerErr
 		}
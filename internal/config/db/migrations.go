@@ -1,6 +1,7 @@
 package db
 
 import (
+	"embed"
 	"errors"
 	"fmt"
 	"log"
@@ -8,18 +9,31 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-// RunMigrations выполняет миграции базы данных PostgreSQL с помощью golang-migrate.
+// migrationsFS — миграции схемы, встроенные в бинарный файл, чтобы они не зависели
+// от рабочего каталога процесса.
 //
-// dsn — строка подключения к базе данных PostgreSQL.
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// RunMigrations выполняет встроенные миграции базы данных PostgreSQL.
 //
-// Функция ищет миграции в папке ./migrations, применяет их к базе данных,
-// логирует процесс и возвращает ошибку, если что-то пошло не так.
-// Если миграции не требуются (ErrNoChange), сообщает об этом в логах.
+// dsn — строка подключения к базе данных PostgreSQL.
 func RunMigrations(dsn string) error {
-	migrationsPath := "file://./migrations"
-	m, err := migrate.New(migrationsPath, dsn)
+	return RunMigrationsFrom(dsn, "")
+}
+
+// RunMigrationsFrom выполняет миграции базы данных PostgreSQL с помощью golang-migrate.
+//
+// dsn — строка подключения к базе данных PostgreSQL.
+// dir — каталог с файлами миграций (пусто — встроенные миграции).
+//
+// Функция применяет миграции к базе данных, логирует процесс и возвращает ошибку,
+// если что-то пошло не так. Если миграции не требуются (ErrNoChange), сообщает об этом в логах.
+func RunMigrationsFrom(dsn, dir string) error {
+	m, err := newMigrate(dsn, dir)
 	if err != nil {
 		return fmt.Errorf("failed to init migrations: %v", err)
 	}
@@ -37,3 +51,15 @@ func RunMigrations(dsn string) error {
 	}
 	return nil
 }
+
+// newMigrate создаёт golang-migrate для миграций из каталога dir или встроенных (dir пуст).
+func newMigrate(dsn, dir string) (*migrate.Migrate, error) {
+	if dir != "" {
+		return migrate.New("file://"+dir, dsn)
+	}
+	src, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewWithSourceInstance("iofs", src, dsn)
+}
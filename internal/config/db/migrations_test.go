@@ -0,0 +1,22 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMigrationsFS проверяет, что миграции встроены в бинарный файл и доступны без рабочего каталога.
+func TestMigrationsFS(t *testing.T) {
+	src, err := iofs.New(migrationsFS, "migrations")
+	require.NoError(t, err)
+	defer func() { _ = src.Close() }()
+
+	first, err := src.First()
+	require.NoError(t, err)
+	require.Equal(t, uint(1), first)
+	next, err := src.Next(first)
+	require.NoError(t, err)
+	require.Equal(t, uint(2), next)
+}
@@ -13,10 +13,22 @@ import (
 // retryIntervals определяет интервалы ожидания между попытками повторения операции.
 var retryIntervals = []time.Duration{1 * time.Second, 3 * time.Second, 5 * time.Second}
 
+// RetryHint — ошибка, которая сама сообщает, имеет ли смысл повторять операцию.
+//
+// Retryable возвращает true, если повтор может завершиться успехом.
+// RetryAfter возвращает рекомендуемую паузу перед повтором; 0 — использовать интервал по умолчанию.
+type RetryHint interface {
+	error
+	Retryable() bool
+	RetryAfter() time.Duration
+}
+
 // RetryWithBackoff выполняет функцию op с повторными попытками и экспоненциальной задержкой между ними.
 //
 // Если функция op возвращает ошибку, которая считается временной (retriable),
 // происходит повторная попытка выполнения с увеличивающимся интервалом ожидания.
+// Если ошибка реализует RetryHint и рекомендует паузу, вместо очередного интервала
+// выдерживается она.
 // Если все попытки исчерпаны или контекст завершён, возвращается последняя ошибка.
 //
 // ctx — контекст для управления временем жизни попыток.
@@ -29,6 +41,10 @@ func RetryWithBackoff(ctx context.Context, op func() error) error {
 		if err := op(); err != nil {
 			if isRetriableError(err) {
 				lastErr = err
+				var hint RetryHint
+				if errors.As(err, &hint) && hint.RetryAfter() > 0 {
+					wait = hint.RetryAfter()
+				}
 				log.Printf("Retriable error: %v (attempt %d/%d). Retrying in %v...", err, i+1, len(retryIntervals), wait)
 				select {
 				case <-ctx.Done():
@@ -44,12 +60,17 @@ func RetryWithBackoff(ctx context.Context, op func() error) error {
 	return fmt.Errorf("operation failed after retries: %w", lastErr)
 }
 
-// isRetriableError определяет, является ли ошибка временной (retriable) для PostgreSQL.
+// isRetriableError определяет, является ли ошибка временной (retriable).
 //
 // err — ошибка для проверки.
 //
-// Возвращает true, если ошибка связана с проблемами соединения (коды SQLSTATE, начинающиеся с "08").
+// Возвращает решение RetryHint, если ошибка его реализует, иначе true для ошибок
+// соединения PostgreSQL (коды SQLSTATE, начинающиеся с "08").
 func isRetriableError(err error) bool {
+	var hint RetryHint
+	if errors.As(err, &hint) {
+		return hint.Retryable()
+	}
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
 		if len(pgErr.Code) >= 2 && pgErr.Code[:2] == "08" {
@@ -10,11 +10,22 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// hintError — ошибка с подсказкой о повторе для тестов RetryWithBackoff.
+type hintError struct {
+	retryable bool          // Можно ли повторить операцию
+	after     time.Duration // Рекомендуемая пауза перед повтором
+}
+
+func (e hintError) Error() string             { return "hinted" }
+func (e hintError) Retryable() bool           { return e.retryable }
+func (e hintError) RetryAfter() time.Duration { return e.after }
+
 // TestRetryWithBackoff тестирует функцию RetryWithBackoff на корректность обработки различных сценариев.
 //
 // Проверяются следующие случаи:
 //   - Успешное выполнение после одной или нескольких повторных попыток (ретраев)
 //   - Немедленный возврат ошибки, если ошибка не является временной (не ретраится)
+//   - Учёт подсказок RetryHint: запрет повтора и рекомендуемая пауза
 //   - Исчерпание всех попыток с возвратом последней ошибки
 //   - Прерывание по отмене контекста (context.Canceled)
 //
@@ -81,6 +92,39 @@ func TestRetryWithBackoff(t *testing.T) {
 			expectMsgContains: "operation failed after retries",
 			expectMinCalls:    2,
 		},
+		{
+			name:      "HintNotRetryable",
+			intervals: []time.Duration{1 * time.Millisecond, 1 * time.Millisecond},
+			opFactory: func() (func() error, *int) {
+				calls := 0
+				return func() error {
+					calls++
+					if calls > 1 {
+						return nil
+					}
+					return hintError{retryable: false}
+				}, &calls
+			},
+			expectErr:        true,
+			expectExactError: "hinted",
+			expectMinCalls:   1,
+		},
+		{
+			name:      "HintRetryAfterOverridesInterval",
+			intervals: []time.Duration{time.Hour, time.Hour},
+			opFactory: func() (func() error, *int) {
+				calls := 0
+				return func() error {
+					calls++
+					if calls == 1 {
+						return hintError{retryable: true, after: time.Millisecond}
+					}
+					return nil
+				}, &calls
+			},
+			expectErr:      false,
+			expectMinCalls: 2,
+		},
 		{
 			name:      "ContextCanceled",
 			intervals: []time.Duration{200 * time.Millisecond},
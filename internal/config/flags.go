@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"strconv"
 	"strings"
@@ -12,11 +13,13 @@ import (
 // Реализует интерфейсы flag.Value и AddrSetter.
 //
 // Поля:
+//   - Scheme: схема из префикса "http://" или "https://" (пусто — http)
 //   - Host: имя хоста (по умолчанию "localhost")
 //   - Port: номер порта (по умолчанию 8080)
 type NetAddress struct {
-	Host string // Имя хоста
-	Port int    // Порт
+	Scheme string // Схема (http или https)
+	Host   string // Имя хоста
+	Port   int    // Порт
 }
 
 // String возвращает строковое представление сетевого адреса в формате host:port.
@@ -24,11 +27,27 @@ func (a *NetAddress) String() string {
 	return a.Host + ":" + strconv.Itoa(a.Port)
 }
 
-// Set разбирает строку вида host:port и устанавливает значения Host и Port.
+// URL возвращает базовый URL адреса: scheme://host:port (по умолчанию схема http).
+func (a *NetAddress) URL() string {
+	scheme := a.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme + "://" + a.String()
+}
+
+// Set разбирает строку вида [http://|https://]host:port и устанавливает значения Scheme, Host и Port.
 //
 // Если порт не указан, по умолчанию используется 8080.
 // Возвращает ошибку, если порт не удаётся преобразовать в число.
 func (a *NetAddress) Set(s string) error {
+	a.Scheme = ""
+	for _, scheme := range []string{"http", "https"} {
+		if rest, ok := strings.CutPrefix(s, scheme+"://"); ok {
+			a.Scheme, s = scheme, rest
+			break
+		}
+	}
 	hp := strings.Split(s, ":")
 	a.Host = hp[0]
 	if len(hp) == 2 {
@@ -47,7 +66,66 @@ func (a *NetAddress) Set(s string) error {
 //
 // Возвращает указатель на NetAddress с дефолтными значениями (localhost:8080).
 func ParseAddressFlag() *NetAddress {
+	return AddressFlag(flag.CommandLine)
+}
+
+// AddressFlag регистрирует в наборе fs флаг -a для указания сетевого адреса.
+//
+// Возвращает указатель на NetAddress с дефолтными значениями (localhost:8080).
+func AddressFlag(fs *flag.FlagSet) *NetAddress {
 	addr := &NetAddress{Host: "localhost", Port: 8080}
-	flag.Var(addr, FlagAddress, "Net address host:port")
+	fs.Var(addr, FlagAddress, "Net address host:port")
 	return addr
 }
+
+// AddressList — список сетевых адресов серверов, заданный через запятую ("host1:8080,host2:8080").
+//
+// Реализует интерфейсы flag.Value и AddrSetter. Используется агентом для отправки
+// метрик на несколько серверов с переключением при отказе.
+type AddressList []NetAddress
+
+// String возвращает адреса через запятую.
+func (l *AddressList) String() string {
+	parts := make([]string, len(*l))
+	for i := range *l {
+		parts[i] = (*l)[i].String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Set разбирает список адресов host:port через запятую, заменяя текущее значение.
+func (l *AddressList) Set(s string) error {
+	var list AddressList
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		var a NetAddress
+		if err := a.Set(item); err != nil {
+			return err
+		}
+		list = append(list, a)
+	}
+	if len(list) == 0 {
+		return errors.New("empty address list")
+	}
+	*l = list
+	return nil
+}
+
+// ParseAddressListFlag регистрирует флаг командной строки -a для списка адресов серверов.
+//
+// Возвращает указатель на AddressList со значением по умолчанию localhost:8080.
+func ParseAddressListFlag() *AddressList {
+	return AddressListFlag(flag.CommandLine)
+}
+
+// AddressListFlag регистрирует в наборе fs флаг -a для списка адресов серверов.
+//
+// Возвращает указатель на AddressList со значением по умолчанию localhost:8080.
+func AddressListFlag(fs *flag.FlagSet) *AddressList {
+	list := &AddressList{{Host: "localhost", Port: 8080}}
+	fs.Var(list, FlagAddress, "Comma-separated server addresses [https://]host:port (tried in order with failover)")
+	return list
+}
@@ -22,6 +22,8 @@ func TestNetAddress_SetAndString_TableDriven(t *testing.T) {
 		{"empty string", "", "", 8080, false},
 		{"empty host with port", ":9090", "", 9090, false},
 		{"bad port", "host:notaport", "", 0, true},
+		{"https scheme", "https://example:8443", "example", 8443, false},
+		{"http scheme", "http://example", "example", 8080, false},
 	}
 
 	for _, tt := range tests {
@@ -72,3 +74,54 @@ func TestParseAddressFlag_Defaults(t *testing.T) {
 		t.Fatalf("default port expected %d, got %d", 8080, addr.Port)
 	}
 }
+
+// TestAddressList_Set проверяет разбор списка адресов через запятую.
+func TestAddressList_Set(t *testing.T) {
+	tests := []struct {
+		name      string // Название теста
+		input     string // Входная строка для метода Set
+		expected  string // Ожидаемое строковое представление
+		expectErr bool   // Ожидается ли ошибка
+	}{
+		{"single", "a:1", "a:1", false},
+		{"several with spaces", "a:1, b:2 ,c", "a:1,b:2,c:8080", false},
+		{"empty", " , ", "", true},
+		{"bad port", "a:1,b:x", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l AddressList
+			err := l.Set(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("expected error for input %q, got nil", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error for input %q: %v", tt.input, err)
+			}
+			if l.String() != tt.expected {
+				t.Fatalf("String() mismatch: expected %q, got %q", tt.expected, l.String())
+			}
+		})
+	}
+}
+
+// TestNetAddress_URL проверяет базовый URL адреса с явной схемой и без неё.
+func TestNetAddress_URL(t *testing.T) {
+	var a NetAddress
+	if err := a.Set("example:9000"); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.URL(); got != "http://example:9000" {
+		t.Fatalf("URL() = %q, want http://example:9000", got)
+	}
+	if err := a.Set("https://example:9443"); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.URL(); got != "https://example:9443" {
+		t.Fatalf("URL() = %q, want https://example:9443", got)
+	}
+}
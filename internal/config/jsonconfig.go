@@ -3,180 +3,691 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
 // Константы для имен переменных окружения
 const (
-	EnvAddress        = "ADDRESS"
-	EnvRestore        = "RESTORE"
-	EnvStoreInterval  = "STORE_INTERVAL"
-	EnvStoreFile      = "FILE_STORAGE_PATH"
-	EnvDatabaseDSN    = "DATABASE_DSN"
-	EnvCryptoKey      = "CRYPTO_KEY"
-	EnvAuditFile      = "AUDIT_FILE"
-	EnvAuditURL       = "AUDIT_URL"
-	EnvKey            = "KEY"
-	EnvTrustedSubnet  = "TRUSTED_SUBNET"
-	EnvPollInterval   = "POLL_INTERVAL"
-	EnvReportInterval = "REPORT_INTERVAL"
-	EnvRateLimit      = "RATE_LIMIT"
-	EnvConfig         = "CONFIG"
-	EnvGRPCAddress    = "GRPC_ADDRESS"
+	EnvAddress          = "ADDRESS"
+	EnvRestore          = "RESTORE"
+	EnvStoreInterval    = "STORE_INTERVAL"
+	EnvStoreFile        = "FILE_STORAGE_PATH"
+	EnvDatabaseDSN      = "DATABASE_DSN"
+	EnvCryptoKey        = "CRYPTO_KEY"
+	EnvAuditFile        = "AUDIT_FILE"
+	EnvAuditURL         = "AUDIT_URL"
+	EnvKey              = "KEY"
+	EnvTrustedSubnet    = "TRUSTED_SUBNET"
+	EnvPollInterval     = "POLL_INTERVAL"
+	EnvReportInterval   = "REPORT_INTERVAL"
+	EnvRateLimit        = "RATE_LIMIT"
+	EnvConfig           = "CONFIG"
+	EnvGRPCAddress      = "GRPC_ADDRESS"
+	EnvSnapshotFsync    = "SNAPSHOT_FSYNC"
+	EnvWatchdog         = "WATCHDOG_INTERVAL"
+	EnvWALFile          = "WAL_FILE"
+	EnvStorageShards    = "STORAGE_SHARDS"
+	EnvNormalizeIDs     = "NORMALIZE_IDS"
+	EnvCSP              = "CONTENT_SECURITY_POLICY"
+	EnvPageRefresh      = "PAGE_REFRESH"
+	EnvShutdownTimeout  = "SHUTDOWN_TIMEOUT"
+	EnvSpoolDir         = "SPOOL_DIR"
+	EnvSpoolMaxSize     = "SPOOL_MAX_SIZE"
+	EnvSpoolMaxAge      = "SPOOL_MAX_AGE"
+	EnvEnrollTokens     = "ENROLL_TOKENS"
+	EnvAgentsFile       = "AGENTS_FILE"
+	EnvEnrollToken      = "ENROLL_TOKEN"
+	EnvCredentialsFile  = "CREDENTIALS_FILE"
+	EnvQueueSize        = "QUEUE_SIZE"
+	EnvQueuePolicy      = "QUEUE_POLICY"
+	EnvQueueTimeout     = "QUEUE_TIMEOUT"
+	EnvAPIKeys          = "API_KEYS"
+	EnvListen           = "LISTEN"
+	EnvJWTSecret        = "JWT_SECRET"
+	EnvAPIKey           = "API_KEY"
+	EnvMaxBatchSize     = "MAX_BATCH_SIZE"
+	EnvEndpointPolicy   = "ENDPOINT_POLICY"
+	EnvEndpointCooldown = "ENDPOINT_COOLDOWN"
+	EnvAdminAddress     = "ADMIN_ADDRESS"
+	EnvAdminToken       = "ADMIN_TOKEN"
+	EnvCollect          = "COLLECT"
+	EnvNetInclude       = "NET_INCLUDE"
+	EnvNetExclude       = "NET_EXCLUDE"
+	EnvStateFile        = "STATE_FILE"
+	EnvStatsDAddress    = "STATSD_ADDRESS"
+	EnvPushAddress      = "PUSH_ADDRESS"
+	EnvCollectIntervals = "COLLECT_INTERVALS"
+	EnvSendChangedOnly  = "SEND_CHANGED_ONLY"
+	EnvCPUMode          = "CPU_MODE"
+	EnvBreakerThreshold = "BREAKER_THRESHOLD"
+	EnvBreakerCooldown  = "BREAKER_COOLDOWN"
+	EnvCompressionDict  = "COMPRESSION_DICT"
+	EnvMaxRPS           = "MAX_RPS"
+	EnvRequestIDFormat  = "REQUEST_ID_FORMAT"
+	EnvSignKey          = "SIGN_KEY"
+	EnvVerifyKey        = "VERIFY_KEY"
+	EnvTLSCert          = "TLS_CERT"
+	EnvTLSKey           = "TLS_KEY"
+	EnvTLSRedirect      = "TLS_REDIRECT"
+	EnvTLSCA            = "TLS_CA"
+	EnvDataDir          = "DATA_DIR"
+	EnvLogDir           = "LOG_DIR"
+	EnvAPIKeysFile      = "API_KEYS_FILE"
+	EnvAPIKeysDB        = "API_KEYS_DB"
+	EnvDeadLetterFile   = "DEAD_LETTER_FILE"
+	EnvAuditRetries     = "AUDIT_RETRIES"
+	EnvCompressLevel    = "COMPRESS_LEVEL"
+	EnvCompressExclude  = "COMPRESS_EXCLUDE"
+	EnvRateLimitRPS     = "RATE_LIMIT_RPS"
+	EnvRateLimitBurst   = "RATE_LIMIT_BURST"
+	EnvMaxBodySize      = "MAX_BODY_SIZE"
+	EnvMaxGzipRatio     = "MAX_GZIP_RATIO"
+	EnvStreamBatch      = "STREAM_BATCH"
+	EnvMetricTTL        = "METRIC_TTL"
+	EnvMetricTTLAction  = "METRIC_TTL_ACTION"
+	EnvReusePort        = "REUSE_PORT"
+	EnvH2C              = "H2C"
+	EnvAuditQueueSize   = "AUDIT_QUEUE_SIZE"
+	EnvAuditWorkers     = "AUDIT_WORKERS"
+	EnvAuditQueuePolicy = "AUDIT_QUEUE_POLICY"
+	EnvHTTP2            = "HTTP2"
 )
 
 // Константы для флагов командной строки
 const (
-	FlagAddress        = "a"
-	FlagRestore        = "r"
-	FlagStoreInterval  = "i"
-	FlagStoreFile      = "f"
-	FlagDatabaseDSN    = "d"
-	FlagCryptoKey      = "crypto-key"
-	FlagAuditFile      = "audit-file"
-	FlagAuditURL       = "audit-url"
-	FlagKey            = "k"
-	FlagTrustedSubnet  = "t"
-	FlagPollInterval   = "p"
-	FlagReportInterval = "r"
-	FlagRateLimit      = "l"
-	FlagConfig         = "c"
-	FlagGRPCAddress    = "grpc-address"
+	FlagAddress          = "a"
+	FlagRestore          = "r"
+	FlagStoreInterval    = "i"
+	FlagStoreFile        = "f"
+	FlagDatabaseDSN      = "d"
+	FlagCryptoKey        = "crypto-key"
+	FlagAuditFile        = "audit-file"
+	FlagAuditURL         = "audit-url"
+	FlagKey              = "k"
+	FlagTrustedSubnet    = "t"
+	FlagPollInterval     = "p"
+	FlagReportInterval   = "r"
+	FlagRateLimit        = "l"
+	FlagConfig           = "c"
+	FlagGRPCAddress      = "grpc-address"
+	FlagSnapshotFsync    = "snapshot-fsync"
+	FlagWatchdog         = "watchdog-interval"
+	FlagWALFile          = "wal-file"
+	FlagStorageShards    = "storage-shards"
+	FlagNormalizeIDs     = "normalize-ids"
+	FlagVersion          = "version"
+	FlagConfigTrace      = "config-trace"
+	FlagSelfTest         = "selftest"
+	FlagCSP              = "csp"
+	FlagPageRefresh      = "page-refresh"
+	FlagShutdownTimeout  = "shutdown-timeout"
+	FlagSpoolDir         = "spool-dir"
+	FlagSpoolMaxSize     = "spool-max-size"
+	FlagSpoolMaxAge      = "spool-max-age"
+	FlagEnrollTokens     = "enroll-tokens"
+	FlagAgentsFile       = "agents-file"
+	FlagEnrollToken      = "enroll-token"
+	FlagCredentialsFile  = "credentials-file"
+	FlagQueueSize        = "queue-size"
+	FlagQueuePolicy      = "queue-policy"
+	FlagQueueTimeout     = "queue-timeout"
+	FlagAPIKeys          = "api-keys"
+	FlagListen           = "listen"
+	FlagJWTSecret        = "jwt-secret"
+	FlagAPIKey           = "api-key"
+	FlagMaxBatchSize     = "max-batch-size"
+	FlagEndpointPolicy   = "endpoint-policy"
+	FlagEndpointCooldown = "endpoint-cooldown"
+	FlagAdminAddress     = "admin-address"
+	FlagAdminToken       = "admin-token"
+	FlagCollect          = "collect"
+	FlagNetInclude       = "net-include"
+	FlagNetExclude       = "net-exclude"
+	FlagStateFile        = "state-file"
+	FlagStatsDAddress    = "statsd-address"
+	FlagPushAddress      = "push-address"
+	FlagCollectIntervals = "collect-intervals"
+	FlagSendChangedOnly  = "send-changed-only"
+	FlagCPUMode          = "cpu-mode"
+	FlagBreakerThreshold = "breaker-threshold"
+	FlagBreakerCooldown  = "breaker-cooldown"
+	FlagCompressionDict  = "compression-dict"
+	FlagMaxRPS           = "max-rps"
+	FlagRequestIDFormat  = "request-id-format"
+	FlagSignKey          = "sign-key"
+	FlagVerifyKey        = "verify-key"
+	FlagTLSCert          = "tls-cert"
+	FlagTLSKey           = "tls-key"
+	FlagTLSRedirect      = "tls-redirect"
+	FlagTLSCA            = "tls-ca"
+	FlagDataDir          = "data-dir"
+	FlagLogDir           = "log-dir"
+	FlagAPIKeysFile      = "api-keys-file"
+	FlagAPIKeysDB        = "api-keys-db"
+	FlagDeadLetterFile   = "dead-letter-file"
+	FlagAuditRetries     = "audit-retries"
+	FlagCompressLevel    = "compress-level"
+	FlagCompressExclude  = "compress-exclude"
+	FlagRateLimitRPS     = "rate-limit-rps"
+	FlagRateLimitBurst   = "rate-limit-burst"
+	FlagMaxBodySize      = "max-body-size"
+	FlagMaxGzipRatio     = "max-gzip-ratio"
+	FlagStreamBatch      = "stream-batch"
+	FlagMetricTTL        = "metric-ttl"
+	FlagMetricTTLAction  = "metric-ttl-action"
+	FlagReusePort        = "reuse-port"
+	FlagH2C              = "h2c"
+	FlagAuditQueueSize   = "audit-queue-size"
+	FlagAuditWorkers     = "audit-workers"
+	FlagAuditQueuePolicy = "audit-queue-policy"
+	FlagHTTP2            = "http2"
 )
 
+// DefaultAdminAddress — адрес административного слушателя сервера (/admin/*, /status, pprof).
+// Порт 9090 не используется: его по умолчанию занимает Prometheus.
+const DefaultAdminAddress = "127.0.0.1:8081"
+
+// Значения по умолчанию для ограничения тел запросов сервера.
+const (
+	DefaultMaxBodySize  = 10 << 20 // 10 МиБ
+	DefaultMaxGzipRatio = 100      // байт распакованных данных на байт сжатых
+)
+
+// Значения по умолчанию для дискового спула агента.
+const (
+	DefaultSpoolMaxSize = 64 << 20 // 64 МиБ
+	DefaultSpoolMaxAge  = 3600     // 1 час, в секундах
+)
+
+// Значения по умолчанию для переключения агента между серверами.
+const (
+	DefaultEndpointPolicy   = "failover"
+	DefaultEndpointCooldown = 30 // в секундах
+)
+
+// Значения по умолчанию для очереди отправки агента.
+const (
+	DefaultQueueSize    = 16
+	DefaultQueuePolicy  = "drop-oldest"
+	DefaultQueueTimeout = 5 // в секундах
+)
+
+// Значения по умолчанию для размыкателя цепи отправки агента.
+const (
+	DefaultBreakerThreshold = 5  // ошибок подряд
+	DefaultBreakerCooldown  = 30 // в секундах
+)
+
+// DefaultCPUMode — режим отчёта о загрузке CPU по умолчанию: отдельный gauge для каждого ядра.
+const DefaultCPUMode = "per-core"
+
+// DefaultCredentialsFile — файл, в котором агент хранит учётные данные, полученные при регистрации.
+const DefaultCredentialsFile = "agent-credentials.json"
+
+// DefaultShutdownTimeout — время ожидания отправки последних батчей при завершении агента (сек).
+const DefaultShutdownTimeout = 15
+
+// DefaultRequestIDFormat — формат идентификаторов запросов и событий аудита по умолчанию.
+const DefaultRequestIDFormat = "ulid"
+
 type (
 	// ServerJSONConfig представляет конфигурацию сервера в формате JSON.
 	ServerJSONConfig struct {
-		Address       string `json:"address"`        // ADDRESS или флаг -a
-		Restore       *bool  `json:"restore"`        // RESTORE или флаг -r
-		StoreInterval string `json:"store_interval"` // STORE_INTERVAL или флаг -i (в формате "1s")
-		StoreFile     string `json:"store_file"`     // FILE_STORAGE_PATH или флаг -f
-		DatabaseDSN   string `json:"database_dsn"`   // DATABASE_DSN или флаг -d
-		CryptoKey     string `json:"crypto_key"`     // CRYPTO_KEY или флаг -crypto-key
-		AuditFile     string `json:"audit_file"`     // AUDIT_FILE или флаг -audit-file
-		AuditURL      string `json:"audit_url"`      // AUDIT_URL или флаг -audit-url
-		Key           string `json:"key"`            // KEY или флаг -k
-		TrustedSubnet string `json:"trusted_subnet"` // TRUSTED_SUBNET или флаг -t
-		GRPCAddress   string `json:"grpc_address"`   // GRPC_ADDRESS или флаг -grpc-address
+		Address         string                     `json:"address"`           // ADDRESS или флаг -a
+		Restore         *bool                      `json:"restore"`           // RESTORE или флаг -r
+		StoreInterval   string                     `json:"store_interval"`    // STORE_INTERVAL или флаг -i (в формате "1s")
+		StoreFile       string                     `json:"store_file"`        // FILE_STORAGE_PATH или флаг -f
+		DatabaseDSN     string                     `json:"database_dsn"`      // DATABASE_DSN или флаг -d
+		CryptoKey       string                     `json:"crypto_key"`        // CRYPTO_KEY или флаг -crypto-key
+		AuditFile       string                     `json:"audit_file"`        // AUDIT_FILE или флаг -audit-file
+		AuditURL        string                     `json:"audit_url"`         // AUDIT_URL или флаг -audit-url
+		Key             string                     `json:"key"`               // KEY или флаг -k
+		VerifyKey       string                     `json:"verify_key"`        // VERIFY_KEY или флаг -verify-key
+		TrustedSubnet   string                     `json:"trusted_subnet"`    // TRUSTED_SUBNET или флаг -t
+		GRPCAddress     string                     `json:"grpc_address"`      // GRPC_ADDRESS или флаг -grpc-address
+		SnapshotFsync   *bool                      `json:"snapshot_fsync"`    // SNAPSHOT_FSYNC или флаг -snapshot-fsync
+		Watchdog        *WatchdogJSONConfig        `json:"watchdog"`          // Настройки сторожевого таймера утечек
+		WALFile         string                     `json:"wal_file"`          // WAL_FILE или флаг -wal-file
+		StorageShards   *int                       `json:"storage_shards"`    // STORAGE_SHARDS или флаг -storage-shards
+		NormalizeIDs    *bool                      `json:"normalize_ids"`     // NORMALIZE_IDS или флаг -normalize-ids
+		Security        *SecurityHeadersJSONConfig `json:"security_headers"`  // Заголовки безопасности HTML-страниц
+		Backup          *BackupJSONConfig          `json:"backup"`            // Периодическое резервное копирование снимков
+		S3              *S3JSONConfig              `json:"s3"`                // Выгрузка снимков в S3-совместимое хранилище
+		PageRefresh     *PageRefreshJSONConfig     `json:"page_refresh"`      // Автообновление HTML-страницы метрик
+		EnrollTokens    []string                   `json:"enroll_tokens"`     // ENROLL_TOKENS или флаг -enroll-tokens (через запятую)
+		AgentsFile      string                     `json:"agents_file"`       // AGENTS_FILE или флаг -agents-file
+		Auth            *AuthJSONConfig            `json:"auth"`              // Ролевой доступ к API
+		Listeners       []ListenerConfig           `json:"listeners"`         // LISTEN или флаг -listen
+		AdminAddress    *string                    `json:"admin_address"`     // ADMIN_ADDRESS или флаг -admin-address (пустая строка отключает)
+		AdminToken      string                     `json:"admin_token"`       // ADMIN_TOKEN или флаг -admin-token
+		Observers       []ObserverConfig           `json:"observers"`         // Наблюдатели аудита (дополняют audit_file и audit_url)
+		RequestIDFormat string                     `json:"request_id_format"` // REQUEST_ID_FORMAT или флаг -request-id-format (ulid или uuidv7)
+		Storage         *StorageJSONConfig         `json:"storage"`           // Хранилища для дублирования записей
+		TLS             *TLSJSONConfig             `json:"tls"`               // Политика TLS слушателей
+		TLSCert         string                     `json:"tls_cert"`          // TLS_CERT или флаг -tls-cert
+		TLSKey          string                     `json:"tls_key"`           // TLS_KEY или флаг -tls-key
+		TLSRedirect     string                     `json:"tls_redirect"`      // TLS_REDIRECT или флаг -tls-redirect
+		DataDir         string                     `json:"data_dir"`          // DATA_DIR или флаг -data-dir
+		LogDir          string                     `json:"log_dir"`           // LOG_DIR или флаг -log-dir
+		DeadLetterFile  string                     `json:"dead_letter_file"`  // DEAD_LETTER_FILE или флаг -dead-letter-file
+		AuditRetries    *int                       `json:"audit_retries"`     // AUDIT_RETRIES или флаг -audit-retries
+		Compression     *CompressionJSONConfig     `json:"compression"`       // Сжатие ответов
+		RateLimitRPS    *int                       `json:"rate_limit_rps"`    // RATE_LIMIT_RPS или флаг -rate-limit-rps
+		RateLimitBurst  *int                       `json:"rate_limit_burst"`  // RATE_LIMIT_BURST или флаг -rate-limit-burst
+		MaxBodySize     *int                       `json:"max_body_size"`     // MAX_BODY_SIZE или флаг -max-body-size (в байтах)
+		MaxGzipRatio    *int                       `json:"max_gzip_ratio"`    // MAX_GZIP_RATIO или флаг -max-gzip-ratio
+		StreamBatch     *int                       `json:"stream_batch"`      // STREAM_BATCH или флаг -stream-batch
+		MetricTTL       *MetricTTLJSONConfig       `json:"metric_ttl"`        // Срок хранения метрик без обновлений
+		Tracing         *TracingJSONConfig         `json:"tracing"`           // Экспорт трассировки OpenTelemetry
+		Logger          *LoggerJSONConfig          `json:"logger"`            // Ротация файла журнала
+		ReusePort       *bool                      `json:"reuse_port"`        // REUSE_PORT или флаг -reuse-port
+		H2C             *bool                      `json:"h2c"`               // H2C или флаг -h2c
+		AuditQueue      *AuditQueueJSONConfig      `json:"audit_queue"`       // Асинхронная доставка событий аудита
+		AuditRotation   *AuditRotationJSONConfig   `json:"audit_rotation"`    // Ротация файлов аудита
+		AuditFilters    []AuditFilterRule          `json:"audit_filters"`     // Правила отбора событий аудита для наблюдателей
 	}
 
 	// AgentJSONConfig представляет конфигурацию агента в формате JSON.
 	AgentJSONConfig struct {
-		Address        string `json:"address"`         // ADDRESS или флаг -a
-		ReportInterval string `json:"report_interval"` // REPORT_INTERVAL или флаг -r (в формате "1s")
-		PollInterval   string `json:"poll_interval"`   // POLL_INTERVAL или флаг -p (в формате "1s")
-		RateLimit      *int   `json:"rate_limit"`      // RATE_LIMIT или флаг -l
-		CryptoKey      string `json:"crypto_key"`      // CRYPTO_KEY или флаг -crypto-key
-		Key            string `json:"key"`             // KEY или флаг -k
-		GRPCAddress    string `json:"grpc_address"`    // GRPC_ADDRESS или флаг -grpc-address
+		Address          string            `json:"address"`           // ADDRESS или флаг -a (несколько адресов через запятую)
+		ReportInterval   string            `json:"report_interval"`   // REPORT_INTERVAL или флаг -r (в формате "1s")
+		PollInterval     string            `json:"poll_interval"`     // POLL_INTERVAL или флаг -p (в формате "1s")
+		RateLimit        *int              `json:"rate_limit"`        // RATE_LIMIT или флаг -l
+		CryptoKey        string            `json:"crypto_key"`        // CRYPTO_KEY или флаг -crypto-key
+		Key              string            `json:"key"`               // KEY или флаг -k
+		SignKey          string            `json:"sign_key"`          // SIGN_KEY или флаг -sign-key
+		TLS              *TLSJSONConfig    `json:"tls"`               // Политика TLS HTTP-клиента
+		TLSCA            string            `json:"tls_ca"`            // TLS_CA или флаг -tls-ca
+		DataDir          string            `json:"data_dir"`          // DATA_DIR или флаг -data-dir
+		GRPCAddress      string            `json:"grpc_address"`      // GRPC_ADDRESS или флаг -grpc-address
+		SpoolDir         string            `json:"spool_dir"`         // SPOOL_DIR или флаг -spool-dir
+		SpoolMaxSize     *int              `json:"spool_max_size"`    // SPOOL_MAX_SIZE или флаг -spool-max-size (в байтах)
+		SpoolMaxAge      string            `json:"spool_max_age"`     // SPOOL_MAX_AGE или флаг -spool-max-age (в формате "1h")
+		ShutdownTimeout  string            `json:"shutdown_timeout"`  // SHUTDOWN_TIMEOUT или флаг -shutdown-timeout (в формате "15s")
+		EnrollToken      string            `json:"enroll_token"`      // ENROLL_TOKEN или флаг -enroll-token
+		CredentialsFile  string            `json:"credentials_file"`  // CREDENTIALS_FILE или флаг -credentials-file
+		QueueSize        *int              `json:"queue_size"`        // QUEUE_SIZE или флаг -queue-size
+		QueuePolicy      string            `json:"queue_policy"`      // QUEUE_POLICY или флаг -queue-policy
+		QueueTimeout     string            `json:"queue_timeout"`     // QUEUE_TIMEOUT или флаг -queue-timeout (в формате "5s")
+		APIKey           string            `json:"api_key"`           // API_KEY или флаг -api-key
+		MaxBatchSize     *int              `json:"max_batch_size"`    // MAX_BATCH_SIZE или флаг -max-batch-size
+		EndpointPolicy   string            `json:"endpoint_policy"`   // ENDPOINT_POLICY или флаг -endpoint-policy
+		EndpointCooldown string            `json:"endpoint_cooldown"` // ENDPOINT_COOLDOWN или флаг -endpoint-cooldown (в формате "30s")
+		Collect          string            `json:"collect"`           // COLLECT или флаг -collect (через запятую, например "disk,net")
+		NetInclude       []string          `json:"net_include"`       // NET_INCLUDE или флаг -net-include (шаблоны интерфейсов через запятую)
+		NetExclude       []string          `json:"net_exclude"`       // NET_EXCLUDE или флаг -net-exclude (шаблоны интерфейсов через запятую)
+		StateFile        string            `json:"state_file"`        // STATE_FILE или флаг -state-file
+		StatsDAddress    string            `json:"statsd_address"`    // STATSD_ADDRESS или флаг -statsd-address (UDP, например ":8125")
+		PushAddress      string            `json:"push_address"`      // PUSH_ADDRESS или флаг -push-address (например "127.0.0.1:8126")
+		CollectIntervals map[string]string `json:"collect_intervals"` // COLLECT_INTERVALS или флаг -collect-intervals (например {"disk": "60s"})
+		SendChangedOnly  *bool             `json:"send_changed_only"` // SEND_CHANGED_ONLY или флаг -send-changed-only
+		CPUMode          string            `json:"cpu_mode"`          // CPU_MODE или флаг -cpu-mode (per-core или total)
+		BreakerThreshold *int              `json:"breaker_threshold"` // BREAKER_THRESHOLD или флаг -breaker-threshold (0 — отключён)
+		BreakerCooldown  string            `json:"breaker_cooldown"`  // BREAKER_COOLDOWN или флаг -breaker-cooldown (в формате "30s")
+		CompressionDict  *bool             `json:"compression_dict"`  // COMPRESSION_DICT или флаг -compression-dict
+		MaxRPS           *int              `json:"max_rps"`           // MAX_RPS или флаг -max-rps (0 — без ограничения)
+		HTTP2            *bool             `json:"http2"`             // HTTP2 или флаг -http2
+
+		Tracing *TracingJSONConfig `json:"tracing"` // Экспорт трассировки OpenTelemetry
 	}
 )
 
-func (jc *AgentJSONConfig) ApplyToAgent(
-	poll *int,
-	report *int,
-	limit *int,
-	key *string,
-	crypto *string,
-	addr *NetAddress,
-	grpcAddr *string,
-) {
+// AgentTargets — параметры агента, в которые ApplyToAgent записывает значения JSON-конфига.
+// Поля со значением nil пропускаются.
+type AgentTargets struct {
+	Address          *AddressList // -a
+	PollInterval     *int         // -p, в секундах
+	ReportInterval   *int         // -r, в секундах
+	RateLimit        *int         // -l
+	Key              *string      // -k
+	CryptoKey        *string      // -crypto-key
+	SignKey          *string      // -sign-key
+	GRPCAddress      *string      // -grpc-address
+	SpoolDir         *string      // -spool-dir
+	SpoolMaxSize     *int         // -spool-max-size
+	SpoolMaxAge      *int         // -spool-max-age, в секундах
+	ShutdownTimeout  *int         // -shutdown-timeout, в секундах
+	EnrollToken      *string      // -enroll-token
+	CredentialsFile  *string      // -credentials-file
+	QueueSize        *int         // -queue-size
+	QueuePolicy      *string      // -queue-policy
+	QueueTimeout     *int         // -queue-timeout, в секундах
+	APIKey           *string      // -api-key
+	MaxBatchSize     *int         // -max-batch-size
+	EndpointPolicy   *string      // -endpoint-policy
+	EndpointCooldown *int         // -endpoint-cooldown, в секундах
+	Collect          *string      // -collect
+	NetInclude       *string      // -net-include
+	NetExclude       *string      // -net-exclude
+	StateFile        *string      // -state-file
+	StatsDAddress    *string      // -statsd-address
+	PushAddress      *string      // -push-address
+	CollectIntervals *string      // -collect-intervals
+	SendChangedOnly  *bool        // -send-changed-only
+	CPUMode          *string      // -cpu-mode
+	BreakerThreshold *int         // -breaker-threshold
+	BreakerCooldown  *int         // -breaker-cooldown, в секундах
+	CompressionDict  *bool        // -compression-dict
+	MaxRPS           *int         // -max-rps
+	HTTP2            *bool        // -http2
+	TLS              *TLSConfig   // Политика TLS HTTP-клиента
+	TLSCA            *string      // -tls-ca
+	DataDir          *string      // -data-dir
+
+	Tracing *TracingConfig // Экспорт трассировки OpenTelemetry
+}
+
+// ApplyToAgent применяет настройки из AgentJSONConfig к параметрам агента t.
+//
+// Значение JSON применяется, только если параметр не задан переменной окружения или флагом
+// (set, см. Options.Explicit), даже если явно заданное значение совпадает со значением по умолчанию.
+// Возвращает имена флагов, значения которых взяты из JSON (используется трассировкой конфигурации).
+func (jc *AgentJSONConfig) ApplyToAgent(t AgentTargets, set Sources) []string {
 	if jc == nil {
-		return
+		return nil
 	}
+	a := &jsonApplier{set: set}
 
-	if jc.Address != "" && addr.String() == "localhost:8080" {
-		_ = addr.Set(jc.Address)
+	if t.Address != nil && a.use(FlagAddress, jc.Address != "") {
+		_ = t.Address.Set(jc.Address)
 	}
 
-	// PollInterval.
-	if *poll == 2 && jc.PollInterval != "" {
-		if val, err := ParseDuration(jc.PollInterval); err == nil && val != 0 {
-			*poll = val
-		}
+	// Интервалы опроса и отправки.
+	a.duration(FlagPollInterval, t.PollInterval, jc.PollInterval)
+	a.duration(FlagReportInterval, t.ReportInterval, jc.ReportInterval)
+
+	if jc.RateLimit != nil {
+		applyJSON(a, FlagRateLimit, t.RateLimit, *jc.RateLimit)
 	}
+	a.str(FlagKey, t.Key, jc.Key)
+	a.str(FlagCryptoKey, t.CryptoKey, jc.CryptoKey)
+	a.str(FlagSignKey, t.SignKey, jc.SignKey)
+	a.str(FlagGRPCAddress, t.GRPCAddress, jc.GRPCAddress)
 
-	// ReportInterval.
-	if *report == 10 && jc.ReportInterval != "" {
-		if val, err := ParseDuration(jc.ReportInterval); err == nil && val != 0 {
-			*report = val
-		}
+	// Spool.
+	a.str(FlagSpoolDir, t.SpoolDir, jc.SpoolDir)
+	if jc.SpoolMaxSize != nil {
+		applyJSON(a, FlagSpoolMaxSize, t.SpoolMaxSize, *jc.SpoolMaxSize)
 	}
+	a.duration(FlagSpoolMaxAge, t.SpoolMaxAge, jc.SpoolMaxAge)
+
+	a.duration(FlagShutdownTimeout, t.ShutdownTimeout, jc.ShutdownTimeout)
+
+	// Enrollment.
+	a.str(FlagEnrollToken, t.EnrollToken, jc.EnrollToken)
+	a.str(FlagCredentialsFile, t.CredentialsFile, jc.CredentialsFile)
 
-	// RateLimit.
-	if *limit == 1 && jc.RateLimit != nil {
-		*limit = *jc.RateLimit
+	// Send queue.
+	if jc.QueueSize != nil {
+		applyJSON(a, FlagQueueSize, t.QueueSize, *jc.QueueSize)
 	}
+	a.str(FlagQueuePolicy, t.QueuePolicy, jc.QueuePolicy)
+	a.duration(FlagQueueTimeout, t.QueueTimeout, jc.QueueTimeout)
 
-	// Key.
-	if *key == "" && jc.Key != "" {
-		*key = jc.Key
+	a.str(FlagAPIKey, t.APIKey, jc.APIKey)
+	if jc.MaxBatchSize != nil {
+		applyJSON(a, FlagMaxBatchSize, t.MaxBatchSize, *jc.MaxBatchSize)
 	}
 
-	// CryptoKey.
-	if *crypto == "" && jc.CryptoKey != "" {
-		*crypto = jc.CryptoKey
+	// Endpoints.
+	a.str(FlagEndpointPolicy, t.EndpointPolicy, jc.EndpointPolicy)
+	a.duration(FlagEndpointCooldown, t.EndpointCooldown, jc.EndpointCooldown)
+
+	// Collect.
+	a.str(FlagCollect, t.Collect, jc.Collect)
+	a.str(FlagNetInclude, t.NetInclude, strings.Join(jc.NetInclude, ","))
+	a.str(FlagNetExclude, t.NetExclude, strings.Join(jc.NetExclude, ","))
+
+	a.str(FlagStateFile, t.StateFile, jc.StateFile)
+	a.str(FlagStatsDAddress, t.StatsDAddress, jc.StatsDAddress)
+	a.str(FlagPushAddress, t.PushAddress, jc.PushAddress)
+
+	// CollectIntervals.
+	if len(jc.CollectIntervals) > 0 {
+		items := make([]string, 0, len(jc.CollectIntervals))
+		for group, interval := range jc.CollectIntervals {
+			items = append(items, group+"="+interval)
+		}
+		sort.Strings(items)
+		a.str(FlagCollectIntervals, t.CollectIntervals, strings.Join(items, ","))
 	}
 
-	// GRPCAddress.
-	if *grpcAddr == "" && jc.GRPCAddress != "" {
-		*grpcAddr = jc.GRPCAddress
+	if jc.SendChangedOnly != nil {
+		applyJSON(a, FlagSendChangedOnly, t.SendChangedOnly, *jc.SendChangedOnly)
 	}
+	a.str(FlagCPUMode, t.CPUMode, jc.CPUMode)
+
+	// BreakerThreshold и BreakerCooldown.
+	if jc.BreakerThreshold != nil {
+		applyJSON(a, FlagBreakerThreshold, t.BreakerThreshold, *jc.BreakerThreshold)
+	}
+	a.duration(FlagBreakerCooldown, t.BreakerCooldown, jc.BreakerCooldown)
+
+	if jc.CompressionDict != nil {
+		applyJSON(a, FlagCompressionDict, t.CompressionDict, *jc.CompressionDict)
+	}
+	if jc.MaxRPS != nil {
+		applyJSON(a, FlagMaxRPS, t.MaxRPS, *jc.MaxRPS)
+	}
+	if jc.HTTP2 != nil {
+		applyJSON(a, FlagHTTP2, t.HTTP2, *jc.HTTP2)
+	}
+
+	// TLS.
+	jc.TLS.apply(t.TLS)
+	a.str(FlagTLSCA, t.TLSCA, jc.TLSCA)
+	a.str(FlagDataDir, t.DataDir, jc.DataDir)
+	jc.Tracing.apply(t.Tracing)
+	return a.applied
+}
+
+// ServerTargets — параметры сервера, в которые ApplyToServer записывает значения JSON-конфига.
+// Поля со значением nil пропускаются.
+type ServerTargets struct {
+	Address         *NetAddress            // -a
+	DatabaseDSN     *string                // -d
+	StoreInterval   *int                   // -i, в секундах
+	StoreFile       *string                // -f
+	Restore         *bool                  // -r
+	Key             *string                // -k
+	CryptoKey       *string                // -crypto-key
+	VerifyKey       *string                // -verify-key
+	AuditFile       *string                // -audit-file
+	AuditURL        *string                // -audit-url
+	TrustedSubnet   *string                // -t
+	GRPCAddress     *string                // -grpc-address
+	SnapshotFsync   *bool                  // -snapshot-fsync
+	Watchdog        *WatchdogConfig        // Сторожевой таймер утечек
+	WALFile         *string                // -wal-file
+	StorageShards   *int                   // -storage-shards
+	NormalizeIDs    *bool                  // -normalize-ids
+	Security        *SecurityHeadersConfig // Заголовки безопасности HTML-страниц
+	Backup          *BackupConfig          // Резервное копирование снимков
+	S3              *S3Config              // Выгрузка снимков в S3
+	PageRefresh     *PageRefreshConfig     // Автообновление HTML-страницы
+	EnrollTokens    *string                // -enroll-tokens
+	AgentsFile      *string                // -agents-file
+	Auth            *AuthConfig            // Ролевой доступ к API
+	Listeners       *[]ListenerConfig      // -listen
+	AdminAddress    *string                // -admin-address
+	AdminToken      *string                // -admin-token
+	Observers       *[]ObserverConfig      // Наблюдатели аудита
+	RequestIDFormat *string                // -request-id-format
+	Storage         *StorageConfig         // Хранилища для дублирования записей
+	TLS             *TLSConfig             // Политика TLS слушателей
+	TLSCert         *string                // -tls-cert
+	TLSKey          *string                // -tls-key
+	TLSRedirect     *string                // -tls-redirect
+	DataDir         *string                // -data-dir
+	LogDir          *string                // -log-dir
+	DeadLetterFile  *string                // -dead-letter-file
+	AuditRetries    *int                   // -audit-retries
+	Compression     *CompressionConfig     // Сжатие ответов
+	RateLimitRPS    *int                   // -rate-limit-rps
+	RateLimitBurst  *int                   // -rate-limit-burst
+	MaxBodySize     *int                   // -max-body-size
+	MaxGzipRatio    *int                   // -max-gzip-ratio
+	StreamBatch     *int                   // -stream-batch
+	MetricTTL       *MetricTTLConfig       // Срок хранения метрик без обновлений
+	Tracing         *TracingConfig         // Экспорт трассировки OpenTelemetry
+	Logger          *LoggerConfig          // Ротация файла журнала
+	ReusePort       *bool                  // -reuse-port
+	H2C             *bool                  // -h2c
+	AuditQueue      *AuditQueueConfig      // Асинхронная доставка событий аудита
+	AuditRotation   *AuditRotationConfig   // Ротация файлов аудита
+	AuditFilters    *[]AuditFilterRule     // Правила отбора событий аудита
 }
 
-// ApplyToServer применяет настройки из ServerJSONConfig к переданным параметрам,
-func (jc *ServerJSONConfig) ApplyToServer(
-	addr *NetAddress,
-	dsn *string,
-	storeInt *int,
-	storeFile *string,
-	restore *bool,
-	key *string,
-	crypto *string,
-	auditFile *string,
-	auditURL *string,
-	trustedSubnet *string,
-	grpcAddr *string,
-) {
+// ApplyToServer применяет настройки из ServerJSONConfig к параметрам сервера t.
+//
+// Правила те же, что у ApplyToAgent: параметры из set не перезаписываются, секции без флагов
+// (backup, s3, observers, storage, tls, tracing, logger) применяются целиком. Возвращает имена флагов,
+// значения которых взяты из JSON.
+func (jc *ServerJSONConfig) ApplyToServer(t ServerTargets, set Sources) []string {
 	if jc == nil {
-		return
+		return nil
 	}
+	a := &jsonApplier{set: set}
 
-	if jc.Address != "" && addr.String() == "localhost:8080" {
-		_ = addr.Set(jc.Address)
+	if t.Address != nil && a.use(FlagAddress, jc.Address != "") {
+		_ = t.Address.Set(jc.Address)
+	}
+	a.str(FlagDatabaseDSN, t.DatabaseDSN, jc.DatabaseDSN)
+	a.duration(FlagStoreInterval, t.StoreInterval, jc.StoreInterval)
+	a.str(FlagStoreFile, t.StoreFile, jc.StoreFile)
+	if jc.Restore != nil {
+		applyJSON(a, FlagRestore, t.Restore, *jc.Restore)
 	}
-	if *dsn == "" && jc.DatabaseDSN != "" {
-		*dsn = jc.DatabaseDSN
+	a.str(FlagKey, t.Key, jc.Key)
+	a.str(FlagCryptoKey, t.CryptoKey, jc.CryptoKey)
+	a.str(FlagVerifyKey, t.VerifyKey, jc.VerifyKey)
+	a.str(FlagAuditFile, t.AuditFile, jc.AuditFile)
+	a.str(FlagAuditURL, t.AuditURL, jc.AuditURL)
+	a.str(FlagTrustedSubnet, t.TrustedSubnet, jc.TrustedSubnet)
+	a.str(FlagGRPCAddress, t.GRPCAddress, jc.GRPCAddress)
+	if jc.SnapshotFsync != nil {
+		applyJSON(a, FlagSnapshotFsync, t.SnapshotFsync, *jc.SnapshotFsync)
 	}
-	if *storeInt == 300 && jc.StoreInterval != "" {
-		if val, err := ParseDuration(jc.StoreInterval); err == nil && val != 0 {
-			*storeInt = val
+	jc.Watchdog.apply(t.Watchdog, a)
+	a.str(FlagWALFile, t.WALFile, jc.WALFile)
+	if jc.StorageShards != nil {
+		applyJSON(a, FlagStorageShards, t.StorageShards, *jc.StorageShards)
+	}
+	if jc.NormalizeIDs != nil {
+		applyJSON(a, FlagNormalizeIDs, t.NormalizeIDs, *jc.NormalizeIDs)
+	}
+	jc.Security.apply(t.Security, a)
+	jc.Backup.apply(t.Backup)
+	jc.S3.apply(t.S3)
+	jc.PageRefresh.apply(t.PageRefresh, a)
+	a.str(FlagEnrollTokens, t.EnrollTokens, strings.Join(jc.EnrollTokens, ","))
+	a.str(FlagAgentsFile, t.AgentsFile, jc.AgentsFile)
+	jc.Auth.apply(t.Auth, a)
+	if t.Listeners != nil && a.use(FlagListen, len(jc.Listeners) > 0) {
+		for _, l := range jc.Listeners {
+			if err := l.Validate(); err != nil {
+				log.Printf("Warning: ignoring listener: %v", err)
+				continue
+			}
+			*t.Listeners = append(*t.Listeners, l)
 		}
 	}
-	if *storeFile == "metrics.json" && jc.StoreFile != "" {
-		*storeFile = jc.StoreFile
+	if jc.AdminAddress != nil {
+		applyJSON(a, FlagAdminAddress, t.AdminAddress, *jc.AdminAddress)
 	}
-	if jc.Restore != nil {
-		*restore = *jc.Restore
+	a.str(FlagAdminToken, t.AdminToken, jc.AdminToken)
+	if t.Observers != nil {
+		*t.Observers = append(*t.Observers, jc.Observers...)
+	}
+	a.str(FlagRequestIDFormat, t.RequestIDFormat, jc.RequestIDFormat)
+	jc.Storage.apply(t.Storage)
+	jc.TLS.apply(t.TLS)
+	a.str(FlagTLSCert, t.TLSCert, jc.TLSCert)
+	a.str(FlagTLSKey, t.TLSKey, jc.TLSKey)
+	a.str(FlagTLSRedirect, t.TLSRedirect, jc.TLSRedirect)
+	a.str(FlagDataDir, t.DataDir, jc.DataDir)
+	a.str(FlagLogDir, t.LogDir, jc.LogDir)
+	a.str(FlagDeadLetterFile, t.DeadLetterFile, jc.DeadLetterFile)
+	if jc.AuditRetries != nil {
+		applyJSON(a, FlagAuditRetries, t.AuditRetries, *jc.AuditRetries)
 	}
-	if *key == "" && jc.Key != "" {
-		*key = jc.Key
+	jc.Compression.apply(t.Compression, a)
+	if jc.RateLimitRPS != nil {
+		applyJSON(a, FlagRateLimitRPS, t.RateLimitRPS, *jc.RateLimitRPS)
 	}
-	if *crypto == "" && jc.CryptoKey != "" {
-		*crypto = jc.CryptoKey
+	if jc.RateLimitBurst != nil {
+		applyJSON(a, FlagRateLimitBurst, t.RateLimitBurst, *jc.RateLimitBurst)
 	}
-	if *auditFile == "" && jc.AuditFile != "" {
-		*auditFile = jc.AuditFile
+	if jc.MaxBodySize != nil {
+		applyJSON(a, FlagMaxBodySize, t.MaxBodySize, *jc.MaxBodySize)
 	}
-	if *auditURL == "" && jc.AuditURL != "" {
-		*auditURL = jc.AuditURL
+	if jc.MaxGzipRatio != nil {
+		applyJSON(a, FlagMaxGzipRatio, t.MaxGzipRatio, *jc.MaxGzipRatio)
 	}
-	if *trustedSubnet == "" && jc.TrustedSubnet != "" {
-		*trustedSubnet = jc.TrustedSubnet
+	if jc.StreamBatch != nil {
+		applyJSON(a, FlagStreamBatch, t.StreamBatch, *jc.StreamBatch)
 	}
-	if *grpcAddr == "" && jc.GRPCAddress != "" {
-		*grpcAddr = jc.GRPCAddress
+	jc.MetricTTL.apply(t.MetricTTL, a)
+	jc.Tracing.apply(t.Tracing)
+	jc.Logger.apply(t.Logger)
+	if jc.ReusePort != nil {
+		applyJSON(a, FlagReusePort, t.ReusePort, *jc.ReusePort)
+	}
+	if jc.H2C != nil {
+		applyJSON(a, FlagH2C, t.H2C, *jc.H2C)
+	}
+	jc.AuditQueue.apply(t.AuditQueue, a)
+	jc.AuditRotation.apply(t.AuditRotation)
+	if t.AuditFilters != nil {
+		*t.AuditFilters = append(*t.AuditFilters, jc.AuditFilters...)
+	}
+	return a.applied
+}
+
+// jsonApplier применяет значения JSON-конфига к параметрам, не заданным окружением или флагом,
+// и запоминает, какие параметры взяты из JSON.
+type jsonApplier struct {
+	set     Sources  // Параметры, заданные окружением или флагом.
+	applied []string // Флаги, значения которых взяты из JSON.
+}
+
+// use сообщает, применять ли значение JSON к параметру flagName, и запоминает применение.
+// ok — значение задано в JSON.
+func (a *jsonApplier) use(flagName string, ok bool) bool {
+	if !ok {
+		return false
+	}
+	if _, explicit := a.set[flagName]; explicit {
+		return false
+	}
+	a.applied = append(a.applied, flagName)
+	return true
+}
+
+// str применяет непустую строку v к dst.
+func (a *jsonApplier) str(flagName string, dst *string, v string) {
+	if dst != nil && a.use(flagName, v != "") {
+		*dst = v
+	}
+}
+
+// duration применяет длительность v в формате "1s" к dst в секундах.
+// Пустое, нулевое и некорректное значение пропускаются.
+func (a *jsonApplier) duration(flagName string, dst *int, v string) {
+	val, err := ParseDuration(v)
+	if dst != nil && a.use(flagName, err == nil && val != 0) {
+		*dst = val
+	}
+}
+
+// applyJSON применяет заданное в JSON значение v к dst.
+func applyJSON[T any](a *jsonApplier, flagName string, dst *T, v T) {
+	if dst != nil && a.use(flagName, true) {
+		*dst = v
 	}
 }
 
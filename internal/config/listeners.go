@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Группы маршрутов, которые может обслуживать слушатель.
+const (
+	RouteGroupIngest = "ingest" // Приём метрик и регистрация агентов
+	RouteGroupRead   = "read"   // Чтение метрик и HTML-страница
+	RouteGroupAdmin  = "admin"  // Административные обработчики /admin/*
+)
+
+// ListenerConfig описывает дополнительный адрес, на котором сервер принимает HTTP-запросы.
+//
+// Поля:
+//   - Address: адрес host:port (IPv6 — в квадратных скобках, например "[::]:8080")
+//   - Routes: обслуживаемые группы маршрутов (пусто — все группы)
+type ListenerConfig struct {
+	Address string   `json:"address"`
+	Routes  []string `json:"routes"`
+}
+
+// ParseListeners разбирает список слушателей в формате "addr[=group+group],...",
+// например "0.0.0.0:8080=ingest+read,[::]:8080=ingest+read,127.0.0.1:8081=admin".
+func ParseListeners(s string) ([]ListenerConfig, error) {
+	var listeners []ListenerConfig
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		addr, groups, _ := strings.Cut(item, "=")
+		l := ListenerConfig{Address: strings.TrimSpace(addr)}
+		if groups != "" {
+			l.Routes = strings.Split(groups, "+")
+		}
+		if err := l.Validate(); err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// Validate проверяет адрес и имена групп маршрутов слушателя.
+func (l ListenerConfig) Validate() error {
+	if l.Address == "" {
+		return fmt.Errorf("listener address is empty")
+	}
+	for _, g := range l.Routes {
+		switch g {
+		case RouteGroupIngest, RouteGroupRead, RouteGroupAdmin:
+		default:
+			return fmt.Errorf("listener %s: unknown route group %q", l.Address, g)
+		}
+	}
+	return nil
+}
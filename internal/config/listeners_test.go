@@ -0,0 +1,49 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseListeners проверяет разбор списка слушателей с группами маршрутов.
+func TestParseListeners(t *testing.T) {
+	tests := []struct {
+		name      string           // Название теста
+		input     string           // Входная строка
+		expected  []ListenerConfig // Ожидаемые слушатели
+		expectErr bool             // Ожидается ли ошибка
+	}{
+		{"empty", "", nil, false},
+		{
+			"dual stack and admin port",
+			"0.0.0.0:8080=ingest+read, [::]:8080=ingest+read,127.0.0.1:8081=admin",
+			[]ListenerConfig{
+				{Address: "0.0.0.0:8080", Routes: []string{"ingest", "read"}},
+				{Address: "[::]:8080", Routes: []string{"ingest", "read"}},
+				{Address: "127.0.0.1:8081", Routes: []string{"admin"}},
+			},
+			false,
+		},
+		{"all routes", ":8080", []ListenerConfig{{Address: ":8080"}}, false},
+		{"unknown group", ":8080=metrics", nil, true},
+		{"empty address", "=admin", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseListeners(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("expected error for input %q, got nil", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error for input %q: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("listeners mismatch: expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
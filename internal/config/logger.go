@@ -1,34 +1,155 @@
 package config
 
 import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/RoGogDBD/metric-alerter/internal/requestid"
+	"github.com/RoGogDBD/metric-alerter/internal/timing"
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+const (
+	// LogDir — директория для файлов логов.
+	LogDir = "./logs"
+	// LogFileName — имя файла журнала приложения в директории логов.
+	LogFileName = "app.log"
+	// LogFile — путь к файлу журнала приложения.
+	LogFile = LogDir + "/" + LogFileName
+)
+
+// Настройки ротации журнала по умолчанию.
+const (
+	DefaultLogMaxSizeMB  = 100 // Размер файла журнала, после которого он ротируется (МБ)
+	DefaultLogMaxBackups = 10  // Количество хранимых ротированных файлов
+)
+
+// DefaultSlowRequestThreshold — длительность запроса, начиная с которой он журналируется как медленный.
+const DefaultSlowRequestThreshold = time.Second
+
+type (
+	// LoggerConfig описывает ротацию файла журнала приложения и журналирование HTTP-запросов.
+	//
+	// Поля:
+	//   - MaxSizeMB: размер файла в мегабайтах, после которого он переименовывается и начинается новый (0 — без ротации)
+	//   - MaxAge: срок хранения ротированных файлов, округляется вверх до суток (0 — без ограничения)
+	//   - MaxBackups: количество хранимых ротированных файлов (0 — без ограничения)
+	//   - Compress: сжимать ротированные файлы gzip
+	//   - ExcludePaths: пути запросов, которые не журналируются ("/ping", "/healthz", "/admin/*")
+	//   - SampleRates: шаблон пути → N; из успешных запросов к таким путям журналируется каждый N-й
+	//   - SlowThreshold: запросы не быстрее этой длительности журналируются с уровнем WARN
+	//     и подробностями (0 — отключено)
+	LoggerConfig struct {
+		MaxSizeMB  int
+		MaxAge     time.Duration
+		MaxBackups int
+		Compress   bool
+
+		ExcludePaths  []string
+		SampleRates   map[string]int
+		SlowThreshold time.Duration
+	}
+
+	// LoggerJSONConfig представляет секцию "logger" JSON-конфигурации сервера.
+	LoggerJSONConfig struct {
+		MaxSizeMB  *int   `json:"max_size_mb"` // Размер файла журнала для ротации (МБ, 0 — без ротации)
+		MaxAge     string `json:"max_age"`     // Срок хранения ротированных файлов (в формате "168h")
+		MaxBackups *int   `json:"max_backups"` // Количество хранимых ротированных файлов
+		Compress   *bool  `json:"compress"`    // Сжимать ротированные файлы
+
+		ExcludePaths  []string       `json:"exclude_paths"`  // Пути запросов, которые не журналируются
+		Sample        map[string]int `json:"sample"`         // Журналировать каждый N-й успешный запрос к пути
+		SlowThreshold string         `json:"slow_threshold"` // Порог медленного запроса (в формате "500ms", "0s" — отключено)
+	}
+)
+
+// DefaultLoggerConfig возвращает настройки ротации журнала по умолчанию.
+func DefaultLoggerConfig() LoggerConfig {
+	return LoggerConfig{
+		MaxSizeMB:     DefaultLogMaxSizeMB,
+		MaxBackups:    DefaultLogMaxBackups,
+		SlowThreshold: DefaultSlowRequestThreshold,
+	}
+}
+
+// Validate проверяет, что параметры ротации и порог медленного запроса не отрицательны,
+// а доли выборки положительны.
+func (c LoggerConfig) Validate() error {
+	for p, n := range c.SampleRates {
+		if n < 1 {
+			return fmt.Errorf("invalid logger sample rate %d for %q: must be at least 1", n, p)
+		}
+	}
+	if c.MaxSizeMB < 0 {
+		return fmt.Errorf("invalid logger max_size_mb %d: must not be negative", c.MaxSizeMB)
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("invalid logger max_age %s: must not be negative", c.MaxAge)
+	}
+	if c.MaxBackups < 0 {
+		return fmt.Errorf("invalid logger max_backups %d: must not be negative", c.MaxBackups)
+	}
+	if c.SlowThreshold < 0 {
+		return fmt.Errorf("invalid logger slow_threshold %s: must not be negative", c.SlowThreshold)
+	}
+	return nil
+}
+
+// apply применяет значения секции JSON к cfg.
+func (jc *LoggerJSONConfig) apply(cfg *LoggerConfig) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if jc.MaxSizeMB != nil {
+		cfg.MaxSizeMB = *jc.MaxSizeMB
+	}
+	if d, err := time.ParseDuration(jc.MaxAge); jc.MaxAge != "" && err == nil {
+		cfg.MaxAge = d
+	}
+	if jc.MaxBackups != nil {
+		cfg.MaxBackups = *jc.MaxBackups
+	}
+	if jc.Compress != nil {
+		cfg.Compress = *jc.Compress
+	}
+	if jc.ExcludePaths != nil {
+		cfg.ExcludePaths = jc.ExcludePaths
+	}
+	if jc.Sample != nil {
+		cfg.SampleRates = jc.Sample
+	}
+	if d, err := time.ParseDuration(jc.SlowThreshold); jc.SlowThreshold != "" && err == nil {
+		cfg.SlowThreshold = d
+	}
+}
+
 // Initialize инициализирует zap.Logger с заданным уровнем логирования.
 //
 // level — строка, определяющая уровень логирования ("debug", "warn", "error", по умолчанию "info").
-// Логи пишутся в файл ./logs/app.log и в stdout. Время логируется в формате ISO8601.
+// Логи пишутся в файл ./logs/app.log (с ротацией по DefaultLoggerConfig) и в stdout.
+// Время логируется в формате ISO8601.
 //
 // Возвращает инициализированный *zap.Logger или ошибку при неудаче.
 func Initialize(level string) (*zap.Logger, error) {
-	if err := os.MkdirAll("./logs", 0755); err != nil {
-		return nil, err
-	}
-	config := zap.NewProductionConfig()
-	config.OutputPaths = []string{
-		"./logs/app.log",
-		"stdout",
-	}
-
-	config.EncoderConfig.TimeKey = "timestamp"
-	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	return InitializeWithLevel(NewLogLevel(level))
+}
 
+// NewLogLevel возвращает изменяемый уровень логирования для строки level
+// ("debug", "warn", "error", по умолчанию "info").
+func NewLogLevel(level string) zap.AtomicLevel {
 	lvl := zapcore.InfoLevel
 	switch strings.ToLower(level) {
 	case "debug":
@@ -38,14 +159,65 @@ func Initialize(level string) (*zap.Logger, error) {
 	case "error":
 		lvl = zapcore.ErrorLevel
 	}
-	config.Level = zap.NewAtomicLevelAt(lvl)
+	return zap.NewAtomicLevelAt(lvl)
+}
 
-	logger, err := config.Build()
+// InitializeWithLevel инициализирует zap.Logger с изменяемым уровнем логирования.
+//
+// Изменение level во время работы сразу меняет подробность журнала без пересоздания логгера.
+func InitializeWithLevel(level zap.AtomicLevel) (*zap.Logger, error) {
+	return InitializeInDir(LogDir, level, DefaultLoggerConfig())
+}
+
+// InitializeInDir инициализирует zap.Logger, который пишет в dir/app.log и stdout.
+//
+// Директория dir создаётся при необходимости. Файл журнала ротируется по настройкам cfg:
+// ротированные файлы (app-<время>.log, при cfg.Compress — .log.gz) остаются в dir.
+// Кодирование, выборка повторяющихся сообщений и вывод стека такие же, как
+// у zap.NewProductionConfig.
+func InitializeInDir(dir string, level zap.AtomicLevel, cfg LoggerConfig) (*zap.Logger, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	file, err := logFileWriter(filepath.Join(dir, LogFileName), cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return logger, nil
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "timestamp"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	core := zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderConfig),
+		zapcore.NewMultiWriteSyncer(file, zapcore.Lock(os.Stdout)),
+		level,
+	)
+	core = zapcore.NewSamplerWithOptions(core, time.Second, 100, 100)
+	return zap.New(core,
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)),
+		zap.AddCaller(),
+		zap.AddStacktrace(zapcore.ErrorLevel),
+	), nil
+}
+
+// logFileWriter открывает файл журнала path; при cfg.MaxSizeMB > 0 — с ротацией.
+func logFileWriter(path string, cfg LoggerConfig) (zapcore.WriteSyncer, error) {
+	if cfg.MaxSizeMB <= 0 {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return zapcore.Lock(f), nil
+	}
+	// lumberjack сам синхронизирует запись и ротацию, поэтому zapcore.Lock не нужен.
+	return zapcore.AddSync(&lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxAge:     int((cfg.MaxAge + 24*time.Hour - 1) / (24 * time.Hour)),
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+	}), nil
 }
 
 // statusRecorder реализует http.ResponseWriter и позволяет сохранять статус и размер ответа.
@@ -70,26 +242,130 @@ func (r *statusRecorder) Write(b []byte) (int, error) {
 	return size, err
 }
 
+// countingBody подсчитывает байты, прочитанные из тела запроса (до распаковки).
+type countingBody struct {
+	io.ReadCloser
+	n int64 // Прочитано байт
+}
+
+// Read читает из тела запроса и увеличивает счётчик прочитанных байт.
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// sampledPath — шаблон пути, запросы к которому журналируются выборочно.
+type sampledPath struct {
+	pattern string        // Шаблон пути (см. LoggerConfig.SampleRates)
+	rate    uint64        // Журналируется каждый rate-й успешный запрос
+	seen    atomic.Uint64 // Количество успешных запросов к пути
+}
+
 // RequestLogger возвращает middleware для логирования HTTP-запросов с помощью zap.Logger.
 //
-// Для каждого запроса логируются метод, URL, статус, размер ответа, длительность и удалённый адрес.
-func RequestLogger(logger *zap.Logger) func(http.Handler) http.Handler {
+// Для каждого запроса логируются метод, URL, статус, размер ответа, длительность, удалённый адрес,
+// IP-адрес клиента и идентификатор запроса (если он задан requestid.Middleware).
+//
+// Запросы к путям cfg.ExcludePaths не журналируются. Из успешных запросов к путям
+// cfg.SampleRates журналируется первый и далее каждый N-й (с полем sample_rate), ответы
+// с кодом 4xx и 5xx журналируются всегда. Если путь подходит под несколько шаблонов выборки,
+// используется самый длинный.
+//
+// Запросы длительностью не меньше cfg.SlowThreshold журналируются без выборки с уровнем WARN
+// и дополнительными полями: шаблон маршрута chi (route), размер прочитанного тела запроса
+// (request_size) и длительность записи в хранилища за время запроса (storage, см. пакет timing).
+func RequestLogger(logger *zap.Logger, cfg LoggerConfig) func(http.Handler) http.Handler {
+	samplers := make([]*sampledPath, 0, len(cfg.SampleRates))
+	for p, n := range cfg.SampleRates {
+		samplers = append(samplers, &sampledPath{pattern: p, rate: uint64(max(n, 1))})
+	}
+	slices.SortFunc(samplers, func(a, b *sampledPath) int {
+		return cmp.Or(cmp.Compare(len(b.pattern), len(a.pattern)), strings.Compare(a.pattern, b.pattern))
+	})
+
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if matchPaths(cfg.ExcludePaths, r.URL.Path) {
+				h.ServeHTTP(w, r)
+				return
+			}
 			start := time.Now()
 			sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			var (
+				timings *timing.Recorder
+				body    *countingBody
+			)
+			if cfg.SlowThreshold > 0 {
+				var ctx context.Context
+				ctx, timings = timing.NewContext(r.Context())
+				r = r.WithContext(ctx)
+				if r.Body != nil && r.Body != http.NoBody {
+					body = &countingBody{ReadCloser: r.Body}
+					r.Body = body
+				}
+			}
 
 			h.ServeHTTP(sr, r)
 			duration := time.Since(start)
 
-			logger.Info("HTTP request",
+			fields := []zap.Field{
 				zap.String("method", r.Method),
 				zap.String("url", r.RequestURI),
 				zap.Int("status", sr.status),
 				zap.Int("size", sr.size),
 				zap.Duration("duration", duration),
 				zap.String("remote_addr", r.RemoteAddr),
-			)
+				zap.String("client_ip", clientIP(r)),
+				zap.String("request_id", requestid.FromContext(r.Context())),
+			}
+			if cfg.SlowThreshold > 0 && duration >= cfg.SlowThreshold {
+				logger.Warn("Slow HTTP request", append(fields, slowRequestFields(r, body, timings)...)...)
+				return
+			}
+			if sr.status < http.StatusBadRequest {
+				if i := slices.IndexFunc(samplers, func(s *sampledPath) bool { return matchPath(s.pattern, r.URL.Path) }); i >= 0 {
+					s := samplers[i]
+					if (s.seen.Add(1)-1)%s.rate != 0 {
+						return
+					}
+					fields = append(fields, zap.Uint64("sample_rate", s.rate))
+				}
+			}
+			logger.Info("HTTP request", fields...)
 		})
 	}
 }
+
+// slowRequestFields возвращает подробности медленного запроса r: шаблон маршрута,
+// размер прочитанного тела и длительность записи в хранилища.
+func slowRequestFields(r *http.Request, body *countingBody, timings *timing.Recorder) []zap.Field {
+	var route string
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		route = rctx.RoutePattern()
+	}
+	var size int64
+	if body != nil {
+		size = body.n
+	}
+	spans := timings.Spans()
+	storage := make([]zap.Field, 0, len(spans))
+	for _, s := range spans {
+		storage = append(storage, zap.Duration(s.Name, s.Duration))
+	}
+	return []zap.Field{
+		zap.String("route", route),
+		zap.Int64("request_size", size),
+		zap.Dict("storage", storage...),
+	}
+}
+
+// clientIP возвращает IP-адрес клиента из r.RemoteAddr без порта.
+//
+// За прокси адрес должен быть заранее подставлен middleware.RealIP.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -1,12 +1,20 @@
 package config
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/RoGogDBD/metric-alerter/internal/timing"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 // TestInitialize_TableDriven выполняет табличные тесты для функции Initialize.
@@ -52,7 +60,7 @@ func TestInitialize_TableDriven(t *testing.T) {
 // Проверяется, что статус и длина тела ответа соответствуют ожидаемым значениям.
 func TestRequestLogger_TableDriven(t *testing.T) {
 	logger := zap.NewNop()
-	middleware := RequestLogger(logger)
+	middleware := RequestLogger(logger, LoggerConfig{})
 
 	tests := []struct {
 		name       string // Название теста
@@ -90,3 +98,129 @@ func TestRequestLogger_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+// TestLogFileWriter_Rotation проверяет, что файл журнала ротируется по размеру,
+// а количество ротированных файлов ограничено MaxBackups.
+func TestLogFileWriter_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	w, err := logFileWriter(filepath.Join(dir, LogFileName), LoggerConfig{MaxSizeMB: 1, MaxBackups: 1})
+	require.NoError(t, err)
+
+	line := []byte(strings.Repeat("x", 1023) + "\n")
+	for i := 0; i < 3*1024; i++ {
+		_, err := w.Write(line)
+		require.NoError(t, err)
+	}
+
+	// Старые файлы удаляются в фоне после ротации.
+	require.Eventually(t, func() bool {
+		entries, err := os.ReadDir(dir)
+		return err == nil && len(entries) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	info, err := os.Stat(filepath.Join(dir, LogFileName))
+	require.NoError(t, err)
+	require.LessOrEqual(t, info.Size(), int64(1<<20))
+}
+
+// TestLoggerConfig проверяет применение секции logger JSON-конфига и проверку параметров ротации.
+func TestLoggerConfig(t *testing.T) {
+	cfg := DefaultLoggerConfig()
+	jc := decodeServerJSON(t, map[string]any{"logger": map[string]any{"max_age": "36h", "max_backups": 0, "compress": true}})
+	jc.ApplyToServer(ServerTargets{Logger: &cfg}, nil)
+
+	require.Equal(t, LoggerConfig{MaxSizeMB: DefaultLogMaxSizeMB, MaxAge: 36 * time.Hour, Compress: true, SlowThreshold: DefaultSlowRequestThreshold}, cfg)
+	require.NoError(t, cfg.Validate())
+
+	jc = decodeServerJSON(t, map[string]any{"logger": map[string]any{"exclude_paths": []string{"/ping"}, "sample": map[string]int{"/updates/": 100}}})
+	jc.ApplyToServer(ServerTargets{Logger: &cfg}, nil)
+	require.Equal(t, []string{"/ping"}, cfg.ExcludePaths)
+	require.Equal(t, map[string]int{"/updates/": 100}, cfg.SampleRates)
+	require.Error(t, LoggerConfig{SampleRates: map[string]int{"/updates/": 0}}.Validate())
+
+	jc = decodeServerJSON(t, map[string]any{"logger": map[string]any{"slow_threshold": "250ms"}})
+	jc.ApplyToServer(ServerTargets{Logger: &cfg}, nil)
+	require.Equal(t, 250*time.Millisecond, cfg.SlowThreshold)
+	require.Error(t, LoggerConfig{SlowThreshold: -time.Second}.Validate())
+	require.Error(t, LoggerConfig{MaxSizeMB: -1}.Validate())
+	require.Error(t, LoggerConfig{MaxAge: -time.Hour}.Validate())
+	require.Error(t, LoggerConfig{MaxBackups: -1}.Validate())
+}
+
+// TestRequestLogger_Filtering проверяет исключение путей, выборку успешных запросов
+// и поля IP-адреса клиента и доли выборки.
+func TestRequestLogger_Filtering(t *testing.T) {
+	tests := []struct {
+		name        string // Название теста
+		path        string // Путь запросов
+		status      int    // HTTP-статус ответа
+		requests    int    // Количество запросов
+		wantLogged  int    // Ожидаемое количество записей журнала
+		wantSampled bool   // Ожидается поле sample_rate
+	}{
+		{name: "Regular", path: "/value/gauge/Alloc", status: http.StatusOK, requests: 3, wantLogged: 3},
+		{name: "Excluded", path: "/healthz", status: http.StatusOK, requests: 3},
+		{name: "ExcludedPrefix", path: "/admin/status", status: http.StatusOK, requests: 3},
+		{name: "Sampled", path: "/updates/", status: http.StatusOK, requests: 7, wantLogged: 3, wantSampled: true},
+		{name: "SampledLongestPattern", path: "/update/gauge/Alloc/1", status: http.StatusOK, requests: 4, wantLogged: 2, wantSampled: true},
+		{name: "ErrorsNotSampled", path: "/updates/", status: http.StatusBadRequest, requests: 3, wantLogged: 3},
+	}
+
+	cfg := LoggerConfig{
+		ExcludePaths: []string{"/healthz", "/admin/*"},
+		SampleRates:  map[string]int{"/updates/": 3, "/update*": 10, "/update/*": 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+			h := RequestLogger(zap.New(core), cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			for i := 0; i < tt.requests; i++ {
+				req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+				req.RemoteAddr = "192.0.2.7:52100"
+				h.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			entries := logs.All()
+			require.Len(t, entries, tt.wantLogged)
+			for _, e := range entries {
+				fields := e.ContextMap()
+				require.Equal(t, "192.0.2.7", fields["client_ip"])
+				_, sampled := fields["sample_rate"]
+				require.Equal(t, tt.wantSampled, sampled)
+			}
+		})
+	}
+}
+
+// TestRequestLogger_Slow проверяет, что медленный запрос журналируется с уровнем WARN
+// без выборки, с шаблоном маршрута, размером тела и длительностью записи в хранилища.
+func TestRequestLogger_Slow(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	cfg := LoggerConfig{SampleRates: map[string]int{"/update/*": 100}, SlowThreshold: 20 * time.Millisecond}
+	r := chi.NewRouter()
+	r.Use(RequestLogger(zap.New(core), cfg))
+	r.Post("/update/{type}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if r.URL.Query().Get("slow") != "" {
+			timing.Add(r.Context(), "sync.postgres", 30*time.Millisecond)
+			time.Sleep(25 * time.Millisecond)
+		}
+	})
+
+	for _, query := range []string{"", "", "?slow=1"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/update/gauge/Alloc"+query, strings.NewReader("12345")))
+	}
+
+	entries := logs.All()
+	require.Len(t, entries, 2, "the second fast request is sampled out")
+	require.Equal(t, zap.InfoLevel, entries[0].Level)
+	require.NotContains(t, entries[0].ContextMap(), "route")
+
+	slow := entries[1]
+	require.Equal(t, zap.WarnLevel, slow.Level)
+	fields := slow.ContextMap()
+	require.Equal(t, "/update/{type}/{name}", fields["route"])
+	require.Equal(t, int64(5), fields["request_size"])
+	require.Equal(t, map[string]any{"sync.postgres": 30 * time.Millisecond}, fields["storage"])
+}
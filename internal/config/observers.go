@@ -0,0 +1,11 @@
+package config
+
+// ObserverConfig описывает наблюдателя событий аудита, создаваемого фабрикой по типу.
+//
+// Поля:
+//   - Type: имя зарегистрированного типа наблюдателя (например "file", "http" или "kafka")
+//   - Options: параметры наблюдателя, зависящие от типа (например "path", "url" или "topic")
+type ObserverConfig struct {
+	Type    string            `json:"type"`
+	Options map[string]string `json:"options"`
+}
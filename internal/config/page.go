@@ -0,0 +1,45 @@
+package config
+
+import "time"
+
+// Режимы автообновления HTML-страницы метрик.
+const (
+	PageRefreshIncremental = "incremental" // Обновление значений скриптом через JSON API без перезагрузки
+	PageRefreshReload      = "reload"      // Полная перезагрузка страницы (заголовок Refresh)
+)
+
+type (
+	// PageRefreshConfig описывает автообновление HTML-страницы метрик.
+	//
+	// Поля:
+	//   - Interval: период обновления (0 — автообновление отключено)
+	//   - Mode: режим обновления (PageRefreshIncremental или PageRefreshReload)
+	PageRefreshConfig struct {
+		Interval time.Duration
+		Mode     string
+	}
+
+	// PageRefreshJSONConfig представляет секцию "page_refresh" JSON-конфигурации сервера.
+	PageRefreshJSONConfig struct {
+		Interval string `json:"interval"` // PAGE_REFRESH или флаг -page-refresh (в формате "10s")
+		Mode     string `json:"mode"`     // "incremental" или "reload"
+	}
+)
+
+// DefaultPageRefreshConfig возвращает настройки автообновления по умолчанию (автообновление отключено).
+func DefaultPageRefreshConfig() PageRefreshConfig {
+	return PageRefreshConfig{Mode: PageRefreshIncremental}
+}
+
+// apply применяет значения секции JSON к cfg, не перезаписывая интервал, заданный флагом или переменной окружения.
+func (jc *PageRefreshJSONConfig) apply(cfg *PageRefreshConfig, a *jsonApplier) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if d, err := time.ParseDuration(jc.Interval); a.use(FlagPageRefresh, jc.Interval != "" && err == nil) {
+		cfg.Interval = d
+	}
+	if jc.Mode != "" {
+		cfg.Mode = jc.Mode
+	}
+}
@@ -0,0 +1,14 @@
+package config
+
+import "path/filepath"
+
+// ResolvePath возвращает путь path относительно каталога dir.
+//
+// Абсолютный path, пустой path и пустой dir возвращаются без изменений, поэтому
+// без -data-dir пути по-прежнему отсчитываются от рабочей директории процесса.
+func ResolvePath(dir, path string) string {
+	if dir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
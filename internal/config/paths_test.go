@@ -0,0 +1,32 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestResolvePath проверяет перенос относительных путей в каталог и сохранение остальных без изменений.
+//
+// t — указатель на структуру теста.
+func TestResolvePath(t *testing.T) {
+	abs := filepath.Join(t.TempDir(), "metrics.json")
+	tests := []struct {
+		name string // Название теста
+		dir  string // Каталог данных
+		path string // Исходный путь
+		want string // Ожидаемый путь
+	}{
+		{name: "relative", dir: "/var/lib/ma", path: "metrics.json", want: filepath.Join("/var/lib/ma", "metrics.json")},
+		{name: "relative dot", dir: "/var/lib/ma", path: "./logs", want: filepath.Join("/var/lib/ma", "logs")},
+		{name: "absolute", dir: "/var/lib/ma", path: abs, want: abs},
+		{name: "no dir", dir: "", path: "metrics.json", want: "metrics.json"},
+		{name: "empty path", dir: "/var/lib/ma", path: "", want: ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, ResolvePath(tc.dir, tc.path))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+// Режимы выгрузки снимков в объектное хранилище.
+const (
+	S3UploadOnSnapshot = "snapshot" // После каждого сохранённого снимка
+	S3UploadOnShutdown = "shutdown" // Только при завершении работы сервера
+)
+
+// DefaultS3Region — регион подписи запросов по умолчанию.
+const DefaultS3Region = "us-east-1"
+
+type (
+	// S3Config описывает выгрузку файла снимка в S3-совместимое объектное хранилище.
+	//
+	// Поля:
+	//   - Endpoint: адрес хранилища ("https://s3.amazonaws.com", "http://minio:9000"); пусто — выгрузка отключена
+	//   - Bucket: имя бакета
+	//   - Region: регион для подписи запросов
+	//   - AccessKeyID, SecretAccessKey: учётные данные
+	//   - ObjectKey: ключ объекта (пусто — имя файла снимка)
+	//   - UploadOn: момент выгрузки (S3UploadOnSnapshot или S3UploadOnShutdown)
+	S3Config struct {
+		Endpoint        string
+		Bucket          string
+		Region          string
+		AccessKeyID     string
+		SecretAccessKey string
+		ObjectKey       string
+		UploadOn        string
+	}
+
+	// S3JSONConfig представляет секцию "s3" JSON-конфигурации сервера.
+	S3JSONConfig struct {
+		Endpoint        string `json:"endpoint"`          // Адрес S3-совместимого хранилища
+		Bucket          string `json:"bucket"`            // Имя бакета
+		Region          string `json:"region"`            // Регион подписи запросов
+		AccessKeyID     string `json:"access_key_id"`     // Идентификатор ключа доступа
+		SecretAccessKey string `json:"secret_access_key"` // Секретный ключ доступа
+		ObjectKey       string `json:"object_key"`        // Ключ объекта
+		UploadOn        string `json:"upload_on"`         // "snapshot" или "shutdown"
+	}
+)
+
+// DefaultS3Config возвращает настройки выгрузки по умолчанию (выгрузка отключена).
+func DefaultS3Config() S3Config {
+	return S3Config{
+		Region:   DefaultS3Region,
+		UploadOn: S3UploadOnSnapshot,
+	}
+}
+
+// Enabled сообщает, настроена ли выгрузка в объектное хранилище.
+func (c S3Config) Enabled() bool {
+	return c.Endpoint != "" && c.Bucket != ""
+}
+
+// apply применяет значения секции JSON к cfg.
+func (jc *S3JSONConfig) apply(cfg *S3Config) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if jc.Endpoint != "" {
+		cfg.Endpoint = jc.Endpoint
+	}
+	if jc.Bucket != "" {
+		cfg.Bucket = jc.Bucket
+	}
+	if jc.Region != "" {
+		cfg.Region = jc.Region
+	}
+	if jc.AccessKeyID != "" {
+		cfg.AccessKeyID = jc.AccessKeyID
+	}
+	if jc.SecretAccessKey != "" {
+		cfg.SecretAccessKey = jc.SecretAccessKey
+	}
+	if jc.ObjectKey != "" {
+		cfg.ObjectKey = jc.ObjectKey
+	}
+	if jc.UploadOn != "" {
+		cfg.UploadOn = jc.UploadOn
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import "time"
+
+// Значения заголовков безопасности по умолчанию.
+const (
+	DefaultContentSecurityPolicy = "default-src 'self'; frame-ancestors 'none'; base-uri 'self'; form-action 'self'"
+	DefaultFrameOptions          = "DENY"
+	DefaultHSTSMaxAge            = 365 * 24 * time.Hour
+)
+
+type (
+	// SecurityHeadersConfig описывает заголовки безопасности для HTML-страниц сервера.
+	//
+	// Поля:
+	//   - Enabled: включает установку заголовков
+	//   - ContentSecurityPolicy: значение Content-Security-Policy (пусто — заголовок не устанавливается)
+	//   - FrameOptions: значение X-Frame-Options (пусто — заголовок не устанавливается)
+	//   - HSTSMaxAge: max-age для Strict-Transport-Security; заголовок отправляется только по TLS (0 — отключён)
+	SecurityHeadersConfig struct {
+		Enabled               bool
+		ContentSecurityPolicy string
+		FrameOptions          string
+		HSTSMaxAge            time.Duration
+	}
+
+	// SecurityHeadersJSONConfig представляет секцию "security_headers" JSON-конфигурации сервера.
+	SecurityHeadersJSONConfig struct {
+		Enabled               *bool   `json:"enabled"`                 // Включение заголовков безопасности
+		ContentSecurityPolicy *string `json:"content_security_policy"` // CONTENT_SECURITY_POLICY или флаг -csp
+		FrameOptions          *string `json:"frame_options"`           // Значение X-Frame-Options
+		HSTSMaxAge            string  `json:"hsts_max_age"`            // max-age HSTS (в формате "8760h")
+	}
+)
+
+// DefaultSecurityHeadersConfig возвращает настройки заголовков безопасности по умолчанию.
+func DefaultSecurityHeadersConfig() SecurityHeadersConfig {
+	return SecurityHeadersConfig{
+		Enabled:               true,
+		ContentSecurityPolicy: DefaultContentSecurityPolicy,
+		FrameOptions:          DefaultFrameOptions,
+		HSTSMaxAge:            DefaultHSTSMaxAge,
+	}
+}
+
+// apply применяет значения секции JSON к cfg, не перезаписывая CSP, заданную флагом или переменной окружения.
+func (jc *SecurityHeadersJSONConfig) apply(cfg *SecurityHeadersConfig, a *jsonApplier) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if jc.Enabled != nil {
+		cfg.Enabled = *jc.Enabled
+	}
+	if jc.ContentSecurityPolicy != nil {
+		applyJSON(a, FlagCSP, &cfg.ContentSecurityPolicy, *jc.ContentSecurityPolicy)
+	}
+	if jc.FrameOptions != nil {
+		cfg.FrameOptions = *jc.FrameOptions
+	}
+	if jc.HSTSMaxAge != "" {
+		if d, err := time.ParseDuration(jc.HSTSMaxAge); err == nil {
+			cfg.HSTSMaxAge = d
+		}
+	}
+}
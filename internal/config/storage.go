@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Типы хранилищ, в которые дублируются записи основного хранилища в памяти.
+const (
+	StorageBackendPostgres = "postgres" // Таблица metrics в PostgreSQL
+	StorageBackendFile     = "file"     // Файл снимка метрик
+)
+
+// Режимы записи в хранилище.
+const (
+	StorageModeSync  = "sync"  // После каждого обновления, до ответа клиенту
+	StorageModeAsync = "async" // Периодически в фоне
+)
+
+// Политики обработки ошибок записи в хранилище.
+const (
+	StorageOnErrorFail = "fail" // Запрос на обновление завершается ошибкой (только для sync)
+	StorageOnErrorLog  = "log"  // Ошибка записывается в журнал, запрос выполняется успешно
+)
+
+type (
+	// StorageBackendConfig описывает хранилище, в которое дублируются записи основного хранилища в памяти.
+	//
+	// Поля:
+	//   - Type: тип хранилища (StorageBackendPostgres или StorageBackendFile)
+	//   - Mode: режим записи (StorageModeSync или StorageModeAsync); пусто — sync
+	//   - OnError: политика ошибок (StorageOnErrorFail или StorageOnErrorLog);
+	//     пусто — fail для sync и log для async
+	//   - Interval: период записи в режиме async (в формате "10s"); пусто — store_interval
+	//   - Options: параметры типа: "dsn" для postgres (пусто — database_dsn),
+	//     "path" для file (пусто — store_file)
+	StorageBackendConfig struct {
+		Type     string            `json:"type"`
+		Mode     string            `json:"mode"`
+		OnError  string            `json:"on_error"`
+		Interval string            `json:"interval"`
+		Options  map[string]string `json:"options"`
+	}
+
+	// StorageConfig описывает хранилища, в которые дублируются записи.
+	//
+	// Поля:
+	//   - Backends: хранилища; пусто — PostgreSQL синхронно при заданном database_dsn
+	//     и файл снимка с периодом store_interval (при 0 — синхронно)
+	StorageConfig struct {
+		Backends []StorageBackendConfig
+	}
+
+	// StorageJSONConfig представляет секцию "storage" JSON-конфигурации сервера.
+	StorageJSONConfig struct {
+		Backends []StorageBackendConfig `json:"backends"` // Хранилища для дублирования записей
+	}
+)
+
+// Async сообщает, выполняется ли запись в хранилище в фоне.
+func (b StorageBackendConfig) Async() bool {
+	return b.Mode == StorageModeAsync
+}
+
+// FailOnError сообщает, должна ли ошибка записи завершать запрос на обновление.
+func (b StorageBackendConfig) FailOnError() bool {
+	return b.OnError == StorageOnErrorFail || (b.OnError == "" && !b.Async())
+}
+
+// Validate проверяет тип, режим, политику ошибок и период записи хранилища.
+func (b StorageBackendConfig) Validate() error {
+	switch b.Type {
+	case StorageBackendPostgres, StorageBackendFile:
+	default:
+		return fmt.Errorf("invalid storage backend type %q (want %q or %q)", b.Type, StorageBackendPostgres, StorageBackendFile)
+	}
+	switch b.Mode {
+	case "", StorageModeSync, StorageModeAsync:
+	default:
+		return fmt.Errorf("storage backend %s: invalid mode %q (want %q or %q)", b.Type, b.Mode, StorageModeSync, StorageModeAsync)
+	}
+	switch b.OnError {
+	case "", StorageOnErrorFail, StorageOnErrorLog:
+	default:
+		return fmt.Errorf("storage backend %s: invalid on_error %q (want %q or %q)", b.Type, b.OnError, StorageOnErrorFail, StorageOnErrorLog)
+	}
+	if b.Async() && b.OnError == StorageOnErrorFail {
+		return fmt.Errorf("storage backend %s: on_error %q requires mode %q", b.Type, StorageOnErrorFail, StorageModeSync)
+	}
+	if b.Interval != "" {
+		if !b.Async() {
+			return fmt.Errorf("storage backend %s: interval requires mode %q", b.Type, StorageModeAsync)
+		}
+		if sec, err := ParseDuration(b.Interval); err != nil || sec <= 0 {
+			return fmt.Errorf("storage backend %s: invalid interval %q", b.Type, b.Interval)
+		}
+	}
+	return nil
+}
+
+// apply применяет значения секции JSON к cfg.
+func (jc *StorageJSONConfig) apply(cfg *StorageConfig) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if len(cfg.Backends) == 0 && len(jc.Backends) > 0 {
+		cfg.Backends = append(cfg.Backends, jc.Backends...)
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestStorageBackendConfig_Validate проверяет проверку хранилищ и значения по умолчанию политики ошибок.
+//
+// t — указатель на структуру теста.
+func TestStorageBackendConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string               // Название теста
+		backend StorageBackendConfig // Хранилище
+		wantErr bool                 // Ожидается ошибка
+		async   bool                 // Ожидаемая асинхронная запись
+		fail    bool                 // Ожидаемое завершение запроса при ошибке
+	}{
+		{name: "DefaultSync", backend: StorageBackendConfig{Type: StorageBackendPostgres}, fail: true},
+		{name: "SyncLog", backend: StorageBackendConfig{Type: StorageBackendFile, OnError: StorageOnErrorLog}},
+		{name: "Async", backend: StorageBackendConfig{Type: StorageBackendFile, Mode: StorageModeAsync, Interval: "10s"}, async: true},
+		{name: "UnknownType", backend: StorageBackendConfig{Type: "redis"}, wantErr: true},
+		{name: "UnknownMode", backend: StorageBackendConfig{Type: StorageBackendFile, Mode: "lazy"}, wantErr: true},
+		{name: "UnknownOnError", backend: StorageBackendConfig{Type: StorageBackendFile, OnError: "retry"}, wantErr: true},
+		{name: "AsyncFail", backend: StorageBackendConfig{Type: StorageBackendFile, Mode: StorageModeAsync, OnError: StorageOnErrorFail}, wantErr: true},
+		{name: "SyncInterval", backend: StorageBackendConfig{Type: StorageBackendFile, Interval: "10s"}, wantErr: true},
+		{name: "InvalidInterval", backend: StorageBackendConfig{Type: StorageBackendFile, Mode: StorageModeAsync, Interval: "soon"}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.backend.Validate()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.async, tc.backend.Async())
+			require.Equal(t, tc.fail, tc.backend.FailOnError())
+		})
+	}
+}
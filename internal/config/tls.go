@@ -0,0 +1,111 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// tlsVersions — допустимые значения TLSConfig.MinVersion.
+//
+// TLS 1.0 и 1.1 не допускаются: политика задаёт базовый уровень не ниже TLS 1.2.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsCurves — допустимые значения TLSConfig.CurvePreferences.
+var tlsCurves = map[string]tls.CurveID{
+	"X25519":         tls.X25519,
+	"X25519MLKEM768": tls.X25519MLKEM768,
+	"P256":           tls.CurveP256,
+	"P384":           tls.CurveP384,
+	"P521":           tls.CurveP521,
+}
+
+type (
+	// TLSConfig описывает политику TLS для слушателей сервера и HTTP-клиента агента.
+	//
+	// Поля:
+	//   - MinVersion: минимальная версия протокола ("1.2", "1.3"; пусто — значение Go по умолчанию)
+	//   - CipherSuites: разрешённые наборы шифров для TLS 1.0–1.2 по именам из crypto/tls,
+	//     например "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"; небезопасные наборы не допускаются
+	//     (пусто — наборы Go по умолчанию; для TLS 1.3 наборы не настраиваются)
+	//   - CurvePreferences: кривые обмена ключами в порядке предпочтения ("X25519", "P256", ...)
+	TLSConfig struct {
+		MinVersion       string
+		CipherSuites     []string
+		CurvePreferences []string
+	}
+
+	// TLSJSONConfig представляет секцию "tls" JSON-конфигурации сервера и агента.
+	TLSJSONConfig struct {
+		MinVersion       string   `json:"min_version"`       // Минимальная версия TLS ("1.2" или "1.3")
+		CipherSuites     []string `json:"cipher_suites"`     // Разрешённые наборы шифров (имена crypto/tls)
+		CurvePreferences []string `json:"curve_preferences"` // Кривые в порядке предпочтения
+	}
+)
+
+// apply применяет значения секции JSON к cfg.
+func (jc *TLSJSONConfig) apply(cfg *TLSConfig) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if jc.MinVersion != "" {
+		cfg.MinVersion = jc.MinVersion
+	}
+	if len(jc.CipherSuites) > 0 {
+		cfg.CipherSuites = jc.CipherSuites
+	}
+	if len(jc.CurvePreferences) > 0 {
+		cfg.CurvePreferences = jc.CurvePreferences
+	}
+}
+
+// IsZero сообщает, что политика не задана и используются значения Go по умолчанию.
+func (c TLSConfig) IsZero() bool {
+	return c.MinVersion == "" && len(c.CipherSuites) == 0 && len(c.CurvePreferences) == 0
+}
+
+// Validate проверяет, что версия, наборы шифров и кривые известны.
+func (c TLSConfig) Validate() error {
+	_, err := c.Build()
+	return err
+}
+
+// Build создаёт *tls.Config с заданной политикой.
+//
+// Возвращает ошибку, если версия, набор шифров или кривая неизвестны.
+func (c TLSConfig) Build() (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if c.MinVersion != "" {
+		v, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid tls min_version %q (want one of %s)", c.MinVersion, strings.Join(slices.Sorted(maps.Keys(tlsVersions)), ", "))
+		}
+		cfg.MinVersion = v
+	}
+	if len(c.CipherSuites) > 0 {
+		suites := make(map[string]uint16)
+		for _, s := range tls.CipherSuites() {
+			suites[s.Name] = s.ID
+		}
+		for _, name := range c.CipherSuites {
+			id, ok := suites[strings.TrimSpace(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown or insecure tls cipher suite %q", name)
+			}
+			cfg.CipherSuites = append(cfg.CipherSuites, id)
+		}
+	}
+	for _, name := range c.CurvePreferences {
+		id, ok := tlsCurves[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("invalid tls curve %q (want one of %s)", name, strings.Join(slices.Sorted(maps.Keys(tlsCurves)), ", "))
+		}
+		cfg.CurvePreferences = append(cfg.CurvePreferences, id)
+	}
+	return cfg, nil
+}
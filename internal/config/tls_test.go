@@ -0,0 +1,51 @@
+package config
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTLSConfig_Build проверяет разбор минимальной версии, наборов шифров и кривых.
+//
+// t — указатель на структуру теста.
+func TestTLSConfig_Build(t *testing.T) {
+	tests := []struct {
+		name       string        // Название теста
+		cfg        TLSConfig     // Политика TLS
+		wantErr    bool          // Ожидается ошибка
+		wantMin    uint16        // Ожидаемая минимальная версия
+		wantSuites []uint16      // Ожидаемые наборы шифров
+		wantCurves []tls.CurveID // Ожидаемые кривые
+	}{
+		{name: "Defaults"},
+		{name: "MinVersion", cfg: TLSConfig{MinVersion: "1.3"}, wantMin: tls.VersionTLS13},
+		{
+			name:       "Strict",
+			cfg:        TLSConfig{MinVersion: "1.2", CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}, CurvePreferences: []string{"X25519", "P256"}},
+			wantMin:    tls.VersionTLS12,
+			wantSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			wantCurves: []tls.CurveID{tls.X25519, tls.CurveP256},
+		},
+		{name: "UnknownVersion", cfg: TLSConfig{MinVersion: "1.4"}, wantErr: true},
+		{name: "TLS10Rejected", cfg: TLSConfig{MinVersion: "1.0"}, wantErr: true},
+		{name: "TLS11Rejected", cfg: TLSConfig{MinVersion: "1.1"}, wantErr: true},
+		{name: "InsecureCipher", cfg: TLSConfig{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}, wantErr: true},
+		{name: "UnknownCurve", cfg: TLSConfig{CurvePreferences: []string{"P192"}}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.cfg.Build()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantMin, got.MinVersion)
+			require.Equal(t, tc.wantSuites, got.CipherSuites)
+			require.Equal(t, tc.wantCurves, got.CurvePreferences)
+		})
+	}
+}
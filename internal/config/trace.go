@@ -0,0 +1,157 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Source — источник итогового значения параметра конфигурации.
+type Source string
+
+// Источники значений в порядке убывания приоритета.
+const (
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+	SourceJSON    Source = "json"
+	SourceDefault Source = "default"
+)
+
+// TraceEntry — итоговое значение параметра и его источник.
+type TraceEntry struct {
+	Option Option // Описание параметра.
+	Source Source // Источник значения.
+	Value  string // Значение в том виде, в каком оно задано в источнике.
+}
+
+// Sources — параметры, заданные переменной окружения или флагом: имя флага → источник.
+// JSON-конфиг такие параметры не перезаписывает.
+type Sources map[string]Source
+
+// Explicit определяет параметры реестра, заданные переменной окружения (непустым значением)
+// или явно переданным флагом fs. Переменная окружения важнее флага.
+//
+// fs — разобранный набор флагов.
+// lookupEnv — функция чтения окружения (обычно os.LookupEnv).
+func (o Options) Explicit(fs *flag.FlagSet, lookupEnv func(string) (string, bool)) Sources {
+	set := Sources{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = SourceFlag })
+	for _, opt := range o {
+		if opt.Env == "" || fs.Lookup(opt.Flag) == nil {
+			continue
+		}
+		if v, ok := lookupEnv(opt.Env); ok && v != "" {
+			set[opt.Flag] = SourceEnv
+		}
+	}
+	return set
+}
+
+// Trace определяет, откуда взято итоговое значение каждого параметра реестра,
+// зарегистрированного в fs.
+//
+// Источники окружения и флагов берутся из Explicit, источник JSON — из результата
+// ApplyToServer или ApplyToAgent, то есть из того же кода, что применяет конфигурацию.
+//
+// fs — разобранный набор флагов.
+// lookupEnv — функция чтения окружения (обычно os.LookupEnv).
+// doc — разобранный JSON-конфиг (nil, если файл не задан).
+// fromJSON — флаги, значения которых применены из JSON-конфига.
+func (o Options) Trace(fs *flag.FlagSet, lookupEnv func(string) (string, bool), doc map[string]any, fromJSON []string) []TraceEntry {
+	set := o.Explicit(fs, lookupEnv)
+	var entries []TraceEntry
+	for _, opt := range o {
+		f := fs.Lookup(opt.Flag)
+		if f == nil || (opt.Env == "" && opt.JSON == "") {
+			continue
+		}
+		entry := TraceEntry{Option: opt, Source: SourceDefault, Value: f.DefValue}
+		switch set[opt.Flag] {
+		case SourceEnv:
+			entry.Source = SourceEnv
+			entry.Value, _ = lookupEnv(opt.Env)
+		case SourceFlag:
+			entry.Source, entry.Value = SourceFlag, f.Value.String()
+		default:
+			if slices.Contains(fromJSON, opt.Flag) {
+				entry.Source = SourceJSON
+				entry.Value, _ = lookupJSON(doc, opt.JSON)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// PrintTrace выводит в w таблицу параметров с их значениями и источниками.
+// Значения секретных параметров скрываются.
+func PrintTrace(w io.Writer, entries []TraceEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "FLAG\tSOURCE\tVALUE")
+	for _, e := range entries {
+		value := e.Value
+		if e.Option.Secret && value != "" {
+			value = "<redacted>"
+		}
+		_, _ = fmt.Fprintf(tw, "-%s\t%s\t%q\n", e.Option.Flag, e.Source, value)
+	}
+	_ = tw.Flush()
+}
+
+// LoadJSONDocument читает JSON-конфиг как дерево значений для Trace.
+//
+// filePath — путь к файлу; пустой путь возвращает nil без ошибки.
+func LoadJSONDocument(filePath string) (map[string]any, error) {
+	if filePath == "" {
+		return nil, nil
+	}
+	var doc map[string]any
+	if err := loadJSONConfig(filePath, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// lookupJSON возвращает значение по ключу вида "auth.jwt_secret" в текстовом виде.
+// Отсутствующий ключ и null считаются незаданными.
+func lookupJSON(doc map[string]any, key string) (string, bool) {
+	var node any = doc
+	for _, part := range strings.Split(key, ".") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		if node, ok = m[part]; !ok || node == nil {
+			return "", false
+		}
+	}
+	if s, ok := node.(string); ok {
+		return s, true
+	}
+	data, err := json.Marshal(node)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// TraceAndExit печатает трассировку конфигурации в стандартный вывод и завершает процесс.
+// Используется флагом -config-trace после применения JSON-конфига.
+//
+// fromJSON — результат ApplyToServer или ApplyToAgent.
+func (o Options) TraceAndExit(fs *flag.FlagSet, configPath string, fromJSON []string) {
+	doc, err := LoadJSONDocument(configPath)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: failed to load JSON config: %v\n", err)
+	}
+	if configPath != "" {
+		fmt.Printf("Config file: %s\n", configPath)
+	}
+	PrintTrace(os.Stdout, o.Trace(fs, os.LookupEnv, doc, fromJSON))
+	os.Exit(0)
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestOptions_Trace проверяет определение источника значения для всех сочетаний источников
+// и совпадение трассировки с результатом применения конфигурации.
+//
+// t — указатель на структуру теста.
+func TestOptions_Trace(t *testing.T) {
+	tests := []struct {
+		name   string            // Название теста
+		args   []string          // Аргументы командной строки
+		env    map[string]string // Переменные окружения
+		doc    map[string]any    // JSON-конфиг
+		source Source            // Ожидаемый источник
+		value  string            // Ожидаемое значение
+	}{
+		{name: "Default", source: SourceDefault, value: "300"},
+		{name: "Flag", args: []string{"-i", "60"}, source: SourceFlag, value: "60"},
+		{name: "Env", env: map[string]string{EnvStoreInterval: "30"}, source: SourceEnv, value: "30"},
+		{name: "EmptyEnvIgnored", env: map[string]string{EnvStoreInterval: ""}, args: []string{"-i", "60"}, source: SourceFlag, value: "60"},
+		{name: "EnvOverFlag", args: []string{"-i", "60"}, env: map[string]string{EnvStoreInterval: "30"}, source: SourceEnv, value: "30"},
+		{name: "JSON", doc: map[string]any{"store_interval": "10s"}, source: SourceJSON, value: "10s"},
+		{name: "FlagOverJSON", args: []string{"-i", "60"}, doc: map[string]any{"store_interval": "10s"}, source: SourceFlag, value: "60"},
+		{name: "EnvOverJSON", env: map[string]string{EnvStoreInterval: "30"}, doc: map[string]any{"store_interval": "10s"}, source: SourceEnv, value: "30"},
+		{name: "DefaultValuedEnvOverJSON", env: map[string]string{EnvStoreInterval: "300"}, doc: map[string]any{"store_interval": "10s"}, source: SourceEnv, value: "300"},
+		{name: "DefaultValuedFlagOverJSON", args: []string{"-i=300"}, doc: map[string]any{"store_interval": "10s"}, source: SourceFlag, value: "300"},
+		{name: "InvalidJSONIgnored", doc: map[string]any{"store_interval": "soon"}, source: SourceDefault, value: "300"},
+		{name: "JSONNullIgnored", doc: map[string]any{"store_interval": nil}, source: SourceDefault, value: "300"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("server", flag.ContinueOnError)
+			storeInterval := fs.Int(FlagStoreInterval, 300, "Store interval in seconds")
+			require.NoError(t, fs.Parse(tc.args))
+			lookupEnv := func(key string) (string, bool) {
+				v, ok := tc.env[key]
+				return v, ok
+			}
+			if v, ok := lookupEnv(EnvStoreInterval); ok && v != "" {
+				*storeInterval, _ = strconv.Atoi(v)
+			}
+
+			jc := decodeServerJSON(t, tc.doc)
+			fromJSON := jc.ApplyToServer(ServerTargets{StoreInterval: storeInterval}, ServerOptions.Explicit(fs, lookupEnv))
+
+			entries := ServerOptions.Trace(fs, lookupEnv, tc.doc, fromJSON)
+			require.Len(t, entries, 1)
+			require.Equal(t, FlagStoreInterval, entries[0].Option.Flag)
+			require.Equal(t, tc.source, entries[0].Source)
+			require.Equal(t, tc.value, entries[0].Value)
+			if tc.source != SourceJSON {
+				require.Equal(t, tc.value, strconv.Itoa(*storeInterval), "trace matches the applied value")
+			} else {
+				require.Equal(t, 10, *storeInterval)
+			}
+		})
+	}
+}
+
+// TestOptions_TraceNestedJSON проверяет поиск вложенных ключей JSON-конфига и их вывод.
+//
+// t — указатель на структуру теста.
+func TestOptions_TraceNestedJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"restore": false, "auth": {"jwt_secret": "s3cret"}}`), 0o600))
+	doc, err := LoadJSONDocument(path)
+	require.NoError(t, err)
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	restore := fs.Bool(FlagRestore, true, "Restore metrics from file at startup")
+	fs.String(FlagJWTSecret, "", "HS256 secret")
+	fs.Bool(FlagVersion, false, "Print build information and exit")
+	require.NoError(t, fs.Parse(nil))
+	noEnv := func(string) (string, bool) { return "", false }
+
+	var auth AuthConfig
+	fromJSON := decodeServerJSON(t, doc).ApplyToServer(ServerTargets{Restore: restore, Auth: &auth}, ServerOptions.Explicit(fs, noEnv))
+	require.False(t, *restore)
+	require.Equal(t, "s3cret", auth.JWTSecret)
+
+	entries := ServerOptions.Trace(fs, noEnv, doc, fromJSON)
+	require.Len(t, entries, 2, "options without env and JSON are not traced")
+
+	var buf bytes.Buffer
+	PrintTrace(&buf, entries)
+	out := buf.String()
+	require.Contains(t, out, `-r           json    "false"`)
+	require.Contains(t, out, `-jwt-secret  json    "<redacted>"`)
+	require.NotContains(t, out, "s3cret")
+}
+
+// TestApplyToServer_ExplicitDefault проверяет, что явно заданный флаг со значением по умолчанию
+// не перезаписывается JSON-конфигом, в том числе для флагов секций.
+//
+// t — указатель на структуру теста.
+func TestApplyToServer_ExplicitDefault(t *testing.T) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	restore := fs.Bool(FlagRestore, true, "Restore metrics from file at startup")
+	csp := fs.String(FlagCSP, DefaultContentSecurityPolicy, "Content-Security-Policy")
+	require.NoError(t, fs.Parse([]string{"-r=true", "-csp=" + DefaultContentSecurityPolicy}))
+
+	security := DefaultSecurityHeadersConfig()
+	security.ContentSecurityPolicy = *csp
+	jc := decodeServerJSON(t, map[string]any{
+		"restore":          false,
+		"security_headers": map[string]any{"content_security_policy": "default-src 'none'", "frame_options": "SAMEORIGIN"},
+	})
+	fromJSON := jc.ApplyToServer(ServerTargets{Restore: restore, Security: &security}, ServerOptions.Explicit(fs, func(string) (string, bool) { return "", false }))
+
+	require.Empty(t, fromJSON)
+	require.True(t, *restore)
+	require.Equal(t, DefaultContentSecurityPolicy, security.ContentSecurityPolicy)
+	require.Equal(t, "SAMEORIGIN", security.FrameOptions, "options without a flag are still applied")
+}
+
+// TestApplyToServer_TLSFiles проверяет, что сертификат и ключ TLS берутся из JSON,
+// если флаг не задан, а явный флаг имеет приоритет.
+//
+// t — указатель на структуру теста.
+func TestApplyToServer_TLSFiles(t *testing.T) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	certFlag := fs.String(FlagTLSCert, "", "TLS certificate")
+	keyFlag := fs.String(FlagTLSKey, "", "TLS key")
+	require.NoError(t, fs.Parse([]string{"-tls-key=flag.key"}))
+
+	jc := decodeServerJSON(t, map[string]any{"tls_cert": "json.crt", "tls_key": "json.key"})
+	fromJSON := jc.ApplyToServer(ServerTargets{TLSCert: certFlag, TLSKey: keyFlag}, ServerOptions.Explicit(fs, func(string) (string, bool) { return "", false }))
+
+	require.Equal(t, []string{FlagTLSCert}, fromJSON)
+	require.Equal(t, "json.crt", *certFlag)
+	require.Equal(t, "flag.key", *keyFlag)
+}
+
+// decodeServerJSON преобразует дерево JSON-конфига в ServerJSONConfig.
+func decodeServerJSON(t *testing.T, doc map[string]any) *ServerJSONConfig {
+	t.Helper()
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	var jc ServerJSONConfig
+	require.NoError(t, json.Unmarshal(data, &jc))
+	return &jc
+}
+
+// TestApplyToServer_Compression проверяет, что секция compression применяется к параметрам,
+// не заданным флагом, а уровень вне диапазона отклоняется.
+func TestApplyToServer_Compression(t *testing.T) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.Int(FlagCompressLevel, DefaultCompressionLevel, "")
+	fs.String(FlagCompressExclude, "/ping", "")
+	require.NoError(t, fs.Parse([]string{"-compress-level=9"}))
+
+	cfg := CompressionConfig{Level: 9, Exclude: []string{"/ping"}}
+	jc := ServerJSONConfig{Compression: &CompressionJSONConfig{Level: new(int), Exclude: []string{"/ping", "/admin/*"}}}
+	fromJSON := jc.ApplyToServer(ServerTargets{Compression: &cfg}, ServerOptions.Explicit(fs, func(string) (string, bool) { return "", false }))
+
+	require.Equal(t, []string{FlagCompressExclude}, fromJSON)
+	require.Equal(t, 9, cfg.Level)
+	require.True(t, cfg.Excluded("/admin/runtime"))
+	require.False(t, cfg.Excluded("/pings"))
+	require.NoError(t, cfg.Validate())
+	require.Error(t, CompressionConfig{Level: 10}.Validate())
+	require.Equal(t, []string{"/ping", "/api/*"}, ParseCompressionExclude(" /ping, ,/api/*"))
+}
+
+// TestApplyToServer_MetricTTL проверяет, что секция metric_ttl применяется к параметрам,
+// не заданным флагом, а неизвестное действие отклоняется.
+func TestApplyToServer_MetricTTL(t *testing.T) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.Int(FlagMetricTTL, 0, "")
+	fs.String(FlagMetricTTLAction, MetricTTLDelete, "")
+	require.NoError(t, fs.Parse([]string{"-metric-ttl-action=flag"}))
+
+	cfg := MetricTTLConfig{Action: MetricTTLFlag}
+	jc := decodeServerJSON(t, map[string]any{"metric_ttl": map[string]any{"ttl": "24h", "action": "delete"}})
+	fromJSON := jc.ApplyToServer(ServerTargets{MetricTTL: &cfg}, ServerOptions.Explicit(fs, func(string) (string, bool) { return "", false }))
+
+	require.Equal(t, []string{FlagMetricTTL}, fromJSON)
+	require.Equal(t, 24*time.Hour, cfg.TTL)
+	require.Equal(t, MetricTTLFlag, cfg.Action)
+	require.NoError(t, cfg.Validate())
+	require.NoError(t, DefaultMetricTTLConfig().Validate())
+	require.Error(t, MetricTTLConfig{Action: "archive"}.Validate())
+	require.Error(t, MetricTTLConfig{TTL: -time.Second, Action: MetricTTLDelete}.Validate())
+}
+
+// TestApplyToServer_Tracing проверяет, что секция tracing применяется к настройкам сервера
+// и агента, а некорректные адрес приёмника и доля запросов отклоняются.
+func TestApplyToServer_Tracing(t *testing.T) {
+	noEnv := func(string) (string, bool) { return "", false }
+	doc := map[string]any{"tracing": map[string]any{"endpoint": "http://collector:4318", "sample_ratio": 0.25}}
+
+	cfg := DefaultTracingConfig()
+	require.False(t, cfg.Enabled())
+	decodeServerJSON(t, doc).ApplyToServer(ServerTargets{Tracing: &cfg}, ServerOptions.Explicit(flag.NewFlagSet("server", flag.ContinueOnError), noEnv))
+	require.True(t, cfg.Enabled())
+	require.Equal(t, "http://collector:4318", cfg.Endpoint)
+	require.Equal(t, 0.25, cfg.SampleRatio)
+	require.NoError(t, cfg.Validate())
+
+	agentCfg := DefaultTracingConfig()
+	jc := AgentJSONConfig{Tracing: &TracingJSONConfig{Endpoint: "https://collector", ServiceName: "edge-agent"}}
+	jc.ApplyToAgent(AgentTargets{Tracing: &agentCfg}, AgentOptions.Explicit(flag.NewFlagSet("agent", flag.ContinueOnError), noEnv))
+	require.Equal(t, "edge-agent", agentCfg.ServiceName)
+	require.Equal(t, DefaultTracingSampleRatio, agentCfg.SampleRatio)
+
+	require.NoError(t, DefaultTracingConfig().Validate())
+	require.Error(t, TracingConfig{Endpoint: "collector:4318", SampleRatio: 1}.Validate())
+	require.Error(t, TracingConfig{Endpoint: "grpc://collector:4317", SampleRatio: 1}.Validate())
+	require.Error(t, TracingConfig{SampleRatio: 1.5}.Validate())
+}
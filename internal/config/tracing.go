@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// DefaultTracingSampleRatio — доля трассируемых корневых запросов по умолчанию.
+const DefaultTracingSampleRatio = 1.0
+
+type (
+	// TracingConfig описывает экспорт трассировки OpenTelemetry.
+	//
+	// Поля:
+	//   - Endpoint: адрес OTLP/HTTP-приёмника ("http://otel-collector:4318"); пусто — трассировка отключена
+	//   - ServiceName: имя сервиса в спанах (пусто — имя по умолчанию для сервера или агента)
+	//   - SampleRatio: доля трассируемых корневых запросов от 0 до 1; решение вызывающей стороны
+	//     (заголовок traceparent) соблюдается независимо от доли
+	TracingConfig struct {
+		Endpoint    string
+		ServiceName string
+		SampleRatio float64
+	}
+
+	// TracingJSONConfig представляет секцию "tracing" JSON-конфигурации сервера и агента.
+	TracingJSONConfig struct {
+		Endpoint    string   `json:"endpoint"`     // Адрес OTLP/HTTP-приёмника
+		ServiceName string   `json:"service_name"` // Имя сервиса в спанах
+		SampleRatio *float64 `json:"sample_ratio"` // Доля трассируемых запросов (0–1)
+	}
+)
+
+// DefaultTracingConfig возвращает настройки трассировки по умолчанию (трассировка отключена).
+func DefaultTracingConfig() TracingConfig {
+	return TracingConfig{SampleRatio: DefaultTracingSampleRatio}
+}
+
+// Enabled сообщает, настроен ли экспорт трассировки.
+func (c TracingConfig) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// Validate проверяет адрес приёмника и долю трассируемых запросов.
+func (c TracingConfig) Validate() error {
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("invalid tracing sample_ratio %v: must be between 0 and 1", c.SampleRatio)
+	}
+	if !c.Enabled() {
+		return nil
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid tracing endpoint %q: want http:// or https:// URL", c.Endpoint)
+	}
+	return nil
+}
+
+// apply применяет значения секции JSON к cfg.
+func (jc *TracingJSONConfig) apply(cfg *TracingConfig) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if jc.Endpoint != "" {
+		cfg.Endpoint = jc.Endpoint
+	}
+	if jc.ServiceName != "" {
+		cfg.ServiceName = jc.ServiceName
+	}
+	if jc.SampleRatio != nil {
+		cfg.SampleRatio = *jc.SampleRatio
+	}
+}
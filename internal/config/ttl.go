@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Действия с метриками, не обновлявшимися дольше срока хранения.
+const (
+	MetricTTLDelete = "delete" // Удаление метрики из хранилища
+	MetricTTLFlag   = "flag"   // Только событие аудита и запись в журнал, метрика остаётся
+)
+
+type (
+	// MetricTTLConfig описывает срок хранения метрик, которые перестали обновляться.
+	//
+	// Поля:
+	//   - TTL: срок, после которого метрика без обновлений считается устаревшей (0 — отключено)
+	//   - Action: действие с устаревшей метрикой (MetricTTLDelete или MetricTTLFlag; пусто — MetricTTLDelete)
+	MetricTTLConfig struct {
+		TTL    time.Duration
+		Action string
+	}
+
+	// MetricTTLJSONConfig представляет секцию "metric_ttl" JSON-конфигурации сервера.
+	MetricTTLJSONConfig struct {
+		TTL    string `json:"ttl"`    // METRIC_TTL или флаг -metric-ttl (в формате "24h")
+		Action string `json:"action"` // METRIC_TTL_ACTION или флаг -metric-ttl-action ("delete" или "flag")
+	}
+)
+
+// DefaultMetricTTLConfig возвращает настройки срока хранения по умолчанию (метрики не устаревают).
+func DefaultMetricTTLConfig() MetricTTLConfig {
+	return MetricTTLConfig{Action: MetricTTLDelete}
+}
+
+// Validate проверяет, что срок хранения не отрицателен, а действие известно.
+func (c MetricTTLConfig) Validate() error {
+	if c.TTL < 0 {
+		return fmt.Errorf("invalid metric ttl %s: must not be negative", c.TTL)
+	}
+	switch c.Action {
+	case "", MetricTTLDelete, MetricTTLFlag:
+	default:
+		return fmt.Errorf("invalid metric ttl action %q (want %q or %q)", c.Action, MetricTTLDelete, MetricTTLFlag)
+	}
+	return nil
+}
+
+// apply применяет значения секции JSON к cfg, не перезаписывая значения, заданные флагами или переменными окружения.
+func (jc *MetricTTLJSONConfig) apply(cfg *MetricTTLConfig, a *jsonApplier) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if d, err := time.ParseDuration(jc.TTL); a.use(FlagMetricTTL, jc.TTL != "" && err == nil) {
+		cfg.TTL = d
+	}
+	a.str(FlagMetricTTLAction, &cfg.Action, jc.Action)
+}
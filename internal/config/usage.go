@@ -0,0 +1,194 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Option описывает параметр конфигурации и его представления в разных источниках.
+//
+// Поля:
+//   - Flag: имя флага командной строки (без дефиса)
+//   - Env: имя переменной окружения (пусто, если не поддерживается)
+//   - JSON: ключ JSON-конфигурации (пусто, если не поддерживается)
+//   - Secret: значение не выводится в трассировке конфигурации
+type Option struct {
+	Flag   string
+	Env    string
+	JSON   string
+	Secret bool
+}
+
+// Options — реестр параметров конфигурации одного бинарного файла.
+type Options []Option
+
+// ServerOptions — реестр параметров конфигурации сервера.
+var ServerOptions = Options{
+	{Flag: FlagAddress, Env: EnvAddress, JSON: "address"},
+	{Flag: FlagConfig, Env: EnvConfig},
+	{Flag: FlagRestore, Env: EnvRestore, JSON: "restore"},
+	{Flag: FlagStoreInterval, Env: EnvStoreInterval, JSON: "store_interval"},
+	{Flag: FlagStoreFile, Env: EnvStoreFile, JSON: "store_file"},
+	{Flag: FlagDatabaseDSN, Env: EnvDatabaseDSN, JSON: "database_dsn", Secret: true},
+	{Flag: FlagCryptoKey, Env: EnvCryptoKey, JSON: "crypto_key"},
+	{Flag: FlagAuditFile, Env: EnvAuditFile, JSON: "audit_file"},
+	{Flag: FlagAuditURL, Env: EnvAuditURL, JSON: "audit_url"},
+	{Flag: FlagKey, Env: EnvKey, JSON: "key", Secret: true},
+	{Flag: FlagVerifyKey, Env: EnvVerifyKey, JSON: "verify_key"},
+	{Flag: FlagTrustedSubnet, Env: EnvTrustedSubnet, JSON: "trusted_subnet"},
+	{Flag: FlagGRPCAddress, Env: EnvGRPCAddress, JSON: "grpc_address"},
+	{Flag: FlagSnapshotFsync, Env: EnvSnapshotFsync, JSON: "snapshot_fsync"},
+	{Flag: FlagWatchdog, Env: EnvWatchdog, JSON: "watchdog.interval"},
+	{Flag: FlagWALFile, Env: EnvWALFile, JSON: "wal_file"},
+	{Flag: FlagStorageShards, Env: EnvStorageShards, JSON: "storage_shards"},
+	{Flag: FlagNormalizeIDs, Env: EnvNormalizeIDs, JSON: "normalize_ids"},
+	{Flag: FlagCSP, Env: EnvCSP, JSON: "security_headers.content_security_policy"},
+	{Flag: FlagPageRefresh, Env: EnvPageRefresh, JSON: "page_refresh.interval"},
+	{Flag: FlagEnrollTokens, Env: EnvEnrollTokens, JSON: "enroll_tokens", Secret: true},
+	{Flag: FlagAgentsFile, Env: EnvAgentsFile, JSON: "agents_file"},
+	{Flag: FlagAPIKeys, Env: EnvAPIKeys, JSON: "auth.api_keys", Secret: true},
+	{Flag: FlagListen, Env: EnvListen, JSON: "listeners"},
+	{Flag: FlagJWTSecret, Env: EnvJWTSecret, JSON: "auth.jwt_secret", Secret: true},
+	{Flag: FlagAPIKeysFile, Env: EnvAPIKeysFile, JSON: "auth.api_keys_file"},
+	{Flag: FlagAPIKeysDB, Env: EnvAPIKeysDB, JSON: "auth.api_keys_db"},
+	{Flag: FlagAdminAddress, Env: EnvAdminAddress, JSON: "admin_address"},
+	{Flag: FlagAdminToken, Env: EnvAdminToken, JSON: "admin_token", Secret: true},
+	{Flag: FlagRequestIDFormat, Env: EnvRequestIDFormat, JSON: "request_id_format"},
+	{Flag: FlagTLSCert, Env: EnvTLSCert, JSON: "tls_cert"},
+	{Flag: FlagTLSKey, Env: EnvTLSKey, JSON: "tls_key"},
+	{Flag: FlagTLSRedirect, Env: EnvTLSRedirect, JSON: "tls_redirect"},
+	{Flag: FlagDataDir, Env: EnvDataDir, JSON: "data_dir"},
+	{Flag: FlagLogDir, Env: EnvLogDir, JSON: "log_dir"},
+	{Flag: FlagDeadLetterFile, Env: EnvDeadLetterFile, JSON: "dead_letter_file"},
+	{Flag: FlagAuditRetries, Env: EnvAuditRetries, JSON: "audit_retries"},
+	{Flag: FlagCompressLevel, Env: EnvCompressLevel, JSON: "compression.level"},
+	{Flag: FlagCompressExclude, Env: EnvCompressExclude, JSON: "compression.exclude"},
+	{Flag: FlagRateLimitRPS, Env: EnvRateLimitRPS, JSON: "rate_limit_rps"},
+	{Flag: FlagRateLimitBurst, Env: EnvRateLimitBurst, JSON: "rate_limit_burst"},
+	{Flag: FlagMaxBodySize, Env: EnvMaxBodySize, JSON: "max_body_size"},
+	{Flag: FlagMaxGzipRatio, Env: EnvMaxGzipRatio, JSON: "max_gzip_ratio"},
+	{Flag: FlagStreamBatch, Env: EnvStreamBatch, JSON: "stream_batch"},
+	{Flag: FlagMetricTTL, Env: EnvMetricTTL, JSON: "metric_ttl.ttl"},
+	{Flag: FlagMetricTTLAction, Env: EnvMetricTTLAction, JSON: "metric_ttl.action"},
+	{Flag: FlagReusePort, Env: EnvReusePort, JSON: "reuse_port"},
+	{Flag: FlagH2C, Env: EnvH2C, JSON: "h2c"},
+	{Flag: FlagAuditQueueSize, Env: EnvAuditQueueSize, JSON: "audit_queue.size"},
+	{Flag: FlagAuditWorkers, Env: EnvAuditWorkers, JSON: "audit_queue.workers"},
+	{Flag: FlagAuditQueuePolicy, Env: EnvAuditQueuePolicy, JSON: "audit_queue.policy"},
+	{Flag: FlagVersion},
+	{Flag: FlagConfigTrace},
+	{Flag: FlagSelfTest},
+}
+
+// AgentOptions — реестр параметров конфигурации агента.
+var AgentOptions = Options{
+	{Flag: FlagAddress, Env: EnvAddress, JSON: "address"},
+	{Flag: FlagConfig, Env: EnvConfig},
+	{Flag: FlagPollInterval, Env: EnvPollInterval, JSON: "poll_interval"},
+	{Flag: FlagReportInterval, Env: EnvReportInterval, JSON: "report_interval"},
+	{Flag: FlagRateLimit, Env: EnvRateLimit, JSON: "rate_limit"},
+	{Flag: FlagMaxRPS, Env: EnvMaxRPS, JSON: "max_rps"},
+	{Flag: FlagKey, Env: EnvKey, JSON: "key", Secret: true},
+	{Flag: FlagCryptoKey, Env: EnvCryptoKey, JSON: "crypto_key"},
+	{Flag: FlagSignKey, Env: EnvSignKey, JSON: "sign_key"},
+	{Flag: FlagGRPCAddress, Env: EnvGRPCAddress, JSON: "grpc_address"},
+	{Flag: FlagSpoolDir, Env: EnvSpoolDir, JSON: "spool_dir"},
+	{Flag: FlagSpoolMaxSize, Env: EnvSpoolMaxSize, JSON: "spool_max_size"},
+	{Flag: FlagSpoolMaxAge, Env: EnvSpoolMaxAge, JSON: "spool_max_age"},
+	{Flag: FlagShutdownTimeout, Env: EnvShutdownTimeout, JSON: "shutdown_timeout"},
+	{Flag: FlagEnrollToken, Env: EnvEnrollToken, JSON: "enroll_token", Secret: true},
+	{Flag: FlagCredentialsFile, Env: EnvCredentialsFile, JSON: "credentials_file"},
+	{Flag: FlagQueueSize, Env: EnvQueueSize, JSON: "queue_size"},
+	{Flag: FlagQueuePolicy, Env: EnvQueuePolicy, JSON: "queue_policy"},
+	{Flag: FlagQueueTimeout, Env: EnvQueueTimeout, JSON: "queue_timeout"},
+	{Flag: FlagAPIKey, Env: EnvAPIKey, JSON: "api_key", Secret: true},
+	{Flag: FlagMaxBatchSize, Env: EnvMaxBatchSize, JSON: "max_batch_size"},
+	{Flag: FlagEndpointPolicy, Env: EnvEndpointPolicy, JSON: "endpoint_policy"},
+	{Flag: FlagCollect, Env: EnvCollect, JSON: "collect"},
+	{Flag: FlagNetInclude, Env: EnvNetInclude, JSON: "net_include"},
+	{Flag: FlagNetExclude, Env: EnvNetExclude, JSON: "net_exclude"},
+	{Flag: FlagStateFile, Env: EnvStateFile, JSON: "state_file"},
+	{Flag: FlagStatsDAddress, Env: EnvStatsDAddress, JSON: "statsd_address"},
+	{Flag: FlagPushAddress, Env: EnvPushAddress, JSON: "push_address"},
+	{Flag: FlagCollectIntervals, Env: EnvCollectIntervals, JSON: "collect_intervals"},
+	{Flag: FlagSendChangedOnly, Env: EnvSendChangedOnly, JSON: "send_changed_only"},
+	{Flag: FlagCPUMode, Env: EnvCPUMode, JSON: "cpu_mode"},
+	{Flag: FlagBreakerThreshold, Env: EnvBreakerThreshold, JSON: "breaker_threshold"},
+	{Flag: FlagBreakerCooldown, Env: EnvBreakerCooldown, JSON: "breaker_cooldown"},
+	{Flag: FlagCompressionDict, Env: EnvCompressionDict, JSON: "compression_dict"},
+	{Flag: FlagHTTP2, Env: EnvHTTP2, JSON: "http2"},
+	{Flag: FlagEndpointCooldown, Env: EnvEndpointCooldown, JSON: "endpoint_cooldown"},
+	{Flag: FlagTLSCA, Env: EnvTLSCA, JSON: "tls_ca"},
+	{Flag: FlagDataDir, Env: EnvDataDir, JSON: "data_dir"},
+	{Flag: FlagVersion},
+	{Flag: FlagConfigTrace},
+	{Flag: FlagSelfTest},
+}
+
+// lookup возвращает описание параметра по имени флага.
+func (o Options) lookup(flagName string) (Option, bool) {
+	for _, opt := range o {
+		if opt.Flag == flagName {
+			return opt, true
+		}
+	}
+	return Option{}, false
+}
+
+// PrintUsage выводит в w справку по флагам fs с указанием переменных окружения и ключей JSON-конфигурации.
+//
+// name — имя бинарного файла в заголовке справки.
+// fs — набор флагов.
+// w — приёмник вывода.
+func (o Options) PrintUsage(name string, fs *flag.FlagSet, w io.Writer) {
+	_, _ = fmt.Fprintf(w, "Usage of %s:\n", name)
+	hasSources := false
+	fs.VisitAll(func(f *flag.Flag) {
+		typeName, usage := flag.UnquoteUsage(f)
+		line := "  -" + f.Name
+		if typeName != "" {
+			line += " " + typeName
+		}
+		_, _ = fmt.Fprintln(w, line)
+		_, _ = fmt.Fprintf(w, "        %s", strings.ReplaceAll(usage, "\n", "\n        "))
+		switch {
+		case f.DefValue == "" || f.DefValue == "false" || f.DefValue == "0":
+		case typeName == "string":
+			_, _ = fmt.Fprintf(w, " (default %q)", f.DefValue)
+		default:
+			_, _ = fmt.Fprintf(w, " (default %s)", f.DefValue)
+		}
+		_, _ = fmt.Fprintln(w)
+
+		opt, ok := o.lookup(f.Name)
+		if !ok {
+			return
+		}
+		var sources []string
+		if opt.Env != "" {
+			sources = append(sources, "env: "+opt.Env)
+		}
+		if opt.JSON != "" {
+			sources = append(sources, "json: "+opt.JSON)
+		}
+		if len(sources) > 0 {
+			hasSources = true
+			_, _ = fmt.Fprintf(w, "        [%s]\n", strings.Join(sources, ", "))
+		}
+	})
+	if hasSources {
+		_, _ = fmt.Fprintln(w, "\nPrecedence: environment variable > flag > JSON config > default.")
+	}
+}
+
+// Usage возвращает функцию для flag.Usage, печатающую справку в стандартный вывод ошибок набора флагов.
+//
+// name — имя бинарного файла в заголовке справки.
+// fs — набор флагов.
+func (o Options) Usage(name string, fs *flag.FlagSet) func() {
+	return func() {
+		o.PrintUsage(name, fs, fs.Output())
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestOptions_PrintUsage проверяет, что справка содержит флаги, значения по умолчанию,
+// переменные окружения и ключи JSON-конфигурации из реестра.
+//
+// t — указатель на структуру теста.
+func TestOptions_PrintUsage(t *testing.T) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.String(FlagStoreFile, "metrics.json", "File storage path")
+	fs.Int(FlagStoreInterval, 300, "Store interval in seconds")
+	fs.Bool(FlagVersion, false, "Print build information and exit")
+	fs.String("unregistered", "", "Flag without registry entry")
+
+	var buf bytes.Buffer
+	ServerOptions.PrintUsage("server", fs, &buf)
+	out := buf.String()
+
+	require.Contains(t, out, "Usage of server:")
+	require.Contains(t, out, "-f string\n        File storage path (default \"metrics.json\")\n        [env: FILE_STORAGE_PATH, json: store_file]")
+	require.Contains(t, out, "-i int\n        Store interval in seconds (default 300)\n        [env: STORE_INTERVAL, json: store_interval]")
+	require.Contains(t, out, "-version\n        Print build information and exit\n")
+	require.Contains(t, out, "-unregistered string\n        Flag without registry entry\n")
+	require.Contains(t, out, "Precedence:")
+}
+
+// TestOptions_UniqueFlags проверяет, что в реестрах нет повторяющихся флагов.
+//
+// t — указатель на структуру теста.
+func TestOptions_UniqueFlags(t *testing.T) {
+	for name, opts := range map[string]Options{"server": ServerOptions, "agent": AgentOptions} {
+		seen := map[string]bool{}
+		for _, o := range opts {
+			require.False(t, seen[o.Flag], "%s: duplicate flag -%s", name, o.Flag)
+			seen[o.Flag] = true
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import "time"
+
+// Значения по умолчанию для сторожевого таймера утечек.
+const (
+	DefaultWatchdogWindow        = 5
+	DefaultWatchdogMaxGoroutines = 10000
+	DefaultWatchdogMaxOpenFDs    = 1000
+	DefaultWatchdogMaxHeapBytes  = 1 << 30
+)
+
+type (
+	// WatchdogConfig описывает настройки сторожевого таймера утечек горутин, файловых дескрипторов и памяти.
+	//
+	// Поля:
+	//   - Interval: период опроса (0 — сторожевой таймер отключён)
+	//   - Window: число последовательных измерений, монотонный рост по которым считается утечкой
+	//   - MaxGoroutines: порог числа горутин
+	//   - MaxOpenFDs: порог числа открытых файловых дескрипторов
+	//   - MaxHeapBytes: порог объёма занятой кучи в байтах
+	WatchdogConfig struct {
+		Interval      time.Duration
+		Window        int
+		MaxGoroutines int
+		MaxOpenFDs    int
+		MaxHeapBytes  uint64
+	}
+
+	// WatchdogJSONConfig представляет секцию "watchdog" JSON-конфигурации сервера.
+	WatchdogJSONConfig struct {
+		Interval      string  `json:"interval"`       // WATCHDOG_INTERVAL или флаг -watchdog-interval (в формате "30s")
+		Window        *int    `json:"window"`         // Размер окна монотонного роста
+		MaxGoroutines *int    `json:"max_goroutines"` // Порог числа горутин
+		MaxOpenFDs    *int    `json:"max_open_fds"`   // Порог числа открытых файловых дескрипторов
+		MaxHeapBytes  *uint64 `json:"max_heap_bytes"` // Порог объёма кучи в байтах
+	}
+)
+
+// DefaultWatchdogConfig возвращает настройки сторожевого таймера по умолчанию (таймер отключён).
+func DefaultWatchdogConfig() WatchdogConfig {
+	return WatchdogConfig{
+		Window:        DefaultWatchdogWindow,
+		MaxGoroutines: DefaultWatchdogMaxGoroutines,
+		MaxOpenFDs:    DefaultWatchdogMaxOpenFDs,
+		MaxHeapBytes:  DefaultWatchdogMaxHeapBytes,
+	}
+}
+
+// apply применяет значения секции JSON к cfg, не перезаписывая интервал, заданный флагом или переменной окружения.
+func (jc *WatchdogJSONConfig) apply(cfg *WatchdogConfig, a *jsonApplier) {
+	if jc == nil || cfg == nil {
+		return
+	}
+	if d, err := time.ParseDuration(jc.Interval); a.use(FlagWatchdog, jc.Interval != "" && err == nil) {
+		cfg.Interval = d
+	}
+	if jc.Window != nil {
+		cfg.Window = *jc.Window
+	}
+	if jc.MaxGoroutines != nil {
+		cfg.MaxGoroutines = *jc.MaxGoroutines
+	}
+	if jc.MaxOpenFDs != nil {
+		cfg.MaxOpenFDs = *jc.MaxOpenFDs
+	}
+	if jc.MaxHeapBytes != nil {
+		cfg.MaxHeapBytes = *jc.MaxHeapBytes
+	}
+}
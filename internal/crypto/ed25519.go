@@ -0,0 +1,99 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// SignatureHeader — заголовок с подписью Ed25519 тела запроса агента (hex).
+const SignatureHeader = "X-Signature-Ed25519"
+
+// SignatureMetadataKey — ключ метаданных gRPC с подписью Ed25519 запроса агента (hex).
+const SignatureMetadataKey = "x-signature-ed25519"
+
+// LoadSigningKey загружает закрытый ключ Ed25519 из файла в формате PEM (PKCS#8).
+//
+// filePath — путь до файла с закрытым ключом, например созданного keygen -type ed25519.
+// Возвращает закрытый ключ или ошибку; ключ, защищённый паролем, не поддерживается.
+func LoadSigningKey(filePath string) (ed25519.PrivateKey, error) {
+	keyData, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key file: %w", err)
+	}
+
+	block, _ := pem.Decode(keyData)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse PEM block containing the key")
+	}
+	if x509.IsEncryptedPEMBlock(block) {
+		return nil, fmt.Errorf("private key is encrypted: passphrase-protected signing keys are not supported")
+	}
+
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	key, ok := priv.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an Ed25519 private key")
+	}
+
+	return key, nil
+}
+
+// LoadVerifyKey загружает открытый ключ Ed25519 из файла в формате PEM (PKIX).
+//
+// filePath — путь до файла с открытым ключом.
+// Возвращает открытый ключ или ошибку.
+func LoadVerifyKey(filePath string) (ed25519.PublicKey, error) {
+	keyData, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key file: %w", err)
+	}
+
+	block, _ := pem.Decode(keyData)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse PEM block containing the key")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	key, ok := pub.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not an Ed25519 public key")
+	}
+
+	return key, nil
+}
+
+// Sign подписывает данные закрытым ключом Ed25519.
+//
+// Возвращает hex-представление подписи.
+func Sign(data []byte, key ed25519.PrivateKey) string {
+	return hex.EncodeToString(ed25519.Sign(key, data))
+}
+
+// Verify проверяет hex-подпись Ed25519 данных открытым ключом.
+func Verify(data []byte, signature string, key ed25519.PublicKey) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key, data, sig)
+}
+
+// MessagePayload возвращает подписываемое представление запроса gRPC —
+// детерминированную сериализацию protobuf сообщения m.
+func MessagePayload(m proto.Message) ([]byte, error) {
+	return proto.MarshalOptions{Deterministic: true}.Marshal(m)
+}
@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -43,6 +44,16 @@ func LoadPublicKey(filePath string) (*rsa.PublicKey, error) {
 // filePath — путь до файла с приватным ключом.
 // Возвращает приватный ключ или ошибку.
 func LoadPrivateKey(filePath string) (*rsa.PrivateKey, error) {
+	return LoadPrivateKeyWithPassphrase(filePath, nil)
+}
+
+// LoadPrivateKeyWithPassphrase загружает приватный RSA ключ из файла в формате PEM,
+// расшифровывая блок, защищённый паролем (RFC 1423).
+//
+// filePath — путь до файла с приватным ключом.
+// passphrase — пароль; для зашифрованного ключа не может быть пустым.
+// Возвращает приватный ключ или ошибку.
+func LoadPrivateKeyWithPassphrase(filePath string, passphrase []byte) (*rsa.PrivateKey, error) {
 	keyData, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read private key file: %w", err)
@@ -53,7 +64,18 @@ func LoadPrivateKey(filePath string) (*rsa.PrivateKey, error) {
 		return nil, fmt.Errorf("failed to parse PEM block containing the key")
 	}
 
-	priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	der := block.Bytes
+	if x509.IsEncryptedPEMBlock(block) {
+		if len(passphrase) == 0 {
+			return nil, fmt.Errorf("private key is encrypted: passphrase required")
+		}
+		der, err = x509.DecryptPEMBlock(block, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+		}
+	}
+
+	priv, err := x509.ParsePKCS1PrivateKey(der)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse private key: %w", err)
 	}
@@ -61,6 +83,58 @@ func LoadPrivateKey(filePath string) (*rsa.PrivateKey, error) {
 	return priv, nil
 }
 
+// SavePrivateKey сохраняет приватный ключ в файл в формате PEM.
+//
+// filePath — путь до файла; создаётся с правами 0600.
+// key — приватный ключ: *rsa.PrivateKey сохраняется в PKCS#1 (формат LoadPrivateKey),
+// ed25519.PrivateKey — в PKCS#8.
+// passphrase — пароль для шифрования блока PEM (AES-256, RFC 1423); пустой — без шифрования.
+// Возвращает ошибку записи.
+func SavePrivateKey(filePath string, key any, passphrase []byte) error {
+	var block *pem.Block
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	case ed25519.PrivateKey:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return fmt.Errorf("failed to marshal private key: %w", err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	default:
+		return fmt.Errorf("unsupported private key type %T", key)
+	}
+	if len(passphrase) > 0 {
+		var err error
+		// RFC 1423 устарел, но это формат, который создаёт и читает openssl genrsa -aes256.
+		block, err = x509.EncryptPEMBlock(rand.Reader, block.Type, block.Bytes, passphrase, x509.PEMCipherAES256)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt private key: %w", err)
+		}
+	}
+	if err := os.WriteFile(filePath, pem.EncodeToMemory(block), 0o600); err != nil {
+		return fmt.Errorf("failed to write private key file: %w", err)
+	}
+	return nil
+}
+
+// SavePublicKey сохраняет публичный ключ в файл в формате PEM (PKIX, формат LoadPublicKey).
+//
+// filePath — путь до файла.
+// key — публичный ключ (*rsa.PublicKey или ed25519.PublicKey).
+// Возвращает ошибку записи.
+func SavePublicKey(filePath string, key any) error {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	block := &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	if err := os.WriteFile(filePath, pem.EncodeToMemory(block), 0o644); err != nil {
+		return fmt.Errorf("failed to write public key file: %w", err)
+	}
+	return nil
+}
+
 // EncryptData шифрует данные с помощью публичного RSA ключа.
 //
 // data — данные для шифрования.
@@ -0,0 +1,176 @@
+// Package expiry удаляет или помечает метрики, которые перестали обновляться.
+//
+// Агент, снятый с эксплуатации, перестаёт отправлять метрики, но без срока хранения они
+// остаются в хранилище и на странице метрик навсегда. Expirer периодически находит метрики,
+// не изменявшиеся дольше MetricTTLConfig.TTL, и в зависимости от MetricTTLConfig.Action
+// удаляет их или только сообщает о них событием аудита и записью в журнал.
+//
+// Удаление попадает в журнал упреждающей записи и во все хранилища, в которые дублируются
+// записи (снимок, PostgreSQL, Redis, SQLite; см. repository.FanOut.Delete), а клиенты API
+// изменений получают его как удалённую метрику (см. repository.Storage.Changes).
+// Время изменения восстановленных метрик отсчитывается от запуска сервера.
+package expiry
+
+import (
+	"context"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"go.uber.org/zap"
+)
+
+// minInterval — минимальный период проверки при малом сроке хранения.
+const minInterval = time.Second
+
+// Expirer периодически удаляет или помечает устаревшие метрики.
+//
+// Поля:
+//   - cfg: срок хранения и действие с устаревшими метриками
+//   - storage: хранилище метрик
+//   - audit: получатель событий аудита (может быть nil)
+//   - sources: учёт вкладов источников в счётчики (может быть nil)
+//   - fanOut: хранилища, из которых удаляются метрики (может быть nil)
+//   - logger: логгер для сообщений об устаревших метриках
+//   - flagged: время изменения помеченных метрик, чтобы не сообщать о них повторно
+//   - now: функция получения текущего времени
+type Expirer struct {
+	cfg     config.MetricTTLConfig
+	storage repository.Storage
+	audit   models.AuditSubject
+	sources *repository.CounterSources
+	fanOut  *repository.FanOut
+	logger  *zap.Logger
+	flagged map[repository.MetricInfo]time.Time
+	now     func() time.Time
+}
+
+// New создаёт новый экземпляр Expirer.
+//
+// cfg — срок хранения и действие с устаревшими метриками.
+// storage — хранилище метрик.
+// audit — получатель событий аудита; если nil, события не отправляются.
+// logger — логгер для сообщений об устаревших метриках.
+//
+// Возвращает указатель на Expirer.
+func New(cfg config.MetricTTLConfig, storage repository.Storage, audit models.AuditSubject, logger *zap.Logger) *Expirer {
+	return &Expirer{
+		cfg:     cfg,
+		storage: storage,
+		audit:   audit,
+		logger:  logger,
+		flagged: make(map[repository.MetricInfo]time.Time),
+		now:     time.Now,
+	}
+}
+
+// SetCounterSources задаёт учёт вкладов источников, из которого удаляются счётчики
+// вместе с хранилищем.
+func (e *Expirer) SetCounterSources(sources *repository.CounterSources) {
+	e.sources = sources
+}
+
+// SetFanOut задаёт хранилища, в которые дублируются записи: удалённые метрики удаляются
+// и из них, иначе они вернутся в память при следующем запуске сервера.
+func (e *Expirer) SetFanOut(fanOut *repository.FanOut) {
+	e.fanOut = fanOut
+}
+
+// Run проверяет метрики с периодом в половину срока хранения (не чаще раза в секунду)
+// до отмены контекста, поэтому устаревшая метрика обрабатывается не позже чем через
+// полтора срока хранения после последнего обновления.
+//
+// Если срок хранения не положителен, функция сразу возвращает управление.
+func (e *Expirer) Run(ctx context.Context) {
+	if e.cfg.TTL <= 0 {
+		return
+	}
+	ticker := time.NewTicker(max(e.cfg.TTL/2, minInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.Sweep(e.now())
+		}
+	}
+}
+
+// Sweep обрабатывает метрики, не изменявшиеся дольше срока хранения к моменту now.
+//
+// В режиме MetricTTLDelete метрики удаляются из хранилища и хранилищ SetFanOut, в режиме MetricTTLFlag остаются,
+// а сообщение о каждой из них отправляется один раз, пока она снова не обновится.
+// Об обработанных метриках отправляется одно событие аудита (AuditMetricExpired или
+// AuditMetricStale).
+//
+// Возвращает обработанные метрики в порядке SortMetricInfo.
+func (e *Expirer) Sweep(now time.Time) []repository.MetricInfo {
+	if e.cfg.TTL <= 0 {
+		return nil
+	}
+	cutoff := now.Add(-e.cfg.TTL)
+	flag := e.cfg.Action == config.MetricTTLFlag
+	flagged := make(map[repository.MetricInfo]time.Time)
+
+	var affected []repository.MetricInfo
+	for _, m := range e.storage.GetAll() {
+		at, ok := e.storage.UpdatedAt(m.Type, m.Name)
+		if !ok || at.After(cutoff) {
+			continue
+		}
+		key := repository.MetricInfo{Name: m.Name, Type: m.Type}
+		if flag {
+			flagged[key] = at
+			if prev, seen := e.flagged[key]; seen && prev.Equal(at) {
+				continue
+			}
+		} else {
+			if !e.storage.Expire(m.Type, m.Name, cutoff) {
+				// Метрика обновилась после выборки.
+				continue
+			}
+			if m.Type == models.Counter && e.sources != nil {
+				e.sources.Forget(m.Name)
+			}
+		}
+		affected = append(affected, m)
+	}
+	e.flagged = flagged
+
+	if !flag {
+		if err := e.fanOut.Delete(context.Background(), affected); err != nil {
+			e.logger.Error("Failed to delete expired metrics from storage backends", zap.Error(err))
+		}
+	}
+	if len(affected) > 0 {
+		e.report(affected, flag, now)
+	}
+	return affected
+}
+
+// report записывает в журнал и отправляет событие аудита об обработанных метриках.
+func (e *Expirer) report(affected []repository.MetricInfo, flag bool, now time.Time) {
+	names := make([]string, 0, len(affected))
+	for _, m := range affected {
+		names = append(names, m.Name)
+	}
+	event, op, msg := models.AuditMetricExpired, models.AuditOpDelete, "Expired stale metrics"
+	if flag {
+		event, op, msg = models.AuditMetricStale, "", "Metrics not updated within TTL"
+	}
+	e.logger.Warn(msg,
+		zap.Duration("ttl", e.cfg.TTL),
+		zap.Int("count", len(names)),
+		zap.Strings("metrics", names),
+	)
+	if e.audit != nil {
+		e.audit.Notify(models.AuditEvent{
+			Timestamp: now.Unix(),
+			Metrics:   names,
+			Event:     event,
+			Operation: op,
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package expiry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// recordingObserver запоминает полученные события аудита.
+type recordingObserver struct {
+	events []models.AuditEvent
+}
+
+// OnAuditEvent запоминает событие.
+func (o *recordingObserver) OnAuditEvent(event models.AuditEvent) error {
+	o.events = append(o.events, event)
+	return nil
+}
+
+// TestExpirer_Sweep_TableDriven проверяет удаление и пометку метрик, не обновлявшихся
+// дольше срока хранения, и события аудита о них.
+//
+// t — указатель на структуру теста.
+func TestExpirer_Sweep_TableDriven(t *testing.T) {
+	tests := []struct {
+		name      string        // Название теста
+		action    string        // Действие с устаревшими метриками
+		ttl       time.Duration // Срок хранения
+		wantSweep []string      // Ожидаемые обработанные метрики (имя/тип)
+		wantLeft  int           // Ожидаемое число метрик в хранилище после проверки
+		wantEvent string        // Ожидаемый тип события аудита ("" — событий нет)
+	}{
+		{
+			name:      "delete",
+			action:    config.MetricTTLDelete,
+			ttl:       time.Minute,
+			wantSweep: []string{"host1.cpu/gauge", "host1.requests/counter"},
+			wantLeft:  1,
+			wantEvent: models.AuditMetricExpired,
+		},
+		{
+			name:      "flag",
+			action:    config.MetricTTLFlag,
+			ttl:       time.Minute,
+			wantSweep: []string{"host1.cpu/gauge", "host1.requests/counter"},
+			wantLeft:  3,
+			wantEvent: models.AuditMetricStale,
+		},
+		{
+			name:     "nothing stale",
+			action:   config.MetricTTLDelete,
+			ttl:      time.Hour,
+			wantLeft: 3,
+		},
+		{
+			name:     "disabled",
+			action:   config.MetricTTLDelete,
+			wantLeft: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			storage := repository.NewMemStorage()
+			sources := repository.NewCounterSources(false)
+			storage.SetGauge("host1.cpu", 1)
+			storage.AddCounter("host1.requests", 5)
+			sources.Add("host1.requests", "host1", 5)
+			time.Sleep(2 * time.Millisecond)
+			storage.SetGauge("host2.cpu", 2)
+
+			obs := &recordingObserver{}
+			audit := repository.NewAuditManager()
+			audit.Attach(obs)
+			e := New(config.MetricTTLConfig{TTL: tt.ttl, Action: tt.action}, storage, audit, zap.NewNop())
+			e.SetCounterSources(sources)
+
+			// Проверка через минуту без наносекунды после обновления host2:
+			// при сроке хранения в минуту устарел только host1.
+			at, _ := storage.UpdatedAt(models.Gauge, "host2.cpu")
+			now := at.Add(time.Minute - time.Nanosecond)
+
+			var got []string
+			for _, m := range e.Sweep(now) {
+				got = append(got, m.Name+"/"+m.Type)
+			}
+			require.Equal(t, tt.wantSweep, got)
+			require.Len(t, storage.GetAll(), tt.wantLeft)
+
+			if tt.wantEvent == "" {
+				require.Empty(t, obs.events)
+				return
+			}
+			require.Len(t, obs.events, 1)
+			require.Equal(t, tt.wantEvent, obs.events[0].Event)
+			require.Equal(t, []string{"host1.cpu", "host1.requests"}, obs.events[0].Metrics)
+			if tt.action == config.MetricTTLDelete {
+				require.Empty(t, sources.Sources("host1.requests"))
+			} else {
+				require.Len(t, sources.Sources("host1.requests"), 1)
+			}
+		})
+	}
+}
+
+// TestExpirer_FlagOnce проверяет, что о помеченной метрике сообщается один раз,
+// пока она снова не обновится и не устареет.
+//
+// t — указатель на структуру теста.
+func TestExpirer_FlagOnce(t *testing.T) {
+	storage := repository.NewMemStorage()
+	storage.SetGauge("g", 1)
+	e := New(config.MetricTTLConfig{TTL: time.Minute, Action: config.MetricTTLFlag}, storage, nil, zap.NewNop())
+
+	now := time.Now().Add(time.Hour)
+	require.Len(t, e.Sweep(now), 1)
+	require.Empty(t, e.Sweep(now))
+
+	storage.SetGauge("g", 2)
+	require.Empty(t, e.Sweep(time.Now()))
+	require.Len(t, e.Sweep(now), 1)
+}
+
+// TestExpirer_KeepsUpdatedMetric проверяет, что метрика, обновлённая после начала проверки,
+// не удаляется, а удалённая метрика создаётся заново при следующем обновлении.
+//
+// t — указатель на структуру теста.
+func TestExpirer_KeepsUpdatedMetric(t *testing.T) {
+	storage := repository.NewMemStorage()
+	storage.SetGauge("g", 1)
+	e := New(config.MetricTTLConfig{TTL: time.Minute, Action: config.MetricTTLDelete}, storage, nil, zap.NewNop())
+
+	require.Len(t, e.Sweep(time.Now().Add(time.Hour)), 1)
+	_, ok := storage.GetGauge("g")
+	require.False(t, ok)
+
+	storage.SetGauge("g", 2)
+	require.Empty(t, e.Sweep(time.Now()))
+	v, ok := storage.GetGauge("g")
+	require.True(t, ok)
+	require.Equal(t, 2.0, v)
+}
+
+// recordingSink запоминает метрики, удалённые из внешнего хранилища.
+type recordingSink struct {
+	deleted []repository.MetricInfo
+}
+
+// Sync ничего не делает.
+func (s *recordingSink) Sync(context.Context) error {
+	return nil
+}
+
+// Delete запоминает удалённые метрики.
+func (s *recordingSink) Delete(_ context.Context, metrics []repository.MetricInfo) error {
+	s.deleted = append(s.deleted, metrics...)
+	return nil
+}
+
+// TestExpirer_DeletesFromFanOut проверяет, что удалённые метрики удаляются и из хранилищ,
+// в которые дублируются записи, а помеченные остаются в них.
+//
+// t — указатель на структуру теста.
+func TestExpirer_DeletesFromFanOut(t *testing.T) {
+	for _, action := range []string{config.MetricTTLDelete, config.MetricTTLFlag} {
+		t.Run(action, func(t *testing.T) {
+			storage := repository.NewMemStorage()
+			storage.SetGauge("g", 1)
+			storage.AddCounter("c", 1)
+			sink := &recordingSink{}
+			e := New(config.MetricTTLConfig{TTL: time.Minute, Action: action}, storage, nil, zap.NewNop())
+			e.SetFanOut(repository.NewFanOut(repository.FanOutBackend{Name: "test", Sink: sink}))
+
+			swept := e.Sweep(time.Now().Add(time.Hour))
+			require.Len(t, swept, 2)
+			if action == config.MetricTTLFlag {
+				require.Empty(t, sink.deleted)
+				return
+			}
+			require.Equal(t, swept, sink.deleted)
+		})
+	}
+}
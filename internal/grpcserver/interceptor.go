@@ -2,17 +2,49 @@ package grpcserver
 
 import (
 	"context"
+	"crypto/ed25519"
+	"errors"
 	"net"
 	"strings"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/RoGogDBD/metric-alerter/internal/crypto"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/requestid"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
+// RequestIDInterceptor присваивает каждому вызову идентификатор запроса и возвращает его
+// в заголовке ответа x-request-id.
+//
+// Корректный идентификатор из метаданных клиента сохраняется; иначе создаётся новый генератором gen.
+func RequestIDInterceptor(gen requestid.Generator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var id string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(requestid.MetadataKey); len(values) > 0 {
+				id = values[0]
+			}
+		}
+		if !requestid.Valid(id) {
+			id = gen()
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestid.MetadataKey, id))
+		return handler(requestid.NewContext(ctx, id), req)
+	}
+}
+
 // IPSubnetInterceptor проверяет IP-адрес агента из метаданных.
-func IPSubnetInterceptor(trustedSubnet *net.IPNet) grpc.UnaryServerInterceptor {
+//
+// Об отказах сообщается событием аудита через audit (может быть nil).
+func IPSubnetInterceptor(trustedSubnet *net.IPNet, audit models.AuditSubject) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		if trustedSubnet == nil {
 			return handler(ctx, req)
@@ -20,20 +52,125 @@ func IPSubnetInterceptor(trustedSubnet *net.IPNet) grpc.UnaryServerInterceptor {
 
 		md, ok := metadata.FromIncomingContext(ctx)
 		if !ok {
+			auditRejection(ctx, audit, info.FullMethod, models.AuditSubnetDenied)
 			return nil, status.Error(codes.PermissionDenied, "missing metadata")
 		}
 
 		values := md.Get("x-real-ip")
 		if len(values) == 0 {
+			auditRejection(ctx, audit, info.FullMethod, models.AuditSubnetDenied)
 			return nil, status.Error(codes.PermissionDenied, "missing x-real-ip")
 		}
 
 		ipString := strings.TrimSpace(values[0])
 		ip := net.ParseIP(ipString)
 		if ip == nil || !trustedSubnet.Contains(ip) {
+			auditRejection(ctx, audit, info.FullMethod, models.AuditSubnetDenied)
 			return nil, status.Error(codes.PermissionDenied, "ip not allowed")
 		}
 
 		return handler(ctx, req)
 	}
 }
+
+// RoleInterceptor проверяет, что токен из метаданных (authorization: Bearer или x-api-key)
+// даёт роль не ниже required. Если a равен nil, ролевой доступ отключён.
+//
+// Об отказах сообщается событием аудита через audit (может быть nil).
+func RoleInterceptor(a *auth.Authenticator, required auth.Role, audit models.AuditSubject) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if a == nil {
+			return handler(ctx, req)
+		}
+
+		var token string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("authorization"); len(values) > 0 {
+				token, _ = strings.CutPrefix(values[0], "Bearer ")
+			} else if values := md.Get(strings.ToLower(auth.APIKeyHeader)); len(values) > 0 {
+				token = values[0]
+			}
+		}
+
+		if err := a.Authorize(strings.TrimSpace(token), required); err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				auditRejection(ctx, audit, info.FullMethod, models.AuditForbidden)
+				return nil, status.Error(codes.PermissionDenied, "insufficient role")
+			}
+			auditRejection(ctx, audit, info.FullMethod, models.AuditInvalidCredentials)
+			return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// SignatureInterceptor проверяет подпись Ed25519 запроса в метаданных crypto.SignatureMetadataKey.
+//
+// Подписывается детерминированная сериализация запроса (crypto.MessagePayload). Если key
+// равен nil, проверка отключена. Об отказах сообщается событием аудита через audit (может быть nil).
+func SignatureInterceptor(key ed25519.PublicKey, audit models.AuditSubject) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if key == nil {
+			return handler(ctx, req)
+		}
+
+		var signature string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(crypto.SignatureMetadataKey); len(values) > 0 {
+				signature = values[0]
+			}
+		}
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return nil, status.Error(codes.Internal, "unsupported request type")
+		}
+		payload, err := crypto.MessagePayload(msg)
+		if err != nil || !crypto.Verify(payload, signature, key) {
+			auditRejection(ctx, audit, info.FullMethod, models.AuditInvalidSignature)
+			return nil, status.Error(codes.Unauthenticated, "invalid signature")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// auditRejection отправляет событие аудита об отказе в доступе к методу gRPC.
+func auditRejection(ctx context.Context, audit models.AuditSubject, method, kind string) {
+	if audit == nil {
+		return
+	}
+	audit.Notify(models.AuditEvent{
+		Timestamp: time.Now().Unix(),
+		Metrics:   []string{},
+		IPAddress: clientIP(ctx),
+		Event:     kind,
+		Route:     method,
+		RequestID: requestid.FromContext(ctx),
+	})
+}
+
+// clientIP возвращает IP-адрес клиента из метаданных x-real-ip, а если их нет — из адреса соединения.
+func clientIP(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-real-ip"); len(values) > 0 {
+			if ip := strings.TrimSpace(values[0]); ip != "" {
+				return ip
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		ip, _, _ := net.SplitHostPort(p.Addr.String())
+		return ip
+	}
+	return ""
+}
+
+// counterSource возвращает источник приращений счётчиков: идентификатор агента из метаданных
+// x-agent-id, а без него — IP-адрес клиента.
+func counterSource(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(strings.ToLower(models.AgentIDHeader)); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return clientIP(ctx)
+}
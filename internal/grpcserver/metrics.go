@@ -6,6 +6,7 @@ import (
 
 	"github.com/RoGogDBD/metric-alerter/internal/proto"
 	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/RoGogDBD/metric-alerter/internal/telemetry"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -14,13 +15,35 @@ import (
 // MetricsService реализует gRPC сервис для обновления метрик.
 type MetricsService struct {
 	proto.UnimplementedMetricsServer
-	storage repository.Storage
-	db      *pgxpool.Pool
+	storage   repository.Storage
+	fanOut    *repository.FanOut
+	telemetry *telemetry.Metrics
+	sources   *repository.CounterSources
 }
 
 // NewMetricsService создает новый gRPC сервис метрик.
+//
+// При заданном пуле db обновления синхронно записываются в базу данных (см. SetFanOut).
 func NewMetricsService(storage repository.Storage, db *pgxpool.Pool) *MetricsService {
-	return &MetricsService{storage: storage, db: db}
+	return &MetricsService{storage: storage, fanOut: repository.NewDBFanOut(storage, db)}
+}
+
+// SetFanOut задаёт хранилища, в которые дублируются записи после каждого обновления.
+func (s *MetricsService) SetFanOut(f *repository.FanOut) {
+	s.fanOut = f
+}
+
+// SetTelemetry задаёт собственные метрики сервера, в которых учитываются размеры принятых пакетов.
+func (s *MetricsService) SetTelemetry(m *telemetry.Metrics) {
+	s.telemetry = m
+}
+
+// SetCounterSources задаёт учёт вкладов источников в счётчики.
+//
+// Источником считается идентификатор агента из метаданных x-agent-id, а без него — IP-адрес
+// из x-real-ip или адрес соединения.
+func (s *MetricsService) SetCounterSources(sources *repository.CounterSources) {
+	s.sources = sources
 }
 
 // UpdateMetrics обновляет метрики на сервере.
@@ -29,25 +52,69 @@ func (s *MetricsService) UpdateMetrics(ctx context.Context, req *proto.UpdateMet
 		return nil, status.Error(codes.InvalidArgument, "empty request")
 	}
 
+	if len(req.GetMetrics()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "empty batch")
+	}
+	if err := validateMetrics(req.GetMetrics()); err != nil {
+		return nil, err
+	}
+	if name, err := checkCounterOverflow(s.storage, req.GetMetrics()); err != nil {
+		return nil, status.Error(codes.OutOfRange, fmt.Sprintf("counter %s: %v", name, err))
+	}
+	s.telemetry.ObserveBatch("grpc", len(req.GetMetrics()))
+
+	var source string
+	if s.sources != nil {
+		source = counterSource(ctx)
+	}
 	for _, metric := range req.GetMetrics() {
-		if metric.GetId() == "" {
-			return nil, status.Error(codes.InvalidArgument, "metric id is required")
-		}
 		switch metric.GetType() {
 		case proto.Metric_GAUGE:
 			s.storage.SetGauge(metric.GetId(), metric.GetValue())
 		case proto.Metric_COUNTER:
 			s.storage.AddCounter(metric.GetId(), metric.GetDelta())
-		default:
-			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("unknown metric type: %v", metric.GetType()))
+			if s.sources != nil {
+				s.sources.Add(metric.GetId(), source, metric.GetDelta())
+			}
 		}
 	}
 
-	if s.db != nil {
-		if err := repository.SyncToDB(ctx, s.storage, s.db); err != nil {
-			return nil, status.Error(codes.Internal, "failed to save metrics")
-		}
+	if err := s.fanOut.Sync(ctx); err != nil {
+		return nil, status.Error(codes.Internal, "failed to save metrics")
 	}
 
 	return &proto.UpdateMetricsResponse{}, nil
 }
+
+// validateMetrics проверяет все метрики запроса до применения к хранилищу,
+// чтобы некорректный запрос не был применён частично.
+func validateMetrics(metrics []*proto.Metric) error {
+	for i, m := range metrics {
+		if m.GetId() == "" {
+			return status.Error(codes.InvalidArgument, fmt.Sprintf("metric %d: metric id is required", i))
+		}
+		switch m.GetType() {
+		case proto.Metric_GAUGE, proto.Metric_COUNTER:
+		default:
+			return status.Error(codes.InvalidArgument, fmt.Sprintf("metric %s: unknown metric type: %v", m.GetId(), m.GetType()))
+		}
+	}
+	return nil
+}
+
+// checkCounterOverflow проверяет, что приращения счётчиков из metrics не переполнят int64
+// ни в сумме внутри запроса, ни вместе с текущими значениями в хранилище.
+func checkCounterOverflow(storage repository.Storage, metrics []*proto.Metric) (string, error) {
+	deltas := make(map[string]int64)
+	for _, m := range metrics {
+		if m.GetType() != proto.Metric_COUNTER {
+			continue
+		}
+		sum, err := repository.AddCounterChecked(deltas[m.GetId()], m.GetDelta())
+		if err != nil {
+			return m.GetId(), err
+		}
+		deltas[m.GetId()] = sum
+	}
+	return repository.CheckCounterOverflow(storage, deltas)
+}
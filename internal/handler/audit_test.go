@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAudit запоминает отправленные события аудита.
+type recordingAudit struct {
+	events []models.AuditEvent
+}
+
+func (a *recordingAudit) Attach(models.AuditObserver) {}
+func (a *recordingAudit) Detach(models.AuditObserver) {}
+func (a *recordingAudit) Notify(event models.AuditEvent) {
+	a.events = append(a.events, event)
+}
+
+// TestHandler_AuditRejections проверяет события аудита для неверной подписи и запросов вне доверенной подсети.
+//
+// t — указатель на структуру теста.
+func TestHandler_AuditRejections(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		realIP   string
+		hash     string
+		wantCode int
+		wantKind string
+	}{
+		{name: "invalid signature", realIP: "10.0.0.1", hash: "bad", wantCode: http.StatusBadRequest, wantKind: models.AuditInvalidSignature},
+		{name: "outside trusted subnet", realIP: "192.168.0.1", wantCode: http.StatusForbidden, wantKind: models.AuditSubnetDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &recordingAudit{}
+			h := NewHandler(repository.NewMemStorage(), nil)
+			h.SetKey("secret")
+			h.SetTrustedSubnet(subnet)
+			h.SetAuditManager(audit)
+
+			r := httptest.NewRequest(http.MethodPost, "/update", bytes.NewBufferString(`{"id":"m","type":"gauge","value":1}`))
+			r.Header.Set("X-Real-IP", tt.realIP)
+			r.Header.Set("HashSHA256", tt.hash)
+			w := httptest.NewRecorder()
+			h.HandleUpdateJSON(w, r)
+
+			require.Equal(t, tt.wantCode, w.Code)
+			require.Len(t, audit.events, 1)
+			require.Equal(t, tt.wantKind, audit.events[0].Event)
+			require.Equal(t, tt.realIP, audit.events[0].IPAddress)
+			require.Equal(t, "POST /update", audit.events[0].Route)
+		})
+	}
+}
+
+// TestHandler_AuditEnrichment проверяет операцию, маршрут, User-Agent и идентификатор
+// клиента в событиях аудита об изменении и чтении метрик.
+//
+// t — указатель на структуру теста.
+func TestHandler_AuditEnrichment(t *testing.T) {
+	tests := []struct {
+		name         string   // Название теста
+		method       string   // Метод запроса
+		target       string   // Путь запроса
+		wantRoute    string   // Ожидаемый маршрут
+		body         string   // Тело запроса
+		identity     string   // Идентификатор клиента из аутентификации
+		agentID      string   // Заголовок X-Agent-ID
+		wantOp       string   // Ожидаемая операция
+		wantIdentity string   // Ожидаемый идентификатор клиента
+		wantMetrics  []string // Ожидаемые метрики события
+	}{
+		{
+			name:         "URL update ignores unverified agent",
+			method:       http.MethodPost,
+			target:       "/update/gauge/Alloc/1.5",
+			wantRoute:    "POST /update/{type}/{name}/{value}",
+			agentID:      "a1",
+			wantOp:       models.AuditOpUpdate,
+			wantMetrics:  []string{"Alloc"},
+			wantIdentity: "",
+		},
+		{
+			name:         "batch by API key",
+			method:       http.MethodPost,
+			target:       "/updates/",
+			wantRoute:    "POST /updates",
+			body:         `[{"id":"Alloc","type":"gauge","value":1},{"id":"PollCount","type":"counter","delta":2}]`,
+			identity:     "key:ci-writer",
+			wantOp:       models.AuditOpBatch,
+			wantMetrics:  []string{"Alloc", "PollCount"},
+			wantIdentity: "key:ci-writer",
+		},
+		{
+			name:         "read",
+			method:       http.MethodGet,
+			target:       "/value/gauge/Alloc",
+			wantRoute:    "GET /value/{type}/{name}",
+			identity:     "jwt:alice",
+			wantOp:       models.AuditOpRead,
+			wantMetrics:  []string{"Alloc"},
+			wantIdentity: "jwt:alice",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &recordingAudit{}
+			storage := repository.NewMemStorage()
+			storage.SetGauge("Alloc", 1)
+			h := NewHandler(storage, nil)
+			h.SetAuditManager(audit)
+
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					if tt.identity != "" {
+						req = req.WithContext(auth.WithIdentity(req.Context(), tt.identity))
+					}
+					next.ServeHTTP(w, req)
+				})
+			})
+			r.Post("/update/{type}/{name}/{value}", h.HandleUpdate)
+			r.Post("/updates/", h.HandlerUpdateBatchJSON)
+			r.Get("/value/{type}/{name}", h.HandleGetMetricValue)
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("User-Agent", "metric-agent/1.0")
+			if tt.agentID != "" {
+				req.Header.Set(models.AgentIDHeader, tt.agentID)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			require.Len(t, audit.events, 1)
+			event := audit.events[0]
+			require.Equal(t, tt.wantOp, event.Operation)
+			require.Equal(t, tt.wantRoute, event.Route)
+			require.Equal(t, "metric-agent/1.0", event.UserAgent)
+			require.Equal(t, tt.wantIdentity, event.Identity)
+			require.ElementsMatch(t, tt.wantMetrics, event.Metrics)
+		})
+	}
+}
+
+// TestSendAgentAuditEvent проверяет, что для запроса с проверенной подписью клиентом
+// считается агент, если клиент не прошёл аутентификацию по ключу.
+//
+// t — указатель на структуру теста.
+func TestSendAgentAuditEvent(t *testing.T) {
+	audit := &recordingAudit{}
+	h := NewHandler(repository.NewMemStorage(), nil)
+	h.SetAuditManager(audit)
+
+	r := httptest.NewRequest(http.MethodPost, "/update/", nil)
+	r.Header.Set(models.AgentIDHeader, "a1")
+	h.sendAgentAuditEvent(r, models.AuditOpUpdate, []string{"Alloc"})
+	h.sendAgentAuditEvent(r.WithContext(auth.WithIdentity(r.Context(), "key:ci-writer")), models.AuditOpUpdate, []string{"Alloc"})
+
+	require.Len(t, audit.events, 2)
+	require.Equal(t, "agent:a1", audit.events[0].Identity)
+	require.Equal(t, "key:ci-writer", audit.events[1].Identity)
+}
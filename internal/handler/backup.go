@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// backupRunner реализуется планировщиком резервного копирования (см. backup.Scheduler).
+type backupRunner interface {
+	RunOnce(t time.Time) (path string, removed []string, err error)
+}
+
+// backupResult — ответ на запрос резервной копии.
+type backupResult struct {
+	File    string   `json:"file"`              // Путь к созданной копии
+	Removed []string `json:"removed,omitempty"` // Удалённые устаревшие копии
+}
+
+// SetBackup задаёт планировщик, создающий резервные копии по запросу POST /admin/backup.
+func (h *Handler) SetBackup(b backupRunner) {
+	h.backup = b
+}
+
+// HandleBackup создаёт резервную копию хранилища вне расписания.
+//
+// Устаревшие копии сверх заданного количества удаляются так же, как после копирования по расписанию.
+//
+// @Summary Создать резервную копию
+// @Description Записывает сжатый снимок метрик в каталог резервных копий и удаляет устаревшие копии
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} backupResult "Созданная и удалённые копии"
+// @Failure 400 {object} models.ErrorResponse "Резервное копирование не настроено"
+// @Failure 500 {object} models.ErrorResponse "Не удалось записать копию"
+// @Router /admin/backup [post]
+func (h *Handler) HandleBackup(w http.ResponseWriter, r *http.Request) {
+	if h.backup == nil {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "backups are not configured")
+		return
+	}
+	path, removed, err := h.backup.RunOnce(time.Now())
+	if err != nil {
+		log.Printf("Failed to write backup: %v", err)
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeInternal, "failed to write backup")
+		return
+	}
+	log.Printf("Backup %s written via %s", path, r.URL.Path)
+	if err := h.writeJSONWithHash(w, backupResult{File: path, Removed: removed}); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// stubBackup — планировщик резервного копирования с заданным результатом.
+type stubBackup struct {
+	path    string
+	removed []string
+	err     error
+}
+
+// RunOnce возвращает заданный результат.
+func (b stubBackup) RunOnce(time.Time) (string, []string, error) {
+	return b.path, b.removed, b.err
+}
+
+// TestHandleBackup проверяет резервное копирование по запросу и ответы без настроенного
+// копирования и при ошибке записи.
+//
+// t — указатель на структуру теста.
+func TestHandleBackup(t *testing.T) {
+	tests := []struct {
+		name       string       // Название теста
+		backup     backupRunner // Планировщик (nil — копирование не настроено)
+		wantStatus int          // Ожидаемый код ответа
+		want       backupResult // Ожидаемый ответ
+	}{
+		{name: "NotConfigured", wantStatus: http.StatusBadRequest},
+		{name: "Failed", backup: stubBackup{err: errors.New("disk full")}, wantStatus: http.StatusInternalServerError},
+		{
+			name:       "Written",
+			backup:     stubBackup{path: "backups/metrics-20250101T1200.json.gz", removed: []string{"backups/metrics-20241231T1200.json.gz"}},
+			wantStatus: http.StatusOK,
+			want:       backupResult{File: "backups/metrics-20250101T1200.json.gz", Removed: []string{"backups/metrics-20241231T1200.json.gz"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(repository.NewMemStorage(), nil)
+			if tc.backup != nil {
+				h.SetBackup(tc.backup)
+			}
+			rec := httptest.NewRecorder()
+			h.HandleBackup(rec, httptest.NewRequest(http.MethodPost, "/admin/backup", nil))
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			var got backupResult
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// TestHandleConfig проверяет, что конфигурация отдаётся со скрытыми секретами.
+//
+// t — указатель на структуру теста.
+func TestHandleConfig(t *testing.T) {
+	h := NewHandler(repository.NewMemStorage(), nil)
+	h.SetDiagnostics(map[string]string{"address": "localhost:8080", "key": "s3cret"}, "")
+
+	rec := httptest.NewRecorder()
+	h.HandleConfig(rec, httptest.NewRequest(http.MethodGet, "/admin/config", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	var got map[string]string
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	require.Equal(t, "localhost:8080", got["address"])
+	require.NotContains(t, rec.Body.String(), "s3cret")
+}
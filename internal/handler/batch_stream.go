@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/RoGogDBD/metric-alerter/internal/compression"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+)
+
+// SetBatchStreaming включает применение батчей POST /updates/ по мере разбора тела запроса.
+//
+// chunk — количество метрик, применяемых к хранилищу одним пакетом (см. repository.UpdateBatch);
+// 0 отключает потоковое применение: батч разбирается и проверяется целиком.
+// Потоково применяются только батчи, подпись которых проверять не нужно: подписанный батч
+// нельзя применять до проверки подписи по всему телу.
+func (h *Handler) SetBatchStreaming(chunk int) {
+	h.batchChunk = chunk
+}
+
+// updateBatchStream разбирает массив метрик из тела запроса через json.Decoder.Token и применяет
+// их порциями по h.batchChunk, поэтому тело запроса не буферизуется целиком.
+//
+// Ответ имеет тот же вид, что и у HandlerUpdateBatchJSON (массив применённых метрик), но батч
+// применяется не атомарно: при ошибке в метрике или в разборе уже применённые порции остаются
+// в хранилище, синхронизируются и попадают в событие аудита. Количество применённых метрик
+// передаётся в заголовке models.BatchAppliedHeader, поэтому ответ с ошибкой
+// и ненулевым значением заголовка означает частично применённый батч.
+func (h *Handler) updateBatchStream(w http.ResponseWriter, r *http.Request) {
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	if tok == nil {
+		// null разбирается в пустой батч, как в validateBatch.
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeEmptyBatch, "empty batch")
+		return
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		writeDecodeError(w, r, fmt.Errorf("expected array of metrics, got %v", tok))
+		return
+	}
+
+	source := h.counterSource(r)
+	var applied models.MetricsBatch
+	chunk := make([]models.Metrics, 0, h.batchChunk)
+
+	// flush проверяет переполнение счётчиков порции и применяет её к хранилищу.
+	flush := func() *metricError {
+		if id, err := h.checkCounterOverflow(chunk); err != nil {
+			return &metricError{http.StatusBadRequest, models.ErrCodeCounterOverflow, "counter value out of int64 range", map[string]string{"id": id}}
+		}
+		h.applyChunk(chunk, source)
+		applied = append(applied, chunk...)
+		chunk = chunk[:0]
+		return nil
+	}
+	// finish синхронизирует применённые метрики, отправляет событие аудита и сообщает
+	// их количество в заголовке ответа.
+	finish := func() error {
+		w.Header().Set(models.BatchAppliedHeader, strconv.Itoa(len(applied)))
+		if len(applied) == 0 {
+			return nil
+		}
+		h.telemetry.ObserveBatch("http", len(applied))
+		names := make(map[string]struct{}, len(applied))
+		metricNames := make([]string, 0, len(applied))
+		for _, m := range applied {
+			if _, ok := names[m.ID]; !ok {
+				names[m.ID] = struct{}{}
+				metricNames = append(metricNames, m.ID)
+			}
+		}
+		sort.Strings(metricNames)
+		defer h.sendAgentAuditEvent(r, models.AuditOpBatch, metricNames)
+		return h.fanOut.Sync(r.Context())
+	}
+	// fail отвечает ошибкой, сохранив уже применённые порции.
+	fail := func(write func()) {
+		if err := finish(); err != nil {
+			log.Printf("Failed to save metrics of a rejected batch: %v", err)
+		}
+		if len(applied) > 0 {
+			log.Printf("Batch partially applied: rejected after %d applied metrics", len(applied))
+		}
+		write()
+	}
+
+	for i := 0; dec.More(); i++ {
+		var m models.Metrics
+		if err := dec.Decode(&m); err != nil {
+			fail(func() { writeDecodeError(w, r, decodeError(err)) })
+			return
+		}
+		if e := validateMetric(m); e != nil {
+			fail(func() { e.at(i).write(w, r) })
+			return
+		}
+		chunk = append(chunk, m)
+		if len(chunk) == h.batchChunk {
+			if e := flush(); e != nil {
+				fail(func() { e.write(w, r) })
+				return
+			}
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		fail(func() { writeDecodeError(w, r, err) })
+		return
+	}
+	if len(applied) == 0 && len(chunk) == 0 {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeEmptyBatch, "empty batch")
+		return
+	}
+	if e := flush(); e != nil {
+		fail(func() { e.write(w, r) })
+		return
+	}
+
+	if err := finish(); err != nil {
+		log.Printf("Failed to save metrics: %v", err)
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeStorageFailed, "failed to save metrics")
+		return
+	}
+	if h.dictionaries != nil {
+		w.Header().Set(compression.AcceptHeader, compression.Encoding)
+	}
+	if err := h.writeJSONWithHash(w, applied); err != nil {
+		log.Printf("Failed to write response: %v", err)
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeInternal, "failed to write response")
+	}
+}
+
+// applyChunk применяет проверенные validateMetric метрики к хранилищу одним пакетом.
+//
+// Приращения счётчиков учитываются как вклад источника source (см. SetCounterSources).
+func (h *Handler) applyChunk(metrics []models.Metrics, source string) {
+	updates := make([]repository.MetricUpdate, len(metrics))
+	for i, m := range metrics {
+		updates[i] = repository.MetricUpdate{Type: m.MType, Name: m.ID, FloatVal: m.Value, IntVal: m.Delta}
+	}
+	repository.UpdateBatch(h.storage, updates)
+	if h.sources == nil {
+		return
+	}
+	for _, m := range metrics {
+		if m.MType == models.Counter {
+			h.sources.Add(m.ID, source, *m.Delta)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandlerUpdateBatchJSON_Stream проверяет потоковое применение батча порциями:
+// ответ тем же массивом метрик, что и без потоковой обработки, сохранение уже применённых
+// порций при ошибке с их количеством в заголовке X-Batch-Applied и разбор батча целиком,
+// если нужна проверка подписи.
+//
+// t — указатель на структуру теста.
+func TestHandlerUpdateBatchJSON_Stream(t *testing.T) {
+	const batch = `[
+		{"id":"PollCount","type":"counter","delta":1},
+		{"id":"PollCount","type":"counter","delta":2},
+		{"id":"Alloc","type":"gauge","value":1.5},
+		{"id":"PollCount","type":"counter","delta":3},
+		{"id":"Alloc","type":"gauge","value":2.5}
+	]`
+
+	tests := []struct {
+		name        string // Название теста
+		body        string // Тело запроса
+		key         string // Ключ HMAC сервера
+		wantStatus  int    // Ожидаемый код ответа
+		wantCode    string // Ожидаемый код ошибки
+		wantApplied string // Ожидаемый заголовок X-Batch-Applied
+		wantCounter int64  // Ожидаемое значение счётчика PollCount
+	}{
+		{name: "Chunks", body: batch, wantStatus: http.StatusOK, wantApplied: "5", wantCounter: 6},
+		{name: "InvalidMetricKeepsAppliedChunks", body: `[
+			{"id":"PollCount","type":"counter","delta":1},
+			{"id":"PollCount","type":"counter","delta":2},
+			{"id":"PollCount","type":"counter"}
+		]`, wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeInvalidMetric, wantApplied: "2", wantCounter: 3},
+		{name: "TruncatedKeepsAppliedChunks", body: `[
+			{"id":"PollCount","type":"counter","delta":1},
+			{"id":"PollCount","type":"counter","delta":2},
+			{"id":"PollCount","type":"counter","delta":`, wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeInvalidJSON, wantApplied: "2", wantCounter: 3},
+		{name: "Empty", body: `[]`, wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeEmptyBatch},
+		{name: "Null", body: `null`, wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeEmptyBatch},
+		{name: "NotArray", body: `{"id":"PollCount"}`, wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeInvalidJSON},
+		{name: "EmptyBody", wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeEmptyBody},
+		{name: "SignedDecodedWhole", body: batch, key: "secret", wantStatus: http.StatusOK, wantCounter: 6},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			storage := repository.NewMemStorage()
+			h := NewHandler(storage, nil)
+			h.SetBatchStreaming(2)
+			h.SetKey(tc.key)
+
+			req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.key != "" {
+				req.Header.Set("HashSHA256", (&Handler{key: tc.key}).computeHash([]byte(tc.body)))
+			}
+			rec := httptest.NewRecorder()
+			h.HandlerUpdateBatchJSON(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			require.Equal(t, tc.wantApplied, rec.Header().Get(models.BatchAppliedHeader))
+			if tc.wantCode != "" {
+				var resp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				require.Equal(t, tc.wantCode, resp.Code)
+			} else {
+				var resp []models.Metrics
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				require.Len(t, resp, 5)
+				require.Equal(t, "PollCount", resp[0].ID)
+				require.Equal(t, 2.5, *resp[4].Value)
+			}
+
+			got, _ := storage.GetCounter("PollCount")
+			require.Equal(t, tc.wantCounter, got)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+const (
+	// cardinalityDefaultTop — количество самых быстрорастущих префиксов в ответе по умолчанию.
+	cardinalityDefaultTop = 10
+	// cardinalityWindow — минимальный интервал между базовыми замерами для расчёта роста.
+	cardinalityWindow = time.Minute
+	// cardinalityPrefixSeparators — символы, отделяющие префикс от остальной части имени метрики.
+	cardinalityPrefixSeparators = "._-/:"
+)
+
+type (
+	// PrefixCardinality содержит количество уникальных метрик с общим префиксом.
+	PrefixCardinality struct {
+		Prefix string `json:"prefix"`
+		Count  int    `json:"count"`
+	}
+
+	// PrefixGrowth описывает рост количества метрик с общим префиксом относительно базового замера.
+	PrefixGrowth struct {
+		Prefix    string  `json:"prefix"`
+		Count     int     `json:"count"`
+		Delta     int     `json:"delta"`
+		PerMinute float64 `json:"per_minute"`
+	}
+
+	// CardinalityReport — ответ эндпоинта анализа кардинальности.
+	//
+	// Поля:
+	//   - Total: общее количество метрик в хранилище
+	//   - Prefixes: количество метрик по префиксам (по убыванию)
+	//   - FastestGrowing: префиксы с наибольшим ростом с момента базового замера
+	//   - BaselineAt: время базового замера, относительно которого считается рост
+	CardinalityReport struct {
+		Total          int                 `json:"total"`
+		Prefixes       []PrefixCardinality `json:"prefixes"`
+		FastestGrowing []PrefixGrowth      `json:"fastest_growing"`
+		BaselineAt     time.Time           `json:"baseline_at"`
+	}
+
+	// cardinalityTracker хранит базовый замер количества метрик по префиксам.
+	cardinalityTracker struct {
+		mu       sync.Mutex
+		baseline map[string]int
+		takenAt  time.Time
+	}
+)
+
+// metricPrefix возвращает префикс имени метрики — часть до первого разделителя.
+//
+// Если разделителей нет, префиксом считается имя целиком.
+func metricPrefix(name string) string {
+	if i := strings.IndexAny(name, cardinalityPrefixSeparators); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// growth сравнивает текущие значения с базовым замером и возвращает рост по префиксам.
+//
+// Базовый замер обновляется, если он старше cardinalityWindow, чтобы частые
+// запросы не сводили рост к нулю.
+func (t *cardinalityTracker) growth(counts map[string]int, now time.Time) ([]PrefixGrowth, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.baseline == nil {
+		t.baseline = counts
+		t.takenAt = now
+		return []PrefixGrowth{}, t.takenAt
+	}
+
+	elapsed := now.Sub(t.takenAt).Minutes()
+	result := make([]PrefixGrowth, 0, len(counts))
+	for prefix, count := range counts {
+		delta := count - t.baseline[prefix]
+		if delta <= 0 {
+			continue
+		}
+		g := PrefixGrowth{Prefix: prefix, Count: count, Delta: delta}
+		if elapsed > 0 {
+			g.PerMinute = float64(delta) / elapsed
+		}
+		result = append(result, g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Delta != result[j].Delta {
+			return result[i].Delta > result[j].Delta
+		}
+		return result[i].Prefix < result[j].Prefix
+	})
+
+	baselineAt := t.takenAt
+	if now.Sub(t.takenAt) >= cardinalityWindow {
+		t.baseline = counts
+		t.takenAt = now
+	}
+	return result, baselineAt
+}
+
+// HandleCardinality возвращает количество метрик по префиксам и самые быстрорастущие префиксы.
+//
+// Позволяет обнаружить агента, начавшего отправлять неограниченное число уникальных
+// имён метрик, до исчерпания памяти. Параметр запроса top задаёт количество
+// быстрорастущих префиксов в ответе (по умолчанию 10).
+//
+// @Summary Анализ кардинальности метрик
+// @Description Возвращает количество метрик по префиксам и top-N самых быстрорастущих префиксов
+// @Tags Admin
+// @Produce json
+// @Param top query int false "Количество быстрорастущих префиксов"
+// @Success 200 {object} CardinalityReport "Отчёт о кардинальности"
+// @Failure 400 {object} models.ErrorResponse "Некорректный параметр top"
+// @Router /api/v1/cardinality [get]
+func (h *Handler) HandleCardinality(w http.ResponseWriter, r *http.Request) {
+	top := cardinalityDefaultTop
+	if v := r.URL.Query().Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "invalid top", map[string]string{"top": v})
+			return
+		}
+		top = n
+	}
+
+	metrics := h.storage.GetAll()
+	counts := make(map[string]int)
+	for _, m := range metrics {
+		counts[metricPrefix(m.Name)]++
+	}
+
+	prefixes := make([]PrefixCardinality, 0, len(counts))
+	for prefix, count := range counts {
+		prefixes = append(prefixes, PrefixCardinality{Prefix: prefix, Count: count})
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if prefixes[i].Count != prefixes[j].Count {
+			return prefixes[i].Count > prefixes[j].Count
+		}
+		return prefixes[i].Prefix < prefixes[j].Prefix
+	})
+
+	growing, baselineAt := h.cardinality.growth(counts, time.Now())
+	if len(growing) > top {
+		growing = growing[:top]
+	}
+
+	report := CardinalityReport{
+		Total:          len(metrics),
+		Prefixes:       prefixes,
+		FastestGrowing: growing,
+		BaselineAt:     baselineAt,
+	}
+	if err := h.writeJSONWithHash(w, report); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
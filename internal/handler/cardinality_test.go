@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMetricPrefix проверяет выделение префикса из имени метрики.
+//
+// t — указатель на структуру теста.
+func TestMetricPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Alloc", "Alloc"},
+		{"http.requests", "http"},
+		{"cpu_user", "cpu"},
+		{"disk/sda", "disk"},
+		{"_hidden", "_hidden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, metricPrefix(tt.name))
+		})
+	}
+}
+
+// TestHandleCardinality проверяет группировку по префиксам и расчёт роста.
+//
+// t — указатель на структуру теста.
+func TestHandleCardinality(t *testing.T) {
+	storage := repository.NewMemStorage()
+	storage.SetGauge("cpu.user", 1)
+	storage.SetGauge("cpu.system", 1)
+	storage.AddCounter("req.total", 1)
+	h := NewHandler(storage, nil)
+
+	get := func(query string) (int, CardinalityReport) {
+		rec := httptest.NewRecorder()
+		h.HandleCardinality(rec, httptest.NewRequest(http.MethodGet, "/api/v1/cardinality"+query, nil))
+		var report CardinalityReport
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		}
+		return rec.Code, report
+	}
+
+	code, report := get("")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 3, report.Total)
+	require.Equal(t, []PrefixCardinality{{Prefix: "cpu", Count: 2}, {Prefix: "req", Count: 1}}, report.Prefixes)
+	require.Empty(t, report.FastestGrowing)
+
+	h.cardinality.takenAt = h.cardinality.takenAt.Add(-2 * time.Minute)
+	for i := 0; i < 5; i++ {
+		storage.SetGauge("leak."+strconv.Itoa(i), 1)
+	}
+	storage.SetGauge("cpu.idle", 1)
+
+	code, report = get("?top=1")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 9, report.Total)
+	require.Len(t, report.FastestGrowing, 1)
+	require.Equal(t, "leak", report.FastestGrowing[0].Prefix)
+	require.Equal(t, 5, report.FastestGrowing[0].Delta)
+	require.Greater(t, report.FastestGrowing[0].PerMinute, 0.0)
+
+	code, _ = get("?top=abc")
+	require.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	// counterSourcesView — суммарное значение счётчика и вклады источников.
+	//
+	// Unattributed — часть значения, не отнесённая ни к одному источнику: принятая до запуска
+	// сервера, восстановленная из снимка или переданная без учёта источников.
+	counterSourcesView struct {
+		Name         string                     `json:"name"`
+		Value        int64                      `json:"value"`
+		Unattributed int64                      `json:"unattributed"`
+		Sources      []repository.CounterSource `json:"sources"`
+	}
+
+	// counterSourceAdjust — поправка вклада источника в счётчик.
+	counterSourceAdjust struct {
+		Delta *int64 `json:"delta"`
+	}
+)
+
+// SetCounterSources задаёт учёт вкладов источников в счётчики.
+//
+// Источником считается идентификатор агента из заголовка X-Agent-ID, подтверждённый подписью
+// персональным ключом (см. verifyAgentHash), а без него — IP-адрес клиента.
+func (h *Handler) SetCounterSources(sources *repository.CounterSources) {
+	h.sources = sources
+}
+
+// counterSource возвращает источник приращений счётчиков запроса r, подпись которого уже проверена.
+func (h *Handler) counterSource(r *http.Request) string {
+	if id := r.Header.Get(models.AgentIDHeader); id != "" {
+		return id
+	}
+	return h.getClientIP(r)
+}
+
+// counterSourcesState возвращает суммарное значение счётчика name и вклады источников.
+func (h *Handler) counterSourcesState(name string) (counterSourcesView, bool) {
+	value, ok := h.storage.GetCounter(name)
+	if !ok {
+		return counterSourcesView{}, false
+	}
+	view := counterSourcesView{Name: name, Value: value, Unattributed: value, Sources: h.sources.Sources(name)}
+	for _, s := range view.Sources {
+		view.Unattributed -= s.Value
+	}
+	return view, true
+}
+
+// HandleCounterSources возвращает суммарное значение счётчика и вклад каждого источника.
+//
+// @Summary Получить вклады источников в счётчик
+// @Description Возвращает суммарное значение счётчика, вклад каждого агента и не отнесённый к источникам остаток
+// @Tags Admin
+// @Produce json
+// @Param name path string true "Имя счётчика"
+// @Success 200 {object} counterSourcesView "Значение и вклады источников"
+// @Failure 400 {object} models.ErrorResponse "Учёт источников отключён"
+// @Failure 404 {object} models.ErrorResponse "Счётчик не найден"
+// @Router /admin/counters/{name}/sources [get]
+func (h *Handler) HandleCounterSources(w http.ResponseWriter, r *http.Request) {
+	if h.sources == nil {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "counter sources are not tracked")
+		return
+	}
+	name := chi.URLParam(r, "name")
+	view, ok := h.counterSourcesState(name)
+	if !ok {
+		WriteErrorDetails(w, r, http.StatusNotFound, models.ErrCodeMetricNotFound, "metric not found", map[string]string{"id": name})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.writeJSONWithHash(w, view); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// HandleCounterSourceAdjust исправляет вклад источника в счётчик на delta; та же поправка
+// применяется к суммарному значению, вклад остальных источников не меняется.
+//
+// @Summary Исправить вклад источника в счётчик
+// @Description Прибавляет delta (обычно отрицательную) к вкладу источника и к суммарному значению счётчика
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Имя счётчика"
+// @Param source path string true "Источник: идентификатор агента или IP-адрес"
+// @Param adjust body counterSourceAdjust true "Поправка"
+// @Success 200 {object} counterSourcesView "Значение и вклады источников после поправки"
+// @Failure 400 {object} models.ErrorResponse "Некорректный запрос или переполнение счётчика"
+// @Failure 404 {object} models.ErrorResponse "Источник не передавал приращений счётчика"
+// @Router /admin/counters/{name}/sources/{source} [patch]
+func (h *Handler) HandleCounterSourceAdjust(w http.ResponseWriter, r *http.Request) {
+	if h.sources == nil {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "counter sources are not tracked")
+		return
+	}
+	var req counterSourceAdjust
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeInvalidJSON, "invalid json")
+		return
+	}
+	if req.Delta == nil {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "delta is required")
+		return
+	}
+	name, source := chi.URLParam(r, "name"), chi.URLParam(r, "source")
+	if !h.correctCounter(w, r, name, source, h.sources.Adjust(h.storage, name, source, *req.Delta)) {
+		return
+	}
+	h.auditCounterCorrection(r, models.AuditOpUpdate, name, source, *req.Delta)
+	h.writeCounterSources(w, name)
+}
+
+// HandleCounterSourceExclude вычитает весь вклад источника из счётчика, не сбрасывая вклад остальных.
+//
+// @Summary Исключить вклад источника из счётчика
+// @Description Вычитает сумму приращений источника из счётчика и удаляет источник из учёта
+// @Tags Admin
+// @Produce json
+// @Param name path string true "Имя счётчика"
+// @Param source path string true "Источник: идентификатор агента или IP-адрес"
+// @Success 200 {object} counterSourcesView "Значение и вклады источников после исключения"
+// @Failure 400 {object} models.ErrorResponse "Учёт источников отключён или переполнение счётчика"
+// @Failure 404 {object} models.ErrorResponse "Источник не передавал приращений счётчика"
+// @Router /admin/counters/{name}/sources/{source} [delete]
+func (h *Handler) HandleCounterSourceExclude(w http.ResponseWriter, r *http.Request) {
+	if h.sources == nil {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "counter sources are not tracked")
+		return
+	}
+	name, source := chi.URLParam(r, "name"), chi.URLParam(r, "source")
+	removed, err := h.sources.Exclude(h.storage, name, source)
+	if !h.correctCounter(w, r, name, source, err) {
+		return
+	}
+	h.auditCounterCorrection(r, models.AuditOpDelete, name, source, -removed)
+	h.writeCounterSources(w, name)
+}
+
+// correctCounter сохраняет исправленный счётчик или отвечает ошибкой исправления err.
+//
+// Возвращает false, если ответ с ошибкой уже записан.
+func (h *Handler) correctCounter(w http.ResponseWriter, r *http.Request, name, source string, err error) bool {
+	switch {
+	case errors.Is(err, repository.ErrUnknownCounterSource):
+		WriteErrorDetails(w, r, http.StatusNotFound, models.ErrCodeNotFound, err.Error(), map[string]string{"id": name, "source": source})
+		return false
+	case errors.Is(err, repository.ErrCounterOverflow):
+		writeCounterOverflow(w, r, name)
+		return false
+	case err != nil:
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeInternal, err.Error())
+		return false
+	}
+	if err := h.fanOut.Sync(r.Context()); err != nil {
+		log.Printf("Failed to save metrics: %v", err)
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeStorageFailed, "failed to save metrics")
+		return false
+	}
+	log.Printf("Counter %s corrected for source %s via %s", name, source, r.URL.Path)
+	return true
+}
+
+// writeCounterSources отвечает текущим значением счётчика name и вкладами источников.
+func (h *Handler) writeCounterSources(w http.ResponseWriter, name string) {
+	view, _ := h.counterSourcesState(name)
+	if err := h.writeJSONWithHash(w, view); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// auditCounterCorrection отправляет событие аудита о поправке delta вклада источника source
+// в счётчик name: op — models.AuditOpUpdate для поправки и models.AuditOpDelete для исключения.
+func (h *Handler) auditCounterCorrection(r *http.Request, op, name, source string, delta int64) {
+	if h.auditManager == nil {
+		return
+	}
+	event := h.newAuditEvent(r)
+	event.Metrics = []string{name + "@" + source + "=" + strconv.FormatInt(delta, 10)}
+	event.Event = models.AuditCounterCorrection
+	event.Operation = op
+	h.auditManager.Notify(event)
+}
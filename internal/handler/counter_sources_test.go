@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandler_CounterSources проверяет учёт вкладов агентов в счётчик, исправление вклада
+// одного агента и его исключение без сброса вклада остальных.
+//
+// t — указатель на структуру теста.
+func TestHandler_CounterSources(t *testing.T) {
+	storage := repository.NewMemStorage()
+	storage.AddCounter("c", 5) // восстановлено из снимка, источник неизвестен
+	registry, err := repository.NewAgentRegistry("", []string{"ta", "tb"})
+	require.NoError(t, err)
+	agentA, keyA, err := registry.Enroll("ta", "a", "10.0.0.1")
+	require.NoError(t, err)
+	agentB, keyB, err := registry.Enroll("tb", "b", "10.0.0.2")
+	require.NoError(t, err)
+	keys := map[string]string{agentA.ID: keyA, agentB.ID: keyB}
+	h := NewHandler(storage, nil)
+	h.SetAgentRegistry(registry)
+	h.SetCounterSources(repository.NewCounterSources(false))
+	r := chi.NewRouter()
+	r.Post("/updates/", h.HandlerUpdateBatchJSON)
+	r.Post("/update/{type}/{name}/{value}", h.HandleUpdate)
+	r.Get("/admin/counters/{name}/sources", h.HandleCounterSources)
+	r.Patch("/admin/counters/{name}/sources/{source}", h.HandleCounterSourceAdjust)
+	r.Delete("/admin/counters/{name}/sources/{source}", h.HandleCounterSourceExclude)
+
+	do := func(method, path, agentID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "10.0.0.9:1234"
+		if agentID != "" {
+			mac := hmac.New(sha256.New, []byte(keys[agentID]))
+			mac.Write([]byte(body))
+			req.Header.Set(models.AgentIDHeader, agentID)
+			req.Header.Set("HashSHA256", hex.EncodeToString(mac.Sum(nil)))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	view := func(rec *httptest.ResponseRecorder) counterSourcesView {
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var v counterSourcesView
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &v))
+		return v
+	}
+
+	sources := func(b int64) []repository.CounterSource {
+		out := []repository.CounterSource{{Source: "10.0.0.9", Value: 3}, {Source: agentA.ID, Value: 10}}
+		if b != 0 {
+			out = append(out, repository.CounterSource{Source: agentB.ID, Value: b})
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Source < out[j].Source })
+		return out
+	}
+
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/updates/", agentA.ID, `[{"id":"c","type":"counter","delta":10}]`).Code)
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/updates/", agentB.ID, `[{"id":"c","type":"counter","delta":100},{"id":"c","type":"counter","delta":100}]`).Code)
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/update/counter/c/3", "", "").Code)
+
+	require.Equal(t, counterSourcesView{Name: "c", Value: 218, Unattributed: 5, Sources: sources(200)},
+		view(do(http.MethodGet, "/admin/counters/c/sources", "", "")))
+
+	// Агент B повторно отправил 100: исправляем только его вклад.
+	got := view(do(http.MethodPatch, "/admin/counters/c/sources/"+agentB.ID, "", `{"delta":-100}`))
+	require.Equal(t, int64(118), got.Value)
+	require.Equal(t, sources(100), got.Sources)
+
+	got = view(do(http.MethodDelete, "/admin/counters/c/sources/"+agentB.ID, "", ""))
+	require.Equal(t, int64(18), got.Value)
+	require.Equal(t, int64(5), got.Unattributed)
+	require.Equal(t, sources(0), got.Sources)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{"unknown counter", http.MethodGet, "/admin/counters/missing/sources", "", http.StatusNotFound, models.ErrCodeMetricNotFound},
+		{"unknown source", http.MethodDelete, "/admin/counters/c/sources/" + agentB.ID, "", http.StatusNotFound, models.ErrCodeNotFound},
+		{"missing delta", http.MethodPatch, "/admin/counters/c/sources/" + agentA.ID, `{}`, http.StatusBadRequest, models.ErrCodeBadRequest},
+		{"invalid json", http.MethodPatch, "/admin/counters/c/sources/" + agentA.ID, `{`, http.StatusBadRequest, models.ErrCodeInvalidJSON},
+		{"overflow", http.MethodPatch, "/admin/counters/c/sources/" + agentA.ID, `{"delta":9223372036854775807}`, http.StatusBadRequest, models.ErrCodeCounterOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(tt.method, tt.path, "", tt.body)
+			require.Equal(t, tt.wantCode, rec.Code)
+			var resp models.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.Equal(t, tt.wantErr, resp.Code)
+		})
+	}
+	value, _ := storage.GetCounter("c")
+	require.Equal(t, int64(18), value)
+}
+
+// TestHandler_CounterSourcesDisabled проверяет ответ без учёта источников.
+//
+// t — указатель на структуру теста.
+func TestHandler_CounterSourcesDisabled(t *testing.T) {
+	h := NewHandler(repository.NewMemStorage(), nil)
+	rec := httptest.NewRecorder()
+	h.HandleCounterSources(rec, httptest.NewRequest(http.MethodGet, "/admin/counters/c/sources", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandlers_ExtremeCounterValues проверяет точную обработку счётчиков на границах int64
+// и явную ошибку counter_overflow при переполнении.
+func TestHandlers_ExtremeCounterValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		initial    int64
+		path       string
+		body       string
+		wantStatus int
+		wantValue  int64
+	}{
+		{"max delta", 0, "/update", `{"id":"c","type":"counter","delta":9223372036854775807}`, http.StatusOK, math.MaxInt64},
+		{"min delta", 0, "/update", `{"id":"c","type":"counter","delta":-9223372036854775808}`, http.StatusOK, math.MinInt64},
+		{"delta above 2^53", 0, "/update", `{"id":"c","type":"counter","delta":9007199254740993}`, http.StatusOK, 9007199254740993},
+		{"delta out of range", 0, "/update", `{"id":"c","type":"counter","delta":9223372036854775808}`, http.StatusBadRequest, 0},
+		{"overflow with stored value", math.MaxInt64, "/update", `{"id":"c","type":"counter","delta":1}`, http.StatusBadRequest, math.MaxInt64},
+		{"overflow within batch", 0, "/updates/", `[{"id":"c","type":"counter","delta":9223372036854775807},{"id":"c","type":"counter","delta":1}]`, http.StatusBadRequest, 0},
+		{"batch up to max", 1, "/updates/", `[{"id":"c","type":"counter","delta":9223372036854775806}]`, http.StatusOK, math.MaxInt64},
+		{"url delta out of range", 0, "/update/counter/c/9223372036854775808", "", http.StatusBadRequest, 0},
+		{"url overflow with stored value", math.MaxInt64, "/update/counter/c/1", "", http.StatusBadRequest, math.MaxInt64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := repository.NewMemStorage()
+			if tt.initial != 0 {
+				storage.AddCounter("c", tt.initial)
+			}
+			h := NewHandler(storage, nil)
+			r := chi.NewRouter()
+			r.Post("/update", h.HandleUpdateJSON)
+			r.Post("/updates/", h.HandlerUpdateBatchJSON)
+			r.Post("/update/{type}/{name}/{value}", h.HandleUpdate)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus != http.StatusOK {
+				var resp models.ErrorResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				require.Equal(t, models.ErrCodeCounterOverflow, resp.Code)
+			}
+			got, _ := storage.GetCounter("c")
+			require.Equal(t, tt.wantValue, got)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+)
+
+// deadLetterStore — менеджер аудита, сохраняющий недоставленные события (см. repository.AuditManager).
+type deadLetterStore interface {
+	DeadLetters() ([]repository.DeadLetterRecord, error)
+	ReplayDeadLetters() (replayed, remaining int, err error)
+}
+
+// replayResult — итог повторной доставки недоставленных событий.
+type replayResult struct {
+	Replayed  int `json:"replayed"`
+	Remaining int `json:"remaining"`
+}
+
+// HandleDeadLetters возвращает события аудита, не доставленные после всех попыток.
+//
+// Если очередь недоставленных событий не настроена, возвращается пустой список.
+//
+// @Summary Получить недоставленные события
+// @Description Возвращает события аудита, не доставленные наблюдателям, с причиной отказа и числом попыток
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} repository.DeadLetterRecord "Недоставленные события"
+// @Failure 500 {object} models.ErrorResponse "Не удалось прочитать очередь"
+// @Router /admin/dead-letters [get]
+func (h *Handler) HandleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	records := []repository.DeadLetterRecord{}
+	if s, ok := h.auditManager.(deadLetterStore); ok {
+		list, err := s.DeadLetters()
+		if err != nil {
+			log.Printf("Failed to read dead letters: %v", err)
+			WriteError(w, r, http.StatusInternalServerError, models.ErrCodeInternal, "failed to read dead letters")
+			return
+		}
+		records = append(records, list...)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.writeJSONWithHash(w, records); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// HandleDeadLettersReplay повторно доставляет недоставленные события аудита.
+//
+// Доставленные события удаляются из очереди, остальные остаются с обновлённой причиной отказа.
+//
+// @Summary Повторить доставку недоставленных событий
+// @Description Повторно отправляет события из очереди наблюдателям аудита и возвращает число доставленных и оставшихся
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} replayResult "Итог повторной доставки"
+// @Failure 400 {object} models.ErrorResponse "Аудит или очередь недоставленных событий не настроены"
+// @Failure 500 {object} models.ErrorResponse "Не удалось обновить очередь"
+// @Router /admin/dead-letters/replay [post]
+func (h *Handler) HandleDeadLettersReplay(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.auditManager.(deadLetterStore)
+	if !ok {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "audit is not configured")
+		return
+	}
+	replayed, remaining, err := s.ReplayDeadLetters()
+	if errors.Is(err, repository.ErrNoDeadLetterQueue) {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "dead letter queue is not configured")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to replay dead letters: %v", err)
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeInternal, "failed to replay dead letters")
+		return
+	}
+	log.Printf("Dead letters replayed=%d remaining=%d via %s", replayed, remaining, r.URL.Path)
+	if err := h.writeJSONWithHash(w, replayResult{Replayed: replayed, Remaining: remaining}); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyObserver — наблюдатель аудита, отказывающий в доставке, пока задан err.
+type flakyObserver struct {
+	err error
+}
+
+func (o *flakyObserver) OnAuditEvent(models.AuditEvent) error { return o.err }
+func (o *flakyObserver) String() string                       { return "flaky" }
+
+// TestHandler_DeadLetters проверяет просмотр и повторную доставку недоставленных событий аудита.
+//
+// t — указатель на структуру теста.
+func TestHandler_DeadLetters(t *testing.T) {
+	queue, err := repository.NewDeadLetterQueue(filepath.Join(t.TempDir(), "dead.jsonl"))
+	require.NoError(t, err)
+	obs := &flakyObserver{err: errors.New("connection refused")}
+	mgr := repository.NewAuditManager()
+	mgr.Attach(obs)
+	mgr.SetDeadLetter(queue)
+	h := NewHandler(repository.NewMemStorage(), nil)
+	h.SetAuditManager(mgr)
+
+	mgr.Notify(models.AuditEvent{ID: "e1"})
+
+	w := httptest.NewRecorder()
+	h.HandleDeadLetters(w, httptest.NewRequest(http.MethodGet, "/admin/dead-letters", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var records []repository.DeadLetterRecord
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &records))
+	require.Len(t, records, 1)
+	require.Equal(t, "flaky", records[0].Target)
+	require.Equal(t, "connection refused", records[0].Reason)
+
+	obs.err = nil
+	w = httptest.NewRecorder()
+	h.HandleDeadLettersReplay(w, httptest.NewRequest(http.MethodPost, "/admin/dead-letters/replay", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var got replayResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(t, replayResult{Replayed: 1, Remaining: 0}, got)
+}
+
+// TestHandler_DeadLettersUnavailable проверяет ответы без аудита и без очереди недоставленных событий.
+//
+// t — указатель на структуру теста.
+func TestHandler_DeadLettersUnavailable(t *testing.T) {
+	h := NewHandler(repository.NewMemStorage(), nil)
+
+	w := httptest.NewRecorder()
+	h.HandleDeadLetters(w, httptest.NewRequest(http.MethodGet, "/admin/dead-letters", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[]`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.HandleDeadLettersReplay(w, httptest.NewRequest(http.MethodPost, "/admin/dead-letters/replay", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	h.SetAuditManager(repository.NewAuditManager())
+	w = httptest.NewRecorder()
+	h.HandleDeadLettersReplay(w, httptest.NewRequest(http.MethodPost, "/admin/dead-letters/replay", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "dead letter queue is not configured")
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/version"
+)
+
+// diagnosticsLogTail — максимальный объём хвоста журнала, включаемого в диагностический архив.
+const diagnosticsLogTail = 1 << 20
+
+// redactedValue — значение, которым заменяются секретные параметры конфигурации.
+const redactedValue = "[REDACTED]"
+
+// sensitiveConfigKeys — параметры конфигурации, значения которых не попадают в диагностический архив.
+var sensitiveConfigKeys = map[string]struct{}{
+	"key":                  {},
+	"database_dsn":         {},
+	"crypto_key":           {},
+	"audit_url":            {},
+	"s3.secret_access_key": {},
+	"enroll_tokens":        {},
+	"auth.jwt_secret":      {},
+	"admin_token":          {},
+}
+
+// storageStats содержит сводную статистику хранилища для диагностического архива.
+type storageStats struct {
+	Gauges       int                 `json:"gauges"`
+	Counters     int                 `json:"counters"`
+	Generation   uint64              `json:"generation"`
+	IDCollisions map[string][]string `json:"id_collisions,omitempty"` // Исходные написания имён, сведённых нормализацией к одному
+}
+
+// collisionReporter реализуется хранилищами, нормализующими имена метрик (см. repository.NormalizingStorage).
+type collisionReporter interface {
+	Collisions() map[string][]string
+}
+
+// SetCollisionReporter задаёт источник коллизий нормализованных имён метрик,
+// которые выводятся в /status и диагностическом архиве.
+func (h *Handler) SetCollisionReporter(r collisionReporter) {
+	h.collisions = r
+}
+
+// SetDiagnostics задаёт данные для диагностического архива.
+//
+// effectiveConfig — итоговая конфигурация сервера (ключи в формате JSON-конфига).
+// logFile — путь к файлу журнала, хвост которого включается в архив.
+func (h *Handler) SetDiagnostics(effectiveConfig map[string]string, logFile string) {
+	h.diagConfig = effectiveConfig
+	h.diagLogFile = logFile
+}
+
+// redactConfig возвращает копию конфигурации со скрытыми значениями секретных параметров.
+func redactConfig(cfg map[string]string) map[string]string {
+	out := make(map[string]string, len(cfg))
+	for k, v := range cfg {
+		if _, ok := sensitiveConfigKeys[k]; ok && v != "" {
+			v = redactedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// HandleConfig возвращает итоговую конфигурацию сервера без секретов.
+//
+// @Summary Получить конфигурацию сервера
+// @Description Возвращает итоговую конфигурацию (ключи в формате JSON-конфига); значения секретов скрыты
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]string "Итоговая конфигурация"
+// @Router /admin/config [get]
+func (h *Handler) HandleConfig(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.writeJSONWithHash(w, redactConfig(h.diagConfig)); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// HandleDiagnostics формирует zip-архив с диагностической информацией о сервере.
+//
+// Архив содержит итоговую конфигурацию (без секретов), хвост журнала,
+// статистику хранилища, дамп горутин и информацию о сборке.
+//
+// @Summary Получить диагностический архив
+// @Description Возвращает zip-архив с конфигурацией, журналом, статистикой хранилища, дампом горутин и информацией о сборке
+// @Tags Admin
+// @Produce application/zip
+// @Success 200 {file} file "Диагностический архив"
+// @Router /admin/diagnostics [get]
+func (h *Handler) HandleDiagnostics(w http.ResponseWriter, _ *http.Request) {
+	filename := fmt.Sprintf("diagnostics-%s.zip", time.Now().UTC().Format("20060102T150405"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(w)
+	entries := []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{"config.json", h.writeDiagConfig},
+		{"storage.json", h.writeDiagStorage},
+		{"build.txt", writeDiagBuild},
+		{"goroutines.txt", writeDiagGoroutines},
+		{"app.log", h.writeDiagLog},
+	}
+	for _, e := range entries {
+		f, err := zw.Create(e.name)
+		if err != nil {
+			log.Printf("Failed to create diagnostics entry %s: %v", e.name, err)
+			return
+		}
+		if err := e.write(f); err != nil {
+			log.Printf("Failed to write diagnostics entry %s: %v", e.name, err)
+			_, _ = fmt.Fprintf(f, "error: %v\n", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("Failed to finalize diagnostics archive: %v", err)
+	}
+}
+
+// writeDiagConfig записывает итоговую конфигурацию без секретов.
+func (h *Handler) writeDiagConfig(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(redactConfig(h.diagConfig))
+}
+
+// collectStorageStats подсчитывает метрики хранилища по типам.
+func (h *Handler) collectStorageStats() storageStats {
+	var stats storageStats
+	for _, m := range h.storage.GetAll() {
+		switch m.Type {
+		case "gauge":
+			stats.Gauges++
+		case "counter":
+			stats.Counters++
+		}
+	}
+	stats.Generation = h.storage.Generation()
+	if h.collisions != nil {
+		if c := h.collisions.Collisions(); len(c) > 0 {
+			stats.IDCollisions = c
+		}
+	}
+	return stats
+}
+
+// writeDiagStorage записывает статистику хранилища.
+func (h *Handler) writeDiagStorage(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(h.collectStorageStats())
+}
+
+// writeDiagBuild записывает информацию о сборке и среде выполнения.
+func writeDiagBuild(w io.Writer) error {
+	info := version.Get()
+	_, _ = fmt.Fprint(w, info.String())
+	_, _ = fmt.Fprintf(w, "Go version: %s\n", info.GoVersion)
+	_, _ = fmt.Fprintf(w, "OS/Arch: %s\n", info.Platform)
+	_, _ = fmt.Fprintf(w, "NumCPU: %d\n", runtime.NumCPU())
+	_, _ = fmt.Fprintf(w, "NumGoroutine: %d\n", runtime.NumGoroutine())
+	if info, ok := debug.ReadBuildInfo(); ok {
+		_, _ = fmt.Fprintf(w, "\n%s", info.String())
+	}
+	return nil
+}
+
+// writeDiagGoroutines записывает дамп стеков всех горутин.
+func writeDiagGoroutines(w io.Writer) error {
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// writeDiagLog записывает хвост файла журнала (не более diagnosticsLogTail байт).
+func (h *Handler) writeDiagLog(w io.Writer) error {
+	if h.diagLogFile == "" {
+		return nil
+	}
+	f, err := os.Open(h.diagLogFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if offset := info.Size() - diagnosticsLogTail; offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandleDiagnostics проверяет состав диагностического архива и скрытие секретов в конфигурации.
+//
+// t — указатель на структуру теста.
+func TestHandleDiagnostics(t *testing.T) {
+	storage := repository.NewMemStorage()
+	storage.SetGauge("g", 1)
+	storage.AddCounter("c", 1)
+
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(logFile, []byte("log line\n"), 0644))
+
+	h := NewHandler(storage, nil)
+	h.SetDiagnostics(map[string]string{
+		"address": "localhost:8080",
+		"key":     "secret",
+	}, logFile)
+
+	rec := httptest.NewRecorder()
+	h.HandleDiagnostics(rec, httptest.NewRequest(http.MethodGet, "/admin/diagnostics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		_ = rc.Close()
+		files[f.Name] = data
+	}
+
+	for _, name := range []string{"config.json", "storage.json", "build.txt", "goroutines.txt", "app.log"} {
+		require.Contains(t, files, name)
+	}
+
+	var cfg map[string]string
+	require.NoError(t, json.Unmarshal(files["config.json"], &cfg))
+	require.Equal(t, "localhost:8080", cfg["address"])
+	require.Equal(t, redactedValue, cfg["key"])
+
+	var stats storageStats
+	require.NoError(t, json.Unmarshal(files["storage.json"], &stats))
+	require.Equal(t, 1, stats.Gauges)
+	require.Equal(t, 1, stats.Counters)
+
+	require.Equal(t, "log line\n", string(files["app.log"]))
+}
+
+// TestHandleStatus_IDCollisions проверяет, что коллизии нормализованных имён выводятся в /status
+// и в storage.json диагностического архива.
+//
+// t — указатель на структуру теста.
+func TestHandleStatus_IDCollisions(t *testing.T) {
+	storage := repository.NewNormalizingStorage(repository.NewMemStorage())
+	storage.SetGauge("Alloc", 1)
+	storage.SetGauge("alloc", 2)
+	storage.SetGauge("Frees", 3)
+
+	h := NewHandler(storage, nil)
+	h.SetCollisionReporter(storage)
+
+	rec := httptest.NewRecorder()
+	h.HandleStatus(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var status serverStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	require.Equal(t, 2, status.Storage.Gauges)
+	require.Equal(t, map[string][]string{"alloc": {"Alloc", "alloc"}}, status.Storage.IDCollisions)
+
+	var buf bytes.Buffer
+	require.NoError(t, h.writeDiagStorage(&buf))
+	require.Contains(t, buf.String(), `"id_collisions"`)
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/RoGogDBD/metric-alerter/internal/compression"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxDecompressedBody — максимальный размер батча после распаковки словарём.
+const maxDecompressedBody = 32 << 20
+
+// plainCodec распаковывает батчи zstd без словаря (без заголовка compression.IDHeader).
+var plainCodec = compression.NewCodec(nil)
+
+// SetDictionaries задаёт реестр словарей сжатия; nil отключает приём батчей, сжатых со словарём.
+func (h *Handler) SetDictionaries(store *compression.Store) {
+	h.dictionaries = store
+}
+
+// HandleRegisterDictionary регистрирует словарь сжатия батчей агента.
+//
+// Тело — сам словарь (не больше compression.MaxSize байт); идентификатор в пути должен
+// совпадать с compression.ID тела.
+//
+// @Summary Зарегистрировать словарь сжатия
+// @Description Сохраняет словарь, с которым агент сжимает последующие батчи (Content-Encoding: zstd)
+// @Tags Metrics
+// @Accept application/octet-stream
+// @Param id path string true "Идентификатор словаря"
+// @Success 204 "Словарь зарегистрирован"
+// @Failure 400 {object} models.ErrorResponse "Некорректный словарь"
+// @Failure 404 {object} models.ErrorResponse "Словари не поддерживаются"
+// @Router /api/v1/dictionaries/{id} [put]
+func (h *Handler) HandleRegisterDictionary(w http.ResponseWriter, r *http.Request) {
+	if h.dictionaries == nil {
+		WriteError(w, r, http.StatusNotFound, models.ErrCodeNotFound, "compression dictionaries are disabled")
+		return
+	}
+	if !h.isTrustedAgentRequest(r) {
+		h.AuditRejection(r, models.AuditSubnetDenied)
+		WriteError(w, r, http.StatusForbidden, models.ErrCodeForbidden, "forbidden")
+		return
+	}
+	dict, err := io.ReadAll(io.LimitReader(r.Body, compression.MaxSize+1))
+	if err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	if len(dict) == 0 || len(dict) > compression.MaxSize {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "invalid dictionary size")
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if compression.ID(dict) != id {
+		WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "dictionary id mismatch", map[string]string{"id": id})
+		return
+	}
+	h.dictionaries.Put(dict)
+	log.Printf("Compression dictionary %s registered (%d bytes)", id, len(dict))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decompressWithDictionary распаковывает тело батча, сжатое zstd, и убирает
+// заголовок Content-Encoding, чтобы тело дальше разбиралось как обычный JSON.
+// Без заголовка compression.IDHeader тело распаковывается без словаря.
+//
+// При ошибке отвечает клиенту и возвращает false.
+func (h *Handler) decompressWithDictionary(w http.ResponseWriter, r *http.Request, body []byte) ([]byte, bool) {
+	codec := plainCodec
+	id := r.Header.Get(compression.IDHeader)
+	ok := id == ""
+	if !ok && h.dictionaries != nil {
+		codec, ok = h.dictionaries.Get(id)
+	}
+	if !ok {
+		WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeUnknownDictionary, "unknown compression dictionary", map[string]string{"id": id})
+		return nil, false
+	}
+	plain, err := codec.Decompress(body, maxDecompressedBody)
+	if err != nil {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "failed to decompress body")
+		return nil, false
+	}
+	r.Header.Del("Content-Encoding")
+	return plain, true
+}
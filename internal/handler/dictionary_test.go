@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/compression"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// registerDictionary вызывает HandleRegisterDictionary с идентификатором id в пути.
+func registerDictionary(h *Handler, id string, dict []byte) *httptest.ResponseRecorder {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	r := httptest.NewRequest(http.MethodPut, compression.DictionaryPath+id, bytes.NewReader(dict))
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	h.HandleRegisterDictionary(w, r)
+	return w
+}
+
+// TestHandler_Dictionary проверяет регистрацию словаря и приём батча, сжатого с ним.
+//
+// t — указатель на структуру теста.
+func TestHandler_Dictionary(t *testing.T) {
+	storage := repository.NewMemStorage()
+	h := NewHandler(storage, nil)
+	h.SetDictionaries(compression.NewStore(4))
+
+	dict := compression.Train([]byte(`[{"id":"Alloc","type":"gauge","value":1}]`))
+	id := compression.ID(dict)
+
+	w := registerDictionary(h, "0000000000000000", dict)
+	require.Equal(t, http.StatusBadRequest, w.Code, "id mismatch")
+	w = registerDictionary(h, id, dict)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	send := func(dictID string) *httptest.ResponseRecorder {
+		body, err := compression.NewCodec(dict).Compress([]byte(`[{"id":"Alloc","type":"gauge","value":2}]`))
+		require.NoError(t, err)
+		r := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body))
+		r.Header.Set("Content-Encoding", compression.Encoding)
+		r.Header.Set(compression.IDHeader, dictID)
+		w := httptest.NewRecorder()
+		h.HandlerUpdateBatchJSON(w, r)
+		return w
+	}
+
+	w = send("unknown")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var resp models.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, models.ErrCodeUnknownDictionary, resp.Code)
+
+	w = send(id)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, compression.Encoding, w.Header().Get(compression.AcceptHeader))
+	v, ok := storage.GetGauge("Alloc")
+	require.True(t, ok)
+	require.Equal(t, 2.0, v)
+
+	// Обычный zstd без словаря принимается без заголовка идентификатора.
+	body, err := compression.NewCodec(nil).Compress([]byte(`[{"id":"Alloc","type":"gauge","value":3}]`))
+	require.NoError(t, err)
+	r := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body))
+	r.Header.Set("Content-Encoding", "zstd")
+	w = httptest.NewRecorder()
+	h.HandlerUpdateBatchJSON(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	v, _ = storage.GetGauge("Alloc")
+	require.Equal(t, 3.0, v)
+}
+
+// TestHandler_DictionaryDisabled проверяет, что без реестра словари не принимаются и не предлагаются.
+//
+// t — указатель на структуру теста.
+func TestHandler_DictionaryDisabled(t *testing.T) {
+	h := NewHandler(repository.NewMemStorage(), nil)
+	w := registerDictionary(h, compression.ID([]byte("d")), []byte("d"))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	r := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBufferString(`[{"id":"a","type":"gauge","value":1}]`))
+	w = httptest.NewRecorder()
+	h.HandlerUpdateBatchJSON(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get(compression.AcceptHeader))
+}
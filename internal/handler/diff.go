@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+)
+
+// MetricsDiff — ответ API изменений метрик.
+//
+// Поля:
+//   - Epoch: идентификатор запуска сервера; передаётся в epoch следующего запроса
+//   - Generation: поколение хранилища на момент ответа; передаётся в since следующего запроса
+//   - Full: true, если поколение since неизвестно серверу (например, после перезапуска)
+//     и Metrics содержит все метрики
+//   - Metrics: метрики, изменённые после since
+//   - Deleted: метрики (только id и type), удалённые после since, например по сроку хранения
+type MetricsDiff struct {
+	Epoch      string           `json:"epoch"`
+	Generation uint64           `json:"generation"`
+	Full       bool             `json:"full"`
+	Metrics    []models.Metrics `json:"metrics"`
+	Deleted    []models.Metrics `json:"deleted,omitempty"`
+}
+
+// HandleMetricsDiff возвращает метрики, изменённые после поколения хранилища или момента времени.
+//
+// Параметр запроса since — поколение из поля generation предыдущего ответа либо время
+// в формате RFC 3339; без параметра возвращаются все метрики. Удалённые после since
+// метрики перечисляются в поле deleted. Позволяет инкрементальным клиентам и репликам
+// догонять сервер без полной выгрузки.
+//
+// Поколение отсчитывается заново при каждом запуске сервера, поэтому вместе с ним
+// передаётся параметр epoch из предыдущего ответа. Если epoch не передан с поколением
+// или не совпадает с текущим запуском, возвращаются все метрики с признаком full.
+//
+// @Summary Получить изменения метрик
+// @Description Возвращает метрики, изменённые или удалённые после поколения хранилища или момента времени
+// @Tags Metrics
+// @Produce json
+// @Param since query string false "Поколение хранилища или время RFC 3339"
+// @Param epoch query string false "Идентификатор запуска сервера из предыдущего ответа"
+// @Success 200 {object} MetricsDiff "Изменённые метрики"
+// @Failure 400 {object} models.ErrorResponse "Некорректный параметр since"
+// @Router /api/v1/diff [get]
+func (h *Handler) HandleMetricsDiff(w http.ResponseWriter, r *http.Request) {
+	var (
+		gen     uint64
+		since   time.Time
+		isGen   bool
+		query   = r.URL.Query()
+		epoch   = query.Get("epoch")
+		unknown = epoch != "" && epoch != h.epoch
+	)
+	if v := query.Get("since"); v != "" {
+		var err error
+		if gen, err = strconv.ParseUint(v, 10, 64); err == nil {
+			isGen = true
+		} else if since, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "invalid since", map[string]string{"since": v})
+			return
+		}
+	}
+
+	// Поколение читается до выборки: изменения, попавшие между чтением и выборкой,
+	// будут повторно отданы следующим запросом, но не потеряются.
+	diff := MetricsDiff{Epoch: h.epoch, Generation: h.storage.Generation()}
+	if isGen && (epoch == "" || gen > diff.Generation) {
+		unknown = true
+	}
+	if unknown {
+		gen, since, diff.Full = 0, time.Time{}, true
+	}
+	var changed []repository.MetricInfo
+	for _, m := range h.storage.Changes(gen, since) {
+		if m.Deleted {
+			diff.Deleted = append(diff.Deleted, models.Metrics{ID: m.Name, MType: m.Type})
+			continue
+		}
+		changed = append(changed, m)
+	}
+	diff.Metrics = h.metricsFromInfo(changed)
+
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.writeJSONWithHash(w, diff); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandleMetricsDiff проверяет выборку изменений по поколению, времени и неизвестному поколению.
+//
+// t — указатель на структуру теста.
+func TestHandleMetricsDiff(t *testing.T) {
+	storage := repository.NewMemStorage()
+	storage.SetGauge("a", 1)
+	storage.AddCounter("b", 2)
+	gen := storage.Generation()
+	time.Sleep(2 * time.Millisecond)
+	since := time.Now()
+	storage.SetGauge("c", 3)
+	h := NewHandler(storage, nil)
+
+	tests := []struct {
+		name     string   // Название теста
+		since    string   // Параметр since
+		wantCode int      // Ожидаемый код ответа
+		wantIDs  []string // Ожидаемые метрики
+		wantFull bool     // Ожидаемый признак полной выгрузки
+	}{
+		{name: "All", wantCode: http.StatusOK, wantIDs: []string{"a", "b", "c"}},
+		{name: "Generation", since: strconv.FormatUint(gen, 10), wantCode: http.StatusOK, wantIDs: []string{"c"}},
+		{name: "Timestamp", since: since.Format(time.RFC3339Nano), wantCode: http.StatusOK, wantIDs: []string{"c"}},
+		{name: "UpToDate", since: "3", wantCode: http.StatusOK},
+		{name: "UnknownGeneration", since: "100", wantCode: http.StatusOK, wantIDs: []string{"a", "b", "c"}, wantFull: true},
+		{name: "Invalid", since: "yesterday", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			target := "/api/v1/diff?epoch=" + h.epoch
+			if tc.since != "" {
+				target += "&since=" + url.QueryEscape(tc.since)
+			}
+			rec := httptest.NewRecorder()
+			h.HandleMetricsDiff(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			require.Equal(t, tc.wantCode, rec.Code)
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+
+			var diff MetricsDiff
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &diff))
+			require.Equal(t, uint64(3), diff.Generation)
+			require.Equal(t, tc.wantFull, diff.Full)
+			var ids []string
+			for _, m := range diff.Metrics {
+				ids = append(ids, m.ID)
+			}
+			require.Equal(t, tc.wantIDs, ids)
+		})
+	}
+}
+
+// TestHandleMetricsDiff_Deleted проверяет, что метрики, удалённые по сроку хранения,
+// возвращаются в поле deleted клиентам, знающим более раннее поколение.
+//
+// t — указатель на структуру теста.
+func TestHandleMetricsDiff_Deleted(t *testing.T) {
+	storage := repository.NewMemStorage()
+	storage.SetGauge("a", 1)
+	storage.AddCounter("b", 2)
+	gen := storage.Generation()
+	require.True(t, storage.Expire("gauge", "a", time.Now()))
+	h := NewHandler(storage, nil)
+
+	get := func(target string) MetricsDiff {
+		rec := httptest.NewRecorder()
+		h.HandleMetricsDiff(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var diff MetricsDiff
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &diff))
+		return diff
+	}
+
+	diff := get("/api/v1/diff?epoch=" + h.epoch + "&since=" + strconv.FormatUint(gen, 10))
+	require.Empty(t, diff.Metrics)
+	require.Len(t, diff.Deleted, 1)
+	require.Equal(t, "a", diff.Deleted[0].ID)
+	require.Equal(t, "gauge", diff.Deleted[0].MType)
+
+	diff = get("/api/v1/diff")
+	require.Empty(t, diff.Deleted)
+	require.Len(t, diff.Metrics, 1)
+	require.Equal(t, "b", diff.Metrics[0].ID)
+}
+
+// TestHandleMetricsDiff_Restart проверяет, что поколение предыдущего запуска сервера не принимается
+// за поколение текущего: после перезапуска поколение отсчитывается заново и может совпасть
+// с известным клиенту, поэтому возвращаются все метрики с признаком full.
+//
+// t — указатель на структуру теста.
+func TestHandleMetricsDiff_Restart(t *testing.T) {
+	get := func(h *Handler, query string) MetricsDiff {
+		rec := httptest.NewRecorder()
+		h.HandleMetricsDiff(rec, httptest.NewRequest(http.MethodGet, "/api/v1/diff?"+query, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var diff MetricsDiff
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &diff))
+		return diff
+	}
+
+	before := repository.NewMemStorage()
+	before.SetGauge("a", 1)
+	before.SetGauge("b", 2)
+	prev := get(NewHandler(before, nil), "")
+	require.NotEmpty(t, prev.Epoch)
+	token := "since=" + strconv.FormatUint(prev.Generation, 10)
+
+	// После перезапуска метрики восстановлены, а поколение снова дошло до известного клиенту.
+	after := repository.NewMemStorage()
+	after.SetGauge("a", 1)
+	after.SetGauge("c", 3)
+	require.Equal(t, prev.Generation, after.Generation())
+	h := NewHandler(after, nil)
+
+	tests := []struct {
+		name  string // Название теста
+		query string // Параметры запроса
+	}{
+		{name: "PreviousEpoch", query: token + "&epoch=" + prev.Epoch},
+		{name: "NoEpoch", query: token},
+		{name: "PreviousEpochTimestamp", query: "since=" + url.QueryEscape(time.Now().Format(time.RFC3339Nano)) + "&epoch=" + prev.Epoch},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := get(h, tc.query)
+			require.True(t, diff.Full)
+			require.NotEqual(t, prev.Epoch, diff.Epoch)
+			require.Len(t, diff.Metrics, 2)
+		})
+	}
+
+	diff := get(h, "since="+strconv.FormatUint(after.Generation(), 10)+"&epoch="+h.epoch)
+	require.False(t, diff.Full)
+	require.Empty(t, diff.Metrics)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+)
+
+// SetAgentRegistry устанавливает реестр агентов для регистрации по одноразовым токенам.
+//
+// registry — реестр агентов; если nil, регистрация отключена, а запросы проверяются общим ключом.
+func (h *Handler) SetAgentRegistry(registry *repository.AgentRegistry) {
+	h.agents = registry
+}
+
+// HandleEnroll регистрирует агента по одноразовому токену и выдаёт ему идентификатор и персональный ключ.
+//
+// @Summary Зарегистрировать агента
+// @Description Обменивает одноразовый токен на идентификатор агента и персональный ключ HMAC
+// @Tags Agents
+// @Accept json
+// @Produce json
+// @Param request body models.EnrollRequest true "Токен регистрации"
+// @Success 200 {object} models.EnrollResponse "Учётные данные агента"
+// @Failure 400 {object} models.ErrorResponse "Некорректный запрос"
+// @Failure 403 {object} models.ErrorResponse "Недействительный токен"
+// @Failure 404 {object} models.ErrorResponse "Регистрация отключена"
+// @Router /api/v1/enroll [post]
+func (h *Handler) HandleEnroll(w http.ResponseWriter, r *http.Request) {
+	if h.agents == nil {
+		WriteError(w, r, http.StatusNotFound, models.ErrCodeEnrollmentDisabled, "enrollment disabled")
+		return
+	}
+	if !h.isTrustedAgentRequest(r) {
+		h.AuditRejection(r, models.AuditSubnetDenied)
+		WriteError(w, r, http.StatusForbidden, models.ErrCodeForbidden, "forbidden")
+		return
+	}
+
+	var req models.EnrollRequest
+	if err := decodeRequestBody(r, &req); err != nil || req.Token == "" {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeInvalidJSON, "invalid json")
+		return
+	}
+
+	identity, key, err := h.agents.Enroll(req.Token, req.Hostname, h.getClientIP(r))
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidEnrollmentToken) {
+			h.AuditRejection(r, models.AuditInvalidCredentials)
+			WriteError(w, r, http.StatusForbidden, models.ErrCodeInvalidEnrollToken, "invalid enrollment token")
+			return
+		}
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeInternal, "failed to enroll agent")
+		return
+	}
+	log.Printf("Agent %s enrolled from %s (%s)", identity.ID, identity.RemoteAddr, identity.Hostname)
+
+	if err := h.writeJSONWithHash(w, models.EnrollResponse{AgentID: identity.ID, Key: key}); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// HandleAgents возвращает список зарегистрированных агентов без их ключей.
+//
+// @Summary Получить список агентов
+// @Description Возвращает зарегистрированных агентов: идентификатор, имя хоста, адрес и время регистрации
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} repository.AgentIdentity "Зарегистрированные агенты"
+// @Router /admin/agents [get]
+func (h *Handler) HandleAgents(w http.ResponseWriter, _ *http.Request) {
+	agents := []repository.AgentIdentity{}
+	if h.agents != nil {
+		agents = h.agents.Agents()
+	}
+	if err := h.writeJSONWithHash(w, agents); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// verifyAgentHash проверяет подпись тела запроса.
+//
+// Если задан ключ проверки Ed25519 (см. SetVerifyKey), подпись в заголовке
+// crypto.SignatureHeader обязательна. Если запрос содержит заголовок X-Agent-ID,
+// подпись HMAC обязательна и проверяется персональным ключом агента; иначе
+// используется общий ключ (см. verifyHash). Для проверки при чтении тела
+// используется signatureVerifier.
+func (h *Handler) verifyAgentHash(r *http.Request, body []byte) bool {
+	v := h.newSignatureVerifier(r)
+	_, _ = v.Write(body)
+	return v.valid()
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/crypto"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandleEnroll проверяет регистрацию агента и проверку подписи его персональным ключом.
+//
+// t — указатель на структуру теста.
+func TestHandleEnroll(t *testing.T) {
+	registry, err := repository.NewAgentRegistry("", []string{"tok"})
+	require.NoError(t, err)
+	h := NewHandler(repository.NewMemStorage(), nil)
+	h.SetKey("shared")
+	h.SetAgentRegistry(registry)
+
+	enroll := func(token string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.EnrollRequest{Token: token, Hostname: "host"})
+		w := httptest.NewRecorder()
+		h.HandleEnroll(w, httptest.NewRequest(http.MethodPost, "/api/v1/enroll", bytes.NewReader(body)))
+		return w
+	}
+
+	w := enroll("tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	var creds models.EnrollResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &creds))
+	require.NotEmpty(t, creds.AgentID)
+	require.NotEmpty(t, creds.Key)
+
+	require.Equal(t, http.StatusForbidden, enroll("tok").Code)
+
+	payload := []byte(`{"id":"m","type":"gauge","value":1}`)
+	sign := func(key string) string {
+		return (&Handler{key: key}).computeHash(payload)
+	}
+	tests := []struct {
+		name    string
+		agentID string
+		hash    string
+		want    bool
+	}{
+		{name: "agent key", agentID: creds.AgentID, hash: sign(creds.Key), want: true},
+		{name: "shared key for agent", agentID: creds.AgentID, hash: sign("shared"), want: false},
+		{name: "agent without signature", agentID: creds.AgentID, want: false},
+		{name: "unknown agent", agentID: "agent-unknown", hash: sign(creds.Key), want: false},
+		{name: "shared key without agent", hash: sign("shared"), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/update", bytes.NewReader(payload))
+			if tt.agentID != "" {
+				r.Header.Set(models.AgentIDHeader, tt.agentID)
+			}
+			if tt.hash != "" {
+				r.Header.Set("HashSHA256", tt.hash)
+			}
+			require.Equal(t, tt.want, h.verifyAgentHash(r, payload))
+		})
+	}
+
+	w = httptest.NewRecorder()
+	h.HandleAgents(w, httptest.NewRequest(http.MethodGet, "/admin/agents", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), creds.AgentID)
+	require.NotContains(t, w.Body.String(), creds.Key)
+}
+
+// TestVerifyAgentHash_Ed25519 проверяет обязательную подпись Ed25519 при заданном ключе проверки.
+//
+// t — указатель на структуру теста.
+func TestVerifyAgentHash_Ed25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, otherPriv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	payload := []byte(`{"id":"m","type":"gauge","value":1}`)
+	tests := []struct {
+		name      string            // Название теста
+		verifyKey ed25519.PublicKey // Ключ проверки сервера
+		key       string            // Общий ключ HMAC сервера
+		signature string            // Подпись Ed25519 в запросе
+		hash      string            // Подпись HMAC в запросе
+		want      bool              // Ожидаемый результат проверки
+	}{
+		{name: "valid signature", verifyKey: pub, signature: crypto.Sign(payload, priv), want: true},
+		{name: "missing signature", verifyKey: pub, want: false},
+		{name: "wrong key", verifyKey: pub, signature: crypto.Sign(payload, otherPriv), want: false},
+		{name: "malformed signature", verifyKey: pub, signature: "zz", want: false},
+		{name: "verification disabled", want: true},
+		{
+			name:      "signature and hmac",
+			verifyKey: pub,
+			key:       "shared",
+			signature: crypto.Sign(payload, priv),
+			hash:      (&Handler{key: "shared"}).computeHash(payload),
+			want:      true,
+		},
+		{
+			name:      "signature with wrong hmac",
+			verifyKey: pub,
+			key:       "shared",
+			signature: crypto.Sign(payload, priv),
+			hash:      (&Handler{key: "other"}).computeHash(payload),
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(repository.NewMemStorage(), nil)
+			h.SetKey(tt.key)
+			h.SetVerifyKey(tt.verifyKey)
+
+			r := httptest.NewRequest(http.MethodPost, "/update", bytes.NewReader(payload))
+			if tt.signature != "" {
+				r.Header.Set(crypto.SignatureHeader, tt.signature)
+			}
+			if tt.hash != "" {
+				r.Header.Set("HashSHA256", tt.hash)
+			}
+			require.Equal(t, tt.want, h.verifyAgentHash(r, payload))
+		})
+	}
+}
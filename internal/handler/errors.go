@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/requestid"
+)
+
+// WriteError отвечает ошибкой в формате models.ErrorResponse.
+//
+// Идентификатор запроса берётся из контекста requestid.Middleware, если он есть.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	WriteErrorDetails(w, r, status, code, message, nil)
+}
+
+// WriteErrorDetails отвечает ошибкой в формате models.ErrorResponse с дополнительными сведениями details.
+//
+// Класс ошибки и признак повторяемости определяются по коду (см. models.ErrorClass);
+// для временных ошибок добавляется заголовок Retry-After, если он ещё не задан.
+func WriteErrorDetails(w http.ResponseWriter, r *http.Request, status int, code, message string, details map[string]string) {
+	resp := models.ErrorResponse{
+		Code:      code,
+		Class:     models.ErrorClass(code),
+		Message:   message,
+		Details:   details,
+		RequestID: requestid.FromContext(r.Context()),
+	}
+	resp.Retryable = resp.Class == models.ErrClassTransient
+	if resp.Retryable && w.Header().Get("Retry-After") == "" {
+		w.Header().Set("Retry-After", strconv.Itoa(models.DefaultRetryAfter))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Failed to write error response: %v", err)
+	}
+}
+
+// HandleNotFound отвечает ошибкой ErrCodeNotFound на запросы к неизвестным маршрутам.
+func HandleNotFound(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, http.StatusNotFound, models.ErrCodeNotFound, "not found")
+}
+
+// HandleMethodNotAllowed отвечает ошибкой ErrCodeMethodNotAllowed на запросы с неподдерживаемым методом.
+func HandleMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, http.StatusMethodNotAllowed, models.ErrCodeMethodNotAllowed, "method not allowed")
+}
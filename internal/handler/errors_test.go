@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/RoGogDBD/metric-alerter/internal/requestid"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriteError проверяет формат ответа с ошибкой и передачу идентификатора запроса.
+func TestWriteError(t *testing.T) {
+	h := requestid.Middleware(requestid.ULID)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeInvalidMetric, "missing value for gauge", map[string]string{"id": "m"})
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/update", nil))
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var got models.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, models.ErrCodeInvalidMetric, got.Code)
+	require.Equal(t, "missing value for gauge", got.Message)
+	require.Equal(t, map[string]string{"id": "m"}, got.Details)
+	require.Equal(t, models.ErrClassValidation, got.Class)
+	require.False(t, got.Retryable)
+	require.Empty(t, rec.Header().Get("Retry-After"))
+	require.NotEmpty(t, got.RequestID)
+	require.Equal(t, rec.Header().Get(requestid.Header), got.RequestID)
+}
+
+// TestWriteError_Retryable проверяет подсказки о повторе для временных ошибок сервера.
+func TestWriteError_Retryable(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, httptest.NewRequest(http.MethodPost, "/update", nil), http.StatusInternalServerError, models.ErrCodeStorageFailed, "failed to save metrics")
+
+	var got models.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, models.ErrClassTransient, got.Class)
+	require.True(t, got.Retryable)
+	require.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	// Отсутствие базы данных — ошибка конфигурации сервера, повтор не поможет.
+	rec = httptest.NewRecorder()
+	WriteError(rec, httptest.NewRequest(http.MethodGet, "/ping", nil), http.StatusInternalServerError, models.ErrCodeDatabaseNotConfigured, "database not configured")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, models.ErrClassInternal, got.Class)
+	require.False(t, got.Retryable)
+	require.Empty(t, rec.Header().Get("Retry-After"))
+}
+
+// TestHandlers_ErrorCodes проверяет коды ошибок, возвращаемые обработчиками.
+func TestHandlers_ErrorCodes(t *testing.T) {
+	h := NewHandler(repository.NewMemStorage(), nil)
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		method     string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"invalid json", h.HandleUpdateJSON, http.MethodPost, "{", http.StatusBadRequest, models.ErrCodeInvalidJSON},
+		{"missing gauge value", h.HandleUpdateJSON, http.MethodPost, `{"id":"m","type":"gauge"}`, http.StatusBadRequest, models.ErrCodeInvalidMetric},
+		{"unknown type in batch", h.HandlerUpdateBatchJSON, http.MethodPost, `[{"id":"m","type":"histogram"}]`, http.StatusNotImplemented, models.ErrCodeUnknownMetricType},
+		{"method not allowed", h.HandleGetMetricJSON, http.MethodGet, "", http.StatusMethodNotAllowed, models.ErrCodeMethodNotAllowed},
+		{"metric not found", h.HandleGetMetricJSON, http.MethodPost, `{"id":"missing","type":"gauge"}`, http.StatusNotFound, models.ErrCodeMetricNotFound},
+		{"database not configured", h.HandlePing, http.MethodGet, "", http.StatusInternalServerError, models.ErrCodeDatabaseNotConfigured},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			var got models.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			require.Equal(t, tt.wantCode, got.Code)
+		})
+	}
+}
@@ -260,7 +260,7 @@ func ExampleHandler_HandlePing() {
 	body, _ := io.ReadAll(resp.Body)
 	fmt.Printf("Status: %s, Body: %s\n", resp.Status, string(body))
 	// Output:
-	// Status: 500 Internal Server Error, Body: database not configured
+	// Status: 500 Internal Server Error, Body: {"code":"database_not_configured","class":"internal","retryable":false,"message":"database not configured"}
 }
 
 // ExampleHandler_Counter демонстрирует работу со счётчиками (counter).
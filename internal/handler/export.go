@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// Форматы выгрузки метрик.
+const (
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+)
+
+// ExportRecord — метрика в выгрузке /export.
+//
+// Поля:
+//   - Name: имя метрики
+//   - Type: тип метрики (gauge или counter)
+//   - Value: значение метрики
+//   - Timestamp: время последнего изменения метрики (UTC)
+type ExportRecord struct {
+	Name      string      `json:"name"`
+	Type      string      `json:"type"`
+	Value     json.Number `json:"value"`
+	Timestamp time.Time   `json:"timestamp,omitzero"`
+}
+
+// exportHeader — заголовок CSV-выгрузки, совпадающий с полями ExportRecord.
+var exportHeader = []string{"name", "type", "value", "timestamp"}
+
+// HandleExport выгружает все метрики файлом CSV или JSON для анализа в электронных таблицах.
+//
+// Параметр запроса format — csv (по умолчанию) или json. Каждая метрика выгружается
+// с именем, типом, значением и временем последнего изменения в формате RFC 3339 (UTC),
+// в порядке имени, а затем типа. Ответ записывается по мере формирования, без сборки
+// в памяти, поэтому заголовок HashSHA256 не выставляется.
+//
+// @Summary Выгрузить метрики
+// @Description Возвращает все метрики файлом CSV или JSON (name, type, value, timestamp)
+// @Tags Metrics
+// @Produce text/csv
+// @Produce json
+// @Param format query string false "Формат выгрузки: csv (по умолчанию) или json"
+// @Success 200 {array} ExportRecord "Выгрузка метрик"
+// @Failure 400 {object} models.ErrorResponse "Неизвестный формат"
+// @Router /export [get]
+func (h *Handler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = ExportCSV
+	}
+	var (
+		contentType string
+		write       func(*bufio.Writer, []ExportRecord) error
+	)
+	switch format {
+	case ExportCSV:
+		contentType, write = "text/csv; charset=utf-8", writeExportCSV
+	case ExportJSON:
+		contentType, write = "application/json", writeExportJSON
+	default:
+		WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "invalid format", map[string]string{"format": format})
+		return
+	}
+
+	all := h.storage.GetAll()
+	records := make([]ExportRecord, 0, len(all))
+	for _, m := range all {
+		rec := ExportRecord{Name: m.Name, Type: m.Type, Value: json.Number(m.Value)}
+		if at, ok := h.storage.UpdatedAt(m.Type, m.Name); ok {
+			rec.Timestamp = at.UTC()
+		}
+		records = append(records, rec)
+	}
+
+	filename := fmt.Sprintf("metrics-%s.%s", time.Now().UTC().Format("20060102T150405"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	bw := bufio.NewWriter(w)
+	if err := write(bw, records); err != nil {
+		log.Printf("Failed to write export: %v", err)
+		return
+	}
+	if err := bw.Flush(); err != nil {
+		log.Printf("Failed to write export: %v", err)
+	}
+}
+
+// writeExportCSV записывает метрики в w в формате CSV с заголовком exportHeader.
+func writeExportCSV(w *bufio.Writer, records []ExportRecord) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportHeader); err != nil {
+		return err
+	}
+	for _, rec := range records {
+		var ts string
+		if !rec.Timestamp.IsZero() {
+			ts = rec.Timestamp.Format(time.RFC3339Nano)
+		}
+		if err := cw.Write([]string{csvCell(rec.Name), rec.Type, rec.Value.String(), ts}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell защищает текстовое значение ячейки CSV от интерпретации электронной таблицей
+// как формулы: значение, начинающееся с =, +, -, @, табуляции или возврата каретки,
+// предваряется апострофом. Имена метрик приходят от агентов и не ограничены.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// writeExportJSON записывает метрики в w JSON-массивом, по одной метрике в строке.
+func writeExportJSON(w *bufio.Writer, records []ExportRecord) error {
+	if _, err := w.WriteString("["); err != nil {
+		return err
+	}
+	for i, rec := range records {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		sep := ",\n"
+		if i == 0 {
+			sep = "\n"
+		}
+		if _, err := w.WriteString(sep); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString("\n]\n")
+	return err
+}
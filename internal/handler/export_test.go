@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandleExport проверяет выгрузку метрик в CSV и JSON, заголовки ответа
+// и отклонение неизвестного формата.
+//
+// t — указатель на структуру теста.
+func TestHandleExport(t *testing.T) {
+	tests := []struct {
+		name        string // Название теста
+		query       string // Параметры запроса
+		wantStatus  int    // Ожидаемый код ответа
+		wantType    string // Ожидаемый Content-Type
+		wantFile    string // Ожидаемое расширение файла выгрузки
+		wantRecords int    // Ожидаемое число метрик в выгрузке
+	}{
+		{name: "DefaultCSV", wantStatus: http.StatusOK, wantType: "text/csv; charset=utf-8", wantFile: ".csv", wantRecords: 3},
+		{name: "CSV", query: "?format=csv", wantStatus: http.StatusOK, wantType: "text/csv; charset=utf-8", wantFile: ".csv", wantRecords: 3},
+		{name: "JSON", query: "?format=json", wantStatus: http.StatusOK, wantType: "application/json", wantFile: ".json", wantRecords: 3},
+		{name: "InvalidFormat", query: "?format=xml", wantStatus: http.StatusBadRequest},
+	}
+
+	storage := repository.NewMemStorage()
+	before := time.Now()
+	storage.SetGauge("Alloc", 1.5)
+	storage.AddCounter("PollCount", 3)
+	storage.SetGauge("=HYPERLINK(1)", 2)
+	h := NewHandler(storage, nil)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/export"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			h.HandleExport(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantStatus != http.StatusOK {
+				var resp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				require.Equal(t, models.ErrCodeBadRequest, resp.Code)
+				return
+			}
+			require.Equal(t, tc.wantType, rec.Header().Get("Content-Type"))
+			disposition := rec.Header().Get("Content-Disposition")
+			require.True(t, strings.HasPrefix(disposition, "attachment; filename=metrics-"), disposition)
+			require.True(t, strings.HasSuffix(disposition, tc.wantFile), disposition)
+
+			var records []ExportRecord
+			if tc.wantFile == ".json" {
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&records))
+			} else {
+				rows, err := csv.NewReader(rec.Body).ReadAll()
+				require.NoError(t, err)
+				require.Equal(t, exportHeader, rows[0])
+				for _, row := range rows[1:] {
+					ts, err := time.Parse(time.RFC3339Nano, row[3])
+					require.NoError(t, err)
+					records = append(records, ExportRecord{Name: row[0], Type: row[1], Value: json.Number(row[2]), Timestamp: ts})
+				}
+			}
+
+			require.Len(t, records, tc.wantRecords)
+			require.Equal(t, ExportRecord{Name: "Alloc", Type: models.Gauge, Value: "1.5"}, withoutTimestamp(records[1]))
+			require.Equal(t, ExportRecord{Name: "PollCount", Type: models.Counter, Value: "3"}, withoutTimestamp(records[2]))
+			if tc.wantFile == ".csv" {
+				require.Equal(t, "'=HYPERLINK(1)", records[0].Name)
+			} else {
+				require.Equal(t, "=HYPERLINK(1)", records[0].Name)
+			}
+			for _, r := range records {
+				require.False(t, r.Timestamp.Before(before), r.Name)
+				require.Equal(t, time.UTC, r.Timestamp.Location(), r.Name)
+			}
+		})
+	}
+}
+
+// withoutTimestamp возвращает копию записи выгрузки без времени изменения.
+func withoutTimestamp(r ExportRecord) ExportRecord {
+	r.Timestamp = time.Time{}
+	return r
+}
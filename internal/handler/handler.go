@@ -2,47 +2,88 @@ package handler
 
 import (
 	"bytes"
-	"compress/gzip"
+	"crypto/ed25519"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"html"
 	"io"
 	"log"
 	"net"
 	"net/http"
-	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/RoGogDBD/metric-alerter/internal/compression"
 	"github.com/RoGogDBD/metric-alerter/internal/crypto"
 	models "github.com/RoGogDBD/metric-alerter/internal/model"
 	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/RoGogDBD/metric-alerter/internal/requestid"
+	"github.com/RoGogDBD/metric-alerter/internal/telemetry"
+	"github.com/RoGogDBD/metric-alerter/internal/version"
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mailru/easyjson"
+	"go.uber.org/zap"
 )
 
 // Handler реализует обработчики HTTP-запросов для работы с метриками.
 //
 // Содержит хранилище метрик, подключение к базе данных, ключ для HMAC и менеджер аудита.
 type Handler struct {
-	storage       repository.Storage  // Хранилище метрик
-	db            *pgxpool.Pool       // Подключение к базе данных
-	key           string              // Ключ для HMAC-подписи
-	cryptoKey     *rsa.PrivateKey     // Приватный ключ для дешифрования
-	auditManager  models.AuditSubject // Менеджер аудита
-	trustedSubnet *net.IPNet          // Доверенная подсеть агента
+	storage       repository.Storage         // Хранилище метрик
+	db            *pgxpool.Pool              // Подключение к базе данных
+	fanOut        *repository.FanOut         // Хранилища, в которые дублируются записи
+	key           string                     // Ключ для HMAC-подписи
+	cryptoKey     *rsa.PrivateKey            // Приватный ключ для дешифрования
+	verifyKey     ed25519.PublicKey          // Открытый ключ для проверки подписи Ed25519
+	auditManager  models.AuditSubject        // Менеджер аудита
+	trustedSubnet *net.IPNet                 // Доверенная подсеть агента
+	diagConfig    map[string]string          // Итоговая конфигурация для диагностики
+	diagLogFile   string                     // Путь к журналу для диагностики
+	cardinality   cardinalityTracker         // Базовый замер для анализа кардинальности
+	collisions    collisionReporter          // Коллизии нормализованных имён метрик (nil — нормализация отключена)
+	agents        *repository.AgentRegistry  // Реестр зарегистрированных агентов
+	sources       *repository.CounterSources // Вклады источников в счётчики (nil — не учитываются)
+	authModes     []string                   // Способы аутентификации ролевого доступа для схемы API
+
+	started  time.Time        // Время запуска сервера
+	epoch    string           // Случайный идентификатор запуска для API изменений (см. HandleMetricsDiff)
+	ready    atomic.Bool      // Метрики восстановлены и остановка не начата (см. SetReady)
+	logLevel *zap.AtomicLevel // Уровень логирования, изменяемый через /admin/runtime (nil — не изменяется)
+
+	telemetry    *telemetry.Metrics // Собственные метрики сервера (nil — не собираются)
+	dictionaries *compression.Store // Словари сжатия батчей агентов (nil — не поддерживаются)
+	backup       backupRunner       // Резервное копирование по запросу (nil — копирование не настроено)
+
+	pageRefresh            time.Duration // Период автообновления HTML-страницы (0 — отключено)
+	pageRefreshIncremental bool          // Инкрементальное обновление вместо перезагрузки
+
+	batchChunk int // Размер порции потокового применения батча (0 — батч разбирается целиком)
 }
 
 // NewHandler создает новый экземпляр Handler.
 //
 // storage — реализация интерфейса Storage для хранения метрик.
-// db — пул подключений к базе данных PostgreSQL.
+// db — пул подключений к базе данных PostgreSQL; при заданном пуле обновления
+// синхронно записываются в базу данных (см. SetFanOut).
 func NewHandler(storage repository.Storage, db *pgxpool.Pool) *Handler {
-	return &Handler{storage: storage, db: db}
+	return &Handler{storage: storage, db: db, fanOut: repository.NewDBFanOut(storage, db), started: time.Now(), epoch: rand.Text()}
+}
+
+// SetFanOut задаёт хранилища, в которые дублируются записи после каждого обновления.
+//
+// Заменяет синхронную запись в базу данных, заданную NewHandler.
+func (h *Handler) SetFanOut(f *repository.FanOut) {
+	h.fanOut = f
 }
 
 // SetKey устанавливает ключ для HMAC-подписи ответов.
@@ -61,6 +102,29 @@ func (h *Handler) SetCryptoKey(key *rsa.PrivateKey) {
 	h.cryptoKey = key
 }
 
+// DecryptBody расшифровывает тело запроса, переданное с заголовком X-Encrypted (см. SetCryptoKey).
+//
+// Без ключа тело возвращается без изменений.
+func (h *Handler) DecryptBody(data []byte) ([]byte, error) {
+	if h.cryptoKey == nil {
+		return data, nil
+	}
+	return crypto.DecryptData(data, h.cryptoKey)
+}
+
+// SetVerifyKey устанавливает открытый ключ Ed25519 для проверки подписи запросов агентов.
+//
+// Если ключ задан, запросы на обновление метрик без корректной подписи отклоняются;
+// nil отключает проверку.
+func (h *Handler) SetVerifyKey(key ed25519.PublicKey) {
+	h.verifyKey = key
+}
+
+// SetTelemetry задаёт собственные метрики сервера, в которых учитываются размеры принятых пакетов.
+func (h *Handler) SetTelemetry(m *telemetry.Metrics) {
+	h.telemetry = m
+}
+
 // SetAuditManager устанавливает менеджер аудита для отправки событий.
 //
 // manager — менеджер аудита, реализующий интерфейс AuditSubject.
@@ -105,20 +169,63 @@ func (h *Handler) isTrustedAgentRequest(r *http.Request) bool {
 	return h.trustedSubnet.Contains(ip)
 }
 
-// sendAuditEvent отправляет событие аудита с именами метрик и IP-адресом клиента.
+// newAuditEvent возвращает событие аудита с данными запроса r: временем, IP-адресом
+// и идентификатором клиента, маршрутом, User-Agent и идентификатором запроса.
+func (h *Handler) newAuditEvent(r *http.Request) models.AuditEvent {
+	route := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		route = rctx.RoutePattern()
+	}
+	return models.AuditEvent{
+		Timestamp: time.Now().Unix(),
+		Metrics:   []string{},
+		IPAddress: h.getClientIP(r),
+		Route:     r.Method + " " + route,
+		RequestID: requestid.FromContext(r.Context()),
+		UserAgent: r.UserAgent(),
+		Identity:  auth.IdentityFromContext(r.Context()),
+	}
+}
+
+// sendAuditEvent отправляет событие аудита об операции op (models.AuditOp*) с метриками metricNames.
 //
 // Если менеджер аудита не установлен, ничего не делает.
-func (h *Handler) sendAuditEvent(r *http.Request, metricNames []string) {
+func (h *Handler) sendAuditEvent(r *http.Request, op string, metricNames []string) {
 	if h.auditManager == nil {
 		return
 	}
+	event := h.newAuditEvent(r)
+	event.Metrics = metricNames
+	event.Operation = op
+	h.auditManager.Notify(event)
+}
 
-	event := models.AuditEvent{
-		Timestamp: time.Now().Unix(),
-		Metrics:   metricNames,
-		IPAddress: h.getClientIP(r),
+// sendAgentAuditEvent отправляет событие аудита, как sendAuditEvent, для запроса с уже
+// проверенной подписью тела (см. counterSource). Без аутентификации по API-ключу или JWT
+// клиентом считается агент из заголовка X-Agent-ID.
+func (h *Handler) sendAgentAuditEvent(r *http.Request, op string, metricNames []string) {
+	if h.auditManager == nil {
+		return
 	}
+	event := h.newAuditEvent(r)
+	event.Metrics = metricNames
+	event.Operation = op
+	if id := r.Header.Get(models.AgentIDHeader); event.Identity == "" && id != "" {
+		event.Identity = "agent:" + id
+	}
+	h.auditManager.Notify(event)
+}
 
+// AuditRejection отправляет событие аудита об отказе в доступе с IP-адресом клиента и маршрутом.
+//
+// kind — тип отказа (models.Audit*).
+// Если менеджер аудита не установлен, ничего не делает.
+func (h *Handler) AuditRejection(r *http.Request, kind string) {
+	if h.auditManager == nil {
+		return
+	}
+	event := h.newAuditEvent(r)
+	event.Event = kind
 	h.auditManager.Notify(event)
 }
 
@@ -148,10 +255,13 @@ func (h *Handler) verifyHash(body []byte, receivedHash string) bool {
 // writeJSONWithHash сериализует данные в JSON, добавляет подпись HMAC (если задан ключ) и пишет в ответ.
 //
 // Устанавливает Content-Type: application/json и HashSHA256 (если ключ задан).
+// Для сериализации используются буферы из пула responseBuffers.
 func (h *Handler) writeJSONWithHash(w http.ResponseWriter, data interface{}) error {
 	w.Header().Set("Content-Type", "application/json")
 
-	body, err := json.Marshal(data)
+	rb := responseBuffers.Get()
+	defer responseBuffers.Put(rb)
+	body, err := rb.encodeJSON(data)
 	if err != nil {
 		return err
 	}
@@ -169,6 +279,8 @@ func (h *Handler) writeJSONWithHash(w http.ResponseWriter, data interface{}) err
 var (
 	// ErrUnknownMetricType возвращается при попытке работы с неизвестным типом метрики.
 	ErrUnknownMetricType = errors.New("unknown metric type")
+	// ErrEmptyMetricName возвращается, если имя метрики не задано.
+	ErrEmptyMetricName = errors.New("metric id is required")
 )
 
 // ValidateMetricInput валидирует входные параметры метрики и возвращает MetricUpdate.
@@ -179,6 +291,9 @@ var (
 //
 // Возвращает MetricUpdate или ошибку.
 func ValidateMetricInput(metricType, metricName, metricValue string) (*repository.MetricUpdate, error) {
+	if metricName == "" {
+		return nil, ErrEmptyMetricName
+	}
 	switch metricType {
 	case "gauge":
 		v, err := strconv.ParseFloat(metricValue, 64)
@@ -191,7 +306,7 @@ func ValidateMetricInput(metricType, metricName, metricValue string) (*repositor
 			FloatVal: &v,
 		}, nil
 	case "counter":
-		v, err := strconv.ParseInt(metricValue, 10, 64)
+		v, err := repository.ParseCounter(metricValue)
 		if err != nil {
 			return nil, err
 		}
@@ -211,6 +326,10 @@ func ValidateMetricInput(metricType, metricName, metricValue string) (*repositor
 // Сохраняет метрику в хранилище и (если настроено) синхронизирует с БД.
 // Отправляет событие аудита.
 //
+// У запроса нет тела, которое можно подписать, поэтому при заданном ключе проверки Ed25519
+// (см. SetVerifyKey) обновление по URL отклоняется: подписанные метрики принимают
+// только POST /update/ и POST /updates/.
+//
 // @Summary Обновить метрику через URL
 // @Description Обновляет значение метрики по параметрам в URL пути
 // @Tags Metrics
@@ -220,12 +339,18 @@ func ValidateMetricInput(metricType, metricName, metricValue string) (*repositor
 // @Param name path string true "Имя метрики"
 // @Param value path string true "Значение метрики"
 // @Success 200 {string} string "Метрика успешно обновлена"
-// @Failure 400 {string} string "Некорректные параметры запроса"
-// @Failure 501 {string} string "Неизвестный тип метрики"
+// @Failure 400 {object} models.ErrorResponse "Некорректные параметры запроса или требуется подпись"
+// @Failure 501 {object} models.ErrorResponse "Неизвестный тип метрики"
 // @Router /update/{type}/{name}/{value} [post]
 func (h *Handler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
 	if !h.isTrustedAgentRequest(r) {
-		http.Error(w, "forbidden", http.StatusForbidden)
+		h.AuditRejection(r, models.AuditSubnetDenied)
+		WriteError(w, r, http.StatusForbidden, models.ErrCodeForbidden, "forbidden")
+		return
+	}
+	if h.verifyKey != nil {
+		h.AuditRejection(r, models.AuditInvalidSignature)
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeInvalidSignature, "signature required: use POST /update/ or /updates/")
 		return
 	}
 
@@ -235,30 +360,46 @@ func (h *Handler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
 
 	metric, err := ValidateMetricInput(metricType, metricName, metricValue)
 	if err != nil {
-		status := http.StatusBadRequest
 		if errors.Is(err, ErrUnknownMetricType) {
-			status = http.StatusNotImplemented
+			WriteErrorDetails(w, r, http.StatusNotImplemented, models.ErrCodeUnknownMetricType, err.Error(), map[string]string{"type": metricType})
+			return
+		}
+		if errors.Is(err, repository.ErrCounterOverflow) {
+			writeCounterOverflow(w, r, metricName)
+			return
 		}
-		http.Error(w, err.Error(), status)
+		if errors.Is(err, ErrEmptyMetricName) {
+			WriteError(w, r, http.StatusBadRequest, models.ErrCodeInvalidMetric, err.Error())
+			return
+		}
+		WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeInvalidMetric, "invalid metric value", map[string]string{"id": metricName, "value": metricValue})
 		return
 	}
+	if metric.Type == "counter" {
+		if _, err := repository.CheckCounterOverflow(h.storage, map[string]int64{metric.Name: *metric.IntVal}); err != nil {
+			writeCounterOverflow(w, r, metricName)
+			return
+		}
+	}
 
 	switch metric.Type {
 	case "gauge":
 		h.storage.SetGauge(metric.Name, *metric.FloatVal)
 	case "counter":
 		h.storage.AddCounter(metric.Name, *metric.IntVal)
+		if h.sources != nil {
+			// Запрос без подписи: заголовок X-Agent-ID не проверен, источник — адрес клиента.
+			h.sources.Add(metric.Name, h.getClientIP(r), *metric.IntVal)
+		}
 	}
 
-	if h.db != nil {
-		if err := repository.SyncToDB(r.Context(), h.storage, h.db); err != nil {
-			log.Printf("Failed to sync metrics to DB: %v", err)
-			http.Error(w, "failed to save metrics", http.StatusInternalServerError)
-			return
-		}
+	if err := h.fanOut.Sync(r.Context()); err != nil {
+		log.Printf("Failed to save metrics: %v", err)
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeStorageFailed, "failed to save metrics")
+		return
 	}
 
-	h.sendAuditEvent(r, []string{metricName})
+	h.sendAuditEvent(r, models.AuditOpUpdate, []string{metricName})
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -275,8 +416,8 @@ func (h *Handler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
 // @Param type path string true "Тип метрики (gauge или counter)"
 // @Param name path string true "Имя метрики"
 // @Success 200 {string} string "Значение метрики"
-// @Failure 400 {string} string "Некорректный тип метрики"
-// @Failure 404 {string} string "Метрика не найдена"
+// @Failure 400 {object} models.ErrorResponse "Некорректный тип метрики"
+// @Failure 404 {object} models.ErrorResponse "Метрика не найдена"
 // @Router /value/{type}/{name} [get]
 func (h *Handler) HandleGetMetricValue(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -287,25 +428,30 @@ func (h *Handler) HandleGetMetricValue(w http.ResponseWriter, r *http.Request) {
 	case "gauge":
 		val, ok := h.storage.GetGauge(metricName)
 		if !ok {
-			http.Error(w, "not found", http.StatusNotFound)
+			WriteErrorDetails(w, r, http.StatusNotFound, models.ErrCodeMetricNotFound, "metric not found", map[string]string{"id": metricName})
 			return
 		}
 		w.Write([]byte(strconv.FormatFloat(val, 'f', -1, 64)))
 	case "counter":
 		val, ok := h.storage.GetCounter(metricName)
 		if !ok {
-			http.Error(w, "not found", http.StatusNotFound)
+			WriteErrorDetails(w, r, http.StatusNotFound, models.ErrCodeMetricNotFound, "metric not found", map[string]string{"id": metricName})
 			return
 		}
 		w.Write([]byte(strconv.FormatInt(val, 10)))
 	default:
-		http.Error(w, "invalid metric type", http.StatusBadRequest)
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeUnknownMetricType, "invalid metric type")
+		return
 	}
+	h.sendAuditEvent(r, models.AuditOpRead, []string{metricName})
 }
 
 // HandleMetricsPage возвращает HTML-страницу со списком всех метрик.
 //
-// Формирует HTML-таблицу с именами и значениями метрик.
+// Формирует HTML-таблицу с именами, значениями и временем последнего изменения (UTC) метрик
+// в порядке, заданном Storage.GetAll.
+// Если задано автообновление (SetPageRefresh), страница обновляется скриптом через
+// /api/v1/metrics или перезагружается целиком по заголовку Refresh.
 //
 // @Summary Получить HTML-страницу со всеми метриками
 // @Description Возвращает HTML-страницу со списком всех сохранённых метрик
@@ -316,36 +462,136 @@ func (h *Handler) HandleGetMetricValue(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) HandleMetricsPage(w http.ResponseWriter, _ *http.Request) {
 	metrics := h.storage.GetAll()
 
-	sort.Slice(metrics, func(i, j int) bool {
-		return metrics[i].Name < metrics[j].Name
-	})
-
-	builder := strings.Builder{}
-	builder.WriteString("<html><body><h1>Metrics</h1><ul>")
+	rb := responseBuffers.Get()
+	defer responseBuffers.Put(rb)
+	rb.buf.WriteString("<html>")
+	if h.pageRefresh > 0 {
+		secs := strconv.Itoa(max(1, int(h.pageRefresh/time.Second)))
+		rb.buf.WriteString("<head>")
+		if h.pageRefreshIncremental {
+			rb.buf.WriteString(`<script src="` + RefreshScriptPath + `" data-interval="` + secs + `" defer></script>`)
+			rb.buf.WriteString(`<noscript><meta http-equiv="refresh" content="` + secs + `"></noscript>`)
+		} else {
+			w.Header().Set("Refresh", secs)
+			rb.buf.WriteString(`<meta http-equiv="refresh" content="` + secs + `">`)
+		}
+		rb.buf.WriteString("</head>")
+	}
+	rb.buf.WriteString(`<body><h1>Metrics</h1><ul id="metrics">`)
 	for _, metric := range metrics {
-		builder.WriteString("<li>" + metric.Name + ": " + metric.Value + "</li>")
+		rb.buf.WriteString(`<li data-metric="`)
+		rb.buf.WriteString(html.EscapeString(metric.Type + "/" + metric.Name))
+		rb.buf.WriteString(`">`)
+		rb.buf.WriteString(html.EscapeString(metric.Name))
+		rb.buf.WriteString(": <span>")
+		rb.buf.WriteString(metric.Value)
+		rb.buf.WriteString("</span>")
+		if at, ok := h.storage.UpdatedAt(metric.Type, metric.Name); ok {
+			var ts [len(time.RFC3339)]byte
+			stamp := at.UTC().AppendFormat(ts[:0], time.RFC3339)
+			rb.buf.WriteString(` <time datetime="`)
+			rb.buf.Write(stamp)
+			rb.buf.WriteString(`">`)
+			rb.buf.Write(stamp)
+			rb.buf.WriteString("</time>")
+		}
+		rb.buf.WriteString("</li>")
 	}
-	builder.WriteString("</ul></body></html>")
+	rb.buf.WriteString("</ul></body></html>")
 
 	w.Header().Set("Content-Type", "text/html")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(builder.String()))
+	w.Write(rb.buf.Bytes())
 }
 
 // decodeRequestBody декодирует тело запроса в структуру v.
 //
-// Поддерживает сжатие gzip, если установлен соответствующий заголовок.
+// Тело должно быть несжатым: запросы gzip распаковывает middleware роутера.
+// Метрики (models.Metrics, models.MetricsBatch) разбираются без рефлексии: json.Decoder
+// всё равно буферизует значение целиком, поэтому тело читается и разбирается напрямую.
 func decodeRequestBody(r *http.Request, v interface{}) error {
-	var reader io.Reader = r.Body
-	if r.Header.Get("Content-Encoding") == "gzip" {
-		gz, err := gzip.NewReader(r.Body)
+	if u, ok := v.(easyjson.Unmarshaler); ok {
+		data, err := io.ReadAll(r.Body)
 		if err != nil {
 			return err
 		}
-		defer gz.Close()
-		reader = gz
+		if len(bytes.TrimSpace(data)) == 0 {
+			return io.EOF
+		}
+		err = easyjson.Unmarshal(data, u)
+		if errors.Is(err, io.EOF) {
+			// jlexer сообщает об оборванном теле через io.EOF, который означает пустое тело.
+			err = io.ErrUnexpectedEOF
+		}
+		return decodeError(err)
 	}
-	return json.NewDecoder(reader).Decode(v)
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	return decodeError(dec.Decode(v))
+}
+
+// decodeError дополняет ошибку разбора метрики err: приращение счётчика вне диапазона int64
+// оборачивается в repository.ErrCounterOverflow.
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && strings.HasPrefix(typeErr.Value, "number") && strings.HasSuffix(typeErr.Field, "delta") {
+		return fmt.Errorf("%w: %v", repository.ErrCounterOverflow, err)
+	}
+	return err
+}
+
+// writeDecodeError отвечает ошибкой разбора тела запроса: empty_body для пустого тела,
+// counter_overflow для приращения вне диапазона int64, иначе invalid_json.
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeReadError(w, r, err)
+		return
+	}
+	if errors.Is(err, io.EOF) {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeEmptyBody, "empty body")
+		return
+	}
+	if errors.Is(err, repository.ErrCounterOverflow) {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeCounterOverflow, "delta out of int64 range")
+		return
+	}
+	WriteError(w, r, http.StatusBadRequest, models.ErrCodeInvalidJSON, "invalid json")
+}
+
+// writeReadError отвечает ошибкой чтения тела запроса: body_too_large, если тело превысило
+// ограничение размера (см. http.MaxBytesReader), иначе bad_request.
+func writeReadError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		WriteError(w, r, http.StatusRequestEntityTooLarge, models.ErrCodeBodyTooLarge, "request body too large")
+		return
+	}
+	WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "failed to read body")
+}
+
+// checkCounterOverflow проверяет, что приращения счётчиков из metrics не переполнят int64
+// ни в сумме внутри запроса, ни вместе с текущими значениями в хранилище.
+//
+// Возвращает имя переполняющегося счётчика и repository.ErrCounterOverflow.
+func (h *Handler) checkCounterOverflow(metrics []models.Metrics) (string, error) {
+	deltas := make(map[string]int64)
+	for _, m := range metrics {
+		if m.MType != models.Counter || m.Delta == nil {
+			continue
+		}
+		sum, err := repository.AddCounterChecked(deltas[m.ID], *m.Delta)
+		if err != nil {
+			return m.ID, err
+		}
+		deltas[m.ID] = sum
+	}
+	return repository.CheckCounterOverflow(h.storage, deltas)
+}
+
+// writeCounterOverflow отвечает ошибкой counter_overflow для счётчика id.
+func writeCounterOverflow(w http.ResponseWriter, r *http.Request, id string) {
+	WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeCounterOverflow, "counter value out of int64 range", map[string]string{"id": id})
 }
 
 // HandleUpdateJSON обрабатывает POST-запрос для обновления одной метрики в формате JSON.
@@ -358,164 +604,136 @@ func decodeRequestBody(r *http.Request, v interface{}) error {
 // @Accept json
 // @Produce json
 // @Param metric body models.Metrics true "Метрика для обновления"
-// @Param HashSHA256 header string false "HMAC-SHA256 подпись тела запроса"
+// @Param HashSHA256 header string false "HMAC-SHA256 подпись несжатого тела запроса"
+// @Param X-Signature-Ed25519 header string false "Ed25519 подпись несжатого тела запроса (обязательна при заданном -verify-key)"
 // @Success 200 {object} models.Metrics "Обновлённая метрика"
-// @Failure 400 {string} string "Некорректный JSON или неверная подпись"
-// @Failure 500 {string} string "Ошибка сохранения метрики"
+// @Failure 400 {object} models.ErrorResponse "Некорректный JSON или неверная подпись"
+// @Failure 500 {object} models.ErrorResponse "Ошибка сохранения метрики"
 // @Router /update [post]
 func (h *Handler) HandleUpdateJSON(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		WriteError(w, r, http.StatusMethodNotAllowed, models.ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	if !h.isTrustedAgentRequest(r) {
-		http.Error(w, "forbidden", http.StatusForbidden)
+		h.AuditRejection(r, models.AuditSubnetDenied)
+		WriteError(w, r, http.StatusForbidden, models.ErrCodeForbidden, "forbidden")
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "failed to read body", http.StatusBadRequest)
+	var m models.Metrics
+	if !h.decodeVerifiedBody(w, r, &m) {
 		return
 	}
-	r.Body = io.NopCloser(bytes.NewReader(body))
-
-	receivedHash := r.Header.Get("HashSHA256")
-	if !h.verifyHash(body, receivedHash) {
-		http.Error(w, "invalid signature", http.StatusBadRequest)
+	if e := validateMetric(m); e != nil {
+		e.write(w, r)
 		return
 	}
-
-	var m models.Metrics
-	if err := decodeRequestBody(r, &m); err != nil {
-		http.Error(w, "invalid json", http.StatusBadRequest)
+	if id, err := h.checkCounterOverflow([]models.Metrics{m}); err != nil {
+		writeCounterOverflow(w, r, id)
 		return
 	}
+	h.applyMetric(m, h.counterSource(r))
 
-	switch m.MType {
-	case "gauge":
-		if m.Value == nil {
-			http.Error(w, "missing value for gauge", http.StatusBadRequest)
-			return
-		}
-		h.storage.SetGauge(m.ID, *m.Value)
-	case "counter":
-		if m.Delta == nil {
-			http.Error(w, "missing delta for counter", http.StatusBadRequest)
-			return
-		}
-		h.storage.AddCounter(m.ID, *m.Delta)
-	default:
-		http.Error(w, "unknown metric type", http.StatusNotImplemented)
+	if err := h.fanOut.Sync(r.Context()); err != nil {
+		log.Printf("Failed to save metrics: %v", err)
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeStorageFailed, "failed to save metrics")
 		return
 	}
 
-	if h.db != nil {
-		if err := repository.SyncToDB(r.Context(), h.storage, h.db); err != nil {
-			log.Printf("Failed to sync metrics to DB: %v", err)
-			http.Error(w, "failed to save metrics", http.StatusInternalServerError)
-			return
-		}
-	}
-
 	if err := h.writeJSONWithHash(w, m); err != nil {
 		log.Printf("Failed to write response: %v", err)
-		http.Error(w, "failed to write response", http.StatusInternalServerError)
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeInternal, "failed to write response")
 		return
 	}
 
-	h.sendAuditEvent(r, []string{m.ID})
+	h.sendAgentAuditEvent(r, models.AuditOpUpdate, []string{m.ID})
 }
 
 // HandlerUpdateBatchJSON обрабатывает POST-запрос для пакетного обновления метрик в формате JSON.
 //
 // Проверяет подпись HMAC, валидирует и сохраняет каждую метрику, синхронизирует с БД (если настроено), отправляет событие аудита.
 // Поддерживает асимметричное дешифрование данных с использованием приватного ключа.
+// Если задан размер порции (см. SetBatchStreaming), неподписанный батч применяется по мере
+// разбора (см. updateBatchStream).
 //
 // @Summary Пакетное обновление метрик
-// @Description Обновляет несколько метрик за один запрос, переданных в теле запроса в формате JSON
+// @Description Обновляет несколько метрик за один запрос, переданных в теле запроса в формате JSON. Если задан -stream-batch, неподписанный батч применяется порциями по мере разбора и не атомарно: при ошибке уже применённые порции остаются в хранилище, а их количество возвращается в заголовке X-Batch-Applied (ненулевое значение в ответе с ошибкой означает частично применённый батч)
 // @Tags Metrics
 // @Accept json
 // @Produce json
 // @Param metrics body []models.Metrics true "Массив метрик для обновления"
-// @Param HashSHA256 header string false "HMAC-SHA256 подпись тела запроса"
+// @Param HashSHA256 header string false "HMAC-SHA256 подпись несжатого тела запроса"
+// @Param X-Signature-Ed25519 header string false "Ed25519 подпись несжатого тела запроса (обязательна при заданном -verify-key)"
 // @Param X-Encrypted header string false "Флаг, указывающий на зашифрованные данные"
 // @Success 200 {array} models.Metrics "Массив обновлённых метрик"
-// @Failure 400 {string} string "Некорректный JSON или неверная подпись"
-// @Failure 500 {string} string "Ошибка сохранения метрик"
+// @Header 200,400,500 {integer} X-Batch-Applied "Количество применённых метрик (только при -stream-batch)"
+// @Failure 400 {object} models.ErrorResponse "Некорректный JSON или неверная подпись"
+// @Failure 500 {object} models.ErrorResponse "Ошибка сохранения метрик"
 // @Router /updates/ [post]
 func (h *Handler) HandlerUpdateBatchJSON(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		WriteError(w, r, http.StatusMethodNotAllowed, models.ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	if !h.isTrustedAgentRequest(r) {
-		http.Error(w, "forbidden", http.StatusForbidden)
-		return
-	}
-
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "failed to read body", http.StatusBadRequest)
+		h.AuditRejection(r, models.AuditSubnetDenied)
+		WriteError(w, r, http.StatusForbidden, models.ErrCodeForbidden, "forbidden")
 		return
 	}
 
-	if r.Header.Get("X-Encrypted") == "true" && h.cryptoKey != nil {
-		decrypted, err := crypto.DecryptData(body, h.cryptoKey)
+	// Подпись вычисляется по несжатому телу, поэтому батч, сжатый словарём, сначала распаковывается.
+	if r.Header.Get("Content-Encoding") == compression.Encoding {
+		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			http.Error(w, "failed to decrypt data", http.StatusBadRequest)
+			writeReadError(w, r, err)
 			return
 		}
-		body = decrypted
+		plain, ok := h.decompressWithDictionary(w, r, body)
+		if !ok {
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(plain))
 	}
 
-	r.Body = io.NopCloser(bytes.NewReader(body))
-
-	receivedHash := r.Header.Get("HashSHA256")
-	if !h.verifyHash(body, receivedHash) {
-		http.Error(w, "invalid signature", http.StatusBadRequest)
+	// Подписанный батч нельзя применять до проверки подписи по всему телу.
+	if h.batchChunk > 0 && !h.newSignatureVerifier(r).required() {
+		h.updateBatchStream(w, r)
 		return
 	}
 
-	var metrics []models.Metrics
-	if err := decodeRequestBody(r, &metrics); err != nil {
-		http.Error(w, "invalid json", http.StatusBadRequest)
+	var metrics models.MetricsBatch
+	if !h.decodeVerifiedBody(w, r, &metrics) {
 		return
 	}
-
+	if e := validateBatch(metrics); e != nil {
+		e.write(w, r)
+		return
+	}
+	if id, err := h.checkCounterOverflow(metrics); err != nil {
+		writeCounterOverflow(w, r, id)
+		return
+	}
+	h.telemetry.ObserveBatch("http", len(metrics))
+	source := h.counterSource(r)
 	for _, m := range metrics {
-		switch m.MType {
-		case "gauge":
-			if m.Value == nil {
-				http.Error(w, "missing value for gauge", http.StatusBadRequest)
-				return
-			}
-			h.storage.SetGauge(m.ID, *m.Value)
-		case "counter":
-			if m.Delta == nil {
-				http.Error(w, "missing delta for counter", http.StatusBadRequest)
-				return
-			}
-			h.storage.AddCounter(m.ID, *m.Delta)
-		default:
-			http.Error(w, "unknown metric type", http.StatusNotImplemented)
-			return
-		}
+		h.applyMetric(m, source)
 	}
 
-	if h.db != nil {
-		if err := repository.SyncToDB(r.Context(), h.storage, h.db); err != nil {
-			log.Printf("Failed to sync metrics to DB: %v", err)
-			http.Error(w, "failed to save metrics", http.StatusInternalServerError)
-			return
-		}
+	if err := h.fanOut.Sync(r.Context()); err != nil {
+		log.Printf("Failed to save metrics: %v", err)
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeStorageFailed, "failed to save metrics")
+		return
 	}
 
+	if h.dictionaries != nil {
+		w.Header().Set(compression.AcceptHeader, compression.Encoding)
+	}
 	if err := h.writeJSONWithHash(w, metrics); err != nil {
 		log.Printf("Failed to write response: %v", err)
-		http.Error(w, "failed to write response", http.StatusInternalServerError)
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeInternal, "failed to write response")
 		return
 	}
 
@@ -524,7 +742,7 @@ func (h *Handler) HandlerUpdateBatchJSON(w http.ResponseWriter, r *http.Request)
 		metricNames[i] = m.ID
 	}
 
-	h.sendAuditEvent(r, metricNames)
+	h.sendAgentAuditEvent(r, models.AuditOpBatch, metricNames)
 }
 
 // HandleGetMetricJSON обрабатывает POST-запрос для получения значения метрики в формате JSON.
@@ -538,45 +756,50 @@ func (h *Handler) HandlerUpdateBatchJSON(w http.ResponseWriter, r *http.Request)
 // @Produce json
 // @Param metric body models.Metrics true "Запрос метрики (id и type обязательны)"
 // @Success 200 {object} models.Metrics "Метрика со значением"
-// @Failure 400 {string} string "Некорректный JSON"
-// @Failure 404 {string} string "Метрика не найдена"
+// @Failure 400 {object} models.ErrorResponse "Некорректный JSON"
+// @Failure 404 {object} models.ErrorResponse "Метрика не найдена"
 // @Router /value [post]
 func (h *Handler) HandleGetMetricJSON(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		WriteError(w, r, http.StatusMethodNotAllowed, models.ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	var req models.Metrics
 	if err := decodeRequestBody(r, &req); err != nil {
-		http.Error(w, "invalid json", http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
-	resp := models.Metrics{
-		ID:    req.ID,
-		MType: req.MType,
+	if req.ID == "" {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeInvalidMetric, ErrEmptyMetricName.Error())
+		return
 	}
-	switch req.MType {
-	case "gauge":
-		val, ok := h.storage.GetGauge(req.ID)
-		if !ok {
-			http.Error(w, "not found", http.StatusNotFound)
-			return
-		}
-		resp.Value = &val
-	case "counter":
-		delta, ok := h.storage.GetCounter(req.ID)
-		if !ok {
-			http.Error(w, "not found", http.StatusNotFound)
-			return
-		}
-		resp.Delta = &delta
-	default:
-		http.Error(w, "unknown metric type", http.StatusNotImplemented)
+	if req.MType != models.Gauge && req.MType != models.Counter {
+		WriteError(w, r, http.StatusNotImplemented, models.ErrCodeUnknownMetricType, "unknown metric type")
+		return
+	}
+	resp, ok := h.lookupMetric(req.ID, req.MType)
+	if !ok {
+		WriteErrorDetails(w, r, http.StatusNotFound, models.ErrCodeMetricNotFound, "metric not found", map[string]string{"id": req.ID})
 		return
 	}
 	if err := h.writeJSONWithHash(w, resp); err != nil {
 		log.Printf("Failed to write response: %v", err)
 	}
+	h.sendAuditEvent(r, models.AuditOpRead, []string{req.ID})
+}
+
+// HandleVersion возвращает информацию о сборке сервера в формате JSON.
+//
+// @Summary Получить информацию о сборке
+// @Description Возвращает версию, дату сборки, коммит, версию Go и платформу сервера
+// @Tags Health
+// @Produce json
+// @Success 200 {object} version.Info "Информация о сборке"
+// @Router /version [get]
+func (h *Handler) HandleVersion(w http.ResponseWriter, _ *http.Request) {
+	if err := h.writeJSONWithHash(w, version.Get()); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
 }
 
 // HandlePing проверяет доступность базы данных.
@@ -588,15 +811,15 @@ func (h *Handler) HandleGetMetricJSON(w http.ResponseWriter, r *http.Request) {
 // @Tags Health
 // @Produce plain
 // @Success 200 {string} string "OK"
-// @Failure 500 {string} string "База данных недоступна"
+// @Failure 500 {object} models.ErrorResponse "База данных недоступна"
 // @Router /ping [get]
 func (h *Handler) HandlePing(w http.ResponseWriter, r *http.Request) {
 	if h.db == nil {
-		http.Error(w, "database not configured", http.StatusInternalServerError)
+		WriteError(w, r, http.StatusInternalServerError, models.ErrCodeDatabaseNotConfigured, "database not configured")
 		return
 	}
 	if err := h.db.Ping(r.Context()); err != nil {
-		http.Error(w, "database not reachable: "+err.Error(), http.StatusInternalServerError)
+		WriteErrorDetails(w, r, http.StatusInternalServerError, models.ErrCodeDatabaseUnavailable, "database not reachable", map[string]string{"error": err.Error()})
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -28,6 +28,7 @@ func TestValidateMetricInput_TableDriven(t *testing.T) {
 		{"counter ok", "counter", "c1", "10", false, "counter", 10, 0},
 		{"counter bad", "counter", "c1", "badint", true, "", 0, 0},
 		{"unknown type", "unknown", "x", "1", true, "", 0, 0},
+		{"empty name", "gauge", "", "1", true, "", 0, 0},
 	}
 
 	for _, tt := range tests {
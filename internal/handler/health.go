@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// healthCheckTimeout — предельное время одной проверки /livez и /readyz.
+const healthCheckTimeout = 2 * time.Second
+
+// Состояния проверок здоровья сервера.
+const (
+	HealthOK   = "ok"
+	HealthFail = "fail"
+)
+
+type (
+	// HealthCheck — результат одной проверки здоровья сервера.
+	//
+	// Поля:
+	//   - Status: HealthOK или HealthFail
+	//   - Error: причина неудачи
+	HealthCheck struct {
+		Status string `json:"status"`
+		Error  string `json:"error,omitempty"`
+	}
+
+	// HealthReport — ответ /healthz, /livez и /readyz.
+	//
+	// Поля:
+	//   - Status: HealthFail, если не прошла хотя бы одна проверка, иначе HealthOK
+	//   - Checks: результаты проверок по именам
+	HealthReport struct {
+		Status string                 `json:"status"`
+		Checks map[string]HealthCheck `json:"checks,omitempty"`
+	}
+)
+
+// SetReady сообщает, готов ли сервер принимать запросы: метрики восстановлены из снимка
+// и журнала, а остановка сервера не начата (см. HandleReadyz).
+func (h *Handler) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// HandleHealthz сообщает, что процесс сервера запущен и обрабатывает HTTP-запросы.
+//
+// @Summary Проверить, что сервер запущен
+// @Description Всегда возвращает status ok, пока процесс обрабатывает запросы
+// @Tags Health
+// @Produce json
+// @Success 200 {object} HealthReport "Сервер запущен"
+// @Router /healthz [get]
+func (h *Handler) HandleHealthz(w http.ResponseWriter, _ *http.Request) {
+	h.writeHealth(w, HealthReport{Status: HealthOK})
+}
+
+// HandleLivez проверяет, что сервер не завис: хранилище метрик отвечает на чтение.
+//
+// Заблокированное хранилище не восстанавливается без перезапуска, поэтому неудача
+// этой проверки означает, что процесс нужно перезапустить. Доступность базы данных
+// здесь не проверяется (см. HandleReadyz).
+//
+// @Summary Проверить, что сервер не завис
+// @Description Проверяет, что хранилище метрик отвечает на чтение; 503 означает, что сервер нужно перезапустить
+// @Tags Health
+// @Produce json
+// @Success 200 {object} HealthReport "Сервер работает"
+// @Failure 503 {object} HealthReport "Хранилище не отвечает"
+// @Router /livez [get]
+func (h *Handler) HandleLivez(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+	h.writeHealth(w, healthReport(map[string]HealthCheck{
+		"storage": healthCheck(h.probeStorage(ctx)),
+	}))
+}
+
+// HandleReadyz проверяет, что сервер готов принимать запросы.
+//
+// Сервер готов, если метрики восстановлены и остановка не начата (см. SetReady), хранилище
+// отвечает на чтение и, если настроена база данных, пул подключений к ней исправен.
+// В отличие от /ping, неудача любой проверки отвечает 503 с описанием в checks.
+//
+// @Summary Проверить готовность сервера
+// @Description Проверяет восстановление метрик, хранилище и базу данных (если настроена)
+// @Tags Health
+// @Produce json
+// @Success 200 {object} HealthReport "Сервер готов"
+// @Failure 503 {object} HealthReport "Сервер не готов"
+// @Router /readyz [get]
+func (h *Handler) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	checks := map[string]HealthCheck{
+		"storage": healthCheck(h.probeStorage(ctx)),
+	}
+	if h.ready.Load() {
+		checks["restore"] = healthCheck(nil)
+	} else {
+		checks["restore"] = healthCheck(errors.New("metrics are not restored yet or server is shutting down"))
+	}
+	if h.db != nil {
+		checks["database"] = healthCheck(h.db.Ping(ctx))
+	}
+	h.writeHealth(w, healthReport(checks))
+}
+
+// probeStorage читает метрику из хранилища и ждёт ответа не дольше ctx.
+//
+// Чтение ожидает блокировку хранилища, поэтому зависшая запись (например, недоступный диск
+// журнала упреждающей записи) обнаруживается по истечении ctx. Горутина чтения в этом
+// случае остаётся ждать блокировку.
+func (h *Handler) probeStorage(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.storage.GetGauge("")
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.New("storage not responding")
+	}
+}
+
+// healthCheck возвращает результат проверки с ошибкой err (nil — проверка прошла).
+func healthCheck(err error) HealthCheck {
+	if err != nil {
+		return HealthCheck{Status: HealthFail, Error: err.Error()}
+	}
+	return HealthCheck{Status: HealthOK}
+}
+
+// healthReport собирает ответ из результатов проверок checks.
+func healthReport(checks map[string]HealthCheck) HealthReport {
+	report := HealthReport{Status: HealthOK, Checks: checks}
+	for _, c := range checks {
+		if c.Status != HealthOK {
+			report.Status = HealthFail
+		}
+	}
+	return report
+}
+
+// writeHealth отвечает отчётом report: 200, если все проверки прошли, иначе 503.
+// Подпись HashSHA256 добавляется так же, как в writeJSONWithHash.
+func (h *Handler) writeHealth(w http.ResponseWriter, report HealthReport) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("Failed to write response: %v", err)
+		return
+	}
+	status := http.StatusOK
+	if report.Status != HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if h.key != "" {
+		w.Header().Set("HashSHA256", h.computeHash(body))
+	}
+	w.WriteHeader(status)
+	if _, err := w.Write(body); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// blockedStorage — хранилище, чтение из которого не завершается, пока не закрыт unblock.
+type blockedStorage struct {
+	repository.Storage
+	unblock chan struct{}
+}
+
+// GetGauge ждёт закрытия unblock.
+func (s blockedStorage) GetGauge(name string) (float64, bool) {
+	<-s.unblock
+	return s.Storage.GetGauge(name)
+}
+
+// TestHandleHealth проверяет ответы /healthz, /livez и /readyz: общий статус, код ответа
+// и результаты отдельных проверок.
+//
+// t — указатель на структуру теста.
+func TestHandleHealth(t *testing.T) {
+	tests := []struct {
+		name       string            // Название теста
+		path       string            // Путь запроса
+		ready      bool              // Готовность сервера (SetReady)
+		blocked    bool              // Хранилище не отвечает на чтение
+		wantStatus int               // Ожидаемый код ответа
+		wantChecks map[string]string // Ожидаемые состояния проверок по именам
+	}{
+		{name: "Healthz", path: "/healthz", wantStatus: http.StatusOK},
+		{name: "HealthzBlocked", path: "/healthz", blocked: true, wantStatus: http.StatusOK},
+		{
+			name:       "Livez",
+			path:       "/livez",
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"storage": HealthOK},
+		},
+		{
+			name:       "LivezBlocked",
+			path:       "/livez",
+			ready:      true,
+			blocked:    true,
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"storage": HealthFail},
+		},
+		{
+			name:       "Ready",
+			path:       "/readyz",
+			ready:      true,
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"storage": HealthOK, "restore": HealthOK},
+		},
+		{
+			name:       "NotRestored",
+			path:       "/readyz",
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"storage": HealthOK, "restore": HealthFail},
+		},
+		{
+			name:       "ReadyBlocked",
+			path:       "/readyz",
+			ready:      true,
+			blocked:    true,
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"storage": HealthFail, "restore": HealthOK},
+		},
+	}
+
+	handlers := map[string]func(*Handler, http.ResponseWriter, *http.Request){
+		"/healthz": (*Handler).HandleHealthz,
+		"/livez":   (*Handler).HandleLivez,
+		"/readyz":  (*Handler).HandleReadyz,
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var storage repository.Storage = repository.NewMemStorage()
+			if tc.blocked {
+				unblock := make(chan struct{})
+				t.Cleanup(func() { close(unblock) })
+				storage = blockedStorage{Storage: storage, unblock: unblock}
+			}
+			h := NewHandler(storage, nil)
+			h.SetReady(tc.ready)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			handlers[tc.path](h, rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			require.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+			var report HealthReport
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+			wantReport := HealthOK
+			if tc.wantStatus != http.StatusOK {
+				wantReport = HealthFail
+			}
+			require.Equal(t, wantReport, report.Status)
+			checks := make(map[string]string, len(report.Checks))
+			for name, c := range report.Checks {
+				checks[name] = c.Status
+				require.Equal(t, c.Status == HealthFail, c.Error != "", name)
+			}
+			if tc.wantChecks == nil {
+				require.Empty(t, checks)
+			} else {
+				require.Equal(t, tc.wantChecks, checks)
+			}
+		})
+	}
+}
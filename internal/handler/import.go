@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// ImportResult — ответ POST /import.
+//
+// Поля:
+//   - DryRun: true, если метрики только проверены и не применены к хранилищу
+//   - Gauges: количество gauge-метрик в загрузке
+//   - Counters: количество counter-метрик в загрузке
+//   - Created: количество метрик, которых не было в хранилище
+type ImportResult struct {
+	DryRun   bool `json:"dry_run"`
+	Gauges   int  `json:"gauges"`
+	Counters int  `json:"counters"`
+	Created  int  `json:"created"`
+}
+
+// HandleImport загружает в хранилище снимок метрик другого сервера.
+//
+// Принимает снимок в формате SaveMetricsToFile (JSON-массив метрик, null — пустой снимок)
+// или, с заголовком Content-Type: text/csv, выгрузку GET /export?format=csv. Значения
+// gauge-метрик заменяются, значения counter-метрик прибавляются к текущим, как при
+// восстановлении из снимка, поэтому снимок загружается в пустое хранилище один раз.
+// Загрузка проверяется целиком до применения; с параметром dry_run=true метрики только
+// проверяются, а ответ показывает, что было бы загружено.
+//
+// @Summary Загрузить снимок метрик
+// @Description Загружает метрики из снимка JSON или выгрузки CSV; dry_run=true только проверяет загрузку
+// @Tags Admin
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param dry_run query bool false "Только проверить загрузку, не применяя её"
+// @Param metrics body []models.Metrics true "Снимок метрик"
+// @Success 200 {object} ImportResult "Результат загрузки"
+// @Failure 400 {object} models.ErrorResponse "Некорректный снимок или параметр dry_run"
+// @Failure 500 {object} models.ErrorResponse "Ошибка сохранения метрик"
+// @Router /import [post]
+func (h *Handler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	var result ImportResult
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "invalid dry_run", map[string]string{"dry_run": v})
+			return
+		}
+		result.DryRun = dryRun
+	}
+
+	var metrics models.MetricsBatch
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		var e *metricError
+		if metrics, e = parseImportCSV(r.Body); e != nil {
+			e.write(w, r)
+			return
+		}
+	} else if err := decodeRequestBody(r, &metrics); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	for i, m := range metrics {
+		if e := validateMetric(m); e != nil {
+			e.at(i).write(w, r)
+			return
+		}
+	}
+	if id, err := h.checkCounterOverflow(metrics); err != nil {
+		writeCounterOverflow(w, r, id)
+		return
+	}
+
+	created := make(map[[2]string]struct{})
+	for _, m := range metrics {
+		var exists bool
+		switch m.MType {
+		case models.Gauge:
+			result.Gauges++
+			_, exists = h.storage.GetGauge(m.ID)
+		case models.Counter:
+			result.Counters++
+			_, exists = h.storage.GetCounter(m.ID)
+		}
+		if !exists {
+			created[[2]string{m.MType, m.ID}] = struct{}{}
+		}
+	}
+	result.Created = len(created)
+
+	if !result.DryRun && len(metrics) > 0 {
+		source := h.counterSource(r)
+		for _, m := range metrics {
+			h.applyMetric(m, source)
+		}
+		if err := h.fanOut.Sync(r.Context()); err != nil {
+			log.Printf("Failed to save metrics: %v", err)
+			WriteError(w, r, http.StatusInternalServerError, models.ErrCodeStorageFailed, "failed to save metrics")
+			return
+		}
+		names := make([]string, len(metrics))
+		for i, m := range metrics {
+			names[i] = m.ID
+		}
+		h.sendAgentAuditEvent(r, models.AuditOpBatch, names)
+	}
+
+	if err := h.writeJSONWithHash(w, result); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// parseImportCSV разбирает выгрузку GET /export?format=csv: заголовок с колонками name,
+// type и value (колонка timestamp и другие пропускаются), затем по метрике в строке.
+//
+// Апостроф, которым выгрузка защищает имена от интерпретации как формулы (см. csvCell),
+// снимается. Ошибка метрики содержит в details её индекс среди строк данных.
+func parseImportCSV(body io.Reader) (models.MetricsBatch, *metricError) {
+	cr := csv.NewReader(body)
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &metricError{http.StatusBadRequest, models.ErrCodeEmptyBody, "empty body", nil}
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	if len(header) < 3 || !slices.Equal(header[:3], exportHeader[:3]) {
+		return nil, &metricError{http.StatusBadRequest, models.ErrCodeBadRequest, "invalid csv header", map[string]string{"want": strings.Join(exportHeader, ",")}}
+	}
+
+	var metrics models.MetricsBatch
+	for i := 0; ; i++ {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return metrics, nil
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		m := models.Metrics{ID: csvUncell(row[0]), MType: row[1]}
+		details := map[string]string{"id": m.ID}
+		switch m.MType {
+		case models.Gauge:
+			v, err := strconv.ParseFloat(row[2], 64)
+			if err != nil {
+				return nil, (&metricError{http.StatusBadRequest, models.ErrCodeInvalidMetric, "invalid value for gauge", details}).at(i)
+			}
+			m.Value = &v
+		case models.Counter:
+			d, err := strconv.ParseInt(row[2], 10, 64)
+			if err != nil {
+				return nil, (&metricError{http.StatusBadRequest, models.ErrCodeInvalidMetric, "invalid delta for counter", details}).at(i)
+			}
+			m.Delta = &d
+		}
+		metrics = append(metrics, m)
+	}
+}
+
+// csvError преобразует ошибку чтения CSV в ошибку ответа: body_too_large при превышении
+// размера тела, иначе bad_request с номером строки.
+func csvError(err error) *metricError {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &metricError{http.StatusRequestEntityTooLarge, models.ErrCodeBodyTooLarge, "request body too large", nil}
+	}
+	details := map[string]string{"error": err.Error()}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		details["line"] = strconv.Itoa(parseErr.Line)
+	}
+	return &metricError{http.StatusBadRequest, models.ErrCodeBadRequest, "invalid csv", details}
+}
+
+// csvUncell снимает апостроф, добавленный csvCell перед значением, похожим на формулу.
+func csvUncell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && csvCell(s[1:]) != s[1:] {
+		return s[1:]
+	}
+	return s
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandleImport проверяет загрузку снимка JSON и выгрузки CSV, режим проверки без
+// применения и отклонение некорректной загрузки целиком.
+//
+// t — указатель на структуру теста.
+func TestHandleImport(t *testing.T) {
+	tests := []struct {
+		name        string       // Название теста
+		query       string       // Параметры запроса
+		contentType string       // Content-Type запроса
+		body        string       // Тело запроса
+		wantStatus  int          // Ожидаемый код ответа
+		wantCode    string       // Ожидаемый код ошибки
+		wantIndex   string       // Ожидаемый индекс метрики в details ошибки
+		want        ImportResult // Ожидаемый результат загрузки
+		wantAlloc   float64      // Ожидаемое значение gauge Alloc после запроса
+		wantPoll    int64        // Ожидаемое значение counter PollCount после запроса
+	}{
+		{
+			name:        "Snapshot",
+			contentType: "application/json",
+			body:        `[{"id":"Alloc","type":"gauge","value":2.5},{"id":"PollCount","type":"counter","delta":4},{"id":"Heap","type":"gauge","value":1}]`,
+			wantStatus:  http.StatusOK,
+			want:        ImportResult{Gauges: 2, Counters: 1, Created: 1},
+			wantAlloc:   2.5,
+			wantPoll:    7,
+		},
+		{
+			name:        "DryRun",
+			query:       "?dry_run=true",
+			contentType: "application/json",
+			body:        `[{"id":"Alloc","type":"gauge","value":2.5},{"id":"PollCount","type":"counter","delta":4}]`,
+			wantStatus:  http.StatusOK,
+			want:        ImportResult{DryRun: true, Gauges: 1, Counters: 1},
+			wantAlloc:   1.5,
+			wantPoll:    3,
+		},
+		{
+			name:       "EmptySnapshot",
+			body:       `null`,
+			wantStatus: http.StatusOK,
+			wantAlloc:  1.5,
+			wantPoll:   3,
+		},
+		{
+			name:        "CSV",
+			contentType: "text/csv",
+			body:        "name,type,value,timestamp\n'=cmd,gauge,1,\nAlloc,gauge,0.5,2025-01-01T00:00:00Z\nPollCount,counter,2,\n",
+			wantStatus:  http.StatusOK,
+			want:        ImportResult{Gauges: 2, Counters: 1, Created: 1},
+			wantAlloc:   0.5,
+			wantPoll:    5,
+		},
+		{
+			name:        "InvalidMetricRejectsAll",
+			contentType: "application/json",
+			body:        `[{"id":"Alloc","type":"gauge","value":9},{"id":"PollCount","type":"counter"}]`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    models.ErrCodeInvalidMetric,
+			wantIndex:   "1",
+			wantAlloc:   1.5,
+			wantPoll:    3,
+		},
+		{
+			name:        "CSVInvalidValue",
+			contentType: "text/csv",
+			body:        "name,type,value\nAlloc,gauge,9\nPollCount,counter,1.5\n",
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    models.ErrCodeInvalidMetric,
+			wantIndex:   "1",
+			wantAlloc:   1.5,
+			wantPoll:    3,
+		},
+		{
+			name:        "CSVInvalidHeader",
+			contentType: "text/csv",
+			body:        "id,type,value\nAlloc,gauge,9\n",
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    models.ErrCodeBadRequest,
+			wantAlloc:   1.5,
+			wantPoll:    3,
+		},
+		{
+			name:        "CounterOverflow",
+			contentType: "application/json",
+			body:        `[{"id":"PollCount","type":"counter","delta":9223372036854775807}]`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    models.ErrCodeCounterOverflow,
+			wantAlloc:   1.5,
+			wantPoll:    3,
+		},
+		{
+			name:       "InvalidDryRun",
+			query:      "?dry_run=maybe",
+			body:       `[]`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   models.ErrCodeBadRequest,
+			wantAlloc:  1.5,
+			wantPoll:   3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			storage := repository.NewMemStorage()
+			storage.SetGauge("Alloc", 1.5)
+			storage.AddCounter("PollCount", 3)
+			h := NewHandler(storage, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/import"+tc.query, bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			rec := httptest.NewRecorder()
+			h.HandleImport(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantCode != "" {
+				var resp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				require.Equal(t, tc.wantCode, resp.Code)
+				require.Equal(t, tc.wantIndex, resp.Details["index"])
+			} else {
+				var got ImportResult
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+				require.Equal(t, tc.want, got)
+			}
+
+			alloc, _ := storage.GetGauge("Alloc")
+			require.Equal(t, tc.wantAlloc, alloc)
+			poll, _ := storage.GetCounter("PollCount")
+			require.Equal(t, tc.wantPoll, poll)
+			if tc.name == "CSV" {
+				v, ok := storage.GetGauge("=cmd")
+				require.True(t, ok)
+				require.Equal(t, 1.0, v)
+			}
+		})
+	}
+}
+
+// TestHandleImport_ExportRoundTrip проверяет, что выгрузка CSV одного сервера загружается
+// в пустое хранилище другого без изменений.
+//
+// t — указатель на структуру теста.
+func TestHandleImport_ExportRoundTrip(t *testing.T) {
+	src := repository.NewMemStorage()
+	src.SetGauge("Alloc", 1e-7)
+	src.SetGauge("@host", -2)
+	src.AddCounter("PollCount", 42)
+
+	rec := httptest.NewRecorder()
+	NewHandler(src, nil).HandleExport(rec, httptest.NewRequest(http.MethodGet, "/export?format=csv", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	dst := repository.NewMemStorage()
+	req := httptest.NewRequest(http.MethodPost, "/import", rec.Body)
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	rec = httptest.NewRecorder()
+	NewHandler(dst, nil).HandleImport(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, src.GetAll(), dst.GetAll())
+}
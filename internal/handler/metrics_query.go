@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+)
+
+const (
+	// metricsListDefaultLimit — размер страницы списка метрик по умолчанию.
+	metricsListDefaultLimit = 100
+	// metricsListMaxLimit — наибольший допустимый размер страницы списка метрик.
+	metricsListMaxLimit = 1000
+)
+
+// MetricsPage — ответ API списка метрик.
+//
+// Поля:
+//   - Total: количество метрик, подходящих под фильтры, на всех страницах
+//   - Offset: смещение первой метрики страницы
+//   - Limit: размер страницы
+//   - Metrics: метрики страницы в порядке имени, а затем типа, со временем последнего изменения
+type MetricsPage struct {
+	Total   int              `json:"total"`
+	Offset  int              `json:"offset"`
+	Limit   int              `json:"limit"`
+	Metrics []models.Metrics `json:"metrics"`
+}
+
+// HandleMetricsQuery возвращает страницу списка метрик со значениями и временем последнего изменения.
+//
+// Параметры запроса:
+//   - prefix: только метрики, имя которых начинается с prefix
+//   - type: только метрики типа gauge или counter
+//   - limit: размер страницы (по умолчанию 100, не больше 1000)
+//   - offset: количество пропускаемых метрик
+//   - stale: только метрики, не изменявшиеся дольше заданной длительности (например, 5m)
+//
+// Заменяет разбор HTML-страницы метрик скриптами и панелями мониторинга.
+//
+// @Summary Получить страницу списка метрик
+// @Description Возвращает метрики с фильтрами по префиксу имени и типу, постранично, со временем последнего изменения
+// @Tags Metrics
+// @Produce json
+// @Param prefix query string false "Префикс имени метрики"
+// @Param type query string false "Тип метрики (gauge или counter)"
+// @Param limit query int false "Размер страницы (по умолчанию 100, не больше 1000)"
+// @Param offset query int false "Количество пропускаемых метрик"
+// @Param stale query string false "Только метрики, не изменявшиеся дольше длительности (например, 5m)"
+// @Success 200 {object} MetricsPage "Страница списка метрик"
+// @Failure 400 {object} models.ErrorResponse "Некорректный параметр запроса"
+// @Router /api/metrics [get]
+func (h *Handler) HandleMetricsQuery(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	metricType := query.Get("type")
+	if metricType != "" && metricType != models.Gauge && metricType != models.Counter {
+		WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "invalid type", map[string]string{"type": metricType})
+		return
+	}
+	page := MetricsPage{Limit: metricsListDefaultLimit}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > metricsListMaxLimit {
+			WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "invalid limit", map[string]string{"limit": v})
+			return
+		}
+		page.Limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "invalid offset", map[string]string{"offset": v})
+			return
+		}
+		page.Offset = n
+	}
+	cutoff, ok := staleCutoff(w, r)
+	if !ok {
+		return
+	}
+
+	all := h.storage.GetAll()
+	matched := all[:0]
+	for _, m := range all {
+		if strings.HasPrefix(m.Name, prefix) && (metricType == "" || m.Type == metricType) {
+			matched = append(matched, m)
+		}
+	}
+	matched = h.filterStale(matched, cutoff)
+	page.Total = len(matched)
+	start := min(page.Offset, len(matched))
+	end := min(start+page.Limit, len(matched))
+
+	page.Metrics = h.metricsFromInfo(matched[start:end])
+
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.writeJSONWithHash(w, page); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// staleCutoff разбирает параметр запроса stale — длительность в формате time.ParseDuration —
+// и возвращает момент, после которого метрика не должна была меняться, чтобы считаться устаревшей.
+//
+// Без параметра возвращает нулевое время. Если параметр некорректен, отвечает ошибкой
+// bad_request и возвращает false.
+func staleCutoff(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	v := r.URL.Query().Get("stale")
+	if v == "" {
+		return time.Time{}, true
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "invalid stale", map[string]string{"stale": v})
+		return time.Time{}, false
+	}
+	return time.Now().Add(-d), true
+}
+
+// filterStale оставляет в metrics только метрики, последнее изменение которых было раньше cutoff,
+// — например, от агентов, переставших отправлять данные. Нулевой cutoff не фильтрует.
+func (h *Handler) filterStale(metrics []repository.MetricInfo, cutoff time.Time) []repository.MetricInfo {
+	if cutoff.IsZero() {
+		return metrics
+	}
+	stale := metrics[:0]
+	for _, m := range metrics {
+		if at, ok := h.storage.UpdatedAt(m.Type, m.Name); ok && at.Before(cutoff) {
+			stale = append(stale, m)
+		}
+	}
+	return stale
+}
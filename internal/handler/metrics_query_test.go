@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandleMetricsQuery проверяет фильтры по префиксу, типу и давности изменения,
+// постраничный вывод и проверку параметров запроса списка метрик.
+//
+// t — указатель на структуру теста.
+func TestHandleMetricsQuery(t *testing.T) {
+	tests := []struct {
+		name       string   // Название теста
+		query      string   // Параметры запроса
+		wantStatus int      // Ожидаемый код ответа
+		wantTotal  int      // Ожидаемое количество подходящих метрик
+		wantLimit  int      // Ожидаемый размер страницы
+		wantIDs    []string // Ожидаемые метрики страницы (имя/тип)
+	}{
+		{
+			name:       "All",
+			wantStatus: http.StatusOK,
+			wantTotal:  4,
+			wantLimit:  metricsListDefaultLimit,
+			wantIDs:    []string{"cpu.idle/gauge", "cpu.user/counter", "cpu.user/gauge", "mem.free/gauge"},
+		},
+		{
+			name:       "Prefix",
+			query:      "?prefix=cpu.",
+			wantStatus: http.StatusOK,
+			wantTotal:  3,
+			wantLimit:  metricsListDefaultLimit,
+			wantIDs:    []string{"cpu.idle/gauge", "cpu.user/counter", "cpu.user/gauge"},
+		},
+		{
+			name:       "Type",
+			query:      "?type=gauge&prefix=cpu",
+			wantStatus: http.StatusOK,
+			wantTotal:  2,
+			wantLimit:  metricsListDefaultLimit,
+			wantIDs:    []string{"cpu.idle/gauge", "cpu.user/gauge"},
+		},
+		{
+			name:       "Page",
+			query:      "?limit=2&offset=1",
+			wantStatus: http.StatusOK,
+			wantTotal:  4,
+			wantLimit:  2,
+			wantIDs:    []string{"cpu.user/counter", "cpu.user/gauge"},
+		},
+		{
+			name:       "OffsetPastEnd",
+			query:      "?offset=10",
+			wantStatus: http.StatusOK,
+			wantTotal:  4,
+			wantLimit:  metricsListDefaultLimit,
+			wantIDs:    []string{},
+		},
+		{
+			name:       "Stale",
+			query:      "?stale=50ms",
+			wantStatus: http.StatusOK,
+			wantTotal:  3,
+			wantLimit:  metricsListDefaultLimit,
+			wantIDs:    []string{"cpu.idle/gauge", "cpu.user/gauge", "mem.free/gauge"},
+		},
+		{
+			name:       "NothingStale",
+			query:      "?stale=1h",
+			wantStatus: http.StatusOK,
+			wantLimit:  metricsListDefaultLimit,
+			wantIDs:    []string{},
+		},
+		{name: "InvalidStale", query: "?stale=5", wantStatus: http.StatusBadRequest},
+		{name: "InvalidType", query: "?type=histogram", wantStatus: http.StatusBadRequest},
+		{name: "ZeroLimit", query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "LimitTooLarge", query: "?limit=1001", wantStatus: http.StatusBadRequest},
+		{name: "NegativeOffset", query: "?offset=-1", wantStatus: http.StatusBadRequest},
+	}
+
+	storage := repository.NewMemStorage()
+	before := time.Now()
+	storage.SetGauge("mem.free", 1)
+	storage.SetGauge("cpu.user", 2.5)
+	storage.SetGauge("cpu.idle", 3)
+	time.Sleep(100 * time.Millisecond)
+	storage.AddCounter("cpu.user", 7)
+	h := NewHandler(storage, nil)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/metrics"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			h.HandleMetricsQuery(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantStatus != http.StatusOK {
+				var resp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				require.Equal(t, models.ErrCodeBadRequest, resp.Code)
+				return
+			}
+
+			var page MetricsPage
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&page))
+			require.Equal(t, tc.wantTotal, page.Total)
+			require.Equal(t, tc.wantLimit, page.Limit)
+			ids := make([]string, 0, len(page.Metrics))
+			for _, m := range page.Metrics {
+				ids = append(ids, m.ID+"/"+m.MType)
+				require.False(t, m.UpdatedAt.Before(before), m.ID)
+				if m.ID == "cpu.user" && m.MType == models.Counter {
+					require.Equal(t, int64(7), *m.Delta)
+				}
+			}
+			require.Equal(t, tc.wantIDs, ids)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+)
+
+// RefreshScriptPath — путь к скрипту инкрементального обновления страницы метрик.
+const RefreshScriptPath = "/static/refresh.js"
+
+// refreshScript опрашивает /api/v1/metrics и обновляет значения и время изменения метрик
+// на странице без перезагрузки.
+//
+// Скрипт подключается отдельным файлом, чтобы не противоречить Content-Security-Policy без 'unsafe-inline'.
+const refreshScript = `(function () {
+  "use strict";
+  var script = document.currentScript;
+  var interval = parseInt(script.getAttribute("data-interval"), 10) * 1000;
+  var list = document.getElementById("metrics");
+  if (!list || !(interval > 0)) {
+    return;
+  }
+  function format(m) {
+    return m.type === "counter" ? String(m.delta) : String(m.value);
+  }
+  function stamp(li, updatedAt) {
+    if (!updatedAt) {
+      return;
+    }
+    var time = li.querySelector("time");
+    if (!time) {
+      li.appendChild(document.createTextNode(" "));
+      time = li.appendChild(document.createElement("time"));
+    }
+    var iso = new Date(updatedAt).toISOString().replace(/\.\d+Z$/, "Z");
+    time.setAttribute("datetime", iso);
+    time.textContent = iso;
+  }
+  function apply(metrics) {
+    var items = {};
+    list.querySelectorAll("li[data-metric]").forEach(function (li) {
+      items[li.getAttribute("data-metric")] = li;
+    });
+    (metrics || []).forEach(function (m) {
+      var key = m.type + "/" + m.id;
+      var li = items[key];
+      if (!li) {
+        li = document.createElement("li");
+        li.setAttribute("data-metric", key);
+        li.appendChild(document.createTextNode(m.id + ": "));
+        li.appendChild(document.createElement("span"));
+        list.appendChild(li);
+      }
+      li.querySelector("span").textContent = format(m);
+      stamp(li, m.updated_at);
+    });
+  }
+  function refresh() {
+    fetch("/api/v1/metrics", { cache: "no-store" })
+      .then(function (resp) { return resp.ok ? resp.json() : null; })
+      .then(function (metrics) { if (metrics) { apply(metrics); } })
+      .catch(function () {})
+      .then(function () { setTimeout(refresh, interval); });
+  }
+  setTimeout(refresh, interval);
+})();
+`
+
+// SetPageRefresh задаёт автообновление HTML-страницы метрик.
+//
+// interval — период обновления; 0 отключает автообновление.
+// incremental — true для обновления значений скриптом через JSON API,
+// false для полной перезагрузки страницы (заголовок Refresh).
+func (h *Handler) SetPageRefresh(interval time.Duration, incremental bool) {
+	h.pageRefresh = interval
+	h.pageRefreshIncremental = incremental
+}
+
+// HandleMetricsList возвращает все метрики в формате JSON со временем последнего изменения.
+//
+// Используется для инкрементального обновления HTML-страницы метрик. Параметр запроса
+// stale (например, 5m) оставляет только метрики, не изменявшиеся дольше этой длительности.
+//
+// @Summary Получить все метрики
+// @Description Возвращает список всех метрик в порядке, заданном хранилищем
+// @Tags Metrics
+// @Produce json
+// @Param stale query string false "Только метрики, не изменявшиеся дольше длительности (например, 5m)"
+// @Success 200 {array} models.Metrics "Список метрик"
+// @Failure 400 {object} models.ErrorResponse "Некорректный параметр stale"
+// @Router /api/v1/metrics [get]
+func (h *Handler) HandleMetricsList(w http.ResponseWriter, r *http.Request) {
+	cutoff, ok := staleCutoff(w, r)
+	if !ok {
+		return
+	}
+	metrics := h.metricsFromInfo(h.filterStale(h.storage.GetAll(), cutoff))
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.writeJSONWithHash(w, metrics); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// metricsFromInfo преобразует метрики хранилища в формат JSON API со временем последнего изменения.
+func (h *Handler) metricsFromInfo(all []repository.MetricInfo) []models.Metrics {
+	metrics := make([]models.Metrics, 0, len(all))
+	for _, m := range all {
+		out := models.Metrics{ID: m.Name, MType: m.Type}
+		out.UpdatedAt, _ = h.storage.UpdatedAt(m.Type, m.Name)
+		switch m.Type {
+		case "gauge":
+			v, _ := strconv.ParseFloat(m.Value, 64)
+			out.Value = &v
+		case "counter":
+			d, _ := strconv.ParseInt(m.Value, 10, 64)
+			out.Delta = &d
+		}
+		metrics = append(metrics, out)
+	}
+	return metrics
+}
+
+// HandleRefreshScript отдаёт скрипт инкрементального обновления страницы метрик.
+func (h *Handler) HandleRefreshScript(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(refreshScript))
+}
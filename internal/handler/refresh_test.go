@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandleMetricsPage_Refresh проверяет разметку страницы метрик в разных режимах автообновления.
+//
+// t — указатель на структуру теста.
+func TestHandleMetricsPage_Refresh(t *testing.T) {
+	tests := []struct {
+		name        string
+		interval    time.Duration
+		incremental bool
+		wantHeader  string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "disabled",
+			contains:    []string{`<li data-metric="gauge/g">g: <span>1.5</span> <time datetime="`},
+			notContains: []string{"<head>", RefreshScriptPath},
+		},
+		{
+			name:        "incremental",
+			interval:    10 * time.Second,
+			incremental: true,
+			contains: []string{
+				`<script src="/static/refresh.js" data-interval="10" defer></script>`,
+				`<noscript><meta http-equiv="refresh" content="10"></noscript>`,
+			},
+		},
+		{
+			name:        "reload",
+			interval:    5 * time.Second,
+			wantHeader:  "5",
+			contains:    []string{`<meta http-equiv="refresh" content="5">`},
+			notContains: []string{RefreshScriptPath},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := repository.NewMemStorage()
+			storage.SetGauge("g", 1.5)
+			h := NewHandler(storage, nil)
+			h.SetPageRefresh(tt.interval, tt.incremental)
+
+			rec := httptest.NewRecorder()
+			h.HandleMetricsPage(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, tt.wantHeader, rec.Header().Get("Refresh"))
+			for _, s := range tt.contains {
+				require.Contains(t, rec.Body.String(), s)
+			}
+			for _, s := range tt.notContains {
+				require.NotContains(t, rec.Body.String(), s)
+			}
+		})
+	}
+}
+
+// TestHandleMetricsPage_EscapesNames проверяет экранирование имён метрик в HTML.
+//
+// t — указатель на структуру теста.
+func TestHandleMetricsPage_EscapesNames(t *testing.T) {
+	storage := repository.NewMemStorage()
+	storage.SetGauge(`<script>"x"`, 1)
+	h := NewHandler(storage, nil)
+
+	rec := httptest.NewRecorder()
+	h.HandleMetricsPage(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.NotContains(t, rec.Body.String(), "<script>")
+	require.Contains(t, rec.Body.String(), "&lt;script&gt;&#34;x&#34;")
+}
+
+// TestHandleMetricsList проверяет JSON-список метрик для инкрементального обновления.
+//
+// t — указатель на структуру теста.
+func TestHandleMetricsList(t *testing.T) {
+	storage := repository.NewMemStorage()
+	storage.SetGauge("g", 1.5)
+	storage.AddCounter("c", 3)
+	h := NewHandler(storage, nil)
+
+	rec := httptest.NewRecorder()
+	h.HandleMetricsList(rec, httptest.NewRequest(http.MethodGet, "/api/v1/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+	var got []models.Metrics
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 2)
+	require.Equal(t, "c", got[0].ID)
+	require.Equal(t, int64(3), *got[0].Delta)
+	require.Equal(t, "g", got[1].ID)
+	require.Equal(t, 1.5, *got[1].Value)
+	require.False(t, got[1].UpdatedAt.IsZero())
+
+	time.Sleep(20 * time.Millisecond)
+	storage.SetGauge("g", 2)
+	rec = httptest.NewRecorder()
+	h.HandleMetricsList(rec, httptest.NewRequest(http.MethodGet, "/api/v1/metrics?stale=10ms", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	got = nil
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	require.Equal(t, "c", got[0].ID)
+
+	rec = httptest.NewRecorder()
+	h.HandleMetricsList(rec, httptest.NewRequest(http.MethodGet, "/api/v1/metrics?stale=-1m", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.HandleRefreshScript(rec, httptest.NewRequest(http.MethodGet, RefreshScriptPath, nil))
+	require.Equal(t, "text/javascript; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), "/api/v1/metrics")
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/RoGogDBD/metric-alerter/pkg/pool"
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// maxPooledBufferSize — максимальная ёмкость буфера, возвращаемого в пул.
+//
+// Буферы, разросшиеся на редких больших ответах, не удерживаются в памяти.
+const maxPooledBufferSize = 1 << 20
+
+// responseBuffer — переиспользуемый буфер ответа с привязанным к нему json.Encoder.
+type responseBuffer struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+// responseBuffers — пул буферов ответа для writeJSONWithHash и страниц со списком метрик.
+var responseBuffers = pool.New(newResponseBuffer)
+
+// newResponseBuffer создаёт буфер ответа с кодировщиком JSON.
+func newResponseBuffer() *responseBuffer {
+	b := &responseBuffer{}
+	b.enc = json.NewEncoder(&b.buf)
+	return b
+}
+
+// Reset очищает буфер перед возвратом в пул.
+func (b *responseBuffer) Reset() {
+	if b.buf.Cap() > maxPooledBufferSize {
+		b.buf = bytes.Buffer{}
+		return
+	}
+	b.buf.Reset()
+}
+
+// encodeJSON сериализует v в буфер и возвращает результат без завершающего перевода строки,
+// совпадающий с выводом json.Marshal. Метрики (models.Metrics, models.MetricsBatch)
+// сериализуются без рефлексии и повторной проверки вывода кодировщиком.
+//
+// Возвращённый срез действителен до возврата буфера в пул.
+func (b *responseBuffer) encodeJSON(v interface{}) ([]byte, error) {
+	if m, ok := v.(easyjson.Marshaler); ok {
+		w := jwriter.Writer{}
+		m.MarshalEasyJSON(&w)
+		if w.Error != nil {
+			return nil, w.Error
+		}
+		if _, err := w.DumpTo(&b.buf); err != nil {
+			return nil, err
+		}
+		return b.buf.Bytes(), nil
+	}
+	if err := b.enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.buf.Bytes(), []byte("\n")), nil
+}
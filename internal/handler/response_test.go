@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriteJSONWithHash_MatchesMarshal проверяет, что ответ из пула совпадает с json.Marshal
+// и подпись вычисляется по тому же телу.
+//
+// t — указатель на структуру теста.
+func TestWriteJSONWithHash_MatchesMarshal(t *testing.T) {
+	h := NewHandler(repository.NewMemStorage(), nil)
+	h.SetKey("secret")
+
+	values := []interface{}{
+		map[string]int{"a": 1},
+		[]string{"<b>", "&"},
+		struct {
+			ID string `json:"id"`
+		}{ID: "x"},
+	}
+	for _, v := range values {
+		want, err := json.Marshal(v)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		require.NoError(t, h.writeJSONWithHash(rec, v))
+		require.Equal(t, string(want), rec.Body.String())
+		require.Equal(t, h.computeHash(want), rec.Header().Get("HashSHA256"))
+	}
+}
+
+// TestResponseBuffer_ResetDropsLargeBuffers проверяет, что разросшиеся буферы не удерживаются пулом.
+//
+// t — указатель на структуру теста.
+func TestResponseBuffer_ResetDropsLargeBuffers(t *testing.T) {
+	b := newResponseBuffer()
+	b.buf.WriteString(strings.Repeat("x", maxPooledBufferSize+1))
+	b.Reset()
+	require.Zero(t, b.buf.Cap())
+
+	body, err := b.encodeJSON([]int{1, 2})
+	require.NoError(t, err)
+	require.Equal(t, "[1,2]", string(body))
+}
+
+// BenchmarkHandleMetricsPage измеряет выделения памяти при формировании страницы метрик.
+func BenchmarkHandleMetricsPage(b *testing.B) {
+	storage := repository.NewMemStorage()
+	for i := 0; i < 100; i++ {
+		storage.SetGauge("gauge"+strconv.Itoa(i), float64(i))
+	}
+	h := NewHandler(storage, nil)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h.HandleMetricsPage(httptest.NewRecorder(), req)
+	}
+}
+
+// BenchmarkWriteJSONWithHash измеряет выделения памяти при записи JSON-ответа.
+func BenchmarkWriteJSONWithHash(b *testing.B) {
+	h := NewHandler(repository.NewMemStorage(), nil)
+	h.SetKey("secret")
+	data := make([]map[string]interface{}, 100)
+	for i := range data {
+		data[i] = map[string]interface{}{"id": "m" + strconv.Itoa(i), "type": "gauge", "value": float64(i)}
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = h.writeJSONWithHash(httptest.NewRecorder(), data)
+	}
+}
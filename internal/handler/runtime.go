@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// auditToggle — менеджер аудита, доставку событий которого можно приостановить во время работы.
+type auditToggle interface {
+	SetEnabled(enabled bool)
+	Enabled() bool
+}
+
+// runtimeSettings — настройки сервера, изменяемые без перезапуска.
+type runtimeSettings struct {
+	AuditEnabled bool   `json:"audit_enabled"`
+	LogLevel     string `json:"log_level"`
+}
+
+// runtimeUpdate — изменение настроек сервера; незаданные поля не меняются.
+type runtimeUpdate struct {
+	AuditEnabled *bool   `json:"audit_enabled"`
+	LogLevel     *string `json:"log_level"`
+}
+
+// SetLogLevel задаёт уровень логирования, изменяемый через /admin/runtime.
+func (h *Handler) SetLogLevel(level zap.AtomicLevel) {
+	h.logLevel = &level
+}
+
+// runtimeState возвращает текущие значения изменяемых настроек.
+func (h *Handler) runtimeState() runtimeSettings {
+	s := runtimeSettings{AuditEnabled: h.auditManager != nil}
+	if t, ok := h.auditManager.(auditToggle); ok {
+		s.AuditEnabled = t.Enabled()
+	}
+	if h.logLevel != nil {
+		s.LogLevel = h.logLevel.Level().String()
+	}
+	return s
+}
+
+// HandleRuntime возвращает настройки сервера, изменяемые без перезапуска.
+//
+// @Summary Получить изменяемые настройки
+// @Description Возвращает состояние доставки событий аудита и текущий уровень логирования
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} runtimeSettings "Текущие настройки"
+// @Router /admin/runtime [get]
+func (h *Handler) HandleRuntime(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.writeJSONWithHash(w, h.runtimeState()); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// HandleRuntimeUpdate включает или приостанавливает доставку событий аудита и меняет уровень
+// логирования без перезапуска сервера.
+//
+// Каждое изменение фиксируется событием аудита AuditRuntimeChange: при отключении аудита —
+// до отключения, при включении — после.
+//
+// @Summary Изменить настройки без перезапуска
+// @Description Принимает audit_enabled и/или log_level (debug, info, warn, error); незаданные поля не меняются
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param settings body runtimeUpdate true "Изменяемые настройки"
+// @Success 200 {object} runtimeSettings "Настройки после изменения"
+// @Failure 400 {object} models.ErrorResponse "Некорректный запрос или настройка недоступна"
+// @Router /admin/runtime [patch]
+func (h *Handler) HandleRuntimeUpdate(w http.ResponseWriter, r *http.Request) {
+	var req runtimeUpdate
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeInvalidJSON, "invalid json")
+		return
+	}
+
+	var level zapcore.Level
+	if req.LogLevel != nil {
+		if h.logLevel == nil {
+			WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "log level is not adjustable")
+			return
+		}
+		var err error
+		if level, err = zapcore.ParseLevel(*req.LogLevel); err != nil {
+			WriteErrorDetails(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "invalid log level", map[string]string{"log_level": *req.LogLevel})
+			return
+		}
+	}
+	toggle, ok := h.auditManager.(auditToggle)
+	if req.AuditEnabled != nil && !ok {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "audit is not configured")
+		return
+	}
+
+	if req.LogLevel != nil {
+		h.logLevel.SetLevel(level)
+		log.Printf("Log level set to %s via %s", level, r.URL.Path)
+	}
+	if req.AuditEnabled != nil {
+		if !*req.AuditEnabled {
+			h.auditRuntimeChange(r, "audit_enabled=false")
+		}
+		toggle.SetEnabled(*req.AuditEnabled)
+		log.Printf("Audit delivery enabled=%t via %s", *req.AuditEnabled, r.URL.Path)
+	}
+	if req.LogLevel != nil {
+		h.auditRuntimeChange(r, "log_level="+level.String())
+	}
+	if req.AuditEnabled != nil && *req.AuditEnabled {
+		h.auditRuntimeChange(r, "audit_enabled=true")
+	}
+
+	if err := h.writeJSONWithHash(w, h.runtimeState()); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// auditRuntimeChange отправляет событие аудита об изменении настройки change ("имя=значение").
+func (h *Handler) auditRuntimeChange(r *http.Request, change string) {
+	if h.auditManager == nil {
+		return
+	}
+	event := h.newAuditEvent(r)
+	event.Metrics = []string{change}
+	event.Event = models.AuditRuntimeChange
+	h.auditManager.Notify(event)
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// toggleAudit — менеджер аудита с приостанавливаемой доставкой для тестов.
+type toggleAudit struct {
+	recordingAudit
+	disabled bool
+}
+
+func (a *toggleAudit) SetEnabled(enabled bool) { a.disabled = !enabled }
+func (a *toggleAudit) Enabled() bool           { return !a.disabled }
+func (a *toggleAudit) Notify(event models.AuditEvent) {
+	if !a.disabled {
+		a.recordingAudit.Notify(event)
+	}
+}
+
+// TestHandler_HandleRuntimeUpdate проверяет изменение уровня логирования и доставки аудита без перезапуска.
+//
+// t — указатель на структуру теста.
+func TestHandler_HandleRuntimeUpdate(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantCode    int
+		wantLevel   zapcore.Level
+		wantAudit   bool
+		wantChanges []string
+	}{
+		{"raise log level", `{"log_level":"debug"}`, http.StatusOK, zapcore.DebugLevel, true, []string{"log_level=debug"}},
+		{"disable audit", `{"audit_enabled":false}`, http.StatusOK, zapcore.InfoLevel, false, []string{"audit_enabled=false"}},
+		{"both", `{"audit_enabled":true,"log_level":"warn"}`, http.StatusOK, zapcore.WarnLevel, true, []string{"log_level=warn", "audit_enabled=true"}},
+		{"empty update", `{}`, http.StatusOK, zapcore.InfoLevel, true, nil},
+		{"invalid level", `{"log_level":"loud","audit_enabled":false}`, http.StatusBadRequest, zapcore.InfoLevel, true, nil},
+		{"unknown field", `{"level":"debug"}`, http.StatusBadRequest, zapcore.InfoLevel, true, nil},
+		{"invalid json", `{`, http.StatusBadRequest, zapcore.InfoLevel, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &toggleAudit{}
+			level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+			h := NewHandler(repository.NewMemStorage(), nil)
+			h.SetAuditManager(audit)
+			h.SetLogLevel(level)
+
+			w := httptest.NewRecorder()
+			h.HandleRuntimeUpdate(w, httptest.NewRequest(http.MethodPatch, "/admin/runtime", bytes.NewBufferString(tt.body)))
+
+			require.Equal(t, tt.wantCode, w.Code)
+			require.Equal(t, tt.wantLevel, level.Level())
+			require.Equal(t, tt.wantAudit, audit.Enabled())
+			var changes []string
+			for _, e := range audit.events {
+				require.Equal(t, models.AuditRuntimeChange, e.Event)
+				changes = append(changes, e.Metrics...)
+			}
+			require.Equal(t, tt.wantChanges, changes)
+			if w.Code == http.StatusOK {
+				var got runtimeSettings
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+				require.Equal(t, runtimeSettings{AuditEnabled: tt.wantAudit, LogLevel: tt.wantLevel.String()}, got)
+			}
+		})
+	}
+}
+
+// TestHandler_HandleRuntimeUpdateUnavailable проверяет отказ, если настройка не поддерживает изменение.
+//
+// t — указатель на структуру теста.
+func TestHandler_HandleRuntimeUpdateUnavailable(t *testing.T) {
+	h := NewHandler(repository.NewMemStorage(), nil)
+	for _, body := range []string{`{"log_level":"debug"}`, `{"audit_enabled":false}`} {
+		w := httptest.NewRecorder()
+		h.HandleRuntimeUpdate(w, httptest.NewRequest(http.MethodPatch, "/admin/runtime", bytes.NewBufferString(body)))
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/RoGogDBD/metric-alerter/internal/compression"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// Способы аутентификации агентов, которые сервер определяет по своим ключам.
+const (
+	AuthModeHMAC    = "hmac-sha256" // Подпись тела заголовком HashSHA256
+	AuthModeEd25519 = "ed25519"     // Подпись тела ключом Ed25519 агента
+	AuthModeEnroll  = "enroll"      // Регистрация по одноразовому токену с выдачей персонального ключа
+)
+
+// Schema — ответ API схемы: что поддерживает сервер и какие у него ограничения.
+//
+// Клиентские SDK и агент настраиваются по схеме под версию сервера, с которым работают.
+//
+// Поля:
+//   - MetricTypes: поддерживаемые типы метрик
+//   - MaxBatchSize: максимальное число метрик в батче (0 — без ограничения)
+//   - MaxNameLength: максимальная длина имени метрики (0 — без ограничения)
+//   - Encodings: поддерживаемые значения Content-Encoding тела запроса
+//   - Encryption: сервер принимает тела, зашифрованные открытым ключом (заголовок X-Encrypted)
+//   - AuthModes: способы аутентификации и подписи запросов (пусто — аутентификация не требуется)
+type Schema struct {
+	MetricTypes   []string `json:"metric_types"`
+	MaxBatchSize  int      `json:"max_batch_size"`
+	MaxNameLength int      `json:"max_name_length"`
+	Encodings     []string `json:"encodings"`
+	Encryption    bool     `json:"encryption"`
+	AuthModes     []string `json:"auth_modes"`
+}
+
+// SetAuthModes задаёт способы аутентификации ролевого доступа для схемы API (например "api-key", "jwt").
+func (h *Handler) SetAuthModes(modes []string) {
+	h.authModes = modes
+}
+
+// schema возвращает схему API по текущим настройкам обработчика.
+func (h *Handler) schema() Schema {
+	s := Schema{
+		MetricTypes: []string{models.Gauge, models.Counter},
+		Encodings:   []string{"gzip"},
+		Encryption:  h.cryptoKey != nil,
+		AuthModes:   []string{},
+	}
+	if h.dictionaries != nil {
+		s.Encodings = append(s.Encodings, compression.Encoding)
+	}
+	if h.key != "" {
+		s.AuthModes = append(s.AuthModes, AuthModeHMAC)
+	}
+	if h.verifyKey != nil {
+		s.AuthModes = append(s.AuthModes, AuthModeEd25519)
+	}
+	if h.agents != nil {
+		s.AuthModes = append(s.AuthModes, AuthModeEnroll)
+	}
+	s.AuthModes = append(s.AuthModes, h.authModes...)
+	return s
+}
+
+// HandleSchema возвращает схему API: типы метрик, ограничения, кодировки и способы аутентификации.
+//
+// @Summary Получить схему API
+// @Description Возвращает поддерживаемые типы метрик, ограничения, кодировки и способы аутентификации сервера
+// @Tags Health
+// @Produce json
+// @Success 200 {object} Schema "Схема API"
+// @Router /api/v1/schema [get]
+func (h *Handler) HandleSchema(w http.ResponseWriter, _ *http.Request) {
+	if err := h.writeJSONWithHash(w, h.schema()); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
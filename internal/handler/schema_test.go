@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/compression"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandleSchema проверяет, что схема API отражает кодировки и способы аутентификации сервера.
+//
+// t — указатель на структуру теста.
+func TestHandleSchema(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string         // Название теста
+		setup         func(*Handler) // Настройка обработчика
+		wantEncodings []string       // Ожидаемые кодировки
+		wantAuthModes []string       // Ожидаемые способы аутентификации
+	}{
+		{name: "Default", setup: func(*Handler) {}, wantEncodings: []string{"gzip"}, wantAuthModes: []string{}},
+		{
+			name: "Configured",
+			setup: func(h *Handler) {
+				h.SetDictionaries(compression.NewStore(compression.DefaultStoreLimit))
+				h.SetKey("secret")
+				h.SetVerifyKey(pub)
+				h.SetAuthModes([]string{"api-key", "jwt"})
+			},
+			wantEncodings: []string{"gzip", compression.Encoding},
+			wantAuthModes: []string{AuthModeHMAC, AuthModeEd25519, "api-key", "jwt"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(repository.NewMemStorage(), nil)
+			tc.setup(h)
+			rec := httptest.NewRecorder()
+			h.HandleSchema(rec, httptest.NewRequest(http.MethodGet, "/api/v1/schema", nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var schema Schema
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &schema))
+			require.Equal(t, []string{"gauge", "counter"}, schema.MetricTypes)
+			require.Equal(t, tc.wantEncodings, schema.Encodings)
+			require.Equal(t, tc.wantAuthModes, schema.AuthModes)
+			require.Zero(t, schema.MaxBatchSize)
+			require.Zero(t, schema.MaxNameLength)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/version"
+)
+
+// serverStatus — краткое состояние работающего сервера.
+//
+// Поля:
+//   - Version: версия сборки
+//   - UptimeSeconds: время работы сервера в секундах
+//   - Goroutines: количество горутин
+//   - Storage: количество метрик по типам и номер поколения хранилища
+type serverStatus struct {
+	Version       string       `json:"version"`
+	UptimeSeconds int64        `json:"uptime_seconds"`
+	Goroutines    int          `json:"goroutines"`
+	Storage       storageStats `json:"storage"`
+}
+
+// HandleStatus возвращает краткое состояние сервера: версию, время работы, число горутин и метрик.
+//
+// @Summary Получить состояние сервера
+// @Description Возвращает версию, время работы, количество горутин и статистику хранилища
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} serverStatus "Состояние сервера"
+// @Router /status [get]
+func (h *Handler) HandleStatus(w http.ResponseWriter, _ *http.Request) {
+	status := serverStatus{
+		Version:       version.Get().Version,
+		UptimeSeconds: int64(time.Since(h.started).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		Storage:       h.collectStorageStats(),
+	}
+	if err := h.writeJSONWithHash(w, status); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
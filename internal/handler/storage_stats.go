@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+)
+
+// storageStatsProvider реализуется хранилищами, собирающими статистику операций (см. repository.InstrumentedStorage).
+type storageStatsProvider interface {
+	Stats() []repository.StorageOpStats
+}
+
+// HandleStorageStats возвращает количество вызовов и гистограммы задержек операций хранилища.
+//
+// Если хранилище не собирает статистику, возвращается пустой список.
+//
+// @Summary Получить статистику операций хранилища
+// @Description Возвращает количество вызовов и гистограммы задержек каждого метода хранилища
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} repository.StorageOpStats "Статистика операций"
+// @Router /admin/storage-stats [get]
+func (h *Handler) HandleStorageStats(w http.ResponseWriter, _ *http.Request) {
+	stats := []repository.StorageOpStats{}
+	if p, ok := h.storage.(storageStatsProvider); ok {
+		stats = p.Stats()
+	}
+	if err := h.writeJSONWithHash(w, stats); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
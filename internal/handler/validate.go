@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// metricError — ошибка проверки метрики из запроса.
+//
+// Поля:
+//   - status: код статуса HTTP
+//   - code: машинно-читаемый код ошибки (models.ErrCode*)
+//   - message: описание ошибки
+//   - details: имя метрики и её позиция в пакете
+type metricError struct {
+	status  int
+	code    string
+	message string
+	details map[string]string
+}
+
+// write отвечает клиенту ошибкой e.
+func (e *metricError) write(w http.ResponseWriter, r *http.Request) {
+	WriteErrorDetails(w, r, e.status, e.code, e.message, e.details)
+}
+
+// validateMetric проверяет, что у метрики задано имя, известный тип и значение для этого типа.
+//
+// Возвращает nil, если метрику можно применить к хранилищу.
+func validateMetric(m models.Metrics) *metricError {
+	details := map[string]string{"id": m.ID}
+	switch {
+	case m.ID == "":
+		return &metricError{http.StatusBadRequest, models.ErrCodeInvalidMetric, "metric id is required", nil}
+	case m.MType == models.Gauge && m.Value == nil:
+		return &metricError{http.StatusBadRequest, models.ErrCodeInvalidMetric, "missing value for gauge", details}
+	case m.MType == models.Counter && m.Delta == nil:
+		return &metricError{http.StatusBadRequest, models.ErrCodeInvalidMetric, "missing delta for counter", details}
+	case m.MType != models.Gauge && m.MType != models.Counter:
+		return &metricError{http.StatusNotImplemented, models.ErrCodeUnknownMetricType, "unknown metric type", details}
+	}
+	return nil
+}
+
+// validateBatch проверяет пакет метрик целиком до применения к хранилищу,
+// чтобы некорректный пакет не был применён частично.
+//
+// Пустой пакет ([] или null) отклоняется с кодом empty_batch.
+// В details ошибки отдельной метрики добавляется её индекс в пакете.
+func validateBatch(metrics []models.Metrics) *metricError {
+	if len(metrics) == 0 {
+		return &metricError{http.StatusBadRequest, models.ErrCodeEmptyBatch, "empty batch", nil}
+	}
+	for i, m := range metrics {
+		if e := validateMetric(m); e != nil {
+			return e.at(i)
+		}
+	}
+	return nil
+}
+
+// at добавляет в details ошибки индекс метрики i в пакете и возвращает e.
+func (e *metricError) at(i int) *metricError {
+	if e.details == nil {
+		e.details = make(map[string]string)
+	}
+	e.details["index"] = strconv.Itoa(i)
+	return e
+}
+
+// applyMetric применяет проверенную validateMetric метрику к хранилищу.
+//
+// Приращение счётчика учитывается как вклад источника source (см. SetCounterSources).
+func (h *Handler) applyMetric(m models.Metrics, source string) {
+	switch m.MType {
+	case models.Gauge:
+		h.storage.SetGauge(m.ID, *m.Value)
+	case models.Counter:
+		h.storage.AddCounter(m.ID, *m.Delta)
+		if h.sources != nil {
+			h.sources.Add(m.ID, source, *m.Delta)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandler_EmptyInput проверяет единообразные ответы на пустые тела, пустые пакеты и пустые имена метрик:
+// 400 без изменения хранилища и без событий аудита.
+//
+// t — указатель на структуру теста.
+func TestHandler_EmptyInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		handle   func(h *Handler) http.HandlerFunc
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{"update: empty body", func(h *Handler) http.HandlerFunc { return h.HandleUpdateJSON }, ``, http.StatusBadRequest, models.ErrCodeEmptyBody},
+		{"update: null", func(h *Handler) http.HandlerFunc { return h.HandleUpdateJSON }, `null`, http.StatusBadRequest, models.ErrCodeInvalidMetric},
+		{"update: empty object", func(h *Handler) http.HandlerFunc { return h.HandleUpdateJSON }, `{}`, http.StatusBadRequest, models.ErrCodeInvalidMetric},
+		{"update: empty id", func(h *Handler) http.HandlerFunc { return h.HandleUpdateJSON }, `{"id":"","type":"gauge","value":1}`, http.StatusBadRequest, models.ErrCodeInvalidMetric},
+		{"batch: empty body", func(h *Handler) http.HandlerFunc { return h.HandlerUpdateBatchJSON }, ``, http.StatusBadRequest, models.ErrCodeEmptyBody},
+		{"batch: empty array", func(h *Handler) http.HandlerFunc { return h.HandlerUpdateBatchJSON }, `[]`, http.StatusBadRequest, models.ErrCodeEmptyBatch},
+		{"batch: null", func(h *Handler) http.HandlerFunc { return h.HandlerUpdateBatchJSON }, `null`, http.StatusBadRequest, models.ErrCodeEmptyBatch},
+		{"batch: empty id after valid metric", func(h *Handler) http.HandlerFunc { return h.HandlerUpdateBatchJSON },
+			`[{"id":"ok","type":"gauge","value":1},{"id":"","type":"counter","delta":1}]`, http.StatusBadRequest, models.ErrCodeInvalidMetric},
+		{"batch: unknown type after valid metric", func(h *Handler) http.HandlerFunc { return h.HandlerUpdateBatchJSON },
+			`[{"id":"ok","type":"counter","delta":1},{"id":"x","type":"histogram"}]`, http.StatusNotImplemented, models.ErrCodeUnknownMetricType},
+		{"value: empty body", func(h *Handler) http.HandlerFunc { return h.HandleGetMetricJSON }, ``, http.StatusBadRequest, models.ErrCodeEmptyBody},
+		{"value: empty id", func(h *Handler) http.HandlerFunc { return h.HandleGetMetricJSON }, `{"id":"","type":"gauge"}`, http.StatusBadRequest, models.ErrCodeInvalidMetric},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := repository.NewMemStorage()
+			audit := &recordingAudit{}
+			h := NewHandler(storage, nil)
+			h.SetAuditManager(audit)
+
+			w := httptest.NewRecorder()
+			tt.handle(h)(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tt.body)))
+
+			require.Equal(t, tt.wantCode, w.Code)
+			var resp models.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, tt.wantErr, resp.Code)
+			require.Empty(t, storage.GetAll())
+			require.Empty(t, audit.events)
+		})
+	}
+}
+
+// TestHandler_HandleUpdateEmptyName проверяет, что обновление по URL с пустым именем отклоняется с 400.
+//
+// t — указатель на структуру теста.
+func TestHandler_HandleUpdateEmptyName(t *testing.T) {
+	storage := repository.NewMemStorage()
+	h := NewHandler(storage, nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("type", "gauge")
+	rctx.URLParams.Add("name", "")
+	rctx.URLParams.Add("value", "1")
+	r := httptest.NewRequest(http.MethodPost, "/update/gauge//1", nil)
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	h.HandleUpdate(w, r)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Empty(t, storage.GetAll())
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// HandleGetMetricsJSON возвращает значения нескольких метрик одним ответом.
+//
+// Ожидает в теле запроса массив метрик с полями id и type (models.MetricsBatch) и возвращает
+// найденные метрики со значениями в порядке запроса. Отсутствующие в хранилище метрики
+// в ответ не попадают, поэтому отсутствие одной метрики не мешает получить остальные.
+// Пустой массив отклоняется с кодом empty_batch, метрика без имени или с неизвестным
+// типом — как в HandleGetMetricJSON, с индексом в details.
+//
+// @Summary Получить значения нескольких метрик
+// @Description Возвращает найденные метрики из списка id и type в порядке запроса; отсутствующие метрики пропускаются
+// @Tags Metrics
+// @Accept json
+// @Produce json
+// @Param metrics body []models.Metrics true "Запрашиваемые метрики (id и type обязательны)"
+// @Success 200 {array} models.Metrics "Найденные метрики со значениями"
+// @Failure 400 {object} models.ErrorResponse "Некорректный JSON, пустой список или метрика без имени"
+// @Failure 501 {object} models.ErrorResponse "Неизвестный тип метрики"
+// @Router /values/ [post]
+func (h *Handler) HandleGetMetricsJSON(w http.ResponseWriter, r *http.Request) {
+	var req models.MetricsBatch
+	if err := decodeRequestBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	if len(req) == 0 {
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeEmptyBatch, "empty batch")
+		return
+	}
+	for i, m := range req {
+		var e *metricError
+		switch {
+		case m.ID == "":
+			e = &metricError{http.StatusBadRequest, models.ErrCodeInvalidMetric, ErrEmptyMetricName.Error(), nil}
+		case m.MType != models.Gauge && m.MType != models.Counter:
+			e = &metricError{http.StatusNotImplemented, models.ErrCodeUnknownMetricType, "unknown metric type", map[string]string{"id": m.ID}}
+		}
+		if e != nil {
+			e.at(i).write(w, r)
+			return
+		}
+	}
+
+	resp := make(models.MetricsBatch, 0, len(req))
+	names := make([]string, 0, len(req))
+	for _, m := range req {
+		if found, ok := h.lookupMetric(m.ID, m.MType); ok {
+			resp = append(resp, found)
+			names = append(names, m.ID)
+		}
+	}
+	if err := h.writeJSONWithHash(w, resp); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+	if len(names) > 0 {
+		h.sendAuditEvent(r, models.AuditOpRead, names)
+	}
+}
+
+// lookupMetric возвращает метрику с именем id и типом mType со значением и временем
+// последнего изменения из хранилища.
+//
+// Возвращает false, если метрика не найдена или тип неизвестен.
+func (h *Handler) lookupMetric(id, mType string) (models.Metrics, bool) {
+	m := models.Metrics{ID: id, MType: mType}
+	m.UpdatedAt, _ = h.storage.UpdatedAt(mType, id)
+	switch mType {
+	case models.Gauge:
+		val, ok := h.storage.GetGauge(id)
+		if !ok {
+			return m, false
+		}
+		m.Value = &val
+	case models.Counter:
+		delta, ok := h.storage.GetCounter(id)
+		if !ok {
+			return m, false
+		}
+		m.Delta = &delta
+	default:
+		return m, false
+	}
+	return m, true
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandleGetMetricsJSON проверяет получение нескольких метрик одним запросом: порядок
+// ответа, пропуск отсутствующих метрик и ошибки проверки запроса с индексом метрики.
+//
+// t — указатель на структуру теста.
+func TestHandleGetMetricsJSON(t *testing.T) {
+	tests := []struct {
+		name       string   // Название теста
+		body       string   // Тело запроса
+		wantStatus int      // Ожидаемый код ответа
+		wantCode   string   // Ожидаемый код ошибки
+		wantIndex  string   // Ожидаемый индекс метрики в details ошибки
+		wantIDs    []string // Ожидаемые имена метрик в ответе
+	}{
+		{
+			name:       "RequestOrder",
+			body:       `[{"id":"PollCount","type":"counter"},{"id":"Alloc","type":"gauge"}]`,
+			wantStatus: http.StatusOK,
+			wantIDs:    []string{"PollCount", "Alloc"},
+		},
+		{
+			name:       "MissingSkipped",
+			body:       `[{"id":"missing","type":"gauge"},{"id":"Alloc","type":"gauge"},{"id":"Alloc","type":"counter"}]`,
+			wantStatus: http.StatusOK,
+			wantIDs:    []string{"Alloc"},
+		},
+		{
+			name:       "NoneFound",
+			body:       `[{"id":"missing","type":"gauge"}]`,
+			wantStatus: http.StatusOK,
+			wantIDs:    []string{},
+		},
+		{name: "Empty", body: `[]`, wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeEmptyBatch},
+		{name: "EmptyBody", wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeEmptyBody},
+		{name: "InvalidJSON", body: `{"id":"Alloc"}`, wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeInvalidJSON},
+		{
+			name:       "EmptyID",
+			body:       `[{"id":"Alloc","type":"gauge"},{"id":"","type":"gauge"}]`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   models.ErrCodeInvalidMetric,
+			wantIndex:  "1",
+		},
+		{
+			name:       "UnknownType",
+			body:       `[{"id":"Alloc","type":"histogram"}]`,
+			wantStatus: http.StatusNotImplemented,
+			wantCode:   models.ErrCodeUnknownMetricType,
+			wantIndex:  "0",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			storage := repository.NewMemStorage()
+			storage.SetGauge("Alloc", 1.5)
+			storage.AddCounter("PollCount", 3)
+			h := NewHandler(storage, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/values/", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			h.HandleGetMetricsJSON(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantCode != "" {
+				var resp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				require.Equal(t, tc.wantCode, resp.Code)
+				require.Equal(t, tc.wantIndex, resp.Details["index"])
+				return
+			}
+
+			var resp []models.Metrics
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			ids := make([]string, 0, len(resp))
+			for _, m := range resp {
+				ids = append(ids, m.ID)
+				require.False(t, m.UpdatedAt.IsZero(), m.ID)
+				switch m.MType {
+				case models.Gauge:
+					require.Equal(t, 1.5, *m.Value)
+				case models.Counter:
+					require.Equal(t, int64(3), *m.Delta)
+				}
+			}
+			require.Equal(t, tc.wantIDs, ids)
+		})
+	}
+}
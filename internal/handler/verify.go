@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+
+	"github.com/RoGogDBD/metric-alerter/internal/crypto"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// signatureVerifier проверяет подписи тела запроса по мере его чтения.
+//
+// Тело передаётся через Write, например io.TeeReader при декодировании JSON, поэтому
+// HMAC вычисляется потоково, без отдельного буфера с копией тела. Подпись Ed25519
+// проверяется только по сообщению целиком, поэтому при заданном ключе проверки
+// (см. SetVerifyKey) тело всё же копируется.
+//
+// Правила проверки описаны у verifyAgentHash.
+type signatureVerifier struct {
+	mac       hash.Hash         // HMAC тела (nil — подпись HMAC не проверяется)
+	wantMAC   string            // Подпись HMAC из заголовка HashSHA256
+	body      *bytes.Buffer     // Копия тела для Ed25519 (nil — подпись Ed25519 не проверяется)
+	wantSig   string            // Подпись Ed25519 из заголовка crypto.SignatureHeader
+	verifyKey ed25519.PublicKey // Открытый ключ Ed25519
+	rejected  bool              // Подпись заведомо неверна: агент неизвестен или нет обязательной подписи HMAC
+}
+
+// newSignatureVerifier создаёт проверку подписей тела запроса r.
+func (h *Handler) newSignatureVerifier(r *http.Request) *signatureVerifier {
+	v := &signatureVerifier{wantMAC: r.Header.Get("HashSHA256")}
+	if h.verifyKey != nil {
+		v.body = &bytes.Buffer{}
+		v.wantSig = r.Header.Get(crypto.SignatureHeader)
+		v.verifyKey = h.verifyKey
+	}
+
+	agentID := r.Header.Get(models.AgentIDHeader)
+	if agentID == "" {
+		// Общий ключ: без ключа или без подписи тело не проверяется.
+		if h.key != "" && v.wantMAC != "" {
+			v.mac = hmac.New(sha256.New, []byte(h.key))
+		}
+		return v
+	}
+	if h.agents == nil || v.wantMAC == "" {
+		v.rejected = true
+		return v
+	}
+	key, ok := h.agents.Key(agentID)
+	if !ok {
+		v.rejected = true
+		return v
+	}
+	v.mac = hmac.New(sha256.New, []byte(key))
+	return v
+}
+
+// Write передаёт очередную часть тела в вычисление подписей.
+func (v *signatureVerifier) Write(p []byte) (int, error) {
+	if v.mac != nil {
+		v.mac.Write(p)
+	}
+	if v.body != nil {
+		v.body.Write(p)
+	}
+	return len(p), nil
+}
+
+// required сообщает, нужно ли проверять подпись по телу запроса. Если нет, тело
+// можно применять по мере разбора (см. updateBatchStream).
+func (v *signatureVerifier) required() bool {
+	return v.rejected || v.mac != nil || v.body != nil
+}
+
+// reader возвращает читатель тела src, передающий прочитанные данные в проверку.
+func (v *signatureVerifier) reader(src io.Reader) io.Reader {
+	if v.mac == nil && v.body == nil {
+		return src
+	}
+	return io.TeeReader(src, v)
+}
+
+// valid сообщает, верны ли подписи тела, переданного через Write целиком.
+func (v *signatureVerifier) valid() bool {
+	if v.rejected {
+		return false
+	}
+	if v.body != nil && !crypto.Verify(v.body.Bytes(), v.wantSig, v.verifyKey) {
+		return false
+	}
+	if v.mac == nil {
+		return true
+	}
+	return hmac.Equal([]byte(v.wantMAC), []byte(hex.EncodeToString(v.mac.Sum(nil))))
+}
+
+// decodeVerifiedBody декодирует тело запроса в dst, одновременно проверяя его подписи.
+//
+// Остаток тела после JSON дочитывается, чтобы подпись покрывала тело целиком. При неверной
+// подписи отправляет событие аудита и отвечает invalid_signature, даже если тело не разбирается;
+// иначе отвечает ошибкой чтения или разбора. Возвращает false, если ответ уже отправлен.
+func (h *Handler) decodeVerifiedBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	verifier := h.newSignatureVerifier(r)
+	body := verifier.reader(r.Body)
+	r.Body = io.NopCloser(body)
+
+	decodeErr := decodeRequestBody(r, dst)
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		writeReadError(w, r, err)
+		return false
+	}
+	if !verifier.valid() {
+		h.AuditRejection(r, models.AuditInvalidSignature)
+		WriteError(w, r, http.StatusBadRequest, models.ErrCodeInvalidSignature, "invalid signature")
+		return false
+	}
+	if decodeErr != nil {
+		writeDecodeError(w, r, decodeErr)
+		return false
+	}
+	return true
+}
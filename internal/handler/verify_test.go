@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDecodeVerifiedBody проверяет проверку подписи HMAC при потоковом разборе тела:
+// подпись покрывает и данные после JSON, а неверная подпись важнее ошибки разбора.
+//
+// t — указатель на структуру теста.
+func TestDecodeVerifiedBody(t *testing.T) {
+	const key = "secret"
+	h := NewHandler(repository.NewMemStorage(), nil)
+	h.SetKey(key)
+	sign := func(data []byte) string {
+		return (&Handler{key: key}).computeHash(data)
+	}
+
+	batch := []byte(`[{"id":"PollCount","type":"counter","delta":3}]`)
+	trailing := append(append([]byte{}, batch...), "\n\n"...)
+	invalid := []byte(`[{"id":`)
+
+	tests := []struct {
+		name       string // Название теста
+		body       []byte // Тело запроса
+		hash       string // Заголовок HashSHA256
+		wantStatus int    // Ожидаемый код ответа
+		wantCode   string // Ожидаемый код ошибки
+	}{
+		{name: "Signed", body: batch, hash: sign(batch), wantStatus: http.StatusOK},
+		{name: "Unsigned", body: batch, wantStatus: http.StatusOK},
+		{name: "TrailingData", body: trailing, hash: sign(trailing), wantStatus: http.StatusOK},
+		{name: "TrailingDataNotSigned", body: trailing, hash: sign(batch), wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeInvalidSignature},
+		{name: "WrongSignature", body: batch, hash: sign(invalid), wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeInvalidSignature},
+		{name: "InvalidJSONWrongSignature", body: invalid, hash: sign(batch), wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeInvalidSignature},
+		{name: "InvalidJSONSigned", body: invalid, hash: sign(invalid), wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeInvalidJSON},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.hash != "" {
+				req.Header.Set("HashSHA256", tc.hash)
+			}
+			rec := httptest.NewRecorder()
+			h.HandlerUpdateBatchJSON(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantCode != "" {
+				var resp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				require.Equal(t, tc.wantCode, resp.Code)
+			}
+		})
+	}
+}
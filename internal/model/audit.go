@@ -1,15 +1,62 @@
 package models
 
+// Типы событий аудита об отказе в доступе.
+const (
+	AuditInvalidSignature   = "invalid_signature"   // Неверная подпись HMAC
+	AuditSubnetDenied       = "subnet_denied"       // Адрес вне доверенной подсети
+	AuditInvalidCredentials = "invalid_credentials" // Неизвестный API-ключ, недействительный JWT или токен регистрации
+	AuditForbidden          = "forbidden"           // Роли клиента недостаточно для операции
+	AuditRateLimited        = "rate_limited"        // Запрос отклонён ограничением частоты
+)
+
+// AuditRuntimeChange — тип события аудита об изменении настроек сервера во время работы.
+const AuditRuntimeChange = "runtime_change"
+
+// AuditCounterCorrection — тип события аудита об исправлении или исключении вклада источника в счётчик.
+const AuditCounterCorrection = "counter_correction"
+
+// Типы событий аудита о метриках, не обновлявшихся дольше срока хранения.
+const (
+	AuditMetricExpired = "metric_expired" // Устаревшие метрики удалены из хранилища
+	AuditMetricStale   = "metric_stale"   // Метрики устарели, но оставлены в хранилище
+)
+
+// Операции с метриками в событиях аудита.
+const (
+	AuditOpUpdate = "update" // Изменение одной метрики
+	AuditOpBatch  = "batch"  // Изменение нескольких метрик одним запросом (батч, импорт)
+	AuditOpRead   = "read"   // Чтение значений метрик
+	AuditOpDelete = "delete" // Удаление метрик или вклада источника в счётчик
+)
+
 // AuditEvent представляет событие аудита.
 //
+// События об операциях с метриками содержат Metrics и Operation; события об отказе в доступе
+// вместо операции содержат тип отказа. Поля запроса (маршрут, User-Agent, идентификатор
+// клиента) заполняются для событий, вызванных HTTP-запросом.
+//
 // Поля:
 //   - Timestamp: временная метка события (Unix-время, int64)
 //   - Metrics: список имён метрик, связанных с событием
 //   - IPAddress: IP-адрес клиента, вызвавшего событие
+//   - Event: тип отказа в доступе или AuditRuntimeChange (пусто для изменений метрик)
+//   - Route: маршрут HTTP или метод gRPC, к которому обращался клиент
+//   - ID: уникальный идентификатор события (заполняется менеджером аудита)
+//   - RequestID: идентификатор запроса, вызвавшего событие (см. заголовок X-Request-ID)
+//   - Operation: операция с метриками (AuditOp*)
+//   - UserAgent: заголовок User-Agent клиента
+//   - Identity: кто выполнил операцию: API-ключ, субъект JWT или агент (например "key:ci-writer", "agent:7f3a")
 type AuditEvent struct {
 	Timestamp int64    `json:"ts"`
 	Metrics   []string `json:"metrics"`
 	IPAddress string   `json:"ip_address"`
+	Event     string   `json:"event,omitempty"`
+	Route     string   `json:"route,omitempty"`
+	ID        string   `json:"id,omitempty"`
+	RequestID string   `json:"request_id,omitempty"`
+	Operation string   `json:"operation,omitempty"`
+	UserAgent string   `json:"user_agent,omitempty"`
+	Identity  string   `json:"identity,omitempty"`
 }
 
 // AuditObserver интерфейс наблюдателя для аудита.
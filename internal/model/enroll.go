@@ -0,0 +1,26 @@
+package models
+
+// AgentIDHeader — заголовок, в котором зарегистрированный агент передаёт свой идентификатор.
+//
+// Запросы с этим заголовком подписываются персональным ключом агента, выданным при регистрации.
+const AgentIDHeader = "X-Agent-ID"
+
+// EnrollRequest — запрос регистрации агента по одноразовому токену.
+//
+// Поля:
+//   - Token: одноразовый токен регистрации
+//   - Hostname: имя хоста агента
+type EnrollRequest struct {
+	Token    string `json:"token"`
+	Hostname string `json:"hostname"`
+}
+
+// EnrollResponse — персональные учётные данные, выданные агенту при регистрации.
+//
+// Поля:
+//   - AgentID: идентификатор агента
+//   - Key: персональный ключ для HMAC-подписи запросов
+type EnrollResponse struct {
+	AgentID string `json:"agent_id"`
+	Key     string `json:"key"`
+}
@@ -0,0 +1,76 @@
+package models
+
+// Машинно-читаемые коды ошибок API (поле ErrorResponse.Code).
+const (
+	ErrCodeBadRequest            = "bad_request"              // Некорректные параметры запроса
+	ErrCodeInvalidJSON           = "invalid_json"             // Тело запроса не является корректным JSON
+	ErrCodeEmptyBody             = "empty_body"               // Тело запроса пустое
+	ErrCodeEmptyBatch            = "empty_batch"              // Пакет не содержит ни одной метрики
+	ErrCodeInvalidSignature      = "invalid_signature"        // Неверная подпись HMAC
+	ErrCodeDecryptFailed         = "decrypt_failed"           // Не удалось расшифровать тело запроса
+	ErrCodeInvalidMetric         = "invalid_metric"           // Метрика без значения или с некорректным значением
+	ErrCodeUnknownMetricType     = "unknown_metric_type"      // Тип метрики не gauge и не counter
+	ErrCodeCounterOverflow       = "counter_overflow"         // Приращение или значение счётчика выходит за пределы int64
+	ErrCodeMetricNotFound        = "metric_not_found"         // Запрошенная метрика отсутствует
+	ErrCodeNotFound              = "not_found"                // Маршрут не найден или недоступен на этом слушателе
+	ErrCodeMethodNotAllowed      = "method_not_allowed"       // Метод HTTP не поддерживается маршрутом
+	ErrCodeUnauthorized          = "unauthorized"             // Нет токена или токен недействителен
+	ErrCodeForbidden             = "forbidden"                // Недостаточно прав или адрес вне доверенной подсети
+	ErrCodeEnrollmentDisabled    = "enrollment_disabled"      // Регистрация агентов не настроена
+	ErrCodeInvalidEnrollToken    = "invalid_enrollment_token" // Токен регистрации неизвестен или уже использован
+	ErrCodeUnknownDictionary     = "unknown_dictionary"       // Словарь сжатия не зарегистрирован на сервере
+	ErrCodeStorageFailed         = "storage_failed"           // Не удалось сохранить метрики
+	ErrCodeDatabaseUnavailable   = "database_unavailable"     // База данных недоступна
+	ErrCodeDatabaseNotConfigured = "database_not_configured"  // База данных не настроена на сервере
+	ErrCodeInternal              = "internal_error"           // Прочие внутренние ошибки сервера
+	ErrCodeRateLimited           = "rate_limited"             // Превышено ограничение частоты запросов клиента
+	ErrCodeBodyTooLarge          = "body_too_large"           // Тело запроса или его распакованный вид превышает ограничение
+	ErrCodeInvalidContentType    = "invalid_content_type"     // Заголовок Content-Type не разбирается
+)
+
+// ErrorResponse — тело ответа сервера с ошибкой.
+//
+// Клиенты (агент, утилиты) должны опираться на Code, а не на текст Message.
+//
+// Поля:
+//   - Code: машинно-читаемый код ошибки (ErrCode*)
+//   - Class: класс ошибки (ErrClass*), определяемый по Code
+//   - Retryable: имеет ли смысл повторить запрос без изменений; для таких ошибок сервер
+//     также возвращает заголовок Retry-After
+//   - Message: описание ошибки для человека
+//   - Details: дополнительные сведения, например имя метрики или параметра
+//   - RequestID: идентификатор запроса для поиска в логах сервера
+type ErrorResponse struct {
+	Code      string            `json:"code"`
+	Class     string            `json:"class,omitempty"`
+	Retryable bool              `json:"retryable"`
+	Message   string            `json:"message"`
+	Details   map[string]string `json:"details,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// Классы ошибок API (поле ErrorResponse.Class).
+const (
+	ErrClassValidation = "validation" // Запрос некорректен; повтор без изменений не поможет
+	ErrClassAuth       = "auth"       // Учётные данные, подпись или шифрование не приняты
+	ErrClassTransient  = "transient"  // Временный сбой сервера; запрос можно повторить позже
+	ErrClassInternal   = "internal"   // Прочие ошибки сервера; повтор вряд ли поможет
+)
+
+// DefaultRetryAfter — значение заголовка Retry-After (в секундах) для повторяемых ошибок.
+const DefaultRetryAfter = 1
+
+// ErrorClass возвращает класс ошибки (ErrClass*) по её коду (ErrCode*).
+func ErrorClass(code string) string {
+	switch code {
+	case ErrCodeUnauthorized, ErrCodeForbidden, ErrCodeEnrollmentDisabled, ErrCodeInvalidEnrollToken,
+		ErrCodeInvalidSignature, ErrCodeDecryptFailed:
+		return ErrClassAuth
+	case ErrCodeStorageFailed, ErrCodeDatabaseUnavailable, ErrCodeRateLimited:
+		return ErrClassTransient
+	case ErrCodeInternal, ErrCodeDatabaseNotConfigured:
+		return ErrClassInternal
+	default:
+		return ErrClassValidation
+	}
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Counter — константа, обозначающая тип метрики "счётчик".
 // Счётчики увеличиваются на указанное значение (delta).
 const Counter = "counter"
@@ -20,10 +22,17 @@ const Gauge = "gauge"
 //   - Delta: приращение для счётчика (используется для Counter)
 //   - Value: значение для датчика (используется для Gauge)
 //   - Hash: HMAC-SHA256 подпись метрики (опционально)
+//   - UpdatedAt: время последнего изменения метрики на сервере; заполняется только в ответах на чтение
 type Metrics struct {
-	ID    string   `json:"id"`
-	MType string   `json:"type"`
-	Delta *int64   `json:"delta,omitempty"`
-	Value *float64 `json:"value,omitempty"`
-	Hash  string   `json:"hash,omitempty"`
+	ID        string    `json:"id"`
+	MType     string    `json:"type"`
+	Delta     *int64    `json:"delta,omitempty"`
+	Value     *float64  `json:"value,omitempty"`
+	Hash      string    `json:"hash,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
 }
+
+// BatchAppliedHeader — заголовок ответа на пакетное обновление, применённое по мере разбора
+// тела запроса: количество метрик, применённых к хранилищу. В ответе с ошибкой ненулевое
+// значение означает, что батч применён частично.
+const BatchAppliedHeader = "X-Batch-Applied"
@@ -0,0 +1,273 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// Сериализация Metrics и MetricsBatch без рефлексии на основе jlexer и jwriter из easyjson.
+//
+// Методы написаны вручную, а не сгенерированы: вывод совпадает с encoding/json
+// (формат чисел, экранирование строк, omitempty), а приращение вне диапазона int64
+// сообщается ошибкой *json.UnmarshalTypeError для поля delta, как при разборе
+// через рефлексию. Ключи сопоставляются без учёта регистра, как в encoding/json.
+
+// MetricsBatch — пакет метрик, сериализуемый в JSON-массив без рефлексии.
+type MetricsBatch []Metrics
+
+// MarshalEasyJSON записывает метрику m в w.
+func (m Metrics) MarshalEasyJSON(w *jwriter.Writer) {
+	w.RawString(`{"id":`)
+	w.String(m.ID)
+	w.RawString(`,"type":`)
+	w.String(m.MType)
+	if m.Delta != nil {
+		w.RawString(`,"delta":`)
+		w.Int64(*m.Delta)
+	}
+	if m.Value != nil {
+		w.RawString(`,"value":`)
+		writeFloat(w, *m.Value)
+	}
+	if m.Hash != "" {
+		w.RawString(`,"hash":`)
+		w.String(m.Hash)
+	}
+	if !m.UpdatedAt.IsZero() {
+		w.RawString(`,"updated_at":`)
+		writeTime(w, m.UpdatedAt)
+	}
+	w.RawByte('}')
+}
+
+// MarshalJSON реализует json.Marshaler.
+func (m Metrics) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	m.MarshalEasyJSON(&w)
+	return w.BuildBytes()
+}
+
+// UnmarshalEasyJSON читает метрику из in в m. Неизвестные поля пропускаются.
+func (m *Metrics) UnmarshalEasyJSON(in *jlexer.Lexer) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := strings.ToLower(in.UnsafeFieldName(false))
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			switch key {
+			case "delta":
+				m.Delta = nil
+			case "value":
+				m.Value = nil
+			}
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "id":
+			m.ID = readString(in)
+		case "type":
+			m.MType = readType(in)
+		case "delta":
+			m.Delta = readDelta(in)
+		case "value":
+			v := in.Float64()
+			m.Value = &v
+		case "hash":
+			m.Hash = readString(in)
+		case "updated_at":
+			if err := m.UpdatedAt.UnmarshalText(in.UnsafeBytes()); err != nil {
+				in.AddError(err)
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+
+// UnmarshalJSON реализует json.Unmarshaler.
+func (m *Metrics) UnmarshalJSON(data []byte) error {
+	in := jlexer.Lexer{Data: data}
+	m.UnmarshalEasyJSON(&in)
+	return in.Error()
+}
+
+// MarshalEasyJSON записывает пакет b в w; nil записывается как null.
+func (b MetricsBatch) MarshalEasyJSON(w *jwriter.Writer) {
+	if b == nil {
+		w.RawString("null")
+		return
+	}
+	w.RawByte('[')
+	for i := range b {
+		if i > 0 {
+			w.RawByte(',')
+		}
+		b[i].MarshalEasyJSON(w)
+	}
+	w.RawByte(']')
+}
+
+// MarshalJSON реализует json.Marshaler.
+func (b MetricsBatch) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	b.MarshalEasyJSON(&w)
+	return w.BuildBytes()
+}
+
+// UnmarshalEasyJSON читает пакет из in в b; null даёт nil.
+func (b *MetricsBatch) UnmarshalEasyJSON(in *jlexer.Lexer) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*b = nil
+	} else {
+		in.Delim('[')
+		if *b == nil {
+			*b = make(MetricsBatch, 0, 8)
+		} else {
+			*b = (*b)[:0]
+		}
+		for !in.IsDelim(']') {
+			var m Metrics
+			m.UnmarshalEasyJSON(in)
+			*b = append(*b, m)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+
+// UnmarshalJSON реализует json.Unmarshaler.
+func (b *MetricsBatch) UnmarshalJSON(data []byte) error {
+	in := jlexer.Lexer{Data: data}
+	b.UnmarshalEasyJSON(&in)
+	return in.Error()
+}
+
+// readString читает строку, всегда копируя её.
+//
+// jlexer.Lexer.String после строки с экранированием может вернуть следующую строку без
+// копирования, и она будет ссылаться на буфер json.Decoder, который перезаписывается.
+func readString(in *jlexer.Lexer) string {
+	return string(in.UnsafeBytes())
+}
+
+// readType читает тип метрики; для известных типов строка не выделяется.
+func readType(in *jlexer.Lexer) string {
+	switch b := in.UnsafeBytes(); string(b) {
+	case Gauge:
+		return Gauge
+	case Counter:
+		return Counter
+	default:
+		return string(b)
+	}
+}
+
+// readDelta читает приращение счётчика.
+//
+// Значение, не являющееся целым числом в диапазоне int64, сообщается ошибкой
+// *json.UnmarshalTypeError с тем же Value, что и у encoding/json ("number 1e100", "string"),
+// по которой обработчики отличают переполнение счётчика от некорректного JSON.
+func readDelta(in *jlexer.Lexer) *int64 {
+	pos := in.GetPos()
+	raw := in.Raw()
+	if !in.Ok() {
+		return nil
+	}
+	d, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		in.AddError(&json.UnmarshalTypeError{
+			Value:  jsonKind(raw),
+			Type:   reflect.TypeFor[int64](),
+			Offset: int64(pos),
+			Struct: "Metrics",
+			Field:  "delta",
+		})
+		return nil
+	}
+	return &d
+}
+
+// jsonKind описывает значение raw для ошибки разбора так же, как encoding/json.
+func jsonKind(raw []byte) string {
+	switch raw[0] {
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "bool"
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	}
+	return "number " + string(raw)
+}
+
+// writeFloat записывает f в формате encoding/json: без экспоненты для значений
+// от 1e-6 до 1e21, иначе с экспонентой без ведущих нулей. NaN и бесконечности
+// не представимы в JSON и дают ошибку записи.
+func writeFloat(w *jwriter.Writer, f float64) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		if w.Error == nil {
+			w.Error = &json.UnsupportedValueError{Value: reflect.ValueOf(f), Str: strconv.FormatFloat(f, 'g', -1, 64)}
+		}
+		w.RawString("null")
+		return
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	w.Buffer.EnsureSpace(24)
+	b := strconv.AppendFloat(w.Buffer.Buf, f, format, -1, 64)
+	if format == 'e' {
+		// e-09 -> e-9, как в encoding/json.
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	w.Buffer.Buf = b
+}
+
+// writeTime записывает t в формате encoding/json (RFC 3339 с наносекундами в кавычках).
+// Время, не представимое в RFC 3339 (год вне диапазона 0–9999), даёт ошибку записи.
+func writeTime(w *jwriter.Writer, t time.Time) {
+	w.RawByte('"')
+	w.Buffer.EnsureSpace(len(time.RFC3339Nano))
+	b, err := t.AppendText(w.Buffer.Buf)
+	if err != nil {
+		if w.Error == nil {
+			w.Error = err
+		}
+	} else {
+		w.Buffer.Buf = b
+	}
+	w.RawByte('"')
+}
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// plainMetrics повторяет Metrics без методов сериализации: encoding/json обрабатывает её
+// через рефлексию.
+type plainMetrics struct {
+	ID        string    `json:"id"`
+	MType     string    `json:"type"`
+	Delta     *int64    `json:"delta,omitempty"`
+	Value     *float64  `json:"value,omitempty"`
+	Hash      string    `json:"hash,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// TestMetricsMarshalJSON проверяет, что MarshalJSON выдаёт тот же JSON, что и encoding/json
+// через рефлексию, и что результат разбирается обратно без потерь.
+//
+// t — указатель на структуру теста.
+func TestMetricsMarshalJSON(t *testing.T) {
+	delta := func(d int64) *int64 { return &d }
+	value := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name       string  // Название теста
+		m          Metrics // Метрика
+		equivalent bool    // Вывод отличается от encoding/json только экранированием
+	}{
+		{name: "Counter", m: Metrics{ID: "PollCount", MType: Counter, Delta: delta(math.MinInt64)}},
+		{name: "Gauge", m: Metrics{ID: "Alloc", MType: Gauge, Value: value(1.5)}},
+		{name: "Hash", m: Metrics{ID: "Alloc", MType: Gauge, Value: value(0), Hash: "abc"}},
+		{name: "Empty", m: Metrics{}},
+		{name: "UpdatedAt", m: Metrics{ID: "g", MType: Gauge, Value: value(1), UpdatedAt: time.Date(2026, 1, 2, 3, 4, 5, 600, time.FixedZone("", 3*3600))}},
+		{name: "UpdatedAtUTC", m: Metrics{ID: "c", MType: Counter, Delta: delta(1), UpdatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}},
+		{name: "LargeFloat", m: Metrics{ID: "g", MType: Gauge, Value: value(-1.058388541602607e+308)}},
+		{name: "SmallFloat", m: Metrics{ID: "g", MType: Gauge, Value: value(1e-7)}},
+		{name: "IntegralFloat", m: Metrics{ID: "g", MType: Gauge, Value: value(1e20)}},
+		{name: "Escaping", m: Metrics{ID: "<a href=\"x\">& \t\x1c\\", MType: Gauge, Value: value(2)}},
+		{name: "InvalidUTF8", m: Metrics{ID: "a\xffb", MType: Gauge, Value: value(2)}, equivalent: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.m.MarshalJSON()
+			require.NoError(t, err)
+			want, err := json.Marshal(plainMetrics(tc.m))
+			require.NoError(t, err)
+			if tc.equivalent {
+				require.JSONEq(t, string(want), string(got))
+			} else {
+				require.Equal(t, string(want), string(got))
+			}
+
+			var back plainMetrics
+			require.NoError(t, json.Unmarshal(got, &back))
+			var parsed Metrics
+			require.NoError(t, parsed.UnmarshalJSON(got))
+			require.Equal(t, Metrics(back), parsed)
+		})
+	}
+
+	_, err := Metrics{ID: "g", MType: Gauge, Value: value(math.NaN())}.MarshalJSON()
+	var unsupported *json.UnsupportedValueError
+	require.ErrorAs(t, err, &unsupported)
+	_, err = Metrics{ID: "g", UpdatedAt: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)}.MarshalJSON()
+	require.Error(t, err)
+
+	batch, err := MetricsBatch{tests[0].m, tests[1].m}.MarshalJSON()
+	require.NoError(t, err)
+	want, err := json.Marshal([]plainMetrics{plainMetrics(tests[0].m), plainMetrics(tests[1].m)})
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(batch))
+
+	null, err := MetricsBatch(nil).MarshalJSON()
+	require.NoError(t, err)
+	require.Equal(t, "null", string(null))
+}
+
+// TestMetricsUnmarshalJSON проверяет совместимость разбора с encoding/json: регистр ключей,
+// неизвестные поля, null и ошибку переполнения приращения.
+//
+// t — указатель на структуру теста.
+func TestMetricsUnmarshalJSON(t *testing.T) {
+	var m Metrics
+	require.NoError(t, m.UnmarshalJSON([]byte(`{"ID":"a","Type":"counter","extra":{"x":[1,2]},"delta":7,"value":null}`)))
+	require.Equal(t, "a", m.ID)
+	require.Equal(t, Counter, m.MType)
+	require.Equal(t, int64(7), *m.Delta)
+	require.Nil(t, m.Value)
+
+	for _, input := range []string{`{"id":"a","delta":9223372036854775808}`, `{"id":"a","delta":1.5}`} {
+		err := new(Metrics).UnmarshalJSON([]byte(input))
+		var typeErr *json.UnmarshalTypeError
+		require.ErrorAs(t, err, &typeErr, input)
+		require.Equal(t, "delta", typeErr.Field)
+		require.True(t, strings.HasPrefix(typeErr.Value, "number"), typeErr.Value)
+	}
+
+	err := new(Metrics).UnmarshalJSON([]byte(`{"id":"a","delta":"7"}`))
+	var typeErr *json.UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+	require.Equal(t, "string", typeErr.Value)
+
+	require.Error(t, new(Metrics).UnmarshalJSON([]byte(`{"id":"a","updated_at":"yesterday"}`)))
+	require.Error(t, new(Metrics).UnmarshalJSON([]byte(`{"id":"a","updated_at":1}`)))
+	require.Error(t, new(Metrics).UnmarshalJSON([]byte(`{"id":`)))
+	require.Error(t, new(Metrics).UnmarshalJSON([]byte(`{"id":"a"} x`)))
+
+	var batch MetricsBatch
+	require.NoError(t, json.Unmarshal([]byte(`null`), &batch))
+	require.Nil(t, batch)
+	require.NoError(t, json.Unmarshal([]byte(`[]`), &batch))
+	require.NotNil(t, batch)
+	require.Empty(t, batch)
+}
+
+// TestMetricsUnmarshalJSON_Decoder проверяет, что строки метрик, разобранных через
+// json.Decoder, не ссылаются на его буфер, перезаписываемый при чтении следующих значений.
+//
+// t — указатель на структуру теста.
+func TestMetricsUnmarshalJSON_Decoder(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := range 200 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, `{"id":"m\u001c%d","type":"counter","delta":%d}`, i, i)
+	}
+	sb.WriteByte(']')
+
+	dec := json.NewDecoder(bufio.NewReaderSize(strings.NewReader(sb.String()), 16))
+	_, err := dec.Token()
+	require.NoError(t, err)
+	var got []Metrics
+	for dec.More() {
+		var m Metrics
+		require.NoError(t, dec.Decode(&m))
+		got = append(got, m)
+	}
+	require.Len(t, got, 200)
+	for i, m := range got {
+		require.Equal(t, fmt.Sprintf("m\x1c%d", i), m.ID)
+		require.Equal(t, Counter, m.MType)
+	}
+}
+
+// benchmarkBatch возвращает пакет из n метрик обоих типов.
+func benchmarkBatch(n int) MetricsBatch {
+	batch := make(MetricsBatch, n)
+	for i := range batch {
+		if i%2 == 0 {
+			v := float64(i) * 1.25
+			batch[i] = Metrics{ID: fmt.Sprintf("Gauge%d", i), MType: Gauge, Value: &v}
+		} else {
+			d := int64(i)
+			batch[i] = Metrics{ID: fmt.Sprintf("Counter%d", i), MType: Counter, Delta: &d}
+		}
+	}
+	return batch
+}
+
+// BenchmarkMetricsBatch_Marshal сравнивает сериализацию пакета через рефлексию и MarshalJSON.
+func BenchmarkMetricsBatch_Marshal(b *testing.B) {
+	batch := benchmarkBatch(1000)
+	plain := make([]plainMetrics, len(batch))
+	for i, m := range batch {
+		plain[i] = plainMetrics(m)
+	}
+
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := json.Marshal(plain); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("easyjson", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := batch.MarshalJSON(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkMetricsBatch_Unmarshal сравнивает разбор пакета через рефлексию и UnmarshalJSON.
+func BenchmarkMetricsBatch_Unmarshal(b *testing.B) {
+	data, err := benchmarkBatch(1000).MarshalJSON()
+	require.NoError(b, err)
+
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var plain []plainMetrics
+			if err := json.Unmarshal(data, &plain); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("easyjson", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var batch MetricsBatch
+			if err := batch.UnmarshalJSON(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("decoder-reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var plain []plainMetrics
+			if err := json.NewDecoder(bytes.NewReader(data)).Decode(&plain); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("readall-easyjson", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			body, err := io.ReadAll(bytes.NewReader(data))
+			if err != nil {
+				b.Fatal(err)
+			}
+			var batch MetricsBatch
+			if err := batch.UnmarshalJSON(body); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
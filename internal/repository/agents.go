@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrInvalidEnrollmentToken возвращается, если токен регистрации неизвестен или уже использован.
+var ErrInvalidEnrollmentToken = errors.New("invalid or already used enrollment token")
+
+type (
+	// AgentIdentity — сведения о зарегистрированном агенте.
+	//
+	// Поля:
+	//   - ID: идентификатор агента
+	//   - Hostname: имя хоста, переданное при регистрации
+	//   - RemoteAddr: адрес, с которого выполнена регистрация
+	//   - EnrolledAt: время регистрации
+	AgentIdentity struct {
+		ID         string    `json:"id"`
+		Hostname   string    `json:"hostname"`
+		RemoteAddr string    `json:"remote_addr"`
+		EnrolledAt time.Time `json:"enrolled_at"`
+	}
+
+	// agentRecord — запись реестра: сведения об агенте и его персональный ключ.
+	agentRecord struct {
+		AgentIdentity
+		Key string `json:"key"`
+	}
+
+	// agentRegistryState — содержимое файла реестра.
+	agentRegistryState struct {
+		Agents     map[string]agentRecord `json:"agents"`
+		UsedTokens map[string]time.Time   `json:"used_tokens"` // SHA-256 использованных токенов
+	}
+
+	// AgentRegistry хранит одноразовые токены регистрации и персональные ключи агентов.
+	//
+	// Если задан путь к файлу, реестр сохраняется после каждой регистрации, поэтому
+	// использованные токены остаются недействительными после перезапуска сервера.
+	//
+	// Поля:
+	//   - path: путь к файлу реестра (пусто — только в памяти)
+	//   - tokens: SHA-256 допустимых токенов
+	//   - state: зарегистрированные агенты и использованные токены
+	//   - now: функция получения текущего времени
+	//   - mu: мьютекс для доступа к реестру
+	AgentRegistry struct {
+		path   string
+		tokens map[string]struct{}
+		state  agentRegistryState
+		now    func() time.Time
+		mu     sync.RWMutex
+	}
+)
+
+// NewAgentRegistry создаёт реестр агентов, загружая его из файла path, если он существует.
+//
+// path — путь к файлу реестра (пусто — реестр хранится только в памяти).
+// tokens — одноразовые токены регистрации; уже использованные токены игнорируются.
+func NewAgentRegistry(path string, tokens []string) (*AgentRegistry, error) {
+	r := &AgentRegistry{
+		path:   path,
+		tokens: make(map[string]struct{}, len(tokens)),
+		state: agentRegistryState{
+			Agents:     make(map[string]agentRecord),
+			UsedTokens: make(map[string]time.Time),
+		},
+		now: time.Now,
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &r.state); err != nil {
+				return nil, err
+			}
+			if r.state.Agents == nil {
+				r.state.Agents = make(map[string]agentRecord)
+			}
+			if r.state.UsedTokens == nil {
+				r.state.UsedTokens = make(map[string]time.Time)
+			}
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+	}
+	for _, t := range tokens {
+		if t != "" {
+			r.tokens[tokenHash(t)] = struct{}{}
+		}
+	}
+	return r, nil
+}
+
+// Enroll регистрирует агента по одноразовому токену и выдаёт ему персональный ключ.
+//
+// token — токен регистрации; после успешной регистрации становится недействительным.
+// hostname — имя хоста агента.
+// remoteAddr — адрес, с которого выполнен запрос.
+//
+// Возвращает ErrInvalidEnrollmentToken, если токен неизвестен или уже использован.
+func (r *AgentRegistry) Enroll(token, hostname, remoteAddr string) (AgentIdentity, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h := tokenHash(token)
+	if _, ok := r.tokens[h]; !ok {
+		return AgentIdentity{}, "", ErrInvalidEnrollmentToken
+	}
+	if _, used := r.state.UsedTokens[h]; used {
+		return AgentIdentity{}, "", ErrInvalidEnrollmentToken
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return AgentIdentity{}, "", err
+	}
+	key, err := randomHex(32)
+	if err != nil {
+		return AgentIdentity{}, "", err
+	}
+	identity := AgentIdentity{
+		ID:         "agent-" + id,
+		Hostname:   hostname,
+		RemoteAddr: remoteAddr,
+		EnrolledAt: r.now().UTC(),
+	}
+	r.state.Agents[identity.ID] = agentRecord{AgentIdentity: identity, Key: key}
+	r.state.UsedTokens[h] = identity.EnrolledAt
+
+	if err := r.save(); err != nil {
+		delete(r.state.Agents, identity.ID)
+		delete(r.state.UsedTokens, h)
+		return AgentIdentity{}, "", err
+	}
+	return identity, key, nil
+}
+
+// Key возвращает персональный ключ агента по идентификатору.
+func (r *AgentRegistry) Key(agentID string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rec, ok := r.state.Agents[agentID]
+	return rec.Key, ok
+}
+
+// Agents возвращает сведения о зарегистрированных агентах, упорядоченные по времени регистрации.
+func (r *AgentRegistry) Agents() []AgentIdentity {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]AgentIdentity, 0, len(r.state.Agents))
+	for _, rec := range r.state.Agents {
+		out = append(out, rec.AgentIdentity)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].EnrolledAt.Equal(out[j].EnrolledAt) {
+			return out[i].EnrolledAt.Before(out[j].EnrolledAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// save атомарно записывает реестр в файл (права 0600, так как файл содержит ключи агентов).
+func (r *AgentRegistry) save() error {
+	if r.path == "" {
+		return nil
+	}
+	if err := writeFileAtomic(r.path, true, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r.state)
+	}); err != nil {
+		return err
+	}
+	return os.Chmod(r.path, 0600)
+}
+
+// tokenHash возвращает SHA-256 токена; сами токены в реестре не хранятся.
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex возвращает n случайных байт в hex-представлении.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestAgentRegistry_Enroll проверяет одноразовость токенов и выдачу персональных ключей.
+//
+// t — указатель на структуру теста.
+func TestAgentRegistry_Enroll(t *testing.T) {
+	r, err := NewAgentRegistry("", []string{"tok-1", "tok-2"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid token", token: "tok-1"},
+		{name: "reused token", token: "tok-1", wantErr: true},
+		{name: "unknown token", token: "tok-x", wantErr: true},
+		{name: "second token", token: "tok-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, key, err := r.Enroll(tt.token, "host", "10.0.0.1")
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidEnrollmentToken)
+				return
+			}
+			require.NoError(t, err)
+			require.NotEmpty(t, identity.ID)
+			require.Len(t, key, 64)
+
+			got, ok := r.Key(identity.ID)
+			require.True(t, ok)
+			require.Equal(t, key, got)
+		})
+	}
+	require.Len(t, r.Agents(), 2)
+}
+
+// TestAgentRegistry_Persistence проверяет, что агенты и использованные токены сохраняются между запусками.
+//
+// t — указатель на структуру теста.
+func TestAgentRegistry_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agents.json")
+
+	r, err := NewAgentRegistry(path, []string{"tok"})
+	require.NoError(t, err)
+	identity, key, err := r.Enroll("tok", "host", "10.0.0.1")
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), `"tok"`)
+
+	reopened, err := NewAgentRegistry(path, []string{"tok"})
+	require.NoError(t, err)
+	got, ok := reopened.Key(identity.ID)
+	require.True(t, ok)
+	require.Equal(t, key, got)
+
+	_, _, err = reopened.Enroll("tok", "other", "10.0.0.2")
+	require.ErrorIs(t, err, ErrInvalidEnrollmentToken)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LoadAPIKeysFile загружает хешированные API-ключи из JSON-файла path.
+//
+// Файл содержит массив объектов {"name", "hash", "scopes"}, где hash — auth.HashKey(ключ).
+// Сами ключи в файле не хранятся.
+func LoadAPIKeysFile(path string) ([]auth.HashedKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api keys file: %w", err)
+	}
+	var keys []auth.HashedKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse api keys file: %w", err)
+	}
+	return keys, nil
+}
+
+// LoadAPIKeysDB загружает хешированные API-ключи из таблицы api_keys.
+//
+// Столбец scopes содержит области доступа через запятую.
+func LoadAPIKeysDB(ctx context.Context, db *pgxpool.Pool) ([]auth.HashedKey, error) {
+	rows, err := db.Query(ctx, `SELECT name, hash, scopes FROM api_keys`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []auth.HashedKey
+	for rows.Next() {
+		var (
+			k      auth.HashedKey
+			scopes string
+		)
+		if err := rows.Scan(&k.Name, &k.Hash, &scopes); err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		for _, sc := range strings.Split(scopes, ",") {
+			if sc = strings.TrimSpace(sc); sc != "" {
+				k.Scopes = append(k.Scopes, sc)
+			}
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load api keys: %w", err)
+	}
+	return keys, nil
+}
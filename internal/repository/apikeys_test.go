@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadAPIKeysFile проверяет загрузку хешированных ключей из файла и ошибки чтения и разбора.
+//
+// t — указатель на структуру теста.
+func TestLoadAPIKeysFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"name":"team-a","hash":"`+auth.HashKey("k")+`","scopes":["read-metrics","write-metrics"]}]`), 0o600))
+
+	keys, err := LoadAPIKeysFile(path)
+	require.NoError(t, err)
+	require.Equal(t, []auth.HashedKey{{Name: "team-a", Hash: auth.HashKey("k"), Scopes: []string{"read-metrics", "write-metrics"}}}, keys)
+
+	_, err = LoadAPIKeysFile(filepath.Join(dir, "missing.json"))
+	require.Error(t, err)
+
+	bad := filepath.Join(dir, "bad.json")
+	require.NoError(t, os.WriteFile(bad, []byte(`{`), 0o600))
+	_, err = LoadAPIKeysFile(bad)
+	require.Error(t, err)
+}
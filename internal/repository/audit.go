@@ -3,14 +3,21 @@ package repository
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/RoGogDBD/metric-alerter/internal/config"
 	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/requestid"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // FileAuditObserver записывает события аудита в файл.
@@ -18,9 +25,12 @@ import (
 // Поля:
 //   - filePath: путь к файлу для записи событий
 //   - mu: мьютекс для синхронизации доступа к файлу
+//   - rotator: запись с ротацией и удалением старых архивов (nil — файл растёт без ограничений)
 type FileAuditObserver struct {
 	filePath string
 	mu       sync.Mutex
+
+	rotator *lumberjack.Logger
 }
 
 // NewFileAuditObserver создает новый экземпляр FileAuditObserver.
@@ -37,33 +47,84 @@ func NewFileAuditObserver(filePath string) *FileAuditObserver {
 	return &FileAuditObserver{filePath: filePath}
 }
 
-// OnAuditEvent обрабатывает событие аудита, записывая его в файл.
-//
-// event — событие аудита для записи.
+// SetRotation включает ротацию файла аудита по размеру cfg.MaxSizeMB. Архивы старше
+// cfg.MaxAge и сверх cfg.MaxBackups удаляются, при cfg.Compress — сжимаются gzip.
 //
-// Возвращает ошибку при неудаче записи.
-func (f *FileAuditObserver) OnAuditEvent(event models.AuditEvent) error {
+// При cfg.MaxSizeMB <= 0 ротация выключается.
+func (f *FileAuditObserver) SetRotation(cfg config.AuditRotationConfig) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	file, err := os.OpenFile(f.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open audit file: %w", err)
+	if f.rotator != nil {
+		_ = f.rotator.Close()
+		f.rotator = nil
 	}
-	defer func() { _ = file.Close() }()
+	if cfg.MaxSizeMB <= 0 {
+		return
+	}
+	f.rotator = &lumberjack.Logger{
+		Filename:   f.filePath,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxAge:     cfg.MaxAgeDays(),
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+	}
+}
+
+// Rotates сообщает, включена ли ротация файла аудита.
+func (f *FileAuditObserver) Rotates() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rotator != nil
+}
 
+// OnAuditEvent обрабатывает событие аудита, записывая его в файл.
+//
+// event — событие аудита для записи.
+//
+// Возвращает ошибку при неудаче записи.
+func (f *FileAuditObserver) OnAuditEvent(event models.AuditEvent) error {
 	data, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal audit event: %w", err)
 	}
 
-	if _, err := file.Write(append(data, '\n')); err != nil {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var w io.Writer = f.rotator
+	if f.rotator == nil {
+		file, err := os.OpenFile(f.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open audit file: %w", err)
+		}
+		defer func() { _ = file.Close() }()
+		w = file
+	}
+
+	if _, err := w.Write(append(data, '\n')); err != nil {
 		return fmt.Errorf("failed to write audit event: %w", err)
 	}
 
 	return nil
 }
 
+// Close закрывает файл аудита, открытый для ротации.
+func (f *FileAuditObserver) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.rotator == nil {
+		return nil
+	}
+	return f.rotator.Close()
+}
+
+// String возвращает имя наблюдателя для очереди недоставленных событий.
+func (f *FileAuditObserver) String() string {
+	return "file:" + f.filePath
+}
+
 // HTTPAuditObserver отправляет события аудита на удалённый сервер.
 //
 // Поля:
@@ -110,25 +171,110 @@ func (h *HTTPAuditObserver) OnAuditEvent(event models.AuditEvent) error {
 	return nil
 }
 
+// String возвращает имя наблюдателя для очереди недоставленных событий.
+func (h *HTTPAuditObserver) String() string {
+	return "http:" + h.url
+}
+
 // AuditManager управляет списком наблюдателей аудита и уведомляет их о событиях.
 //
 // Поля:
 //   - observers: список наблюдателей (AuditObserver)
 //   - mu: RW-мьютекс для синхронизации доступа к списку наблюдателей
+//   - disabled: доставка событий приостановлена (см. SetEnabled)
+//   - newID: генератор идентификаторов событий
+//   - attempts: число попыток доставки события одному наблюдателю
+//   - backoff: пауза перед повторной попыткой (умножается на номер попытки)
+//   - deadLetter: очередь событий, не доставленных после всех попыток (nil — события теряются)
+//   - queue: очередь асинхронной доставки (nil — события доставляются в Notify)
+//   - queueMu: защищает закрытие queue от одновременной постановки событий
+//   - dropOnFull: при переполнении queue событие отбрасывается, а не ждёт места
+//   - workers: воркеры, доставляющие события из queue
+//   - dropped: число событий, отброшенных при переполнении queue
+//   - filter: правила отбора событий для доставки (nil — доставляются все)
+//   - filtered: число событий, исключённых filter
 type AuditManager struct {
-	observers []models.AuditObserver
-	mu        sync.RWMutex
+	observers  []models.AuditObserver
+	mu         sync.RWMutex
+	disabled   atomic.Bool
+	newID      requestid.Generator
+	attempts   int
+	backoff    time.Duration
+	deadLetter *DeadLetterQueue
+
+	queue      chan models.AuditEvent
+	queueMu    sync.RWMutex
+	dropOnFull bool
+	workers    sync.WaitGroup
+	dropped    atomic.Uint64
+
+	filter   *AuditFilter
+	filtered atomic.Uint64
 }
 
+// auditFlushTimeout — предел ожидания доставки событий из очереди при закрытии AuditManager.
+const auditFlushTimeout = 5 * time.Second
+
 // NewAuditManager создает новый экземпляр AuditManager.
 //
 // Возвращает указатель на AuditManager.
 func NewAuditManager() *AuditManager {
 	return &AuditManager{
 		observers: make([]models.AuditObserver, 0),
+		newID:     requestid.ULID,
+		attempts:  1,
 	}
 }
 
+// SetRetry задаёт число попыток доставки события каждому наблюдателю и паузу между ними.
+//
+// attempts меньше 1 приводится к 1 (без повторов). Пауза перед n-й повторной попыткой
+// равна n*backoff; без очереди (см. SetQueue) повторы задерживают вызвавший Notify запрос.
+func (a *AuditManager) SetRetry(attempts int, backoff time.Duration) {
+	a.attempts = max(attempts, 1)
+	a.backoff = backoff
+}
+
+// SetQueue включает асинхронную доставку: Notify ставит событие в очередь ёмкостью cfg.Size,
+// а cfg.Workers горутин доставляют события наблюдателям. Медленный наблюдатель перестаёт
+// задерживать обработку запросов, пока очередь не переполнится; тогда событие ждёт места
+// (config.AuditQueueBlock) или отбрасывается (config.AuditQueueDrop).
+//
+// Нулевой cfg.Size оставляет синхронную доставку. Вызывается один раз до первого Notify;
+// накопленные события доставляет Close.
+func (a *AuditManager) SetQueue(cfg config.AuditQueueConfig) {
+	if cfg.Size <= 0 {
+		return
+	}
+	a.queue = make(chan models.AuditEvent, cfg.Size)
+	a.dropOnFull = cfg.Policy == config.AuditQueueDrop
+	for range max(cfg.Workers, 1) {
+		a.workers.Add(1)
+		go func() {
+			defer a.workers.Done()
+			for event := range a.queue {
+				a.dispatch(event)
+			}
+		}()
+	}
+}
+
+// SetDeadLetter задаёт очередь, в которую сохраняются события, не доставленные после всех попыток.
+func (a *AuditManager) SetDeadLetter(q *DeadLetterQueue) {
+	a.deadLetter = q
+}
+
+// SetFilter задаёт правила отбора событий: исключённые фильтром события не доставляются
+// наблюдателям и не попадают в очередь. Вызывается до первого Notify.
+func (a *AuditManager) SetFilter(f *AuditFilter) {
+	a.filter = f
+}
+
+// SetIDGenerator задаёт генератор идентификаторов событий (по умолчанию ULID).
+func (a *AuditManager) SetIDGenerator(gen requestid.Generator) {
+	a.newID = gen
+}
+
 // Attach добавляет наблюдателя к списку.
 //
 // observer — наблюдатель, реализующий интерфейс AuditObserver.
@@ -153,17 +299,187 @@ func (a *AuditManager) Detach(observer models.AuditObserver) {
 }
 
 // Notify уведомляет всех подключённых наблюдателей о событии.
+// Событию без идентификатора присваивается новый.
 //
 // event — событие аудита для рассылки.
 func (a *AuditManager) Notify(event models.AuditEvent) {
+	if a.disabled.Load() {
+		return
+	}
+	if !a.filter.Allow(event) {
+		a.filtered.Add(1)
+		return
+	}
+	if event.ID == "" {
+		event.ID = a.newID()
+	}
+
+	a.queueMu.RLock()
+	if a.queue != nil {
+		if !a.dropOnFull {
+			a.queue <- event
+		} else {
+			select {
+			case a.queue <- event:
+			default:
+				a.dropped.Add(1)
+			}
+		}
+		a.queueMu.RUnlock()
+		return
+	}
+	a.queueMu.RUnlock()
+	a.dispatch(event)
+}
+
+// dispatch доставляет событие всем наблюдателям; недоставленное сохраняется в очередь недоставленных.
+func (a *AuditManager) dispatch(event models.AuditEvent) {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
 	for _, observer := range a.observers {
-		if err := observer.OnAuditEvent(event); err != nil {
-			log.Printf("Audit observer error: %v", err)
+		if err := a.deliver(observer, event); err != nil {
+			a.toDeadLetter(observer, event, err)
+		}
+	}
+}
+
+// Queued возвращает число событий, ожидающих доставки в очереди.
+func (a *AuditManager) Queued() int {
+	a.queueMu.RLock()
+	defer a.queueMu.RUnlock()
+	return len(a.queue)
+}
+
+// Dropped возвращает число событий, отброшенных при переполнении очереди.
+func (a *AuditManager) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Filtered возвращает число событий, исключённых правилами фильтрации.
+func (a *AuditManager) Filtered() uint64 {
+	return a.filtered.Load()
+}
+
+// deliver доставляет событие наблюдателю, повторяя попытки согласно SetRetry.
+func (a *AuditManager) deliver(observer models.AuditObserver, event models.AuditEvent) error {
+	var err error
+	for attempt := 1; attempt <= a.attempts; attempt++ {
+		if err = observer.OnAuditEvent(event); err == nil {
+			return nil
+		}
+		if attempt < a.attempts {
+			time.Sleep(time.Duration(attempt) * a.backoff)
+		}
+	}
+	return err
+}
+
+// toDeadLetter сохраняет недоставленное событие в очередь с причиной отказа.
+func (a *AuditManager) toDeadLetter(observer models.AuditObserver, event models.AuditEvent, err error) {
+	log.Printf("Audit observer %s error after %d attempts: %v", observerName(observer), a.attempts, err)
+	if a.deadLetter == nil {
+		return
+	}
+	rec := DeadLetterRecord{
+		Kind:     DeadLetterAudit,
+		Target:   observerName(observer),
+		Reason:   err.Error(),
+		Attempts: a.attempts,
+		FailedAt: time.Now(),
+		Event:    event,
+	}
+	if derr := a.deadLetter.Append(rec); derr != nil {
+		log.Printf("Audit event %s lost: failed to write dead letter: %v", event.ID, derr)
+	}
+}
+
+// DeadLetters возвращает события из очереди недоставленных (пусто, если очередь не задана).
+func (a *AuditManager) DeadLetters() ([]DeadLetterRecord, error) {
+	if a.deadLetter == nil {
+		return nil, nil
+	}
+	return a.deadLetter.List()
+}
+
+// ReplayDeadLetters повторно доставляет события из очереди тем наблюдателям, которым
+// они не были доставлены. Записи для отключённых наблюдателей остаются в очереди.
+//
+// Возвращает число доставленных и оставшихся событий.
+func (a *AuditManager) ReplayDeadLetters() (replayed, remaining int, err error) {
+	if a.deadLetter == nil {
+		return 0, 0, ErrNoDeadLetterQueue
+	}
+	a.mu.RLock()
+	observers := make(map[string]models.AuditObserver, len(a.observers))
+	for _, o := range a.observers {
+		observers[observerName(o)] = o
+	}
+	a.mu.RUnlock()
+
+	return a.deadLetter.Replay(func(rec DeadLetterRecord) error {
+		observer, ok := observers[rec.Target]
+		if !ok {
+			return fmt.Errorf("observer %s is not attached", rec.Target)
+		}
+		return a.deliver(observer, rec.Event)
+	})
+}
+
+// observerName возвращает имя наблюдателя: результат String, если он реализован, иначе тип.
+func observerName(o models.AuditObserver) string {
+	if s, ok := o.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", o)
+}
+
+// SetEnabled включает или приостанавливает доставку событий наблюдателям.
+//
+// Пока доставка приостановлена, события отбрасываются; наблюдатели остаются подключёнными.
+func (a *AuditManager) SetEnabled(enabled bool) {
+	a.disabled.Store(!enabled)
+}
+
+// Enabled сообщает, доставляются ли события наблюдателям.
+func (a *AuditManager) Enabled() bool {
+	return !a.disabled.Load()
+}
+
+// Close доставляет события, оставшиеся в очереди (не дольше auditFlushTimeout), и закрывает
+// наблюдателей, удерживающих ресурсы (реализующих io.Closer), например отправляет
+// накопленные батчи Kafka. События, полученные после Close, доставляются синхронно.
+func (a *AuditManager) Close() error {
+	a.queueMu.Lock()
+	queue := a.queue
+	a.queue = nil
+	a.queueMu.Unlock()
+	if queue != nil {
+		close(queue)
+		flushed := make(chan struct{})
+		go func() {
+			a.workers.Wait()
+			close(flushed)
+		}()
+		select {
+		case <-flushed:
+		case <-time.After(auditFlushTimeout):
+			log.Printf("Audit queue flush timed out: %d events not delivered", len(queue))
+		}
+		if dropped := a.dropped.Load(); dropped > 0 {
+			log.Printf("Audit queue overflowed: %d events dropped", dropped)
+		}
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var err error
+	for _, observer := range a.observers {
+		if c, ok := observer.(io.Closer); ok {
+			err = errors.Join(err, c.Close())
 		}
 	}
+	return err
 }
 
 // HasObservers проверяет, есть ли подключённые наблюдатели.
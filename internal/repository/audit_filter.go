@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"fmt"
+	"net/netip"
+	"path"
+	"strings"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// auditFilterOperations — значения, допустимые в условии operations правил фильтрации.
+var auditFilterOperations = map[string]bool{
+	models.AuditOpUpdate:           true,
+	models.AuditOpBatch:            true,
+	models.AuditOpRead:             true,
+	models.AuditOpDelete:           true,
+	models.AuditInvalidSignature:   true,
+	models.AuditSubnetDenied:       true,
+	models.AuditInvalidCredentials: true,
+	models.AuditForbidden:          true,
+	models.AuditRateLimited:        true,
+	models.AuditRuntimeChange:      true,
+	models.AuditCounterCorrection:  true,
+	models.AuditMetricExpired:      true,
+	models.AuditMetricStale:        true,
+}
+
+// auditFilterRule — проверенное правило фильтрации событий аудита.
+type auditFilterRule struct {
+	exclude    bool
+	metrics    []string
+	networks   []netip.Prefix
+	operations map[string]bool
+}
+
+// AuditFilter решает по правилам config.AuditFilterRule, доставляется ли событие аудита наблюдателям.
+//
+// Нулевой указатель пропускает все события.
+type AuditFilter struct {
+	rules []auditFilterRule
+}
+
+// NewAuditFilter проверяет правила rules и создаёт по ним фильтр.
+//
+// Для пустого списка правил возвращает nil (фильтрация выключена).
+func NewAuditFilter(rules []config.AuditFilterRule) (*AuditFilter, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	f := &AuditFilter{rules: make([]auditFilterRule, len(rules))}
+	for i, r := range rules {
+		rule := &f.rules[i]
+		switch r.Action {
+		case "", config.AuditFilterExclude:
+			rule.exclude = true
+		case config.AuditFilterInclude:
+		default:
+			return nil, fmt.Errorf("audit filter %d: invalid action %q (want %q or %q)", i, r.Action, config.AuditFilterExclude, config.AuditFilterInclude)
+		}
+		for _, p := range r.Metrics {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("audit filter %d: invalid metric pattern %q: %w", i, p, err)
+			}
+		}
+		rule.metrics = r.Metrics
+		for _, n := range r.Networks {
+			prefix, err := parseNetwork(n)
+			if err != nil {
+				return nil, fmt.Errorf("audit filter %d: %w", i, err)
+			}
+			rule.networks = append(rule.networks, prefix)
+		}
+		if len(r.Operations) > 0 {
+			rule.operations = make(map[string]bool, len(r.Operations))
+		}
+		for _, op := range r.Operations {
+			if !auditFilterOperations[op] {
+				return nil, fmt.Errorf("audit filter %d: unknown operation %q", i, op)
+			}
+			rule.operations[op] = true
+		}
+	}
+	return f, nil
+}
+
+// parseNetwork разбирает подсеть CIDR или отдельный IP-адрес.
+func parseNetwork(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid network %q: want CIDR or IP address", s)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Allow сообщает, доставляется ли событие event наблюдателям.
+func (f *AuditFilter) Allow(event models.AuditEvent) bool {
+	if f == nil {
+		return true
+	}
+	for _, r := range f.rules {
+		if r.match(event) {
+			return !r.exclude
+		}
+	}
+	return true
+}
+
+// match сообщает, подходит ли событие под все условия правила.
+func (r auditFilterRule) match(event models.AuditEvent) bool {
+	if len(r.operations) > 0 && !r.operations[event.Operation] && !r.operations[event.Event] {
+		return false
+	}
+	if len(r.networks) > 0 {
+		addr, err := netip.ParseAddr(strings.TrimSpace(event.IPAddress))
+		if err != nil || !containsAddr(r.networks, addr.Unmap()) {
+			return false
+		}
+	}
+	if len(r.metrics) > 0 {
+		if len(event.Metrics) == 0 {
+			return false
+		}
+		for _, name := range event.Metrics {
+			if !matchAnyPattern(r.metrics, name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// containsAddr сообщает, входит ли addr хотя бы в одну из подсетей.
+func containsAddr(networks []netip.Prefix, addr netip.Addr) bool {
+	for _, n := range networks {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAnyPattern сообщает, подходит ли name хотя бы под один шаблон.
+func matchAnyPattern(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuditFilter_Allow проверяет отбор событий по шаблонам метрик, подсетям и операциям
+// и порядок применения правил.
+//
+// t — указатель на структуру теста.
+func TestAuditFilter_Allow(t *testing.T) {
+	agents := config.AuditFilterRule{Networks: []string{"10.0.0.0/8", "192.168.1.7"}}
+	runtime := config.AuditFilterRule{Metrics: []string{"runtime_*", "Gauge*"}}
+	reads := config.AuditFilterRule{Operations: []string{models.AuditOpRead, models.AuditRateLimited}}
+
+	tests := []struct {
+		name  string                   // Название теста
+		rules []config.AuditFilterRule // Правила фильтрации
+		event models.AuditEvent        // Проверяемое событие
+		want  bool                     // Ожидается ли доставка события
+	}{
+		{name: "NoRules", event: models.AuditEvent{IPAddress: "10.0.0.1"}, want: true},
+		{name: "NetworkMatch", rules: []config.AuditFilterRule{agents}, event: models.AuditEvent{IPAddress: "10.1.2.3"}, want: false},
+		{name: "SingleAddress", rules: []config.AuditFilterRule{agents}, event: models.AuditEvent{IPAddress: "192.168.1.7"}, want: false},
+		{name: "NetworkMiss", rules: []config.AuditFilterRule{agents}, event: models.AuditEvent{IPAddress: "192.168.1.8"}, want: true},
+		{name: "InvalidAddress", rules: []config.AuditFilterRule{agents}, event: models.AuditEvent{IPAddress: "unknown"}, want: true},
+		{name: "AllMetricsMatch", rules: []config.AuditFilterRule{runtime}, event: models.AuditEvent{Metrics: []string{"runtime_alloc", "GaugeHeap"}}, want: false},
+		{name: "SomeMetricsMatch", rules: []config.AuditFilterRule{runtime}, event: models.AuditEvent{Metrics: []string{"runtime_alloc", "orders"}}, want: true},
+		{name: "NoMetrics", rules: []config.AuditFilterRule{runtime}, event: models.AuditEvent{}, want: true},
+		{name: "Operation", rules: []config.AuditFilterRule{reads}, event: models.AuditEvent{Operation: models.AuditOpRead}, want: false},
+		{name: "EventType", rules: []config.AuditFilterRule{reads}, event: models.AuditEvent{Event: models.AuditRateLimited}, want: false},
+		{name: "OtherOperation", rules: []config.AuditFilterRule{reads}, event: models.AuditEvent{Operation: models.AuditOpUpdate}, want: true},
+		{
+			name:  "AllConditions",
+			rules: []config.AuditFilterRule{{Networks: []string{"10.0.0.0/8"}, Operations: []string{models.AuditOpBatch}}},
+			event: models.AuditEvent{IPAddress: "10.0.0.1", Operation: models.AuditOpUpdate},
+			want:  true,
+		},
+		{
+			name:  "FirstMatchWins",
+			rules: []config.AuditFilterRule{{Action: config.AuditFilterInclude, Metrics: []string{"orders"}}, agents},
+			event: models.AuditEvent{IPAddress: "10.0.0.1", Metrics: []string{"orders"}},
+			want:  true,
+		},
+		{
+			name:  "Allowlist",
+			rules: []config.AuditFilterRule{{Action: config.AuditFilterInclude, Operations: []string{models.AuditOpDelete}}, {}},
+			event: models.AuditEvent{Operation: models.AuditOpUpdate},
+			want:  false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := NewAuditFilter(tc.rules)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, f.Allow(tc.event))
+		})
+	}
+}
+
+// TestNewAuditFilter_Invalid проверяет отказ для правил с ошибками.
+//
+// t — указатель на структуру теста.
+func TestNewAuditFilter_Invalid(t *testing.T) {
+	tests := []struct {
+		name string                 // Название теста
+		rule config.AuditFilterRule // Правило с ошибкой
+	}{
+		{name: "Action", rule: config.AuditFilterRule{Action: "drop"}},
+		{name: "Pattern", rule: config.AuditFilterRule{Metrics: []string{"runtime_["}}},
+		{name: "Network", rule: config.AuditFilterRule{Networks: []string{"10.0.0.0/33"}}},
+		{name: "Operation", rule: config.AuditFilterRule{Operations: []string{"write"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewAuditFilter([]config.AuditFilterRule{tc.rule})
+			require.Error(t, err)
+		})
+	}
+}
+
+// TestAuditManager_Filter проверяет, что исключённые фильтром события не доставляются и учитываются.
+//
+// t — указатель на структуру теста.
+func TestAuditManager_Filter(t *testing.T) {
+	f, err := NewAuditFilter([]config.AuditFilterRule{{Networks: []string{"10.0.0.0/8"}}})
+	require.NoError(t, err)
+
+	mgr := NewAuditManager()
+	mgr.SetFilter(f)
+	obs := &recordingObserver{}
+	mgr.Attach(obs)
+
+	mgr.Notify(models.AuditEvent{IPAddress: "10.0.0.1", Metrics: []string{"cpu"}})
+	mgr.Notify(models.AuditEvent{IPAddress: "127.0.0.1", Metrics: []string{"cpu"}})
+
+	require.Len(t, obs.events, 1)
+	require.Equal(t, "127.0.0.1", obs.events[0].IPAddress)
+	require.Equal(t, uint64(1), mgr.Filtered())
+}
@@ -8,9 +8,11 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/RoGogDBD/metric-alerter/internal/config"
 	models "github.com/RoGogDBD/metric-alerter/internal/model"
 	"github.com/stretchr/testify/require"
 )
@@ -66,6 +68,63 @@ func TestFileAuditObserver_OnAuditEvent_TableDriven(t *testing.T) {
 	}
 }
 
+// TestFileAuditObserver_Rotation проверяет ротацию файла аудита по размеру, сжатие архивов
+// и удаление архивов сверх MaxBackups.
+//
+// t — указатель на структуру теста.
+func TestFileAuditObserver_Rotation(t *testing.T) {
+	tests := []struct {
+		name        string                     // Название теста
+		rotation    config.AuditRotationConfig // Параметры ротации
+		wantBackups int                        // Ожидаемое число архивов
+		wantExt     string                     // Ожидаемое расширение архивов
+	}{
+		{name: "Disabled", rotation: config.AuditRotationConfig{}},
+		{name: "Plain", rotation: config.AuditRotationConfig{MaxSizeMB: 1, MaxBackups: 5}, wantBackups: 2, wantExt: ".log"},
+		{name: "Compressed", rotation: config.AuditRotationConfig{MaxSizeMB: 1, MaxBackups: 1, Compress: true}, wantBackups: 1, wantExt: ".gz"},
+	}
+
+	// ~256 КБ на событие: 10 событий дают два переполнения файла размером 1 МБ.
+	metrics := make([]string, 256)
+	for i := range metrics {
+		metrics[i] = string(bytes.Repeat([]byte("m"), 1020))
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			obs := NewFileAuditObserver(filepath.Join(dir, "audit.log"))
+			obs.SetRotation(tc.rotation)
+			require.Equal(t, tc.rotation.MaxSizeMB > 0, obs.Rotates())
+			defer func() { require.NoError(t, obs.Close()) }()
+
+			for i := 0; i < 10; i++ {
+				require.NoError(t, obs.OnAuditEvent(models.AuditEvent{Metrics: metrics}))
+			}
+
+			// Сжатие и удаление архивов lumberjack выполняет в фоне.
+			require.Eventually(t, func() bool {
+				backups, _ := filepath.Glob(filepath.Join(dir, "audit-*"))
+				if len(backups) != tc.wantBackups {
+					return false
+				}
+				for _, b := range backups {
+					if filepath.Ext(b) != tc.wantExt {
+						return false
+					}
+				}
+				return true
+			}, 5*time.Second, 10*time.Millisecond)
+
+			info, err := os.Stat(filepath.Join(dir, "audit.log"))
+			require.NoError(t, err)
+			if tc.rotation.MaxSizeMB > 0 {
+				require.LessOrEqual(t, info.Size(), int64(tc.rotation.MaxSizeMB)<<20)
+			}
+		})
+	}
+}
+
 // TestHTTPAuditObserver_OnAuditEvent_TableDriven выполняет табличные тесты для метода OnAuditEvent структуры HTTPAuditObserver.
 //
 // Проверяет, что события аудита корректно отправляются на HTTP-сервер и обрабатываются различные коды ответа.
@@ -165,3 +224,193 @@ func TestAuditManager_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+// TestAuditManager_SetEnabled проверяет приостановку доставки событий без отключения наблюдателей.
+func TestAuditManager_SetEnabled(t *testing.T) {
+	var received int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	mgr := NewAuditManager()
+	mgr.Attach(NewHTTPAuditObserver(srv.URL))
+	require.True(t, mgr.Enabled())
+
+	mgr.SetEnabled(false)
+	mgr.Notify(models.AuditEvent{Metrics: []string{"m"}})
+	require.False(t, mgr.Enabled())
+	require.True(t, mgr.HasObservers())
+	require.Zero(t, received)
+
+	mgr.SetEnabled(true)
+	mgr.Notify(models.AuditEvent{Metrics: []string{"m"}})
+	require.Equal(t, 1, received)
+}
+
+// recordingObserver запоминает полученные события аудита.
+type recordingObserver struct {
+	events []models.AuditEvent
+}
+
+// OnAuditEvent запоминает событие.
+func (o *recordingObserver) OnAuditEvent(event models.AuditEvent) error {
+	o.events = append(o.events, event)
+	return nil
+}
+
+// TestAuditManager_EventIDs проверяет, что событию присваивается идентификатор генератора,
+// а заданный идентификатор и идентификатор запроса сохраняются.
+func TestAuditManager_EventIDs(t *testing.T) {
+	obs := &recordingObserver{}
+	mgr := NewAuditManager()
+	mgr.Attach(obs)
+	mgr.SetIDGenerator(func() string { return "event-1" })
+
+	mgr.Notify(models.AuditEvent{Metrics: []string{"m"}, RequestID: "req-1"})
+	mgr.Notify(models.AuditEvent{Metrics: []string{"m"}, ID: "preset"})
+
+	require.Len(t, obs.events, 2)
+	require.Equal(t, "event-1", obs.events[0].ID)
+	require.Equal(t, "req-1", obs.events[0].RequestID)
+	require.Equal(t, "preset", obs.events[1].ID)
+}
+
+// gatedObserver доставляет события только после закрытия release.
+type gatedObserver struct {
+	release   chan struct{}
+	started   chan struct{}
+	mu        sync.Mutex
+	delivered []string
+}
+
+// OnAuditEvent сообщает о начале доставки, ждёт release и запоминает идентификатор события.
+func (o *gatedObserver) OnAuditEvent(event models.AuditEvent) error {
+	select {
+	case o.started <- struct{}{}:
+	default:
+	}
+	<-o.release
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.delivered = append(o.delivered, event.ID)
+	return nil
+}
+
+// TestAuditManager_Queue проверяет, что с очередью медленный наблюдатель не задерживает Notify,
+// при переполнении событие ждёт места или отбрасывается по политике, а Close доставляет остаток.
+func TestAuditManager_Queue(t *testing.T) {
+	tests := []struct {
+		name          string   // Название теста
+		policy        string   // Политика переполнения очереди
+		wantDelivered []string // Ожидаемые доставленные события
+		wantDropped   uint64   // Ожидаемое число отброшенных событий
+	}{
+		{name: "Drop", policy: config.AuditQueueDrop, wantDelivered: []string{"e1", "e2"}, wantDropped: 1},
+		{name: "Block", policy: config.AuditQueueBlock, wantDelivered: []string{"e1", "e2", "e3"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			obs := &gatedObserver{release: make(chan struct{}), started: make(chan struct{}, 1)}
+			mgr := NewAuditManager()
+			mgr.Attach(obs)
+			mgr.SetQueue(config.AuditQueueConfig{Size: 1, Workers: 1, Policy: tc.policy})
+
+			// e1 занимает воркер, e2 — очередь; e3 не помещается.
+			mgr.Notify(models.AuditEvent{ID: "e1"})
+			<-obs.started
+			mgr.Notify(models.AuditEvent{ID: "e2"})
+			require.Equal(t, 1, mgr.Queued())
+
+			notified := make(chan struct{})
+			go func() {
+				mgr.Notify(models.AuditEvent{ID: "e3"})
+				close(notified)
+			}()
+			if tc.policy == config.AuditQueueBlock {
+				select {
+				case <-notified:
+					t.Fatal("Notify returned while the queue was full")
+				case <-time.After(50 * time.Millisecond):
+				}
+			} else {
+				<-notified
+			}
+
+			close(obs.release)
+			<-notified
+			require.NoError(t, mgr.Close())
+			require.Equal(t, tc.wantDelivered, obs.delivered)
+			require.Equal(t, tc.wantDropped, mgr.Dropped())
+
+			// После Close события доставляются синхронно.
+			mgr.Notify(models.AuditEvent{ID: "late"})
+			require.Equal(t, "late", obs.delivered[len(obs.delivered)-1])
+		})
+	}
+}
+
+// TestAuditManager_DeadLetter проверяет повторные попытки доставки, сохранение события в очередь
+// недоставленных после всех попыток и повторную доставку из очереди.
+func TestAuditManager_DeadLetter(t *testing.T) {
+	var fail bool
+	var received int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	queue, err := NewDeadLetterQueue(filepath.Join(t.TempDir(), "dead", "audit.jsonl"))
+	require.NoError(t, err)
+	mgr := NewAuditManager()
+	mgr.Attach(NewHTTPAuditObserver(srv.URL))
+	mgr.SetRetry(3, 0)
+	mgr.SetDeadLetter(queue)
+
+	fail = true
+	mgr.Notify(models.AuditEvent{ID: "e1", Metrics: []string{"m"}})
+	require.Equal(t, 3, received)
+
+	records, err := mgr.DeadLetters()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, DeadLetterAudit, records[0].Kind)
+	require.Equal(t, "http:"+srv.URL, records[0].Target)
+	require.Equal(t, 3, records[0].Attempts)
+	require.Contains(t, records[0].Reason, "503")
+	require.Equal(t, "e1", records[0].Event.ID)
+
+	replayed, remaining, err := mgr.ReplayDeadLetters()
+	require.NoError(t, err)
+	require.Equal(t, 0, replayed)
+	require.Equal(t, 1, remaining)
+	records, err = mgr.DeadLetters()
+	require.NoError(t, err)
+	require.Equal(t, 4, records[0].Attempts)
+
+	fail = false
+	replayed, remaining, err = mgr.ReplayDeadLetters()
+	require.NoError(t, err)
+	require.Equal(t, 1, replayed)
+	require.Zero(t, remaining)
+	records, err = mgr.DeadLetters()
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
+
+// TestAuditManager_ReplayWithoutQueue проверяет отказ повторной доставки без очереди недоставленных событий.
+func TestAuditManager_ReplayWithoutQueue(t *testing.T) {
+	mgr := NewAuditManager()
+	_, _, err := mgr.ReplayDeadLetters()
+	require.ErrorIs(t, err, ErrNoDeadLetterQueue)
+	records, err := mgr.DeadLetters()
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
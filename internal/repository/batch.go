@@ -0,0 +1,55 @@
+package repository
+
+import "time"
+
+// batchUpdater реализуется хранилищами, применяющими пакет обновлений за одну блокировку.
+type batchUpdater interface {
+	UpdateBatch(updates []MetricUpdate)
+}
+
+// UpdateBatch применяет пакет обновлений к хранилищу storage.
+//
+// Если хранилище поддерживает пакетное обновление (например, MemStorage), пакет применяется
+// за одну блокировку; иначе обновления применяются по одному через SetGauge и AddCounter,
+// чтобы декораторы (WALStorage, NormalizingStorage и др.) видели каждое изменение.
+// Обновления без значения пропускаются.
+func UpdateBatch(storage Storage, updates []MetricUpdate) {
+	if b, ok := storage.(batchUpdater); ok {
+		b.UpdateBatch(updates)
+		return
+	}
+	for _, u := range updates {
+		switch {
+		case u.Type == "gauge" && u.FloatVal != nil:
+			storage.SetGauge(u.Name, *u.FloatVal)
+		case u.Type == "counter" && u.IntVal != nil:
+			storage.AddCounter(u.Name, *u.IntVal)
+		}
+	}
+}
+
+// UpdateBatch применяет пакет обновлений за одну блокировку.
+//
+// Номер поколения увеличивается один раз на пакет, если в нём было хотя бы одно изменение;
+// все метрики пакета получают это поколение (см. Changes).
+func (s *MemStorage) UpdateBatch(updates []MetricUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := change{gen: s.gen.Load() + 1, at: time.Now()}
+	changed := false
+	for _, u := range updates {
+		switch {
+		case u.Type == "gauge" && u.FloatVal != nil:
+			s.gauge[u.Name] = *u.FloatVal
+			s.gaugeChanges[u.Name] = c
+			changed = true
+		case u.Type == "counter" && u.IntVal != nil:
+			s.counter[u.Name] += *u.IntVal
+			s.counterChanges[u.Name] = c
+			changed = true
+		}
+	}
+	if changed {
+		s.gen.Add(1)
+	}
+}
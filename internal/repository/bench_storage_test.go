@@ -50,3 +50,52 @@ func BenchmarkMemStorage_SetGet(b *testing.B) {
 	b.StopTimer()
 	maybeWriteHeapProfile(b)
 }
+
+// BenchmarkShardedMemStorage_SetGet измеряет производительность тех же операций, что и
+// BenchmarkMemStorage_SetGet, для ShardedMemStorage с 16 сегментами.
+//
+// b — указатель на структуру теста/бенчмарка.
+func BenchmarkShardedMemStorage_SetGet(b *testing.B) {
+	s := NewShardedMemStorage(16)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		name := "metric" + strconv.Itoa(i%1000)
+		s.SetGauge(name, float64(i))
+		_, _ = s.GetGauge(name)
+		s.AddCounter(name, int64(i%10))
+		_, _ = s.GetCounter(name)
+	}
+	b.StopTimer()
+	maybeWriteHeapProfile(b)
+}
+
+// BenchmarkStorage_SetGetParallel сравнивает MemStorage и ShardedMemStorage
+// при параллельных обновлениях из нескольких горутин (b.RunParallel).
+//
+// b — указатель на структуру теста/бенчмарка.
+func BenchmarkStorage_SetGetParallel(b *testing.B) {
+	impls := []struct {
+		name string
+		new  func() Storage
+	}{
+		{"mem", NewMemStorage},
+		{"sharded16", func() Storage { return NewShardedMemStorage(16) }},
+	}
+	for _, impl := range impls {
+		b.Run(impl.name, func(b *testing.B) {
+			s := impl.new()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					name := "metric" + strconv.Itoa(i%1000)
+					s.SetGauge(name, float64(i))
+					_, _ = s.GetGauge(name)
+					s.AddCounter(name, int64(i%10))
+					_, _ = s.GetCounter(name)
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ErrCounterOverflow возвращается, если значение счётчика выходит за пределы int64.
+var ErrCounterOverflow = errors.New("counter value out of int64 range")
+
+// AddCounterChecked возвращает сумму current и delta или ErrCounterOverflow при переполнении int64.
+func AddCounterChecked(current, delta int64) (int64, error) {
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return 0, ErrCounterOverflow
+	}
+	return current + delta, nil
+}
+
+// ParseCounter разбирает значение счётчика без потери точности.
+//
+// Значения вне диапазона int64 дают ErrCounterOverflow, остальные ошибки разбора возвращаются как есть.
+func ParseCounter(s string) (int64, error) {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, fmt.Errorf("%w: %s", ErrCounterOverflow, s)
+	}
+	return v, err
+}
+
+// CheckCounterOverflow проверяет, что применение приращений deltas (имя → сумма приращений)
+// к счётчикам storage не приведёт к переполнению.
+//
+// Возвращает имя первого переполняющегося счётчика и ErrCounterOverflow.
+// Проверка не атомарна с последующей записью: параллельные обновления того же счётчика
+// между проверкой и записью не учитываются.
+func CheckCounterOverflow(storage Storage, deltas map[string]int64) (string, error) {
+	for name, delta := range deltas {
+		current, _ := storage.GetCounter(name)
+		if _, err := AddCounterChecked(current, delta); err != nil {
+			return name, err
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownCounterSource возвращается, если источник не передавал приращений счётчика.
+var ErrUnknownCounterSource = errors.New("unknown counter source")
+
+type (
+	// CounterSource — вклад одного источника (агента) в значение счётчика.
+	//
+	// Поля:
+	//   - Source: идентификатор агента или IP-адрес клиента
+	//   - Value: сумма приращений, принятых от источника
+	CounterSource struct {
+		Source string `json:"source"`
+		Value  int64  `json:"value"`
+	}
+
+	// CounterSources учитывает приращения счётчиков отдельно по каждому источнику.
+	//
+	// Хранилище содержит только суммарное значение счётчика; CounterSources позволяет
+	// исправить или исключить вклад одного источника (например, агента, повторно отправившего
+	// старые приращения), не сбрасывая счётчик целиком. Учёт ведётся в памяти с момента
+	// запуска сервера: вклад, принятый до перезапуска, отдельно не известен.
+	CounterSources struct {
+		mu        sync.Mutex
+		sources   map[string]map[string]int64 // имя счётчика → источник → сумма приращений
+		normalize bool                        // Приводить имена к виду NormalizeMetricID
+	}
+)
+
+// NewCounterSources создаёт пустой учёт вкладов источников.
+//
+// normalize должен совпадать с нормализацией имён в хранилище (см. NormalizingStorage),
+// чтобы разные написания одного счётчика учитывались вместе.
+func NewCounterSources(normalize bool) *CounterSources {
+	return &CounterSources{sources: make(map[string]map[string]int64), normalize: normalize}
+}
+
+// key возвращает имя счётчика, под которым учитываются вклады.
+func (c *CounterSources) key(name string) string {
+	if c.normalize {
+		return NormalizeMetricID(name)
+	}
+	return name
+}
+
+// Add учитывает приращение delta счётчика name от источника source.
+func (c *CounterSources) Add(name, source string, delta int64) {
+	name = c.key(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bySource, ok := c.sources[name]
+	if !ok {
+		bySource = make(map[string]int64)
+		c.sources[name] = bySource
+	}
+	bySource[source] += delta
+}
+
+// Sources возвращает вклады источников в счётчик name, упорядоченные по источнику.
+func (c *CounterSources) Sources(name string) []CounterSource {
+	name = c.key(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]CounterSource, 0, len(c.sources[name]))
+	for source, v := range c.sources[name] {
+		out = append(out, CounterSource{Source: source, Value: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Source < out[j].Source })
+	return out
+}
+
+// Adjust исправляет вклад источника source в счётчик name на delta и применяет ту же
+// поправку к суммарному значению в storage.
+//
+// Возвращает ErrUnknownCounterSource, если источник не передавал приращений счётчика,
+// и ErrCounterOverflow, если поправка переполняет суммарное значение.
+func (c *CounterSources) Adjust(storage Storage, name, source string, delta int64) error {
+	name = c.key(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bySource := c.sources[name]
+	current, ok := bySource[source]
+	if !ok {
+		return ErrUnknownCounterSource
+	}
+	if _, err := AddCounterChecked(current, delta); err != nil {
+		return err
+	}
+	if _, err := CheckCounterOverflow(storage, map[string]int64{name: delta}); err != nil {
+		return err
+	}
+	storage.AddCounter(name, delta)
+	bySource[source] = current + delta
+	return nil
+}
+
+// Exclude вычитает весь вклад источника source из счётчика name в storage и забывает источник.
+//
+// Возвращает вычтенный вклад или ErrUnknownCounterSource. Приращения, принятые от источника
+// позже, снова учитываются.
+func (c *CounterSources) Exclude(storage Storage, name, source string) (int64, error) {
+	name = c.key(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bySource := c.sources[name]
+	contribution, ok := bySource[source]
+	if !ok {
+		return 0, ErrUnknownCounterSource
+	}
+	if contribution == math.MinInt64 {
+		return 0, ErrCounterOverflow
+	}
+	if _, err := CheckCounterOverflow(storage, map[string]int64{name: -contribution}); err != nil {
+		return 0, err
+	}
+	storage.AddCounter(name, -contribution)
+	delete(bySource, source)
+	if len(bySource) == 0 {
+		delete(c.sources, name)
+	}
+	return contribution, nil
+}
+
+// Forget забывает вклады всех источников в счётчик name, например после его удаления
+// из хранилища по истечении срока хранения. Хранилище не изменяется.
+func (c *CounterSources) Forget(name string) {
+	name = c.key(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sources, name)
+}
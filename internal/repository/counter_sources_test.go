@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCounterSources_Normalize проверяет, что разные написания счётчика учитываются вместе
+// при нормализации имён и исключение источника вычитается из нормализованного счётчика.
+func TestCounterSources_Normalize(t *testing.T) {
+	storage := NewNormalizingStorage(NewMemStorage())
+	sources := NewCounterSources(true)
+	for _, name := range []string{"Requests", " requests "} {
+		storage.AddCounter(name, 2)
+		sources.Add(name, "a", 2)
+	}
+	storage.AddCounter("requests", 1)
+	sources.Add("requests", "b", 1)
+
+	require.Equal(t, []CounterSource{{Source: "a", Value: 4}, {Source: "b", Value: 1}}, sources.Sources("REQUESTS"))
+
+	removed, err := sources.Exclude(storage, "Requests", "a")
+	require.NoError(t, err)
+	require.Equal(t, int64(4), removed)
+	v, _ := storage.GetCounter("requests")
+	require.Equal(t, int64(1), v)
+
+	_, err = sources.Exclude(storage, "requests", "a")
+	require.ErrorIs(t, err, ErrUnknownCounterSource)
+}
+
+// TestCounterSources_Overflow проверяет, что поправка, переполняющая счётчик, не применяется.
+func TestCounterSources_Overflow(t *testing.T) {
+	storage := NewMemStorage()
+	sources := NewCounterSources(false)
+	storage.AddCounter("c", math.MinInt64)
+	sources.Add("c", "a", math.MinInt64)
+
+	_, err := sources.Exclude(storage, "c", "a")
+	require.ErrorIs(t, err, ErrCounterOverflow)
+	require.ErrorIs(t, sources.Adjust(storage, "c", "a", -1), ErrCounterOverflow)
+
+	v, _ := storage.GetCounter("c")
+	require.Equal(t, int64(math.MinInt64), v)
+	require.Equal(t, []CounterSource{{Source: "a", Value: math.MinInt64}}, sources.Sources("c"))
+}
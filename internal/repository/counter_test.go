@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestAddCounterChecked проверяет сложение счётчиков с обнаружением переполнения int64.
+//
+// t — указатель на структуру теста.
+func TestAddCounterChecked(t *testing.T) {
+	tests := []struct {
+		name    string
+		current int64
+		delta   int64
+		want    int64
+		wantErr bool
+	}{
+		{"regular", 1, 2, 3, false},
+		{"up to max", math.MaxInt64 - 1, 1, math.MaxInt64, false},
+		{"positive overflow", math.MaxInt64, 1, 0, true},
+		{"down to min", math.MinInt64 + 1, -1, math.MinInt64, false},
+		{"negative overflow", math.MinInt64, -1, 0, true},
+		{"max plus min", math.MaxInt64, math.MinInt64, -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AddCounterChecked(tt.current, tt.delta)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrCounterOverflow)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestParseCounter проверяет разбор крайних значений счётчика и явную ошибку переполнения.
+//
+// t — указатель на структуру теста.
+func TestParseCounter(t *testing.T) {
+	v, err := ParseCounter("9223372036854775807")
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MaxInt64), v)
+
+	v, err = ParseCounter("-9223372036854775808")
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MinInt64), v)
+
+	_, err = ParseCounter("9223372036854775808")
+	require.ErrorIs(t, err, ErrCounterOverflow)
+
+	_, err = ParseCounter("1.5")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrCounterOverflow)
+}
+
+// TestCheckCounterOverflow проверяет обнаружение переполнения с учётом текущих значений хранилища.
+//
+// t — указатель на структуру теста.
+func TestCheckCounterOverflow(t *testing.T) {
+	s := NewMemStorage()
+	s.AddCounter("big", math.MaxInt64-10)
+
+	_, err := CheckCounterOverflow(s, map[string]int64{"big": 10, "new": math.MaxInt64})
+	require.NoError(t, err)
+
+	name, err := CheckCounterOverflow(s, map[string]int64{"big": 11})
+	require.ErrorIs(t, err, ErrCounterOverflow)
+	require.Equal(t, "big", name)
+}
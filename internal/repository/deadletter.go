@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// DeadLetterAudit — вид записи очереди недоставленных событий: событие аудита.
+const DeadLetterAudit = "audit"
+
+// ErrNoDeadLetterQueue возвращается при повторной доставке, если очередь недоставленных событий не задана.
+var ErrNoDeadLetterQueue = errors.New("dead letter queue is not configured")
+
+type (
+	// DeadLetterRecord — событие, которое не удалось доставить после всех попыток.
+	//
+	// Поля:
+	//   - Kind: вид события (DeadLetterAudit)
+	//   - Target: получатель, которому событие не доставлено (см. observerName)
+	//   - Reason: текст последней ошибки доставки
+	//   - Attempts: число выполненных попыток, включая повторы при replay
+	//   - FailedAt: время последней неудачной попытки
+	//   - Event: недоставленное событие
+	DeadLetterRecord struct {
+		Kind     string            `json:"kind"`
+		Target   string            `json:"target"`
+		Reason   string            `json:"reason"`
+		Attempts int               `json:"attempts"`
+		FailedAt time.Time         `json:"failed_at"`
+		Event    models.AuditEvent `json:"event"`
+	}
+
+	// DeadLetterQueue хранит недоставленные события в файле, по одной записи JSON в строке.
+	//
+	// Записи добавляются в конец файла и удаляются только после успешной повторной доставки
+	// (см. Replay), поэтому события не теряются при перезапуске сервера.
+	DeadLetterQueue struct {
+		path string
+		mu   sync.Mutex
+	}
+)
+
+// NewDeadLetterQueue создаёт очередь недоставленных событий в файле path.
+//
+// Директория файла создаётся при необходимости.
+func NewDeadLetterQueue(path string) (*DeadLetterQueue, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create dead letter directory: %w", err)
+	}
+	return &DeadLetterQueue{path: path}, nil
+}
+
+// Append добавляет запись в конец очереди и синхронизирует файл с диском.
+func (q *DeadLetterQueue) Append(rec DeadLetterRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	f, err := os.OpenFile(q.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to sync dead letter file: %w", err)
+	}
+	return f.Close()
+}
+
+// List возвращает записи очереди в порядке добавления.
+func (q *DeadLetterQueue) List() ([]DeadLetterRecord, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.read()
+}
+
+// Replay повторно доставляет записи очереди функцией deliver.
+//
+// Доставленные записи удаляются из очереди; у остальных обновляются причина, число попыток
+// и время. Возвращает число доставленных и оставшихся записей.
+func (q *DeadLetterQueue) Replay(deliver func(DeadLetterRecord) error) (replayed, remaining int, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	records, err := q.read()
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(records) == 0 {
+		return 0, 0, nil
+	}
+
+	var kept []DeadLetterRecord
+	for _, rec := range records {
+		if derr := deliver(rec); derr != nil {
+			rec.Reason = derr.Error()
+			rec.Attempts++
+			rec.FailedAt = time.Now()
+			kept = append(kept, rec)
+			continue
+		}
+		replayed++
+	}
+	if err := q.write(kept); err != nil {
+		return 0, len(records), err
+	}
+	return replayed, len(kept), nil
+}
+
+// read читает записи файла очереди. Вызывается под q.mu.
+func (q *DeadLetterQueue) read() ([]DeadLetterRecord, error) {
+	f, err := os.Open(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var records []DeadLetterRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec DeadLetterRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("failed to parse dead letter: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead letter file: %w", err)
+	}
+	return records, nil
+}
+
+// write атомарно заменяет содержимое файла очереди записями records. Вызывается под q.mu.
+func (q *DeadLetterQueue) write(records []DeadLetterRecord) error {
+	tmp := q.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create dead letter file: %w", err)
+	}
+	enc := json.NewEncoder(f)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to write dead letter: %w", err)
+		}
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to sync dead letter file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close dead letter file: %w", err)
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return fmt.Errorf("failed to replace dead letter file: %w", err)
+	}
+	return nil
+}
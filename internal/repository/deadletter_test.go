@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeadLetterQueue_Replay проверяет, что повторная доставка удаляет доставленные записи
+// и сохраняет остальные с обновлённой причиной отказа.
+func TestDeadLetterQueue_Replay(t *testing.T) {
+	q, err := NewDeadLetterQueue(filepath.Join(t.TempDir(), "dead.jsonl"))
+	require.NoError(t, err)
+
+	records, err := q.List()
+	require.NoError(t, err)
+	require.Empty(t, records)
+
+	failedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, q.Append(DeadLetterRecord{
+			Kind: DeadLetterAudit, Target: "http:x", Reason: "timeout", Attempts: 1,
+			FailedAt: failedAt, Event: models.AuditEvent{ID: id},
+		}))
+	}
+
+	replayed, remaining, err := q.Replay(func(rec DeadLetterRecord) error {
+		if rec.Event.ID == "b" {
+			return errors.New("still down")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, replayed)
+	require.Equal(t, 1, remaining)
+
+	records, err = q.List()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "b", records[0].Event.ID)
+	require.Equal(t, "still down", records[0].Reason)
+	require.Equal(t, 2, records[0].Attempts)
+	require.True(t, records[0].FailedAt.After(failedAt))
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type (
+	// StorageOptions содержит параметры, общие для всех реализаций хранилища.
+	//
+	// Поля:
+	//   - Shards: количество сегментов in-memory хранилища (см. NewStorage)
+	StorageOptions struct {
+		Shards int
+	}
+
+	// StorageBackend — хранилище, созданное фабрикой, вместе с его внешними ресурсами.
+	//
+	// Поля:
+	//   - Storage: хранилище метрик
+	//   - DB: пул подключений к PostgreSQL (nil, если хранилище не использует PostgreSQL)
+	//   - Sink: внешнее хранилище, в которое выгружаются метрики после каждого обновления
+	//     (nil, если хранилище находится только в памяти или использует PostgreSQL через DB)
+	//   - Preloaded: сохранённые метрики уже загружены в Storage при открытии; повторное
+	//     восстановление из снимка или WAL удвоило бы счётчики
+	StorageBackend struct {
+		Storage   Storage
+		DB        *pgxpool.Pool
+		Sink      Sink
+		Preloaded bool
+		close     func()
+	}
+
+	// StorageFactory создаёт хранилище по строке подключения.
+	StorageFactory func(ctx context.Context, dsn string, opts StorageOptions) (*StorageBackend, error)
+)
+
+var (
+	storageFactoriesMu sync.RWMutex
+	storageFactories   = map[string]StorageFactory{}
+)
+
+func init() {
+	RegisterStorage("memory", openMemoryStorage)
+	RegisterStorage("postgres", openPostgresStorage)
+	RegisterStorage("postgresql", openPostgresStorage)
+	RegisterStorage("redis", openRedisStorage)
+	RegisterStorage("rediss", openRedisStorage)
+	RegisterStorage("sqlite", openSQLiteStorage)
+}
+
+// RegisterStorage регистрирует фабрику хранилища для схемы DSN.
+//
+// Повторная регистрация схемы заменяет ранее зарегистрированную фабрику.
+func RegisterStorage(scheme string, factory StorageFactory) {
+	storageFactoriesMu.Lock()
+	defer storageFactoriesMu.Unlock()
+	storageFactories[strings.ToLower(scheme)] = factory
+}
+
+// RegisteredStorageSchemes возвращает отсортированный список зарегистрированных схем DSN.
+func RegisteredStorageSchemes() []string {
+	storageFactoriesMu.RLock()
+	defer storageFactoriesMu.RUnlock()
+	schemes := make([]string, 0, len(storageFactories))
+	for s := range storageFactories {
+		schemes = append(schemes, s)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// StorageScheme определяет схему хранилища по DSN.
+//
+// Пустой DSN соответствует in-memory хранилищу, а DSN без схемы
+// (формат "host=... user=...") — PostgreSQL.
+func StorageScheme(dsn string) string {
+	if dsn == "" {
+		return "memory"
+	}
+	i := strings.Index(dsn, "://")
+	if i <= 0 {
+		return "postgres"
+	}
+	return strings.ToLower(dsn[:i])
+}
+
+// OpenStorage создаёт хранилище, выбирая фабрику по схеме DSN.
+//
+// ctx — контекст для подключения к внешним хранилищам.
+// dsn — строка подключения (пустая строка — in-memory хранилище).
+// opts — общие параметры хранилища.
+func OpenStorage(ctx context.Context, dsn string, opts StorageOptions) (*StorageBackend, error) {
+	scheme := StorageScheme(dsn)
+
+	storageFactoriesMu.RLock()
+	factory, ok := storageFactories[scheme]
+	storageFactoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage scheme %q (supported: %s)",
+			scheme, strings.Join(RegisteredStorageSchemes(), ", "))
+	}
+	return factory(ctx, dsn, opts)
+}
+
+// Close освобождает внешние ресурсы хранилища.
+func (b *StorageBackend) Close() {
+	if b.close != nil {
+		b.close()
+	}
+}
+
+// openMemoryStorage создаёт in-memory хранилище.
+func openMemoryStorage(_ context.Context, _ string, opts StorageOptions) (*StorageBackend, error) {
+	return &StorageBackend{Storage: NewStorage(opts.Shards)}, nil
+}
+
+// openPostgresStorage создаёт in-memory хранилище с синхронизацией в PostgreSQL.
+func openPostgresStorage(ctx context.Context, dsn string, opts StorageOptions) (*StorageBackend, error) {
+	pool, err := db.InitDB(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &StorageBackend{Storage: NewStorage(opts.Shards), DB: pool, close: pool.Close}, nil
+}
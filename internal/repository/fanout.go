@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/timing"
+	"github.com/RoGogDBD/metric-alerter/internal/tracing"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type (
+	// Sink — внешнее хранилище, в которое выгружаются метрики основного хранилища в памяти.
+	Sink interface {
+		// Sync записывает текущее состояние основного хранилища.
+		Sync(ctx context.Context) error
+		// Delete удаляет метрики, удалённые из основного хранилища (см. Storage.Expire):
+		// Sync только добавляет и обновляет метрики.
+		Delete(ctx context.Context, metrics []MetricInfo) error
+	}
+
+	// SinkFunc позволяет использовать функцию записи как Sink без удаления метрик.
+	SinkFunc func(ctx context.Context) error
+
+	// dbSink — Sink, выгружающий метрики в PostgreSQL.
+	dbSink struct {
+		storage Storage
+		db      *pgxpool.Pool
+	}
+
+	// FanOutBackend описывает хранилище, в которое FanOut дублирует записи.
+	//
+	// Поля:
+	//   - Name: имя хранилища для журнала и сообщений об ошибках
+	//   - Sink: хранилище
+	//   - Async: запись выполняется в фоне с периодом Interval, а не после каждого обновления
+	//   - Interval: период записи в режиме Async
+	//   - FailOnError: ошибка синхронной записи завершает запрос на обновление
+	FanOutBackend struct {
+		Name        string
+		Sink        Sink
+		Async       bool
+		Interval    time.Duration
+		FailOnError bool
+	}
+
+	// FanOut дублирует записи основного хранилища в несколько внешних хранилищ.
+	//
+	// Синхронные хранилища записываются вызовом Sync после каждого обновления,
+	// асинхронные — в фоне (Run). Нулевой указатель — допустимый FanOut без хранилищ.
+	FanOut struct {
+		backends []FanOutBackend
+		onError  func(backend string, err error) // Получатель ошибок записи (nil — только журнал)
+	}
+)
+
+// Sync вызывает функцию f.
+func (f SinkFunc) Sync(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Delete ничего не делает: SinkFunc не поддерживает удаление метрик.
+func (f SinkFunc) Delete(context.Context, []MetricInfo) error {
+	return nil
+}
+
+// Sync сохраняет снимок, если хранилище изменилось (см. Save), что позволяет использовать
+// SnapshotSaver как Sink.
+func (s *SnapshotSaver) Sync(context.Context) error {
+	_, err := s.Save()
+	return err
+}
+
+// Delete сохраняет снимок: он содержит всё хранилище, поэтому удалённые метрики
+// исчезают из него при сохранении.
+func (s *SnapshotSaver) Delete(ctx context.Context, _ []MetricInfo) error {
+	return s.Sync(ctx)
+}
+
+// DBSink возвращает Sink, выгружающий все метрики storage в PostgreSQL (см. SyncToDB)
+// и удаляющий из него метрики (см. DeleteFromDB).
+func DBSink(storage Storage, db *pgxpool.Pool) Sink {
+	return dbSink{storage: storage, db: db}
+}
+
+// Sync выгружает все метрики в PostgreSQL.
+func (s dbSink) Sync(ctx context.Context) error {
+	return SyncToDB(ctx, s.storage, s.db)
+}
+
+// Delete удаляет метрики из PostgreSQL.
+func (s dbSink) Delete(ctx context.Context, metrics []MetricInfo) error {
+	return DeleteFromDB(ctx, s.db, metrics)
+}
+
+// NewFanOut создаёт FanOut для хранилищ backends.
+func NewFanOut(backends ...FanOutBackend) *FanOut {
+	return &FanOut{backends: backends}
+}
+
+// NewDBFanOut создаёт FanOut с синхронной записью в PostgreSQL, ошибка которой завершает
+// запрос на обновление. Возвращает nil, если db не задан.
+func NewDBFanOut(storage Storage, db *pgxpool.Pool) *FanOut {
+	if db == nil {
+		return nil
+	}
+	return NewFanOut(FanOutBackend{Name: "postgres", Sink: DBSink(storage, db), FailOnError: true})
+}
+
+// Backends возвращает хранилища FanOut.
+func (f *FanOut) Backends() []FanOutBackend {
+	if f == nil {
+		return nil
+	}
+	return f.backends
+}
+
+// SetErrorObserver задаёт функцию, получающую имя хранилища и ошибку каждой неудачной записи
+// (например, для собственных метрик сервера). Вызывается до Run и до начала обработки запросов.
+func (f *FanOut) SetErrorObserver(fn func(backend string, err error)) {
+	if f != nil {
+		f.onError = fn
+	}
+}
+
+// Sync записывает метрики во все синхронные хранилища по порядку.
+//
+// Возвращает ошибки хранилищ с FailOnError; ошибки остальных записываются в журнал.
+func (f *FanOut) Sync(ctx context.Context) error {
+	var errs error
+	for _, b := range f.Backends() {
+		if !b.Async {
+			errs = errors.Join(errs, f.sync(ctx, b))
+		}
+	}
+	return errs
+}
+
+// Flush записывает метрики во все хранилища, включая асинхронные. Вызывается при остановке сервера.
+//
+// Возвращает ошибки всех хранилищ.
+func (f *FanOut) Flush(ctx context.Context) error {
+	var errs error
+	for _, b := range f.Backends() {
+		if err := f.write(ctx, b); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", b.Name, err))
+		}
+	}
+	return errs
+}
+
+// Run периодически записывает метрики в асинхронные хранилища до отмены ctx.
+func (f *FanOut) Run(ctx context.Context) {
+	for _, b := range f.Backends() {
+		if b.Async && b.Interval > 0 {
+			go f.run(ctx, b)
+		}
+	}
+}
+
+// Delete удаляет метрики metrics из всех хранилищ, включая асинхронные, и сообщает об ошибках
+// получателю SetErrorObserver. Вызывается после удаления метрик из основного хранилища.
+//
+// Возвращает ошибки всех хранилищ.
+func (f *FanOut) Delete(ctx context.Context, metrics []MetricInfo) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	var errs error
+	for _, b := range f.Backends() {
+		if err := b.Sink.Delete(ctx, metrics); err != nil {
+			if f.onError != nil {
+				f.onError(b.Name, err)
+			}
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", b.Name, err))
+		}
+	}
+	return errs
+}
+
+// write записывает метрики в хранилище b и сообщает об ошибке получателю SetErrorObserver.
+//
+// Запись отмечается дочерним спаном трассировки контекста ctx (например, запроса на обновление),
+// а её длительность — в timing.Recorder контекста как "sync.<имя хранилища>".
+func (f *FanOut) write(ctx context.Context, b FanOutBackend) error {
+	ctx, span := tracing.Tracer().Start(ctx, "storage sync "+b.Name,
+		trace.WithAttributes(attribute.String("storage.backend", b.Name), attribute.Bool("storage.async", b.Async)))
+	defer span.End()
+
+	start := time.Now()
+	err := b.Sink.Sync(ctx)
+	timing.Add(ctx, "sync."+b.Name, time.Since(start))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if f.onError != nil {
+			f.onError(b.Name, err)
+		}
+	}
+	return err
+}
+
+// sync записывает метрики в хранилище b и применяет политику ошибок.
+func (f *FanOut) sync(ctx context.Context, b FanOutBackend) error {
+	err := f.write(ctx, b)
+	if err == nil {
+		return nil
+	}
+	if b.FailOnError {
+		return fmt.Errorf("%s: %w", b.Name, err)
+	}
+	log.Printf("Failed to save metrics to %s: %v", b.Name, err)
+	return nil
+}
+
+// run записывает метрики в хранилище b с периодом Interval до отмены ctx.
+func (f *FanOut) run(ctx context.Context, b FanOutBackend) {
+	ticker := time.NewTicker(b.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := f.write(ctx, b); err != nil {
+				log.Printf("Failed to save metrics to %s: %v", b.Name, err)
+			}
+		}
+	}
+}
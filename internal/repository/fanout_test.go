@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestFanOut_Sync проверяет запись в синхронные хранилища и политики ошибок.
+//
+// t — указатель на структуру теста.
+func TestFanOut_Sync(t *testing.T) {
+	var calls atomic.Int32
+	ok := SinkFunc(func(context.Context) error { calls.Add(1); return nil })
+	failing := SinkFunc(func(context.Context) error { calls.Add(1); return errors.New("down") })
+
+	tests := []struct {
+		name      string          // Название теста
+		backends  []FanOutBackend // Хранилища
+		wantErr   bool            // Ожидается ошибка Sync
+		wantCalls int32           // Ожидаемое число записей
+		wantFails []string        // Ожидаемые хранилища, о неудаче записи в которые сообщено наблюдателю
+	}{
+		{name: "Empty"},
+		{name: "AllOK", backends: []FanOutBackend{{Name: "a", Sink: ok}, {Name: "b", Sink: ok}}, wantCalls: 2},
+		{name: "Fail", backends: []FanOutBackend{{Name: "db", Sink: failing, FailOnError: true}, {Name: "b", Sink: ok}}, wantErr: true, wantCalls: 2, wantFails: []string{"db"}},
+		{name: "Log", backends: []FanOutBackend{{Name: "db", Sink: failing}}, wantCalls: 1, wantFails: []string{"db"}},
+		{name: "AsyncSkipped", backends: []FanOutBackend{{Name: "file", Sink: ok, Async: true}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls.Store(0)
+			var fails []string
+			f := NewFanOut(tc.backends...)
+			f.SetErrorObserver(func(backend string, err error) {
+				require.Error(t, err)
+				fails = append(fails, backend)
+			})
+			err := f.Sync(context.Background())
+			if tc.wantErr {
+				require.ErrorContains(t, err, "db: down")
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.wantCalls, calls.Load())
+			require.Equal(t, tc.wantFails, fails)
+		})
+	}
+}
+
+// TestFanOut_Nil проверяет, что FanOut без хранилищ (nil) ничего не записывает.
+//
+// t — указатель на структуру теста.
+func TestFanOut_Nil(t *testing.T) {
+	var f *FanOut
+	f.SetErrorObserver(func(string, error) {})
+	require.NoError(t, f.Sync(context.Background()))
+	require.NoError(t, f.Flush(context.Background()))
+	f.Run(context.Background())
+	require.Nil(t, NewDBFanOut(NewMemStorage(), nil))
+}
+
+// TestFanOut_RunAndFlush проверяет фоновую запись в асинхронные хранилища и запись всех хранилищ при Flush.
+//
+// t — указатель на структуру теста.
+func TestFanOut_RunAndFlush(t *testing.T) {
+	var async, sync atomic.Int32
+	f := NewFanOut(
+		FanOutBackend{Name: "file", Sink: SinkFunc(func(context.Context) error { async.Add(1); return nil }), Async: true, Interval: 5 * time.Millisecond},
+		FanOutBackend{Name: "db", Sink: SinkFunc(func(context.Context) error { sync.Add(1); return errors.New("down") })},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.Run(ctx)
+	require.Eventually(t, func() bool { return async.Load() >= 2 }, time.Second, time.Millisecond)
+	cancel()
+	require.Zero(t, sync.Load(), "sync backends are not written in background")
+
+	before := async.Load()
+	require.ErrorContains(t, f.Flush(context.Background()), "db: down")
+	require.Equal(t, int32(1), sync.Load())
+	require.GreaterOrEqual(t, async.Load(), before+1)
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+// snapshotOf возвращает содержимое хранилища в виде map "тип/имя" -> значение для сравнения.
+func snapshotOf(s Storage) map[string]string {
+	out := make(map[string]string)
+	for _, m := range s.GetAll() {
+		out[m.Type+"/"+m.Name] = m.Value
+	}
+	return out
+}
+
+// applyUpdates применяет обновления к хранилищу в заданном порядке.
+func applyUpdates(s Storage, updates []MetricUpdate) {
+	for _, u := range updates {
+		switch u.Type {
+		case "gauge":
+			s.SetGauge(u.Name, *u.FloatVal)
+		case "counter":
+			s.AddCounter(u.Name, *u.IntVal)
+		}
+	}
+}
+
+// FuzzSaveLoadRoundTrip проверяет, что сохранение и последующая загрузка снимка
+// восстанавливают исходное содержимое хранилища.
+//
+// f — указатель на структуру фаззинг-теста.
+func FuzzSaveLoadRoundTrip(f *testing.F) {
+	f.Add("Alloc", 1.5, int64(10))
+	f.Add("", 0.0, int64(0))
+	f.Add("name with spaces", -1e300, int64(math.MaxInt64))
+	f.Add("юникод", math.SmallestNonzeroFloat64, int64(math.MinInt64))
+
+	f.Fuzz(func(t *testing.T, name string, gauge float64, counter int64) {
+		if math.IsNaN(gauge) || math.IsInf(gauge, 0) {
+			t.Skip("JSON does not represent NaN and Inf")
+		}
+		if !utf8.ValidString(name) {
+			t.Skip("JSON replaces invalid UTF-8 sequences")
+		}
+
+		s := NewMemStorage()
+		s.SetGauge(name, gauge)
+		s.AddCounter(name, counter)
+
+		fpath := filepath.Join(t.TempDir(), "metrics.json")
+		require.NoError(t, SaveMetricsToFile(s, fpath))
+
+		loaded := NewMemStorage()
+		require.NoError(t, LoadMetricsFromFile(loaded, fpath))
+		require.Equal(t, snapshotOf(s), snapshotOf(loaded))
+	})
+}
+
+// FuzzLoadMetricsFromFile проверяет, что загрузка произвольного содержимого файла
+// не приводит к панике, а при ошибке разбора хранилище остаётся пустым.
+//
+// f — указатель на структуру фаззинг-теста.
+func FuzzLoadMetricsFromFile(f *testing.F) {
+	f.Add([]byte(`[{"id":"g","type":"gauge","value":1}]`))
+	f.Add([]byte(`[{"id":"c","type":"counter","delta":1},{"id":"x","type":"unknown"}]`))
+	f.Add([]byte(`[{"id":"g","type":"gauge"}]`))
+	f.Add([]byte(`{"not":"array"}`))
+	f.Add([]byte(``))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fpath := filepath.Join(t.TempDir(), "metrics.json")
+		require.NoError(t, os.WriteFile(fpath, data, 0644))
+
+		s := NewMemStorage()
+		if err := LoadMetricsFromFile(s, fpath); err != nil {
+			require.Empty(t, s.GetAll())
+		}
+	})
+}
+
+// TestProperty_SaveLoadRoundTrip проверяет свойство: для любого набора метрик
+// загрузка сохранённого снимка даёт хранилище, равное исходному.
+//
+// t — указатель на структуру теста.
+func TestProperty_SaveLoadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	i := 0
+	property := func(gauges map[string]float64, counters map[string]int64) bool {
+		s := NewMemStorage()
+		for k, v := range gauges {
+			if math.IsNaN(v) || math.IsInf(v, 0) || !utf8.ValidString(k) {
+				continue
+			}
+			s.SetGauge(k, v)
+		}
+		for k, v := range counters {
+			if !utf8.ValidString(k) {
+				continue
+			}
+			s.AddCounter(k, v)
+		}
+
+		i++
+		fpath := filepath.Join(dir, fmt.Sprintf("metrics-%d.json", i))
+		if err := SaveMetricsToFile(s, fpath); err != nil {
+			return false
+		}
+		loaded := NewMemStorage()
+		if err := LoadMetricsFromFile(loaded, fpath); err != nil {
+			return false
+		}
+		return fmt.Sprint(snapshotOf(s)) == fmt.Sprint(snapshotOf(loaded))
+	}
+	require.NoError(t, quick.Check(property, nil))
+}
+
+// TestProperty_ConcurrentBatchEqualsSequential проверяет свойство: параллельное применение
+// пакетов обновлений даёт тот же результат, что и последовательное.
+//
+// Каждый gauge обновляется только одним пакетом, поэтому порядок записи между пакетами
+// не влияет на итоговое значение; счётчики коммутативны по построению.
+//
+// t — указатель на структуру теста.
+func TestProperty_ConcurrentBatchEqualsSequential(t *testing.T) {
+	property := func(seed int64) bool {
+		rng := rand.New(rand.NewSource(seed))
+		workers := 1 + rng.Intn(8)
+		batches := make([][]MetricUpdate, workers)
+		for w := range batches {
+			for n := rng.Intn(50); n > 0; n-- {
+				if rng.Intn(2) == 0 {
+					v := rng.NormFloat64()
+					batches[w] = append(batches[w], MetricUpdate{
+						Type:     "gauge",
+						Name:     fmt.Sprintf("g%d_%d", w, rng.Intn(5)),
+						FloatVal: &v,
+					})
+				} else {
+					d := rng.Int63n(1000) - 500
+					batches[w] = append(batches[w], MetricUpdate{
+						Type:   "counter",
+						Name:   fmt.Sprintf("c%d", rng.Intn(5)),
+						IntVal: &d,
+					})
+				}
+			}
+		}
+
+		sequential := NewMemStorage()
+		for _, b := range batches {
+			applyUpdates(sequential, b)
+		}
+
+		concurrent := NewMemStorage()
+		var wg sync.WaitGroup
+		for _, b := range batches {
+			wg.Add(1)
+			go func(b []MetricUpdate) {
+				defer wg.Done()
+				applyUpdates(concurrent, b)
+			}(b)
+		}
+		wg.Wait()
+
+		return fmt.Sprint(snapshotOf(sequential)) == fmt.Sprint(snapshotOf(concurrent))
+	}
+	require.NoError(t, quick.Check(property, nil))
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// StorageLatencyBuckets — верхние границы интервалов гистограммы задержек операций хранилища.
+var StorageLatencyBuckets = []time.Duration{
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// Операции хранилища, для которых собирается статистика.
+const (
+	opSetGauge = iota
+	opAddCounter
+	opGetGauge
+	opGetCounter
+	opGetAll
+	opGeneration
+	opChanges
+	opUpdatedAt
+	opExpire
+	opCount
+)
+
+// storageOpNames — имена операций в порядке констант op*.
+var storageOpNames = [opCount]string{
+	"SetGauge", "AddCounter", "GetGauge", "GetCounter", "GetAll", "Generation", "Changes", "UpdatedAt", "Expire",
+}
+
+type (
+	// opStats содержит счётчики одной операции хранилища.
+	opStats struct {
+		count   atomic.Uint64
+		totalNs atomic.Int64
+		buckets []atomic.Uint64 // последний элемент — интервал +Inf
+	}
+
+	// LatencyBucket — интервал гистограммы задержек (накопительно, как в Prometheus).
+	LatencyBucket struct {
+		LE    string `json:"le"`
+		Count uint64 `json:"count"`
+	}
+
+	// StorageOpStats — статистика одной операции хранилища.
+	//
+	// Поля:
+	//   - Op: имя метода Storage
+	//   - Count: количество вызовов
+	//   - TotalSeconds: суммарное время выполнения в секундах
+	//   - Buckets: накопительная гистограмма задержек
+	StorageOpStats struct {
+		Op           string          `json:"op"`
+		Count        uint64          `json:"count"`
+		TotalSeconds float64         `json:"total_seconds"`
+		Buckets      []LatencyBucket `json:"buckets"`
+	}
+
+	// InstrumentedStorage — декоратор Storage, считающий вызовы и задержки каждого метода.
+	//
+	// Позволяет измерять конкуренцию за блокировки и задержки хранилищ на основе БД
+	// в работающем сервере, а не только в бенчмарках.
+	InstrumentedStorage struct {
+		Storage
+		ops      [opCount]opStats
+		observer func(op string, d time.Duration) // Дополнительный получатель задержек (nil — нет)
+	}
+)
+
+// NewInstrumentedStorage оборачивает хранилище storage сбором статистики операций.
+func NewInstrumentedStorage(storage Storage) *InstrumentedStorage {
+	s := &InstrumentedStorage{Storage: storage}
+	for i := range s.ops {
+		s.ops[i].buckets = make([]atomic.Uint64, len(StorageLatencyBuckets)+1)
+	}
+	return s
+}
+
+// SetObserver задаёт функцию, получающую имя и задержку каждой операции хранилища
+// (например, для собственных метрик сервера). Вызывается до начала работы с хранилищем.
+func (s *InstrumentedStorage) SetObserver(fn func(op string, d time.Duration)) {
+	s.observer = fn
+}
+
+// observe учитывает вызов операции op, начатый в момент start.
+func (s *InstrumentedStorage) observe(op int, start time.Time) {
+	d := time.Since(start)
+	st := &s.ops[op]
+	st.count.Add(1)
+	st.totalNs.Add(int64(d))
+	i := 0
+	for i < len(StorageLatencyBuckets) && d > StorageLatencyBuckets[i] {
+		i++
+	}
+	st.buckets[i].Add(1)
+	if s.observer != nil {
+		s.observer(storageOpNames[op], d)
+	}
+}
+
+// SetGauge устанавливает значение gauge-метрики с учётом задержки.
+func (s *InstrumentedStorage) SetGauge(name string, value float64) {
+	defer s.observe(opSetGauge, time.Now())
+	s.Storage.SetGauge(name, value)
+}
+
+// AddCounter увеличивает значение counter-метрики с учётом задержки.
+func (s *InstrumentedStorage) AddCounter(name string, delta int64) {
+	defer s.observe(opAddCounter, time.Now())
+	s.Storage.AddCounter(name, delta)
+}
+
+// GetGauge возвращает значение gauge-метрики с учётом задержки.
+func (s *InstrumentedStorage) GetGauge(name string) (float64, bool) {
+	defer s.observe(opGetGauge, time.Now())
+	return s.Storage.GetGauge(name)
+}
+
+// GetCounter возвращает значение counter-метрики с учётом задержки.
+func (s *InstrumentedStorage) GetCounter(name string) (int64, bool) {
+	defer s.observe(opGetCounter, time.Now())
+	return s.Storage.GetCounter(name)
+}
+
+// GetAll возвращает все метрики с учётом задержки.
+func (s *InstrumentedStorage) GetAll() []MetricInfo {
+	defer s.observe(opGetAll, time.Now())
+	return s.Storage.GetAll()
+}
+
+// Generation возвращает номер поколения хранилища с учётом задержки.
+func (s *InstrumentedStorage) Generation() uint64 {
+	defer s.observe(opGeneration, time.Now())
+	return s.Storage.Generation()
+}
+
+// Changes возвращает метрики, изменённые после поколения gen и момента since, с учётом задержки.
+func (s *InstrumentedStorage) Changes(gen uint64, since time.Time) []MetricInfo {
+	defer s.observe(opChanges, time.Now())
+	return s.Storage.Changes(gen, since)
+}
+
+// UpdatedAt возвращает время последнего изменения метрики с учётом задержки.
+func (s *InstrumentedStorage) UpdatedAt(metricType, name string) (time.Time, bool) {
+	defer s.observe(opUpdatedAt, time.Now())
+	return s.Storage.UpdatedAt(metricType, name)
+}
+
+// Expire удаляет устаревшую метрику с учётом задержки.
+func (s *InstrumentedStorage) Expire(metricType, name string, before time.Time) bool {
+	defer s.observe(opExpire, time.Now())
+	return s.Storage.Expire(metricType, name, before)
+}
+
+// Checkpoint передаёт контрольную точку обёрнутому хранилищу, если оно её поддерживает (см. WALStorage).
+func (s *InstrumentedStorage) Checkpoint(save func() error) error {
+	if cp, ok := s.Storage.(checkpointer); ok {
+		return cp.Checkpoint(save)
+	}
+	return save()
+}
+
+// Stats возвращает статистику всех операций хранилища.
+func (s *InstrumentedStorage) Stats() []StorageOpStats {
+	result := make([]StorageOpStats, 0, opCount)
+	for op := range s.ops {
+		st := &s.ops[op]
+		buckets := make([]LatencyBucket, 0, len(st.buckets))
+		var cumulative uint64
+		for i := range st.buckets {
+			cumulative += st.buckets[i].Load()
+			le := "+Inf"
+			if i < len(StorageLatencyBuckets) {
+				le = strconv.FormatFloat(StorageLatencyBuckets[i].Seconds(), 'g', -1, 64)
+			}
+			buckets = append(buckets, LatencyBucket{LE: le, Count: cumulative})
+		}
+		result = append(result, StorageOpStats{
+			Op:           storageOpNames[op],
+			Count:        st.count.Load(),
+			TotalSeconds: time.Duration(st.totalNs.Load()).Seconds(),
+			Buckets:      buckets,
+		})
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestInstrumentedStorage проверяет подсчёт вызовов и накопительную гистограмму задержек.
+//
+// t — указатель на структуру теста.
+func TestInstrumentedStorage(t *testing.T) {
+	s := NewInstrumentedStorage(NewMemStorage())
+	observed := make(map[string]uint64)
+	s.SetObserver(func(op string, _ time.Duration) { observed[op]++ })
+	s.SetGauge("g", 1)
+	s.SetGauge("g", 2)
+	s.AddCounter("c", 3)
+	v, ok := s.GetGauge("g")
+	require.True(t, ok)
+	require.Equal(t, 2.0, v)
+	_, ok = s.GetCounter("missing")
+	require.False(t, ok)
+	require.Len(t, s.GetAll(), 2)
+
+	want := map[string]uint64{
+		"SetGauge":   2,
+		"AddCounter": 1,
+		"GetGauge":   1,
+		"GetCounter": 1,
+		"GetAll":     1,
+		"Generation": 0,
+		"Changes":    0,
+		"UpdatedAt":  0,
+		"Expire":     0,
+	}
+	stats := s.Stats()
+	require.Len(t, stats, len(want))
+	for _, st := range stats {
+		require.Equal(t, want[st.Op], st.Count, st.Op)
+		require.Equal(t, want[st.Op], observed[st.Op], st.Op)
+		require.Len(t, st.Buckets, len(StorageLatencyBuckets)+1)
+		require.Equal(t, "+Inf", st.Buckets[len(st.Buckets)-1].LE)
+		require.Equal(t, st.Count, st.Buckets[len(st.Buckets)-1].Count)
+		for i := 1; i < len(st.Buckets); i++ {
+			require.GreaterOrEqual(t, st.Buckets[i].Count, st.Buckets[i-1].Count)
+		}
+	}
+}
+
+// TestInstrumentedStorage_Checkpoint проверяет, что контрольная точка доходит до WALStorage.
+//
+// t — указатель на структуру теста.
+func TestInstrumentedStorage_Checkpoint(t *testing.T) {
+	dir := t.TempDir()
+	wal, err := OpenWAL(NewMemStorage(), filepath.Join(dir, "wal.log"))
+	require.NoError(t, err)
+	defer wal.Close()
+
+	s := NewInstrumentedStorage(wal)
+	s.SetGauge("g", 1)
+
+	saver := NewSnapshotSaver(s, filepath.Join(dir, "metrics.json"))
+	saved, err := saver.Save()
+	require.NoError(t, err)
+	require.True(t, saved)
+
+	n, err := ReplayWAL(NewMemStorage(), filepath.Join(dir, "wal.log"))
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"sync"
+	"time"
+)
+
+// Seed описывает начальные метрики изолированного хранилища.
+//
+// Поля:
+//   - Gauges: значения gauge-метрик по имени
+//   - Counters: значения counter-метрик по имени
+type Seed struct {
+	Gauges   map[string]float64
+	Counters map[string]int64
+}
+
+// IsolatedStorage — хранилище в памяти, не связанное с файлами и глобальным состоянием,
+// которое можно вернуть к начальным метрикам вызовом Reset.
+//
+// Предназначено для интеграционных тестов обработчиков и маршрутизатора: каждый тест
+// получает собственный экземпляр вместо общих metrics.json и ./logs в рабочей директории.
+// GetAll и Changes возвращают метрики в порядке SortMetricInfo.
+type IsolatedStorage struct {
+	mu   sync.RWMutex // Защищает замену cur при Reset
+	cur  *MemStorage  // Текущее содержимое
+	seed Seed         // Начальные метрики
+}
+
+// NewIsolatedStorage создаёт изолированное хранилище, заполненное метриками seed.
+func NewIsolatedStorage(seed Seed) *IsolatedStorage {
+	s := &IsolatedStorage{seed: seed}
+	s.cur = seed.fill()
+	return s
+}
+
+// fill создаёт MemStorage с метриками seed, записанными одним пакетом.
+func (seed Seed) fill() *MemStorage {
+	m := NewMemStorage().(*MemStorage)
+	updates := make([]MetricUpdate, 0, len(seed.Gauges)+len(seed.Counters))
+	for name, v := range seed.Gauges {
+		updates = append(updates, MetricUpdate{Type: "gauge", Name: name, FloatVal: &v})
+	}
+	for name, v := range seed.Counters {
+		updates = append(updates, MetricUpdate{Type: "counter", Name: name, IntVal: &v})
+	}
+	m.UpdateBatch(updates)
+	return m
+}
+
+// Reset возвращает хранилище к начальным метрикам.
+//
+// Поколение также сбрасывается к значению сразу после создания хранилища.
+func (s *IsolatedStorage) Reset() {
+	m := s.seed.fill()
+	s.mu.Lock()
+	s.cur = m
+	s.mu.Unlock()
+}
+
+// storage возвращает текущее содержимое хранилища.
+func (s *IsolatedStorage) storage() *MemStorage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cur
+}
+
+// SetGauge устанавливает значение gauge-метрики по имени.
+func (s *IsolatedStorage) SetGauge(name string, value float64) {
+	s.storage().SetGauge(name, value)
+}
+
+// AddCounter увеличивает значение counter-метрики по имени на delta.
+func (s *IsolatedStorage) AddCounter(name string, delta int64) {
+	s.storage().AddCounter(name, delta)
+}
+
+// GetGauge возвращает значение gauge-метрики по имени и флаг наличия.
+func (s *IsolatedStorage) GetGauge(name string) (float64, bool) {
+	return s.storage().GetGauge(name)
+}
+
+// GetCounter возвращает значение counter-метрики по имени и флаг наличия.
+func (s *IsolatedStorage) GetCounter(name string) (int64, bool) {
+	return s.storage().GetCounter(name)
+}
+
+// GetAll возвращает все метрики, упорядоченные по имени, а затем по типу.
+func (s *IsolatedStorage) GetAll() []MetricInfo {
+	return s.storage().GetAll()
+}
+
+// Generation возвращает текущее поколение хранилища.
+func (s *IsolatedStorage) Generation() uint64 {
+	return s.storage().Generation()
+}
+
+// Changes возвращает метрики, изменённые после поколения gen и позже момента since.
+func (s *IsolatedStorage) Changes(gen uint64, since time.Time) []MetricInfo {
+	return s.storage().Changes(gen, since)
+}
+
+// UpdatedAt возвращает время последнего изменения метрики по типу и имени и флаг наличия.
+func (s *IsolatedStorage) UpdatedAt(metricType, name string) (time.Time, bool) {
+	return s.storage().UpdatedAt(metricType, name)
+}
+
+// Expire удаляет метрику, если она не изменялась позже момента before.
+func (s *IsolatedStorage) Expire(metricType, name string, before time.Time) bool {
+	return s.storage().Expire(metricType, name, before)
+}
+
+// UpdateBatch применяет пакет обновлений за одну блокировку.
+func (s *IsolatedStorage) UpdateBatch(updates []MetricUpdate) {
+	s.storage().UpdateBatch(updates)
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestIsolatedStorage_SeedAndReset проверяет начальные метрики, детерминированный порядок GetAll
+// и возврат к начальному состоянию после Reset.
+//
+// t — указатель на структуру теста.
+func TestIsolatedStorage_SeedAndReset(t *testing.T) {
+	s := NewIsolatedStorage(Seed{
+		Gauges:   map[string]float64{"b": 2.5, "a": 1},
+		Counters: map[string]int64{"a": 3},
+	})
+	want := []MetricInfo{
+		{Name: "a", Type: "counter", Value: "3"},
+		{Name: "a", Type: "gauge", Value: "1"},
+		{Name: "b", Type: "gauge", Value: "2.5"},
+	}
+	require.Equal(t, want, s.GetAll())
+	gen := s.Generation()
+
+	s.SetGauge("c", 4)
+	s.AddCounter("a", 2)
+	v, ok := s.GetCounter("a")
+	require.True(t, ok)
+	require.Equal(t, int64(5), v)
+	require.Len(t, s.Changes(gen, time.Time{}), 2)
+
+	s.Reset()
+	require.Equal(t, want, s.GetAll())
+	require.Equal(t, gen, s.Generation())
+	_, ok = s.GetGauge("c")
+	require.False(t, ok)
+}
+
+// TestIsolatedStorage_Independent проверяет, что хранилища с одинаковыми начальными метриками
+// не разделяют состояние.
+//
+// t — указатель на структуру теста.
+func TestIsolatedStorage_Independent(t *testing.T) {
+	seed := Seed{Gauges: map[string]float64{"g": 1}}
+	first, second := NewIsolatedStorage(seed), NewIsolatedStorage(seed)
+	first.SetGauge("g", 10)
+
+	v, ok := second.GetGauge("g")
+	require.True(t, ok)
+	require.Equal(t, 1.0, v)
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/segmentio/kafka-go"
+)
+
+// Параметры наблюдателя Kafka по умолчанию.
+const (
+	DefaultKafkaBatchSize    = 100                   // Событий в одном запросе к брокеру
+	DefaultKafkaBatchTimeout = 10 * time.Millisecond // Ожидание заполнения батча
+	DefaultKafkaMaxAttempts  = 3                     // Попыток доставки батча
+	kafkaWriteTimeout        = 10 * time.Second      // Предел ожидания подтверждения батча брокером
+)
+
+// kafkaWriter — часть kafka.Writer, используемая наблюдателем (подменяется в тестах).
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaAuditOptions задаёт батчирование и повторы доставки событий в Kafka.
+//
+// Поля:
+//   - BatchSize: максимальное число событий в одном запросе к брокеру
+//   - BatchTimeout: сколько ждать заполнения батча перед отправкой
+//   - MaxAttempts: число попыток доставки батча до возврата ошибки
+type KafkaAuditOptions struct {
+	BatchSize    int
+	BatchTimeout time.Duration
+	MaxAttempts  int
+}
+
+// KafkaAuditObserver публикует события аудита в топик Kafka.
+//
+// Доставка синхронная: OnAuditEvent ждёт подтверждения брокера, поэтому ошибки попадают
+// в повторы и очередь недоставленных AuditManager. События одновременных запросов
+// объединяются в батчи, ожидание которых ограничено BatchTimeout.
+//
+// Поля:
+//   - topic: топик, в который публикуются события
+//   - writer: продюсер Kafka
+type KafkaAuditObserver struct {
+	topic  string
+	writer kafkaWriter
+}
+
+// NewKafkaAuditObserver создаёт наблюдателя, публикующего события в топик topic брокеров brokers.
+//
+// Нулевые поля opts заменяются значениями по умолчанию.
+func NewKafkaAuditObserver(brokers []string, topic string, opts KafkaAuditOptions) *KafkaAuditObserver {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultKafkaBatchSize
+	}
+	if opts.BatchTimeout <= 0 {
+		opts.BatchTimeout = DefaultKafkaBatchTimeout
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultKafkaMaxAttempts
+	}
+	return &KafkaAuditObserver{
+		topic: topic,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			BatchSize:    opts.BatchSize,
+			BatchTimeout: opts.BatchTimeout,
+			MaxAttempts:  opts.MaxAttempts,
+			WriteTimeout: kafkaWriteTimeout,
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+}
+
+// OnAuditEvent публикует событие в топик; ключ сообщения — идентификатор события.
+//
+// event — событие аудита для публикации.
+//
+// Возвращает ошибку, если брокер не подтвердил запись после всех попыток.
+func (k *KafkaAuditObserver) OnAuditEvent(event models.AuditEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaWriteTimeout)
+	defer cancel()
+	if err := k.writer.WriteMessages(ctx, kafka.Message{Key: []byte(event.ID), Value: data}); err != nil {
+		return fmt.Errorf("failed to publish audit event to kafka: %w", err)
+	}
+	return nil
+}
+
+// Close отправляет накопленные события и закрывает соединения с брокерами.
+func (k *KafkaAuditObserver) Close() error {
+	return k.writer.Close()
+}
+
+// String возвращает имя наблюдателя для очереди недоставленных событий.
+func (k *KafkaAuditObserver) String() string {
+	return "kafka:" + k.topic
+}
+
+// openKafkaObserver создаёт наблюдателя Kafka.
+//
+// Параметры: "brokers" — адреса брокеров через запятую, "topic" — топик (оба обязательны);
+// "batch_size", "batch_timeout" (например "10ms") и "retries" — необязательны.
+func openKafkaObserver(opts map[string]string) (models.AuditObserver, error) {
+	brokersOpt, err := requiredOption(opts, "brokers")
+	if err != nil {
+		return nil, err
+	}
+	topic, err := requiredOption(opts, "topic")
+	if err != nil {
+		return nil, err
+	}
+	var brokers []string
+	for _, b := range strings.Split(brokersOpt, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			brokers = append(brokers, b)
+		}
+	}
+	if len(brokers) == 0 {
+		return nil, errors.New(`option "brokers" has no addresses`)
+	}
+
+	var ko KafkaAuditOptions
+	if v := opts["batch_size"]; v != "" {
+		if ko.BatchSize, err = strconv.Atoi(v); err != nil || ko.BatchSize < 1 {
+			return nil, fmt.Errorf(`option "batch_size" must be a positive integer, got %q`, v)
+		}
+	}
+	if v := opts["batch_timeout"]; v != "" {
+		if ko.BatchTimeout, err = time.ParseDuration(v); err != nil || ko.BatchTimeout <= 0 {
+			return nil, fmt.Errorf(`option "batch_timeout" must be a positive duration, got %q`, v)
+		}
+	}
+	if v := opts["retries"]; v != "" {
+		if ko.MaxAttempts, err = strconv.Atoi(v); err != nil || ko.MaxAttempts < 1 {
+			return nil, fmt.Errorf(`option "retries" must be a positive integer, got %q`, v)
+		}
+	}
+	return NewKafkaAuditObserver(brokers, topic, ko), nil
+}
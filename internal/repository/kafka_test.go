@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKafkaWriter запоминает опубликованные сообщения и возвращает заданную ошибку.
+type fakeKafkaWriter struct {
+	msgs   []kafka.Message
+	err    error
+	closed bool
+}
+
+func (w *fakeKafkaWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+// TestKafkaAuditObserver проверяет публикацию события в формате JSON с ключом по
+// идентификатору, передачу ошибки брокера в AuditManager и закрытие продюсера.
+//
+// t — указатель на структуру теста.
+func TestKafkaAuditObserver(t *testing.T) {
+	tests := []struct {
+		name     string // Название теста
+		writeErr error  // Ошибка записи в Kafka
+		wantMsgs int    // Ожидаемое число опубликованных сообщений
+	}{
+		{name: "Published", wantMsgs: 1},
+		{name: "BrokerError", writeErr: errors.New("leader not available")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			writer := &fakeKafkaWriter{err: tc.writeErr}
+			observer := &KafkaAuditObserver{topic: "audit", writer: writer}
+			require.Equal(t, "kafka:audit", observer.String())
+
+			event := models.AuditEvent{ID: "01HZ", Timestamp: 1700000000, Metrics: []string{"Alloc"}, IPAddress: "10.0.0.1"}
+			err := observer.OnAuditEvent(event)
+			if tc.writeErr != nil {
+				require.ErrorIs(t, err, tc.writeErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Len(t, writer.msgs, tc.wantMsgs)
+			if tc.wantMsgs > 0 {
+				require.Equal(t, "01HZ", string(writer.msgs[0].Key))
+				var got models.AuditEvent
+				require.NoError(t, json.Unmarshal(writer.msgs[0].Value, &got))
+				require.Equal(t, event, got)
+			}
+
+			manager := NewAuditManager()
+			manager.Attach(observer)
+			require.NoError(t, manager.Close())
+			require.True(t, writer.closed)
+		})
+	}
+}
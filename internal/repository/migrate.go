@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultMigrateBatchSize — количество метрик, записываемых в целевое хранилище одним пакетом.
+const DefaultMigrateBatchSize = 500
+
+// ErrMigrationMismatch возвращается, если метрики целевого хранилища после переноса
+// не совпадают с исходными.
+var ErrMigrationMismatch = errors.New("migrated metrics do not match the source")
+
+type (
+	// MigrateOptions — параметры переноса метрик между хранилищами.
+	//
+	// Поля:
+	//   - BatchSize: метрик в одном пакете записи (не больше нуля — DefaultMigrateBatchSize)
+	//   - Progress: вызывается после каждого пакета с числом перенесённых и всех метрик (может быть nil)
+	MigrateOptions struct {
+		BatchSize int
+		Progress  func(done, total int)
+	}
+
+	// MigrateReport — итог переноса метрик.
+	//
+	// Поля:
+	//   - Gauges: перенесено gauge-метрик
+	//   - Counters: перенесено counter-метрик
+	//   - Batches: выполнено пакетов записи
+	MigrateReport struct {
+		Gauges   int
+		Counters int
+		Batches  int
+	}
+
+	// migrationEndpoint — исходное или целевое хранилище переноса.
+	//
+	// Поля:
+	//   - load: загружает метрики хранилища в storage
+	//   - write: записывает метрики chunk, заменяя одноимённые
+	//   - flush: завершает запись (nil, если каждый пакет записывается сразу)
+	//   - close: освобождает подключение
+	migrationEndpoint struct {
+		load  func(ctx context.Context, storage Storage) error
+		write func(ctx context.Context, chunk Storage) error
+		flush func(ctx context.Context) error
+		close func()
+	}
+)
+
+// MigrateStorage переносит все метрики из хранилища from в хранилище to и проверяет результат.
+//
+// DSN задаются так же, как для сервера (postgres://, redis://, sqlite://), а файл снимка —
+// как file://путь. Метрики записываются пакетами по opts.BatchSize; в PostgreSQL, Redis и SQLite
+// одноимённые метрики заменяются, остальные сохраняются, а файл снимка перезаписывается целиком.
+// После записи целевое хранилище загружается заново и сравнивается с исходным: при расхождении
+// возвращается ErrMigrationMismatch.
+func MigrateStorage(ctx context.Context, from, to string, opts MigrateOptions) (MigrateReport, error) {
+	var report MigrateReport
+	if from == to {
+		return report, errors.New("source and destination storages are the same")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultMigrateBatchSize
+	}
+
+	src, err := openMigrationEndpoint(ctx, from)
+	if err != nil {
+		return report, fmt.Errorf("source: %w", err)
+	}
+	defer src.close()
+	dst, err := openMigrationEndpoint(ctx, to)
+	if err != nil {
+		return report, fmt.Errorf("destination: %w", err)
+	}
+	defer dst.close()
+
+	source := NewMemStorage()
+	if err := src.load(ctx, source); err != nil {
+		return report, fmt.Errorf("source: %w", err)
+	}
+	metrics := source.GetAll()
+
+	for start := 0; start < len(metrics); start += opts.BatchSize {
+		end := min(start+opts.BatchSize, len(metrics))
+		chunk := NewMemStorage()
+		updates := make([]MetricUpdate, 0, end-start)
+		for _, m := range metrics[start:end] {
+			u, err := metricUpdateFromInfo(m)
+			if err != nil {
+				return report, err
+			}
+			updates = append(updates, u)
+		}
+		UpdateBatch(chunk, updates)
+		if err := dst.write(ctx, chunk); err != nil {
+			return report, fmt.Errorf("destination: %w", err)
+		}
+		for _, m := range metrics[start:end] {
+			if m.Type == "gauge" {
+				report.Gauges++
+			} else {
+				report.Counters++
+			}
+		}
+		report.Batches++
+		if opts.Progress != nil {
+			opts.Progress(end, len(metrics))
+		}
+	}
+	if dst.flush != nil {
+		if err := dst.flush(ctx); err != nil {
+			return report, fmt.Errorf("destination: %w", err)
+		}
+	}
+
+	migrated := NewMemStorage()
+	if err := dst.load(ctx, migrated); err != nil {
+		return report, fmt.Errorf("verify: %w", err)
+	}
+	if err := verifyMigration(source, migrated); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// verifyMigration проверяет, что каждая метрика source есть в migrated с тем же значением.
+func verifyMigration(source, migrated Storage) error {
+	var mismatched []string
+	for _, m := range source.GetAll() {
+		var same bool
+		switch m.Type {
+		case "gauge":
+			want, _ := source.GetGauge(m.Name)
+			got, ok := migrated.GetGauge(m.Name)
+			same = ok && got == want
+		case "counter":
+			want, _ := source.GetCounter(m.Name)
+			got, ok := migrated.GetCounter(m.Name)
+			same = ok && got == want
+		}
+		if !same {
+			mismatched = append(mismatched, m.Type+" "+m.Name)
+		}
+	}
+	if len(mismatched) == 0 {
+		return nil
+	}
+	const shown = 5
+	if len(mismatched) > shown {
+		return fmt.Errorf("%w: %d metrics, including %s", ErrMigrationMismatch, len(mismatched), strings.Join(mismatched[:shown], ", "))
+	}
+	return fmt.Errorf("%w: %s", ErrMigrationMismatch, strings.Join(mismatched, ", "))
+}
+
+// metricUpdateFromInfo преобразует метрику из GetAll в обновление для UpdateBatch.
+func metricUpdateFromInfo(m MetricInfo) (MetricUpdate, error) {
+	u := MetricUpdate{Type: m.Type, Name: m.Name}
+	switch m.Type {
+	case "gauge":
+		v, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil {
+			return u, fmt.Errorf("gauge %s: %w", m.Name, err)
+		}
+		u.FloatVal = &v
+	case "counter":
+		v, err := ParseCounter(m.Value)
+		if err != nil {
+			return u, fmt.Errorf("counter %s: %w", m.Name, err)
+		}
+		u.IntVal = &v
+	default:
+		return u, fmt.Errorf("metric %s: unknown type %q", m.Name, m.Type)
+	}
+	return u, nil
+}
+
+// openMigrationEndpoint подключается к хранилищу переноса по DSN.
+func openMigrationEndpoint(ctx context.Context, dsn string) (*migrationEndpoint, error) {
+	switch scheme := StorageScheme(dsn); scheme {
+	case "file":
+		path := dsn[len("file://"):]
+		if path == "" {
+			return nil, errors.New("invalid file DSN: empty path")
+		}
+		// Снимок записывается целиком, поэтому пакеты накапливаются до flush.
+		written := NewMemStorage()
+		return &migrationEndpoint{
+			load: func(_ context.Context, storage Storage) error {
+				return LoadMetricsFromFile(storage, path)
+			},
+			write: func(_ context.Context, chunk Storage) error {
+				updates := make([]MetricUpdate, 0, len(chunk.GetAll()))
+				for _, m := range chunk.GetAll() {
+					u, err := metricUpdateFromInfo(m)
+					if err != nil {
+						return err
+					}
+					updates = append(updates, u)
+				}
+				UpdateBatch(written, updates)
+				return nil
+			},
+			flush: func(context.Context) error {
+				return SaveMetricsToFileSync(written, path)
+			},
+			close: func() {},
+		}, nil
+	case "postgres", "postgresql":
+		pool, err := db.InitDB(ctx, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return &migrationEndpoint{
+			load: func(ctx context.Context, storage Storage) error {
+				return loadFromPostgres(ctx, storage, pool)
+			},
+			write: func(ctx context.Context, chunk Storage) error {
+				return SyncToDB(ctx, chunk, pool)
+			},
+			close: pool.Close,
+		}, nil
+	case "redis", "rediss":
+		client, err := openRedisClient(ctx, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return &migrationEndpoint{
+			load: func(ctx context.Context, storage Storage) error {
+				return loadFromRedis(ctx, storage, client)
+			},
+			write: func(ctx context.Context, chunk Storage) error {
+				return RedisSink(chunk, client).Sync(ctx)
+			},
+			close: func() { _ = client.Close() },
+		}, nil
+	case "sqlite":
+		sqlDB, err := openSQLiteDB(ctx, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return &migrationEndpoint{
+			load: func(ctx context.Context, storage Storage) error {
+				return loadFromSQLite(ctx, storage, sqlDB)
+			},
+			write: func(ctx context.Context, chunk Storage) error {
+				return SQLiteSink(chunk, sqlDB).Sync(ctx)
+			},
+			close: func() { _ = sqlDB.Close() },
+		}, nil
+	default:
+		return nil, fmt.Errorf("storage scheme %q cannot be migrated (supported: file, postgres, redis, sqlite)", scheme)
+	}
+}
+
+// loadFromPostgres загружает метрики из таблицы metrics PostgreSQL в storage.
+func loadFromPostgres(ctx context.Context, storage Storage, pool *pgxpool.Pool) error {
+	rows, err := pool.Query(ctx, `SELECT id, type, delta, value FROM metrics`)
+	if err != nil {
+		return fmt.Errorf("failed to load metrics from postgres: %w", err)
+	}
+	defer rows.Close()
+
+	var updates []MetricUpdate
+	for rows.Next() {
+		var u MetricUpdate
+		if err := rows.Scan(&u.Name, &u.Type, &u.IntVal, &u.FloatVal); err != nil {
+			return fmt.Errorf("failed to scan metric: %w", err)
+		}
+		updates = append(updates, u)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load metrics from postgres: %w", err)
+	}
+	UpdateBatch(storage, updates)
+	return nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMigrateStorage проверяет перенос метрик из файла в SQLite и из SQLite в Redis
+// пакетами с отчётом о прогрессе.
+//
+// t — указатель на структуру теста.
+func TestMigrateStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	snapshot := filepath.Join(dir, "metrics.json")
+
+	src := NewMemStorage()
+	for i, name := range []string{"Alloc", "HeapInuse", "RandomValue"} {
+		src.SetGauge(name, float64(i)+0.5)
+	}
+	src.AddCounter("PollCount", 42)
+	src.AddCounter("Requests", 7)
+	require.NoError(t, SaveMetricsToFileSync(src, snapshot))
+
+	var progress [][2]int
+	sqliteDSN := "sqlite://" + filepath.Join(dir, "metrics.db")
+	report, err := MigrateStorage(ctx, "file://"+snapshot, sqliteDSN, MigrateOptions{
+		BatchSize: 2,
+		Progress:  func(done, total int) { progress = append(progress, [2]int{done, total}) },
+	})
+	require.NoError(t, err)
+	require.Equal(t, MigrateReport{Gauges: 3, Counters: 2, Batches: 3}, report)
+	require.Equal(t, [][2]int{{2, 5}, {4, 5}, {5, 5}}, progress)
+
+	mr := miniredis.RunT(t)
+	redisDSN := "redis://" + mr.Addr()
+	_, err = MigrateStorage(ctx, sqliteDSN, redisDSN, MigrateOptions{})
+	require.NoError(t, err)
+
+	b, err := OpenStorage(ctx, redisDSN, StorageOptions{})
+	require.NoError(t, err)
+	defer b.Close()
+	require.Equal(t, src.GetAll(), b.Storage.GetAll())
+
+	// Повторный перенос заменяет метрики, а не складывает счётчики.
+	_, err = MigrateStorage(ctx, sqliteDSN, redisDSN, MigrateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "42", mr.HGet(redisCounterKey, "PollCount"))
+}
+
+// TestMigrateStorage_Invalid проверяет отказ для неподдерживаемых и совпадающих хранилищ.
+//
+// t — указатель на структуру теста.
+func TestMigrateStorage_Invalid(t *testing.T) {
+	ctx := context.Background()
+	snapshot := "file://" + filepath.Join(t.TempDir(), "metrics.json")
+
+	tests := []struct {
+		name     string // Название теста
+		from, to string // DSN исходного и целевого хранилищ
+		wantErr  string // Ожидаемый фрагмент ошибки
+	}{
+		{name: "Same", from: snapshot, to: snapshot, wantErr: "are the same"},
+		{name: "Memory", from: "memory://", to: snapshot, wantErr: `"memory" cannot be migrated`},
+		{name: "EmptyFile", from: "file://", to: snapshot, wantErr: "empty path"},
+		{name: "MissingSource", from: snapshot, to: "sqlite://" + filepath.Join(t.TempDir(), "m.db"), wantErr: "source"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := MigrateStorage(ctx, tc.from, tc.to, MigrateOptions{})
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+// TestVerifyMigration проверяет обнаружение отсутствующих и отличающихся метрик.
+//
+// t — указатель на структуру теста.
+func TestVerifyMigration(t *testing.T) {
+	source := NewMemStorage()
+	source.SetGauge("Alloc", 1.5)
+	source.AddCounter("PollCount", 3)
+
+	migrated := NewMemStorage()
+	migrated.SetGauge("Alloc", 1.5)
+	require.ErrorIs(t, verifyMigration(source, migrated), ErrMigrationMismatch)
+
+	migrated.AddCounter("PollCount", 2)
+	require.ErrorContains(t, verifyMigration(source, migrated), "counter PollCount")
+
+	migrated.AddCounter("PollCount", 1)
+	require.NoError(t, verifyMigration(source, migrated))
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NormalizeMetricID приводит имя метрики к каноническому виду: обрезает пробелы и переводит в нижний регистр.
+func NormalizeMetricID(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Ограничения учёта исходных написаний в NormalizingStorage.
+const (
+	maxTrackedIDs     = 10000 // нормализованных имён
+	maxSpellingsPerID = 16    // написаний одного имени
+)
+
+// NormalizingStorage — декоратор Storage, нормализующий имена метрик (NormalizeMetricID) при записи и чтении.
+//
+// Агенты с непоследовательным регистром имён (например, "Alloc" и "alloc") в противном случае
+// создают почти одинаковые метрики-дубликаты. Если под одно нормализованное имя попадают
+// несколько исходных написаний, коллизия записывается в лог и доступна через Collisions.
+//
+// Учёт написаний ограничен: не более maxTrackedIDs нормализованных имён и maxSpellingsPerID
+// написаний каждого, чтобы поток уникальных имён не приводил к неограниченному росту памяти.
+//
+// Поля:
+//   - Storage: исходное хранилище
+//   - originals: исходные написания для каждого нормализованного имени
+//   - mu: мьютекс для доступа к originals
+type NormalizingStorage struct {
+	Storage
+	originals map[string]map[string]struct{}
+	mu        sync.Mutex
+}
+
+// NewNormalizingStorage оборачивает хранилище storage нормализацией имён метрик.
+//
+// Возвращает указатель на NormalizingStorage.
+func NewNormalizingStorage(storage Storage) *NormalizingStorage {
+	return &NormalizingStorage{
+		Storage:   storage,
+		originals: make(map[string]map[string]struct{}),
+	}
+}
+
+// SetGauge устанавливает значение gauge-метрики по нормализованному имени.
+func (n *NormalizingStorage) SetGauge(name string, value float64) {
+	n.Storage.SetGauge(n.track(name), value)
+}
+
+// AddCounter увеличивает значение counter-метрики по нормализованному имени.
+func (n *NormalizingStorage) AddCounter(name string, delta int64) {
+	n.Storage.AddCounter(n.track(name), delta)
+}
+
+// GetGauge возвращает значение gauge-метрики по нормализованному имени.
+func (n *NormalizingStorage) GetGauge(name string) (float64, bool) {
+	return n.Storage.GetGauge(NormalizeMetricID(name))
+}
+
+// GetCounter возвращает значение counter-метрики по нормализованному имени.
+func (n *NormalizingStorage) GetCounter(name string) (int64, bool) {
+	return n.Storage.GetCounter(NormalizeMetricID(name))
+}
+
+// UpdatedAt возвращает время последнего изменения метрики по нормализованному имени.
+func (n *NormalizingStorage) UpdatedAt(metricType, name string) (time.Time, bool) {
+	return n.Storage.UpdatedAt(metricType, NormalizeMetricID(name))
+}
+
+// Expire удаляет метрику по нормализованному имени, если она не изменялась позже момента before.
+func (n *NormalizingStorage) Expire(metricType, name string, before time.Time) bool {
+	return n.Storage.Expire(metricType, NormalizeMetricID(name), before)
+}
+
+// Collisions возвращает нормализованные имена, под которые попало больше одного исходного написания,
+// вместе с этими написаниями (в отсортированном порядке).
+func (n *NormalizingStorage) Collisions() map[string][]string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	out := make(map[string][]string)
+	for norm, originals := range n.originals {
+		if len(originals) < 2 {
+			continue
+		}
+		names := make([]string, 0, len(originals))
+		for o := range originals {
+			names = append(names, o)
+		}
+		sort.Strings(names)
+		out[norm] = names
+	}
+	return out
+}
+
+// track нормализует имя, запоминает исходное написание и сообщает о новой коллизии.
+func (n *NormalizingStorage) track(name string) string {
+	norm := NormalizeMetricID(name)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	originals, ok := n.originals[norm]
+	if !ok {
+		if len(n.originals) >= maxTrackedIDs {
+			return norm
+		}
+		originals = make(map[string]struct{}, 1)
+		n.originals[norm] = originals
+	}
+	if _, seen := originals[name]; !seen && len(originals) < maxSpellingsPerID {
+		originals[name] = struct{}{}
+		if len(originals) > 1 {
+			log.Printf("Metric ID collision: %q normalized to %q (%d distinct spellings)", name, norm, len(originals))
+		}
+	}
+	return norm
+}
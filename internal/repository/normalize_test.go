@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestNormalizingStorage проверяет нормализацию имён при записи и чтении,
+// объединение написаний в одну метрику и отчёт о коллизиях.
+//
+// t — указатель на структуру теста.
+func TestNormalizingStorage(t *testing.T) {
+	s := NewNormalizingStorage(NewMemStorage())
+
+	s.AddCounter("PollCount", 1)
+	s.AddCounter(" pollcount ", 2)
+	s.AddCounter("PollCount", 3)
+	s.SetGauge("Alloc", 1.5)
+
+	c, ok := s.GetCounter("POLLCOUNT")
+	require.True(t, ok)
+	require.Equal(t, int64(6), c)
+
+	g, ok := s.GetGauge("alloc")
+	require.True(t, ok)
+	require.Equal(t, 1.5, g)
+
+	all := s.GetAll()
+	require.Len(t, all, 2)
+	require.Equal(t, "alloc", all[0].Name)
+	require.Equal(t, "pollcount", all[1].Name)
+
+	require.Equal(t, map[string][]string{
+		"pollcount": {" pollcount ", "PollCount"},
+	}, s.Collisions())
+}
+
+// TestNormalizingStorage_TrackingLimit проверяет, что учёт написаний не растёт сверх ограничений.
+//
+// t — указатель на структуру теста.
+func TestNormalizingStorage_TrackingLimit(t *testing.T) {
+	s := NewNormalizingStorage(NewMemStorage())
+	for i := 0; i < maxTrackedIDs+10; i++ {
+		s.AddCounter(fmt.Sprintf("c%d", i), 1)
+	}
+	for i := 0; i < maxSpellingsPerID+10; i++ {
+		s.SetGauge("c0"+strings.Repeat(" ", i), 1)
+	}
+
+	require.Len(t, s.originals, maxTrackedIDs)
+	require.Len(t, s.Collisions()["c0"], maxSpellingsPerID)
+	_, ok := s.GetCounter(fmt.Sprintf("C%d", maxTrackedIDs+5))
+	require.True(t, ok, "untracked names are still normalized")
+}
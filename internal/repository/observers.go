@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// ObserverFactory создаёт наблюдателя событий аудита по параметрам из конфигурации.
+type ObserverFactory func(opts map[string]string) (models.AuditObserver, error)
+
+var (
+	observerFactoriesMu sync.RWMutex
+	observerFactories   = map[string]ObserverFactory{}
+)
+
+func init() {
+	RegisterObserver("file", openFileObserver)
+	RegisterObserver("http", openHTTPObserver)
+	RegisterObserver("kafka", openKafkaObserver)
+}
+
+// RegisterObserver регистрирует фабрику наблюдателя для типа kind.
+//
+// Повторная регистрация типа заменяет ранее зарегистрированную фабрику.
+func RegisterObserver(kind string, factory ObserverFactory) {
+	observerFactoriesMu.Lock()
+	defer observerFactoriesMu.Unlock()
+	observerFactories[strings.ToLower(kind)] = factory
+}
+
+// RegisteredObserverTypes возвращает отсортированный список зарегистрированных типов наблюдателей.
+func RegisteredObserverTypes() []string {
+	observerFactoriesMu.RLock()
+	defer observerFactoriesMu.RUnlock()
+	kinds := make([]string, 0, len(observerFactories))
+	for k := range observerFactories {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// NewObserver создаёт наблюдателя, выбирая фабрику по типу kind.
+//
+// kind — имя зарегистрированного типа (регистр не учитывается).
+// opts — параметры наблюдателя.
+func NewObserver(kind string, opts map[string]string) (models.AuditObserver, error) {
+	observerFactoriesMu.RLock()
+	factory, ok := observerFactories[strings.ToLower(kind)]
+	observerFactoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown observer type %q (supported: %s)",
+			kind, strings.Join(RegisteredObserverTypes(), ", "))
+	}
+	observer, err := factory(opts)
+	if err != nil {
+		return nil, fmt.Errorf("observer %s: %w", kind, err)
+	}
+	return observer, nil
+}
+
+// requiredOption возвращает обязательный параметр наблюдателя name.
+func requiredOption(opts map[string]string, name string) (string, error) {
+	v := opts[name]
+	if v == "" {
+		return "", fmt.Errorf("option %q is required", name)
+	}
+	return v, nil
+}
+
+// openFileObserver создаёт наблюдателя, записывающего события в файл (параметр "path").
+//
+// Необязательные параметры ротации: "max_size_mb", "max_age" (например "720h"),
+// "max_backups" и "compress"; без "max_size_mb" действуют настройки audit_rotation сервера.
+func openFileObserver(opts map[string]string) (models.AuditObserver, error) {
+	path, err := requiredOption(opts, "path")
+	if err != nil {
+		return nil, err
+	}
+
+	var rc config.AuditRotationConfig
+	if v := opts["max_size_mb"]; v != "" {
+		if rc.MaxSizeMB, err = strconv.Atoi(v); err != nil || rc.MaxSizeMB < 1 {
+			return nil, fmt.Errorf(`option "max_size_mb" must be a positive integer, got %q`, v)
+		}
+	}
+	if v := opts["max_age"]; v != "" {
+		if rc.MaxAge, err = time.ParseDuration(v); err != nil || rc.MaxAge <= 0 {
+			return nil, fmt.Errorf(`option "max_age" must be a positive duration, got %q`, v)
+		}
+	}
+	if v := opts["max_backups"]; v != "" {
+		if rc.MaxBackups, err = strconv.Atoi(v); err != nil || rc.MaxBackups < 0 {
+			return nil, fmt.Errorf(`option "max_backups" must be a non-negative integer, got %q`, v)
+		}
+	}
+	if v := opts["compress"]; v != "" {
+		if rc.Compress, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf(`option "compress" must be a boolean, got %q`, v)
+		}
+	}
+
+	observer := NewFileAuditObserver(path)
+	observer.SetRotation(rc)
+	return observer, nil
+}
+
+// openHTTPObserver создаёт наблюдателя, отправляющего события POST-запросом (параметр "url").
+func openHTTPObserver(opts map[string]string) (models.AuditObserver, error) {
+	url, err := requiredOption(opts, "url")
+	if err != nil {
+		return nil, err
+	}
+	return NewHTTPAuditObserver(url), nil
+}
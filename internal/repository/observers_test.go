@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewObserver проверяет создание наблюдателей аудита по типу из реестра.
+//
+// t — указатель на структуру теста.
+func TestNewObserver(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		opts    map[string]string
+		want    any
+		wantErr bool
+	}{
+		{"file", "file", map[string]string{"path": filepath.Join(t.TempDir(), "audit.log")}, &FileAuditObserver{}, false},
+		{"http case insensitive", "HTTP", map[string]string{"url": "http://localhost/audit"}, &HTTPAuditObserver{}, false},
+		{"file without path", "file", nil, nil, true},
+		{"file with rotation", "file", map[string]string{"path": filepath.Join(t.TempDir(), "audit.log"), "max_size_mb": "100", "max_age": "720h", "max_backups": "5", "compress": "true"}, &FileAuditObserver{}, false},
+		{"file invalid max size", "file", map[string]string{"path": "audit.log", "max_size_mb": "0"}, nil, true},
+		{"file invalid max age", "file", map[string]string{"path": "audit.log", "max_size_mb": "1", "max_age": "30d"}, nil, true},
+		{"file invalid compress", "file", map[string]string{"path": "audit.log", "max_size_mb": "1", "compress": "gzip"}, nil, true},
+		{"http without url", "http", map[string]string{"path": "x"}, nil, true},
+		{"kafka", "kafka", map[string]string{"brokers": "localhost:9092, localhost:9093", "topic": "audit", "batch_timeout": "50ms"}, &KafkaAuditObserver{}, false},
+		{"kafka without topic", "kafka", map[string]string{"brokers": "localhost:9092"}, nil, true},
+		{"kafka empty brokers", "kafka", map[string]string{"brokers": " , ", "topic": "audit"}, nil, true},
+		{"kafka invalid batch size", "kafka", map[string]string{"brokers": "localhost:9092", "topic": "audit", "batch_size": "0"}, nil, true},
+		{"kafka invalid retries", "kafka", map[string]string{"brokers": "localhost:9092", "topic": "audit", "retries": "x"}, nil, true},
+		{"unknown type", "amqp", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observer, err := NewObserver(tt.kind, tt.opts)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.IsType(t, tt.want, observer)
+		})
+	}
+}
+
+// TestRegisterObserver проверяет регистрацию собственного типа наблюдателя.
+//
+// t — указатель на структуру теста.
+func TestRegisterObserver(t *testing.T) {
+	recorder := NewFileAuditObserver(filepath.Join(t.TempDir(), "audit.log"))
+	RegisterObserver("test-recorder", func(map[string]string) (models.AuditObserver, error) {
+		return recorder, nil
+	})
+	require.Contains(t, RegisteredObserverTypes(), "test-recorder")
+
+	observer, err := NewObserver("test-recorder", nil)
+	require.NoError(t, err)
+	require.Same(t, recorder, observer)
+}
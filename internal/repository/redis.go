@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Ключи хэшей Redis, в которых хранятся метрики (поле — имя метрики, значение — её значение).
+const (
+	redisGaugeKey   = "metrics:gauge"
+	redisCounterKey = "metrics:counter"
+)
+
+// openRedisStorage создаёт in-memory хранилище с синхронизацией в Redis.
+//
+// DSN имеет вид redis://[user:password@]host:port[/db]. Сохранённые в Redis метрики
+// загружаются в память при открытии.
+func openRedisStorage(ctx context.Context, dsn string, opts StorageOptions) (*StorageBackend, error) {
+	client, err := openRedisClient(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	storage := NewStorage(opts.Shards)
+	if err := loadFromRedis(ctx, storage, client); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return &StorageBackend{
+		Storage:   storage,
+		Sink:      RedisSink(storage, client),
+		Preloaded: true,
+		close:     func() { _ = client.Close() },
+	}, nil
+}
+
+// openRedisClient подключается к Redis по DSN и проверяет соединение.
+func openRedisClient(ctx context.Context, dsn string) (*redis.Client, error) {
+	redisOpts, err := redis.ParseURL(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis DSN: %w", err)
+	}
+	client := redis.NewClient(redisOpts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return client, nil
+}
+
+// redisSink — Sink, выгружающий метрики в хэши Redis.
+type redisSink struct {
+	storage Storage
+	client  *redis.Client
+}
+
+// RedisSink возвращает Sink, выгружающий все метрики storage в хэши Redis одной транзакцией.
+func RedisSink(storage Storage, client *redis.Client) Sink {
+	return redisSink{storage: storage, client: client}
+}
+
+// Sync выгружает все метрики в хэши Redis.
+func (s redisSink) Sync(ctx context.Context) error {
+	metrics := s.storage.GetAll()
+	if len(metrics) == 0 {
+		return nil
+	}
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, m := range metrics {
+			if key := redisKey(m.Type); key != "" {
+				pipe.HSet(ctx, key, m.Name, m.Value)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sync metrics to redis: %w", err)
+	}
+	return nil
+}
+
+// Delete удаляет метрики из хэшей Redis одной транзакцией.
+func (s redisSink) Delete(ctx context.Context, metrics []MetricInfo) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, m := range metrics {
+			if key := redisKey(m.Type); key != "" {
+				pipe.HDel(ctx, key, m.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete metrics from redis: %w", err)
+	}
+	return nil
+}
+
+// redisKey возвращает ключ хэша Redis для метрик типа metricType или пустую строку.
+func redisKey(metricType string) string {
+	switch metricType {
+	case "gauge":
+		return redisGaugeKey
+	case "counter":
+		return redisCounterKey
+	}
+	return ""
+}
+
+// loadFromRedis загружает метрики из хэшей Redis в storage.
+func loadFromRedis(ctx context.Context, storage Storage, client *redis.Client) error {
+	gauges, err := client.HGetAll(ctx, redisGaugeKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to load gauges from redis: %w", err)
+	}
+	counters, err := client.HGetAll(ctx, redisCounterKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to load counters from redis: %w", err)
+	}
+
+	updates := make([]MetricUpdate, 0, len(gauges)+len(counters))
+	for name, s := range gauges {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("gauge %s: %w", name, err)
+		}
+		updates = append(updates, MetricUpdate{Type: "gauge", Name: name, FloatVal: &v})
+	}
+	for name, s := range counters {
+		v, err := ParseCounter(s)
+		if err != nil {
+			return fmt.Errorf("counter %s: %w", name, err)
+		}
+		updates = append(updates, MetricUpdate{Type: "counter", Name: name, IntVal: &v})
+	}
+	UpdateBatch(storage, updates)
+	return nil
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+)
+
+// s3UploadTimeout — максимальная длительность выгрузки одного снимка.
+const s3UploadTimeout = 5 * time.Minute
+
+// S3Uploader выгружает файл снимка в S3-совместимое объектное хранилище.
+//
+// Запросы подписываются AWS Signature Version 4; используется адресация
+// в стиле пути (endpoint/bucket/key), которую поддерживают AWS S3, MinIO и аналоги.
+//
+// Поля:
+//   - cfg: настройки объектного хранилища
+//   - client: HTTP-клиент для запросов
+//   - now: функция получения текущего времени (для подписи)
+type S3Uploader struct {
+	cfg    config.S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Uploader создаёт новый экземпляр S3Uploader.
+//
+// cfg — настройки объектного хранилища.
+func NewS3Uploader(cfg config.S3Config) *S3Uploader {
+	return &S3Uploader{
+		cfg:    cfg,
+		client: &http.Client{Timeout: s3UploadTimeout},
+		now:    time.Now,
+	}
+}
+
+// Upload выгружает файл filePath в бакет.
+//
+// Ключ объекта берётся из настроек, а если он не задан — совпадает с именем файла.
+func (u *S3Uploader) Upload(ctx context.Context, filePath string) error {
+	payloadHash, size, err := fileSHA256(filePath)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	key := u.cfg.ObjectKey
+	if key == "" {
+		key = filepath.Base(filePath)
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(u.cfg.Endpoint, "/"))
+	if err != nil {
+		return fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+	endpoint.Path += "/" + u.cfg.Bucket + "/" + strings.TrimPrefix(key, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint.String(), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/json")
+	u.sign(req, payloadHash)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 upload failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 upload failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// sign добавляет к запросу заголовки подписи AWS Signature Version 4.
+func (u *S3Uploader) sign(req *http.Request, payloadHash string) {
+	now := u.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"content-type:" + req.Header.Get("Content-Type") + "\n" +
+			"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + u.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	signature := hex.EncodeToString(hmacSHA256(signingKey(u.cfg.SecretAccessKey, date, u.cfg.Region, "s3"), stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+u.cfg.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// signingKey вычисляет ключ подписи AWS Signature Version 4.
+func signingKey(secret, date, region, service string) []byte {
+	k := hmacSHA256([]byte("AWS4"+secret), date)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	return hmacSHA256(k, "aws4_request")
+}
+
+// hmacSHA256 возвращает HMAC-SHA256 строки data с ключом key.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sha256Hex возвращает hex-представление SHA-256 от data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileSHA256 потоково вычисляет SHA-256 файла и возвращает его hex-представление и размер.
+func fileSHA256(filePath string) (string, int64, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
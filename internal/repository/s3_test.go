@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSigningKey проверяет вычисление ключа подписи на тестовом векторе из документации AWS.
+//
+// t — указатель на структуру теста.
+func TestSigningKey(t *testing.T) {
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	require.Equal(t, "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d", hex.EncodeToString(key))
+}
+
+// TestS3Uploader_Upload проверяет выгрузку файла снимка и заголовки подписи запроса.
+//
+// t — указатель на структуру теста.
+func TestS3Uploader_Upload(t *testing.T) {
+	content := `[{"id":"g","type":"gauge","value":1}]`
+	fpath := filepath.Join(t.TempDir(), "metrics.json")
+	require.NoError(t, os.WriteFile(fpath, []byte(content), 0644))
+
+	tests := []struct {
+		name      string
+		objectKey string
+		status    int
+		wantPath  string
+		wantErr   bool
+	}{
+		{name: "default key", status: http.StatusOK, wantPath: "/bucket/metrics.json"},
+		{name: "custom key", objectKey: "backups/server-1.json", status: http.StatusOK, wantPath: "/bucket/backups/server-1.json"},
+		{name: "server error", status: http.StatusForbidden, wantPath: "/bucket/metrics.json", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotBody, gotAuth, gotHash, gotDate string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPut, r.Method)
+				body, _ := io.ReadAll(r.Body)
+				gotPath, gotBody = r.URL.Path, string(body)
+				gotAuth = r.Header.Get("Authorization")
+				gotHash = r.Header.Get("X-Amz-Content-Sha256")
+				gotDate = r.Header.Get("X-Amz-Date")
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			u := NewS3Uploader(config.S3Config{
+				Endpoint:        srv.URL,
+				Bucket:          "bucket",
+				Region:          "us-east-1",
+				AccessKeyID:     "AKID",
+				SecretAccessKey: "secret",
+				ObjectKey:       tt.objectKey,
+			})
+			u.now = func() time.Time { return time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC) }
+
+			err := u.Upload(context.Background(), fpath)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantPath, gotPath)
+			require.Equal(t, content, gotBody)
+			require.Equal(t, sha256Hex([]byte(content)), gotHash)
+			require.Equal(t, "20250101T120000Z", gotDate)
+			require.True(t, strings.HasPrefix(gotAuth,
+				"AWS4-HMAC-SHA256 Credential=AKID/20250101/us-east-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature="))
+		})
+	}
+}
@@ -1,16 +1,20 @@
 package repository
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/RoGogDBD/metric-alerter/internal/config"
 	models "github.com/RoGogDBD/metric-alerter/internal/model"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mailru/easyjson/jwriter"
 )
 
 // GetEnvOrFlagInt возвращает значение переменной окружения по ключу envKey как int,
@@ -59,15 +63,48 @@ func GetEnvOrFlagBool(envKey string, flagVal bool) bool {
 	return flagVal
 }
 
+// BackupSuffix — суффикс резервной копии файла снимка метрик.
+//
+// Перед заменой основного файла предыдущая версия сохраняется с этим суффиксом
+// и используется при восстановлении, если основной файл повреждён или отсутствует.
+const BackupSuffix = ".bak"
+
 // SaveMetricsToFile сохраняет все метрики из хранилища storage в файл filePath в формате JSON.
 //
+// Запись выполняется атомарно без принудительного сброса на диск (см. SaveMetricsToFileSync).
+//
 // storage — интерфейс хранилища метрик.
 // filePath — путь к файлу для сохранения.
 //
 // Возвращает ошибку при неудаче записи.
 func SaveMetricsToFile(storage Storage, filePath string) error {
+	return saveMetricsToFile(storage, filePath, false)
+}
+
+// SaveMetricsToFileSync сохраняет метрики так же, как SaveMetricsToFile,
+// дополнительно выполняя fsync временного файла и каталога перед возвратом.
+//
+// storage — интерфейс хранилища метрик.
+// filePath — путь к файлу для сохранения.
+//
+// Возвращает ошибку при неудаче записи.
+func SaveMetricsToFileSync(storage Storage, filePath string) error {
+	return saveMetricsToFile(storage, filePath, true)
+}
+
+// saveMetricsToFile формирует снимок метрик и атомарно записывает его в filePath.
+func saveMetricsToFile(storage Storage, filePath string, fsync bool) error {
+	return writeFileAtomic(filePath, fsync, func(w io.Writer) error {
+		return WriteMetrics(storage, w)
+	})
+}
+
+// WriteMetrics записывает снимок всех метрик хранилища storage в w в формате JSON.
+//
+// Формат совпадает с файлом снимка (см. SaveMetricsToFile) и читается LoadMetricsFromFile.
+func WriteMetrics(storage Storage, w io.Writer) error {
 	metrics := storage.GetAll()
-	var out []models.Metrics
+	var out models.MetricsBatch
 	for _, m := range metrics {
 		switch m.Type {
 		case "gauge":
@@ -78,7 +115,10 @@ func SaveMetricsToFile(storage Storage, filePath string) error {
 				Value: &val,
 			})
 		case "counter":
-			delta, _ := strconv.ParseInt(m.Value, 10, 64)
+			delta, err := ParseCounter(m.Value)
+			if err != nil {
+				return fmt.Errorf("counter %s: %w", m.Name, err)
+			}
 			out = append(out, models.Metrics{
 				ID:    m.Name,
 				MType: "counter",
@@ -86,13 +126,65 @@ func SaveMetricsToFile(storage Storage, filePath string) error {
 			})
 		}
 	}
-	f, err := os.Create(filePath)
+	jw := jwriter.Writer{}
+	out.MarshalEasyJSON(&jw)
+	jw.RawByte('\n')
+	if jw.Error != nil {
+		return jw.Error
+	}
+	_, err := jw.DumpTo(w)
+	return err
+}
+
+// writeFileAtomic записывает данные во временный файл рядом с filePath и переименовывает его в filePath.
+//
+// Предыдущая версия файла (если есть) переименовывается в filePath+BackupSuffix.
+// Если fsync равен true, временный файл и каталог сбрасываются на диск,
+// чтобы результат пережил аварийное завершение процесса или ОС.
+//
+// Возвращает ошибку при неудаче любого этапа; при ошибке временный файл удаляется.
+func writeFileAtomic(filePath string, fsync bool, write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(filePath)
+	tmp, err := os.CreateTemp(dir, filepath.Base(filePath)+".tmp-*")
 	if err != nil {
 		return err
 	}
-	defer func() { _ = f.Close() }()
-	enc := json.NewEncoder(f)
-	return enc.Encode(out)
+	tmpPath := tmp.Name()
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if fsync {
+		if err = tmp.Sync(); err != nil {
+			return fmt.Errorf("failed to sync snapshot: %w", err)
+		}
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if _, statErr := os.Stat(filePath); statErr == nil {
+		if err = os.Rename(filePath, filePath+BackupSuffix); err != nil {
+			return fmt.Errorf("failed to back up snapshot: %w", err)
+		}
+	}
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+
+	if fsync {
+		if d, dirErr := os.Open(dir); dirErr == nil {
+			_ = d.Sync()
+			_ = d.Close()
+		}
+	}
+	return nil
 }
 
 // SyncToDB синхронизирует все метрики из хранилища storage с базой данных db.
@@ -132,7 +224,10 @@ func SyncToDB(ctx context.Context, storage Storage, db *pgxpool.Pool) error {
 					return fmt.Errorf("failed to insert gauge %s: %w", m.Name, err)
 				}
 			case "counter":
-				delta, _ := strconv.ParseInt(m.Value, 10, 64)
+				delta, err := ParseCounter(m.Value)
+				if err != nil {
+					return fmt.Errorf("counter %s: %w", m.Name, err)
+				}
 				if _, err := tx.Exec(ctx, stmt, m.Name, "counter", delta, nil); err != nil {
 					return fmt.Errorf("failed to insert counter %s: %w", m.Name, err)
 				}
@@ -147,40 +242,110 @@ func SyncToDB(ctx context.Context, storage Storage, db *pgxpool.Pool) error {
 	})
 }
 
+// DeleteFromDB удаляет метрики metrics из таблицы metrics базы данных db.
+//
+// Использует транзакцию и стратегию повторов с экспоненциальной задержкой.
+//
+// Возвращает ошибку при неудаче удаления.
+func DeleteFromDB(ctx context.Context, db *pgxpool.Pool, metrics []MetricInfo) error {
+	return config.RetryWithBackoff(ctx, func() error {
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		for _, m := range metrics {
+			if _, err := tx.Exec(ctx, `DELETE FROM metrics WHERE id = $1 AND type = $2`, m.Name, m.Type); err != nil {
+				return fmt.Errorf("failed to delete %s %s: %w", m.Type, m.Name, err)
+			}
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	})
+}
+
 // LoadMetricsFromFile загружает метрики из файла filePath в хранилище storage.
 //
-// Ожидает, что файл содержит массив метрик в формате JSON.
-// Для каждой метрики вызывает соответствующий метод хранилища.
+// Ожидает, что файл содержит массив метрик в формате JSON. Файл разбирается потоково,
+// метрики применяются пакетами по мере разбора (см. UpdateBatch).
+// Если основной файл отсутствует или повреждён до применения первого пакета, выполняется
+// попытка восстановления из резервной копии filePath+BackupSuffix. Если повреждение
+// обнаружено позже, возвращается ошибка, а уже применённые метрики остаются в хранилище.
 //
 // storage — интерфейс хранилища метрик.
 // filePath — путь к файлу для загрузки.
 //
-// Возвращает ошибку при неудаче чтения или декодирования.
+// Возвращает ошибку при неудаче чтения или декодирования (ошибку основного файла,
+// если резервная копия также недоступна).
 func LoadMetricsFromFile(storage Storage, filePath string) error {
+	n, err := loadMetricsFile(storage, filePath)
+	if err == nil {
+		return nil
+	}
+	if n > 0 {
+		// Часть метрик уже применена: восстановление из копии повторно увеличило бы счётчики.
+		return fmt.Errorf("snapshot %s is corrupted after %d metrics: %w", filePath, n, err)
+	}
+	if _, bakErr := loadMetricsFile(storage, filePath+BackupSuffix); bakErr != nil {
+		return err
+	}
+	log.Printf("Snapshot %s is unusable (%v), restoring from backup", filePath, err)
+	return nil
+}
+
+// loadBatchSize — количество метрик, применяемых к хранилищу одним пакетом при загрузке снимка.
+const loadBatchSize = 1024
+
+// loadMetricsFile потоково разбирает файл снимка и применяет метрики пакетами через UpdateBatch.
+//
+// Файл не загружается в память целиком, поэтому снимки в сотни мегабайт восстанавливаются
+// без удвоения потребления памяти.
+//
+// Возвращает количество применённых метрик и ошибку разбора или чтения.
+func loadMetricsFile(storage Storage, filePath string) (int, error) {
 	f, err := os.Open(filePath)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer func() { _ = f.Close() }()
-	data, err := io.ReadAll(f)
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	tok, err := dec.Token()
 	if err != nil {
-		return err
+		return 0, err
 	}
-	var metrics []models.Metrics
-	if err := json.Unmarshal(data, &metrics); err != nil {
-		return err
+	if tok == nil {
+		// Пустой снимок записывается как null.
+		return 0, nil
 	}
-	for _, m := range metrics {
-		switch m.MType {
-		case "gauge":
-			if m.Value != nil {
-				storage.SetGauge(m.ID, *m.Value)
-			}
-		case "counter":
-			if m.Delta != nil {
-				storage.AddCounter(m.ID, *m.Delta)
-			}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return 0, fmt.Errorf("invalid snapshot: expected array, got %v", tok)
+	}
+
+	applied := 0
+	batch := make([]MetricUpdate, 0, loadBatchSize)
+	flush := func() {
+		UpdateBatch(storage, batch)
+		applied += len(batch)
+		batch = batch[:0]
+	}
+	for dec.More() {
+		var m models.Metrics
+		if err := dec.Decode(&m); err != nil {
+			return applied, err
+		}
+		batch = append(batch, MetricUpdate{Type: m.MType, Name: m.ID, FloatVal: m.Value, IntVal: m.Delta})
+		if len(batch) == loadBatchSize {
+			flush()
 		}
 	}
-	return nil
+	if _, err := dec.Token(); err != nil {
+		return applied, err
+	}
+	flush()
+	return applied, nil
 }
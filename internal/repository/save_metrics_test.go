@@ -2,8 +2,10 @@ package repository
 
 import (
 	"encoding/json"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 
 	models "github.com/RoGogDBD/metric-alerter/internal/model"
@@ -139,3 +141,135 @@ func TestSaveAndLoadMetrics_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+// TestSaveMetricsToFile_AtomicWithBackup проверяет, что повторное сохранение оставляет
+// предыдущую версию снимка в резервной копии и не оставляет временных файлов.
+//
+// t — указатель на структуру теста.
+func TestSaveMetricsToFile_AtomicWithBackup(t *testing.T) {
+	dir := t.TempDir()
+	fpath := filepath.Join(dir, "metrics.json")
+
+	s := NewMemStorage()
+	s.SetGauge("g", 1)
+	require.NoError(t, SaveMetricsToFile(s, fpath))
+	_, err := os.Stat(fpath + BackupSuffix)
+	require.True(t, os.IsNotExist(err), "backup must not exist after first save")
+
+	s.SetGauge("g", 2)
+	require.NoError(t, SaveMetricsToFileSync(s, fpath))
+
+	prev := NewMemStorage()
+	require.NoError(t, LoadMetricsFromFile(prev, fpath+BackupSuffix))
+	v, ok := prev.GetGauge("g")
+	require.True(t, ok)
+	require.Equal(t, 1.0, v)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "only snapshot and backup are expected in directory")
+}
+
+// TestLoadMetricsFromFile_RecoverFromBackup проверяет восстановление метрик из резервной копии,
+// если основной файл снимка повреждён или отсутствует.
+//
+// t — указатель на структуру теста.
+func TestLoadMetricsFromFile_RecoverFromBackup(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary func(t *testing.T, path string)
+	}{
+		{
+			name: "corrupted primary",
+			primary: func(t *testing.T, path string) {
+				require.NoError(t, os.WriteFile(path, []byte(`[{"id":"g","type":"gau`), 0644))
+			},
+		},
+		{
+			name:    "missing primary",
+			primary: func(t *testing.T, path string) {},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fpath := filepath.Join(t.TempDir(), "metrics.json")
+			require.NoError(t, os.WriteFile(fpath+BackupSuffix, []byte(`[{"id":"g","type":"gauge","value":5}]`), 0644))
+			tt.primary(t, fpath)
+
+			s := NewMemStorage()
+			require.NoError(t, LoadMetricsFromFile(s, fpath))
+			v, ok := s.GetGauge("g")
+			require.True(t, ok)
+			require.Equal(t, 5.0, v)
+		})
+	}
+
+	t.Run("no primary and no backup", func(t *testing.T) {
+		err := LoadMetricsFromFile(NewMemStorage(), filepath.Join(t.TempDir(), "metrics.json"))
+		require.True(t, os.IsNotExist(err))
+	})
+}
+
+// TestLoadMetricsFromFile_Streaming проверяет загрузку снимка, превышающего размер пакета,
+// и поведение при повреждении файла после применения части метрик.
+//
+// t — указатель на структуру теста.
+func TestLoadMetricsFromFile_Streaming(t *testing.T) {
+	src := NewMemStorage()
+	for i := 0; i < 3*loadBatchSize+7; i++ {
+		src.SetGauge("g"+strconv.Itoa(i), float64(i))
+		src.AddCounter("c"+strconv.Itoa(i), int64(i))
+	}
+	fpath := filepath.Join(t.TempDir(), "metrics.json")
+	require.NoError(t, SaveMetricsToFile(src, fpath))
+
+	dst := NewMemStorage()
+	require.NoError(t, LoadMetricsFromFile(dst, fpath))
+	require.Equal(t, src.GetAll(), dst.GetAll())
+
+	data, err := os.ReadFile(fpath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(fpath, data[:len(data)-100], 0644))
+	require.NoError(t, os.WriteFile(fpath+BackupSuffix, []byte(`[{"id":"g0","type":"gauge","value":5}]`), 0644))
+
+	partial := NewMemStorage()
+	err = LoadMetricsFromFile(partial, fpath)
+	require.ErrorContains(t, err, "corrupted after")
+	require.NotEmpty(t, partial.GetAll())
+	v, ok := partial.GetGauge("g0")
+	require.True(t, ok)
+	require.Equal(t, 0.0, v, "backup must not be applied on top of a partially loaded snapshot")
+}
+
+// TestLoadMetricsFromFile_EmptySnapshot проверяет загрузку пустого снимка (null).
+//
+// t — указатель на структуру теста.
+func TestLoadMetricsFromFile_EmptySnapshot(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "metrics.json")
+	require.NoError(t, SaveMetricsToFile(NewMemStorage(), fpath))
+	s := NewMemStorage()
+	require.NoError(t, LoadMetricsFromFile(s, fpath))
+	require.Empty(t, s.GetAll())
+}
+
+// TestSaveAndLoadMetrics_ExtremeCounters проверяет сохранение и загрузку счётчиков на границах int64 без потери точности.
+//
+// t — указатель на структуру теста.
+func TestSaveAndLoadMetrics_ExtremeCounters(t *testing.T) {
+	src := NewMemStorage()
+	src.AddCounter("max", math.MaxInt64)
+	src.AddCounter("min", math.MinInt64)
+	src.AddCounter("above_2_53", 1<<53+1)
+
+	fpath := filepath.Join(t.TempDir(), "metrics.json")
+	require.NoError(t, SaveMetricsToFile(src, fpath))
+
+	dst := NewMemStorage()
+	require.NoError(t, LoadMetricsFromFile(dst, fpath))
+	require.Equal(t, src.GetAll(), dst.GetAll())
+
+	v, _ := dst.GetCounter("above_2_53")
+	require.Equal(t, int64(1<<53+1), v)
+}
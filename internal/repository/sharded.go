@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// memShard — отдельный сегмент ShardedMemStorage со своим мьютексом.
+type memShard struct {
+	gauge          map[string]float64 // Хранилище gauge-метрик сегмента
+	counter        map[string]int64   // Хранилище counter-метрик сегмента
+	gaugeChanges   map[string]change  // Последние изменения gauge-метрик сегмента
+	counterChanges map[string]change  // Последние изменения counter-метрик сегмента
+	mu             sync.RWMutex       // Мьютекс сегмента
+}
+
+// ShardedMemStorage реализует интерфейс Storage на основе памяти, разделённой на сегменты.
+//
+// Метрика попадает в сегмент по хешу имени (FNV-1a), поэтому обновления разных метрик
+// блокируют разные мьютексы и меньше конкурируют между собой, чем в MemStorage.
+type ShardedMemStorage struct {
+	shards []*memShard   // Сегменты хранилища
+	gen    atomic.Uint64 // Счётчик изменений (поколение) хранилища
+}
+
+// NewShardedMemStorage создаёт хранилище из shards сегментов.
+//
+// shards — число сегментов; значения меньше 1 приводятся к 1.
+//
+// Возвращает Storage с пустыми сегментами.
+func NewShardedMemStorage(shards int) Storage {
+	if shards < 1 {
+		shards = 1
+	}
+	s := &ShardedMemStorage{shards: make([]*memShard, shards)}
+	for i := range s.shards {
+		s.shards[i] = &memShard{
+			gauge:          make(map[string]float64),
+			counter:        make(map[string]int64),
+			gaugeChanges:   make(map[string]change),
+			counterChanges: make(map[string]change),
+		}
+	}
+	return s
+}
+
+// NewStorage создаёт хранилище метрик в памяти.
+//
+// shards — число сегментов; при значении больше 1 возвращается ShardedMemStorage,
+// иначе — MemStorage.
+func NewStorage(shards int) Storage {
+	if shards > 1 {
+		return NewShardedMemStorage(shards)
+	}
+	return NewMemStorage()
+}
+
+// shard возвращает сегмент, в котором хранится метрика name.
+func (s *ShardedMemStorage) shard(name string) *memShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// SetGauge устанавливает значение gauge-метрики по имени.
+func (s *ShardedMemStorage) SetGauge(name string, value float64) {
+	sh := s.shard(name)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.gauge[name] = value
+	sh.gaugeChanges[name] = change{gen: s.gen.Add(1), at: time.Now()}
+}
+
+// AddCounter увеличивает значение counter-метрики по имени на delta.
+func (s *ShardedMemStorage) AddCounter(name string, delta int64) {
+	sh := s.shard(name)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.counter[name] += delta
+	sh.counterChanges[name] = change{gen: s.gen.Add(1), at: time.Now()}
+}
+
+// GetGauge возвращает значение gauge-метрики по имени и флаг наличия.
+func (s *ShardedMemStorage) GetGauge(name string) (float64, bool) {
+	sh := s.shard(name)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	val, ok := sh.gauge[name]
+	return val, ok
+}
+
+// GetCounter возвращает значение counter-метрики по имени и флаг наличия.
+func (s *ShardedMemStorage) GetCounter(name string) (int64, bool) {
+	sh := s.shard(name)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	val, ok := sh.counter[name]
+	return val, ok
+}
+
+// GetAll возвращает срез всех метрик в виде MetricInfo, упорядоченный по имени, а затем по типу.
+//
+// Сегменты блокируются поочерёдно, поэтому результат не является атомарным снимком
+// всего хранилища, но каждая метрика возвращается в согласованном состоянии.
+func (s *ShardedMemStorage) GetAll() []MetricInfo {
+	var result []MetricInfo
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		for k, v := range sh.gauge {
+			result = append(result, MetricInfo{
+				Name:  k,
+				Type:  "gauge",
+				Value: strconv.FormatFloat(v, 'f', -1, 64),
+			})
+		}
+		for k, v := range sh.counter {
+			result = append(result, MetricInfo{
+				Name:  k,
+				Type:  "counter",
+				Value: strconv.FormatInt(v, 10),
+			})
+		}
+		sh.mu.RUnlock()
+	}
+	SortMetricInfo(result)
+	return result
+}
+
+// Generation возвращает текущее поколение хранилища.
+func (s *ShardedMemStorage) Generation() uint64 {
+	return s.gen.Load()
+}
+
+// Changes возвращает метрики, изменённые после поколения gen и позже момента since.
+//
+// Как и GetAll, блокирует сегменты поочерёдно.
+func (s *ShardedMemStorage) Changes(gen uint64, since time.Time) []MetricInfo {
+	var result []MetricInfo
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		result = appendChanges(result, sh.gauge, sh.counter, sh.gaugeChanges, sh.counterChanges, gen, since)
+		sh.mu.RUnlock()
+	}
+	SortMetricInfo(result)
+	return result
+}
+
+// UpdatedAt возвращает время последнего изменения метрики по типу и имени и флаг наличия.
+func (s *ShardedMemStorage) UpdatedAt(metricType, name string) (time.Time, bool) {
+	sh := s.shard(name)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	return lastChange(sh.gaugeChanges, sh.counterChanges, metricType, name)
+}
+
+// Expire удаляет метрику, если она не изменялась позже момента before.
+func (s *ShardedMemStorage) Expire(metricType, name string, before time.Time) bool {
+	sh := s.shard(name)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return expireChange(sh.gauge, sh.counter, sh.gaugeChanges, sh.counterChanges, metricType, name, before, &s.gen)
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// checkpointer реализуется хранилищами, которым нужно знать о моменте сохранения снимка (например, WALStorage).
+type checkpointer interface {
+	Checkpoint(save func() error) error
+}
+
+// SnapshotSaver сохраняет снимок хранилища в файл только при наличии изменений.
+//
+// Запоминает поколение хранилища (Storage.Generation) на момент последнего
+// успешного сохранения и пропускает запись, если хранилище с тех пор не менялось.
+//
+// Поля:
+//   - storage: хранилище метрик
+//   - filePath: путь к файлу снимка
+//   - savedGen: поколение хранилища при последнем успешном сохранении
+//   - saved: признак того, что снимок уже сохранялся
+//   - fsync: признак принудительного сброса снимка на диск
+//   - noCheckpoint: снимок не считается контрольной точкой журнала упреждающей записи
+//   - afterSave: действие после успешного сохранения (например, выгрузка в объектное хранилище)
+//   - observer: получатель длительности и результата каждой записи снимка
+//   - mu: мьютекс, исключающий параллельную запись снимка
+type SnapshotSaver struct {
+	storage      Storage
+	filePath     string
+	savedGen     uint64
+	saved        bool
+	fsync        bool
+	noCheckpoint bool
+	afterSave    func(filePath string) error
+	observer     func(d time.Duration, err error)
+	mu           sync.Mutex
+}
+
+// NewSnapshotSaver создаёт новый экземпляр SnapshotSaver.
+//
+// storage — хранилище метрик.
+// filePath — путь к файлу снимка.
+//
+// Возвращает указатель на SnapshotSaver.
+func NewSnapshotSaver(storage Storage, filePath string) *SnapshotSaver {
+	return &SnapshotSaver{storage: storage, filePath: filePath}
+}
+
+// SetFsync включает или отключает fsync снимка при сохранении.
+func (s *SnapshotSaver) SetFsync(fsync bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fsync = fsync
+}
+
+// SetCheckpoint включает или отключает очистку журнала упреждающей записи после сохранения
+// (по умолчанию включена).
+//
+// Журнал восстанавливается поверх снимка StoreFile, поэтому очищать его может только
+// сохранение этого снимка; копии в других файлах сохраняются без контрольной точки.
+func (s *SnapshotSaver) SetCheckpoint(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noCheckpoint = !enabled
+}
+
+// SetAfterSave задаёт действие, выполняемое после каждого успешного сохранения снимка.
+//
+// fn получает путь к файлу снимка; файл не перезаписывается до завершения fn.
+func (s *SnapshotSaver) SetAfterSave(fn func(filePath string) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.afterSave = fn
+}
+
+// SetObserver задаёт функцию, получающую длительность и ошибку каждой записи снимка
+// (например, для собственных метрик сервера). Пропущенные без изменений сохранения не учитываются.
+func (s *SnapshotSaver) SetObserver(fn func(d time.Duration, err error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observer = fn
+}
+
+// Save сохраняет метрики в файл, если хранилище изменилось с момента последнего сохранения.
+//
+// Первый вызов всегда выполняет запись. Если хранилище ведёт журнал упреждающей записи,
+// снимок сохраняется как контрольная точка журнала (см. SetCheckpoint).
+// Возвращает true, если запись была выполнена, и ошибку при неудаче записи
+// или действия, заданного SetAfterSave.
+func (s *SnapshotSaver) Save() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	gen := s.storage.Generation()
+	if s.saved && gen == s.savedGen {
+		return false, nil
+	}
+
+	save := func() error {
+		return saveMetricsToFile(s.storage, s.filePath, s.fsync)
+	}
+	start := time.Now()
+	var err error
+	if cp, ok := s.storage.(checkpointer); ok && !s.noCheckpoint {
+		err = cp.Checkpoint(save)
+	} else {
+		err = save()
+	}
+	if s.observer != nil {
+		s.observer(time.Since(start), err)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	s.savedGen = gen
+	s.saved = true
+	if s.afterSave != nil {
+		if err := s.afterSave(s.filePath); err != nil {
+			return true, fmt.Errorf("after save: %w", err)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSnapshotSaver_SkipsUnchanged проверяет, что SnapshotSaver пропускает запись,
+// если хранилище не изменялось с момента последнего сохранения.
+//
+// t — указатель на структуру теста.
+func TestSnapshotSaver_SkipsUnchanged(t *testing.T) {
+	s := NewMemStorage()
+	fpath := filepath.Join(t.TempDir(), "metrics.json")
+	saver := NewSnapshotSaver(s, fpath)
+
+	written, err := saver.Save()
+	require.NoError(t, err)
+	require.True(t, written, "first save must always write")
+
+	written, err = saver.Save()
+	require.NoError(t, err)
+	require.False(t, written, "unchanged storage must not be rewritten")
+
+	require.NoError(t, os.Remove(fpath))
+	written, err = saver.Save()
+	require.NoError(t, err)
+	require.False(t, written)
+	_, err = os.Stat(fpath)
+	require.True(t, os.IsNotExist(err))
+
+	s.SetGauge("g", 1)
+	written, err = saver.Save()
+	require.NoError(t, err)
+	require.True(t, written)
+	_, err = os.Stat(fpath)
+	require.NoError(t, err)
+
+	s.AddCounter("c", 1)
+	written, err = saver.Save()
+	require.NoError(t, err)
+	require.True(t, written)
+}
+
+// TestMemStorage_Generation проверяет, что поколение хранилища растёт при каждом изменении
+// и не меняется при чтении.
+//
+// t — указатель на структуру теста.
+func TestMemStorage_Generation(t *testing.T) {
+	s := NewMemStorage()
+	require.Equal(t, uint64(0), s.Generation())
+
+	s.SetGauge("g", 1)
+	s.AddCounter("c", 2)
+	require.Equal(t, uint64(2), s.Generation())
+
+	_, _ = s.GetGauge("g")
+	_, _ = s.GetCounter("c")
+	_ = s.GetAll()
+	require.Equal(t, uint64(2), s.Generation())
+}
+
+// TestSnapshotSaver_AfterSave проверяет, что действие после сохранения выполняется только при записи снимка.
+//
+// t — указатель на структуру теста.
+func TestSnapshotSaver_AfterSave(t *testing.T) {
+	storage := NewMemStorage()
+	fpath := filepath.Join(t.TempDir(), "metrics.json")
+	saver := NewSnapshotSaver(storage, fpath)
+
+	var calls []string
+	saver.SetAfterSave(func(path string) error {
+		calls = append(calls, path)
+		return nil
+	})
+
+	_, err := saver.Save()
+	require.NoError(t, err)
+	_, err = saver.Save()
+	require.NoError(t, err)
+	require.Equal(t, []string{fpath}, calls)
+
+	saver.SetAfterSave(func(string) error { return errors.New("upload failed") })
+	storage.SetGauge("g", 1)
+	saved, err := saver.Save()
+	require.True(t, saved)
+	require.ErrorContains(t, err, "upload failed")
+}
+
+// TestSnapshotSaver_Observer проверяет, что наблюдатель получает только выполненные записи снимка
+// и их ошибки.
+//
+// t — указатель на структуру теста.
+func TestSnapshotSaver_Observer(t *testing.T) {
+	storage := NewMemStorage()
+	dir := t.TempDir()
+	saver := NewSnapshotSaver(storage, filepath.Join(dir, "metrics.json"))
+
+	var errs []error
+	saver.SetObserver(func(d time.Duration, err error) {
+		require.GreaterOrEqual(t, d, time.Duration(0))
+		errs = append(errs, err)
+	})
+
+	_, err := saver.Save()
+	require.NoError(t, err)
+	_, err = saver.Save()
+	require.NoError(t, err)
+	require.Equal(t, []error{nil}, errs)
+
+	saver = NewSnapshotSaver(storage, filepath.Join(dir, "missing", "metrics.json"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "missing"), nil, 0644))
+	errs = nil
+	saver.SetObserver(func(_ time.Duration, err error) { errs = append(errs, err) })
+	_, err = saver.Save()
+	require.Error(t, err)
+	require.Len(t, errs, 1)
+	require.Equal(t, err, errs[0])
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema — таблица метрик SQLite; повторяет схему миграции PostgreSQL.
+const sqliteSchema = `
+	CREATE TABLE IF NOT EXISTS metrics (
+		id TEXT PRIMARY KEY,
+		type TEXT NOT NULL,
+		delta INTEGER,
+		value REAL
+	)`
+
+// openSQLiteStorage создаёт in-memory хранилище с синхронизацией в файл SQLite.
+//
+// DSN имеет вид sqlite:///абсолютный/путь.db или sqlite://относительный/путь.db.
+// Таблица создаётся при открытии, сохранённые метрики загружаются в память.
+func openSQLiteStorage(ctx context.Context, dsn string, opts StorageOptions) (*StorageBackend, error) {
+	db, err := openSQLiteDB(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	storage := NewStorage(opts.Shards)
+	if err := loadFromSQLite(ctx, storage, db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &StorageBackend{
+		Storage:   storage,
+		Sink:      SQLiteSink(storage, db),
+		Preloaded: true,
+		close:     func() { _ = db.Close() },
+	}, nil
+}
+
+// openSQLiteDB открывает файл SQLite по DSN и создаёт таблицу метрик.
+func openSQLiteDB(ctx context.Context, dsn string) (*sql.DB, error) {
+	path := dsn[strings.Index(dsn, "://")+len("://"):]
+	if path == "" {
+		return nil, errors.New("invalid sqlite DSN: empty path")
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+	// SQLite допускает одного писателя, параллельные транзакции завершались бы ошибкой SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+	return db, nil
+}
+
+// sqliteSink — Sink, выгружающий метрики в таблицу metrics SQLite.
+type sqliteSink struct {
+	storage Storage
+	db      *sql.DB
+}
+
+// SQLiteSink возвращает Sink, выгружающий все метрики storage в таблицу metrics SQLite одной транзакцией.
+func SQLiteSink(storage Storage, db *sql.DB) Sink {
+	return sqliteSink{storage: storage, db: db}
+}
+
+// Sync выгружает все метрики в таблицу metrics.
+func (s sqliteSink) Sync(ctx context.Context) error {
+	metrics := s.storage.GetAll()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt := `
+		INSERT INTO metrics (id, type, delta, value)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE
+		SET type = excluded.type,
+			delta = excluded.delta,
+			value = excluded.value
+	`
+	for _, m := range metrics {
+		switch m.Type {
+		case "gauge":
+			val, _ := strconv.ParseFloat(m.Value, 64)
+			if _, err := tx.ExecContext(ctx, stmt, m.Name, "gauge", nil, val); err != nil {
+				return fmt.Errorf("failed to insert gauge %s: %w", m.Name, err)
+			}
+		case "counter":
+			delta, err := ParseCounter(m.Value)
+			if err != nil {
+				return fmt.Errorf("counter %s: %w", m.Name, err)
+			}
+			if _, err := tx.ExecContext(ctx, stmt, m.Name, "counter", delta, nil); err != nil {
+				return fmt.Errorf("failed to insert counter %s: %w", m.Name, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Delete удаляет метрики из таблицы metrics одной транзакцией.
+func (s sqliteSink) Delete(ctx context.Context, metrics []MetricInfo) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, m := range metrics {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM metrics WHERE id = ? AND type = ?`, m.Name, m.Type); err != nil {
+			return fmt.Errorf("failed to delete %s %s: %w", m.Type, m.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// loadFromSQLite загружает метрики из таблицы metrics SQLite в storage.
+func loadFromSQLite(ctx context.Context, storage Storage, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `SELECT id, type, delta, value FROM metrics`)
+	if err != nil {
+		return fmt.Errorf("failed to load metrics from sqlite: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var updates []MetricUpdate
+	for rows.Next() {
+		var (
+			u     MetricUpdate
+			delta sql.NullInt64
+			value sql.NullFloat64
+		)
+		if err := rows.Scan(&u.Name, &u.Type, &delta, &value); err != nil {
+			return fmt.Errorf("failed to scan metric: %w", err)
+		}
+		if delta.Valid {
+			u.IntVal = &delta.Int64
+		}
+		if value.Valid {
+			u.FloatVal = &value.Float64
+		}
+		updates = append(updates, u)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load metrics from sqlite: %w", err)
+	}
+	UpdateBatch(storage, updates)
+	return nil
+}
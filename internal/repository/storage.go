@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Storage определяет интерфейс для работы с хранилищем метрик.
@@ -18,16 +21,46 @@ type Storage interface {
 	// GetCounter возвращает значение counter-метрики по имени и флаг наличия.
 	GetCounter(name string) (int64, bool)
 	// GetAll возвращает срез всех метрик в виде MetricInfo.
+	// Метрики упорядочены по имени, а при совпадении имён — по типу (см. SortMetricInfo).
 	GetAll() []MetricInfo
+	// Generation возвращает номер поколения хранилища, который увеличивается при каждом изменении.
+	Generation() uint64
+	// Changes возвращает метрики, изменённые или удалённые (MetricInfo.Deleted) после поколения gen
+	// и позже момента since (нулевое время не ограничивает выборку), в порядке SortMetricInfo.
+	Changes(gen uint64, since time.Time) []MetricInfo
+	// UpdatedAt возвращает время последнего изменения метрики по типу и имени и флаг наличия.
+	UpdatedAt(metricType, name string) (time.Time, bool)
+	// Expire удаляет метрику по типу и имени, если она не изменялась позже момента before.
+	// Возвращает true, если метрика удалена.
+	Expire(metricType, name string, before time.Time) bool
 }
 
 // MemStorage реализует интерфейс Storage на основе памяти.
 //
 // Использует map для хранения gauge и counter, защищённых мьютексом.
 type MemStorage struct {
-	gauge   map[string]float64 // Хранилище gauge-метрик
-	counter map[string]int64   // Хранилище counter-метрик
-	mu      sync.RWMutex       // Мьютекс для конкурентного доступа
+	gauge          map[string]float64 // Хранилище gauge-метрик
+	counter        map[string]int64   // Хранилище counter-метрик
+	gaugeChanges   map[string]change  // Последние изменения gauge-метрик
+	counterChanges map[string]change  // Последние изменения counter-метрик
+	gen            atomic.Uint64      // Счётчик изменений (поколение) хранилища
+	mu             sync.RWMutex       // Мьютекс для конкурентного доступа
+}
+
+// change описывает последнее изменение метрики: поколение хранилища и время.
+//
+// Удалённая метрика (см. Expire) остаётся в таблице изменений с признаком deleted, чтобы
+// Changes сообщил об удалении клиентам, знающим более раннее поколение. Запись удаляется
+// следующим обновлением метрики.
+type change struct {
+	gen     uint64
+	at      time.Time
+	deleted bool
+}
+
+// after сообщает, произошло ли изменение после поколения gen и позже момента since.
+func (c change) after(gen uint64, since time.Time) bool {
+	return c.gen > gen && (since.IsZero() || c.at.After(since))
 }
 
 // MetricInfo содержит информацию о метрике для сериализации/вывода.
@@ -35,10 +68,12 @@ type MemStorage struct {
 // Name — имя метрики.
 // Type — тип метрики ("gauge" или "counter").
 // Value — строковое представление значения.
+// Deleted — метрика удалена (только в результате Changes, Value пусто).
 type MetricInfo struct {
-	Name  string
-	Type  string
-	Value string
+	Name    string
+	Type    string
+	Value   string
+	Deleted bool
 }
 
 // MetricUpdate описывает обновление метрики.
@@ -59,8 +94,10 @@ type MetricUpdate struct {
 // Возвращает Storage с пустыми map для gauge и counter.
 func NewMemStorage() Storage {
 	return &MemStorage{
-		gauge:   make(map[string]float64),
-		counter: make(map[string]int64),
+		gauge:          make(map[string]float64),
+		counter:        make(map[string]int64),
+		gaugeChanges:   make(map[string]change),
+		counterChanges: make(map[string]change),
 	}
 }
 
@@ -72,6 +109,7 @@ func (s *MemStorage) SetGauge(name string, value float64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.gauge[name] = value
+	s.gaugeChanges[name] = change{gen: s.gen.Add(1), at: time.Now()}
 }
 
 // AddCounter увеличивает значение counter-метрики по имени на delta.
@@ -82,6 +120,7 @@ func (s *MemStorage) AddCounter(name string, delta int64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.counter[name] += delta
+	s.counterChanges[name] = change{gen: s.gen.Add(1), at: time.Now()}
 }
 
 // GetGauge возвращает значение gauge-метрики по имени и флаг наличия.
@@ -108,12 +147,13 @@ func (s *MemStorage) GetCounter(name string) (int64, bool) {
 
 // GetAll возвращает срез всех метрик в виде MetricInfo.
 //
-// Формирует список из всех gauge и counter метрик с их значениями.
+// Формирует список из всех gauge и counter метрик с их значениями,
+// упорядоченный по имени, а затем по типу.
 func (s *MemStorage) GetAll() []MetricInfo {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var result []MetricInfo
+	result := make([]MetricInfo, 0, len(s.gauge)+len(s.counter))
 	for k, v := range s.gauge {
 		result = append(result, MetricInfo{
 			Name:  k,
@@ -128,5 +168,133 @@ func (s *MemStorage) GetAll() []MetricInfo {
 			Value: strconv.FormatInt(v, 10),
 		})
 	}
+	SortMetricInfo(result)
+	return result
+}
+
+// SortMetricInfo упорядочивает метрики по имени, а при совпадении имён — по типу.
+//
+// Определяет стабильный порядок, в котором GetAll, снимки и API возвращают метрики,
+// чтобы результаты разных запусков можно было сравнивать построчно.
+func SortMetricInfo(metrics []MetricInfo) {
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Name != metrics[j].Name {
+			return metrics[i].Name < metrics[j].Name
+		}
+		return metrics[i].Type < metrics[j].Type
+	})
+}
+
+// Generation возвращает текущее поколение хранилища.
+//
+// Значение увеличивается при каждом вызове SetGauge или AddCounter,
+// что позволяет определить, изменилось ли хранилище с момента последнего сохранения.
+func (s *MemStorage) Generation() uint64 {
+	return s.gen.Load()
+}
+
+// Changes возвращает метрики, изменённые после поколения gen и позже момента since,
+// и удалённые за это время метрики (см. Expire).
+//
+// gen — поколение хранилища, известное клиенту (0 — все метрики).
+// since — момент времени, известный клиенту (нулевое время не ограничивает выборку).
+func (s *MemStorage) Changes(gen uint64, since time.Time) []MetricInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := appendChanges(nil, s.gauge, s.counter, s.gaugeChanges, s.counterChanges, gen, since)
+	SortMetricInfo(result)
+	return result
+}
+
+// UpdatedAt возвращает время последнего изменения метрики.
+//
+// metricType — тип метрики ("gauge" или "counter"), name — имя метрики.
+// Возвращает время и true, если метрика найдена.
+func (s *MemStorage) UpdatedAt(metricType, name string) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return lastChange(s.gaugeChanges, s.counterChanges, metricType, name)
+}
+
+// Expire удаляет метрику, если она не изменялась позже момента before.
+//
+// Проверка и удаление выполняются под одной блокировкой, поэтому метрика, обновлённая
+// после выбора кандидатов на удаление, не теряется. Удаление увеличивает поколение хранилища
+// и возвращается Changes как MetricInfo с признаком Deleted.
+//
+// metricType — тип метрики ("gauge" или "counter"), name — имя метрики.
+// Возвращает true, если метрика удалена.
+func (s *MemStorage) Expire(metricType, name string, before time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return expireChange(s.gauge, s.counter, s.gaugeChanges, s.counterChanges, metricType, name, before, &s.gen)
+}
+
+// lastChange возвращает время последнего изменения метрики из таблиц изменений gauge и counter.
+// Удалённые метрики не учитываются.
+func lastChange(gauges, counters map[string]change, metricType, name string) (time.Time, bool) {
+	var (
+		c  change
+		ok bool
+	)
+	switch metricType {
+	case "gauge":
+		c, ok = gauges[name]
+	case "counter":
+		c, ok = counters[name]
+	}
+	return c.at, ok && !c.deleted
+}
+
+// expireChange удаляет метрику из таблицы значений, если её последнее изменение не позже
+// момента before, и отмечает удаление в таблице изменений новым поколением из gen.
+// Возвращает true, если метрика удалена.
+func expireChange(gauge map[string]float64, counter map[string]int64, gauges, counters map[string]change, metricType, name string, before time.Time, gen *atomic.Uint64) bool {
+	at, ok := lastChange(gauges, counters, metricType, name)
+	if !ok || at.After(before) {
+		return false
+	}
+	tombstone := change{gen: gen.Add(1), at: time.Now(), deleted: true}
+	switch metricType {
+	case "gauge":
+		delete(gauge, name)
+		gauges[name] = tombstone
+	case "counter":
+		delete(counter, name)
+		counters[name] = tombstone
+	}
+	return true
+}
+
+// appendChanges добавляет к result метрики из таблиц значений, изменённые после поколения gen
+// и позже момента since, и удалённые за это время метрики с признаком Deleted.
+//
+// При выборке всех метрик (gen 0 и нулевое since) удаления не возвращаются: клиенту, у которого
+// ещё нет метрик, удалять нечего.
+func appendChanges(result []MetricInfo, gauge map[string]float64, counter map[string]int64, gauges, counters map[string]change, gen uint64, since time.Time) []MetricInfo {
+	full := gen == 0 && since.IsZero()
+	for k, c := range gauges {
+		switch {
+		case !c.after(gen, since):
+		case c.deleted:
+			if !full {
+				result = append(result, MetricInfo{Name: k, Type: "gauge", Deleted: true})
+			}
+		default:
+			result = append(result, MetricInfo{Name: k, Type: "gauge", Value: strconv.FormatFloat(gauge[k], 'f', -1, 64)})
+		}
+	}
+	for k, c := range counters {
+		switch {
+		case !c.after(gen, since):
+		case c.deleted:
+			if !full {
+				result = append(result, MetricInfo{Name: k, Type: "counter", Deleted: true})
+			}
+		default:
+			result = append(result, MetricInfo{Name: k, Type: "counter", Value: strconv.FormatInt(counter[k], 10)})
+		}
+	}
 	return result
 }
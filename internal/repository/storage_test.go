@@ -2,6 +2,7 @@ package repository
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -71,16 +72,218 @@ func TestMemStorage_TableDriven(t *testing.T) {
 		},
 	}
 
-	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
-			s := NewMemStorage()
-			if tt.setup != nil {
-				tt.setup(s)
-			}
-			if tt.check != nil {
-				tt.check(t, s)
+	impls := []struct {
+		name string         // Название реализации
+		new  func() Storage // Конструктор хранилища
+	}{
+		{"mem", NewMemStorage},
+		{"sharded", func() Storage { return NewShardedMemStorage(4) }},
+	}
+
+	for _, impl := range impls {
+		for _, tt := range tests {
+			tt := tt
+			t.Run(impl.name+"/"+tt.name, func(t *testing.T) {
+				s := impl.new()
+				if tt.setup != nil {
+					tt.setup(s)
+				}
+				if tt.check != nil {
+					tt.check(t, s)
+				}
+			})
+		}
+	}
+}
+
+// TestMemStorage_GetAllOrder проверяет, что GetAll возвращает метрики упорядоченными
+// по имени, а при совпадении имён — по типу.
+//
+// t — указатель на структуру теста.
+func TestMemStorage_GetAllOrder(t *testing.T) {
+	for _, s := range []Storage{NewMemStorage(), NewShardedMemStorage(4)} {
+		s.SetGauge("b", 1)
+		s.AddCounter("a", 1)
+		s.SetGauge("a", 1)
+		s.AddCounter("c", 1)
+
+		var got []string
+		for _, m := range s.GetAll() {
+			got = append(got, m.Name+"/"+m.Type)
+		}
+		require.Equal(t, []string{"a/counter", "a/gauge", "b/gauge", "c/counter"}, got)
+		require.Equal(t, uint64(4), s.Generation())
+	}
+}
+
+// TestUpdateBatch проверяет пакетное обновление для MemStorage и для хранилищ без поддержки пакетов.
+//
+// t — указатель на структуру теста.
+func TestUpdateBatch(t *testing.T) {
+	g, c := 1.5, int64(2)
+	updates := []MetricUpdate{
+		{Type: "gauge", Name: "g", FloatVal: &g},
+		{Type: "counter", Name: "c", IntVal: &c},
+		{Type: "counter", Name: "c", IntVal: &c},
+		{Type: "gauge", Name: "empty"},
+	}
+	for name, s := range map[string]Storage{
+		"mem":     NewMemStorage(),
+		"sharded": NewShardedMemStorage(4),
+	} {
+		t.Run(name, func(t *testing.T) {
+			UpdateBatch(s, updates)
+			v, ok := s.GetGauge("g")
+			require.True(t, ok)
+			require.Equal(t, g, v)
+			d, ok := s.GetCounter("c")
+			require.True(t, ok)
+			require.Equal(t, int64(4), d)
+			_, ok = s.GetGauge("empty")
+			require.False(t, ok)
+			require.NotZero(t, s.Generation())
+		})
+	}
+}
+
+// TestStorage_Changes проверяет выборку изменений по поколению и времени для всех реализаций в памяти.
+//
+// t — указатель на структуру теста.
+func TestStorage_Changes(t *testing.T) {
+	for name, s := range map[string]Storage{
+		"mem":     NewMemStorage(),
+		"sharded": NewShardedMemStorage(4),
+	} {
+		t.Run(name, func(t *testing.T) {
+			s.SetGauge("a", 1)
+			s.AddCounter("b", 1)
+			gen := s.Generation()
+			time.Sleep(2 * time.Millisecond)
+			since := time.Now()
+
+			s.SetGauge("c", 2)
+			g := 3.0
+			UpdateBatch(s, []MetricUpdate{{Type: "gauge", Name: "a", FloatVal: &g}})
+
+			names := func(metrics []MetricInfo) []string {
+				var out []string
+				for _, m := range metrics {
+					out = append(out, m.Name+"/"+m.Type+"="+m.Value)
+				}
+				return out
 			}
+			require.Equal(t, []string{"a/gauge=3", "b/counter=1", "c/gauge=2"}, names(s.Changes(0, time.Time{})))
+			require.Equal(t, []string{"a/gauge=3", "c/gauge=2"}, names(s.Changes(gen, time.Time{})))
+			require.Equal(t, []string{"a/gauge=3", "c/gauge=2"}, names(s.Changes(0, since)))
+			require.Equal(t, []string{"a/gauge=3"}, names(s.Changes(gen+1, time.Time{})))
+			require.Empty(t, s.Changes(s.Generation(), time.Time{}))
+		})
+	}
+}
+
+// TestStorage_ChangesDeleted проверяет, что удалённые метрики возвращаются Changes с признаком Deleted
+// клиентам, знающим более раннее поколение, и перестают считаться удалёнными после обновления.
+//
+// t — указатель на структуру теста.
+func TestStorage_ChangesDeleted(t *testing.T) {
+	for name, s := range map[string]Storage{
+		"mem":     NewMemStorage(),
+		"sharded": NewShardedMemStorage(4),
+	} {
+		t.Run(name, func(t *testing.T) {
+			s.SetGauge("a", 1)
+			s.AddCounter("b", 1)
+			gen := s.Generation()
+
+			require.True(t, s.Expire("gauge", "a", time.Now()))
+			require.Greater(t, s.Generation(), gen)
+			_, ok := s.UpdatedAt("gauge", "a")
+			require.False(t, ok)
+			require.False(t, s.Expire("gauge", "a", time.Now()))
+
+			require.Equal(t, []MetricInfo{{Name: "a", Type: "gauge", Deleted: true}}, s.Changes(gen, time.Time{}))
+			require.Equal(t, []MetricInfo{{Name: "b", Type: "counter", Value: "1"}}, s.Changes(0, time.Time{}))
+			require.Empty(t, s.Changes(s.Generation(), time.Time{}))
+
+			s.SetGauge("a", 2)
+			require.Equal(t, []MetricInfo{{Name: "a", Type: "gauge", Value: "2"}}, s.Changes(gen, time.Time{}))
+		})
+	}
+}
+
+// TestStorage_UpdatedAt проверяет время последнего изменения метрики для всех реализаций в памяти.
+//
+// t — указатель на структуру теста.
+func TestStorage_UpdatedAt(t *testing.T) {
+	for name, s := range map[string]Storage{
+		"mem":         NewMemStorage(),
+		"sharded":     NewShardedMemStorage(4),
+		"normalizing": NewNormalizingStorage(NewMemStorage()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			before := time.Now()
+			s.SetGauge("a", 1)
+			s.AddCounter("b", 1)
+			time.Sleep(2 * time.Millisecond)
+			mid := time.Now()
+			s.AddCounter("b", 1)
+
+			at, ok := s.UpdatedAt("gauge", "a")
+			require.True(t, ok)
+			require.False(t, at.Before(before))
+			require.True(t, at.Before(mid))
+
+			at, ok = s.UpdatedAt("counter", "b")
+			require.True(t, ok)
+			require.False(t, at.Before(mid))
+
+			_, ok = s.UpdatedAt("counter", "a")
+			require.False(t, ok)
+			_, ok = s.UpdatedAt("histogram", "a")
+			require.False(t, ok)
+		})
+	}
+}
+
+// TestStorage_Expire проверяет удаление метрик, не изменявшихся после заданного момента,
+// и сохранение метрик, обновлённых позже него.
+//
+// t — указатель на структуру теста.
+func TestStorage_Expire(t *testing.T) {
+	for name, s := range map[string]Storage{
+		"mem":         NewMemStorage(),
+		"sharded":     NewShardedMemStorage(4),
+		"normalizing": NewNormalizingStorage(NewMemStorage()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			s.SetGauge("a", 1)
+			s.AddCounter("a", 1)
+			time.Sleep(2 * time.Millisecond)
+			cutoff := time.Now()
+			s.SetGauge("b", 2)
+			gen := s.Generation()
+
+			require.False(t, s.Expire("gauge", "b", cutoff))
+			require.False(t, s.Expire("gauge", "missing", cutoff))
+			require.Equal(t, gen, s.Generation())
+
+			require.True(t, s.Expire("gauge", "a", cutoff))
+			require.Greater(t, s.Generation(), gen)
+			_, ok := s.GetGauge("a")
+			require.False(t, ok)
+			_, ok = s.UpdatedAt("gauge", "a")
+			require.False(t, ok)
+
+			c, ok := s.GetCounter("a")
+			require.True(t, ok)
+			require.Equal(t, int64(1), c)
+			require.Len(t, s.GetAll(), 2)
+
+			// Удалённая метрика создаётся заново при следующем обновлении.
+			s.SetGauge("a", 3)
+			v, ok := s.GetGauge("a")
+			require.True(t, ok)
+			require.Equal(t, 3.0, v)
 		})
 	}
 }
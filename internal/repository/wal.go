@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// walMaxLineSize — максимальный размер одной записи журнала упреждающей записи.
+const walMaxLineSize = 1 << 20
+
+// walExpiry — запись журнала об удалении устаревшей метрики (см. Expire).
+//
+// Обычные записи журнала — это models.Metrics со значением или приращением; запись
+// об удалении не содержит значения и отличается полем expired.
+type walExpiry struct {
+	ID      string `json:"id"`
+	MType   string `json:"type"`
+	Expired bool   `json:"expired"`
+}
+
+// WALStorage — декоратор Storage, записывающий каждое изменение в журнал упреждающей записи (WAL).
+//
+// Журнал хранит обновления, полученные после последнего снимка. При перезапуске
+// сервер загружает снимок и применяет к нему журнал (ReplayWAL), поэтому обновления
+// между периодическими сохранениями не теряются при аварийном завершении.
+// После успешного сохранения снимка журнал очищается (Checkpoint).
+//
+// Поля:
+//   - Storage: исходное хранилище
+//   - file: открытый файл журнала
+//   - pending: количество записей журнала после последней контрольной точки
+//   - mu: мьютекс, упорядочивающий запись в журнал и контрольные точки
+type WALStorage struct {
+	Storage
+	file    *os.File
+	pending atomic.Int64
+	mu      sync.Mutex
+}
+
+// OpenWAL открывает (или создаёт) журнал filePath для дозаписи и оборачивает им хранилище storage.
+//
+// storage — исходное хранилище.
+// filePath — путь к файлу журнала.
+//
+// Возвращает указатель на WALStorage или ошибку открытия файла.
+func OpenWAL(storage Storage, filePath string) (*WALStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create WAL directory: %w", err)
+	}
+	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL: %w", err)
+	}
+	return &WALStorage{Storage: storage, file: f}, nil
+}
+
+// SetGauge записывает обновление gauge-метрики в журнал и применяет его к хранилищу.
+func (w *WALStorage) SetGauge(name string, value float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.append(models.Metrics{ID: name, MType: models.Gauge, Value: &value})
+	w.Storage.SetGauge(name, value)
+}
+
+// AddCounter записывает обновление counter-метрики в журнал и применяет его к хранилищу.
+func (w *WALStorage) AddCounter(name string, delta int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.append(models.Metrics{ID: name, MType: models.Counter, Delta: &delta})
+	w.Storage.AddCounter(name, delta)
+}
+
+// Expire удаляет метрику, если она не изменялась позже момента before, и записывает удаление в журнал.
+//
+// Удаление записывается только если оно произошло, чтобы при восстановлении из журнала
+// метрика, обновлённая после выбора кандидатов на удаление, не была потеряна.
+func (w *WALStorage) Expire(metricType, name string, before time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.Storage.Expire(metricType, name, before) {
+		return false
+	}
+	w.append(walExpiry{ID: name, MType: metricType, Expired: true})
+	return true
+}
+
+// append дописывает запись в журнал. Ошибки записи логируются: хранилище продолжает работу без WAL.
+func (w *WALStorage) append(m any) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		log.Printf("Failed to encode WAL record: %v", err)
+		return
+	}
+	if _, err := w.file.Write(append(data, '\n')); err != nil {
+		log.Printf("Failed to write WAL record: %v", err)
+		return
+	}
+	w.pending.Add(1)
+}
+
+// Checkpoint выполняет save при заблокированной записи и очищает журнал в случае успеха.
+//
+// Пока выполняется save, обновления хранилища ожидают, поэтому снимок и очищенный журнал
+// согласованы: всё, что не попало в снимок, будет записано в журнал после очистки.
+//
+// save — функция сохранения снимка.
+//
+// Возвращает ошибку save или ошибку очистки журнала.
+func (w *WALStorage) Checkpoint(save func() error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := save(); err != nil {
+		return err
+	}
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate WAL: %w", err)
+	}
+	w.pending.Store(0)
+	return nil
+}
+
+// Pending возвращает количество записей журнала, ещё не вошедших в снимок.
+//
+// Записи, оставшиеся в файле журнала с прошлого запуска, не учитываются.
+func (w *WALStorage) Pending() int64 {
+	return w.pending.Load()
+}
+
+// Close закрывает файл журнала.
+func (w *WALStorage) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// ReplayWAL применяет записи журнала filePath к хранилищу storage.
+//
+// Записи об удалении устаревших метрик удаляют метрику независимо от времени её изменения:
+// все предшествующие обновления уже применены. Повреждённые записи (например, недописанная
+// последняя строка после сбоя) пропускаются с записью в лог.
+// Отсутствие файла журнала не считается ошибкой.
+//
+// storage — хранилище, к которому применяются записи.
+// filePath — путь к файлу журнала.
+//
+// Возвращает число применённых записей и ошибку чтения файла.
+func ReplayWAL(storage Storage, filePath string) (int, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), walMaxLineSize)
+
+	applied := 0
+	line := 0
+	for scanner.Scan() {
+		line++
+		var m models.Metrics
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			log.Printf("Skipping corrupted WAL record at line %d: %v", line, err)
+			continue
+		}
+		switch {
+		case m.MType == models.Gauge && m.Value != nil:
+			storage.SetGauge(m.ID, *m.Value)
+		case m.MType == models.Counter && m.Delta != nil:
+			storage.AddCounter(m.ID, *m.Delta)
+		case isExpiry(scanner.Bytes()):
+			storage.Expire(m.MType, m.ID, time.Now())
+		default:
+			log.Printf("Skipping invalid WAL record at line %d", line)
+			continue
+		}
+		applied++
+	}
+	return applied, scanner.Err()
+}
+
+// isExpiry сообщает, является ли запись журнала data записью об удалении метрики.
+func isExpiry(data []byte) bool {
+	var e walExpiry
+	return json.Unmarshal(data, &e) == nil && e.Expired
+}
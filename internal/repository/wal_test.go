@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestWAL_ReplayAfterCrash проверяет, что обновления, полученные после последнего снимка,
+// восстанавливаются из журнала при повторном запуске.
+//
+// t — указатель на структуру теста.
+func TestWAL_ReplayAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := filepath.Join(dir, "metrics.json")
+	walPath := filepath.Join(dir, "metrics.wal")
+
+	wal, err := OpenWAL(NewMemStorage(), walPath)
+	require.NoError(t, err)
+	saver := NewSnapshotSaver(wal, snapshot)
+
+	wal.SetGauge("g", 1)
+	wal.AddCounter("c", 10)
+	_, err = saver.Save()
+	require.NoError(t, err)
+
+	info, err := os.Stat(walPath)
+	require.NoError(t, err)
+	require.Zero(t, info.Size(), "WAL must be truncated after checkpoint")
+
+	// Обновления после снимка, затем «сбой» без сохранения.
+	wal.SetGauge("g", 2)
+	wal.AddCounter("c", 5)
+	wal.AddCounter("c2", 1)
+	require.NoError(t, wal.Close())
+
+	restored := NewMemStorage()
+	require.NoError(t, LoadMetricsFromFile(restored, snapshot))
+	n, err := ReplayWAL(restored, walPath)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	g, ok := restored.GetGauge("g")
+	require.True(t, ok)
+	require.Equal(t, 2.0, g)
+	c, ok := restored.GetCounter("c")
+	require.True(t, ok)
+	require.Equal(t, int64(15), c)
+	c2, ok := restored.GetCounter("c2")
+	require.True(t, ok)
+	require.Equal(t, int64(1), c2)
+}
+
+// TestWAL_ExpireReplay проверяет, что удаление устаревшей метрики записывается в журнал
+// и не отменяется восстановлением после сбоя, а несостоявшееся удаление не записывается.
+//
+// t — указатель на структуру теста.
+func TestWAL_ExpireReplay(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "metrics.wal")
+	wal, err := OpenWAL(NewMemStorage(), walPath)
+	require.NoError(t, err)
+
+	wal.SetGauge("old", 1)
+	wal.SetGauge("fresh", 2)
+	require.True(t, wal.Expire("gauge", "old", time.Now()))
+	require.False(t, wal.Expire("gauge", "fresh", time.Now().Add(-time.Hour)))
+	require.NoError(t, wal.Close())
+
+	restored := NewMemStorage()
+	n, err := ReplayWAL(restored, walPath)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	_, ok := restored.GetGauge("old")
+	require.False(t, ok)
+	_, ok = restored.GetGauge("fresh")
+	require.True(t, ok)
+}
+
+// TestWAL_Pending проверяет подсчёт записей журнала, не вошедших в снимок.
+//
+// t — указатель на структуру теста.
+func TestWAL_Pending(t *testing.T) {
+	dir := t.TempDir()
+	wal, err := OpenWAL(NewMemStorage(), filepath.Join(dir, "metrics.wal"))
+	require.NoError(t, err)
+	defer func() { _ = wal.Close() }()
+
+	wal.SetGauge("g", 1)
+	wal.AddCounter("c", 2)
+	require.False(t, wal.Expire("gauge", "g", time.Time{}))
+	require.Equal(t, int64(2), wal.Pending())
+
+	saved, err := NewSnapshotSaver(wal, filepath.Join(dir, "metrics.json")).Save()
+	require.NoError(t, err)
+	require.True(t, saved)
+	require.Zero(t, wal.Pending())
+}
+
+// TestWAL_CopyWithoutCheckpoint проверяет, что сохранение копии снимка в другой файл
+// не очищает журнал, нужный для восстановления основного снимка.
+//
+// t — указатель на структуру теста.
+func TestWAL_CopyWithoutCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, "metrics.wal")
+
+	wal, err := OpenWAL(NewMemStorage(), walPath)
+	require.NoError(t, err)
+	defer func() { _ = wal.Close() }()
+	saver := NewSnapshotSaver(wal, filepath.Join(dir, "copy.json"))
+	saver.SetCheckpoint(false)
+
+	wal.SetGauge("g", 1)
+	saved, err := saver.Save()
+	require.NoError(t, err)
+	require.True(t, saved)
+
+	info, err := os.Stat(walPath)
+	require.NoError(t, err)
+	require.NotZero(t, info.Size(), "WAL must survive a non-checkpoint save")
+}
+
+// TestReplayWAL_TableDriven проверяет разбор журнала, включая отсутствующий файл
+// и повреждённые записи.
+//
+// t — указатель на структуру теста.
+func TestReplayWAL_TableDriven(t *testing.T) {
+	tests := []struct {
+		name    string // Название теста
+		content string // Содержимое журнала ("" — файл отсутствует)
+		applied int    // Ожидаемое число применённых записей
+		counter int64  // Ожидаемое значение счётчика c
+	}{
+		{"missing file", "", 0, 0},
+		{"valid records", "{\"id\":\"c\",\"type\":\"counter\",\"delta\":2}\n{\"id\":\"c\",\"type\":\"counter\",\"delta\":3}\n", 2, 5},
+		{"torn last record", "{\"id\":\"c\",\"type\":\"counter\",\"delta\":2}\n{\"id\":\"c\",\"ty", 1, 2},
+		{"invalid record skipped", "{\"id\":\"c\",\"type\":\"counter\"}\n{\"id\":\"c\",\"type\":\"counter\",\"delta\":4}\n", 1, 4},
+		{"max int64 delta", "{\"id\":\"c\",\"type\":\"counter\",\"delta\":9223372036854775807}\n", 1, 9223372036854775807},
+		{"delta out of range skipped", "{\"id\":\"c\",\"type\":\"counter\",\"delta\":9223372036854775808}\n", 0, 0},
+		{"expired counter removed", "{\"id\":\"c\",\"type\":\"counter\",\"delta\":2}\n{\"id\":\"c\",\"type\":\"counter\",\"expired\":true}\n", 2, 0},
+		{"counter recreated after expiry", "{\"id\":\"c\",\"type\":\"counter\",\"delta\":2}\n{\"id\":\"c\",\"type\":\"counter\",\"expired\":true}\n{\"id\":\"c\",\"type\":\"counter\",\"delta\":3}\n", 3, 3},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			walPath := filepath.Join(t.TempDir(), "metrics.wal")
+			if tt.content != "" {
+				require.NoError(t, os.WriteFile(walPath, []byte(tt.content), 0644))
+			}
+
+			s := NewMemStorage()
+			n, err := ReplayWAL(s, walPath)
+			require.NoError(t, err)
+			require.Equal(t, tt.applied, n)
+			c, _ := s.GetCounter("c")
+			require.Equal(t, tt.counter, c)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package requestid генерирует идентификаторы запросов и событий аудита и передаёт их
+// через контекст, заголовки HTTP и метаданные gRPC.
+//
+// Идентификатор создаётся один раз на запрос и попадает в ответ (заголовок X-Request-ID
+// и поле request_id ошибок), журнал запросов и события аудита. Формат выбирается
+// параметром -request-id-format: ULID или UUIDv7; оба упорядочены по времени создания.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Header — заголовок HTTP с идентификатором запроса.
+const Header = "X-Request-ID"
+
+// MetadataKey — ключ метаданных gRPC с идентификатором запроса.
+const MetadataKey = "x-request-id"
+
+// maxLen — максимальная длина идентификатора, принимаемого от клиента.
+const maxLen = 64
+
+// Форматы идентификаторов.
+const (
+	FormatULID   = "ulid"
+	FormatUUIDv7 = "uuidv7"
+)
+
+// Generator создаёт новый уникальный идентификатор.
+type Generator func() string
+
+// Parse возвращает генератор для формата FormatULID или FormatUUIDv7.
+func Parse(format string) (Generator, error) {
+	switch format {
+	case FormatULID:
+		return ULID, nil
+	case FormatUUIDv7:
+		return UUIDv7, nil
+	default:
+		return nil, fmt.Errorf("invalid request id format %q (want %q or %q)", format, FormatULID, FormatUUIDv7)
+	}
+}
+
+// crockford — алфавит Base32 Crockford, используемый в ULID.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID возвращает идентификатор ULID: 48 бит времени в миллисекундах и 80 случайных бит
+// в кодировке Base32 Crockford (26 символов).
+func ULID() string {
+	var b [16]byte
+	putTimestamp(b[:6])
+	_, _ = rand.Read(b[6:])
+
+	// 128 бит кодируются 26 символами по 5 бит, старший символ содержит 3 бита.
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// UUIDv7 возвращает идентификатор UUID версии 7 (RFC 9562): 48 бит времени в миллисекундах
+// и случайные биты, в каноническом текстовом виде.
+func UUIDv7() string {
+	var b [16]byte
+	putTimestamp(b[:6])
+	_, _ = rand.Read(b[6:])
+	b[6] = b[6]&0x0f | 0x70 // версия 7
+	b[8] = b[8]&0x3f | 0x80 // вариант RFC 9562
+
+	var out [36]byte
+	hex.Encode(out[0:8], b[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], b[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], b[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], b[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], b[10:])
+	return string(out[:])
+}
+
+// putTimestamp записывает текущее время в миллисекундах в 6 байт dst (big-endian).
+func putTimestamp(dst []byte) {
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		dst[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+// ctxKey — ключ контекста для идентификатора запроса.
+type ctxKey struct{}
+
+// NewContext возвращает контекст с идентификатором запроса id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext возвращает идентификатор запроса из контекста или пустую строку.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Valid сообщает, можно ли принять идентификатор id от клиента: непустой, не длиннее
+// 64 символов и состоит только из печатных символов ASCII без пробелов.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware присваивает каждому запросу идентификатор и возвращает его в заголовке Header.
+//
+// Корректный идентификатор из заголовка запроса сохраняется, чтобы связать записи клиента
+// и сервера; иначе создаётся новый генератором gen.
+func Middleware(gen Generator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(Header)
+			if !Valid(id) {
+				id = gen()
+			}
+			w.Header().Set(Header, id)
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerators проверяет формат, уникальность и упорядоченность по времени идентификаторов.
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		format  string         // Формат идентификатора
+		pattern *regexp.Regexp // Ожидаемый вид
+	}{
+		{format: FormatULID, pattern: regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)},
+		{format: FormatUUIDv7, pattern: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			gen, err := Parse(tc.format)
+			require.NoError(t, err)
+
+			seen := map[string]bool{}
+			for range 1000 {
+				id := gen()
+				require.Regexp(t, tc.pattern, id)
+				require.False(t, seen[id], "duplicate id %s", id)
+				seen[id] = true
+			}
+
+			first := gen()
+			time.Sleep(2 * time.Millisecond)
+			second := gen()
+			require.Less(t, strings.ToLower(first), strings.ToLower(second), "ids must sort by creation time")
+		})
+	}
+}
+
+// TestParse_Invalid проверяет ошибку для неизвестного формата.
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse("uuidv4")
+	require.Error(t, err)
+}
+
+// TestMiddleware проверяет, что идентификатор создаётся один раз на запрос, передаётся
+// обработчику через контекст и возвращается в заголовке ответа.
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string // Название теста
+		incoming string // Заголовок X-Request-ID запроса
+		want     string // Ожидаемый идентификатор (пусто — сгенерированный)
+	}{
+		{name: "Generated", want: "generated"},
+		{name: "Propagated", incoming: "agent-42", want: "agent-42"},
+		{name: "InvalidReplaced", incoming: "bad id\n", want: "generated"},
+		{name: "TooLongReplaced", incoming: strings.Repeat("a", 65), want: "generated"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var inHandler string
+			h := Middleware(func() string { return "generated" })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				inHandler = FromContext(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				req.Header.Set(Header, tc.incoming)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.want, inHandler)
+			require.Equal(t, tc.want, rec.Header().Get(Header))
+		})
+	}
+}
+
+// TestFromContext_Empty проверяет пустой идентификатор для контекста без него.
+func TestFromContext_Empty(t *testing.T) {
+	require.Empty(t, FromContext(context.Background()))
+	require.Equal(t, "id", FromContext(NewContext(context.Background(), "id")))
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Ожидание запросов при остановке сервера.
+const (
+	shutdownTimeout = 5 * time.Second // Ожидание завершения выполняющихся запросов
+	cancelGrace     = time.Second     // Ожидание запросов после отмены их контекста
+)
+
+// inflight отслеживает выполняющиеся HTTP-запросы всех слушателей сервера.
+//
+// Контексты запросов порождаются от общего базового контекста, поэтому при остановке
+// запросы, не завершившиеся за отведённое время, можно отменить все сразу.
+type inflight struct {
+	active   atomic.Int64       // Выполняющиеся запросы
+	drained  atomic.Int64       // Запросы, завершившиеся после начала остановки
+	draining atomic.Bool        // Остановка начата
+	base     context.Context    // Базовый контекст запросов
+	cancel   context.CancelFunc // Отмена контекстов всех запросов
+}
+
+// newInflight создаёт счётчик выполняющихся запросов.
+func newInflight() *inflight {
+	t := &inflight{}
+	t.base, t.cancel = context.WithCancel(context.Background())
+	return t
+}
+
+// track возвращает обработчик, учитывающий выполняющиеся запросы к next.
+func (t *inflight) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.active.Add(1)
+		defer func() {
+			t.active.Add(-1)
+			if t.draining.Load() {
+				t.drained.Add(1)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// baseContext возвращает базовый контекст запросов для http.Server.BaseContext.
+func (t *inflight) baseContext(net.Listener) context.Context {
+	return t.base
+}
+
+// waitIdle ждёт завершения всех запросов не дольше timeout.
+func (t *inflight) waitIdle(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for t.active.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// drain прекращает приём запросов и ждёт завершения выполняющихся HTTP-запросов и вызовов gRPC.
+//
+// Слушатели закрываются сразу. Запросы, не завершившиеся за timeout, получают отмену контекста
+// (например, прерывается запись в базу данных) и ещё cancelGrace на завершение, после чего
+// их соединения закрываются принудительно.
+//
+// Возвращает число дождавшихся и отменённых HTTP-запросов и ошибки остановки HTTP-серверов.
+func (s *Server) drain(timeout time.Duration) (drained, cancelled int64, err error) {
+	s.inflight.draining.Store(true)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(s.servers))
+	for i, srv := range s.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = srv.Shutdown(ctx)
+		}()
+	}
+	if s.grpcSrv != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stopped := make(chan struct{})
+			go func() {
+				s.grpcSrv.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				s.grpcSrv.Stop()
+			}
+		}()
+	}
+	wg.Wait()
+	drained = s.inflight.drained.Load()
+
+	if cancelled = s.inflight.active.Load(); cancelled > 0 {
+		s.inflight.cancel()
+		s.inflight.waitIdle(cancelGrace)
+		for _, srv := range s.servers {
+			_ = srv.Close()
+		}
+	}
+	return drained, cancelled, errors.Join(errs...)
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestServer_Drain проверяет, что остановка дожидается выполняющихся запросов,
+// а не завершившиеся вовремя запросы получают отмену контекста.
+//
+// t — указатель на структуру теста.
+func TestServer_Drain(t *testing.T) {
+	tests := []struct {
+		name          string        // Название теста
+		work          time.Duration // Длительность обработки запроса
+		wantDrained   int64         // Ожидаемое число дождавшихся запросов
+		wantCancelled int64         // Ожидаемое число отменённых запросов
+	}{
+		{name: "Drained", work: 100 * time.Millisecond, wantDrained: 1},
+		{name: "Cancelled", work: time.Minute, wantCancelled: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			started := make(chan struct{})
+			ctxErr := make(chan error, 1)
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-time.After(tc.work):
+				case <-r.Context().Done():
+				}
+				ctxErr <- r.Context().Err()
+			})
+
+			s := &Server{inflight: newInflight()}
+			require.NoError(t, s.listen("127.0.0.1:0", h, nil))
+			go func() { _ = s.servers[0].Serve(s.listeners[0]) }()
+			defer s.Close()
+
+			go func() {
+				resp, err := http.Get("http://" + s.listeners[0].Addr().String() + "/updates/")
+				if err == nil {
+					resp.Body.Close()
+				}
+			}()
+			<-started
+
+			drained, cancelled, _ := s.drain(300 * time.Millisecond)
+			require.Equal(t, tc.wantDrained, drained)
+			require.Equal(t, tc.wantCancelled, cancelled)
+			if tc.wantCancelled > 0 {
+				require.Error(t, <-ctxErr, "request context is cancelled")
+			} else {
+				require.NoError(t, <-ctxErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestServer_ReusePort проверяет, что с ReusePort второй процесс может занять уже
+// прослушиваемый порт, а без него — нет.
+//
+// t — указатель на структуру теста.
+func TestServer_ReusePort(t *testing.T) {
+	old := &Server{cfg: Config{ReusePort: true}}
+	ln, err := old.listenTCP("127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	addr := ln.Addr().String()
+
+	next := &Server{cfg: Config{ReusePort: true}}
+	ln2, err := next.listenTCP(addr)
+	require.NoError(t, err, "new process binds while the old one still listens")
+	require.NoError(t, ln2.Close())
+
+	plain := &Server{}
+	_, err = plain.listenTCP(addr)
+	require.Error(t, err)
+}
@@ -0,0 +1,969 @@
+// Package server собирает сервер метрик из внутренних пакетов: хранилище, HTTP- и gRPC-обработчики,
+// аудит, резервное копирование и административный слушатель.
+//
+// Используется бинарным файлом cmd/server, а также позволяет встроить сервер в другое
+// приложение или запустить его целиком в тестах:
+//
+//	srv, err := server.New(server.Config{Address: "localhost:0"})
+//	if err != nil { ... }
+//	go srv.Run(ctx)
+//	resp, err := http.Post("http://"+srv.Addr()+"/update/gauge/Alloc/1", "", nil)
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/RoGogDBD/metric-alerter/internal/backup"
+	"github.com/RoGogDBD/metric-alerter/internal/compression"
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/config/db"
+	"github.com/RoGogDBD/metric-alerter/internal/crypto"
+	"github.com/RoGogDBD/metric-alerter/internal/expiry"
+	"github.com/RoGogDBD/metric-alerter/internal/grpcserver"
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+	"github.com/RoGogDBD/metric-alerter/internal/proto"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/RoGogDBD/metric-alerter/internal/requestid"
+	"github.com/RoGogDBD/metric-alerter/internal/service"
+	"github.com/RoGogDBD/metric-alerter/internal/telemetry"
+	"github.com/RoGogDBD/metric-alerter/internal/tracing"
+	"github.com/RoGogDBD/metric-alerter/internal/watchdog"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// auditRetryBackoff — пауза перед первой повторной доставкой события аудита; далее растёт линейно.
+const auditRetryBackoff = 100 * time.Millisecond
+
+// Config — итоговая конфигурация сервера после применения флагов, окружения и JSON-конфига.
+type Config struct {
+	Address         string                       // Адрес HTTP-сервера, если Listeners не заданы.
+	Listeners       []config.ListenerConfig      // HTTP-слушатели с наборами маршрутов (пусто — один слушатель Address).
+	DatabaseDSN     string                       // DSN хранилища (пусто — хранилище в памяти).
+	StoreInterval   int                          // Интервал сохранения снимка метрик (сек, 0 — при каждом обновлении).
+	StoreFile       string                       // Файл снимка метрик (пусто — снимок не сохраняется).
+	Restore         bool                         // Восстанавливать метрики из снимка при запуске.
+	Key             string                       // Ключ проверки подписи запросов.
+	CryptoKey       string                       // Путь к закрытому ключу для расшифровки запросов.
+	VerifyKey       string                       // Путь к открытому ключу Ed25519 для проверки подписи агентов.
+	AuditFile       string                       // Файл журнала аудита.
+	AuditURL        string                       // URL удалённого сервера аудита.
+	TrustedSubnet   string                       // Доверенная подсеть агентов в формате CIDR.
+	GRPCAddress     string                       // Адрес gRPC-сервера (пусто — отключён).
+	SnapshotFsync   bool                         // Выполнять fsync снимка при каждом сохранении.
+	WALFile         string                       // Файл журнала упреждающей записи (пусто — отключён).
+	StorageShards   int                          // Число сегментов хранилища в памяти.
+	NormalizeIDs    bool                         // Нормализовать идентификаторы метрик при приёме.
+	EnrollTokens    string                       // Одноразовые токены регистрации агентов через запятую.
+	AgentsFile      string                       // Файл реестра зарегистрированных агентов.
+	AdminAddress    string                       // Адрес административного слушателя (пусто — /admin/* на основных слушателях).
+	AdminToken      string                       // Токен доступа к административному слушателю.
+	Watchdog        config.WatchdogConfig        // Сторожевой таймер утечек.
+	Security        config.SecurityHeadersConfig // Заголовки безопасности HTML-страниц.
+	Backup          config.BackupConfig          // Резервное копирование снимков по расписанию.
+	S3              config.S3Config              // Выгрузка снимков в S3-совместимое хранилище.
+	PageRefresh     config.PageRefreshConfig     // Автообновление страницы метрик.
+	Auth            config.AuthConfig            // Ролевой доступ по API-ключам и JWT.
+	Observers       []config.ObserverConfig      // Наблюдатели аудита из JSON-конфига.
+	RequestIDFormat string                       // Формат идентификаторов запросов и событий аудита: ulid или uuidv7 (пусто — ulid).
+	Storage         config.StorageConfig         // Хранилища для дублирования записей (пусто — PostgreSQL при DatabaseDSN и файл StoreFile).
+	TLS             config.TLSConfig             // Политика TLS слушателей (версия, наборы шифров, кривые).
+	TLSCert         string                       // Файл сертификата (PEM); вместе с TLSKey включает HTTPS и TLS gRPC на всех слушателях.
+	TLSKey          string                       // Файл закрытого ключа сертификата (PEM).
+	TLSRedirect     string                       // Адрес обычного HTTP-слушателя, перенаправляющего на HTTPS (пусто — отключён).
+	DataDir         string                       // Каталог, от которого отсчитываются относительные пути файлов сервера (пусто — рабочая директория).
+	LogDir          string                       // Каталог журнала (пусто — logs внутри DataDir).
+	LogFile         string                       // Журнал для диагностического архива (пусто — app.log в LogDir).
+	DeadLetterFile  string                       // Файл недоставленных событий аудита (пусто — такие события теряются).
+	AuditRetries    int                          // Число попыток доставки события аудита каждому наблюдателю (0 — одна).
+	Compression     config.CompressionConfig     // Сжатие ответов.
+	RateLimitRPS    int                          // Запросов в секунду на клиента (0 — без ограничения).
+	RateLimitBurst  int                          // Допустимый всплеск запросов клиента (0 — равен RateLimitRPS).
+	MaxBodySize     int                          // Максимальный размер тела запроса в байтах (0 — без ограничения).
+	MaxGzipRatio    int                          // Максимальная степень распаковки тела gzip (0 — без проверки).
+	StreamBatch     int                          // Размер порции потокового применения батчей (0 — батч разбирается целиком).
+	MetricTTL       config.MetricTTLConfig       // Срок хранения метрик без обновлений (0 — метрики не устаревают).
+	Tracing         config.TracingConfig         // Экспорт трассировки OpenTelemetry (пустой Endpoint — отключён).
+	ReusePort       bool                         // Открывать слушатели с SO_REUSEPORT для перезапуска без простоя.
+	H2C             bool                         // Принимать HTTP/2 без TLS (h2c) на обычных HTTP-слушателях.
+	AuditQueue      config.AuditQueueConfig      // Асинхронная доставка событий аудита (нулевой Size — синхронная).
+	AuditRotation   config.AuditRotationConfig   // Ротация файлов аудита без собственных параметров ротации (нулевой MaxSizeMB — без ротации).
+	AuditFilters    []config.AuditFilterRule     // Правила отбора событий аудита для наблюдателей (пусто — доставляются все).
+	Log             config.LoggerConfig          // Ротация журнала LogDir/app.log (если не задан Logger), фильтрация и выборка журнала запросов.
+	Logger          *zap.Logger                  // Логгер (nil — журнал в LogDir/app.log и stdout).
+}
+
+// Server — сервер метрик в сборе: хранилище, HTTP-, административный и gRPC-серверы.
+//
+// Создаётся через New и запускается методом Run.
+type Server struct {
+	Logger        *zap.Logger               // Логгер сервера.
+	storage       repository.Storage        // Хранилище метрик со всеми обёртками.
+	saver         *repository.SnapshotSaver // Сохранение снимков метрик.
+	fanOut        *repository.FanOut        // Хранилища, в которые дублируются записи.
+	uploader      *repository.S3Uploader    // Выгрузка снимков в S3 (nil — отключена).
+	handler       *handler.Handler          // HTTP-обработчики; сообщают готовность через /readyz.
+	cfg           Config                    // Конфигурация сервера.
+	tlsConfig     *tls.Config               // TLS слушателей (nil — обычный HTTP).
+	servers       []*http.Server            // HTTP-серверы, включая административный.
+	listeners     []net.Listener            // Открытые слушатели HTTP-серверов (в том же порядке).
+	listenerAddrs []string                  // Описания слушателей для журнала.
+	inflight      *inflight                 // Выполняющиеся HTTP-запросы всех слушателей.
+	grpcSrv       *grpc.Server              // gRPC-сервер (nil — отключён).
+	grpcListener  net.Listener              // Слушатель gRPC-сервера.
+	bgCancel      context.CancelFunc        // Остановка фоновых задач.
+	closers       []func() error            // Освобождение ресурсов в обратном порядке.
+	closeLog      bool                      // Логгер создан сервером и синхронизируется при закрытии.
+}
+
+// New создаёт сервер по конфигурации: открывает хранилище, восстанавливает метрики,
+// настраивает обработчики и открывает все слушатели.
+//
+// Если в адресе указан порт 0, фактический адрес можно узнать через Addr.
+// Возвращает ошибку, если конфигурация некорректна или ресурсы не удалось открыть;
+// в этом случае всё уже открытое закрывается.
+func New(cfg Config) (s *Server, err error) {
+	cfg = resolvePaths(cfg)
+	s = &Server{cfg: cfg, Logger: cfg.Logger, inflight: newInflight()}
+	defer func() {
+		if err != nil {
+			_ = s.Close()
+			s = nil
+		}
+	}()
+
+	// Уровень журнала можно изменить без перезапуска через /admin/runtime.
+	logLevel := config.NewLogLevel("info")
+	if cfg.DataDir != "" {
+		if err = os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+			return s, fmt.Errorf("failed to create data dir: %w", err)
+		}
+	}
+	if err = cfg.Log.Validate(); err != nil {
+		return s, err
+	}
+	if s.Logger == nil {
+		if s.Logger, err = config.InitializeInDir(cfg.LogDir, logLevel, cfg.Log); err != nil {
+			return s, err
+		}
+		s.closeLog = true
+	}
+
+	// Политика TLS проверяется при запуске, чтобы ошибка в именах шифров не всплыла при рукопожатии.
+	if s.tlsConfig, err = serverTLSConfig(cfg); err != nil {
+		return s, err
+	}
+	if s.tlsConfig == nil && !cfg.TLS.IsZero() {
+		log.Printf("Warning: TLS policy is set but no certificate is configured; listeners serve plain HTTP")
+	}
+
+	// Идентификаторы запросов попадают в ответы, журнал и события аудита.
+	requestIDFormat := cfg.RequestIDFormat
+	if requestIDFormat == "" {
+		requestIDFormat = config.DefaultRequestIDFormat
+	}
+	newRequestID, err := requestid.Parse(requestIDFormat)
+	if err != nil {
+		return s, err
+	}
+
+	// Трассировка OpenTelemetry: спаны запросов и обращений к PostgreSQL экспортируются по OTLP.
+	// Экспорт останавливается последним, чтобы отправить спаны остановки остальных ресурсов.
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, tracing.ServiceServer)
+	if err != nil {
+		return s, err
+	}
+	s.closers = append(s.closers, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return shutdownTracing(ctx)
+	})
+	if cfg.Tracing.Enabled() {
+		log.Printf("Tracing enabled: %s (sample ratio %g)", cfg.Tracing.Endpoint, cfg.Tracing.SampleRatio)
+	}
+
+	// Загрузка RSA ключа.
+	var privateKey *rsa.PrivateKey
+	if cfg.CryptoKey != "" {
+		if privateKey, err = crypto.LoadPrivateKey(cfg.CryptoKey); err != nil {
+			return s, fmt.Errorf("failed to load private key: %w", err)
+		}
+	}
+
+	// Загрузка ключа проверки подписи Ed25519.
+	var verifyKey ed25519.PublicKey
+	if cfg.VerifyKey != "" {
+		if verifyKey, err = crypto.LoadVerifyKey(cfg.VerifyKey); err != nil {
+			return s, fmt.Errorf("failed to load verify key: %w", err)
+		}
+	}
+
+	// Инициализация менеджера аудита: -audit-file и -audit-url дополняют список observers из JSON.
+	observers := append([]config.ObserverConfig(nil), cfg.Observers...)
+	if auditFile := cfg.AuditFile; auditFile != "" {
+		if !filepath.IsAbs(auditFile) {
+			if wd, err := os.Getwd(); err == nil {
+				auditFile = filepath.Join(wd, auditFile)
+			}
+		}
+		observers = append(observers, config.ObserverConfig{Type: "file", Options: map[string]string{"path": auditFile}})
+	}
+	if cfg.AuditURL != "" {
+		observers = append(observers, config.ObserverConfig{Type: "http", Options: map[string]string{"url": cfg.AuditURL}})
+	}
+	if err := cfg.AuditQueue.Validate(); err != nil {
+		return s, err
+	}
+	if err := cfg.AuditRotation.Validate(); err != nil {
+		return s, err
+	}
+	auditFilter, err := repository.NewAuditFilter(cfg.AuditFilters)
+	if err != nil {
+		return s, err
+	}
+	auditManager := repository.NewAuditManager()
+	auditManager.SetIDGenerator(newRequestID)
+	auditManager.SetRetry(cfg.AuditRetries, auditRetryBackoff)
+	var deadLetter *repository.DeadLetterQueue
+	if cfg.DeadLetterFile != "" {
+		if deadLetter, err = repository.NewDeadLetterQueue(cfg.DeadLetterFile); err != nil {
+			return s, err
+		}
+		auditManager.SetDeadLetter(deadLetter)
+		log.Printf("Audit dead letter file: %s", cfg.DeadLetterFile)
+	}
+	auditManager.SetQueue(cfg.AuditQueue)
+	auditManager.SetFilter(auditFilter)
+	s.closers = append(s.closers, auditManager.Close)
+	observerTypes := make([]string, len(observers))
+	for i, o := range observers {
+		observer, err := repository.NewObserver(o.Type, o.Options)
+		if err != nil {
+			return s, err
+		}
+		if f, ok := observer.(*repository.FileAuditObserver); ok && !f.Rotates() {
+			f.SetRotation(cfg.AuditRotation)
+		}
+		auditManager.Attach(observer)
+		observerTypes[i] = o.Type
+		log.Printf("Audit observer enabled: %s", o.Type)
+	}
+
+	// Инициализация хранилища: реализация выбирается по схеме DSN.
+	backend, err := repository.OpenStorage(context.Background(), cfg.DatabaseDSN, repository.StorageOptions{Shards: cfg.StorageShards})
+	if err != nil {
+		return s, err
+	}
+	s.closers = append(s.closers, func() error { backend.Close(); return nil })
+	dbPool := backend.DB
+	storage := backend.Storage
+	var normalizing *repository.NormalizingStorage
+	if cfg.NormalizeIDs {
+		normalizing = repository.NewNormalizingStorage(storage)
+		storage = normalizing
+	}
+	// Хранилища, загружающие метрики сами (Redis, SQLite), не восстанавливаются из снимка и WAL:
+	// приращения счётчиков применились бы повторно.
+	restore := cfg.Restore
+	if restore && backend.Preloaded {
+		log.Printf("Storage %s restores metrics itself, skipping snapshot and WAL restore", repository.StorageScheme(cfg.DatabaseDSN))
+		restore = false
+	}
+	if restore {
+		if err := repository.LoadMetricsFromFile(storage, cfg.StoreFile); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to restore metrics: %v", err)
+		}
+	}
+
+	// Журнал упреждающей записи: применяем обновления, не попавшие в последний снимок.
+	var walStorage *repository.WALStorage
+	if cfg.WALFile != "" {
+		if restore {
+			n, err := repository.ReplayWAL(storage, cfg.WALFile)
+			if err != nil {
+				return s, fmt.Errorf("failed to replay WAL: %w", err)
+			}
+			log.Printf("Replayed %d WAL records from %s", n, cfg.WALFile)
+		} else if err := os.Remove(cfg.WALFile); err != nil && !os.IsNotExist(err) {
+			return s, fmt.Errorf("failed to reset WAL: %w", err)
+		}
+		if walStorage, err = repository.OpenWAL(storage, cfg.WALFile); err != nil {
+			return s, err
+		}
+		s.closers = append(s.closers, walStorage.Close)
+		storage = walStorage
+	}
+
+	// Статистика операций хранилища (вызовы и задержки), доступна через /admin/storage-stats.
+	instrumented := repository.NewInstrumentedStorage(storage)
+	storage = instrumented
+	s.storage = storage
+
+	// Вклады агентов в счётчики учитываются отдельно, чтобы исправлять их без сброса счётчика.
+	counterSources := repository.NewCounterSources(cfg.NormalizeIDs)
+
+	// Инициализация обработчиков.
+	h := handler.NewHandler(storage, dbPool)
+	s.handler = h
+	h.SetCounterSources(counterSources)
+	h.SetKey(cfg.Key)
+	h.SetCryptoKey(privateKey)
+	h.SetVerifyKey(verifyKey)
+	h.SetAuditManager(auditManager)
+	h.SetLogLevel(logLevel)
+	if normalizing != nil {
+		h.SetCollisionReporter(normalizing)
+	}
+	h.SetPageRefresh(cfg.PageRefresh.Interval, cfg.PageRefresh.Mode != config.PageRefreshReload)
+	h.SetBatchStreaming(cfg.StreamBatch)
+	// Словари сжатия регистрируются агентами с включённым -compression-dict.
+	h.SetDictionaries(compression.NewStore(compression.DefaultStoreLimit))
+	// Регистрация агентов по одноразовым токенам с выдачей персональных ключей.
+	if cfg.EnrollTokens != "" || cfg.AgentsFile != "" {
+		registry, err := repository.NewAgentRegistry(cfg.AgentsFile, strings.Split(cfg.EnrollTokens, ","))
+		if err != nil {
+			return s, fmt.Errorf("failed to load agents registry: %w", err)
+		}
+		h.SetAgentRegistry(registry)
+	}
+	var trustedSubnetNet *net.IPNet
+	if cfg.TrustedSubnet != "" {
+		_, subnet, err := net.ParseCIDR(cfg.TrustedSubnet)
+		if err != nil {
+			return s, fmt.Errorf("invalid trusted subnet: %w", err)
+		}
+		trustedSubnetNet = subnet
+		h.SetTrustedSubnet(subnet)
+	}
+
+	s.saver = repository.NewSnapshotSaver(storage, cfg.StoreFile)
+	s.saver.SetFsync(cfg.SnapshotFsync)
+
+	// Выгрузка снимков в S3-совместимое хранилище для хранения вне хоста.
+	if cfg.S3.Enabled() {
+		if cfg.S3.UploadOn != config.S3UploadOnSnapshot && cfg.S3.UploadOn != config.S3UploadOnShutdown {
+			return s, fmt.Errorf("invalid s3 upload_on %q", cfg.S3.UploadOn)
+		}
+		uploader := repository.NewS3Uploader(cfg.S3)
+		if cfg.S3.UploadOn == config.S3UploadOnSnapshot {
+			s.saver.SetAfterSave(func(path string) error {
+				return uploader.Upload(context.Background(), path)
+			})
+		} else {
+			s.uploader = uploader
+		}
+		log.Printf("S3 snapshot upload enabled: %s/%s (on %s)", cfg.S3.Endpoint, cfg.S3.Bucket, cfg.S3.UploadOn)
+	}
+	// Дублирование записей во внешние хранилища: синхронно после обновления или в фоне.
+	if s.fanOut, err = s.openFanOut(storage, backend); err != nil {
+		return s, err
+	}
+	h.SetFanOut(s.fanOut)
+	// Ролевой доступ: reader — чтение, writer — отправка метрик, admin — административные операции.
+	// Хешированные ключи из файла или базы данных получают области доступа вместо роли.
+	var hashedKeys []auth.HashedKey
+	if cfg.Auth.APIKeysFile != "" {
+		keys, err := repository.LoadAPIKeysFile(cfg.Auth.APIKeysFile)
+		if err != nil {
+			return s, err
+		}
+		hashedKeys = append(hashedKeys, keys...)
+	}
+	if cfg.Auth.APIKeysDB {
+		if dbPool == nil {
+			return s, errors.New("api keys from database require a postgres database DSN")
+		}
+		keys, err := repository.LoadAPIKeysDB(context.Background(), dbPool)
+		if err != nil {
+			return s, err
+		}
+		hashedKeys = append(hashedKeys, keys...)
+	}
+	authenticator, err := auth.New(cfg.Auth.APIKeys, cfg.Auth.JWTSecret, hashedKeys...)
+	if err != nil {
+		return s, fmt.Errorf("invalid auth config: %w", err)
+	}
+	if cfg.Auth.Enabled() {
+		log.Printf("Role-based access enabled (%d API keys, %d hashed API keys, JWT %t)", len(cfg.Auth.APIKeys), len(hashedKeys), cfg.Auth.JWTSecret != "")
+	}
+	h.SetAuthModes(authenticator.Modes())
+	// Собственные метрики сервера отдаются на /metrics административного слушателя.
+	var serverTelemetry *telemetry.Metrics
+	if cfg.AdminAddress != "" {
+		serverTelemetry = telemetry.New()
+		h.SetTelemetry(serverTelemetry)
+		instrumented.SetObserver(serverTelemetry.ObserveStorage)
+		s.fanOut.SetErrorObserver(serverTelemetry.ObserveSyncError)
+		for _, b := range s.fanOut.Backends() {
+			if saver, ok := b.Sink.(*repository.SnapshotSaver); ok {
+				saver.SetObserver(serverTelemetry.ObserveSnapshot)
+			}
+		}
+		if walStorage != nil {
+			serverTelemetry.RegisterGauge("wal_pending_records",
+				"Number of WAL records written since the last snapshot.",
+				func() float64 { return float64(walStorage.Pending()) })
+		}
+		if cfg.AuditQueue.Size > 0 {
+			serverTelemetry.RegisterGauge("audit_queue_events",
+				"Number of audit events waiting for delivery in the queue.",
+				func() float64 { return float64(auditManager.Queued()) })
+			serverTelemetry.RegisterGauge("audit_queue_dropped_events",
+				"Number of audit events dropped because the queue was full.",
+				func() float64 { return float64(auditManager.Dropped()) })
+		}
+		if auditFilter != nil {
+			serverTelemetry.RegisterGauge("audit_filtered_events",
+				"Number of audit events excluded by audit filters.",
+				func() float64 { return float64(auditManager.Filtered()) })
+		}
+		if deadLetter != nil {
+			serverTelemetry.RegisterGauge("audit_dead_letter_records",
+				"Number of undelivered audit events in the dead letter queue.",
+				func() float64 {
+					records, err := deadLetter.List()
+					if err != nil {
+						log.Printf("Failed to read dead letter queue: %v", err)
+					}
+					return float64(len(records))
+				})
+		}
+	}
+	if err := cfg.MetricTTL.Validate(); err != nil {
+		return s, err
+	}
+	if err := cfg.Compression.Validate(); err != nil {
+		return s, err
+	}
+	var rateLimiter *service.RateLimiter
+	if cfg.RateLimitRPS > 0 {
+		rateLimiter = service.NewRateLimiter(float64(cfg.RateLimitRPS), cfg.RateLimitBurst)
+		log.Printf("Rate limit: %d requests per second per client (burst %d)", cfg.RateLimitRPS, cfg.RateLimitBurst)
+	}
+	r := service.NewRouter(h, s.Logger,
+		service.WithSecurityHeaders(cfg.Security),
+		service.WithCompression(cfg.Compression),
+		service.WithRateLimit(rateLimiter),
+		service.WithBodyLimit(int64(cfg.MaxBodySize), cfg.MaxGzipRatio),
+		service.WithAuth(authenticator),
+		service.WithTelemetry(serverTelemetry),
+		service.WithTracing(cfg.Tracing.Enabled()),
+		service.WithRequestID(newRequestID),
+		service.WithRequestLog(cfg.Log),
+	)
+
+	// Фоновые задачи завершаются при закрытии сервера.
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	s.bgCancel = bgCancel
+	s.fanOut.Run(bgCtx)
+
+	// Сторожевой таймер утечек горутин, файловых дескрипторов и памяти.
+	go watchdog.New(cfg.Watchdog, storage, s.Logger).Run(bgCtx)
+
+	// Удаление (или пометка) метрик, не обновлявшихся дольше срока хранения.
+	if cfg.MetricTTL.TTL > 0 {
+		expirer := expiry.New(cfg.MetricTTL, storage, auditManager, s.Logger)
+		expirer.SetCounterSources(counterSources)
+		expirer.SetFanOut(s.fanOut)
+		go expirer.Run(bgCtx)
+		log.Printf("Metric TTL: %s (action %s)", cfg.MetricTTL.TTL, cfg.MetricTTL.Action)
+	}
+
+	// Периодическое резервное копирование снимков по расписанию.
+	if cfg.Backup.Schedule != "" {
+		scheduler, err := backup.New(cfg.Backup, storage, s.Logger)
+		if err != nil {
+			return s, fmt.Errorf("invalid backup config: %w", err)
+		}
+		h.SetBackup(scheduler)
+		go scheduler.Run(bgCtx)
+		log.Printf("Backups enabled: %s (schedule %q, retention %d)", cfg.Backup.Dir, cfg.Backup.Schedule, cfg.Backup.Retention)
+	}
+
+	// Слушатели HTTP: по умолчанию один адрес Address со всеми маршрутами.
+	listeners := append([]config.ListenerConfig(nil), cfg.Listeners...)
+	if len(listeners) == 0 {
+		listeners = []config.ListenerConfig{{Address: cfg.Address}}
+	}
+	// С отдельным административным слушателем служебные маршруты по умолчанию
+	// не обслуживаются на портах, доступных агентам.
+	if cfg.AdminAddress != "" {
+		for i := range listeners {
+			if len(listeners[i].Routes) == 0 {
+				listeners[i].Routes = []string{config.RouteGroupIngest, config.RouteGroupRead}
+			}
+		}
+	}
+	for _, l := range listeners {
+		desc := l.Address
+		if len(l.Routes) > 0 {
+			desc += "=" + strings.Join(l.Routes, "+")
+		}
+		s.listenerAddrs = append(s.listenerAddrs, desc)
+		if err := s.listen(l.Address, service.RestrictRoutes(l.Routes)(r), s.tlsConfig); err != nil {
+			return s, err
+		}
+	}
+
+	// Перенаправление HTTP → HTTPS на порт первого основного слушателя.
+	if cfg.TLSRedirect != "" {
+		if s.tlsConfig == nil {
+			return s, errors.New("tls redirect requires tls certificate and key")
+		}
+		_, httpsPort, _ := net.SplitHostPort(s.Addr())
+		s.listenerAddrs = append(s.listenerAddrs, cfg.TLSRedirect+" (https redirect)")
+		if err := s.listen(cfg.TLSRedirect, httpsRedirect(httpsPort), nil); err != nil {
+			return s, err
+		}
+	}
+
+	// Итоговая конфигурация для диагностического архива (секреты скрываются при выдаче).
+	h.SetDiagnostics(map[string]string{
+		"address":        cfg.Address,
+		"database_dsn":   cfg.DatabaseDSN,
+		"store_interval": strconv.Itoa(cfg.StoreInterval),
+		"store_file":     cfg.StoreFile,
+		"restore":        strconv.FormatBool(cfg.Restore),
+		"key":            cfg.Key,
+		"crypto_key":     cfg.CryptoKey,
+		"verify_key":     cfg.VerifyKey,
+		"audit_file":     cfg.AuditFile,
+		"audit_url":      cfg.AuditURL,
+		"trusted_subnet": cfg.TrustedSubnet,
+		"grpc_address":   cfg.GRPCAddress,
+		"reuse_port":     strconv.FormatBool(cfg.ReusePort),
+		"h2c":            strconv.FormatBool(cfg.H2C),
+		"snapshot_fsync": strconv.FormatBool(cfg.SnapshotFsync),
+		"watchdog":       cfg.Watchdog.Interval.String(),
+		"wal_file":       cfg.WALFile,
+		"storage_shards": strconv.Itoa(cfg.StorageShards),
+		"normalize_ids":  strconv.FormatBool(cfg.NormalizeIDs),
+		"security_headers.content_security_policy": cfg.Security.ContentSecurityPolicy,
+		"backup.schedule":                          cfg.Backup.Schedule,
+		"backup.dir":                               cfg.Backup.Dir,
+		"backup.retention":                         strconv.Itoa(cfg.Backup.Retention),
+		"enroll_tokens":                            cfg.EnrollTokens,
+		"agents_file":                              cfg.AgentsFile,
+		"auth.api_keys":                            strconv.Itoa(len(cfg.Auth.APIKeys)),
+		"auth.jwt_secret":                          cfg.Auth.JWTSecret,
+		"auth.api_keys_file":                       cfg.Auth.APIKeysFile,
+		"auth.api_keys_db":                         strconv.FormatBool(cfg.Auth.APIKeysDB),
+		"listeners":                                strings.Join(s.listenerAddrs, ","),
+		"admin_address":                            cfg.AdminAddress,
+		"admin_token":                              cfg.AdminToken,
+		"observers":                                strings.Join(observerTypes, ","),
+		"request_id_format":                        requestIDFormat,
+		"storage.backends":                         strings.Join(backendNames(s.fanOut), ","),
+		"tls.min_version":                          cfg.TLS.MinVersion,
+		"tls.cert_file":                            cfg.TLSCert,
+		"tls.redirect":                             cfg.TLSRedirect,
+		"data_dir":                                 cfg.DataDir,
+		"log_dir":                                  cfg.LogDir,
+		"dead_letter_file":                         cfg.DeadLetterFile,
+		"audit_retries":                            strconv.Itoa(cfg.AuditRetries),
+		"audit_queue.size":                         strconv.Itoa(cfg.AuditQueue.Size),
+		"audit_queue.workers":                      strconv.Itoa(cfg.AuditQueue.Workers),
+		"audit_queue.policy":                       cfg.AuditQueue.Policy,
+		"audit_rotation.max_size_mb":               strconv.Itoa(cfg.AuditRotation.MaxSizeMB),
+		"audit_rotation.max_age":                   cfg.AuditRotation.MaxAge.String(),
+		"audit_rotation.max_backups":               strconv.Itoa(cfg.AuditRotation.MaxBackups),
+		"audit_rotation.compress":                  strconv.FormatBool(cfg.AuditRotation.Compress),
+		"audit_filters":                            strconv.Itoa(len(cfg.AuditFilters)),
+		"compression.level":                        strconv.Itoa(cfg.Compression.Level),
+		"compression.exclude":                      strings.Join(cfg.Compression.Exclude, ","),
+		"rate_limit_rps":                           strconv.Itoa(cfg.RateLimitRPS),
+		"rate_limit_burst":                         strconv.Itoa(cfg.RateLimitBurst),
+		"max_body_size":                            strconv.Itoa(cfg.MaxBodySize),
+		"max_gzip_ratio":                           strconv.Itoa(cfg.MaxGzipRatio),
+		"stream_batch":                             strconv.Itoa(cfg.StreamBatch),
+		"metric_ttl.ttl":                           cfg.MetricTTL.TTL.String(),
+		"metric_ttl.action":                        cfg.MetricTTL.Action,
+		"tracing.endpoint":                         cfg.Tracing.Endpoint,
+		"tracing.sample_ratio":                     strconv.FormatFloat(cfg.Tracing.SampleRatio, 'g', -1, 64),
+		"logger.max_size_mb":                       strconv.Itoa(cfg.Log.MaxSizeMB),
+		"logger.max_age":                           cfg.Log.MaxAge.String(),
+		"logger.max_backups":                       strconv.Itoa(cfg.Log.MaxBackups),
+		"logger.compress":                          strconv.FormatBool(cfg.Log.Compress),
+		"logger.exclude_paths":                     strings.Join(cfg.Log.ExcludePaths, ","),
+		"logger.slow_threshold":                    cfg.Log.SlowThreshold.String(),
+	}, cfg.LogFile)
+
+	// Административный слушатель: /admin/*, /status и pprof.
+	if cfg.AdminAddress != "" {
+		s.listenerAddrs = append(s.listenerAddrs, cfg.AdminAddress+" (admin)")
+		adminRouter := service.NewAdminRouter(h, s.Logger,
+			service.WithAuth(authenticator),
+			service.WithAdminToken(cfg.AdminToken),
+			service.WithBodyLimit(int64(cfg.MaxBodySize), cfg.MaxGzipRatio),
+			service.WithTelemetry(serverTelemetry),
+			service.WithRequestID(newRequestID),
+			service.WithRequestLog(cfg.Log),
+		)
+		if err := s.listen(cfg.AdminAddress, adminRouter, s.tlsConfig); err != nil {
+			return s, err
+		}
+	}
+
+	if cfg.GRPCAddress != "" {
+		if s.grpcListener, err = s.listenTCP(cfg.GRPCAddress); err != nil {
+			return s, fmt.Errorf("failed to listen gRPC address: %w", err)
+		}
+		grpcOpts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(
+			grpcserver.RequestIDInterceptor(newRequestID),
+			grpcserver.IPSubnetInterceptor(trustedSubnetNet, auditManager),
+			grpcserver.RoleInterceptor(authenticator, auth.RoleWriter, auditManager),
+			grpcserver.SignatureInterceptor(verifyKey, auditManager),
+		)}
+		if s.tlsConfig != nil {
+			grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(s.tlsConfig.Clone())))
+		}
+		s.grpcSrv = grpc.NewServer(grpcOpts...)
+		metricsSvc := grpcserver.NewMetricsService(storage, dbPool)
+		metricsSvc.SetTelemetry(serverTelemetry)
+		metricsSvc.SetFanOut(s.fanOut)
+		metricsSvc.SetCounterSources(counterSources)
+		proto.RegisterMetricsServer(s.grpcSrv, metricsSvc)
+	}
+
+	// Метрики восстановлены из снимка и журнала выше: /readyz отвечает готовностью.
+	h.SetReady(true)
+	return s, nil
+}
+
+// openFanOut создаёт хранилища, в которые дублируются записи основного хранилища storage.
+//
+// Если хранилище, открытое по DSN (primary), выгружает метрики во внешнее хранилище
+// (Redis, SQLite), оно записывается синхронно первым независимо от Config.Storage.
+// Без явной конфигурации (Config.Storage) записи синхронно дублируются в PostgreSQL при заданном
+// пуле primary.DB, а снимок сохраняется в StoreFile каждые StoreInterval секунд (при 0 — после
+// каждого обновления).
+func (s *Server) openFanOut(storage repository.Storage, primary *repository.StorageBackend) (*repository.FanOut, error) {
+	dbPool := primary.DB
+	var result []repository.FanOutBackend
+	if primary.Sink != nil {
+		scheme := repository.StorageScheme(s.cfg.DatabaseDSN)
+		result = append(result, repository.FanOutBackend{Name: scheme, Sink: primary.Sink, FailOnError: true})
+		log.Printf("Storage backend enabled: %s (async false, fail on error true)", scheme)
+	}
+
+	backends := s.cfg.Storage.Backends
+	if len(backends) == 0 {
+		if dbPool != nil {
+			backends = append(backends, config.StorageBackendConfig{Type: config.StorageBackendPostgres})
+		}
+		if s.cfg.StoreFile != "" {
+			file := config.StorageBackendConfig{Type: config.StorageBackendFile, OnError: config.StorageOnErrorLog}
+			if s.cfg.StoreInterval > 0 {
+				file.Mode = config.StorageModeAsync
+			}
+			backends = append(backends, file)
+		}
+	}
+
+	for _, b := range backends {
+		if err := b.Validate(); err != nil {
+			return nil, err
+		}
+		fb := repository.FanOutBackend{Name: b.Type, Async: b.Async(), FailOnError: b.FailOnError()}
+		if fb.Async {
+			interval := s.cfg.StoreInterval
+			if b.Interval != "" {
+				var err error
+				if interval, err = config.ParseDuration(b.Interval); err != nil {
+					return nil, fmt.Errorf("storage backend %s: %w", b.Type, err)
+				}
+			}
+			if interval <= 0 {
+				return nil, fmt.Errorf("storage backend %s: mode %q requires interval or store_interval", b.Type, config.StorageModeAsync)
+			}
+			fb.Interval = time.Duration(interval) * time.Second
+		}
+
+		switch b.Type {
+		case config.StorageBackendPostgres:
+			pool := dbPool
+			if dsn := b.Options["dsn"]; dsn != "" {
+				var err error
+				if pool, err = db.InitDB(context.Background(), dsn); err != nil {
+					return nil, fmt.Errorf("storage backend %s: %w", b.Type, err)
+				}
+				s.closers = append(s.closers, func() error { pool.Close(); return nil })
+			}
+			if pool == nil {
+				return nil, fmt.Errorf("storage backend %s: database_dsn is not set", b.Type)
+			}
+			fb.Sink = repository.DBSink(storage, pool)
+		case config.StorageBackendFile:
+			path := b.Options["path"]
+			if path == "" && s.cfg.StoreFile == "" {
+				return nil, fmt.Errorf("storage backend %s: path and store_file are not set", b.Type)
+			}
+			if path == "" || path == s.cfg.StoreFile {
+				fb.Name += ":" + s.cfg.StoreFile
+				fb.Sink = s.saver
+			} else {
+				// Журнал упреждающей записи восстанавливается поверх StoreFile: копия в другом
+				// файле не должна его очищать.
+				saver := repository.NewSnapshotSaver(storage, path)
+				saver.SetFsync(s.cfg.SnapshotFsync)
+				saver.SetCheckpoint(false)
+				fb.Name += ":" + path
+				fb.Sink = saver
+			}
+		}
+		result = append(result, fb)
+		log.Printf("Storage backend enabled: %s (async %t, fail on error %t)", fb.Name, fb.Async, fb.FailOnError)
+	}
+	return repository.NewFanOut(result...), nil
+}
+
+// backendNames возвращает имена хранилищ f для диагностики.
+func backendNames(f *repository.FanOut) []string {
+	var names []string
+	for _, b := range f.Backends() {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+// resolvePaths переносит относительные пути файлов сервера (снимок, WAL, аудит, включая
+// наблюдателей типа file, недоставленные события, реестр агентов, резервные копии) в каталог DataDir и заполняет LogDir и LogFile значениями по умолчанию.
+func resolvePaths(cfg Config) Config {
+	if cfg.LogDir == "" {
+		cfg.LogDir = config.ResolvePath(cfg.DataDir, config.LogDir)
+	}
+	if cfg.LogFile == "" {
+		cfg.LogFile = filepath.Join(cfg.LogDir, config.LogFileName)
+	}
+	cfg.StoreFile = config.ResolvePath(cfg.DataDir, cfg.StoreFile)
+	cfg.WALFile = config.ResolvePath(cfg.DataDir, cfg.WALFile)
+	cfg.AuditFile = config.ResolvePath(cfg.DataDir, cfg.AuditFile)
+	cfg.DeadLetterFile = config.ResolvePath(cfg.DataDir, cfg.DeadLetterFile)
+	cfg.AgentsFile = config.ResolvePath(cfg.DataDir, cfg.AgentsFile)
+	cfg.Backup.Dir = config.ResolvePath(cfg.DataDir, cfg.Backup.Dir)
+	observers := make([]config.ObserverConfig, len(cfg.Observers))
+	for i, o := range cfg.Observers {
+		if o.Type == "file" && o.Options["path"] != "" {
+			o.Options = maps.Clone(o.Options)
+			o.Options["path"] = config.ResolvePath(cfg.DataDir, o.Options["path"])
+		}
+		observers[i] = o
+	}
+	cfg.Observers = observers
+	return cfg
+}
+
+// listenTCP открывает TCP-слушатель addr; с Config.ReusePort — с SO_REUSEPORT.
+func (s *Server) listenTCP(addr string) (net.Listener, error) {
+	var lc net.ListenConfig
+	if s.cfg.ReusePort {
+		lc.Control = reusePortControl
+	}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
+
+// listen открывает слушатель addr и добавляет HTTP-сервер с обработчиком h.
+// С непустым tlsConfig сервер обслуживает HTTPS.
+func (s *Server) listen(addr string, h http.Handler, tlsConfig *tls.Config) error {
+	ln, err := s.listenTCP(addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	s.listeners = append(s.listeners, ln)
+	srv := &http.Server{
+		Addr:        addr,
+		Handler:     s.inflight.track(h),
+		TLSConfig:   tlsConfig.Clone(),
+		BaseContext: s.inflight.baseContext,
+	}
+	if s.cfg.H2C {
+		// По TLS HTTP/2 согласуется через ALPN и без этой настройки; h2c нужен обычным слушателям.
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetHTTP2(true)
+		srv.Protocols.SetUnencryptedHTTP2(true)
+	}
+	s.servers = append(s.servers, srv)
+	return nil
+}
+
+// httpsRedirect возвращает обработчик, перенаправляющий запрос на тот же хост и путь по HTTPS
+// на порт httpsPort. Код 308 сохраняет метод и тело запроса агентов.
+func httpsRedirect(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+		target := host
+		if strings.Contains(host, ":") {
+			target = "[" + host + "]"
+		}
+		if httpsPort != "443" {
+			target = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+target+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// modernCipherSuites — наборы шифров TLS 1.2 по умолчанию: только ECDHE с AEAD.
+// Наборы TLS 1.3 в Go не настраиваются и всегда современные.
+var modernCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// serverTLSConfig создаёт конфигурацию TLS слушателей по политике cfg.TLS и паре TLSCert/TLSKey.
+//
+// Без сертификата возвращает nil: слушатели обслуживают обычный HTTP, а политика только проверяется.
+func serverTLSConfig(cfg Config) (*tls.Config, error) {
+	tlsConfig, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TLSCert == "" && cfg.TLSKey == "" {
+		return nil, nil
+	}
+	if cfg.TLSCert == "" || cfg.TLSKey == "" {
+		return nil, errors.New("tls certificate and key must be set together")
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tls certificate: %w", err)
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	// Не заданные политикой параметры заменяются современными значениями по умолчанию.
+	if tlsConfig.MinVersion == 0 {
+		tlsConfig.MinVersion = tls.VersionTLS12
+	}
+	if len(tlsConfig.CipherSuites) == 0 {
+		tlsConfig.CipherSuites = modernCipherSuites
+	}
+	if len(tlsConfig.CurvePreferences) == 0 {
+		tlsConfig.CurvePreferences = []tls.CurveID{tls.X25519MLKEM768, tls.X25519, tls.CurveP256}
+	}
+	return tlsConfig, nil
+}
+
+// Addr возвращает фактический адрес первого HTTP-слушателя.
+func (s *Server) Addr() string {
+	return s.listeners[0].Addr().String()
+}
+
+// GRPCAddr возвращает фактический адрес gRPC-сервера или пустую строку, если он отключён.
+func (s *Server) GRPCAddr() string {
+	if s.grpcListener == nil {
+		return ""
+	}
+	return s.grpcListener.Addr().String()
+}
+
+// Storage возвращает хранилище метрик сервера.
+func (s *Server) Storage() repository.Storage {
+	return s.storage
+}
+
+// Run обслуживает запросы до отмены ctx или ошибки одного из серверов.
+//
+// При отмене ctx прекращает приём запросов и ждёт завершения выполняющихся не дольше
+// 5 секунд (оставшиеся отменяются, см. drain), затем сохраняет снимок метрик и синхронизирует
+// хранилища, выгружает снимок в S3 (если задано upload_on: shutdown) и освобождает ресурсы.
+func (s *Server) Run(ctx context.Context) error {
+	defer s.Close()
+
+	errChan := make(chan error, len(s.servers)+1)
+	for i, srv := range s.servers {
+		go func() {
+			log.Printf("Server listening on %s\n", s.listenerAddrs[i])
+			if srv.TLSConfig != nil {
+				errChan <- srv.ServeTLS(s.listeners[i], "", "")
+				return
+			}
+			errChan <- srv.Serve(s.listeners[i])
+		}()
+	}
+	if s.grpcSrv != nil {
+		go func() {
+			log.Printf("gRPC server listening on %s\n", s.grpcListener.Addr())
+			if err := s.grpcSrv.Serve(s.grpcListener); err != nil {
+				errChan <- fmt.Errorf("gRPC server error: %w", err)
+			}
+		}()
+	}
+
+	select {
+	case err := <-errChan:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, grpc.ErrServerStopped) {
+			return fmt.Errorf("server error: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		log.Printf("Starting graceful shutdown: %d requests in flight", s.inflight.active.Load())
+		s.handler.SetReady(false)
+		// Снимок и синхронизация с БД выполняются после завершения запросов,
+		// чтобы в них попали все принятые обновления.
+		drained, cancelled, shutdownErr := s.drain(shutdownTimeout)
+		log.Printf("Drained %d in-flight requests, cancelled %d", drained, cancelled)
+		if err := s.fanOut.Flush(context.Background()); err != nil {
+			log.Printf("Failed to save metrics: %v", err)
+		}
+		if s.uploader != nil {
+			if err := s.uploader.Upload(context.Background(), s.cfg.StoreFile); err != nil {
+				log.Printf("Failed to upload snapshot: %v", err)
+			}
+		}
+		return shutdownErr
+	}
+}
+
+// Close останавливает фоновые задачи и освобождает хранилище и слушатели.
+// Вызывается автоматически при выходе из Run; нужен, если сервер создан, но не запущен.
+func (s *Server) Close() error {
+	if s.bgCancel != nil {
+		s.bgCancel()
+		s.bgCancel = nil
+	}
+	s.inflight.cancel()
+	if s.grpcSrv != nil {
+		s.grpcSrv.Stop()
+	} else if s.grpcListener != nil {
+		_ = s.grpcListener.Close()
+	}
+	for _, srv := range s.servers {
+		_ = srv.Close()
+	}
+	for _, ln := range s.listeners {
+		_ = ln.Close()
+	}
+	var err error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		err = errors.Join(err, s.closers[i]())
+	}
+	s.closers = nil
+	if s.closeLog {
+		_ = s.Logger.Sync()
+		s.closeLog = false
+	}
+	return err
+}
@@ -0,0 +1,339 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/crypto"
+	"github.com/RoGogDBD/metric-alerter/internal/proto"
+	"github.com/RoGogDBD/metric-alerter/internal/requestid"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TestServer_EndToEnd проверяет полный цикл: запуск сервера, приём и чтение метрики,
+// сохранение снимка при остановке и восстановление из него новым сервером.
+//
+// t — указатель на структуру теста.
+func TestServer_EndToEnd(t *testing.T) {
+	storeFile := filepath.Join(t.TempDir(), "metrics.json")
+	cfg := Config{
+		Address:       "127.0.0.1:0",
+		StoreInterval: 300,
+		StoreFile:     storeFile,
+		Restore:       true,
+		Logger:        zap.NewNop(),
+	}
+
+	srv, err := New(cfg)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+
+	base := "http://" + srv.Addr()
+	resp, err := http.Post(base+"/update/gauge/Alloc/12.5", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotEmpty(t, resp.Header.Get(requestid.Header))
+	require.Equal(t, "12.5", getValue(t, base+"/value/gauge/Alloc"))
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+	_, err = os.Stat(storeFile)
+	require.NoError(t, err, "snapshot must be saved on shutdown")
+
+	// Новый сервер восстанавливает метрику из снимка.
+	restored, err := New(cfg)
+	require.NoError(t, err)
+	defer restored.Close()
+	value, ok := restored.Storage().GetGauge("Alloc")
+	require.True(t, ok)
+	require.Equal(t, 12.5, value)
+}
+
+// TestServer_RestartPersistentBackend проверяет, что после перезапуска сервера с хранилищем,
+// загружающим метрики само, счётчик не увеличивается повторно восстановлением из снимка.
+//
+// t — указатель на структуру теста.
+func TestServer_RestartPersistentBackend(t *testing.T) {
+	tests := []struct {
+		name string              // Название теста
+		dsn  func(string) string // DSN хранилища по временному каталогу
+	}{
+		{name: "Redis", dsn: func(string) string { return "redis://" + miniredis.RunT(t).Addr() + "/0" }},
+		{name: "SQLite", dsn: func(dir string) string { return "sqlite://" + filepath.Join(dir, "metrics.db") }},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := Config{
+				Address:       "127.0.0.1:0",
+				DatabaseDSN:   tc.dsn(dir),
+				StoreInterval: 300,
+				StoreFile:     filepath.Join(dir, "metrics.json"),
+				WALFile:       filepath.Join(dir, "metrics.wal"),
+				Restore:       true,
+				Logger:        zap.NewNop(),
+			}
+
+			for restart := 0; restart < 2; restart++ {
+				srv, err := New(cfg)
+				require.NoError(t, err)
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan error, 1)
+				go func() { done <- srv.Run(ctx) }()
+
+				if restart == 0 {
+					resp, err := http.Post("http://"+srv.Addr()+"/update/counter/Hits/10", "text/plain", nil)
+					require.NoError(t, err)
+					resp.Body.Close()
+					require.Equal(t, http.StatusOK, resp.StatusCode)
+				}
+				c, ok := srv.Storage().GetCounter("Hits")
+				require.True(t, ok)
+				require.Equal(t, int64(10), c, "counter after restart %d", restart)
+
+				cancel()
+				require.NoError(t, <-done)
+			}
+		})
+	}
+}
+
+// TestServer_H2C проверяет, что с H2C обычный HTTP-слушатель принимает HTTP/2 без TLS,
+// а клиенты HTTP/1.1 продолжают работать.
+//
+// t — указатель на структуру теста.
+func TestServer_H2C(t *testing.T) {
+	srv, err := New(Config{Address: "127.0.0.1:0", H2C: true, Logger: zap.NewNop()})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Run(ctx) }()
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	h2c := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	base := "http://" + srv.Addr()
+
+	resp, err := h2c.Post(base+"/update/gauge/Alloc/1.5", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 2, resp.ProtoMajor)
+
+	resp, err = http.Get(base + "/value/gauge/Alloc")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 1, resp.ProtoMajor)
+}
+
+// TestNew_InvalidConfig проверяет, что New отклоняет некорректную конфигурацию и освобождает ресурсы.
+//
+// t — указатель на структуру теста.
+func TestNew_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string // Название теста
+		cfg  Config // Конфигурация сервера
+	}{
+		{name: "TrustedSubnet", cfg: Config{Address: "127.0.0.1:0", TrustedSubnet: "not-a-cidr"}},
+		{name: "Address", cfg: Config{Address: "127.0.0.1:-1"}},
+		{name: "CryptoKey", cfg: Config{Address: "127.0.0.1:0", CryptoKey: "/nonexistent/key.pem"}},
+		{name: "VerifyKey", cfg: Config{Address: "127.0.0.1:0", VerifyKey: "/nonexistent/verify.pem"}},
+		{name: "TLSMinVersion", cfg: Config{Address: "127.0.0.1:0", TLS: config.TLSConfig{MinVersion: "1.4"}}},
+		{name: "TLSCertWithoutKey", cfg: Config{Address: "127.0.0.1:0", TLSCert: "cert.pem"}},
+		{name: "APIKeysDBWithoutDatabase", cfg: Config{Address: "127.0.0.1:0", Auth: config.AuthConfig{APIKeysDB: true}}},
+		{name: "TLSRedirectWithoutCert", cfg: Config{Address: "127.0.0.1:0", TLSRedirect: "127.0.0.1:0"}},
+		{name: "TLSCertMissing", cfg: Config{Address: "127.0.0.1:0", TLSCert: "/nonexistent/cert.pem", TLSKey: "/nonexistent/key.pem"}},
+		{name: "StorageBackendType", cfg: Config{Address: "127.0.0.1:0", Storage: config.StorageConfig{Backends: []config.StorageBackendConfig{{Type: "redis"}}}}},
+		{name: "StoragePostgresWithoutDSN", cfg: Config{Address: "127.0.0.1:0", Storage: config.StorageConfig{Backends: []config.StorageBackendConfig{{Type: config.StorageBackendPostgres}}}}},
+		{name: "StorageAsyncInvalidInterval", cfg: Config{Address: "127.0.0.1:0", StoreFile: "m.json", Storage: config.StorageConfig{Backends: []config.StorageBackendConfig{{Type: config.StorageBackendFile, Mode: config.StorageModeAsync, Interval: "soon"}}}}},
+		{name: "StorageAsyncWithoutInterval", cfg: Config{Address: "127.0.0.1:0", StoreFile: "m.json", Storage: config.StorageConfig{Backends: []config.StorageBackendConfig{{Type: config.StorageBackendFile, Mode: config.StorageModeAsync}}}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.Logger = zap.NewNop()
+			srv, err := New(tc.cfg)
+			require.Error(t, err)
+			require.Nil(t, srv)
+		})
+	}
+}
+
+// TestServer_StorageBackends проверяет синхронную запись в файл, заданный явной конфигурацией хранилищ,
+// и то, что эта запись не очищает журнал упреждающей записи основного снимка.
+//
+// t — указатель на структуру теста.
+func TestServer_StorageBackends(t *testing.T) {
+	dir := t.TempDir()
+	mirror := filepath.Join(dir, "mirror.json")
+	srv, err := New(Config{
+		Address:       "127.0.0.1:0",
+		StoreInterval: 300,
+		StoreFile:     filepath.Join(dir, "metrics.json"),
+		WALFile:       filepath.Join(dir, "metrics.wal"),
+		Storage: config.StorageConfig{Backends: []config.StorageBackendConfig{
+			{Type: config.StorageBackendFile, Options: map[string]string{"path": mirror}},
+		}},
+		Logger: zap.NewNop(),
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Run(ctx) }()
+
+	resp, err := http.Post("http://"+srv.Addr()+"/update/counter/Hits/3", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	data, err := os.ReadFile(mirror)
+	require.NoError(t, err, "sync file backend must be written before the response")
+	require.Contains(t, string(data), `"Hits"`)
+	_, err = os.Stat(filepath.Join(dir, "metrics.json"))
+	require.True(t, os.IsNotExist(err), "store_file is not written when backends are configured explicitly")
+	info, err := os.Stat(filepath.Join(dir, "metrics.wal"))
+	require.NoError(t, err)
+	require.NotZero(t, info.Size(), "mirror save must not checkpoint the WAL")
+}
+
+// TestServer_VerifyKey проверяет, что при заданном ключе проверки Ed25519 неподписанные
+// обновления отклоняются на всех путях записи: по URL и через gRPC.
+//
+// t — указатель на структуру теста.
+func TestServer_VerifyKey(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "verify.pem")
+	require.NoError(t, crypto.SavePublicKey(keyPath, pub))
+
+	srv, err := New(Config{
+		Address:     "127.0.0.1:0",
+		GRPCAddress: "127.0.0.1:0",
+		VerifyKey:   keyPath,
+		Logger:      zap.NewNop(),
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Run(ctx) }()
+
+	resp, err := http.Post("http://"+srv.Addr()+"/update/gauge/Alloc/1", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode, "unsigned URL-form update")
+
+	conn, err := grpc.NewClient(srv.GRPCAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := proto.NewMetricsClient(conn)
+	value := 2.0
+	req := &proto.UpdateMetricsRequest{Metrics: []*proto.Metric{{Id: "Alloc", Type: proto.Metric_GAUGE, Value: value}}}
+
+	_, err = client.UpdateMetrics(ctx, req)
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "unsigned gRPC update")
+	_, ok := srv.Storage().GetGauge("Alloc")
+	require.False(t, ok)
+
+	payload, err := crypto.MessagePayload(req)
+	require.NoError(t, err)
+	signedCtx := metadata.AppendToOutgoingContext(ctx, crypto.SignatureMetadataKey, crypto.Sign(payload, priv))
+	_, err = client.UpdateMetrics(signedCtx, req)
+	require.NoError(t, err)
+	got, ok := srv.Storage().GetGauge("Alloc")
+	require.True(t, ok)
+	require.Equal(t, value, got)
+}
+
+// getValue выполняет GET-запрос и возвращает тело ответа.
+func getValue(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+// TestServer_DataDir проверяет, что относительные пути файлов сервера и журнал
+// отсчитываются от DataDir, а не от рабочей директории.
+//
+// t — указатель на структуру теста.
+func TestServer_DataDir(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "data")
+	srv, err := New(Config{
+		Address:   "127.0.0.1:0",
+		StoreFile: "metrics.json",
+		WALFile:   "metrics.wal",
+		DataDir:   dataDir,
+		Logger:    zap.NewNop(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dataDir, "metrics.json"), srv.cfg.StoreFile)
+	require.Equal(t, filepath.Join(dataDir, "logs", config.LogFileName), srv.cfg.LogFile)
+
+	srv.Storage().SetGauge("Alloc", 1)
+	require.NoError(t, srv.fanOut.Flush(context.Background()))
+	require.NoError(t, srv.Close())
+	for _, name := range []string{"metrics.json", "metrics.wal"} {
+		_, err = os.Stat(filepath.Join(dataDir, name))
+		require.NoError(t, err, "%s must be written under the data dir", name)
+	}
+}
+
+// TestServer_HashedAPIKeyScopes проверяет, что ключ из -api-keys-file с областью write-metrics
+// может отправлять метрики, но не читать их.
+//
+// t — указатель на структуру теста.
+func TestServer_HashedAPIKeyScopes(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(keysFile, []byte(`[{"name":"agents","hash":"`+auth.HashKey("ingest-key")+`","scopes":["write-metrics"]}]`), 0o600))
+
+	srv, err := New(Config{
+		Address: "127.0.0.1:0",
+		Auth:    config.AuthConfig{APIKeysFile: keysFile},
+		Logger:  zap.NewNop(),
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Run(ctx) }()
+
+	do := func(method, path string) int {
+		req, err := http.NewRequest(method, "http://"+srv.Addr()+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer ingest-key")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/update/gauge/Alloc/1"))
+	require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/value/gauge/Alloc"))
+}
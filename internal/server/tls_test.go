@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// writeSelfSignedCert создаёт самоподписанный сертификат для 127.0.0.1 и возвращает пути
+// к файлам сертификата и ключа вместе с пулом, которому доверяет клиент.
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string, pool *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "metric-alerter-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+// TestServer_TLSPolicyHandshake проверяет, что слушатель обслуживает HTTPS по политике TLS:
+// клиент с TLS 1.3 проходит рукопожатие, клиент не выше TLS 1.2 отклоняется.
+//
+// t — указатель на структуру теста.
+func TestServer_TLSPolicyHandshake(t *testing.T) {
+	certFile, keyFile, pool := writeSelfSignedCert(t)
+	srv, err := New(Config{
+		Address: "127.0.0.1:0",
+		TLS:     config.TLSConfig{MinVersion: "1.3"},
+		TLSCert: certFile,
+		TLSKey:  keyFile,
+		Logger:  zap.NewNop(),
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	base := "https://" + srv.Addr()
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Post(base+"/update/gauge/Alloc/1", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotNil(t, resp.TLS)
+	require.Equal(t, uint16(tls.VersionTLS13), resp.TLS.Version)
+
+	legacy := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MaxVersion: tls.VersionTLS12}}}
+	_, err = legacy.Get(base + "/value/gauge/Alloc")
+	require.Error(t, err, "handshake below the policy minimum must fail")
+}
+
+// TestServer_TLSRedirect проверяет, что обычный HTTP-слушатель перенаправляет запросы
+// на тот же путь по HTTPS с кодом 308, а HTTPS-слушатель использует современные наборы шифров.
+//
+// t — указатель на структуру теста.
+func TestServer_TLSRedirect(t *testing.T) {
+	certFile, keyFile, pool := writeSelfSignedCert(t)
+	srv, err := New(Config{
+		Address:     "127.0.0.1:0",
+		TLSCert:     certFile,
+		TLSKey:      keyFile,
+		TLSRedirect: "127.0.0.1:0",
+		Logger:      zap.NewNop(),
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	redirectAddr := srv.listeners[len(srv.listeners)-1].Addr().String()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Post("http://"+redirectAddr+"/update/gauge/Alloc/1?x=1", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusPermanentRedirect, resp.StatusCode)
+	require.Equal(t, "https://"+srv.Addr()+"/update/gauge/Alloc/1?x=1", resp.Header.Get("Location"))
+
+	tls12 := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MaxVersion: tls.VersionTLS12}}}
+	resp, err = tls12.Get("https://" + srv.Addr() + "/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Contains(t, modernCipherSuites, resp.TLS.CipherSuite)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/requestid"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// NewAdminRouter создаёт роутер отдельного административного слушателя.
+//
+// Обслуживает /admin/* (конфигурация, уровень логирования через /admin/runtime, резервное
+// копирование), /status, /metrics (если задан WithTelemetry) и профилировщик /debug/pprof/*,
+// чтобы служебные обработчики не были доступны на порту приёма метрик.
+//
+// Проверки здоровья /healthz, /livez и /readyz открыты, чтобы оркестратор мог опрашивать
+// административный порт без учётных данных. Если задан токен (WithAdminToken), остальные
+// запросы требуют его в заголовке Authorization: Bearer или X-API-Key независимо от ролевого
+// доступа основного слушателя; иначе требуется роль admin (если ролевой доступ включён через WithAuth).
+//
+// Параметры:
+//   - h: обработчик запросов (handler.Handler)
+//   - logger: логгер для логирования запросов
+//   - opts: необязательные настройки роутера (RouterOption)
+func NewAdminRouter(h *handler.Handler, logger *zap.Logger, opts ...RouterOption) *chi.Mux {
+	o := defaultRouterOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := chi.NewRouter()
+	r.Use(requestid.Middleware(o.requestID))
+	r.Use(middleware.RealIP)
+	r.Use(config.RequestLogger(logger, o.requestLog))
+	r.Use(middleware.Recoverer)
+	r.Use(LimitBody(o.maxBodyBytes))
+	r.Use(DecompressRequest(o.maxGzipRatio, nil))
+	r.NotFound(handler.HandleNotFound)
+	r.MethodNotAllowed(handler.HandleMethodNotAllowed)
+
+	r.Get("/healthz", h.HandleHealthz)
+	r.Get("/livez", h.HandleLivez)
+	r.Get("/readyz", h.HandleReadyz)
+
+	r.Group(func(r chi.Router) {
+		if o.adminToken != "" {
+			r.Use(RequireToken(o.adminToken, h.AuditRejection))
+		} else {
+			r.Use(RequireRole(o.auth, auth.RoleAdmin, h.AuditRejection))
+		}
+
+		registerAdminRoutes(r, h)
+		if o.adminToken != "" || o.auth != nil {
+			registerRuntimeRoutes(r, h)
+		}
+		r.Get("/status", h.HandleStatus)
+		if o.telemetry != nil {
+			r.Method(http.MethodGet, "/metrics", o.telemetry)
+		}
+		r.HandleFunc("/debug/pprof/*", pprof.Index)
+		r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	})
+
+	return r
+}
+
+// RequireToken возвращает middleware, пропускающий только запросы с заданным токеном
+// в заголовке Authorization: Bearer или X-API-Key.
+//
+// Об отказе сообщается через onReject (может быть nil). Идентификатор клиента в контексте
+// запроса (для журнала аудита) — auth.AdminTokenIdentity.
+func RequireToken(token string, onReject func(r *http.Request, kind string)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subtle.ConstantTimeCompare([]byte(auth.TokenFromRequest(r)), []byte(token)) != 1 {
+				if onReject != nil {
+					onReject(r, models.AuditInvalidCredentials)
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				handler.WriteError(w, r, http.StatusUnauthorized, models.ErrCodeUnauthorized, "unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), auth.AdminTokenIdentity)))
+		})
+	}
+}
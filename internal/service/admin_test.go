@@ -0,0 +1,90 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/RoGogDBD/metric-alerter/internal/telemetry"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestNewAdminRouter проверяет маршруты административного слушателя и его собственный токен доступа.
+//
+// t — указатель на структуру теста.
+func TestNewAdminRouter(t *testing.T) {
+	a, err := auth.New(map[string]string{"adm": "admin", "agent": "writer"}, "")
+	require.NoError(t, err)
+	h := handler.NewHandler(repository.NewMemStorage(), nil)
+
+	tests := []struct {
+		name  string
+		opts  []RouterOption
+		path  string
+		token string
+		want  int
+	}{
+		{"status without auth", nil, "/status", "", http.StatusOK},
+		{"pprof index", nil, "/debug/pprof/", "", http.StatusOK},
+		{"admin routes served", nil, "/admin/storage-stats", "", http.StatusOK},
+		{"ingest not served", nil, "/updates/", "", http.StatusNotFound},
+		{"token required", []RouterOption{WithAdminToken("secret")}, "/status", "", http.StatusUnauthorized},
+		{"wrong token", []RouterOption{WithAdminToken("secret")}, "/status", "other", http.StatusUnauthorized},
+		{"valid token", []RouterOption{WithAdminToken("secret")}, "/admin/storage-stats", "secret", http.StatusOK},
+		{"token overrides roles", []RouterOption{WithAuth(a), WithAdminToken("secret")}, "/status", "secret", http.StatusOK},
+		{"admin role without token", []RouterOption{WithAuth(a)}, "/status", "adm", http.StatusOK},
+		{"writer role without token", []RouterOption{WithAuth(a)}, "/status", "agent", http.StatusForbidden},
+		{"runtime hidden without auth", nil, "/admin/runtime", "", http.StatusNotFound},
+		{"metrics hidden without telemetry", nil, "/metrics", "", http.StatusNotFound},
+		{"metrics with telemetry", []RouterOption{WithTelemetry(telemetry.New())}, "/metrics", "", http.StatusOK},
+		{"runtime with token", []RouterOption{WithAdminToken("secret")}, "/admin/runtime", "secret", http.StatusOK},
+		{"runtime with admin role", []RouterOption{WithAuth(a)}, "/admin/runtime", "adm", http.StatusOK},
+		{"health without token", []RouterOption{WithAdminToken("secret")}, "/healthz", "", http.StatusOK},
+		{"readiness without token", []RouterOption{WithAdminToken("secret")}, "/readyz", "", http.StatusServiceUnavailable},
+		{"config dump", []RouterOption{WithAdminToken("secret")}, "/admin/config", "secret", http.StatusOK},
+		{"config dump requires token", []RouterOption{WithAdminToken("secret")}, "/admin/config", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			NewAdminRouter(h, zap.NewNop(), tt.opts...).ServeHTTP(w, req)
+			require.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+// TestNewAdminRouter_Status проверяет содержимое ответа /status.
+//
+// t — указатель на структуру теста.
+func TestNewAdminRouter_Status(t *testing.T) {
+	storage := repository.NewMemStorage()
+	storage.SetGauge("g", 1)
+	storage.AddCounter("c", 2)
+
+	w := httptest.NewRecorder()
+	NewAdminRouter(handler.NewHandler(storage, nil), zap.NewNop()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var got struct {
+		Version    string `json:"version"`
+		Goroutines int    `json:"goroutines"`
+		Storage    struct {
+			Gauges   int `json:"gauges"`
+			Counters int `json:"counters"`
+		} `json:"storage"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.NotEmpty(t, got.Version)
+	require.Positive(t, got.Goroutines)
+	require.Equal(t, 1, got.Storage.Gauges)
+	require.Equal(t, 1, got.Storage.Counters)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// RequireRole возвращает middleware, пропускающий только запросы с ролью не ниже required.
+//
+// Токен берётся из заголовка Authorization: Bearer или X-API-Key. Без токена или с
+// недействительным токеном возвращается 401, при недостаточной роли — 403.
+// Об отказе сообщается через onReject (может быть nil) с типом события аудита. Идентификатор
+// пропущенного клиента (см. auth.Authenticator.Identify) добавляется в контекст запроса.
+// Если a равен nil (ролевой доступ отключён), запросы передаются без изменений.
+func RequireRole(a *auth.Authenticator, required auth.Role, onReject func(r *http.Request, kind string)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if a == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := auth.TokenFromRequest(r)
+			if err := a.Authorize(token, required); err != nil {
+				kind := models.AuditInvalidCredentials
+				if errors.Is(err, auth.ErrForbidden) {
+					kind = models.AuditForbidden
+				}
+				if onReject != nil {
+					onReject(r, kind)
+				}
+				if kind == models.AuditForbidden {
+					handler.WriteError(w, r, http.StatusForbidden, models.ErrCodeForbidden, "forbidden")
+					return
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				handler.WriteError(w, r, http.StatusUnauthorized, models.ErrCodeUnauthorized, "unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), a.Identify(token))))
+		})
+	}
+}
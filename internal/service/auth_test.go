@@ -0,0 +1,92 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestNewRouter_RoleBasedAccess проверяет разделение доступа к отправке, чтению и административным роутам.
+//
+// t — указатель на структуру теста.
+func TestNewRouter_RoleBasedAccess(t *testing.T) {
+	a, err := auth.New(map[string]string{"adm": "admin", "agent": "writer", "viewer": "reader"}, "")
+	require.NoError(t, err)
+
+	r := NewRouter(handler.NewHandler(repository.NewMemStorage(), nil), zap.NewNop(), WithAuth(a))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		token  string
+		want   int
+	}{
+		{"version is public", http.MethodGet, "/version", "", "", http.StatusOK},
+		{"ingest without token", http.MethodPost, "/update", `{"id":"m","type":"gauge","value":1}`, "", http.StatusUnauthorized},
+		{"ingest as writer", http.MethodPost, "/update", `{"id":"m","type":"gauge","value":1}`, "agent", http.StatusOK},
+		{"ingest as reader", http.MethodPost, "/update", `{"id":"m","type":"gauge","value":1}`, "viewer", http.StatusForbidden},
+		{"read as reader", http.MethodGet, "/value/gauge/m", "", "viewer", http.StatusOK},
+		{"admin as writer", http.MethodGet, "/admin/storage-stats", "", "agent", http.StatusForbidden},
+		{"admin as admin", http.MethodGet, "/admin/storage-stats", "", "adm", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+// TestRequireRole_OnReject проверяет, что об отказе в доступе сообщается с типом события аудита.
+//
+// t — указатель на структуру теста.
+func TestRequireRole_OnReject(t *testing.T) {
+	a, err := auth.New(map[string]string{"viewer": "reader"}, "")
+	require.NoError(t, err)
+
+	var kinds []string
+	mw := RequireRole(a, auth.RoleAdmin, func(_ *http.Request, kind string) { kinds = append(kinds, kind) })
+	next := mw(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+
+	for _, token := range []string{"unknown", "viewer"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/diagnostics", nil)
+		req.Header.Set(auth.APIKeyHeader, token)
+		next.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	require.Equal(t, []string{models.AuditInvalidCredentials, models.AuditForbidden}, kinds)
+}
+
+// TestRequireRole_Identity проверяет, что пропущенный запрос получает в контексте идентификатор клиента.
+//
+// t — указатель на структуру теста.
+func TestRequireRole_Identity(t *testing.T) {
+	a, err := auth.New(nil, "", auth.HashedKey{Name: "ci-writer", Hash: auth.HashKey("w"), Scopes: []string{"write-metrics"}})
+	require.NoError(t, err)
+
+	var identity string
+	next := RequireRole(a, auth.RoleWriter, nil)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		identity = auth.IdentityFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/updates/", nil)
+	req.Header.Set(auth.APIKeyHeader, "w")
+	next.ServeHTTP(httptest.NewRecorder(), req)
+	require.Equal(t, "key:ci-writer", identity)
+}
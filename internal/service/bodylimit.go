@@ -0,0 +1,41 @@
+package service
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// LimitBody возвращает middleware, ограничивающий тела запросов.
+//
+// Тело длиннее maxBytes байт отклоняется ответом 413: по заголовку Content-Length сразу,
+// а без него — при чтении (см. http.MaxBytesReader). Запрос с неразбираемым заголовком
+// Content-Type отклоняется ответом 400. Нулевой maxBytes отключает ограничение размера.
+// Степень распаковки тел gzip ограничивает DecompressRequest.
+func LimitBody(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ct := r.Header.Get("Content-Type"); ct != "" {
+				if _, _, err := mime.ParseMediaType(ct); err != nil {
+					handler.WriteError(w, r, http.StatusBadRequest, models.ErrCodeInvalidContentType, "malformed Content-Type")
+					return
+				}
+			}
+			if maxBytes > 0 {
+				if r.ContentLength > maxBytes {
+					writeBodyTooLarge(w, r)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeBodyTooLarge отвечает ошибкой body_too_large.
+func writeBodyTooLarge(w http.ResponseWriter, r *http.Request) {
+	handler.WriteError(w, r, http.StatusRequestEntityTooLarge, models.ErrCodeBodyTooLarge, "request body too large")
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestNewRouter_BodyLimit проверяет ограничение размера тела, отказ для неразбираемого
+// Content-Type и для тел gzip со слишком большой степенью распаковки.
+//
+// t — указатель на структуру теста.
+func TestNewRouter_BodyLimit(t *testing.T) {
+	gzipped := func(data []byte) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, _ = gz.Write(data)
+		_ = gz.Close()
+		return buf.Bytes()
+	}
+	metric := []byte(`{"id":"Alloc","type":"gauge","value":1.5}`)
+	padded := append(metric, bytes.Repeat([]byte(" "), 2048)...)
+
+	tests := []struct {
+		name        string // Название теста
+		body        []byte // Тело запроса
+		contentType string // Заголовок Content-Type
+		gzip        bool   // Тело сжато gzip
+		chunked     bool   // Длина тела не передаётся в Content-Length
+		wantStatus  int    // Ожидаемый код ответа
+		wantCode    string // Ожидаемый код ошибки
+	}{
+		{name: "Plain", body: metric, contentType: "application/json", wantStatus: http.StatusOK},
+		{name: "Gzip", body: gzipped(metric), contentType: "application/json", gzip: true, wantStatus: http.StatusOK},
+		{name: "TooLarge", body: padded, contentType: "application/json", wantStatus: http.StatusRequestEntityTooLarge, wantCode: models.ErrCodeBodyTooLarge},
+		{name: "TooLargeChunked", body: padded, contentType: "application/json", chunked: true, wantStatus: http.StatusRequestEntityTooLarge, wantCode: models.ErrCodeBodyTooLarge},
+		{name: "MalformedContentType", body: metric, contentType: "application/json; charset", wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeInvalidContentType},
+		{name: "GzipBomb", body: gzipped(append(metric, bytes.Repeat([]byte(" "), 1<<20)...)), contentType: "application/json", gzip: true, wantStatus: http.StatusRequestEntityTooLarge, wantCode: models.ErrCodeBodyTooLarge},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := handler.NewHandler(repository.NewMemStorage(), nil)
+			r := NewRouter(h, zap.NewNop(), WithBodyLimit(1024, 100))
+
+			var body io.Reader = bytes.NewReader(tc.body)
+			if tc.chunked {
+				body = io.MultiReader(body)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/update", body)
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+			req.Header.Set("Content-Type", tc.contentType)
+			if tc.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantCode != "" {
+				var resp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				require.Equal(t, tc.wantCode, resp.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Compress возвращает middleware, сжимающий ответы с уровнем cfg.Level.
+//
+// Ответы на пути из cfg.Exclude (например, /ping или потоковые ответы, которые буферизация
+// сжатия задержала бы) передаются без сжатия. Нулевой уровень заменяется на
+// config.DefaultCompressionLevel, отрицательный отключает сжатие.
+func Compress(cfg config.CompressionConfig) func(http.Handler) http.Handler {
+	if cfg.Level < 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	level := cfg.Level
+	if level == 0 {
+		level = config.DefaultCompressionLevel
+	}
+	compress := middleware.Compress(level)
+	return func(next http.Handler) http.Handler {
+		compressed := compress(next)
+		if len(cfg.Exclude) == 0 {
+			return compressed
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Excluded(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			compressed.ServeHTTP(w, r)
+		})
+	}
+}
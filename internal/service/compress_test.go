@@ -0,0 +1,50 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompress проверяет сжатие ответов, отключение сжатия и исключённые пути.
+func TestCompress(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.CompressionConfig
+		path     string
+		wantGzip bool
+	}{
+		{"default level", config.CompressionConfig{}, "/value", true},
+		{"custom level", config.CompressionConfig{Level: 9}, "/value", true},
+		{"disabled", config.CompressionConfig{Level: -1}, "/value", false},
+		{"excluded path", config.DefaultCompressionConfig(), "/ping", false},
+		{"excluded prefix", config.CompressionConfig{Exclude: []string{"/stream/*"}}, "/stream/metrics", false},
+		{"prefix does not match sibling", config.CompressionConfig{Exclude: []string{"/stream/*"}}, "/streams", true},
+	}
+	body := strings.Repeat(`{"id":"Alloc","type":"gauge","value":1}`, 100)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Compress(tt.cfg)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(body))
+			}))
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			if tt.wantGzip {
+				require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+				require.Less(t, rec.Body.Len(), len(body))
+			} else {
+				require.Empty(t, rec.Header().Get("Content-Encoding"))
+				require.Equal(t, body, rec.Body.String())
+			}
+		})
+	}
+}
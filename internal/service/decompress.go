@@ -0,0 +1,93 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// errGzipRatio возвращается gunzip, если тело распаковывается больше чем в допустимое число раз.
+var errGzipRatio = errors.New("gzip expansion ratio exceeded")
+
+// DecompressRequest возвращает middleware, передающий обработчикам тело запроса в исходном виде.
+//
+// Тело с заголовком X-Encrypted: true расшифровывается функцией decrypt (nil — не расшифровывается),
+// затем тело с Content-Encoding: gzip распаковывается, а заголовки X-Encrypted и Content-Encoding
+// удаляются. Поэтому обработчики всегда получают JSON без сжатия, и подпись HMAC проверяется
+// по несжатому телу независимо от способа передачи.
+//
+// Тело, распаковка которого даёт больше maxGzipRatio байт на байт сжатых данных, отклоняется
+// ответом 413; распаковка останавливается на первом лишнем байте, поэтому «gzip-бомба» не
+// разворачивается в памяти. Нулевой maxGzipRatio отключает проверку.
+func DecompressRequest(maxGzipRatio int, decrypt func([]byte) ([]byte, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encrypted := decrypt != nil && r.Header.Get("X-Encrypted") == "true"
+			gzipped := r.Header.Get("Content-Encoding") == "gzip"
+			if (!encrypted && !gzipped) || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeBodyTooLarge(w, r)
+					return
+				}
+				handler.WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "failed to read body")
+				return
+			}
+			if encrypted {
+				if body, err = decrypt(body); err != nil {
+					handler.WriteError(w, r, http.StatusBadRequest, models.ErrCodeDecryptFailed, "failed to decrypt data")
+					return
+				}
+				r.Header.Del("X-Encrypted")
+			}
+			if gzipped {
+				body, err = gunzip(body, maxGzipRatio)
+				if errors.Is(err, errGzipRatio) {
+					handler.WriteError(w, r, http.StatusRequestEntityTooLarge, models.ErrCodeBodyTooLarge, "gzip expansion ratio exceeded")
+					return
+				}
+				if err != nil {
+					handler.WriteError(w, r, http.StatusBadRequest, models.ErrCodeBadRequest, "failed to decompress body")
+					return
+				}
+				r.Header.Del("Content-Encoding")
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// gunzip распаковывает body. Если ratio больше нуля и данные распаковываются больше чем
+// в ratio раз, возвращает errGzipRatio.
+func gunzip(body []byte, ratio int) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	if ratio <= 0 {
+		return io.ReadAll(gz)
+	}
+	limit := int64(len(body)) * int64(ratio)
+	plain, err := io.ReadAll(io.LimitReader(gz, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(plain)) > limit {
+		return nil, errGzipRatio
+	}
+	return plain, nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/crypto"
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestNewRouter_DecompressRequest проверяет, что подпись HMAC проверяется по несжатому телу
+// для сжатых и зашифрованных батчей, а подпись сжатого тела отклоняется.
+//
+// t — указатель на структуру теста.
+func TestNewRouter_DecompressRequest(t *testing.T) {
+	const key = "secret"
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	plain := []byte(`[{"id":"PollCount","type":"counter","delta":3}]`)
+	compressed := gzipData(t, plain)
+	encrypted, err := crypto.EncryptData(compressed, &privateKey.PublicKey)
+	require.NoError(t, err)
+	sign := func(data []byte) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write(data)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name       string // Название теста
+		body       []byte // Тело запроса
+		hash       string // Заголовок HashSHA256
+		gzip       bool   // Тело сжато gzip
+		encrypted  bool   // Тело зашифровано
+		wantStatus int    // Ожидаемый код ответа
+		wantCode   string // Ожидаемый код ошибки
+	}{
+		{name: "Plain", body: plain, hash: sign(plain), wantStatus: http.StatusOK},
+		{name: "Gzip", body: compressed, hash: sign(plain), gzip: true, wantStatus: http.StatusOK},
+		{name: "GzipEncrypted", body: encrypted, hash: sign(plain), gzip: true, encrypted: true, wantStatus: http.StatusOK},
+		{name: "SignedCompressed", body: compressed, hash: sign(compressed), gzip: true, wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeInvalidSignature},
+		{name: "NotGzip", body: plain, hash: sign(plain), gzip: true, wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeBadRequest},
+		{name: "DecryptFailed", body: compressed, hash: sign(plain), gzip: true, encrypted: true, wantStatus: http.StatusBadRequest, wantCode: models.ErrCodeDecryptFailed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := handler.NewHandler(repository.NewMemStorage(), nil)
+			h.SetKey(key)
+			h.SetCryptoKey(privateKey)
+			r := NewRouter(h, zap.NewNop())
+
+			req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("HashSHA256", tc.hash)
+			if tc.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+			if tc.encrypted {
+				req.Header.Set("X-Encrypted", "true")
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantCode != "" {
+				var resp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				require.Equal(t, tc.wantCode, resp.Code)
+			}
+		})
+	}
+}
+
+// TestGunzip проверяет распаковку, ограничение степени распаковки и отказ для данных не в gzip.
+//
+// t — указатель на структуру теста.
+func TestGunzip(t *testing.T) {
+	data := []byte(strings.Repeat("a", 10000))
+	compressed := gzipData(t, data)
+
+	plain, err := gunzip(compressed, 1000)
+	require.NoError(t, err)
+	require.Equal(t, data, plain)
+
+	plain, err = gunzip(compressed, 0)
+	require.NoError(t, err)
+	require.Equal(t, data, plain)
+
+	_, err = gunzip(compressed, 10)
+	require.ErrorIs(t, err, errGzipRatio)
+
+	_, err = gunzip([]byte("not gzip"), 10)
+	require.Error(t, err)
+}
+
+// gzipData сжимает data gzip.
+func gzipData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
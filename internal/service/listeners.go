@@ -0,0 +1,49 @@
+package service
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+)
+
+// RouteGroup возвращает группу маршрутов (config.RouteGroup*), к которой относится путь запроса.
+//
+// Пустая строка означает служебный маршрут (/ping, /version, /api/v1/schema, проверки здоровья),
+// доступный на любом слушателе.
+func RouteGroup(path string) string {
+	switch {
+	case path == "/ping" || path == "/version" || path == "/api/v1/schema" ||
+		path == "/healthz" || path == "/livez" || path == "/readyz":
+		return ""
+	case strings.HasPrefix(path, "/admin/") || strings.HasPrefix(path, "/debug/pprof/") ||
+		path == "/status" || path == "/metrics" || path == "/import":
+		return config.RouteGroupAdmin
+	case path == "/update" || strings.HasPrefix(path, "/update/") ||
+		path == "/updates/" || path == "/api/v1/enroll" || strings.HasPrefix(path, "/api/v1/dictionaries/"):
+		return config.RouteGroupIngest
+	default:
+		return config.RouteGroupRead
+	}
+}
+
+// RestrictRoutes возвращает middleware, отвечающий 404 на маршруты вне групп groups.
+//
+// Используется для разделения слушателей, например административного порта и публичного
+// порта приёма метрик. Пустой groups разрешает все маршруты.
+func RestrictRoutes(groups []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(groups) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if g := RouteGroup(r.URL.Path); g != "" && !slices.Contains(groups, g) {
+				handler.HandleNotFound(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
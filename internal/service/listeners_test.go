@@ -0,0 +1,45 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRestrictRoutes проверяет, что слушатель обслуживает только свои группы маршрутов.
+//
+// t — указатель на структуру теста.
+func TestRestrictRoutes(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+
+	tests := []struct {
+		name   string
+		groups []string
+		path   string
+		want   int
+	}{
+		{"all groups by default", nil, "/admin/diagnostics", http.StatusOK},
+		{"ingest on public port", []string{config.RouteGroupIngest, config.RouteGroupRead}, "/updates/", http.StatusOK},
+		{"read on public port", []string{config.RouteGroupIngest, config.RouteGroupRead}, "/value/gauge/m", http.StatusOK},
+		{"admin hidden on public port", []string{config.RouteGroupIngest, config.RouteGroupRead}, "/admin/diagnostics", http.StatusNotFound},
+		{"ingest hidden on admin port", []string{config.RouteGroupAdmin}, "/update/gauge/m/1", http.StatusNotFound},
+		{"ping on admin port", []string{config.RouteGroupAdmin}, "/ping", http.StatusOK},
+		{"schema on admin port", []string{config.RouteGroupAdmin}, "/api/v1/schema", http.StatusOK},
+		{"readyz on admin port", []string{config.RouteGroupAdmin}, "/readyz", http.StatusOK},
+		{"livez on public port", []string{config.RouteGroupIngest, config.RouteGroupRead}, "/livez", http.StatusOK},
+		{"pprof hidden on public port", []string{config.RouteGroupIngest, config.RouteGroupRead}, "/debug/pprof/", http.StatusNotFound},
+		{"status hidden on public port", []string{config.RouteGroupIngest, config.RouteGroupRead}, "/status", http.StatusNotFound},
+		{"metrics hidden on public port", []string{config.RouteGroupIngest, config.RouteGroupRead}, "/metrics", http.StatusNotFound},
+		{"import hidden on public port", []string{config.RouteGroupIngest, config.RouteGroupRead}, "/import", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			RestrictRoutes(tt.groups)(ok).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			require.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/requestid"
+	"github.com/RoGogDBD/metric-alerter/internal/telemetry"
+)
+
+// RouterOption настраивает роутер, создаваемый NewRouter.
+type RouterOption func(*routerOptions)
+
+// routerOptions содержит необязательные настройки роутера.
+//
+// Поля:
+//   - securityHeaders: заголовки безопасности для HTML-страниц
+//   - auth: проверка ролей клиентов (nil — ролевой доступ отключён)
+//   - adminToken: токен административного слушателя (пусто — используется ролевой доступ)
+//   - telemetry: собственные метрики сервера (nil — не собираются)
+//   - tracing: спаны OpenTelemetry на каждый запрос
+//   - requestID: генератор идентификаторов запросов
+//   - compression: сжатие ответов
+//   - requestLog: фильтрация и выборка журнала запросов
+//   - rateLimit: ограничение частоты запросов клиентов (nil — не ограничивается)
+//   - maxBodyBytes: максимальный размер тела запроса (0 — не ограничивается)
+//   - maxGzipRatio: максимальная степень распаковки тела gzip (0 — не ограничивается)
+type routerOptions struct {
+	securityHeaders config.SecurityHeadersConfig
+	compression     config.CompressionConfig
+	rateLimit       *RateLimiter
+	maxBodyBytes    int64
+	maxGzipRatio    int
+	auth            *auth.Authenticator
+	adminToken      string
+	telemetry       *telemetry.Metrics
+	tracing         bool
+	requestID       requestid.Generator
+	requestLog      config.LoggerConfig
+}
+
+// defaultRouterOptions возвращает настройки роутера по умолчанию.
+func defaultRouterOptions() routerOptions {
+	return routerOptions{
+		securityHeaders: config.DefaultSecurityHeadersConfig(),
+		compression:     config.DefaultCompressionConfig(),
+		requestID:       requestid.ULID,
+	}
+}
+
+// WithSecurityHeaders задаёт заголовки безопасности для HTML-страниц.
+func WithSecurityHeaders(cfg config.SecurityHeadersConfig) RouterOption {
+	return func(o *routerOptions) {
+		o.securityHeaders = cfg
+	}
+}
+
+// WithRequestLog задаёт пути, запросы к которым не журналируются или журналируются выборочно
+// (см. config.RequestLogger).
+func WithRequestLog(cfg config.LoggerConfig) RouterOption {
+	return func(o *routerOptions) {
+		o.requestLog = cfg
+	}
+}
+
+// WithCompression задаёт уровень сжатия ответов и пути, ответы на которые не сжимаются.
+func WithCompression(cfg config.CompressionConfig) RouterOption {
+	return func(o *routerOptions) {
+		o.compression = cfg
+	}
+}
+
+// WithRateLimit включает ограничение частоты запросов каждого клиента (см. RateLimit).
+func WithRateLimit(l *RateLimiter) RouterOption {
+	return func(o *routerOptions) {
+		o.rateLimit = l
+	}
+}
+
+// WithBodyLimit ограничивает размер тел запросов (см. LimitBody) и степень распаковки тел gzip
+// (см. DecompressRequest).
+func WithBodyLimit(maxBytes int64, maxGzipRatio int) RouterOption {
+	return func(o *routerOptions) {
+		o.maxBodyBytes = maxBytes
+		o.maxGzipRatio = maxGzipRatio
+	}
+}
+
+// WithAuth включает ролевой доступ: чтение метрик требует роли reader,
+// отправка — writer, административные обработчики — admin.
+func WithAuth(a *auth.Authenticator) RouterOption {
+	return func(o *routerOptions) {
+		o.auth = a
+	}
+}
+
+// WithAdminToken задаёт отдельный токен доступа к административному слушателю (см. NewAdminRouter).
+func WithAdminToken(token string) RouterOption {
+	return func(o *routerOptions) {
+		o.adminToken = token
+	}
+}
+
+// WithTelemetry включает собственные метрики сервера: NewRouter измеряет задержку обработчиков
+// по маршрутам, NewAdminRouter отдаёт метрики в формате Prometheus на /metrics.
+func WithTelemetry(m *telemetry.Metrics) RouterOption {
+	return func(o *routerOptions) {
+		o.telemetry = m
+	}
+}
+
+// WithTracing включает спаны OpenTelemetry на каждый запрос основного роутера (см. tracing.Middleware).
+func WithTracing(enabled bool) RouterOption {
+	return func(o *routerOptions) {
+		o.tracing = enabled
+	}
+}
+
+// WithRequestID задаёт генератор идентификаторов запросов (по умолчанию ULID).
+func WithRequestID(gen requestid.Generator) RouterOption {
+	return func(o *routerOptions) {
+		o.requestID = gen
+	}
+}
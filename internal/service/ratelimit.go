@@ -0,0 +1,139 @@
+package service
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+)
+
+// rateLimitSweepInterval — период удаления корзин клиентов, которые успели заполниться.
+const rateLimitSweepInterval = time.Minute
+
+type (
+	// RateLimiter ограничивает частоту запросов каждого клиента алгоритмом token bucket.
+	//
+	// Клиент определяется по действительному API-ключу или токену (заголовки Authorization: Bearer
+	// и X-API-Key), а без них или с непроверенным токеном — по IP-адресу. Корзины простаивающих клиентов удаляются, так как заполненная
+	// корзина неотличима от новой.
+	//
+	// Поля:
+	//   - rate: маркеров в секунду
+	//   - burst: ёмкость корзины
+	//   - buckets: корзины клиентов
+	//   - lastSweep: время последнего удаления заполненных корзин
+	//   - now: функция получения текущего времени
+	//   - mu: мьютекс для доступа к корзинам
+	RateLimiter struct {
+		rate      float64
+		burst     float64
+		buckets   map[string]*clientBucket
+		lastSweep time.Time
+		now       func() time.Time
+		mu        sync.Mutex
+	}
+
+	// clientBucket — корзина маркеров одного клиента.
+	clientBucket struct {
+		tokens float64
+		last   time.Time
+	}
+)
+
+// NewRateLimiter создаёт ограничение rps (больше нуля) запросов в секунду на клиента со всплеском
+// до burst запросов. burst меньше 1 заменяется на rps (но не меньше 1).
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = max(int(math.Ceil(rps)), 1)
+	}
+	return &RateLimiter{
+		rate:    rps,
+		burst:   float64(burst),
+		buckets: make(map[string]*clientBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow забирает маркер клиента key. Если маркера нет, возвращает false и время до его появления.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &clientBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(l.burst, b.tokens+elapsed.Seconds()*l.rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep удаляет корзины, заполнившиеся после последнего запроса клиента. Вызывается под l.mu.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// clientKey возвращает ключ клиента запроса: идентификатор клиента с токеном, прошедшим
+// проверку a (см. auth.Authenticator.Identify), или IP-адрес.
+//
+// Непроверенный токен не даёт отдельной корзины: иначе клиент обходил бы ограничение,
+// меняя заголовок Authorization в каждом запросе. Идентификатор вместо токена не оставляет
+// секреты в памяти ограничителя.
+func clientKey(r *http.Request, a *auth.Authenticator) string {
+	if id := a.Identify(auth.TokenFromRequest(r)); id != "" {
+		return "auth:" + id
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return "ip:" + host
+	}
+	return "ip:" + r.RemoteAddr
+}
+
+// RateLimit возвращает middleware, отклоняющий запросы клиента сверх ограничения l
+// ответом 429 с заголовком Retry-After.
+//
+// Клиенты с токеном, прошедшим проверку a, учитываются отдельно от своего IP-адреса (a может быть nil).
+// Об отказе сообщается через onReject (может быть nil) с типом события аудита AuditRateLimited.
+// Если l равен nil, запросы передаются без изменений.
+func RateLimit(l *RateLimiter, a *auth.Authenticator, onReject func(r *http.Request, kind string)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, wait := l.Allow(clientKey(r, a))
+			if ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if onReject != nil {
+				onReject(r, models.AuditRateLimited)
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+			handler.WriteError(w, r, http.StatusTooManyRequests, models.ErrCodeRateLimited, "rate limit exceeded")
+		})
+	}
+}
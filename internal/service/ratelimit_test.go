@@ -0,0 +1,98 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
+	"github.com/RoGogDBD/metric-alerter/internal/handler"
+	models "github.com/RoGogDBD/metric-alerter/internal/model"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestRateLimiter_Allow проверяет всплеск, пополнение корзины и время до следующего маркера.
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("a")
+		require.True(t, ok, "request %d", i)
+	}
+	ok, wait := l.Allow("a")
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, wait)
+
+	ok, _ = l.Allow("b")
+	require.True(t, ok, "clients have separate buckets")
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = l.Allow("a")
+	require.True(t, ok)
+
+	now = now.Add(2 * rateLimitSweepInterval)
+	_, _ = l.Allow("a")
+	require.Len(t, l.buckets, 1, "refilled bucket of b is swept")
+}
+
+// TestNewRouter_RateLimit проверяет ответ 429 с Retry-After, раздельный учёт клиентов по IP
+// и действительному API-ключу и событие аудита об отказе.
+//
+// t — указатель на структуру теста.
+func TestNewRouter_RateLimit(t *testing.T) {
+	h := handler.NewHandler(repository.NewMemStorage(), nil)
+	audit := &rejectionRecorder{}
+	h.SetAuditManager(audit)
+	a, err := auth.New(map[string]string{"key-1": "reader"}, "")
+	require.NoError(t, err)
+	r := NewRouter(h, zap.NewNop(), WithRateLimit(NewRateLimiter(1, 2)), WithAuth(a))
+
+	do := func(remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/version", nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, do("10.0.0.1:1000", "").Code)
+	require.Equal(t, http.StatusOK, do("10.0.0.1:1001", "").Code)
+	rec := do("10.0.0.1:1002", "")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "1", rec.Header().Get("Retry-After"))
+	var resp models.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, models.ErrCodeRateLimited, resp.Code)
+	require.True(t, resp.Retryable)
+	require.Equal(t, []string{models.AuditRateLimited}, audit.kinds)
+
+	require.Equal(t, http.StatusOK, do("10.0.0.2:1000", "").Code)
+	require.Equal(t, http.StatusOK, do("10.0.0.1:1003", "key-1").Code, "API key has its own bucket")
+
+	// Непроверенные токены учитываются по IP: смена токена не даёт новой корзины.
+	for i := 0; i < 3; i++ {
+		rec := do("10.0.0.1:1004", "bogus-"+strconv.Itoa(i))
+		require.Equal(t, http.StatusTooManyRequests, rec.Code, "bogus token %d", i)
+	}
+}
+
+// rejectionRecorder запоминает типы событий аудита.
+type rejectionRecorder struct {
+	kinds []string
+}
+
+func (a *rejectionRecorder) Attach(models.AuditObserver) {}
+func (a *rejectionRecorder) Detach(models.AuditObserver) {}
+func (a *rejectionRecorder) Notify(event models.AuditEvent) {
+	a.kinds = append(a.kinds, event.Event)
+}
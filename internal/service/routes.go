@@ -1,76 +1,122 @@
 package service
 
 import (
-	"log"
-	"net/http"
-	"time"
-
+	"github.com/RoGogDBD/metric-alerter/internal/auth"
 	"github.com/RoGogDBD/metric-alerter/internal/config"
 	"github.com/RoGogDBD/metric-alerter/internal/handler"
-	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/RoGogDBD/metric-alerter/internal/requestid"
+	"github.com/RoGogDBD/metric-alerter/internal/tracing"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
 )
 
 // NewRouter создает и настраивает HTTP-роутер для сервиса метрик.
-// В зависимости от значения storeInterval, роутер либо сохраняет метрики в файл после каждого обновления,
-// либо запускает отдельную горутину для периодического сохранения метрик.
+// Запись метрик во внешние хранилища (база данных, файл снимка) выполняет обработчик
+// (см. handler.Handler.SetFanOut).
 //
 // Параметры:
 //   - h: обработчик запросов (handler.Handler)
-//   - storage: хранилище метрик (repository.Storage)
-//   - storeInterval: интервал сохранения метрик в файл (в секундах); если 0 — сохраняет после каждого обновления
-//   - filePath: путь к файлу для сохранения метрик
 //   - logger: логгер для логирования запросов
+//   - opts: необязательные настройки роутера (RouterOption)
 //
 // Возвращает:
 //   - *chi.Mux: настроенный роутер
-func NewRouter(h *handler.Handler, storage repository.Storage, storeInterval int, filePath string, logger *zap.Logger) *chi.Mux {
+func NewRouter(h *handler.Handler, logger *zap.Logger, opts ...RouterOption) *chi.Mux {
+	o := defaultRouterOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	r := chi.NewRouter()
-	r.Use(middleware.RequestID)         // Добавляет уникальный идентификатор запроса
-	r.Use(middleware.RealIP)            // Определяет реальный IP клиента
-	r.Use(config.RequestLogger(logger)) // Логирует запросы с помощью zap
-	r.Use(middleware.Recoverer)         // Восстанавливает после паники
-	r.Use(middleware.Compress(5))       // Сжимает ответы
+	r.Use(requestid.Middleware(o.requestID))                // Добавляет уникальный идентификатор запроса
+	r.Use(middleware.RealIP)                                // Определяет реальный IP клиента
+	r.Use(config.RequestLogger(logger, o.requestLog))       // Логирует запросы с помощью zap
+	r.Use(middleware.Recoverer)                             // Восстанавливает после паники
+	r.Use(RateLimit(o.rateLimit, o.auth, h.AuditRejection)) // Ограничивает частоту запросов клиента
+	r.Use(LimitBody(o.maxBodyBytes))                        // Ограничивает размер тел запросов
+	r.Use(DecompressRequest(o.maxGzipRatio, h.DecryptBody)) // Расшифровывает и распаковывает тела запросов
+	r.Use(Compress(o.compression))                          // Сжимает ответы, кроме исключённых путей
+	if o.tracing {
+		r.Use(tracing.Middleware) // Начинает спан OpenTelemetry, продолжающий трассу клиента
+	}
+	if o.telemetry != nil {
+		r.Use(o.telemetry.Middleware) // Измеряет задержку обработчиков по маршрутам
+	}
+	r.NotFound(handler.HandleNotFound)
+	r.MethodNotAllowed(handler.HandleMethodNotAllowed)
+
+	// Открытые роуты: проверки доступности и готовности, версия, схема API, регистрация агентов по одноразовому токену.
+	r.Get("/ping", h.HandlePing)
+	r.Get("/healthz", h.HandleHealthz)
+	r.Get("/livez", h.HandleLivez)
+	r.Get("/readyz", h.HandleReadyz)
+	r.Get("/version", h.HandleVersion)
+	r.Get("/api/v1/schema", h.HandleSchema)
+	r.Post("/api/v1/enroll", h.HandleEnroll)
+	r.Get(handler.RefreshScriptPath, h.HandleRefreshScript)
 
-	if storeInterval == 0 {
-		// Если storeInterval == 0, сохраняет метрики в файл после каждого обновления
-		r.Post("/update", func(w http.ResponseWriter, r *http.Request) {
-			h.HandleUpdateJSON(w, r)
-			if err := repository.SaveMetricsToFile(storage, filePath); err != nil {
-				log.Printf("Failed to save metrics: %v", err)
-			}
-		})
-		r.Post("/update/", func(w http.ResponseWriter, r *http.Request) {
-			h.HandleUpdateJSON(w, r)
-			if err := repository.SaveMetricsToFile(storage, filePath); err != nil {
-				log.Printf("Failed to save metrics: %v", err)
-			}
-		})
-	} else {
-		// Если storeInterval > 0, запускает периодическое сохранение метрик в отдельной горутине
-		go func() {
-			ticker := time.NewTicker(time.Duration(storeInterval) * time.Second)
-			defer ticker.Stop()
-			for range ticker.C {
-				if err := repository.SaveMetricsToFile(storage, filePath); err != nil {
-					log.Printf("Failed to save metrics: %v", err)
-				}
-			}
-		}()
+	// Отправка метрик (роль writer).
+	r.Group(func(r chi.Router) {
+		r.Use(RequireRole(o.auth, auth.RoleWriter, h.AuditRejection))
 		r.Post("/update", h.HandleUpdateJSON)
 		r.Post("/update/", h.HandleUpdateJSON)
-	}
+		r.Post("/update/{type}/{name}/{value}", h.HandleUpdate)
+		r.Post("/updates/", h.HandlerUpdateBatchJSON)
+		r.Put("/api/v1/dictionaries/{id}", h.HandleRegisterDictionary)
+	})
 
-	// Роуты для получения и обновления метрик
-	r.Post("/value", h.HandleGetMetricJSON)
-	r.Post("/value/", h.HandleGetMetricJSON)
-	r.Post("/update/{type}/{name}/{value}", h.HandleUpdate)
-	r.Post("/updates/", h.HandlerUpdateBatchJSON)
-	r.Get("/value/{type}/{name}", h.HandleGetMetricValue)
-	r.Get("/ping", h.HandlePing)
-	r.Get("/", h.HandleMetricsPage)
+	// Чтение метрик (роль reader).
+	r.Group(func(r chi.Router) {
+		r.Use(RequireRole(o.auth, auth.RoleReader, h.AuditRejection))
+		r.Post("/value", h.HandleGetMetricJSON)
+		r.Post("/value/", h.HandleGetMetricJSON)
+		r.Get("/value/{type}/{name}", h.HandleGetMetricValue)
+		r.Post("/values/", h.HandleGetMetricsJSON)
+		r.With(SecurityHeaders(o.securityHeaders)).Get("/", h.HandleMetricsPage)
+		r.Get("/api/v1/cardinality", h.HandleCardinality)
+		r.Get("/api/v1/metrics", h.HandleMetricsList)
+		r.Get("/api/metrics", h.HandleMetricsQuery)
+		r.Get("/api/v1/diff", h.HandleMetricsDiff)
+		r.Get("/export", h.HandleExport)
+	})
+
+	// Административные операции (роль admin).
+	r.Group(func(r chi.Router) {
+		r.Use(RequireRole(o.auth, auth.RoleAdmin, h.AuditRejection))
+		registerAdminRoutes(r, h)
+		if o.auth != nil {
+			registerRuntimeRoutes(r, h)
+		}
+	})
 
 	return r
 }
+
+// registerAdminRoutes регистрирует административные обработчики /admin/*.
+func registerAdminRoutes(r chi.Router, h *handler.Handler) {
+	r.Get("/admin/config", h.HandleConfig)
+	r.Get("/admin/diagnostics", h.HandleDiagnostics)
+	r.Get("/admin/storage-stats", h.HandleStorageStats)
+	r.Get("/admin/agents", h.HandleAgents)
+	r.Get("/admin/dead-letters", h.HandleDeadLetters)
+	r.Get("/admin/counters/{name}/sources", h.HandleCounterSources)
+}
+
+// registerRuntimeRoutes регистрирует /admin/runtime — изменение настроек без перезапуска,
+// /admin/dead-letters/replay — повторную доставку недоставленных событий аудита,
+// /admin/backup — резервное копирование вне расписания,
+// исправление вкладов источников в счётчики /admin/counters/{name}/sources/{source}
+// и загрузку снимка метрик /import.
+//
+// Маршруты меняют поведение сервера, поэтому регистрируются только там, где административный
+// доступ требует аутентификации (ролевой доступ или токен административного слушателя).
+func registerRuntimeRoutes(r chi.Router, h *handler.Handler) {
+	r.Get("/admin/runtime", h.HandleRuntime)
+	r.Patch("/admin/runtime", h.HandleRuntimeUpdate)
+	r.Post("/admin/dead-letters/replay", h.HandleDeadLettersReplay)
+	r.Post("/admin/backup", h.HandleBackup)
+	r.Patch("/admin/counters/{name}/sources/{source}", h.HandleCounterSourceAdjust)
+	r.Delete("/admin/counters/{name}/sources/{source}", h.HandleCounterSourceExclude)
+	r.Post("/import", h.HandleImport)
+}
@@ -16,8 +16,8 @@ import (
 )
 
 // TestNewRouter_TableDriven выполняет параметризованный тест для функции NewRouter.
-// Проверяет, что при storeInterval == 0 метрики сохраняются в файл после каждого POST-запроса,
-// а при storeInterval > 0 — сохранение происходит периодически, и файл не создаётся немедленно.
+// Проверяет, что при синхронной записи в файл метрики сохраняются после каждого POST-запроса,
+// а при асинхронной — сохранение происходит периодически, и файл не создаётся немедленно.
 //
 // Для каждого случая тестируются основные маршруты: /update, /value, /ping, /.
 // После POST-запроса на /update дополнительно проверяется, был ли создан файл метрик.
@@ -29,21 +29,23 @@ func TestNewRouter_TableDriven(t *testing.T) {
 
 	tests := []struct {
 		name             string // Название теста
-		storeInterval    int    // Интервал сохранения метрик
+		async            bool   // Асинхронная запись в файл
 		expectSaveOnPost bool   // Ожидается ли сохранение метрик сразу после POST
 	}{
-		{"interval zero: save on POST", 0, true},
-		{"interval non-zero: no immediate save on POST", 5, false},
+		{"sync: save on POST", false, true},
+		{"async: no immediate save on POST", true, false},
 	}
 
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 
-			storage := repository.NewMemStorage()                       // Инициализация in-memory хранилища метрик
-			h := handler.NewHandler(storage, nil)                       // Создание обработчика с хранилищем
-			logger := zap.NewNop()                                      // "Пустой" логгер для теста
-			r := NewRouter(h, storage, tt.storeInterval, fpath, logger) // Создание роутера
+			storage := repository.NewMemStorage()                // Инициализация in-memory хранилища метрик
+			h := handler.NewHandler(storage, nil)                // Создание обработчика с хранилищем
+			logger := zap.NewNop()                               // "Пустой" логгер для теста
+			saver := repository.NewSnapshotSaver(storage, fpath) // Сохранение снимков метрик
+			h.SetFanOut(repository.NewFanOut(repository.FanOutBackend{Name: "file", Sink: saver, Async: tt.async, Interval: time.Hour}))
+			r := NewRouter(h, logger) // Создание роутера
 
 			// Набор тестовых HTTP-запросов для проверки основных маршрутов
 			cases := []struct {
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+)
+
+// SecurityHeaders возвращает middleware, устанавливающий заголовки безопасности для HTML-страниц.
+//
+// Устанавливает Content-Security-Policy, X-Content-Type-Options и X-Frame-Options,
+// а для запросов по TLS (в том числе через прокси с X-Forwarded-Proto: https) — Strict-Transport-Security.
+// Если cfg.Enabled равно false, запросы передаются без изменений.
+func SecurityHeaders(cfg config.SecurityHeadersConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !cfg.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hdr := w.Header()
+			hdr.Set("X-Content-Type-Options", "nosniff")
+			if cfg.ContentSecurityPolicy != "" {
+				hdr.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+			}
+			if cfg.FrameOptions != "" {
+				hdr.Set("X-Frame-Options", cfg.FrameOptions)
+			}
+			if cfg.HSTSMaxAge > 0 && isTLSRequest(r) {
+				hdr.Set("Strict-Transport-Security",
+					"max-age="+strconv.FormatInt(int64(cfg.HSTSMaxAge.Seconds()), 10)+"; includeSubDomains")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isTLSRequest определяет, пришёл ли запрос по TLS напрямую или через TLS-терминирующий прокси.
+func isTLSRequest(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.SecurityHeadersConfig
+		tls       bool
+		forwarded string
+		wantCSP   string
+		wantFrame string
+		wantHSTS  string
+	}{
+		{
+			name:      "defaults over http",
+			cfg:       config.DefaultSecurityHeadersConfig(),
+			wantCSP:   config.DefaultContentSecurityPolicy,
+			wantFrame: config.DefaultFrameOptions,
+		},
+		{
+			name:      "defaults over tls",
+			cfg:       config.DefaultSecurityHeadersConfig(),
+			tls:       true,
+			wantCSP:   config.DefaultContentSecurityPolicy,
+			wantFrame: config.DefaultFrameOptions,
+			wantHSTS:  "max-age=31536000; includeSubDomains",
+		},
+		{
+			name:      "forwarded https",
+			cfg:       config.DefaultSecurityHeadersConfig(),
+			forwarded: "https",
+			wantCSP:   config.DefaultContentSecurityPolicy,
+			wantFrame: config.DefaultFrameOptions,
+			wantHSTS:  "max-age=31536000; includeSubDomains",
+		},
+		{
+			name: "custom policy",
+			cfg: config.SecurityHeadersConfig{
+				Enabled:               true,
+				ContentSecurityPolicy: "default-src 'none'",
+			},
+			tls:     true,
+			wantCSP: "default-src 'none'",
+		},
+		{
+			name: "disabled",
+			cfg:  config.SecurityHeadersConfig{},
+			tls:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := SecurityHeaders(tt.cfg)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.forwarded)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, tt.wantCSP, rec.Header().Get("Content-Security-Policy"))
+			require.Equal(t, tt.wantFrame, rec.Header().Get("X-Frame-Options"))
+			require.Equal(t, tt.wantHSTS, rec.Header().Get("Strict-Transport-Security"))
+			if tt.cfg.Enabled {
+				require.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+			} else {
+				require.Empty(t, rec.Header().Get("X-Content-Type-Options"))
+			}
+		})
+	}
+}
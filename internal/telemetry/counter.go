@@ -0,0 +1,94 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// labelSeparator разделяет значения меток в ключе CounterVec; не встречается в UTF-8.
+const labelSeparator = "\xff"
+
+type (
+	// Counter — монотонно возрастающий счётчик.
+	Counter struct {
+		values []string
+		n      atomic.Uint64
+	}
+
+	// CounterVec — семейство счётчиков, различающихся значениями меток.
+	//
+	// Поля:
+	//   - name: имя метрики в формате Prometheus
+	//   - help: описание метрики
+	//   - labels: имена меток
+	//   - byValues: счётчики по значениям меток (ключ — значения через labelSeparator)
+	CounterVec struct {
+		name     string
+		help     string
+		labels   []string
+		mu       sync.RWMutex
+		byValues map[string]*Counter
+	}
+)
+
+// Inc увеличивает счётчик на единицу.
+func (c *Counter) Inc() {
+	c.n.Add(1)
+}
+
+// NewCounterVec создаёт семейство счётчиков name с метками labels.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{name: name, help: help, labels: labels, byValues: make(map[string]*Counter)}
+}
+
+// With возвращает счётчик для значений меток values (в порядке labels), создавая его
+// при первом обращении.
+func (v *CounterVec) With(values ...string) *Counter {
+	key := strings.Join(values, labelSeparator)
+	v.mu.RLock()
+	c, ok := v.byValues[key]
+	v.mu.RUnlock()
+	if ok {
+		return c
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok = v.byValues[key]; !ok {
+		c = &Counter{values: append([]string(nil), values...)}
+		v.byValues[key] = c
+	}
+	return c
+}
+
+// WriteText записывает семейство в текстовом формате экспозиции Prometheus.
+//
+// Счётчики выводятся по возрастанию значений меток.
+func (v *CounterVec) WriteText(w io.Writer) error {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.byValues))
+	for key := range v.byValues {
+		keys = append(keys, key)
+	}
+	counters := make([]*Counter, len(keys))
+	sort.Strings(keys)
+	for i, key := range keys {
+		counters[i] = v.byValues[key]
+	}
+	v.mu.RUnlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", v.name, v.help, v.name)
+	for _, c := range counters {
+		pairs := make([]string, len(v.labels))
+		for i, label := range v.labels {
+			pairs[i] = label + `="` + escapeLabel(c.values[i]) + `"`
+		}
+		fmt.Fprintf(&b, "%s{%s} %d\n", v.name, strings.Join(pairs, ","), c.n.Load())
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
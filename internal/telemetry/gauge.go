@@ -0,0 +1,24 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+)
+
+// GaugeFunc — метрика-gauge, значение которой вычисляется при каждой выдаче.
+//
+// Поля:
+//   - name: имя метрики в формате Prometheus
+//   - help: описание метрики
+//   - fn: функция, возвращающая текущее значение
+type GaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+// WriteText записывает gauge в текстовом формате экспозиции Prometheus.
+func (g *GaugeFunc) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", g.name, g.help, g.name, g.name, formatFloat(g.fn()))
+	return err
+}
@@ -0,0 +1,122 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Histogram — гистограмма наблюдений с фиксированными верхними границами корзин.
+//
+// Поля:
+//   - bounds: верхние границы корзин по возрастанию (без +Inf)
+//   - counts: количество наблюдений в каждой корзине (последняя — +Inf)
+//   - sumBits: сумма наблюдений (биты float64)
+type Histogram struct {
+	bounds  []float64
+	counts  []atomic.Uint64
+	sumBits atomic.Uint64
+}
+
+// newHistogram создаёт гистограмму с границами корзин bounds.
+func newHistogram(bounds []float64) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]atomic.Uint64, len(bounds)+1)}
+}
+
+// Observe учитывает наблюдение v.
+func (h *Histogram) Observe(v float64) {
+	h.counts[sort.SearchFloat64s(h.bounds, v)].Add(1)
+	for {
+		old := h.sumBits.Load()
+		if h.sumBits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			break
+		}
+	}
+}
+
+// HistogramVec — семейство гистограмм, различающихся значением одной метки.
+//
+// Поля:
+//   - name: имя метрики в формате Prometheus
+//   - help: описание метрики
+//   - label: имя метки
+//   - bounds: верхние границы корзин
+//   - byValue: гистограммы по значениям метки
+type HistogramVec struct {
+	name    string
+	help    string
+	label   string
+	bounds  []float64
+	mu      sync.RWMutex
+	byValue map[string]*Histogram
+}
+
+// NewHistogramVec создаёт семейство гистограмм name с меткой label и границами корзин bounds.
+func NewHistogramVec(name, help, label string, bounds []float64) *HistogramVec {
+	b := append([]float64(nil), bounds...)
+	sort.Float64s(b)
+	return &HistogramVec{name: name, help: help, label: label, bounds: b, byValue: make(map[string]*Histogram)}
+}
+
+// With возвращает гистограмму для значения метки value, создавая её при первом обращении.
+func (v *HistogramVec) With(value string) *Histogram {
+	v.mu.RLock()
+	h, ok := v.byValue[value]
+	v.mu.RUnlock()
+	if ok {
+		return h
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if h, ok = v.byValue[value]; !ok {
+		h = newHistogram(v.bounds)
+		v.byValue[value] = h
+	}
+	return h
+}
+
+// WriteText записывает семейство в текстовом формате экспозиции Prometheus.
+//
+// Корзины выводятся накопительно, значения метки — по возрастанию.
+func (v *HistogramVec) WriteText(w io.Writer) error {
+	v.mu.RLock()
+	values := make([]string, 0, len(v.byValue))
+	for value := range v.byValue {
+		values = append(values, value)
+	}
+	v.mu.RUnlock()
+	sort.Strings(values)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", v.name, v.help, v.name)
+	for _, value := range values {
+		h := v.With(value)
+		label := v.label + `="` + escapeLabel(value) + `"`
+		var cumulative uint64
+		for i := range h.counts {
+			cumulative += h.counts[i].Load()
+			le := "+Inf"
+			if i < len(h.bounds) {
+				le = formatFloat(h.bounds[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket{%s,le=%q} %d\n", v.name, label, le, cumulative)
+		}
+		fmt.Fprintf(&b, "%s_sum{%s} %s\n", v.name, label, formatFloat(math.Float64frombits(h.sumBits.Load())))
+		// Общее количество берётся из корзин, чтобы совпадать с le="+Inf" при конкурентной записи.
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", v.name, label, cumulative)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// labelEscaper экранирует значение метки по правилам текстового формата Prometheus.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel экранирует обратную косую черту, кавычки и перевод строки в значении метки.
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,189 @@
+// Package telemetry содержит собственные метрики сервера в формате Prometheus:
+// запросы и задержки обработчиков по маршрутам, размеры принимаемых пакетов, задержки
+// операций хранилища, длительность сохранения снимков, ошибки записи во внешние хранилища
+// и глубину очередей.
+package telemetry
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ContentType — тип содержимого текстового формата экспозиции Prometheus.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// unmatchedRoute — значение метки для запросов, не совпавших ни с одним маршрутом.
+//
+// Исходный путь не используется, чтобы произвольные URL не порождали новые ряды.
+const unmatchedRoute = "unmatched"
+
+var (
+	// latencyBuckets — границы корзин задержки обработчиков в секундах.
+	latencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+	// batchSizeBuckets — границы корзин количества метрик в пакете.
+	batchSizeBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+	// storageBuckets — границы корзин задержки операций хранилища в секундах.
+	storageBuckets = []float64{0.000001, 0.00001, 0.0001, 0.001, 0.01, 0.1, 1}
+	// snapshotBuckets — границы корзин длительности сохранения снимка в секундах.
+	snapshotBuckets = []float64{0.001, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+)
+
+// Значения метки result гистограммы длительности сохранения снимков.
+const (
+	resultOK    = "ok"
+	resultError = "error"
+)
+
+// Metrics — собственные метрики сервера.
+//
+// Поля:
+//   - RequestDuration: задержка обработки HTTP-запросов по маршрутам (метка route, "METHOD pattern")
+//   - Requests: количество HTTP-запросов по маршрутам и кодам ответа (метки route и status)
+//   - BatchSize: количество метрик в принятых пакетах по транспорту (метка transport, "http" или "grpc")
+//   - StorageDuration: задержка операций хранилища (метка op, имя метода Storage)
+//   - SnapshotDuration: длительность сохранения снимков (метка result, "ok" или "error")
+//   - SyncErrors: ошибки записи во внешние хранилища (метка backend, например "postgres")
+//   - gauges: текущие значения, вычисляемые при выдаче (глубина очередей)
+type Metrics struct {
+	RequestDuration  *HistogramVec
+	Requests         *CounterVec
+	BatchSize        *HistogramVec
+	StorageDuration  *HistogramVec
+	SnapshotDuration *HistogramVec
+	SyncErrors       *CounterVec
+
+	mu     sync.Mutex
+	gauges []*GaugeFunc
+}
+
+// New создаёт пустой набор собственных метрик сервера.
+func New() *Metrics {
+	return &Metrics{
+		RequestDuration: NewHistogramVec("http_request_duration_seconds",
+			"Latency of HTTP handlers by route.", "route", latencyBuckets),
+		Requests: NewCounterVec("http_requests_total",
+			"Number of HTTP requests by route and response status.", "route", "status"),
+		BatchSize: NewHistogramVec("ingest_batch_size",
+			"Number of metrics per accepted update request.", "transport", batchSizeBuckets),
+		StorageDuration: NewHistogramVec("storage_operation_duration_seconds",
+			"Latency of metric storage operations by method.", "op", storageBuckets),
+		SnapshotDuration: NewHistogramVec("snapshot_duration_seconds",
+			"Duration of snapshot writes by result.", "result", snapshotBuckets),
+		SyncErrors: NewCounterVec("storage_sync_errors_total",
+			"Number of failed writes to external storage backends.", "backend"),
+	}
+}
+
+// RegisterGauge добавляет метрику-gauge name, значение которой возвращает fn при каждой выдаче.
+//
+// Безопасно вызывать на nil: метрика не добавляется.
+func (m *Metrics) RegisterGauge(name, help string, fn func() float64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges = append(m.gauges, &GaugeFunc{name: name, help: help, fn: fn})
+}
+
+// ObserveBatch учитывает размер принятого пакета n для транспорта transport.
+//
+// Безопасно вызывать на nil: наблюдение пропускается.
+func (m *Metrics) ObserveBatch(transport string, n int) {
+	if m == nil {
+		return
+	}
+	m.BatchSize.With(transport).Observe(float64(n))
+}
+
+// ObserveStorage учитывает задержку d операции хранилища op.
+//
+// Безопасно вызывать на nil: наблюдение пропускается.
+func (m *Metrics) ObserveStorage(op string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.StorageDuration.With(op).Observe(d.Seconds())
+}
+
+// ObserveSnapshot учитывает длительность d сохранения снимка, завершившегося ошибкой err.
+//
+// Безопасно вызывать на nil: наблюдение пропускается.
+func (m *Metrics) ObserveSnapshot(d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	result := resultOK
+	if err != nil {
+		result = resultError
+	}
+	m.SnapshotDuration.With(result).Observe(d.Seconds())
+}
+
+// ObserveSyncError учитывает ошибку записи во внешнее хранилище backend.
+//
+// Безопасно вызывать на nil: наблюдение пропускается.
+func (m *Metrics) ObserveSyncError(backend string, _ error) {
+	if m == nil {
+		return
+	}
+	m.SyncErrors.With(backend).Inc()
+}
+
+// Middleware возвращает middleware chi, учитывающий количество и задержку обработки запросов
+// по шаблону маршрута и код ответа.
+//
+// Должен подключаться через Use роутера chi: шаблон маршрута известен только после маршрутизации.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = r.Method + " " + rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m.RequestDuration.With(route).Observe(time.Since(start).Seconds())
+		m.Requests.With(route, strconv.Itoa(status)).Inc()
+	})
+}
+
+// ServeHTTP отдаёт метрики в текстовом формате экспозиции Prometheus.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	m.mu.Lock()
+	families := []interface{ WriteText(io.Writer) error }{
+		m.RequestDuration, m.Requests, m.BatchSize, m.StorageDuration, m.SnapshotDuration, m.SyncErrors,
+	}
+	for _, g := range m.gauges {
+		families = append(families, g)
+	}
+	m.mu.Unlock()
+
+	for _, f := range families {
+		if err := f.WriteText(w); err != nil {
+			log.Printf("Failed to write telemetry: %v", err)
+			return
+		}
+	}
+}
+
+// formatFloat форматирует число для текстового формата экспозиции.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
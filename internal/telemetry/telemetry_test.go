@@ -0,0 +1,96 @@
+package telemetry
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHistogramVec_WriteText проверяет накопительные корзины, сумму и количество в формате Prometheus.
+func TestHistogramVec_WriteText(t *testing.T) {
+	v := NewHistogramVec("test_seconds", "Test histogram.", "route", []float64{1, 0.1})
+	h := v.With(`GET /a"b`)
+	for _, x := range []float64{0.05, 0.5, 0.5, 3} {
+		h.Observe(x)
+	}
+
+	var b strings.Builder
+	require.NoError(t, v.WriteText(&b))
+	require.Equal(t, `# HELP test_seconds Test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{route="GET /a\"b",le="0.1"} 1
+test_seconds_bucket{route="GET /a\"b",le="1"} 3
+test_seconds_bucket{route="GET /a\"b",le="+Inf"} 4
+test_seconds_sum{route="GET /a\"b"} 4.05
+test_seconds_count{route="GET /a\"b"} 4
+`, b.String())
+}
+
+// TestMetrics_Middleware проверяет, что задержка учитывается по шаблону маршрута, а не по пути.
+func TestMetrics_Middleware(t *testing.T) {
+	m := New()
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/value/{type}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "name") == "b" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	for _, path := range []string{"/value/gauge/a", "/value/gauge/b", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	m.ObserveBatch("http", 42)
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	require.Contains(t, body, `http_request_duration_seconds_count{route="GET /value/{type}/{name}"} 2`)
+	require.Contains(t, body, `http_request_duration_seconds_count{route="unmatched"} 1`)
+	require.Contains(t, body, `http_requests_total{route="GET /value/{type}/{name}",status="200"} 1`)
+	require.Contains(t, body, `http_requests_total{route="GET /value/{type}/{name}",status="404"} 1`)
+	require.Contains(t, body, `http_requests_total{route="unmatched",status="404"} 1`)
+	require.Contains(t, body, `ingest_batch_size_bucket{transport="http",le="50"} 1`)
+	require.NotContains(t, body, "/missing")
+}
+
+// TestMetrics_ObserveBatchNil проверяет, что наблюдение на nil пропускается.
+func TestMetrics_ObserveBatchNil(t *testing.T) {
+	var m *Metrics
+	require.NotPanics(t, func() {
+		m.ObserveBatch("grpc", 1)
+		m.ObserveStorage("GetGauge", time.Millisecond)
+		m.ObserveSnapshot(time.Second, nil)
+		m.ObserveSyncError("postgres", errors.New("down"))
+		m.RegisterGauge("queue", "Queue.", func() float64 { return 1 })
+	})
+}
+
+// TestMetrics_Observe проверяет учёт задержек хранилища, сохранения снимков, ошибок записи
+// во внешние хранилища и вычисляемых gauge.
+func TestMetrics_Observe(t *testing.T) {
+	m := New()
+	m.ObserveStorage("SetGauge", 5*time.Microsecond)
+	m.ObserveSnapshot(20*time.Millisecond, nil)
+	m.ObserveSnapshot(time.Second, errors.New("disk full"))
+	m.ObserveSyncError("postgres", errors.New("down"))
+	m.ObserveSyncError("postgres", errors.New("down"))
+	depth := 3.0
+	m.RegisterGauge("wal_pending_records", "Pending WAL records.", func() float64 { return depth })
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	require.Contains(t, body, `storage_operation_duration_seconds_bucket{op="SetGauge",le="1e-05"} 1`)
+	require.Contains(t, body, `snapshot_duration_seconds_count{result="ok"} 1`)
+	require.Contains(t, body, `snapshot_duration_seconds_count{result="error"} 1`)
+	require.Contains(t, body, "# TYPE storage_sync_errors_total counter\nstorage_sync_errors_total{backend=\"postgres\"} 2\n")
+	require.Contains(t, body, "# TYPE wal_pending_records gauge\nwal_pending_records 3\n")
+}
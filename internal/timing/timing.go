@@ -0,0 +1,65 @@
+// Package timing накапливает длительность операций с хранилищами в пределах одного запроса.
+//
+// Журнал запросов (config.RequestLogger) создаёт Recorder и передаёт его через контекст,
+// хранилища отмечают в нём длительность записи (см. repository.FanOut), а медленные запросы
+// журналируются вместе с накопленными длительностями. Без Recorder в контексте Add ничего не делает.
+package timing
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// contextKey — ключ контекста для Recorder.
+type contextKey struct{}
+
+// Recorder накапливает суммарную длительность операций по именам.
+//
+// Безопасен для одновременного использования.
+type Recorder struct {
+	mu    sync.Mutex
+	spans map[string]time.Duration
+}
+
+// NewContext возвращает контекст с новым Recorder и сам Recorder.
+func NewContext(ctx context.Context) (context.Context, *Recorder) {
+	rec := &Recorder{}
+	return context.WithValue(ctx, contextKey{}, rec), rec
+}
+
+// Add прибавляет d к длительности операции name в Recorder контекста ctx.
+func Add(ctx context.Context, name string, d time.Duration) {
+	if rec, ok := ctx.Value(contextKey{}).(*Recorder); ok {
+		rec.add(name, d)
+	}
+}
+
+// add прибавляет d к длительности операции name.
+func (r *Recorder) add(name string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.spans == nil {
+		r.spans = make(map[string]time.Duration)
+	}
+	r.spans[name] += d
+}
+
+// Span — суммарная длительность операции.
+type Span struct {
+	Name     string        // Имя операции
+	Duration time.Duration // Суммарная длительность
+}
+
+// Spans возвращает накопленные длительности, отсортированные по имени операции.
+func (r *Recorder) Spans() []Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := make([]Span, 0, len(r.spans))
+	for name, d := range r.spans {
+		spans = append(spans, Span{Name: name, Duration: d})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Name < spans[j].Name })
+	return spans
+}
@@ -0,0 +1,37 @@
+package timing
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRecorder проверяет накопление длительностей по именам и порядок вывода.
+//
+// t — указатель на структуру теста.
+func TestRecorder(t *testing.T) {
+	// Без Recorder в контексте Add ничего не делает.
+	Add(context.Background(), "sync.db", time.Second)
+
+	ctx, rec := NewContext(context.Background())
+	require.Empty(t, rec.Spans())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Add(ctx, "sync.file", time.Millisecond)
+		}()
+	}
+	wg.Wait()
+	Add(ctx, "sync.db", 5*time.Millisecond)
+
+	require.Equal(t, []Span{
+		{Name: "sync.db", Duration: 5 * time.Millisecond},
+		{Name: "sync.file", Duration: 10 * time.Millisecond},
+	}, rec.Spans())
+}
@@ -0,0 +1,75 @@
+// Package tracing подключает трассировку OpenTelemetry: экспорт спанов по OTLP/HTTP,
+// спаны HTTP-запросов сервера, передачу контекста трассировки в заголовках запросов агента
+// и дочерние спаны запросов к PostgreSQL.
+//
+// Пока Setup не вызван или трассировка отключена, используются глобальные провайдер
+// и пропагатор OpenTelemetry по умолчанию, которые ничего не записывают и не передают.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName — имя библиотеки инструментирования в спанах.
+const instrumentationName = "github.com/RoGogDBD/metric-alerter"
+
+// Имена сервисов по умолчанию (config.TracingConfig.ServiceName).
+const (
+	ServiceServer = "metric-alerter-server"
+	ServiceAgent  = "metric-alerter-agent"
+)
+
+// Tracer возвращает трассировщик приложения из глобального провайдера.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup настраивает глобальные провайдер трассировки и пропагатор W3C Trace Context
+// для экспорта спанов по OTLP/HTTP на cfg.Endpoint.
+//
+// service — имя сервиса, если cfg.ServiceName не задано.
+// Возвращает функцию, отправляющую накопленные спаны и останавливающую экспорт; если
+// трассировка отключена, функция ничего не делает.
+func Setup(ctx context.Context, cfg config.TracingConfig, service string) (func(context.Context) error, error) {
+	if !cfg.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	if cfg.ServiceName != "" {
+		service = cfg.ServiceName
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", service),
+			attribute.String("service.version", version.Get().Version),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Inject записывает контекст трассировки ctx в заголовки header (traceparent, tracestate).
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestMiddleware проверяет имя спана по шаблону маршрута, продолжение трассы вызывающей
+// стороны из заголовка traceparent и отметку ошибки при ответе 5xx.
+//
+// t — указатель на структуру теста.
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string       // Название теста
+		path        string       // Путь запроса
+		traceparent string       // Заголовок traceparent вызывающей стороны
+		wantName    string       // Ожидаемое имя спана
+		wantStatus  codes.Code   // Ожидаемый статус спана
+		wantParent  trace.SpanID // Ожидаемый родительский спан
+	}{
+		{name: "RoutePattern", path: "/value/gauge/Alloc", wantName: "GET /value/{type}/{name}", wantStatus: codes.Unset},
+		{
+			name:        "RemoteParent",
+			path:        "/value/gauge/Alloc",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantName:    "GET /value/{type}/{name}",
+			wantStatus:  codes.Unset,
+			wantParent:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		},
+		{name: "ServerError", path: "/fail", wantName: "GET /fail", wantStatus: codes.Error},
+		{name: "NotFound", path: "/missing", wantName: "GET", wantStatus: codes.Unset},
+	}
+
+	recorder := useRecorder(t)
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/value/{type}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, trace.SpanFromContext(r.Context()).SpanContext().IsValid(), "handler sees the request span")
+		w.WriteHeader(http.StatusOK)
+	})
+	r.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder.Reset()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.traceparent != "" {
+				req.Header.Set("traceparent", tc.traceparent)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			require.Equal(t, tc.wantName, spans[0].Name())
+			require.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+			require.Equal(t, tc.wantStatus, spans[0].Status().Code)
+			require.Equal(t, tc.wantParent, spans[0].Parent().SpanID())
+		})
+	}
+}
+
+// TestInject проверяет, что контекст трассировки записывается в заголовок traceparent.
+//
+// t — указатель на структуру теста.
+func TestInject(t *testing.T) {
+	useRecorder(t)
+	ctx, span := Tracer().Start(context.Background(), "send batch")
+	defer span.End()
+
+	header := http.Header{}
+	Inject(ctx, header)
+	require.Contains(t, header.Get("traceparent"), span.SpanContext().TraceID().String())
+}
+
+// TestSetup_Disabled проверяет, что без адреса приёмника Setup не меняет глобальный провайдер.
+//
+// t — указатель на структуру теста.
+func TestSetup_Disabled(t *testing.T) {
+	before := otel.GetTracerProvider()
+	shutdown, err := Setup(context.Background(), config.DefaultTracingConfig(), ServiceServer)
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+	require.Equal(t, before, otel.GetTracerProvider())
+
+	_, err = Setup(context.Background(), config.TracingConfig{Endpoint: "collector:4318", SampleRatio: 1}, ServiceServer)
+	require.Error(t, err)
+}
+
+// TestSQLOperation проверяет определение операции запроса для имени спана.
+//
+// t — указатель на структуру теста.
+func TestSQLOperation(t *testing.T) {
+	require.Equal(t, "SELECT", sqlOperation("\n\t select id from metrics"))
+	require.Equal(t, "INSERT", sqlOperation("INSERT INTO metrics VALUES ($1)"))
+	require.Equal(t, "query", sqlOperation("  "))
+}
+
+// useRecorder устанавливает на время теста глобальный провайдер, записывающий спаны
+// в память, и пропагатор W3C Trace Context.
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
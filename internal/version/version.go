@@ -1,6 +1,20 @@
+// Package version предоставляет информацию о сборке приложения.
+//
+// Значения версии, даты и коммита задаются при сборке через ldflags:
+//
+//	go build -ldflags "-X github.com/RoGogDBD/metric-alerter/internal/version.buildVersion=v1.0.0 \
+//	  -X github.com/RoGogDBD/metric-alerter/internal/version.buildDate=2025-01-01 \
+//	  -X github.com/RoGogDBD/metric-alerter/internal/version.buildCommit=abc1234"
 package version
 
-import "fmt"
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// notAvailable — значение поля, не заданного при сборке.
+const notAvailable = "N/A"
 
 var (
 	// buildVersion — версия сборки приложения.
@@ -11,22 +25,48 @@ var (
 	buildCommit string
 )
 
-// PrintBuildInfo выводит информацию о сборке приложения.
-func PrintBuildInfo() {
-	version := "N/A"
-	if buildVersion != "" {
-		version = buildVersion
-	}
-	date := "N/A"
-	if buildDate != "" {
-		date = buildDate
-	}
-	commit := "N/A"
-	if buildCommit != "" {
-		commit = buildCommit
+// Info содержит информацию о сборке приложения.
+//
+// Поля:
+//   - Version: версия сборки
+//   - Date: дата сборки
+//   - Commit: хеш коммита сборки
+//   - GoVersion: версия Go, которой собран бинарный файл
+//   - Platform: целевая платформа в формате GOOS/GOARCH
+type Info struct {
+	Version   string `json:"version"`
+	Date      string `json:"date"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// Get возвращает информацию о текущей сборке.
+//
+// Значения, не заданные через ldflags, заменяются на "N/A".
+func Get() Info {
+	return Info{
+		Version:   valueOrNA(buildVersion),
+		Date:      valueOrNA(buildDate),
+		Commit:    valueOrNA(buildCommit),
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
 	}
+}
 
-	fmt.Printf("Build version: %s\n", version)
-	fmt.Printf("Build date: %s\n", date)
-	fmt.Printf("Build commit: %s\n", commit)
+// String возвращает многострочное текстовое представление информации о сборке.
+func (i Info) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Build version: %s\n", i.Version)
+	fmt.Fprintf(&b, "Build date: %s\n", i.Date)
+	fmt.Fprintf(&b, "Build commit: %s\n", i.Commit)
+	return b.String()
+}
+
+// valueOrNA возвращает v или "N/A", если v пусто.
+func valueOrNA(v string) string {
+	if v == "" {
+		return notAvailable
+	}
+	return v
 }
@@ -0,0 +1,45 @@
+package version
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestGet_TableDriven проверяет заполнение Info из значений ldflags и их JSON-представление.
+//
+// t — указатель на структуру теста.
+func TestGet_TableDriven(t *testing.T) {
+	tests := []struct {
+		name    string // Название теста
+		version string // Значение buildVersion
+		commit  string // Значение buildCommit
+		want    Info   // Ожидаемые поля (без GoVersion/Platform)
+	}{
+		{"not set", "", "", Info{Version: "N/A", Date: "N/A", Commit: "N/A"}},
+		{"set via ldflags", "v1.2.3", "abc1234", Info{Version: "v1.2.3", Date: "N/A", Commit: "abc1234"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			oldVersion, oldCommit := buildVersion, buildCommit
+			defer func() { buildVersion, buildCommit = oldVersion, oldCommit }()
+			buildVersion, buildCommit = tt.version, tt.commit
+
+			got := Get()
+			require.Equal(t, tt.want.Version, got.Version)
+			require.Equal(t, tt.want.Date, got.Date)
+			require.Equal(t, tt.want.Commit, got.Commit)
+			require.NotEmpty(t, got.GoVersion)
+			require.Contains(t, got.String(), "Build version: "+tt.want.Version)
+
+			data, err := json.Marshal(got)
+			require.NoError(t, err)
+			var decoded Info
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			require.Equal(t, got, decoded)
+		})
+	}
+}
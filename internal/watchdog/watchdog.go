@@ -0,0 +1,161 @@
+// Package watchdog реализует сторожевой таймер для обнаружения утечек ресурсов сервера.
+//
+// Watchdog периодически измеряет число горутин, открытых файловых дескрипторов и объём кучи,
+// публикует их как собственные метрики сервера и пишет предупреждение в журнал,
+// если значение монотонно растёт на протяжении окна измерений и превышает порог.
+package watchdog
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/shirou/gopsutil/v3/process"
+	"go.uber.org/zap"
+)
+
+// Имена собственных метрик сервера, публикуемых сторожевым таймером.
+const (
+	MetricGoroutines = "ServerGoroutines"
+	MetricOpenFDs    = "ServerOpenFDs"
+	MetricHeapAlloc  = "ServerHeapAlloc"
+)
+
+// Sample — одно измерение ресурсов процесса.
+//
+// OpenFDs равно -1, если число дескрипторов не удалось определить на текущей платформе.
+type Sample struct {
+	Goroutines int
+	OpenFDs    int
+	HeapAlloc  uint64
+}
+
+// Watchdog периодически измеряет ресурсы процесса и сообщает о подозрении на утечку.
+//
+// Поля:
+//   - cfg: настройки сторожевого таймера
+//   - storage: хранилище для публикации собственных метрик (может быть nil)
+//   - logger: логгер для предупреждений
+//   - history: последние измерения (не более cfg.Window)
+//   - sample: функция получения измерения
+type Watchdog struct {
+	cfg     config.WatchdogConfig
+	storage repository.Storage
+	logger  *zap.Logger
+	history []Sample
+	sample  func() Sample
+}
+
+// New создаёт новый экземпляр Watchdog.
+//
+// cfg — настройки сторожевого таймера.
+// storage — хранилище для публикации собственных метрик; если nil, метрики не публикуются.
+// logger — логгер для предупреждений.
+//
+// Возвращает указатель на Watchdog.
+func New(cfg config.WatchdogConfig, storage repository.Storage, logger *zap.Logger) *Watchdog {
+	if cfg.Window < 2 {
+		cfg.Window = 2
+	}
+	return &Watchdog{
+		cfg:     cfg,
+		storage: storage,
+		logger:  logger,
+		history: make([]Sample, 0, cfg.Window),
+		sample:  readSample,
+	}
+}
+
+// Run выполняет измерения с периодом cfg.Interval до отмены контекста.
+//
+// Если интервал не положителен, функция сразу возвращает управление.
+func (w *Watchdog) Run(ctx context.Context) {
+	if w.cfg.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Observe(w.sample())
+		}
+	}
+}
+
+// Observe обрабатывает очередное измерение: публикует метрики и проверяет пороги.
+//
+// Возвращает имена метрик, по которым обнаружен монотонный рост выше порога.
+func (w *Watchdog) Observe(s Sample) []string {
+	if w.storage != nil {
+		w.storage.SetGauge(MetricGoroutines, float64(s.Goroutines))
+		w.storage.SetGauge(MetricHeapAlloc, float64(s.HeapAlloc))
+		if s.OpenFDs >= 0 {
+			w.storage.SetGauge(MetricOpenFDs, float64(s.OpenFDs))
+		}
+	}
+
+	if len(w.history) == w.cfg.Window {
+		copy(w.history, w.history[1:])
+		w.history = w.history[:len(w.history)-1]
+	}
+	w.history = append(w.history, s)
+	if len(w.history) < w.cfg.Window {
+		return nil
+	}
+
+	var alerts []string
+	if s.Goroutines > w.cfg.MaxGoroutines && w.growing(func(s Sample) uint64 { return uint64(s.Goroutines) }) {
+		alerts = append(alerts, MetricGoroutines)
+	}
+	if s.OpenFDs >= 0 && s.OpenFDs > w.cfg.MaxOpenFDs && w.growing(func(s Sample) uint64 { return uint64(s.OpenFDs) }) {
+		alerts = append(alerts, MetricOpenFDs)
+	}
+	if s.HeapAlloc > w.cfg.MaxHeapBytes && w.growing(func(s Sample) uint64 { return s.HeapAlloc }) {
+		alerts = append(alerts, MetricHeapAlloc)
+	}
+
+	for _, name := range alerts {
+		w.logger.Warn("Possible resource leak detected",
+			zap.String("metric", name),
+			zap.Int("goroutines", s.Goroutines),
+			zap.Int("open_fds", s.OpenFDs),
+			zap.Uint64("heap_alloc", s.HeapAlloc),
+			zap.Int("window", w.cfg.Window),
+		)
+	}
+	return alerts
+}
+
+// growing проверяет, что значение строго возрастает на всём окне измерений.
+func (w *Watchdog) growing(value func(Sample) uint64) bool {
+	for i := 1; i < len(w.history); i++ {
+		if value(w.history[i]) <= value(w.history[i-1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// readSample измеряет текущие ресурсы процесса.
+func readSample() Sample {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	s := Sample{
+		Goroutines: runtime.NumGoroutine(),
+		OpenFDs:    -1,
+		HeapAlloc:  m.HeapAlloc,
+	}
+	if p, err := process.NewProcess(int32(os.Getpid())); err == nil {
+		if n, err := p.NumFDs(); err == nil {
+			s.OpenFDs = int(n)
+		}
+	}
+	return s
+}
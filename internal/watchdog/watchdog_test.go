@@ -0,0 +1,87 @@
+package watchdog
+
+import (
+	"testing"
+
+	"github.com/RoGogDBD/metric-alerter/internal/config"
+	"github.com/RoGogDBD/metric-alerter/internal/repository"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestWatchdog_Observe_TableDriven проверяет срабатывание сторожевого таймера
+// только при монотонном росте выше порога на всём окне измерений.
+//
+// t — указатель на структуру теста.
+func TestWatchdog_Observe_TableDriven(t *testing.T) {
+	cfg := config.WatchdogConfig{
+		Window:        3,
+		MaxGoroutines: 10,
+		MaxOpenFDs:    10,
+		MaxHeapBytes:  100,
+	}
+
+	tests := []struct {
+		name    string   // Название теста
+		samples []Sample // Последовательность измерений
+		want    []string // Ожидаемые срабатывания на последнем измерении
+	}{
+		{
+			name:    "goroutines growing above threshold",
+			samples: []Sample{{Goroutines: 11}, {Goroutines: 12}, {Goroutines: 13}},
+			want:    []string{MetricGoroutines},
+		},
+		{
+			name:    "growing below threshold",
+			samples: []Sample{{Goroutines: 1}, {Goroutines: 2}, {Goroutines: 3}},
+			want:    nil,
+		},
+		{
+			name:    "above threshold but not monotonic",
+			samples: []Sample{{Goroutines: 20}, {Goroutines: 15}, {Goroutines: 25}},
+			want:    nil,
+		},
+		{
+			name:    "window not filled",
+			samples: []Sample{{Goroutines: 20}, {Goroutines: 30}},
+			want:    nil,
+		},
+		{
+			name:    "fds and heap growing",
+			samples: []Sample{{OpenFDs: 11, HeapAlloc: 101}, {OpenFDs: 12, HeapAlloc: 102}, {OpenFDs: 13, HeapAlloc: 103}},
+			want:    []string{MetricOpenFDs, MetricHeapAlloc},
+		},
+		{
+			name:    "unknown fds ignored",
+			samples: []Sample{{OpenFDs: -1}, {OpenFDs: -1}, {OpenFDs: -1}},
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(cfg, nil, zap.NewNop())
+			var got []string
+			for _, s := range tt.samples {
+				got = w.Observe(s)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestWatchdog_PublishesMetrics проверяет публикацию собственных метрик сервера в хранилище.
+//
+// t — указатель на структуру теста.
+func TestWatchdog_PublishesMetrics(t *testing.T) {
+	storage := repository.NewMemStorage()
+	w := New(config.DefaultWatchdogConfig(), storage, zap.NewNop())
+	w.Observe(readSample())
+
+	v, ok := storage.GetGauge(MetricGoroutines)
+	require.True(t, ok)
+	require.Greater(t, v, 0.0)
+	_, ok = storage.GetGauge(MetricHeapAlloc)
+	require.True(t, ok)
+}
@@ -0,0 +1,204 @@
+// Package client — SDK для отправки метрик на сервер metric-alerter из сторонних приложений.
+//
+// Все сетевые операции принимают context.Context: срок и отмена контекста ограничивают
+// и саму отправку, и паузы между повторными попытками. Метрики можно отправить сразу
+// через Push или накапливать через Add и отправлять периодически через Run; при
+// завершении приложения Close отправляет остаток в пределах срока контекста.
+//
+//	c := client.New("http://localhost:8080", client.WithKey(key))
+//	go c.Run(ctx, nil)
+//	c.Add(client.Counter("requests", 1), client.Gauge("queue_len", 12))
+//	...
+//	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	err := c.Close(shutdownCtx)
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Client отправляет метрики на сервер с повторными попытками и накапливает их между отправками.
+//
+// Накопленные счётчики с одинаковым именем суммируются, датчики перезаписываются.
+// Методы безопасны для конкурентного использования.
+//
+// Поля:
+//   - opts: параметры клиента
+//   - mu: мьютекс для доступа к накопленным метрикам
+//   - pending: накопленные метрики в порядке первого добавления
+//   - index: позиция метрики в pending по типу и имени
+//   - closed: клиент закрыт, новые метрики не принимаются
+type Client struct {
+	opts    options
+	mu      sync.Mutex
+	pending []Metric
+	index   map[string]int
+	closed  bool
+}
+
+// New создаёт клиент сервера с адресом baseURL (например, "http://localhost:8080").
+//
+// baseURL не используется, если транспорт задан через WithTransport.
+func New(baseURL string, opts ...Option) *Client {
+	o := options{
+		flushInterval: DefaultFlushInterval,
+		attempts:      DefaultRetryAttempts,
+		backoff:       DefaultRetryBackoff,
+	}
+	o.http.BaseURL = baseURL
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.transport == nil {
+		o.transport = &o.http
+	}
+	o.attempts = max(o.attempts, 1)
+	return &Client{opts: o, index: make(map[string]int)}
+}
+
+// Push сразу отправляет метрики, разбивая их на батчи не больше WithMaxBatchSize.
+//
+// Возвращает ошибку первого батча, который не удалось отправить за все попытки;
+// батчи, отправленные до него, не повторяются.
+func (c *Client) Push(ctx context.Context, metrics ...Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	size := c.opts.maxBatchSize
+	if size <= 0 {
+		size = len(metrics)
+	}
+	for start := 0; start < len(metrics); start += size {
+		end := min(start+size, len(metrics))
+		if err := c.send(ctx, metrics[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send отправляет батч с повторными попытками.
+func (c *Client) send(ctx context.Context, batch []Metric) error {
+	wait := c.opts.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = c.attempt(ctx, batch); err == nil || !Retryable(err) || attempt >= c.opts.attempts {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+}
+
+// attempt выполняет одну попытку отправки с учётом WithRequestTimeout.
+func (c *Client) attempt(ctx context.Context, batch []Metric) error {
+	if c.opts.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.requestTimeout)
+		defer cancel()
+	}
+	return c.opts.transport.Send(ctx, batch)
+}
+
+// Add накапливает метрики до следующего Flush.
+//
+// Возвращает ErrClosed, если клиент уже закрыт.
+func (c *Client) Add(metrics ...Metric) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	for _, m := range metrics {
+		c.merge(m)
+	}
+	return nil
+}
+
+// merge добавляет метрику к накопленным. Вызывается под c.mu.
+func (c *Client) merge(m Metric) {
+	key := m.Type + ":" + m.ID
+	i, ok := c.index[key]
+	if !ok {
+		c.index[key] = len(c.pending)
+		c.pending = append(c.pending, m)
+		return
+	}
+	if m.Type == TypeCounter && m.Delta != nil && c.pending[i].Delta != nil {
+		sum := *c.pending[i].Delta + *m.Delta
+		c.pending[i].Delta = &sum
+		return
+	}
+	c.pending[i] = m
+}
+
+// Flush отправляет накопленные метрики.
+//
+// Если отправка не удалась с ошибкой, после которой её стоит повторить (Retryable),
+// метрики возвращаются в накопленные и уйдут со следующим Flush.
+func (c *Client) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	batch := c.pending
+	c.pending = nil
+	c.index = make(map[string]int)
+	c.mu.Unlock()
+
+	err := c.Push(ctx, batch...)
+	if err != nil && Retryable(err) {
+		c.requeue(batch)
+	}
+	return err
+}
+
+// requeue возвращает неотправленные метрики перед добавленными после них,
+// чтобы более новые значения датчиков не были перезаписаны старыми.
+func (c *Client) requeue(batch []Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	newer := c.pending
+	c.pending = nil
+	c.index = make(map[string]int)
+	for _, m := range batch {
+		c.merge(m)
+	}
+	for _, m := range newer {
+		c.merge(m)
+	}
+}
+
+// Run отправляет накопленные метрики каждые WithFlushInterval, пока ctx не завершён.
+//
+// Ошибки отдельных отправок передаются в onError (nil — игнорируются). Накопленный
+// к моменту завершения остаток не отправляется: для этого служит Close.
+func (c *Client) Run(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(c.opts.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Flush(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Close запрещает добавление метрик и отправляет накопленный остаток в пределах срока ctx.
+func (c *Client) Close(ctx context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	return c.Flush(ctx)
+}
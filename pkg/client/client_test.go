@@ -0,0 +1,147 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTransport — транспорт для тестов, возвращающий ошибки из errs по очереди.
+type fakeTransport struct {
+	errs  []error
+	calls int
+	sent  [][]Metric
+}
+
+// Send запоминает успешно отправленный батч.
+func (f *fakeTransport) Send(_ context.Context, metrics []Metric) error {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	f.sent = append(f.sent, append([]Metric(nil), metrics...))
+	return nil
+}
+
+// TestClient_PushRetry проверяет повтор временных ошибок и отказ от повтора остальных.
+func TestClient_PushRetry(t *testing.T) {
+	unavailable := &StatusError{StatusCode: http.StatusServiceUnavailable}
+	rejected := &StatusError{StatusCode: http.StatusBadRequest, Code: "invalid_metric"}
+	tests := []struct {
+		name      string
+		errs      []error
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "success", wantCalls: 1},
+		{name: "retried until success", errs: []error{unavailable, unavailable}, wantCalls: 3},
+		{name: "attempts exhausted", errs: []error{unavailable, unavailable, unavailable}, wantErr: unavailable, wantCalls: 3},
+		{name: "rejected is not retried", errs: []error{rejected}, wantErr: rejected, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &fakeTransport{errs: tt.errs}
+			c := New("", WithTransport(tr), WithRetry(3, time.Millisecond))
+			err := c.Push(context.Background(), Gauge("g", 1))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantCalls, tr.calls)
+		})
+	}
+}
+
+// TestClient_PushContext проверяет, что отмена контекста прерывает ожидание повторной попытки.
+func TestClient_PushContext(t *testing.T) {
+	tr := &fakeTransport{errs: []error{errors.New("connection refused")}}
+	c := New("", WithTransport(tr), WithRetry(3, time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := c.Push(ctx, Gauge("g", 1))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 1, tr.calls)
+}
+
+// TestClient_PushMaxBatchSize проверяет разбиение батча на части.
+func TestClient_PushMaxBatchSize(t *testing.T) {
+	tr := &fakeTransport{}
+	c := New("", WithTransport(tr), WithMaxBatchSize(2))
+	require.NoError(t, c.Push(context.Background(), Gauge("a", 1), Gauge("b", 2), Gauge("c", 3)))
+	require.Len(t, tr.sent, 2)
+	require.Len(t, tr.sent[0], 2)
+	require.Len(t, tr.sent[1], 1)
+}
+
+// TestClient_AddFlush проверяет объединение накопленных метрик и их сохранение при ошибке отправки.
+func TestClient_AddFlush(t *testing.T) {
+	tr := &fakeTransport{errs: []error{errors.New("connection refused")}}
+	c := New("", WithTransport(tr), WithRetry(1, 0))
+	ctx := context.Background()
+
+	require.NoError(t, c.Add(Counter("hits", 1), Gauge("temp", 10), Counter("hits", 2)))
+	require.Error(t, c.Flush(ctx))
+
+	// Неотправленные метрики объединяются с добавленными позже; новый датчик важнее старого.
+	require.NoError(t, c.Add(Counter("hits", 4), Gauge("temp", 20)))
+	require.NoError(t, c.Close(ctx))
+	require.Equal(t, [][]Metric{{Counter("hits", 7), Gauge("temp", 20)}}, tr.sent)
+
+	require.ErrorIs(t, c.Add(Gauge("late", 1)), ErrClosed)
+}
+
+// TestHTTPTransport проверяет формат запроса и разбор ошибки сервера.
+func TestHTTPTransport(t *testing.T) {
+	const key = "secret"
+	status := http.StatusOK
+	var got []Metric
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/updates/", r.URL.Path)
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		require.Equal(t, "Bearer api", r.Header.Get("Authorization"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		h := hmac.New(sha256.New, []byte(key))
+		h.Write(body)
+		require.Equal(t, hex.EncodeToString(h.Sum(nil)), r.Header.Get("HashSHA256"))
+
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		require.NoError(t, json.NewDecoder(gz).Decode(&got))
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			_, _ = w.Write([]byte(`{"code":"invalid_metric","message":"bad"}`))
+		}
+	}))
+	defer ts.Close()
+
+	c := New(ts.URL, WithKey(key), WithAPIKey("api"), WithRetry(1, 0))
+	require.NoError(t, c.Push(context.Background(), Counter("hits", 3)))
+	require.Equal(t, []Metric{Counter("hits", 3)}, got)
+
+	status = http.StatusBadRequest
+	err := c.Push(context.Background(), Counter("hits", 3))
+	var se *StatusError
+	require.ErrorAs(t, err, &se)
+	require.Equal(t, http.StatusBadRequest, se.StatusCode)
+	require.Equal(t, "invalid_metric", se.Code)
+	require.False(t, Retryable(err))
+}
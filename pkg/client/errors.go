@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrClosed возвращается при добавлении метрик в закрытый клиент.
+var ErrClosed = errors.New("client: closed")
+
+// StatusError — ответ сервера с кодом статуса, отличным от 200.
+//
+// Поля:
+//   - StatusCode: код статуса HTTP
+//   - Code: машинно-читаемый код ошибки из тела ответа (пусто, если тело в другом формате)
+//   - Message: описание ошибки из тела ответа
+type StatusError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+// Error возвращает описание ошибки.
+func (e *StatusError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("client: unexpected status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("client: unexpected status %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Retryable сообщает, имеет ли смысл повторить отправку, завершившуюся ошибкой err.
+//
+// Повторяются ошибки сети, ответы 429 и 5xx. Отмена и истечение контекста,
+// а также отказ сервера принять данные или учётные данные не повторяются.
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
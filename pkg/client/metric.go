@@ -0,0 +1,33 @@
+package client
+
+// Типы метрик.
+const (
+	// TypeGauge — датчик: значение Value перезаписывает предыдущее.
+	TypeGauge = "gauge"
+	// TypeCounter — счётчик: приращение Delta прибавляется к накопленному значению.
+	TypeCounter = "counter"
+)
+
+// Metric — метрика в формате JSON API сервера.
+//
+// Поля:
+//   - ID: имя метрики
+//   - Type: тип метрики (TypeGauge или TypeCounter)
+//   - Delta: приращение счётчика
+//   - Value: значение датчика
+type Metric struct {
+	ID    string   `json:"id"`
+	Type  string   `json:"type"`
+	Delta *int64   `json:"delta,omitempty"`
+	Value *float64 `json:"value,omitempty"`
+}
+
+// Gauge создаёт метрику-датчик.
+func Gauge(id string, value float64) Metric {
+	return Metric{ID: id, Type: TypeGauge, Value: &value}
+}
+
+// Counter создаёт метрику-счётчик с приращением delta.
+func Counter(id string, delta int64) Metric {
+	return Metric{ID: id, Type: TypeCounter, Delta: &delta}
+}
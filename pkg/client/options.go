@@ -0,0 +1,73 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+// Значения по умолчанию для клиента.
+const (
+	DefaultFlushInterval = 10 * time.Second
+	DefaultRetryAttempts = 3
+	DefaultRetryBackoff  = 500 * time.Millisecond
+)
+
+// Option настраивает Client.
+type Option func(*options)
+
+// options — параметры, собранные из Option.
+type options struct {
+	transport      Transport
+	http           HTTPTransport
+	maxBatchSize   int
+	flushInterval  time.Duration
+	attempts       int
+	backoff        time.Duration
+	requestTimeout time.Duration
+}
+
+// WithTransport заменяет HTTP-транспорт по умолчанию, например на gRPC или тестовый.
+//
+// Параметры WithHTTPClient, WithKey и WithAPIKey к такому транспорту не применяются.
+func WithTransport(t Transport) Option {
+	return func(o *options) { o.transport = t }
+}
+
+// WithHTTPClient задаёт HTTP-клиент транспорта по умолчанию.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) { o.http.Client = c }
+}
+
+// WithKey задаёт ключ подписи HMAC-SHA256 тела запроса.
+func WithKey(key string) Option {
+	return func(o *options) { o.http.Key = key }
+}
+
+// WithAPIKey задаёт API-ключ с ролью writer.
+func WithAPIKey(key string) Option {
+	return func(o *options) { o.http.APIKey = key }
+}
+
+// WithMaxBatchSize ограничивает число метрик в одном запросе; большие батчи разбиваются (0 — без ограничения).
+func WithMaxBatchSize(n int) Option {
+	return func(o *options) { o.maxBatchSize = n }
+}
+
+// WithFlushInterval задаёт период отправки накопленных метрик в Run.
+func WithFlushInterval(d time.Duration) Option {
+	return func(o *options) { o.flushInterval = d }
+}
+
+// WithRetry задаёт число попыток отправки и паузу перед первой повторной попыткой;
+// каждая следующая пауза вдвое длиннее. attempts = 1 отключает повторы.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// WithRequestTimeout ограничивает время одной попытки отправки (0 — только срок контекста вызова).
+func WithRequestTimeout(d time.Duration) Option {
+	return func(o *options) { o.requestTimeout = d }
+}
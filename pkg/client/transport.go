@@ -0,0 +1,99 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Transport доставляет батч метрик на сервер.
+//
+// Реализация должна прекращать отправку при отмене ctx. Ошибки, после которых
+// отправку стоит повторить, должны распознаваться функцией Retryable.
+type Transport interface {
+	Send(ctx context.Context, metrics []Metric) error
+}
+
+// TransportFunc позволяет использовать функцию как Transport.
+type TransportFunc func(ctx context.Context, metrics []Metric) error
+
+// Send вызывает f(ctx, metrics).
+func (f TransportFunc) Send(ctx context.Context, metrics []Metric) error {
+	return f(ctx, metrics)
+}
+
+// HTTPTransport отправляет батч в POST /updates/ в сжатом gzip JSON.
+//
+// Поля:
+//   - BaseURL: адрес сервера, например "http://localhost:8080"
+//   - Client: HTTP-клиент (nil — http.DefaultClient)
+//   - Key: ключ подписи HMAC-SHA256 тела запроса (пусто — без подписи)
+//   - APIKey: API-ключ для заголовка Authorization (пусто — не передаётся)
+type HTTPTransport struct {
+	BaseURL string
+	Client  *http.Client
+	Key     string
+	APIKey  string
+}
+
+// Send отправляет батч одним запросом.
+func (t *HTTPTransport) Send(ctx context.Context, metrics []Metric) error {
+	body, err := json.Marshal(metrics)
+	if err != nil {
+		return fmt.Errorf("client: marshal batch: %w", err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return fmt.Errorf("client: compress batch: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("client: compress batch: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(t.BaseURL, "/")+"/updates/", bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return fmt.Errorf("client: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	if t.Key != "" {
+		h := hmac.New(sha256.New, []byte(t.Key))
+		h.Write(buf.Bytes())
+		req.Header.Set("HashSHA256", hex.EncodeToString(h.Sum(nil)))
+	}
+	if t.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	}
+
+	httpClient := t.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("client: send batch: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	se := &StatusError{StatusCode: resp.StatusCode}
+	var apiErr struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&apiErr); err == nil {
+		se.Code, se.Message = apiErr.Code, apiErr.Message
+	}
+	return se
+}